@@ -7,26 +7,35 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"math"
 	"net/url"
 	"os"
 	"os/exec"
+	"os/user"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"text/tabwriter"
 	"time"
 
+	. "github.com/etix/mirrorbits/config"
 	"github.com/etix/mirrorbits/core"
 	"github.com/etix/mirrorbits/filesystem"
 	"github.com/etix/mirrorbits/mirrors"
+	"github.com/etix/mirrorbits/network"
 	"github.com/etix/mirrorbits/rpc"
 	"github.com/etix/mirrorbits/utils"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/golang/protobuf/ptypes/timestamp"
 	"github.com/op/go-logging"
 	"golang.org/x/term"
 	"google.golang.org/grpc"
@@ -89,33 +98,43 @@ func ParseCommands(args ...string) error {
 }
 
 func (c *cli) getMethod(name string) (reflect.Method, bool) {
+	name = strings.Replace(name, "-", "", -1)
 	methodName := "Cmd" + strings.ToUpper(name[:1]) + strings.ToLower(name[1:])
 	return reflect.TypeOf(c).MethodByName(methodName)
 }
 
 func (c *cli) CmdHelp() error {
 	help := fmt.Sprintf("Usage: mirrorbits [OPTIONS] COMMAND [arg...]\n\nA smart download redirector.\n\n")
-	help += fmt.Sprintf("Server commands:\n    %-10.10s%s\n\n", "daemon", "Start the server")
+	help += fmt.Sprintf("Server commands:\n    %-14.14s%s\n\n", "daemon", "Start the server")
 	help += fmt.Sprintf("CLI commands:\n")
 	for _, command := range [][]string{
 		{"add", "Add a new mirror"},
+		{"check-config", "Validate the configuration file"},
+		{"diff", "Compare the file indexes of two mirrors"},
 		{"disable", "Disable a mirror"},
 		{"edit", "Edit a mirror"},
 		{"enable", "Enable a mirror"},
 		{"export", "Export the mirror database"},
+		{"force-state", "Force a mirror up or down"},
+		{"geoip-update", "Download fresh GeoIP databases"},
 		{"geoupdate", "Update geolocation of a mirror"},
+		{"import", "Import mirrors from a yaml file"},
 		{"list", "List all mirrors"},
+		{"locate", "Print the mirrors carrying a file"},
 		{"logs", "Print logs of a mirror"},
+		{"maintenance", "Toggle the maintenance mode"},
+		{"prune", "Remove the mirrors down for a long time"},
 		{"refresh", "Refresh the local repository"},
 		{"reload", "Reload configuration"},
 		{"remove", "Remove a mirror"},
 		{"scan", "(Re-)Scan a mirror"},
 		{"show", "Print a mirror configuration"},
+		{"simulate", "Simulate the mirror selection for a client"},
 		{"stats", "Show download stats"},
 		{"upgrade", "Seamless binary upgrade"},
 		{"version", "Print version information"},
 	} {
-		help += fmt.Sprintf("    %-10.10s%s\n", command[0], command[1])
+		help += fmt.Sprintf("    %-14.14s%s\n", command[0], command[1])
 	}
 	fmt.Fprintf(os.Stderr, "%s\n", help)
 	return nil
@@ -143,11 +162,15 @@ func (c *cli) CmdList(args ...string) error {
 	rsync := cmd.Bool("rsync", false, "Print rsync addresses")
 	ftp := cmd.Bool("ftp", false, "Print FTP addresses")
 	location := cmd.Bool("location", false, "Print the country and continent code")
+	region := cmd.Bool("region", false, "Print the region of the mirror")
 	state := cmd.Bool("state", true, "Print the state of the mirror")
 	score := cmd.Bool("score", false, "Print the score of the mirror")
 	disabled := cmd.Bool("disabled", false, "List disabled mirrors only")
 	enabled := cmd.Bool("enabled", false, "List enabled mirrors only")
 	down := cmd.Bool("down", false, "List only mirrors currently down")
+	labels := labelFilter{}
+	cmd.Var(labels, "label", "List only the mirrors with the label key=value (can be repeated)")
+	jsonOutput := cmd.Bool("json", false, "Print the list of mirrors as JSON")
 
 	if err := cmd.Parse(args); err != nil {
 		return nil
@@ -167,6 +190,42 @@ func (c *cli) CmdList(args ...string) error {
 
 	sort.Sort(ByDate(list.Mirrors))
 
+	selected := make([]*rpc.Mirror, 0, len(list.Mirrors))
+	for _, mirror := range list.Mirrors {
+		if *disabled == true {
+			if mirror.Enabled == true {
+				continue
+			}
+		}
+		if *enabled == true {
+			if mirror.Enabled == false {
+				continue
+			}
+		}
+		if *down == true {
+			if IsUp(mirror) || mirror.Enabled == false {
+				continue
+			}
+		}
+		if !mirrors.Labels(mirror.Labels).Match(mirrors.Labels(labels)) {
+			continue
+		}
+		selected = append(selected, mirror)
+	}
+
+	if *jsonOutput == true {
+		jsonMirrors := make([]listMirrorJSON, 0, len(selected))
+		for _, mirror := range selected {
+			jsonMirrors = append(jsonMirrors, newListMirrorJSON(mirror))
+		}
+		out, err := json.MarshalIndent(jsonMirrors, "", "    ")
+		if err != nil {
+			log.Fatal("list error:", err)
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+
 	w := new(tabwriter.Writer)
 	w.Init(os.Stdout, 0, 8, 1, '\t', 0)
 	fmt.Fprint(w, "IDENTIFIER")
@@ -185,27 +244,15 @@ func (c *cli) CmdList(args ...string) error {
 	if *location == true {
 		fmt.Fprint(w, "\tLOCATION")
 	}
+	if *region == true {
+		fmt.Fprint(w, "\tREGION")
+	}
 	if *state == true {
 		fmt.Fprint(w, "\tSTATE\tSINCE\tREASON")
 	}
 	fmt.Fprint(w, "\n")
 
-	for _, mirror := range list.Mirrors {
-		if *disabled == true {
-			if mirror.Enabled == true {
-				continue
-			}
-		}
-		if *enabled == true {
-			if mirror.Enabled == false {
-				continue
-			}
-		}
-		if *down == true {
-			if IsUp(mirror) || mirror.Enabled == false {
-				continue
-			}
-		}
+	for _, mirror := range selected {
 		stateSince, err := ptypes.Timestamp(mirror.StateSince)
 		if err != nil {
 			log.Fatal("list error:", err)
@@ -231,6 +278,9 @@ func (c *cli) CmdList(args ...string) error {
 			}
 			fmt.Fprintf(w, "\t%s (%s)", countryCode, mirror.ContinentCode)
 		}
+		if *region == true {
+			fmt.Fprintf(w, "\t%s", mirror.Region)
+		}
 		if *state == true {
 			status := "disabled"
 			reason := ""
@@ -249,6 +299,64 @@ func (c *cli) CmdList(args ...string) error {
 	return nil
 }
 
+// listMirrorJSON is the representation of a mirror in the JSON output of
+// the list command
+type listMirrorJSON struct {
+	Name               string            `json:"name"`
+	HttpURL            string            `json:"httpURL"`
+	Enabled            bool              `json:"enabled"`
+	Up                 bool              `json:"up"`
+	Score              int32             `json:"score"`
+	CountryCodes       []string          `json:"countryCodes"`
+	Region             string            `json:"region,omitempty"`
+	LastSync           string            `json:"lastSync,omitempty"`
+	LastSuccessfulSync string            `json:"lastSuccessfulSync,omitempty"`
+	LatencyMs          float64           `json:"latencyMs,omitempty"`
+	Labels             map[string]string `json:"labels,omitempty"`
+}
+
+func newListMirrorJSON(m *rpc.Mirror) listMirrorJSON {
+	return listMirrorJSON{
+		Name:               m.Name,
+		HttpURL:            m.HttpURL,
+		Enabled:            m.Enabled,
+		Up:                 IsUp(m),
+		Score:              m.Score,
+		CountryCodes:       strings.Fields(m.CountryCodes),
+		Region:             m.Region,
+		LastSync:           formatRFC3339(m.LastSync),
+		LastSuccessfulSync: formatRFC3339(m.LastSuccessfulSync),
+		LatencyMs:          math.Round(m.Latency),
+		Labels:             m.Labels,
+	}
+}
+
+// labelFilter holds the labels given to the -label flag of the list command
+type labelFilter mirrors.Labels
+
+func (l labelFilter) String() string { return mirrors.Labels(l).String() }
+
+func (l labelFilter) Set(s string) error {
+	labels, err := mirrors.ParseLabels(s)
+	if err != nil {
+		return err
+	}
+	for k, v := range labels {
+		l[k] = v
+	}
+	return nil
+}
+
+// formatRFC3339 returns the timestamp as an RFC3339 string, or an empty
+// string if the timestamp is unset
+func formatRFC3339(ts *timestamp.Timestamp) string {
+	t, err := ptypes.Timestamp(ts)
+	if err != nil || t.IsZero() || t.Unix() == 0 {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
 func IsHTTPOnly(m *rpc.Mirror) bool {
 	return strings.HasPrefix(m.HttpURL, "http://")
 }
@@ -323,11 +431,40 @@ func ReasonString(m *rpc.Mirror) string {
 	return fmt.Sprintf("%s / %s", http, https)
 }
 
+// checkHTTPURL validates the HTTP URL of a mirror
+func checkHTTPURL(u string) error {
+	if u == "" {
+		return errors.New("You *must* pass at least an HTTP URL")
+	}
+
+	if utils.HasAnyPrefix(u, "http://", "https://") {
+		_, err := url.Parse(u)
+		if err != nil {
+			return errors.New("Can't parse HTTP URL")
+		}
+	} else if strings.Contains(u, "://") {
+		return errors.New("The HTTP URL has an invalid scheme")
+	} else {
+		// No scheme, yes we do accept it.
+		// Note that the documentation of net/url mentions that parsing
+		// such URL is "invalid but may not necessarily return an error",
+		// so let's add a scheme before we parse it.
+		_, err := url.Parse("http://" + u)
+		if err != nil {
+			return errors.New("Can't parse HTTP URL")
+		}
+	}
+	return nil
+}
+
 func (c *cli) CmdAdd(args ...string) error {
 	cmd := SubCmd("add", "[OPTIONS] IDENTIFIER", "Add a new mirror")
 	http := cmd.String("http", "", "HTTP base URL")
 	rsync := cmd.String("rsync", "", "RSYNC base URL (for scanning only)")
-	ftp := cmd.String("ftp", "", "FTP base URL (for scanning only)")
+	ftp := cmd.String("ftp", "", "FTP base URL, ftps:// for FTP over TLS (for scanning only)")
+	ftpTLS := cmd.Bool("ftp-tls", false, "Use TLS (explicit AUTH TLS) to scan the FTP URL")
+	ftpInsecure := cmd.Bool("ftp-insecure", false, "Don't verify the certificate of the FTPS server")
+	local := cmd.String("local", "", "file:// URL of the directory of a mirror on this host (for scanning only)")
 	sponsorName := cmd.String("sponsor-name", "", "Name of the sponsor")
 	sponsorURL := cmd.String("sponsor-url", "", "URL of the sponsor")
 	sponsorLogo := cmd.String("sponsor-logo", "", "URL of a logo to display for this mirror")
@@ -337,7 +474,20 @@ func (c *cli) CmdAdd(args ...string) error {
 	continentOnly := cmd.Bool("continent-only", false, "The mirror should only handle its continent")
 	countryOnly := cmd.Bool("country-only", false, "The mirror should only handle its country")
 	asOnly := cmd.Bool("as-only", false, "The mirror should only handle clients in the same AS number")
+	excludedCountry := cmd.String("excluded-country", "", "Country codes excluded from this mirror (space separated)")
 	score := cmd.Int("score", 0, "Weight to give to the mirror during selection")
+	bandwidth := cmd.Int("bandwidth", 0, "Bandwidth capacity of the mirror in Mbps")
+	tier := cmd.Int("tier", 0, "Tier of the mirror, higher tiers are only used when the lower ones can't serve the file")
+	maxConnections := cmd.Int("max-connections", 0, "Approximate maximum number of downloads in progress on the mirror, 0 for no limit")
+	region := cmd.String("region", "", "Region of the mirror (see Regions in the configuration)")
+	ipv4 := cmd.Bool("ipv4", false, "The mirror is reachable over IPv4")
+	ipv6 := cmd.Bool("ipv6", false, "The mirror is reachable over IPv6")
+	manualIP := cmd.Bool("manual-ip", false, "Don't detect the IP versions of the mirror during the scans")
+	scanSchedule := cmd.String("scan-schedule", "", "Time windows the mirror can be scanned in by the daemon (i.e. 22:00-06:00)")
+	scanProxy := cmd.String("scan-proxy", "", "http://, https:// or socks5:// URL of the proxy used for the HTTP and FTP scans")
+	clientCert := cmd.String("client-cert", "", "Client certificate (PEM) used to connect to the mirror over HTTPS")
+	clientKey := cmd.String("client-key", "", "Private key (PEM) of the client certificate")
+	labelList := cmd.String("labels", "", "Labels of the mirror (i.e. provider=acme,rack=b2)")
 	comment := cmd.String("comment", "", "Comment")
 
 	if err := cmd.Parse(args); err != nil {
@@ -353,30 +503,35 @@ func (c *cli) CmdAdd(args ...string) error {
 		os.Exit(-1)
 	}
 
-	if *http == "" {
-		fmt.Fprintf(os.Stderr, "You *must* pass at least an HTTP URL\n")
+	if err := checkHTTPURL(*http); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
 		os.Exit(-1)
 	}
 
-	if utils.HasAnyPrefix(*http, "http://", "https://") {
-		_, err := url.Parse(*http)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Can't parse HTTP URL\n")
-			os.Exit(-1)
-		}
-	} else if strings.Contains(*http, "://") {
-		fmt.Fprintf(os.Stderr, "The HTTP URL has an invalid scheme\n")
+	if err := checkLocationCodes(*excludedCountry); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid excluded country: %s\n", err)
+		os.Exit(-1)
+	}
+
+	if err := checkLocalURL(*local); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(-1)
+	}
+
+	if _, err := mirrors.ParseScanSchedule(*scanSchedule); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid scan schedule: %s\n", err)
+		os.Exit(-1)
+	}
+
+	if err := checkScanProxy(*scanProxy); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(-1)
+	}
+
+	labels, err := mirrors.ParseLabels(*labelList)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid labels: %s\n", err)
 		os.Exit(-1)
-	} else {
-		// No scheme, yes we do accept it.
-		// Note that the documentation of net/url mentions that parsing
-		// such URL is "invalid but may not necessarily return an error",
-		// so let's add a scheme before we parse it.
-		_, err := url.Parse("http://" + *http)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Can't parse HTTP URL\n")
-			os.Exit(-1)
-		}
 	}
 
 	mirror := &mirrors.Mirror{
@@ -384,6 +539,9 @@ func (c *cli) CmdAdd(args ...string) error {
 		HttpURL:        *http,
 		RsyncURL:       *rsync,
 		FtpURL:         *ftp,
+		FtpUseTLS:      *ftpTLS,
+		FtpInsecureSkipVerify: *ftpInsecure,
+		LocalURL:       *local,
 		SponsorName:    *sponsorName,
 		SponsorURL:     *sponsorURL,
 		SponsorLogoURL: *sponsorLogo,
@@ -393,8 +551,21 @@ func (c *cli) CmdAdd(args ...string) error {
 		ContinentOnly:  *continentOnly,
 		CountryOnly:    *countryOnly,
 		ASOnly:         *asOnly,
-		Score:          *score,
-		Comment:        *comment,
+		ExcludedCountryCodes: *excludedCountry,
+		Score:             *score,
+		BandwidthCapacity: *bandwidth,
+		Tier:              *tier,
+		MaxConnections:    *maxConnections,
+		Region:            *region,
+		HasIPv4:           *ipv4,
+		HasIPv6:           *ipv6,
+		ManualIPFamilies:  *manualIP,
+		ScanSchedule:      *scanSchedule,
+		ScanProxy:         *scanProxy,
+		ClientCertFile:    *clientCert,
+		ClientKeyFile:     *clientKey,
+		Labels:            labels,
+		Comment:           *comment,
 	}
 
 	client := c.GetRPC()
@@ -482,7 +653,11 @@ func (c *cli) CmdScan(args ...string) error {
 	all := cmd.Bool("all", false, "Scan all mirrors at once")
 	ftp := cmd.Bool("ftp", false, "Force a scan using FTP")
 	rsync := cmd.Bool("rsync", false, "Force a scan using rsync")
+	local := cmd.Bool("local", false, "Force a scan of the local directory")
 	timeout := cmd.Uint("timeout", 0, "Timeout in seconds")
+	only := cmd.String("only", "", "Only scan the files under the given path (relative to the repository root)")
+	dryRun := cmd.Bool("dry-run", false, "Report the changes without updating the index of the mirror")
+	forceRehash := cmd.Bool("force-rehash", false, "Hash all the files of the mirror again during the cross-check, ignoring TrustMtime")
 
 	if err := cmd.Parse(args); err != nil {
 		return nil
@@ -491,6 +666,12 @@ func (c *cli) CmdScan(args ...string) error {
 		cmd.Usage()
 		return nil
 	}
+	if *dryRun && *enable {
+		return errors.New("-dry-run and -enable are mutually exclusive")
+	}
+	if *dryRun && *forceRehash {
+		return errors.New("-dry-run and -force-rehash are mutually exclusive")
+	}
 
 	client := c.GetRPC()
 	ctx, cancel := context.WithCancel(context.Background())
@@ -518,8 +699,10 @@ func (c *cli) CmdScan(args ...string) error {
 
 	// Set the method of the scan (if not default)
 	var method rpc.ScanMirrorRequest_Method
-	if *ftp == false && *rsync == false {
+	if *ftp == false && *rsync == false && *local == false {
 		method = rpc.ScanMirrorRequest_ALL
+	} else if *local == true {
+		method = rpc.ScanMirrorRequest_LOCAL
 	} else if *rsync == true {
 		method = rpc.ScanMirrorRequest_RSYNC
 	} else if *ftp == true {
@@ -535,11 +718,16 @@ func (c *cli) CmdScan(args ...string) error {
 
 		fmt.Printf("Scanning %s... ", name)
 
+		stopProgress := c.showScanProgress(id, name)
 		reply, err := client.ScanMirror(ctx, &rpc.ScanMirrorRequest{
-			ID:         int32(id),
-			AutoEnable: *enable,
-			Protocol:   method,
+			ID:          int32(id),
+			AutoEnable:  *enable,
+			Protocol:    method,
+			Only:        *only,
+			DryRun:      *dryRun,
+			ForceRehash: *forceRehash,
 		})
+		stopProgress()
 		if err != nil {
 			s := status.Convert(err)
 			if s.Code() == codes.FailedPrecondition || len(list) == 1 {
@@ -547,8 +735,25 @@ func (c *cli) CmdScan(args ...string) error {
 			}
 			fmt.Println("scan error:", grpc.ErrorDesc(err))
 			continue
+		} else if *dryRun {
+			fmt.Printf("%d files found, %d known, %d added, %d changed and %d removed (dry run)\n",
+				reply.FilesIndexed, reply.KnownIndexed, reply.Added, reply.Changed, reply.Removed)
+			printSample("+", reply.SampleAdded, reply.Added)
+			printSample("~", reply.SampleChanged, reply.Changed)
+			printSample("-", reply.SampleRemoved, reply.Removed)
 		} else {
 			fmt.Printf("%d files indexed, %d known and %d removed\n", reply.FilesIndexed, reply.KnownIndexed, reply.Removed)
+			if reply.Suspect > 0 {
+				fmt.Printf("  ∟ %d missing files kept as suspect\n", reply.Suspect)
+			}
+			if reply.HashMismatches > 0 {
+				fmt.Printf("  ∟ %d files not matching the reference hashes\n", reply.HashMismatches)
+			}
+			if reply.Truncated {
+				fmt.Printf("  ∟ Truncated to the first %d files (MaxFilesPerMirror)\n", reply.MaxFiles)
+			} else if reply.MaxFiles > 0 {
+				fmt.Printf("  ∟ Cap of %d files (MaxFilesPerMirror)\n", reply.MaxFiles)
+			}
 			if reply.GetTZOffsetMs() != 0 {
 				fmt.Printf("  ∟ Timezone offset detected and corrected: %d milliseconds\n", reply.TZOffsetMs)
 			}
@@ -556,11 +761,75 @@ func (c *cli) CmdScan(args ...string) error {
 				fmt.Println("  ∟ Enabled")
 			}
 		}
+		if reply.OutsideSchedule {
+			fmt.Println("  ∟ Warning: scanned outside of the scan schedule of the mirror")
+		}
 	}
 
 	return nil
 }
 
+// showScanProgress prints a live progress line while the given mirror is
+// being scanned, until the returned function is called. Nothing is printed
+// if the standard output is not a terminal.
+func (c *cli) showScanProgress(id int, name string) func() {
+	if !term.IsTerminal(int(os.Stdout.Fd())) {
+		return func() {}
+	}
+
+	client := c.GetRPC()
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+		printed := false
+		for {
+			select {
+			case <-done:
+				if printed {
+					// Clear the progress line
+					fmt.Printf("\r\033[KScanning %s... ", name)
+				}
+				return
+			case <-ticker.C:
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), defaultRPCTimeout)
+			reply, err := client.ScanStatus(ctx, &rpc.MirrorIDRequest{
+				ID: int32(id),
+			})
+			cancel()
+			if err != nil || !reply.Running {
+				continue
+			}
+			elapsed := time.Duration(0)
+			if started, err := ptypes.Timestamp(reply.Started); err == nil {
+				elapsed = time.Since(started).Round(time.Second)
+			}
+			fmt.Printf("\r\033[KScanning %s... %s over %s, %d files enumerated (%s)", name, reply.Phase, reply.Protocol, reply.Files, elapsed)
+			printed = true
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// printSample prints the sample of paths returned by a dry run
+func printSample(prefix string, sample []string, total int64) {
+	for _, p := range sample {
+		fmt.Printf("  %s %s\n", prefix, p)
+	}
+	if more := total - int64(len(sample)); more > 0 {
+		fmt.Printf("  %s ... and %d more\n", prefix, more)
+	}
+}
+
 func (c *cli) CmdRefresh(args ...string) error {
 	cmd := SubCmd("refresh", "", "Scan the local repository")
 	rehash := cmd.Bool("rehash", false, "Force a rehash of the files")
@@ -637,11 +906,24 @@ func GetSingle(list []*rpc.MirrorID) (int, string, error) {
 }
 
 func (c *cli) CmdEdit(args ...string) error {
-	cmd := SubCmd("edit", "[IDENTIFIER]", "Edit a mirror")
+	cmd := SubCmd("edit", "[OPTIONS] IDENTIFIER...", "Edit a mirror\n\nWithout options the mirror configuration is opened in a text editor,\notherwise the given fields are set on all the mirrors listed.")
+	values := editFlags(cmd)
 
 	if err := cmd.Parse(args); err != nil {
 		return nil
 	}
+
+	set := make(map[string]string)
+	cmd.Visit(func(f *flag.Flag) {
+		if v, ok := values[f.Name]; ok {
+			set[f.Name] = v.value
+		}
+	})
+	if len(set) > 0 && cmd.NArg() > 0 {
+		c.editWithFlags(cmd.Args(), set)
+		return nil
+	}
+
 	if cmd.NArg() != 1 {
 		cmd.Usage()
 		return nil
@@ -748,6 +1030,9 @@ reopen:
 
 	// Fill the struct from the yaml
 	err = yaml.Unmarshal([]byte(yamlstr), &mirror)
+	if err == nil {
+		err = mirror.Labels.Validate()
+	}
 	if err != nil {
 		switch reopen(err) {
 		case true:
@@ -894,15 +1179,47 @@ func (c *cli) CmdShow(args ...string) error {
 	}
 
 	fmt.Printf("%s\nComment:\n%s\n", out, mirror.Comment)
+	if mirror.IsForced() {
+		state := "down"
+		if mirror.ForcedUp {
+			state = "up"
+		}
+		fmt.Printf("\nState forced %s until %s\n", state, mirror.ForcedUntil.Local().Format(time.RFC1123))
+	}
+	if mirror.InCooldown() {
+		fmt.Printf("\nFlapping, excluded from the selection until %s\n", mirror.CooldownUntil.Local().Format(time.RFC1123))
+	}
+	if len(rpcm.HashMismatches) > 0 {
+		fmt.Printf("\nFiles not matching the reference hashes:\n")
+		for _, f := range rpcm.HashMismatches {
+			fmt.Printf("  %s\n", f)
+		}
+	}
+	if t := mirror.LastSuccessfulSync.Time; !t.IsZero() && t.Unix() != 0 {
+		fmt.Printf("\nLast successful scan: %s (%s ago)\n", t.Local().Format(time.RFC1123), time.Since(t).Round(time.Minute))
+	} else {
+		fmt.Printf("\nNever scanned successfully\n")
+	}
+	if mirror.Throughput > 0 {
+		fmt.Printf("\nMeasured throughput: %s/s (updated %s)\n", utils.ReadableSize(int64(mirror.Throughput)), mirror.ThroughputUpdated.Local().Format(time.RFC1123))
+	}
+	if mirror.Latency > 0 {
+		fmt.Printf("\nMeasured latency: %dms (updated %s)\n", int64(math.Round(mirror.Latency)), mirror.LatencyUpdated.Local().Format(time.RFC1123))
+	}
+	if reply, err := client.GetMaintenance(ctx, &empty.Empty{}); err == nil && reply.Enabled {
+		fmt.Println()
+		printMaintenance(reply)
+	}
 	return nil
 }
 
 func (c *cli) CmdExport(args ...string) error {
-	cmd := SubCmd("export", "[format]", "Export the mirror database.\n\nAvailable formats: mirmon")
-	rsync := cmd.Bool("rsync", true, "Export rsync URLs")
-	http := cmd.Bool("http", true, "Export http URLs")
-	ftp := cmd.Bool("ftp", true, "Export ftp URLs")
+	cmd := SubCmd("export", "[format]", "Export the mirror database.\n\nAvailable formats: mirmon, yaml")
+	rsync := cmd.Bool("rsync", true, "Export rsync URLs (mirmon only)")
+	http := cmd.Bool("http", true, "Export http URLs (mirmon only)")
+	ftp := cmd.Bool("ftp", true, "Export ftp URLs (mirmon only)")
 	disabled := cmd.Bool("disabled", true, "Export disabled mirrors")
+	output := cmd.String("o", "", "Write to the given file instead of stdout")
 
 	if err := cmd.Parse(args); err != nil {
 		return nil
@@ -912,7 +1229,8 @@ func (c *cli) CmdExport(args ...string) error {
 		return nil
 	}
 
-	if cmd.Arg(0) != "mirmon" {
+	format := cmd.Arg(0)
+	if format != "mirmon" && format != "yaml" {
 		fmt.Fprintf(os.Stderr, "Unsupported format\n")
 		cmd.Usage()
 		return nil
@@ -926,15 +1244,35 @@ func (c *cli) CmdExport(args ...string) error {
 		log.Fatal("export error:", err)
 	}
 
-	w := new(tabwriter.Writer)
-	w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Fatal("export error:", err)
+		}
+		defer f.Close()
+		out = f
+	}
 
+	mlist := make([]*rpc.Mirror, 0, len(list.Mirrors))
 	for _, m := range list.Mirrors {
 		if *disabled == false {
 			if m.Enabled == false {
 				continue
 			}
 		}
+		mlist = append(mlist, m)
+	}
+
+	if format == "yaml" {
+		exportYAML(out, mlist)
+		return nil
+	}
+
+	w := new(tabwriter.Writer)
+	w.Init(out, 0, 8, 1, '\t', 0)
+
+	for _, m := range mlist {
 		ccodes := strings.Fields(m.CountryCodes)
 
 		urls := make([]string, 0, 3)
@@ -962,106 +1300,496 @@ func (c *cli) CmdExport(args ...string) error {
 	return nil
 }
 
-func (c *cli) CmdEnable(args ...string) error {
-	cmd := SubCmd("enable", "[IDENTIFIER]", "Enable a mirror")
+// mirrorSpec is the definition of a mirror as found in the files
+// produced by the export command and read by the import command
+type mirrorSpec struct {
+	mirrors.Mirror `yaml:",inline"`
+	Comment        string `yaml:"Comment,omitempty"`
+}
+
+func exportYAML(w io.Writer, mlist []*rpc.Mirror) {
+	specs := make([]mirrorSpec, 0, len(mlist))
+	for _, m := range mlist {
+		mirror, err := rpc.MirrorFromRPC(m)
+		if err != nil {
+			log.Fatal("export error:", err)
+		}
+		specs = append(specs, mirrorSpec{
+			Mirror:  *mirror,
+			Comment: mirror.Comment,
+		})
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(specs); err != nil {
+		log.Fatal("export error:", err)
+	}
+	if err := enc.Close(); err != nil {
+		log.Fatal("export error:", err)
+	}
+}
+
+func (c *cli) CmdGeoipupdate(args ...string) error {
+	cmd := SubCmd("geoip-update", "", "Download fresh GeoIP databases")
+	configFile := cmd.String("config", "", "Path to the config file")
+	reload := cmd.Bool("reload", false, "Reload the databases of the running daemon")
 
 	if err := cmd.Parse(args); err != nil {
 		return nil
 	}
-	if cmd.NArg() != 1 {
+	if cmd.NArg() != 0 {
 		cmd.Usage()
 		return nil
 	}
 
-	c.changeStatus(cmd.Arg(0), true)
+	if *configFile != "" {
+		core.ConfigFile = *configFile
+	}
+	LoadConfig()
+
+	paths, err := network.UpdateGeoIPDatabases()
+	for _, path := range paths {
+		fmt.Printf("Updated %s\n", path)
+	}
+	if err != nil {
+		log.Fatal("geoip-update error:", err)
+	}
+
+	if *reload {
+		client := c.GetRPC()
+		ctx, cancel := context.WithTimeout(context.Background(), defaultRPCTimeout)
+		defer cancel()
+		_, err := client.Reload(ctx, &empty.Empty{})
+		if err != nil {
+			log.Fatal("reload error:", grpc.ErrorDesc(err))
+		}
+		fmt.Println("Configuration reloaded")
+	}
 	return nil
 }
 
-func (c *cli) CmdDisable(args ...string) error {
-	cmd := SubCmd("disable", "[IDENTIFIER]", "Disable a mirror")
+func (c *cli) CmdCheckconfig(args ...string) error {
+	cmd := SubCmd("check-config", "", "Validate the configuration file as the daemon does at startup")
+	configFile := cmd.String("config", "", "Path to the config file (default: /etc/mirrorbits.conf)")
+	cmd.StringVar(configFile, "f", "", "Alias of -config")
 
 	if err := cmd.Parse(args); err != nil {
 		return nil
 	}
-	if cmd.NArg() != 1 {
+	if cmd.NArg() != 0 {
 		cmd.Usage()
 		return nil
 	}
 
-	c.changeStatus(cmd.Arg(0), false)
-	return nil
-}
-
-func (c *cli) changeStatus(pattern string, enabled bool) {
-	id, name := c.matchMirror(pattern)
-
-	client := c.GetRPC()
-	ctx, cancel := context.WithTimeout(context.Background(), defaultRPCTimeout)
-	defer cancel()
-	_, err := client.ChangeStatus(ctx, &rpc.ChangeStatusRequest{
-		ID:      int32(id),
-		Enabled: enabled,
-	})
+	if *configFile != "" {
+		core.ConfigFile = *configFile
+	}
+	config, err := ParseConfigFile()
 	if err != nil {
-		if enabled {
-			log.Fatalf("Couldn't enable mirror '%s': %s\n", name, err)
-		} else {
-			log.Fatalf("Couldn't disable mirror '%s': %s\n", name, err)
-		}
+		return fmt.Errorf("%s: %w", core.ConfigFile, err)
 	}
+	SetConfiguration(&config)
 
-	if enabled {
-		fmt.Printf("Mirror '%s' enabled successfully\n", name)
-	} else {
-		fmt.Printf("Mirror '%s' disabled successfully\n", name)
+	// Load the GeoIP databases as the daemon does, the problems it only
+	// reports (i.e. running in degraded mode) are printed as warnings
+	if err = network.NewGeoIP().LoadGeoIP(); err != nil {
+		var gerr network.GeoIPError
+		if !errors.As(err, &gerr) {
+			return fmt.Errorf("%s: %w", core.ConfigFile, err)
+		}
+		fatal := gerr.IsStrict() || (gerr.IsFatal() && len(config.Fallbacks) == 0)
+		for _, e := range gerr.Errors {
+			if fatal {
+				fmt.Fprintf(os.Stderr, "Error: %s\n", e)
+			} else {
+				fmt.Fprintf(os.Stderr, "Warning: %s\n", e)
+			}
+		}
+		if fatal {
+			return fmt.Errorf("%s: the GeoIP databases can't be loaded", core.ConfigFile)
+		}
 	}
-	return
+
+	fmt.Printf("%s: configuration OK\n", core.ConfigFile)
+	return nil
 }
 
-func (c *cli) CmdStats(args ...string) error {
-	cmd := SubCmd("stats", "[OPTIONS] [mirror|file] [IDENTIFIER|PATTERN]", "Show download stats for a particular mirror or a file pattern")
-	dateStart := cmd.String("start-date", "", "Starting date (format YYYY-MM-DD)")
-	dateEnd := cmd.String("end-date", "", "Ending date (format YYYY-MM-DD)")
-	human := cmd.Bool("h", true, "Human readable version")
+func (c *cli) CmdImport(args ...string) error {
+	cmd := SubCmd("import", "[OPTIONS] FILE", "Create mirrors from a yaml file produced by 'export yaml'")
+	update := cmd.Bool("update", false, "Update the mirrors that already exist")
 
 	if err := cmd.Parse(args); err != nil {
 		return nil
 	}
-	if cmd.NArg() != 2 || (cmd.Arg(0) != "mirror" && cmd.Arg(0) != "file") {
+	if cmd.NArg() != 1 {
 		cmd.Usage()
 		return nil
 	}
 
-	start, err := time.Parse("2006-1-2", *dateStart)
+	content, err := os.ReadFile(cmd.Arg(0))
 	if err != nil {
-		start = time.Now()
+		log.Fatal("import error:", err)
 	}
-	startproto, _ := ptypes.TimestampProto(start)
 
-	end, err := time.Parse("2006-1-2", *dateEnd)
-	if err != nil {
-		end = time.Now()
+	var specs []mirrorSpec
+	if err := yaml.Unmarshal(content, &specs); err != nil {
+		log.Fatal("import error:", err)
+	}
+
+	// Validate the whole file before touching the database
+	names := make(map[string]bool, len(specs))
+	valid := true
+	for i, s := range specs {
+		ferr := func(format string, args ...any) {
+			fmt.Fprintf(os.Stderr, "Entry #%d (%s): %s\n", i+1, s.Name, fmt.Sprintf(format, args...))
+			valid = false
+		}
+		if s.Name == "" {
+			ferr("missing name")
+		} else if strings.Contains(s.Name, " ") {
+			ferr("the identifier cannot contain a space")
+		} else if names[s.Name] {
+			ferr("duplicate name")
+		}
+		names[s.Name] = true
+		if err := checkHTTPURL(s.HttpURL); err != nil {
+			ferr("%s", err)
+		}
+		if s.RsyncURL != "" && !strings.HasPrefix(s.RsyncURL, "rsync://") {
+			ferr("the rsync URL must start with rsync://")
+		}
+		if s.FtpURL != "" && !utils.HasAnyPrefix(s.FtpURL, "ftp://", "ftps://") {
+			ferr("the FTP URL must start with ftp:// or ftps://")
+		}
+		if err := checkLocalURL(s.LocalURL); err != nil {
+			ferr("%s", err)
+		}
+		if err := checkScanProxy(s.ScanProxy); err != nil {
+			ferr("%s", err)
+		}
+		if err := s.Labels.Validate(); err != nil {
+			ferr("%s", err)
+		}
+	}
+	if !valid {
+		fmt.Fprintf(os.Stderr, "Aborted, nothing was imported\n")
+		os.Exit(1)
 	}
-	endproto, _ := ptypes.TimestampProto(end)
 
 	client := c.GetRPC()
 	ctx, cancel := context.WithTimeout(context.Background(), defaultRPCTimeout)
 	defer cancel()
+	list, err := client.List(ctx, &empty.Empty{})
+	if err != nil {
+		log.Fatal("import error:", err)
+	}
+	existing := make(map[string]int32, len(list.Mirrors))
+	for _, m := range list.Mirrors {
+		existing[m.Name] = m.ID
+	}
 
-	if cmd.Arg(0) == "file" {
-		// File stats
-
-		reply, err := client.StatsFile(ctx, &rpc.StatsFileRequest{
-			Pattern:   cmd.Arg(1),
-			DateStart: startproto,
-			DateEnd:   endproto,
-		})
+	failed := 0
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprint(w, "IDENTIFIER\tRESULT\n")
+	for _, s := range specs {
+		result, err := c.importMirror(s, existing, *update)
 		if err != nil {
-			log.Fatal("file stats error:", err)
+			failed++
+			result = "failed: " + err.Error()
 		}
+		fmt.Fprintf(w, "%s\t%s\n", s.Name, result)
+	}
+	w.Flush()
 
-		// Format the results
-		w := new(tabwriter.Writer)
+	fmt.Printf("\n%d imported, %d failed\n", len(specs)-failed, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+	return nil
+}
+
+// importMirror creates or updates a single mirror from its specification
+func (c *cli) importMirror(s mirrorSpec, existing map[string]int32, update bool) (string, error) {
+	mirror := s.Mirror
+	mirror.Comment = s.Comment
+
+	client := c.GetRPC()
+
+	id, exists := existing[mirror.Name]
+	if exists && !update {
+		return "", errors.New("already exists")
+	}
+
+	if !exists {
+		mirror.ID = 0
+		m, err := rpc.MirrorToRPC(&mirror)
+		if err != nil {
+			return "", err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), defaultRPCTimeout)
+		defer cancel()
+		if _, err = client.AddMirror(ctx, m); err != nil {
+			return "", err
+		}
+		if !hasLocation(mirror) {
+			return "created", nil
+		}
+
+		// The location was guessed when the mirror was added, restore
+		// the one found in the file.
+		reply, err := client.MatchMirror(ctx, &rpc.MatchRequest{
+			Pattern: mirror.Name,
+		})
+		if err != nil {
+			return "", err
+		}
+		for _, m := range reply.Mirrors {
+			if m.Name == mirror.Name {
+				id = m.ID
+			}
+		}
+		if id == 0 {
+			return "", errors.New("mirror not found after creation")
+		}
+	}
+
+	mirror.ID = int(id)
+	m, err := rpc.MirrorToRPC(&mirror)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), defaultRPCTimeout)
+	defer cancel()
+	if _, err = client.UpdateMirror(ctx, m); err != nil {
+		return "", err
+	}
+	if !exists {
+		return "created", nil
+	}
+	return "updated", nil
+}
+
+// hasLocation returns true if the mirror has any location information set
+func hasLocation(m mirrors.Mirror) bool {
+	return m.Latitude != 0 || m.Longitude != 0 || m.CountryCodes != "" ||
+		m.ContinentCode != "" || m.Asnum != 0
+}
+
+func (c *cli) CmdEnable(args ...string) error {
+	cmd := SubCmd("enable", "[-tag TAG | IDENTIFIER]", "Enable a mirror, or all the mirrors of a region at once")
+	tag := cmd.String("tag", "", "Enable all the mirrors of the given region")
+
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if *tag != "" && cmd.NArg() == 0 {
+		c.changeStatusByTag(*tag, true)
+		return nil
+	}
+	if *tag != "" || cmd.NArg() != 1 {
+		cmd.Usage()
+		return nil
+	}
+
+	c.changeStatus(cmd.Arg(0), true)
+	return nil
+}
+
+func (c *cli) CmdDisable(args ...string) error {
+	cmd := SubCmd("disable", "[-tag TAG | IDENTIFIER]", "Disable a mirror, or all the mirrors of a region at once")
+	tag := cmd.String("tag", "", "Disable all the mirrors of the given region")
+
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if *tag != "" && cmd.NArg() == 0 {
+		c.changeStatusByTag(*tag, false)
+		return nil
+	}
+	if *tag != "" || cmd.NArg() != 1 {
+		cmd.Usage()
+		return nil
+	}
+
+	c.changeStatus(cmd.Arg(0), false)
+	return nil
+}
+
+func (c *cli) CmdForcestate(args ...string) error {
+	cmd := SubCmd("force-state", "[OPTIONS] IDENTIFIER up|down|auto", "Force a mirror up or down, overriding the health checks.\n\nUse 'auto' to hand the mirror back to the health checks.")
+	ttl := cmd.Duration("ttl", time.Hour, "Duration of the override")
+
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	state := cmd.Arg(1)
+	if cmd.NArg() != 2 || (state != "up" && state != "down" && state != "auto") {
+		cmd.Usage()
+		return nil
+	}
+	if state != "auto" && *ttl < time.Second {
+		fmt.Fprintf(os.Stderr, "The duration must be at least one second\n")
+		os.Exit(1)
+	}
+
+	id, name := c.matchMirror(cmd.Arg(0))
+
+	request := &rpc.ForceMirrorStateRequest{
+		ID: int32(id),
+		Up: state == "up",
+	}
+	if state != "auto" {
+		request.TTL = int64(*ttl / time.Second)
+	}
+
+	client := c.GetRPC()
+	ctx, cancel := context.WithTimeout(context.Background(), defaultRPCTimeout)
+	defer cancel()
+	_, err := client.ForceMirrorState(ctx, request)
+	if err != nil {
+		log.Fatal("force-state error:", err)
+	}
+
+	if state == "auto" {
+		fmt.Printf("Mirror '%s' is now handled by the health checks\n", name)
+	} else {
+		fmt.Printf("Mirror '%s' forced %s for %s\n", name, state, *ttl)
+	}
+	return nil
+}
+
+func (c *cli) changeStatus(pattern string, enabled bool) {
+	id, name := c.matchMirror(pattern)
+
+	client := c.GetRPC()
+	ctx, cancel := context.WithTimeout(context.Background(), defaultRPCTimeout)
+	defer cancel()
+	_, err := client.ChangeStatus(ctx, &rpc.ChangeStatusRequest{
+		ID:      int32(id),
+		Enabled: enabled,
+	})
+	if err != nil {
+		if enabled {
+			log.Fatalf("Couldn't enable mirror '%s': %s\n", name, err)
+		} else {
+			log.Fatalf("Couldn't disable mirror '%s': %s\n", name, err)
+		}
+	}
+
+	if enabled {
+		fmt.Printf("Mirror '%s' enabled successfully\n", name)
+	} else {
+		fmt.Printf("Mirror '%s' disabled successfully\n", name)
+	}
+	return
+}
+
+func (c *cli) changeStatusByTag(tag string, enabled bool) {
+	client := c.GetRPC()
+	ctx, cancel := context.WithTimeout(context.Background(), defaultRPCTimeout)
+	defer cancel()
+	reply, err := client.SetMirrorsEnabledByTag(ctx, &rpc.SetMirrorsEnabledByTagRequest{
+		Tag:     tag,
+		Enabled: enabled,
+	})
+	if err != nil {
+		if enabled {
+			log.Fatalf("Couldn't enable the mirrors tagged '%s': %s\n", tag, err)
+		} else {
+			log.Fatalf("Couldn't disable the mirrors tagged '%s': %s\n", tag, err)
+		}
+	}
+
+	if len(reply.Names) == 0 {
+		fmt.Printf("No mirror tagged '%s'\n", tag)
+		return
+	}
+	for _, name := range reply.Names {
+		if enabled {
+			fmt.Printf("Mirror '%s' enabled successfully\n", name)
+		} else {
+			fmt.Printf("Mirror '%s' disabled successfully\n", name)
+		}
+	}
+}
+
+func (c *cli) CmdStats(args ...string) error {
+	cmd := SubCmd("stats", "[OPTIONS] [mirror|file|files|rates|export] [IDENTIFIER|PATTERN]", "Show download stats for a particular mirror, a file pattern, the top files or the current redirect rates, or export the stats of the mirrors")
+	dateStart := cmd.String("start-date", "", "Starting date (format YYYY-MM-DD)")
+	dateEnd := cmd.String("end-date", "", "Ending date (format YYYY-MM-DD)")
+	human := cmd.Bool("h", true, "Human readable version")
+	reset := cmd.Bool("reset", false, "Reset the download stats of the mirror")
+	all := cmd.Bool("all", false, "Reset the download stats of all mirrors (with -reset)")
+	force := cmd.Bool("f", false, "Never prompt for confirmation (with -reset)")
+	period := cmd.String("period", "daily", "Period of the top files: daily, monthly, yearly or all (with files), or of the rows: daily, monthly or yearly (with export)")
+	date := cmd.String("date", "", "Day, month or year of the top files (format YYYY-MM-DD, default today)")
+	limit := cmd.Int("limit", 10, "Number of top files to show (with files)")
+	format := cmd.String("format", "csv", "Output format: csv (with export)")
+
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if *reset {
+		if *all && cmd.NArg() != 0 || !*all && (cmd.NArg() != 2 || cmd.Arg(0) != "mirror") {
+			cmd.Usage()
+			return nil
+		}
+		return c.resetStats(cmd.Arg(1), *all, *force)
+	}
+	if cmd.NArg() == 1 && cmd.Arg(0) == "files" {
+		return c.topFiles(*period, *date, *limit)
+	}
+	if cmd.NArg() == 1 && cmd.Arg(0) == "rates" {
+		return c.requestRates()
+	}
+	if cmd.NArg() > 0 && cmd.Arg(0) == "export" {
+		// The options may also follow the subcommand
+		if err := cmd.Parse(cmd.Args()[1:]); err != nil {
+			return nil
+		}
+		if cmd.NArg() != 0 {
+			cmd.Usage()
+			return nil
+		}
+		return c.exportStats(*period, *format, *dateStart, *dateEnd)
+	}
+	if cmd.NArg() != 2 || (cmd.Arg(0) != "mirror" && cmd.Arg(0) != "file") {
+		cmd.Usage()
+		return nil
+	}
+
+	start, err := time.Parse("2006-1-2", *dateStart)
+	if err != nil {
+		start = time.Now()
+	}
+	startproto, _ := ptypes.TimestampProto(start)
+
+	end, err := time.Parse("2006-1-2", *dateEnd)
+	if err != nil {
+		end = time.Now()
+	}
+	endproto, _ := ptypes.TimestampProto(end)
+
+	client := c.GetRPC()
+	ctx, cancel := context.WithTimeout(context.Background(), defaultRPCTimeout)
+	defer cancel()
+
+	if cmd.Arg(0) == "file" {
+		// File stats
+
+		reply, err := client.StatsFile(ctx, &rpc.StatsFileRequest{
+			Pattern:   cmd.Arg(1),
+			DateStart: startproto,
+			DateEnd:   endproto,
+		})
+		if err != nil {
+			log.Fatal("file stats error:", err)
+		}
+
+		// Format the results
+		w := new(tabwriter.Writer)
 		w.Init(os.Stdout, 0, 8, 1, '\t', 0)
 
 		// Sort keys and count requests
@@ -1121,6 +1849,151 @@ func (c *cli) CmdStats(args ...string) error {
 	return nil
 }
 
+func (c *cli) topFiles(period, date string, limit int) error {
+	day, err := time.Parse("2006-1-2", date)
+	if err != nil {
+		day = time.Now()
+	}
+	dateproto, _ := ptypes.TimestampProto(day)
+
+	client := c.GetRPC()
+	ctx, cancel := context.WithTimeout(context.Background(), defaultRPCTimeout)
+	defer cancel()
+	reply, err := client.TopFiles(ctx, &rpc.TopFilesRequest{
+		Period: period,
+		Date:   dateproto,
+		Limit:  int32(limit),
+	})
+	if err != nil {
+		log.Fatal("top files error:", grpc.ErrorDesc(err))
+	}
+
+	if len(reply.Files) == 0 {
+		fmt.Println("No download recorded for this period")
+		return nil
+	}
+
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+	for i, f := range reply.Files {
+		fmt.Fprintf(w, "%d.\t%s\t%d\n", i+1, f.Path, f.Downloads)
+	}
+	w.Flush()
+	return nil
+}
+
+// exportStats writes the downloads of each mirror, for each day, month or
+// year of the date range, the numbers adding up to the ones of 'stats mirror'
+// for the same range
+func (c *cli) exportStats(period, format, dateStart, dateEnd string) error {
+	if format != "csv" {
+		return fmt.Errorf("unsupported format %s", format)
+	}
+
+	start, err := time.Parse("2006-1-2", dateStart)
+	if err != nil {
+		start = time.Now()
+	}
+	startproto, _ := ptypes.TimestampProto(start)
+
+	end, err := time.Parse("2006-1-2", dateEnd)
+	if err != nil {
+		end = time.Now()
+	}
+	endproto, _ := ptypes.TimestampProto(end)
+
+	client := c.GetRPC()
+	ctx, cancel := context.WithTimeout(context.Background(), defaultRPCTimeout)
+	defer cancel()
+	reply, err := client.ExportStats(ctx, &rpc.ExportStatsRequest{
+		Period:    period,
+		DateStart: startproto,
+		DateEnd:   endproto,
+	})
+	if err != nil {
+		return errors.New("stats export error: " + grpc.ErrorDesc(err))
+	}
+
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"mirror", "period", "downloads", "bytes"})
+	for _, s := range reply.Stats {
+		w.Write([]string{s.Name, s.Period, strconv.FormatInt(s.Requests, 10), strconv.FormatInt(s.Bytes, 10)})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func (c *cli) requestRates() error {
+	client := c.GetRPC()
+	ctx, cancel := context.WithTimeout(context.Background(), defaultRPCTimeout)
+	defer cancel()
+	reply, err := client.RequestRates(ctx, &empty.Empty{})
+	if err != nil {
+		log.Fatal("request rates error:", grpc.ErrorDesc(err))
+	}
+
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprint(w, "Redirects/s")
+	for _, s := range reply.WindowSeconds {
+		fmt.Fprintf(w, "\t%s", time.Duration(s)*time.Second)
+	}
+	fmt.Fprintln(w)
+	printRates := func(name string, rates []float64) {
+		fmt.Fprint(w, name)
+		for _, r := range rates {
+			fmt.Fprintf(w, "\t%.2f", r)
+		}
+		fmt.Fprintln(w)
+	}
+	printRates("Total", reply.Total)
+	for _, m := range reply.Mirrors {
+		printRates(m.Name, m.Rates)
+	}
+	w.Flush()
+	return nil
+}
+
+func (c *cli) resetStats(identifier string, all, force bool) error {
+	var id int
+	name := "all mirrors"
+	if !all {
+		id, name = c.matchMirror(identifier)
+	}
+
+	if force == false {
+		fmt.Printf("Resetting the download stats of %s, are you sure? [y/N]", name)
+		reader := bufio.NewReader(os.Stdin)
+		s, _ := reader.ReadString('\n')
+		switch s[0] {
+		case 'y', 'Y':
+			break
+		default:
+			return nil
+		}
+	}
+
+	operator := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		operator = u.Username
+	}
+
+	client := c.GetRPC()
+	ctx, cancel := context.WithTimeout(context.Background(), defaultRPCTimeout)
+	defer cancel()
+	reply, err := client.ResetStats(ctx, &rpc.ResetStatsRequest{
+		ID:       int32(id),
+		All:      all,
+		Operator: operator,
+	})
+	if err != nil {
+		log.Fatal("stats reset error:", grpc.ErrorDesc(err))
+	}
+
+	fmt.Printf("Download stats of %s reset (%d counter%s cleared)\n", name, reply.Counters, utils.Plural(int(reply.Counters)))
+	return nil
+}
+
 func (c *cli) CmdLogs(args ...string) error {
 	cmd := SubCmd("logs", "[IDENTIFIER]", "Print logs of a mirror")
 	maxResults := cmd.Uint("l", 500, "Maximum number of logs to return")
@@ -1160,6 +2033,153 @@ func (c *cli) CmdLogs(args ...string) error {
 	return nil
 }
 
+func (c *cli) CmdLocate(args ...string) error {
+	cmd := SubCmd("locate", "PATH", "Print the mirrors carrying a file")
+	human := cmd.Bool("h", true, "Human readable version")
+
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 1 {
+		cmd.Usage()
+		return nil
+	}
+
+	client := c.GetRPC()
+	ctx, cancel := context.WithTimeout(context.Background(), defaultRPCTimeout)
+	defer cancel()
+	reply, err := client.FileMirrors(ctx, &rpc.FileMirrorsRequest{
+		Path: cmd.Arg(0),
+	})
+	if err != nil {
+		log.Fatal("locate error:", grpc.ErrorDesc(err))
+	}
+
+	if len(reply.Mirrors) == 0 {
+		fmt.Printf("No mirror carries %s\n", cmd.Arg(0))
+		return nil
+	}
+
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprint(w, "Identifier \tSize \tModification time\n")
+	for _, m := range reply.Mirrors {
+		size := fmt.Sprint(m.Size)
+		if *human {
+			size = utils.ReadableSize(m.Size)
+		}
+		modTime := formatRFC3339(m.ModTime)
+		if modTime == "" {
+			modTime = "unknown"
+		}
+		fmt.Fprintf(w, "%s \t%s \t%s\n", m.Name, size, modTime)
+	}
+	w.Flush()
+	return nil
+}
+
+func (c *cli) CmdDiff(args ...string) error {
+	cmd := SubCmd("diff", "[IDENTIFIER_A] [IDENTIFIER_B]", "Compare the file indexes of two mirrors")
+	full := cmd.Bool("full", false, fmt.Sprintf("List up to %d differing files of each kind", mirrors.DiffFullMaxSize))
+
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 2 {
+		cmd.Usage()
+		return nil
+	}
+
+	idA, nameA := c.matchMirror(cmd.Arg(0))
+	idB, nameB := c.matchMirror(cmd.Arg(1))
+
+	client := c.GetRPC()
+	ctx, cancel := context.WithTimeout(context.Background(), defaultRPCTimeout)
+	defer cancel()
+	reply, err := client.DiffMirrors(ctx, &rpc.DiffMirrorsRequest{
+		IDA:  int32(idA),
+		IDB:  int32(idB),
+		Full: *full,
+	})
+	if err != nil {
+		log.Fatal("diff error:", grpc.ErrorDesc(err))
+	}
+
+	fmt.Printf("%d files on both, %d differing in size or modification time\n", reply.Common, reply.Changed)
+	printSample("~", reply.SampleChanged, reply.Changed)
+	fmt.Printf("%d files only on %s\n", reply.OnlyA, nameA)
+	printSample("<", reply.SampleOnlyA, reply.OnlyA)
+	fmt.Printf("%d files only on %s\n", reply.OnlyB, nameB)
+	printSample(">", reply.SampleOnlyB, reply.OnlyB)
+	return nil
+}
+
+func (c *cli) CmdSimulate(args ...string) error {
+	cmd := SubCmd("simulate", "", "Simulate the mirror selection for a client")
+	ip := cmd.String("ip", "", "IP address of the client")
+	file := cmd.String("file", "", "Path of the requested file")
+	excluded := cmd.Bool("excluded", false, "Print the excluded mirrors")
+
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 0 || *ip == "" || *file == "" {
+		cmd.Usage()
+		return nil
+	}
+
+	client := c.GetRPC()
+	ctx, cancel := context.WithTimeout(context.Background(), defaultRPCTimeout)
+	defer cancel()
+	reply, err := client.Simulate(ctx, &rpc.SimulateRequest{
+		IP:   *ip,
+		Path: *file,
+	})
+	if err != nil {
+		log.Fatal("simulate error:", grpc.ErrorDesc(err))
+	}
+
+	fmt.Println("Client location:")
+	if reply.Located {
+		fmt.Printf("Latitude:  %.4f\n", reply.Latitude)
+		fmt.Printf("Longitude: %.4f\n", reply.Longitude)
+		fmt.Printf("Continent: %s\n", reply.Continent)
+		fmt.Printf("Country:   %s (%s)\n", reply.Country, reply.CountryCode)
+		fmt.Printf("ASN:       %s\n", reply.ASN)
+	} else {
+		fmt.Println("Unknown")
+	}
+	fmt.Println("")
+
+	if len(reply.Mirrors) == 0 {
+		fmt.Println("No mirror can serve the file")
+	} else {
+		if reply.Fallback {
+			fmt.Println("No mirror can serve the file, using the fallbacks")
+		}
+		w := new(tabwriter.Writer)
+		w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+		fmt.Fprint(w, "# \tIdentifier \tDistance \tScore \tURL\n")
+		for i, m := range reply.Mirrors {
+			fmt.Fprintf(w, "%d \t%s \t%.0f km \t%d \t%s\n", i+1, m.Name, m.Distance, m.Score, m.URL)
+		}
+		w.Flush()
+		fmt.Printf("\nChosen mirror: %s\n", reply.Mirrors[0].Name)
+	}
+
+	if *excluded && len(reply.Excluded) > 0 {
+		fmt.Println("\nExcluded mirrors:")
+		w := new(tabwriter.Writer)
+		w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+		fmt.Fprint(w, "Identifier \tReason\n")
+		for _, m := range reply.Excluded {
+			fmt.Fprintf(w, "%s \t%s\n", m.Name, m.ExcludeReason)
+		}
+		w.Flush()
+	}
+	return nil
+}
+
 func (c *cli) CmdReload(args ...string) error {
 	cmd := SubCmd("reload", "", "Reload configuration")
 
@@ -1176,12 +2196,70 @@ func (c *cli) CmdReload(args ...string) error {
 	defer cancel()
 	_, err := client.Reload(ctx, &empty.Empty{})
 	if err != nil {
-		log.Fatal("reload error:", err)
+		log.Fatal("reload error:", grpc.ErrorDesc(err))
 	}
 
+	fmt.Println("Configuration reloaded")
 	return nil
 }
 
+func (c *cli) CmdMaintenance(args ...string) error {
+	cmd := SubCmd("maintenance", "[OPTIONS] [on|off]", "Toggle the maintenance mode, or print its status without argument.\nThe mode applies until the next reload of the configuration.")
+	message := cmd.String("message", "", "Message sent to the clients (default: MaintenanceMessage)")
+
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() > 1 {
+		cmd.Usage()
+		return nil
+	}
+
+	client := c.GetRPC()
+	ctx, cancel := context.WithTimeout(context.Background(), defaultRPCTimeout)
+	defer cancel()
+
+	if cmd.NArg() == 1 {
+		var enabled bool
+		switch cmd.Arg(0) {
+		case "on":
+			enabled = true
+		case "off":
+			enabled = false
+		default:
+			cmd.Usage()
+			return nil
+		}
+		_, err := client.SetMaintenance(ctx, &rpc.SetMaintenanceRequest{
+			Enabled: enabled,
+			Message: *message,
+		})
+		if err != nil {
+			log.Fatal("maintenance error:", grpc.ErrorDesc(err))
+		}
+	}
+
+	reply, err := client.GetMaintenance(ctx, &empty.Empty{})
+	if err != nil {
+		log.Fatal("maintenance error:", grpc.ErrorDesc(err))
+	}
+	printMaintenance(reply)
+	return nil
+}
+
+// printMaintenance prints the status of the maintenance mode
+func printMaintenance(reply *rpc.MaintenanceReply) {
+	if !reply.Enabled {
+		fmt.Println("Maintenance mode disabled")
+		return
+	}
+	source := "configuration"
+	if reply.Override {
+		source = "set at runtime"
+	}
+	fmt.Printf("Maintenance mode enabled (%s): %s\n", source, reply.Message)
+}
+
 func (c *cli) CmdUpgrade(args ...string) error {
 	cmd := SubCmd("upgrade", "", "Seamless binary upgrade")
 
@@ -1205,7 +2283,8 @@ func (c *cli) CmdUpgrade(args ...string) error {
 }
 
 func (c *cli) CmdVersion(args ...string) error {
-	cmd := SubCmd("version", "", "Print version information")
+	cmd := SubCmd("version", "[OPTIONS]", "Print version information")
+	jsonOutput := cmd.Bool("json", false, "Print the version information in JSON")
 
 	if err := cmd.Parse(args); err != nil {
 		return nil
@@ -1215,9 +2294,11 @@ func (c *cli) CmdVersion(args ...string) error {
 		return nil
 	}
 
-	fmt.Printf("Client:\n")
-	core.PrintVersion(core.GetVersionInfo())
-	fmt.Println()
+	if !*jsonOutput {
+		fmt.Printf("Client:\n")
+		core.PrintVersion(core.GetVersionInfo())
+		fmt.Println()
+	}
 
 	client := c.GetRPC()
 	ctx, cancel := context.WithTimeout(context.Background(), defaultRPCTimeout)
@@ -1228,16 +2309,39 @@ func (c *cli) CmdVersion(args ...string) error {
 		return fmt.Errorf("version error: %w", s.Err())
 	}
 
+	server := core.VersionInfo{
+		Version:    reply.Version,
+		Build:      reply.Build,
+		GoVersion:  reply.GoVersion,
+		OS:         reply.OS,
+		Arch:       reply.Arch,
+		GoMaxProcs: int(reply.GoMaxProcs),
+		Commit:     reply.Commit,
+		BuildDate:  reply.BuildDate,
+		Daemon:     reply.Daemon,
+		Uptime:     reply.Uptime,
+		ConfigFile: reply.ConfigFile,
+	}
+
+	if *jsonOutput {
+		out := struct {
+			Client core.VersionInfo
+			Server *core.VersionInfo `json:",omitempty"`
+		}{Client: core.GetVersionInfo()}
+		if reply.Version != "" {
+			out.Server = &server
+		}
+		b, err := json.MarshalIndent(out, "", "    ")
+		if err != nil {
+			return fmt.Errorf("version error: %w", err)
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+
 	if reply.Version != "" {
 		fmt.Printf("Server:\n")
-		core.PrintVersion(core.VersionInfo{
-			Version:    reply.Version,
-			Build:      reply.Build,
-			GoVersion:  reply.GoVersion,
-			OS:         reply.OS,
-			Arch:       reply.Arch,
-			GoMaxProcs: int(reply.GoMaxProcs),
-		})
+		core.PrintVersion(server)
 	}
 	return nil
 }
@@ -0,0 +1,250 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/etix/mirrorbits/mirrors"
+	"github.com/etix/mirrorbits/rpc"
+	"github.com/etix/mirrorbits/utils"
+	"google.golang.org/grpc"
+)
+
+// editField is a field of a mirror that can be set by a flag of the edit
+// command, without opening an editor
+type editField struct {
+	name   string
+	usage  string
+	get    func(m *mirrors.Mirror) string
+	set    func(m *mirrors.Mirror, value string) error
+	isBool bool
+}
+
+// fieldValue holds the raw value given to the flag of an editField
+type fieldValue struct {
+	value  string
+	isBool bool
+}
+
+func (f *fieldValue) String() string     { return f.value }
+func (f *fieldValue) Set(s string) error { f.value = s; return nil }
+func (f *fieldValue) IsBoolFlag() bool   { return f.isBool }
+
+func stringField(name, usage string, ptr func(m *mirrors.Mirror) *string, check func(string) error) editField {
+	return editField{
+		name:  name,
+		usage: usage,
+		get:   func(m *mirrors.Mirror) string { return *ptr(m) },
+		set: func(m *mirrors.Mirror, value string) error {
+			if check != nil {
+				if err := check(value); err != nil {
+					return err
+				}
+			}
+			*ptr(m) = value
+			return nil
+		},
+	}
+}
+
+func boolField(name, usage string, ptr func(m *mirrors.Mirror) *bool) editField {
+	return editField{
+		name:   name,
+		usage:  usage,
+		isBool: true,
+		get:    func(m *mirrors.Mirror) string { return strconv.FormatBool(*ptr(m)) },
+		set: func(m *mirrors.Mirror, value string) error {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid boolean %q", value)
+			}
+			*ptr(m) = b
+			return nil
+		},
+	}
+}
+
+func intField(name, usage string, ptr func(m *mirrors.Mirror) *int, unsigned bool) editField {
+	return editField{
+		name:  name,
+		usage: usage,
+		get:   func(m *mirrors.Mirror) string { return strconv.Itoa(*ptr(m)) },
+		set: func(m *mirrors.Mirror, value string) error {
+			i, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid number %q", value)
+			}
+			if unsigned && i < 0 {
+				return fmt.Errorf("must be >= 0")
+			}
+			*ptr(m) = i
+			return nil
+		},
+	}
+}
+
+func labelsField(name, usage string) editField {
+	return editField{
+		name:  name,
+		usage: usage,
+		get:   func(m *mirrors.Mirror) string { return m.Labels.String() },
+		set: func(m *mirrors.Mirror, value string) error {
+			labels, err := mirrors.ParseLabels(value)
+			if err != nil {
+				return err
+			}
+			m.Labels = labels
+			return nil
+		},
+	}
+}
+
+// checkLocationCodes validates a list of two letters country or continent
+// codes separated by spaces or commas
+func checkLocationCodes(value string) error {
+	for _, code := range strings.Fields(utils.SanitizeLocationCodes(value)) {
+		if len(code) != 2 || strings.Trim(code, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+			return fmt.Errorf("invalid code %q", code)
+		}
+	}
+	return nil
+}
+
+// checkLocalURL validates the URL of the local directory of a mirror
+func checkLocalURL(value string) error {
+	if value != "" && !strings.HasPrefix(value, "file:///") {
+		return fmt.Errorf("the local URL must start with file:///")
+	}
+	return nil
+}
+
+// checkScanProxy validates the URL of the proxy used to scan a mirror
+func checkScanProxy(value string) error {
+	_, err := mirrors.ParseScanProxy(value)
+	return err
+}
+
+// checkScanSchedule validates the time windows of a scan schedule
+func checkScanSchedule(value string) error {
+	_, err := mirrors.ParseScanSchedule(value)
+	return err
+}
+
+var editFields = []editField{
+	stringField("http-url", "HTTP base URL", func(m *mirrors.Mirror) *string { return &m.HttpURL }, checkHTTPURL),
+	stringField("rsync-url", "RSYNC base URL (for scanning only)", func(m *mirrors.Mirror) *string { return &m.RsyncURL }, nil),
+	stringField("ftp-url", "FTP base URL, ftps:// for FTP over TLS (for scanning only)", func(m *mirrors.Mirror) *string { return &m.FtpURL }, nil),
+	boolField("ftp-tls", "Use TLS (explicit AUTH TLS) to scan the FTP URL", func(m *mirrors.Mirror) *bool { return &m.FtpUseTLS }),
+	boolField("ftp-insecure", "Don't verify the certificate of the FTPS server", func(m *mirrors.Mirror) *bool { return &m.FtpInsecureSkipVerify }),
+	stringField("local-url", "file:// URL of the directory of a mirror on this host (for scanning only)", func(m *mirrors.Mirror) *string { return &m.LocalURL }, checkLocalURL),
+	stringField("sponsor-name", "Name of the sponsor", func(m *mirrors.Mirror) *string { return &m.SponsorName }, nil),
+	stringField("sponsor-url", "URL of the sponsor", func(m *mirrors.Mirror) *string { return &m.SponsorURL }, nil),
+	stringField("sponsor-logo", "URL of a logo to display for this mirror", func(m *mirrors.Mirror) *string { return &m.SponsorLogoURL }, nil),
+	stringField("admin-name", "Admin's name", func(m *mirrors.Mirror) *string { return &m.AdminName }, nil),
+	stringField("admin-email", "Admin's email", func(m *mirrors.Mirror) *string { return &m.AdminEmail }, nil),
+	stringField("custom-data", "Associated data to return when the mirror is selected", func(m *mirrors.Mirror) *string { return &m.CustomData }, nil),
+	stringField("continent", "Continent code of the mirror", func(m *mirrors.Mirror) *string { return &m.ContinentCode }, checkLocationCodes),
+	stringField("country", "Country codes of the mirror (space separated)", func(m *mirrors.Mirror) *string { return &m.CountryCodes }, checkLocationCodes),
+	stringField("excluded-country", "Country codes excluded from this mirror (space separated)", func(m *mirrors.Mirror) *string { return &m.ExcludedCountryCodes }, checkLocationCodes),
+	boolField("continent-only", "The mirror should only handle its continent", func(m *mirrors.Mirror) *bool { return &m.ContinentOnly }),
+	boolField("country-only", "The mirror should only handle its country", func(m *mirrors.Mirror) *bool { return &m.CountryOnly }),
+	boolField("as-only", "The mirror should only handle clients in the same AS number", func(m *mirrors.Mirror) *bool { return &m.ASOnly }),
+	boolField("enabled", "Whether the mirror is enabled", func(m *mirrors.Mirror) *bool { return &m.Enabled }),
+	intField("score", "Weight to give to the mirror during selection", func(m *mirrors.Mirror) *int { return &m.Score }, false),
+	intField("bandwidth", "Bandwidth capacity of the mirror in Mbps", func(m *mirrors.Mirror) *int { return &m.BandwidthCapacity }, true),
+	intField("tier", "Tier of the mirror", func(m *mirrors.Mirror) *int { return &m.Tier }, true),
+	stringField("region", "Region of the mirror (see Regions in the configuration)", func(m *mirrors.Mirror) *string { return &m.Region }, nil),
+	boolField("ipv4", "The mirror is reachable over IPv4", func(m *mirrors.Mirror) *bool { return &m.HasIPv4 }),
+	boolField("ipv6", "The mirror is reachable over IPv6", func(m *mirrors.Mirror) *bool { return &m.HasIPv6 }),
+	boolField("manual-ip", "Don't detect the IP versions of the mirror during the scans", func(m *mirrors.Mirror) *bool { return &m.ManualIPFamilies }),
+	intField("max-connections", "Approximate maximum number of downloads in progress on the mirror, 0 for no limit", func(m *mirrors.Mirror) *int { return &m.MaxConnections }, true),
+	stringField("scan-schedule", "Time windows the mirror can be scanned in by the daemon (i.e. 22:00-06:00)", func(m *mirrors.Mirror) *string { return &m.ScanSchedule }, checkScanSchedule),
+	stringField("scan-proxy", "http://, https:// or socks5:// URL of the proxy used for the HTTP and FTP scans", func(m *mirrors.Mirror) *string { return &m.ScanProxy }, checkScanProxy),
+	stringField("client-cert", "Client certificate (PEM) used to connect to the mirror over HTTPS", func(m *mirrors.Mirror) *string { return &m.ClientCertFile }, nil),
+	stringField("client-key", "Private key (PEM) of the client certificate", func(m *mirrors.Mirror) *string { return &m.ClientKeyFile }, nil),
+	labelsField("labels", "Labels of the mirror, replacing the existing ones (i.e. provider=acme,rack=b2)"),
+	stringField("comment", "Comment", func(m *mirrors.Mirror) *string { return &m.Comment }, nil),
+}
+
+// editFlags registers the flags of the edit command
+func editFlags(cmd *flag.FlagSet) map[string]*fieldValue {
+	values := make(map[string]*fieldValue)
+	for _, f := range editFields {
+		v := &fieldValue{isBool: f.isBool}
+		cmd.Var(v, f.name, f.usage)
+		values[f.name] = v
+	}
+	return values
+}
+
+// editWithFlags sets the fields given on the command line on all the
+// matching mirrors. All the values are validated before any mirror is
+// updated.
+func (c *cli) editWithFlags(patterns []string, set map[string]string) {
+	client := c.GetRPC()
+
+	var list []*mirrors.Mirror
+	changes := make(map[int][]string)
+	for _, pattern := range patterns {
+		id, _ := c.matchMirror(pattern)
+		if _, ok := changes[id]; ok {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), defaultRPCTimeout)
+		rpcm, err := client.MirrorInfo(ctx, &rpc.MirrorIDRequest{
+			ID: int32(id),
+		})
+		cancel()
+		if err != nil {
+			log.Fatal("edit error:", grpc.ErrorDesc(err))
+		}
+		mirror, err := rpc.MirrorFromRPC(rpcm)
+		if err != nil {
+			log.Fatal("edit error:", err)
+		}
+
+		changes[id] = nil
+		for _, f := range editFields {
+			value, ok := set[f.name]
+			if !ok {
+				continue
+			}
+			before := f.get(mirror)
+			if err := f.set(mirror, value); err != nil {
+				log.Fatalf("Invalid value for -%s: %s", f.name, err)
+			}
+			if after := f.get(mirror); after != before {
+				changes[mirror.ID] = append(changes[mirror.ID], fmt.Sprintf("  %s: %q -> %q", f.name, before, after))
+			}
+		}
+		list = append(list, mirror)
+	}
+
+	for _, mirror := range list {
+		if len(changes[mirror.ID]) == 0 {
+			fmt.Printf("Mirror '%s' unmodified, nothing to change\n", mirror.Name)
+			continue
+		}
+
+		m, err := rpc.MirrorToRPC(mirror)
+		if err != nil {
+			log.Fatal("edit error:", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), defaultRPCTimeout)
+		_, err = client.UpdateMirror(ctx, m)
+		cancel()
+		if err != nil {
+			log.Fatalf("Couldn't edit mirror '%s': %s", mirror.Name, grpc.ErrorDesc(err))
+		}
+
+		fmt.Printf("Mirror '%s' edited successfully:\n", mirror.Name)
+		fmt.Println(strings.Join(changes[mirror.ID], "\n"))
+	}
+}
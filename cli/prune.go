@@ -0,0 +1,142 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/etix/mirrorbits/rpc"
+	"github.com/etix/mirrorbits/utils"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc"
+)
+
+// pruneCandidate is a mirror matching the criteria of the prune command
+type pruneCandidate struct {
+	mirror *rpc.Mirror
+	reason string
+}
+
+func (c *cli) CmdPrune(args ...string) error {
+	cmd := SubCmd("prune", "[OPTIONS]", "Remove the mirrors down or out of sync for a long time\n\nThe matching mirrors are only listed unless -confirm is given.")
+	downDays := cmd.Uint("down-days", 0, "Prune the mirrors continuously down for more than the given number of days")
+	unsyncedDays := cmd.Uint("unsynced-days", 0, "Prune the mirrors whose last successful scan is older than the given number of days")
+	confirm := cmd.Bool("confirm", false, "Remove the matching mirrors")
+	stats := cmd.Bool("stats", false, "Also remove the download stats of the mirrors (with -confirm)")
+	force := cmd.Bool("f", false, "Never prompt for confirmation (with -confirm)")
+
+	if err := cmd.Parse(args); err != nil {
+		return nil
+	}
+	if cmd.NArg() != 0 || (*downDays == 0 && *unsyncedDays == 0) {
+		cmd.Usage()
+		return nil
+	}
+
+	client := c.GetRPC()
+	ctx, cancel := context.WithTimeout(context.Background(), defaultRPCTimeout)
+	list, err := client.List(ctx, &empty.Empty{})
+	cancel()
+	if err != nil {
+		log.Fatal("prune error:", grpc.ErrorDesc(err))
+	}
+
+	sort.Slice(list.Mirrors, func(i, j int) bool { return list.Mirrors[i].Name < list.Mirrors[j].Name })
+
+	day := 24 * time.Hour
+	candidates := pruneCandidates(list.Mirrors, time.Duration(*downDays)*day, time.Duration(*unsyncedDays)*day, time.Now())
+	if len(candidates) == 0 {
+		fmt.Println("No mirror to prune")
+		return nil
+	}
+
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, 0, 8, 1, '\t', 0)
+	fmt.Fprint(w, "IDENTIFIER\tREASON\n")
+	for _, p := range candidates {
+		fmt.Fprintf(w, "%s\t%s\n", p.mirror.Name, p.reason)
+	}
+	w.Flush()
+
+	if *confirm == false {
+		fmt.Printf("\nDry run, use -confirm to remove the %d mirror%s\n", len(candidates), utils.Plural(len(candidates)))
+		return nil
+	}
+
+	if *force == false {
+		fmt.Printf("\nRemoving %d mirror%s, are you sure? [y/N]", len(candidates), utils.Plural(len(candidates)))
+		reader := bufio.NewReader(os.Stdin)
+		s, _ := reader.ReadString('\n')
+		switch s[0] {
+		case 'y', 'Y':
+			break
+		default:
+			return nil
+		}
+	}
+
+	operator := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		operator = u.Username
+	}
+
+	for _, p := range candidates {
+		// The stats are looked up by name, they must go first
+		if *stats {
+			ctx, cancel := context.WithTimeout(context.Background(), defaultRPCTimeout)
+			_, err := client.ResetStats(ctx, &rpc.ResetStatsRequest{
+				ID:       p.mirror.ID,
+				Operator: operator,
+			})
+			cancel()
+			if err != nil {
+				log.Fatalf("Couldn't remove the stats of mirror '%s': %s", p.mirror.Name, grpc.ErrorDesc(err))
+			}
+		}
+
+		// Use a timeout longer than the default, removing a mirror can take time
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*60)
+		_, err := client.RemoveMirror(ctx, &rpc.MirrorIDRequest{
+			ID: p.mirror.ID,
+		})
+		cancel()
+		if err != nil {
+			log.Fatalf("Couldn't remove mirror '%s': %s", p.mirror.Name, grpc.ErrorDesc(err))
+		}
+		fmt.Printf("Mirror '%s' removed successfully\n", p.mirror.Name)
+	}
+	return nil
+}
+
+// pruneCandidates returns the mirrors of the list down for longer than
+// down, or whose last successful scan is older than unsynced. A zero
+// duration disables the criterion. The mirrors whose state or last
+// successful scan is unknown are never pruned.
+func pruneCandidates(list []*rpc.Mirror, down, unsynced time.Duration, now time.Time) []pruneCandidate {
+	var candidates []pruneCandidate
+	for _, m := range list {
+		if down > 0 && !IsUp(m) {
+			since, err := ptypes.Timestamp(m.StateSince)
+			if err == nil && since.Unix() > 0 && now.Sub(since) > down {
+				candidates = append(candidates, pruneCandidate{m, fmt.Sprintf("down since %s", since.Local().Format(time.RFC1123))})
+				continue
+			}
+		}
+		if unsynced > 0 {
+			last, err := ptypes.Timestamp(m.LastSuccessfulSync)
+			if err == nil && last.Unix() > 0 && now.Sub(last) > unsynced {
+				candidates = append(candidates, pruneCandidate{m, fmt.Sprintf("last successful scan on %s", last.Local().Format(time.RFC1123))})
+			}
+		}
+	}
+	return candidates
+}
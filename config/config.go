@@ -4,20 +4,35 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"net/textproto"
 	"os"
+	"path"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/etix/mirrorbits/core"
 	"github.com/etix/mirrorbits/utils"
 	"github.com/op/go-logging"
+	"golang.org/x/net/http/httpguts"
 	"gopkg.in/yaml.v3"
 )
 
 var (
 	// TEMPLATES_PATH is set at compile time
 	TEMPLATES_PATH = ""
+
+	// ErrConfigNotFound is returned when the configuration file can't be read
+	ErrConfigNotFound = errors.New("configuration could not be found")
+)
+
+const (
+	// maxRsyncTimeout is the maximum value allowed for the rsync timeouts
+	maxRsyncTimeout = 600
 )
 
 var (
@@ -33,34 +48,140 @@ func defaultConfig() Configuration {
 	return Configuration{
 		Repository:             "",
 		Templates:              TEMPLATES_PATH,
+		TemplatesPath:          "",
+		DefaultLocale:          "en",
 		LocalJSPath:            "",
 		OutputMode:             "auto",
 		ListenAddress:          ":8080",
+		TLSListenAddress:       "",
+		TLSCertFile:            "",
+		TLSKeyFile:             "",
+		EnableHTTP2:            true,
+		ShutdownTimeout:        5,
 		Gzip:                   false,
+		GzipMinSize:            1024,
 		AllowHTTPToHTTPSRedirects: true,
+		PreferHTTPS:            true,
+		HTTPOnlyPenalty:        0,
 		SameDownloadInterval:   600,
+		FileStatsRetention:     0,
+		CountRangeRequests:     false,
+		StatsWebhookURL:        "",
+		StatsWebhookBatchSize:  100,
+		StatsWebhookInterval:   5,
+		CountHeadRequests:      false,
 		RedisAddress:           "127.0.0.1:6379",
 		RedisPassword:          "",
 		RedisDB:                0,
+		RedisMaxConnections:    0,
+		RedisMaxIdleConnections: 10,
+		RedisIdleTimeout:       240,
+		SelectionCacheTTL:      0,
+		SelectionCacheMaxEntries: 10000,
 		LogDir:                 "",
+		LogFormat:              "text",
 		TraceFileLocation:      "",
 		GeoipDatabasePath:      "/usr/share/GeoIP/",
+		GeoIPv6Fallback:        true,
+		GeoIPProvider:          "",
+		GeoIPAutoReload:        false,
+		GeoIPUpdateURL:         "",
+		GeoIPAccountID:         "",
+		GeoIPLicenseKey:        "",
 		ConcurrentSync:         5,
+		MaxConcurrentScans:     0,
+		StaleFileGracePeriod:   0,
+		CrossCheckHashes:       false,
+		CrossCheckReference:    "",
+		CrossCheckMaxFiles:     10,
+		CrossCheckMaxSize:      0,
+		CrossCheckExclude:      false,
+		TrustMtime:             true,
 		ScanInterval:           30,
+		RsyncConnectTimeout:    0,
+		RsyncReadTimeout:       0,
+		LocalScanFollowSymlinks: false,
+		MaxFilesPerMirror:      0,
+		ScanRetries:            2,
+		ScanRetryBackoff:       10,
+		MaxFilesPerMirrorTruncate: false,
 		CheckInterval:          1,
+		HealthCheckJitter:      10,
+		DeepHealthCheck:        false,
+		SentinelFile:           "",
+		DeepCheckInterval:      60,
+		FlapThreshold:          0,
+		FlapWindow:             60,
+		FlapCooldown:           60,
 		RepositoryScanInterval: 5,
 		MaxLinkHeaders:         10,
+		MetalinkMirrors:        0,
+		MirrorListMax:          0,
+		TorrentPieceLength:     4 << 20,
+		TorrentMinSize:         0,
+		TorrentWebSeeds:        10,
+		ZsyncMinSize:           0,
+		ZsyncBlockSize:         4096,
 		FixTimezoneOffsets:     false,
+		VerifySizeOnRedirect:   false,
+		VerifySizeReference:    "",
 		Hashes: hashing{
 			SHA1:   false,
 			SHA256: true,
+			SHA512: false,
 			MD5:    false,
 		},
 		DisallowRedirects:       false,
+		ExposeMirrorHeader:      false,
+		SelectionStrategy:       "weighted",
 		WeightDistributionRange: 1.5,
+		PopularityAwareSelection: false,
+		PopularityThreshold:     1000,
+		PopularityWindowFactor:  2,
+		StickySelection:         false,
+		StickySelectionTTL:      3600,
+		StickySelectionCookie:   "",
+		BandwidthDistanceRange:  100,
+		DynamicScoring:          false,
+		DynamicScoringWeight:    0.5,
+		ThroughputHalfLife:      1440,
+		SelectionDebugSampleRate: 0,
+		DistanceRoundingKm:      10,
 		DisableOnMissingFile:    false,
+		FallbackMode:            "redirect",
+		FallbackOrigin:          "",
+		MinMirrorsForRedirect:   0,
+		MaxScanAge:              0,
+		BelowMinMirrors:         "proceed",
 		RPCListenAddress:        "localhost:3390",
 		RPCPassword:             "",
+		PrometheusEndpoint:      false,
+		LivenessPath:            "/healthz",
+		ReadinessPath:           "/readyz",
+		EventStream:             false,
+		EventStreamToken:        "",
+		EventStreamBuffer:       100,
+		ClientIPHeader:          "X-Forwarded-For",
+		TrustedProxies:          []string{},
+		RateLimitPerSecond:      0,
+		RateLimitBurst:          0,
+		MaxConcurrentRedirects:  1000,
+		RedirectTimeout:         5,
+		ConnectionDuration:      60,
+		CountryPins:             map[string][]string{},
+		RegionAffinity:          false,
+		Regions:                 map[string]Region{},
+		RedirectStatusCode:      302,
+		SignedURLs:              false,
+		SignedURLSecret:         "",
+		SignedURLExpiry:         3600,
+		MaintenanceMode:         false,
+		MaintenanceMessage:      "Service under maintenance, please retry later.",
+		MaintenanceRetryAfter:   300,
+		VirtualHosts:            []VirtualHost{},
+		DefaultVirtualHost:      "",
+		PathAllowlist:           []string{},
+		PathBlocklist:           []string{},
 	}
 }
 
@@ -68,36 +189,161 @@ func defaultConfig() Configuration {
 type Configuration struct {
 	Repository              string     `yaml:"Repository"`
 	Templates               string     `yaml:"Templates"`
+	TemplatesPath           string     `yaml:"TemplatesPath"`
+	DefaultLocale           string     `yaml:"DefaultLocale"`
 	LocalJSPath             string     `yaml:"LocalJSPath"`
 	OutputMode              string     `yaml:"OutputMode"`
 	ListenAddress           string     `yaml:"ListenAddress"`
+	TLSListenAddress        string     `yaml:"TLSListenAddress"`
+	TLSCertFile             string     `yaml:"TLSCertFile"`
+	TLSKeyFile              string     `yaml:"TLSKeyFile"`
+	EnableHTTP2             bool       `yaml:"EnableHTTP2"`
+	ShutdownTimeout         int        `yaml:"ShutdownTimeout"`
 	Gzip                    bool       `yaml:"Gzip"`
+	GzipMinSize             int        `yaml:"GzipMinSize"`
 	AllowHTTPToHTTPSRedirects bool     `yaml:"AllowHTTPToHTTPSRedirects"`
+	PreferHTTPS             bool       `yaml:"PreferHTTPS"`
+	HTTPOnlyPenalty         float32    `yaml:"HTTPOnlyPenalty"`
 	SameDownloadInterval    int        `yaml:"SameDownloadInterval"`
+	CountRangeRequests      bool       `yaml:"CountRangeRequests"`
+	CountHeadRequests       bool       `yaml:"CountHeadRequests"`
+	StatsWebhookURL         string     `yaml:"StatsWebhookURL"`
+	StatsWebhookBatchSize   int        `yaml:"StatsWebhookBatchSize"`
+	StatsWebhookInterval    int        `yaml:"StatsWebhookInterval"`
+	FileStatsRetention      int        `yaml:"FileStatsRetention"`
 	RedisAddress            string     `yaml:"RedisAddress"`
+	RedisReplicaAddress     string     `yaml:"RedisReplicaAddress"`
 	RedisPassword           string     `yaml:"RedisPassword"`
 	RedisDB                 int        `yaml:"RedisDB"`
+	RedisMaxConnections     int        `yaml:"RedisMaxConnections"`
+	RedisMaxIdleConnections int        `yaml:"RedisMaxIdleConnections"`
+	RedisIdleTimeout        int        `yaml:"RedisIdleTimeout"`
+	SelectionCacheTTL       int        `yaml:"SelectionCacheTTL"`
+	SelectionCacheMaxEntries int       `yaml:"SelectionCacheMaxEntries"`
 	LogDir                  string     `yaml:"LogDir"`
+	LogFormat               string     `yaml:"LogFormat"`
+	AccessLogFormat         string     `yaml:"AccessLogFormat"`
+	AccessLogFile           string     `yaml:"AccessLogFile"`
 	TraceFileLocation       string     `yaml:"TraceFileLocation"`
 	GeoipDatabasePath       string     `yaml:"GeoipDatabasePath"`
+	GeoIPv6Fallback         bool       `yaml:"GeoIPv6Fallback"`
+	GeoIPProvider           string     `yaml:"GeoIPProvider"`
+	GeoIPAutoReload         bool       `yaml:"GeoIPAutoReload"`
+	GeoIPUpdateURL          string     `yaml:"GeoIPUpdateURL"`
+	GeoIPAccountID          string     `yaml:"GeoIPAccountID"`
+	GeoIPLicenseKey         string     `yaml:"GeoIPLicenseKey"`
 	ConcurrentSync          int        `yaml:"ConcurrentSync"`
+	MaxConcurrentScans      int        `yaml:"MaxConcurrentScans"`
+	StaleFileGracePeriod    int        `yaml:"StaleFileGracePeriod"`
+	CrossCheckHashes        bool       `yaml:"CrossCheckHashes"`
+	CrossCheckReference     string     `yaml:"CrossCheckReference"`
+	CrossCheckMaxFiles      int        `yaml:"CrossCheckMaxFiles"`
+	CrossCheckMaxSize       int64      `yaml:"CrossCheckMaxSize"`
+	CrossCheckExclude       bool       `yaml:"CrossCheckExclude"`
+	TrustMtime              bool       `yaml:"TrustMtime"`
 	ScanInterval            int        `yaml:"ScanInterval"`
+	RsyncConnectTimeout     int        `yaml:"RsyncConnectTimeout"`
+	RsyncReadTimeout        int        `yaml:"RsyncReadTimeout"`
+	LocalScanFollowSymlinks bool       `yaml:"LocalScanFollowSymlinks"`
+	MaxFilesPerMirror       int        `yaml:"MaxFilesPerMirror"`
+	ScanRetries             int        `yaml:"ScanRetries"`
+	ScanRetryBackoff        int        `yaml:"ScanRetryBackoff"`
+	MaxFilesPerMirrorTruncate bool     `yaml:"MaxFilesPerMirrorTruncate"`
 	CheckInterval           int        `yaml:"CheckInterval"`
+	HealthCheckJitter       int        `yaml:"HealthCheckJitter"`
+	DeepHealthCheck         bool       `yaml:"DeepHealthCheck"`
+	SentinelFile            string     `yaml:"SentinelFile"`
+	DeepCheckInterval       int        `yaml:"DeepCheckInterval"`
+	FlapThreshold           int        `yaml:"FlapThreshold"`
+	FlapWindow              int        `yaml:"FlapWindow"`
+	FlapCooldown            int        `yaml:"FlapCooldown"`
 	RepositoryScanInterval  int        `yaml:"RepositoryScanInterval"`
 	MaxLinkHeaders          int        `yaml:"MaxLinkHeaders"`
+	MetalinkMirrors         int        `yaml:"MetalinkMirrors"`
+	MirrorListMax           int        `yaml:"MirrorListMax"`
+	TorrentPieceLength      int        `yaml:"TorrentPieceLength"`
+	TorrentMinSize          int64      `yaml:"TorrentMinSize"`
+	TorrentWebSeeds         int        `yaml:"TorrentWebSeeds"`
+	ZsyncMinSize            int64      `yaml:"ZsyncMinSize"`
+	ZsyncBlockSize          int        `yaml:"ZsyncBlockSize"`
 	FixTimezoneOffsets      bool       `yaml:"FixTimezoneOffsets"`
 	Hashes                  hashing    `yaml:"Hashes"`
 	DisallowRedirects       bool       `yaml:"DisallowRedirects"`
+	ExposeMirrorHeader      bool       `yaml:"ExposeMirrorHeader"`
+	SelectionStrategy       string     `yaml:"SelectionStrategy"`
 	WeightDistributionRange float32    `yaml:"WeightDistributionRange"`
+	PopularityAwareSelection bool      `yaml:"PopularityAwareSelection"`
+	PopularityThreshold     int        `yaml:"PopularityThreshold"`
+	PopularityWindowFactor  float32    `yaml:"PopularityWindowFactor"`
+	StickySelection         bool       `yaml:"StickySelection"`
+	StickySelectionTTL      int        `yaml:"StickySelectionTTL"`
+	StickySelectionCookie   string     `yaml:"StickySelectionCookie"`
+	BandwidthDistanceRange  float32    `yaml:"BandwidthDistanceRange"`
+	DynamicScoring          bool       `yaml:"DynamicScoring"`
+	DynamicScoringWeight    float32    `yaml:"DynamicScoringWeight"`
+	SelectionDebugSampleRate float32   `yaml:"SelectionDebugSampleRate"`
+	ThroughputHalfLife      int        `yaml:"ThroughputHalfLife"`
+	DistanceRoundingKm      float32    `yaml:"DistanceRoundingKm"`
 	DisableOnMissingFile    bool       `yaml:"DisableOnMissingFile"`
 	AllowOutdatedFiles      []OutdatedFilesConfig `yaml:"AllowOutdatedFiles"`
+	VerifySizeOnRedirect    bool       `yaml:"VerifySizeOnRedirect"`
+	VerifySizeReference     string     `yaml:"VerifySizeReference"`
 	Fallbacks               []Fallback `yaml:"Fallbacks"`
+	FallbackMode            string     `yaml:"FallbackMode"`
+	FallbackOrigin          string     `yaml:"FallbackOrigin"`
+	MinMirrorsForRedirect   int        `yaml:"MinMirrorsForRedirect"`
+	MaxScanAge              int        `yaml:"MaxScanAge"`
+	BelowMinMirrors         string     `yaml:"BelowMinMirrors"`
 
 	RedisSentinelMasterName string      `yaml:"RedisSentinelMasterName"`
 	RedisSentinels          []sentinels `yaml:"RedisSentinels"`
 
 	RPCListenAddress string `yaml:"RPCListenAddress"`
 	RPCPassword      string `yaml:"RPCPassword"`
+
+	PrometheusEndpoint bool `yaml:"PrometheusEndpoint"`
+
+	LivenessPath  string `yaml:"LivenessPath"`
+	ReadinessPath string `yaml:"ReadinessPath"`
+
+	EventStream       bool   `yaml:"EventStream"`
+	EventStreamToken  string `yaml:"EventStreamToken"`
+	EventStreamBuffer int    `yaml:"EventStreamBuffer"`
+
+	ClientIPHeader string   `yaml:"ClientIPHeader"`
+	TrustedProxies []string `yaml:"TrustedProxies"`
+
+	RateLimitPerSecond float32 `yaml:"RateLimitPerSecond"`
+	RateLimitBurst     int     `yaml:"RateLimitBurst"`
+
+	MaxConcurrentRedirects int `yaml:"MaxConcurrentRedirects"`
+	RedirectTimeout        int `yaml:"RedirectTimeout"`
+	ConnectionDuration     int `yaml:"ConnectionDuration"`
+
+	CountryPins map[string][]string `yaml:"CountryPins"`
+
+	RegionAffinity bool              `yaml:"RegionAffinity"`
+	Regions        map[string]Region `yaml:"Regions"`
+
+	PathAllowlist []string `yaml:"PathAllowlist"`
+	PathBlocklist []string `yaml:"PathBlocklist"`
+
+	RedirectStatusCode      int                    `yaml:"RedirectStatusCode"`
+	RedirectStatusOverrides []RedirectStatusConfig `yaml:"RedirectStatusOverrides"`
+
+	ResponseHeaders         map[string]string            `yaml:"ResponseHeaders"`
+	ResponseHeadersByStatus map[string]map[string]string `yaml:"ResponseHeadersByStatus"`
+
+	SignedURLs      bool   `yaml:"SignedURLs"`
+	SignedURLSecret string `yaml:"SignedURLSecret"`
+	SignedURLExpiry int    `yaml:"SignedURLExpiry"`
+
+	MaintenanceMode       bool   `yaml:"MaintenanceMode"`
+	MaintenanceMessage    string `yaml:"MaintenanceMessage"`
+	MaintenanceRetryAfter int    `yaml:"MaintenanceRetryAfter"`
+
+	VirtualHosts       []VirtualHost `yaml:"VirtualHosts"`
+	DefaultVirtualHost string        `yaml:"DefaultVirtualHost"`
 }
 
 type Fallback struct {
@@ -113,6 +359,7 @@ type sentinels struct {
 type hashing struct {
 	SHA1   bool `yaml:"SHA1"`
 	SHA256 bool `yaml:"SHA256"`
+	SHA512 bool `yaml:"SHA512"`
 	MD5    bool `yaml:"MD5"`
 }
 
@@ -121,6 +368,43 @@ type OutdatedFilesConfig struct {
 	Minutes int    `yaml:"Minutes"`
 }
 
+// Region groups the clients of the given continents and countries, the
+// mirrors are added to a region with their Region tag
+type Region struct {
+	Continents []string `yaml:"Continents"`
+	Countries  []string `yaml:"Countries"`
+}
+
+type RedirectStatusConfig struct {
+	Prefix     string `yaml:"Prefix"`
+	StatusCode int    `yaml:"StatusCode"`
+}
+
+// VirtualHost serves the archive stored under Prefix in the repository to
+// the requests made to Host, from the given mirrors only. The root of these
+// mirrors is the Prefix directory of the repository.
+type VirtualHost struct {
+	Host    string   `yaml:"Host"`
+	Prefix  string   `yaml:"Prefix"`
+	Mirrors []string `yaml:"Mirrors"`
+}
+
+// HasMirror returns true if the mirror serves the virtual host
+func (v *VirtualHost) HasMirror(name string) bool {
+	return utils.IsInSlice(name, v.Mirrors)
+}
+
+// MirrorPrefix returns the directory of the repository the root of the given
+// mirror maps to, empty if the mirror is not part of a virtual host
+func (c *Configuration) MirrorPrefix(name string) string {
+	for i := range c.VirtualHosts {
+		if c.VirtualHosts[i].HasMirror(name) {
+			return c.VirtualHosts[i].Prefix
+		}
+	}
+	return ""
+}
+
 // LoadConfig loads the configuration file if it has not yet been loaded
 func LoadConfig() {
 	if config != nil {
@@ -134,6 +418,42 @@ func LoadConfig() {
 
 // ReloadConfig reloads the configuration file and update it globally
 func ReloadConfig() error {
+	c, err := ParseConfigFile()
+	if errors.Is(err, ErrConfigNotFound) {
+		fmt.Println("Configuration could not be found.\n\tUse -config <path>")
+		os.Exit(1)
+	} else if err != nil {
+		return err
+	}
+
+	if config != nil &&
+		(c.RedisAddress != config.RedisAddress ||
+			c.RedisPassword != config.RedisPassword ||
+			!testSentinelsEq(c.RedisSentinels, config.RedisSentinels)) {
+		// TODO reload redis connections
+		// Currently established connections will be updated only in case of disconnection
+	}
+
+	// Lock the pointer during the swap
+	configMutex.Lock()
+	config = &c
+	configMutex.Unlock()
+
+	// Notify all subscribers that the configuration has been reloaded
+	notifySubscribers()
+
+	return nil
+}
+
+// CheckConfig reads and validates the configuration file without applying it
+func CheckConfig() error {
+	_, err := ParseConfigFile()
+	return err
+}
+
+// ParseConfigFile reads and validates the configuration file, defaulting to
+// /etc/mirrorbits.conf, and returns it without applying it
+func ParseConfigFile() (Configuration, error) {
 	if core.ConfigFile == "" {
 		if fileExists("/etc/mirrorbits.conf") {
 			core.ConfigFile = "/etc/mirrorbits.conf"
@@ -141,71 +461,397 @@ func ReloadConfig() error {
 	}
 
 	content, err := os.ReadFile(core.ConfigFile)
-	if err != nil {
-		fmt.Println("Configuration could not be found.\n\tUse -config <path>")
-		os.Exit(1)
+	if err != nil && !hasEnvOverrides() {
+		return Configuration{}, fmt.Errorf("%w: %s", ErrConfigNotFound, err)
 	}
 
 	if os.Getenv("DEBUG") != "" {
 		fmt.Println("Reading configuration from", core.ConfigFile)
 	}
 
+	return parseConfig(content)
+}
+
+// parseConfig parses and sanitizes the given configuration
+func parseConfig(content []byte) (Configuration, error) {
 	c := defaultConfig()
 
 	// Overload the default configuration with the user's one
-	err = yaml.Unmarshal(content, &c)
+	err := yaml.Unmarshal(content, &c)
 	if err != nil {
-		return fmt.Errorf("%s in %s", err, core.ConfigFile)
+		return c, fmt.Errorf("%s in %s", err, core.ConfigFile)
+	}
+
+	// The environment takes precedence over the file
+	if err = applyEnv(&c); err != nil {
+		return c, err
+	}
+
+	// Templates is the former name of TemplatesPath
+	if c.TemplatesPath == "" {
+		c.TemplatesPath = c.Templates
 	}
 
 	// Sanitize
+	if !utils.IsInSlice(c.SelectionStrategy, []string{"weighted", "nearest", "roundrobin", "leastloaded"}) {
+		return c, fmt.Errorf("Config: SelectionStrategy can only be set to 'weighted', 'nearest', 'roundrobin' or 'leastloaded'")
+	}
 	if c.WeightDistributionRange <= 0 {
-		return fmt.Errorf("WeightDistributionRange must be > 0")
+		return c, fmt.Errorf("WeightDistributionRange must be > 0")
+	}
+	if c.PopularityThreshold < 0 {
+		return c, fmt.Errorf("PopularityThreshold must be >= 0")
+	}
+	if c.PopularityWindowFactor < 1 {
+		return c, fmt.Errorf("PopularityWindowFactor must be >= 1")
+	}
+	if c.StickySelectionTTL <= 0 {
+		return c, fmt.Errorf("StickySelectionTTL must be > 0")
+	}
+	if c.BandwidthDistanceRange < 0 {
+		return c, fmt.Errorf("BandwidthDistanceRange must be >= 0")
+	}
+	if c.EventStreamBuffer <= 0 {
+		return c, fmt.Errorf("EventStreamBuffer must be > 0")
+	}
+	if c.HTTPOnlyPenalty < 0 || c.HTTPOnlyPenalty > 1 {
+		return c, fmt.Errorf("HTTPOnlyPenalty must be between 0 and 1")
+	}
+	if c.DynamicScoringWeight < 0 || c.DynamicScoringWeight > 1 {
+		return c, fmt.Errorf("DynamicScoringWeight must be between 0 and 1")
+	}
+	if c.SelectionDebugSampleRate < 0 || c.SelectionDebugSampleRate > 1 {
+		return c, fmt.Errorf("SelectionDebugSampleRate must be between 0 and 1")
+	}
+	if c.ThroughputHalfLife <= 0 {
+		return c, fmt.Errorf("ThroughputHalfLife must be > 0")
+	}
+	for _, cidr := range c.TrustedProxies {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return c, fmt.Errorf("TrustedProxies: invalid network %s", cidr)
+		}
+	}
+	pins := make(map[string][]string, len(c.CountryPins))
+	for country, names := range c.CountryPins {
+		pins[strings.ToUpper(country)] = names
+	}
+	c.CountryPins = pins
+	continents := make(map[string]string)
+	countries := make(map[string]string)
+	for name, region := range c.Regions {
+		for i, code := range region.Continents {
+			code = strings.ToUpper(code)
+			if other, ok := continents[code]; ok {
+				return c, fmt.Errorf("Regions: continent %s is in both %s and %s", code, other, name)
+			}
+			continents[code] = name
+			region.Continents[i] = code
+		}
+		for i, code := range region.Countries {
+			code = strings.ToUpper(code)
+			if other, ok := countries[code]; ok {
+				return c, fmt.Errorf("Regions: country %s is in both %s and %s", code, other, name)
+			}
+			countries[code] = name
+			region.Countries[i] = code
+		}
+	}
+	for _, pattern := range append(c.PathAllowlist, c.PathBlocklist...) {
+		if _, err := utils.MatchPathPattern(pattern, "/"); err != nil {
+			return c, fmt.Errorf("Invalid path pattern %s: %s", pattern, err)
+		}
+	}
+	for _, p := range []string{c.LivenessPath, c.ReadinessPath} {
+		if p != "" && !strings.HasPrefix(p, "/") {
+			return c, fmt.Errorf("LivenessPath and ReadinessPath must start with a '/'")
+		}
+	}
+	if c.LivenessPath != "" && c.LivenessPath == c.ReadinessPath {
+		return c, fmt.Errorf("LivenessPath and ReadinessPath must be different")
+	}
+	if c.GzipMinSize < 0 {
+		return c, fmt.Errorf("GzipMinSize must be >= 0")
+	}
+	if c.RateLimitPerSecond < 0 || c.RateLimitBurst < 0 {
+		return c, fmt.Errorf("RateLimitPerSecond and RateLimitBurst must be >= 0")
+	}
+	if c.MaxConcurrentRedirects < 0 {
+		return c, fmt.Errorf("MaxConcurrentRedirects must be >= 0")
+	}
+	if c.RedirectTimeout < 0 {
+		return c, fmt.Errorf("RedirectTimeout must be >= 0")
+	}
+	if c.ConnectionDuration <= 0 {
+		return c, fmt.Errorf("ConnectionDuration must be > 0")
+	}
+	if c.RateLimitPerSecond > 0 && c.RateLimitBurst == 0 {
+		// Allow at least one second worth of requests
+		c.RateLimitBurst = utils.Max(1, int(c.RateLimitPerSecond))
 	}
 	if !utils.IsInSlice(c.OutputMode, []string{"auto", "json", "redirect"}) {
-		return fmt.Errorf("Config: outputMode can only be set to 'auto', 'json' or 'redirect'")
+		return c, fmt.Errorf("Config: outputMode can only be set to 'auto', 'json' or 'redirect'")
+	}
+	if !utils.IsInSlice(c.GeoIPProvider, []string{"", "maxmind", "dbip"}) {
+		return c, fmt.Errorf("Config: GeoIPProvider can only be set to 'maxmind' or 'dbip'")
+	}
+	if !utils.IsInSlice(c.LogFormat, []string{"text", "json"}) {
+		return c, fmt.Errorf("Config: LogFormat can only be set to 'text' or 'json'")
+	}
+	if !utils.IsInSlice(c.AccessLogFormat, []string{"", "combined"}) {
+		return c, fmt.Errorf("Config: AccessLogFormat can only be set to 'combined'")
+	}
+	if c.AccessLogFormat != "" && c.AccessLogFile == "" {
+		return c, fmt.Errorf("AccessLogFile is required by AccessLogFormat")
+	}
+	if c.ListenAddress == "" && c.TLSListenAddress == "" {
+		return c, fmt.Errorf("ListenAddress and TLSListenAddress cannot be both empty")
+	}
+	for _, address := range []string{c.ListenAddress, c.TLSListenAddress} {
+		if address != "" && !strings.HasPrefix(address, "unix:") {
+			if err := checkAddress(address); err != nil {
+				return c, fmt.Errorf("Invalid listen address %s: %s", address, err)
+			}
+		}
+	}
+	for _, address := range []string{c.RedisAddress, c.RedisReplicaAddress} {
+		if address != "" {
+			if err := checkAddress(address); err != nil {
+				return c, fmt.Errorf("Invalid Redis address %s: %s", address, err)
+			}
+		}
+	}
+	for _, s := range c.RedisSentinels {
+		if err := checkAddress(s.Host); err != nil {
+			return c, fmt.Errorf("Invalid Redis sentinel address %s: %s", s.Host, err)
+		}
+	}
+	if c.TLSListenAddress != "" && (c.TLSCertFile == "" || c.TLSKeyFile == "") {
+		return c, fmt.Errorf("TLSCertFile and TLSKeyFile are required when TLSListenAddress is set")
 	}
 	if c.Repository == "" {
-		return fmt.Errorf("Path to local repository not configured (see mirrorbits.conf)")
+		return c, fmt.Errorf("Path to local repository not configured (see mirrorbits.conf)")
 	}
 	c.Repository, err = filepath.Abs(c.Repository)
 	if err != nil {
-		return fmt.Errorf("Invalid local repository path: %s", err)
+		return c, fmt.Errorf("Invalid local repository path: %s", err)
+	}
+	if c.MetalinkMirrors < 0 {
+		c.MetalinkMirrors = 0
+	}
+	if c.MirrorListMax < 0 {
+		c.MirrorListMax = 0
+	}
+	if c.TorrentPieceLength < 16<<10 || c.TorrentPieceLength&(c.TorrentPieceLength-1) != 0 {
+		return c, fmt.Errorf("TorrentPieceLength must be a power of two >= 16384")
+	}
+	if c.TorrentMinSize < 0 {
+		return c, fmt.Errorf("TorrentMinSize must be >= 0")
+	}
+	if c.TorrentWebSeeds < 0 {
+		c.TorrentWebSeeds = 0
+	}
+	if c.ZsyncBlockSize < 512 || c.ZsyncBlockSize&(c.ZsyncBlockSize-1) != 0 {
+		return c, fmt.Errorf("ZsyncBlockSize must be a power of two >= 512")
+	}
+	if c.ZsyncMinSize < 0 {
+		return c, fmt.Errorf("ZsyncMinSize must be >= 0")
+	}
+	if c.DistanceRoundingKm < 0 {
+		return c, fmt.Errorf("DistanceRoundingKm must be >= 0")
+	}
+	if c.FileStatsRetention < 0 {
+		return c, fmt.Errorf("FileStatsRetention must be >= 0")
+	}
+	if c.SameDownloadInterval < 0 {
+		return c, fmt.Errorf("SameDownloadInterval must be >= 0")
+	}
+	if c.ShutdownTimeout < 0 {
+		return c, fmt.Errorf("ShutdownTimeout must be >= 0")
+	}
+	if c.MaxConcurrentScans < 0 {
+		return c, fmt.Errorf("MaxConcurrentScans must be >= 0")
+	}
+	if c.StaleFileGracePeriod < 0 {
+		return c, fmt.Errorf("StaleFileGracePeriod must be >= 0")
+	}
+	if c.CrossCheckMaxFiles < 0 || c.CrossCheckMaxSize < 0 {
+		return c, fmt.Errorf("CrossCheckMaxFiles and CrossCheckMaxSize must be >= 0")
 	}
 	if c.RepositoryScanInterval < 0 {
 		c.RepositoryScanInterval = 0
 	}
+	if c.RedisMaxConnections < 0 || c.RedisMaxIdleConnections < 0 || c.RedisIdleTimeout < 0 {
+		return c, fmt.Errorf("RedisMaxConnections, RedisMaxIdleConnections and RedisIdleTimeout must be >= 0")
+	}
+	if c.RedisMaxConnections > 0 {
+		c.RedisMaxIdleConnections = utils.Min(c.RedisMaxIdleConnections, c.RedisMaxConnections)
+	}
+	if c.SelectionCacheTTL < 0 {
+		return c, fmt.Errorf("SelectionCacheTTL must be >= 0")
+	}
+	if c.SelectionCacheMaxEntries <= 0 {
+		return c, fmt.Errorf("SelectionCacheMaxEntries must be > 0")
+	}
+	if c.HealthCheckJitter < 0 || (c.CheckInterval > 0 && c.HealthCheckJitter >= c.CheckInterval*60) {
+		return c, fmt.Errorf("HealthCheckJitter must be >= 0 and shorter than CheckInterval")
+	}
+	if c.DeepHealthCheck {
+		if len(c.SentinelFile) == 0 || c.SentinelFile[0] != '/' {
+			return c, fmt.Errorf("SentinelFile must start with '/' when DeepHealthCheck is enabled")
+		}
+		if c.DeepCheckInterval < c.CheckInterval {
+			return c, fmt.Errorf("DeepCheckInterval must be >= CheckInterval")
+		}
+	}
+	if c.FlapThreshold < 0 {
+		return c, fmt.Errorf("FlapThreshold must be >= 0")
+	}
+	if c.FlapThreshold > 0 && (c.FlapWindow <= 0 || c.FlapCooldown <= 0) {
+		return c, fmt.Errorf("FlapWindow and FlapCooldown must be > 0 when FlapThreshold is set")
+	}
+	if c.MaxFilesPerMirror < 0 {
+		return c, fmt.Errorf("MaxFilesPerMirror must be >= 0")
+	}
+	if c.ScanRetries < 0 || c.ScanRetryBackoff < 0 {
+		return c, fmt.Errorf("ScanRetries and ScanRetryBackoff must be >= 0")
+	}
+	if c.RsyncConnectTimeout < 0 || c.RsyncReadTimeout < 0 {
+		return c, fmt.Errorf("RsyncConnectTimeout and RsyncReadTimeout must be >= 0")
+	}
+	c.RsyncConnectTimeout = utils.Min(c.RsyncConnectTimeout, maxRsyncTimeout)
+	c.RsyncReadTimeout = utils.Min(c.RsyncReadTimeout, maxRsyncTimeout)
+	if !utils.IsInSlice(c.FallbackMode, []string{"redirect", "proxy", "notfound"}) {
+		return c, fmt.Errorf("Config: FallbackMode can only be set to 'redirect', 'proxy' or 'notfound'")
+	}
+	if c.FallbackMode == "proxy" && !utils.HasAnyPrefix(c.FallbackOrigin, "http://", "https://") {
+		return c, fmt.Errorf("FallbackOrigin must be an http:// or https:// URL when FallbackMode is 'proxy'")
+	}
+	if c.MinMirrorsForRedirect < 0 {
+		return c, fmt.Errorf("MinMirrorsForRedirect must be >= 0")
+	}
+	if c.StatsWebhookBatchSize <= 0 || c.StatsWebhookInterval <= 0 {
+		return c, fmt.Errorf("StatsWebhookBatchSize and StatsWebhookInterval must be > 0")
+	}
+	if c.SignedURLs && c.SignedURLSecret == "" {
+		return c, fmt.Errorf("SignedURLSecret is required by SignedURLs")
+	}
+	if c.SignedURLExpiry <= 0 {
+		return c, fmt.Errorf("SignedURLExpiry must be > 0")
+	}
+	if c.MaintenanceRetryAfter < 0 {
+		return c, fmt.Errorf("MaintenanceRetryAfter must be >= 0")
+	}
+	if c.MaxScanAge < 0 {
+		return c, fmt.Errorf("MaxScanAge must be >= 0")
+	}
+	if !utils.IsInSlice(c.BelowMinMirrors, []string{"proceed", "fallback"}) {
+		return c, fmt.Errorf("Config: BelowMinMirrors can only be set to 'proceed' or 'fallback'")
+	}
 	for i := range c.Fallbacks {
 		c.Fallbacks[i].URL = utils.NormalizeURL(c.Fallbacks[i].URL)
 	}
 	for _, rule := range c.AllowOutdatedFiles {
 		if len(rule.Prefix) > 0 && rule.Prefix[0] != '/' {
-			return fmt.Errorf("AllowOutdatedFiles.Prefix must start with '/'")
+			return c, fmt.Errorf("AllowOutdatedFiles.Prefix must start with '/'")
 		}
 		if rule.Minutes < 0 {
-			return fmt.Errorf("AllowOutdatedFiles.Minutes must be >= 0")
+			return c, fmt.Errorf("AllowOutdatedFiles.Minutes must be >= 0")
 		}
 	}
-
-	if config != nil &&
-		(c.RedisAddress != config.RedisAddress ||
-			c.RedisPassword != config.RedisPassword ||
-			!testSentinelsEq(c.RedisSentinels, config.RedisSentinels)) {
-		// TODO reload redis connections
-		// Currently established connections will be updated only in case of disconnection
+	if !isRedirectStatusCode(c.RedirectStatusCode) {
+		return c, fmt.Errorf("RedirectStatusCode must be one of 301, 302, 303, 307 or 308")
+	}
+	for _, rule := range c.RedirectStatusOverrides {
+		if len(rule.Prefix) == 0 || rule.Prefix[0] != '/' {
+			return c, fmt.Errorf("RedirectStatusOverrides.Prefix must start with '/'")
+		}
+		if !isRedirectStatusCode(rule.StatusCode) {
+			return c, fmt.Errorf("RedirectStatusOverrides.StatusCode must be one of 301, 302, 303, 307 or 308")
+		}
+	}
+	hosts := make(map[string]bool, len(c.VirtualHosts))
+	vhostOf := make(map[string]string)
+	for i := range c.VirtualHosts {
+		vhost := &c.VirtualHosts[i]
+		vhost.Host = strings.TrimSuffix(strings.ToLower(vhost.Host), ".")
+		if vhost.Host == "" {
+			return c, fmt.Errorf("VirtualHosts.Host is required")
+		}
+		if hosts[vhost.Host] {
+			return c, fmt.Errorf("VirtualHosts: %s is declared twice", vhost.Host)
+		}
+		hosts[vhost.Host] = true
+		if len(vhost.Prefix) > 0 {
+			if vhost.Prefix[0] != '/' {
+				return c, fmt.Errorf("VirtualHosts.Prefix must start with '/'")
+			}
+			vhost.Prefix = strings.TrimSuffix(path.Clean(vhost.Prefix), "/")
+		}
+		for _, name := range vhost.Mirrors {
+			if other, ok := vhostOf[name]; ok {
+				return c, fmt.Errorf("VirtualHosts: mirror %s is in both %s and %s", name, other, vhost.Host)
+			}
+			vhostOf[name] = vhost.Host
+		}
+	}
+	c.DefaultVirtualHost = strings.TrimSuffix(strings.ToLower(c.DefaultVirtualHost), ".")
+	if c.DefaultVirtualHost != "" && !hosts[c.DefaultVirtualHost] {
+		return c, fmt.Errorf("DefaultVirtualHost must be one of the VirtualHosts")
+	}
+	if c.ResponseHeaders, err = parseResponseHeaders(c.ResponseHeaders); err != nil {
+		return c, fmt.Errorf("ResponseHeaders: %s", err)
+	}
+	for class, headers := range c.ResponseHeadersByStatus {
+		if !utils.IsInSlice(class, []string{"1xx", "2xx", "3xx", "4xx", "5xx"}) {
+			return c, fmt.Errorf("ResponseHeadersByStatus: invalid status class %s (1xx to 5xx)", class)
+		}
+		if c.ResponseHeadersByStatus[class], err = parseResponseHeaders(headers); err != nil {
+			return c, fmt.Errorf("ResponseHeadersByStatus: %s", err)
+		}
 	}
 
-	// Lock the pointer during the swap
-	configMutex.Lock()
-	config = &c
-	configMutex.Unlock()
+	return c, nil
+}
 
-	// Notify all subscribers that the configuration has been reloaded
-	notifySubscribers()
+// parseResponseHeaders returns the headers with their canonical names, the
+// Location of the redirects can't be replaced
+func parseResponseHeaders(headers map[string]string) (map[string]string, error) {
+	parsed := make(map[string]string, len(headers))
+	for key, value := range headers {
+		if !httpguts.ValidHeaderFieldName(key) || !httpguts.ValidHeaderFieldValue(value) {
+			return nil, fmt.Errorf("invalid header %s", key)
+		}
+		key = textproto.CanonicalMIMEHeaderKey(key)
+		if key == "Location" {
+			return nil, fmt.Errorf("the Location header can't be set")
+		}
+		parsed[key] = value
+	}
+	return parsed, nil
+}
 
+// checkAddress returns an error if the address is not of the host:port form
+func checkAddress(address string) error {
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if _, err = strconv.ParseUint(port, 10, 16); err != nil {
+		return fmt.Errorf("invalid port %s", port)
+	}
 	return nil
 }
 
+// isRedirectStatusCode returns true if code can be used to redirect the
+// clients to the mirrors
+func isRedirectStatusCode(code int) bool {
+	switch code {
+	case 301, 302, 303, 307, 308:
+		return true
+	}
+	return false
+}
+
 // GetConfig returns a pointer to a configuration object
 // FIXME reading from the pointer could cause a race!
 func GetConfig() *Configuration {
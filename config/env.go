@@ -0,0 +1,113 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	// envPrefix is the prefix of the environment variables overriding the
+	// configuration
+	envPrefix = "MIRRORBITS_"
+)
+
+// applyEnv overrides the configuration with the environment variables named
+// after the prefix and the uppercased path of the yaml keys, the keys of
+// the nested sections being separated by an underscore (i.e.
+// MIRRORBITS_REDISADDRESS or MIRRORBITS_HASHES_SHA256). The lists are
+// given as comma separated values, the lists of sections and the maps
+// can't be overridden.
+func applyEnv(c *Configuration) error {
+	return applyEnvStruct(reflect.ValueOf(c).Elem(), envPrefix)
+}
+
+// hasEnvOverrides returns true if any of the configuration is given in the
+// environment, the configuration file is then optional
+func hasEnvOverrides() bool {
+	for _, e := range os.Environ() {
+		if strings.HasPrefix(e, envPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func applyEnvStruct(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if key == "" || key == "-" {
+			continue
+		}
+		name := prefix + strings.ToUpper(key)
+		field := v.Field(i)
+
+		if field.Kind() == reflect.Struct {
+			if err := applyEnvStruct(field, name+"_"); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := setEnvValue(field, strings.TrimSpace(value)); err != nil {
+			return fmt.Errorf("%s: %s", name, err)
+		}
+	}
+	return nil
+}
+
+// setEnvValue sets the field from the value of an environment variable
+func setEnvValue(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", value)
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("can't be set from the environment")
+		}
+		list := reflect.MakeSlice(field.Type(), 0, 0)
+		for _, s := range strings.Split(value, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				list = reflect.Append(list, reflect.ValueOf(s).Convert(field.Type().Elem()))
+			}
+		}
+		field.Set(list)
+	default:
+		return fmt.Errorf("can't be set from the environment")
+	}
+	return nil
+}
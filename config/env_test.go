@@ -0,0 +1,126 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+type envTestValues struct {
+	String   string   `yaml:"String"`
+	Bool     bool     `yaml:"Bool"`
+	Int      int      `yaml:"Int"`
+	Int8     int8     `yaml:"Int8"`
+	Uint     uint     `yaml:"Uint"`
+	Float    float64  `yaml:"Float"`
+	Float32  float32  `yaml:"Float32"`
+	List     []string `yaml:"List"`
+	Sections []struct {
+		Name string `yaml:"Name"`
+	} `yaml:"Sections"`
+	Map map[string]string `yaml:"Map"`
+}
+
+func TestSetEnvValue(t *testing.T) {
+	tests := map[string]struct {
+		field    string
+		value    string
+		expected any
+		err      bool
+	}{
+		"string":           {"String", "redis:6379", "redis:6379", false},
+		"bool":             {"Bool", "true", true, false},
+		"bool as number":   {"Bool", "1", true, false},
+		"invalid bool":     {"Bool", "yes", nil, true},
+		"int":              {"Int", "-42", -42, false},
+		"invalid int":      {"Int", "4.2", nil, true},
+		"int overflow":     {"Int8", "300", nil, true},
+		"uint":             {"Uint", "42", uint(42), false},
+		"negative uint":    {"Uint", "-1", nil, true},
+		"float":            {"Float", "0.25", 0.25, false},
+		"float32":          {"Float32", "0.5", float32(0.5), false},
+		"invalid float":    {"Float", "half", nil, true},
+		"list":             {"List", "/a, /b,,/c", []string{"/a", "/b", "/c"}, false},
+		"empty list":       {"List", "", []string{}, false},
+		"list of sections": {"Sections", "a,b", nil, true},
+		"map":              {"Map", "a=b", nil, true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var values envTestValues
+			field := reflect.ValueOf(&values).Elem().FieldByName(test.field)
+			err := setEnvValue(field, test.value)
+			if test.err {
+				if err == nil {
+					t.Fatalf("Expected an error, got %v", field.Interface())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(field.Interface(), test.expected) {
+				t.Fatalf("Expected %#v, got %#v", test.expected, field.Interface())
+			}
+		})
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	t.Setenv("MIRRORBITS_REDISADDRESS", " redis:6379 ")
+	t.Setenv("MIRRORBITS_REDISDB", "3")
+	t.Setenv("MIRRORBITS_HTTPONLYPENALTY", "0.5")
+	t.Setenv("MIRRORBITS_CROSSCHECKHASHES", "true")
+	t.Setenv("MIRRORBITS_PATHBLOCKLIST", "/private/*,/tmp/*")
+	t.Setenv("MIRRORBITS_HASHES_SHA256", "false")
+
+	c := defaultConfig()
+	if err := applyEnv(&c); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if c.RedisAddress != "redis:6379" || c.RedisDB != 3 || c.HTTPOnlyPenalty != 0.5 || !c.CrossCheckHashes {
+		t.Fatalf("Unexpected configuration %+v", c)
+	}
+	if !reflect.DeepEqual(c.PathBlocklist, []string{"/private/*", "/tmp/*"}) {
+		t.Fatalf("Unexpected PathBlocklist %v", c.PathBlocklist)
+	}
+	if c.Hashes.SHA256 {
+		t.Fatalf("Expected the nested Hashes.SHA256 to be overridden")
+	}
+}
+
+func TestApplyEnvInvalid(t *testing.T) {
+	tests := map[string]string{
+		"MIRRORBITS_REDISDB":          "first",
+		"MIRRORBITS_CROSSCHECKHASHES": "maybe",
+		"MIRRORBITS_FALLBACKS":        "http://fallback.example",
+	}
+
+	for name, value := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			c := defaultConfig()
+			if err := applyEnv(&c); err == nil {
+				t.Fatalf("Expected an error for %s=%s", name, value)
+			}
+		})
+	}
+}
+
+func TestParseConfigEnvPrecedence(t *testing.T) {
+	t.Setenv("MIRRORBITS_REDISADDRESS", "env:6379")
+
+	c, err := parseConfig([]byte("Repository: /srv/repo\nRedisAddress: yaml:6379\nRedisDB: 2\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if c.RedisAddress != "env:6379" {
+		t.Fatalf("Expected the environment to take precedence, got %s", c.RedisAddress)
+	}
+	if c.RedisDB != 2 {
+		t.Fatalf("Expected the yaml value without override, got %d", c.RedisDB)
+	}
+}
@@ -37,19 +37,28 @@ _mirrorbits() {
     # Mirrorbits commands
     local COMMANDS=(
         "add"
+        "check-config"
         "daemon"
+        "diff"
         "disable"
         "edit"
         "enable"
         "export"
+        "force-state"
+        "geoip-update"
         "geoupdate"
+        "import"
         "list"
+        "locate"
         "logs"
+        "maintenance"
+        "prune"
         "refresh"
         "reload"
         "remove"
         "scan"
         "show"
+        "simulate"
         "stats"
         "upgrade"
         "version")
@@ -95,11 +104,58 @@ _mirrorbits() {
             add)
                 COMPREPLY=( $( compgen -W '-help -admin-email -admin-name
                     -as-only -comment -continent-only -country-only
-                    -custom-data -ftp -http -rsync -score
+                    -bandwidth -client-cert -client-key -custom-data -excluded-country -ftp -ftp-insecure
+                    -ftp-tls -http -ipv4 -ipv6 -labels -local -manual-ip -max-connections -region -rsync -scan-proxy -scan-schedule -score -tier
                     -sponsor-logo -sponsor-name -sponsor-url
                     ' -- "$cur" ) )
                 ;;
-            disable|edit|enable|show)
+            check-config)
+                case $prev in
+                    -config|-f)
+                        _filedir
+                        ;;
+                    *)
+                        COMPREPLY=( $( compgen -W '-help -config -f' -- "$cur" ) )
+                        ;;
+                esac
+                ;;
+            edit)
+                case $cur in
+                    -*)
+                        COMPREPLY=( $( compgen -W '-help -admin-email -admin-name
+                            -as-only -bandwidth -client-cert -client-key -comment
+                            -continent -continent-only -country -country-only
+                            -custom-data -enabled -excluded-country -ftp-insecure
+                            -ftp-tls -ftp-url
+                            -http-url -ipv4 -ipv6 -labels -local-url -manual-ip -max-connections -region -rsync-url -scan-proxy -scan-schedule -score -sponsor-logo
+                            -sponsor-name -sponsor-url -tier' -- "$cur" ) )
+                        ;;
+                    *)
+                        COMPREPLY=( $( compgen -W "$( _mirrorbits_list $port )" -- "$cur" ) )
+                        ;;
+                esac
+                ;;
+            diff)
+                case $cur in
+                    -*)
+                        COMPREPLY=( $( compgen -W '-help -full' -- "$cur" ) )
+                        ;;
+                    *)
+                        COMPREPLY=( $( compgen -W "$( _mirrorbits_list $port )" -- "$cur" ) )
+                        ;;
+                esac
+                ;;
+            disable|enable)
+                case $cur in
+                    -*)
+                        COMPREPLY=( $( compgen -W '-help -tag' -- "$cur" ) )
+                        ;;
+                    *)
+                        COMPREPLY=( $( compgen -W "$( _mirrorbits_list $port )" -- "$cur" ) )
+                        ;;
+                esac
+                ;;
+            show)
                 case $cur in
                     -*)
                         COMPREPLY=( $( compgen -W '-help' -- "$cur" ) )
@@ -110,9 +166,35 @@ _mirrorbits() {
                 esac
                 ;;
             export)
-                COMPREPLY=( $( compgen -W '-help -disabled -ftp
+                COMPREPLY=( $( compgen -W '-help -disabled -ftp -o
                     -http -rsync' -- "$cur" ) )
                 ;;
+            force-state)
+                case $cur in
+                    -*)
+                        COMPREPLY=( $( compgen -W '-help -ttl' -- "$cur" ) )
+                        ;;
+                    *)
+                        if [[ $prev == -ttl ]]; then
+                            :
+                        elif [[ $prev == force-state || ${words[cword-2]} == -ttl ]]; then
+                            COMPREPLY=( $( compgen -W "$( _mirrorbits_list $port )" -- "$cur" ) )
+                        else
+                            COMPREPLY=( $( compgen -W 'up down auto' -- "$cur" ) )
+                        fi
+                        ;;
+                esac
+                ;;
+            geoip-update)
+                case $prev in
+                    -config)
+                        _filedir
+                        ;;
+                    *)
+                        COMPREPLY=( $( compgen -W '-help -config -reload' -- "$cur" ) )
+                        ;;
+                esac
+                ;;
             geoupdate)
                 case $cur in
                     -*)
@@ -123,11 +205,36 @@ _mirrorbits() {
                         ;;
                 esac
                 ;;
+            import)
+                case $cur in
+                    -*)
+                        COMPREPLY=( $( compgen -W '-help -update' -- "$cur" ) )
+                        ;;
+                    *)
+                        _filedir
+                        ;;
+                esac
+                ;;
             list)
                 COMPREPLY=( $( compgen -W '-help -disabled -down -enabled
-                    -ftp -http -location -rsync -score -state
+                    -ftp -http -json -label -location -region -rsync -score -state
                     ' -- "$cur" ) )
                 ;;
+            maintenance)
+                case $cur in
+                    -*)
+                        COMPREPLY=( $( compgen -W '-help -message' -- "$cur" ) )
+                        ;;
+                    *)
+                        if [[ $prev != -message ]]; then
+                            COMPREPLY=( $( compgen -W 'on off' -- "$cur" ) )
+                        fi
+                        ;;
+                esac
+                ;;
+            locate)
+                COMPREPLY=( $( compgen -W '-help -h' -- "$cur" ) )
+                ;;
             logs)
                 case $cur in
                     -*)
@@ -141,9 +248,18 @@ _mirrorbits() {
             refresh)
                 COMPREPLY=( $( compgen -W '-help -rehash' -- "$cur" ) )
                 ;;
-            reload|upgrade|version)
+            simulate)
+                COMPREPLY=( $( compgen -W '-help -excluded -file -ip' -- "$cur" ) )
+                ;;
+            reload|upgrade)
                 COMPREPLY=( $( compgen -W '-help' -- "$cur" ) )
                 ;;
+            version)
+                COMPREPLY=( $( compgen -W '-help -json' -- "$cur" ) )
+                ;;
+            prune)
+                COMPREPLY=( $( compgen -W '-help -confirm -down-days -f -stats -unsynced-days' -- "$cur" ) )
+                ;;
             remove)
                 case $cur in
                     -*)
@@ -158,7 +274,8 @@ _mirrorbits() {
                 case $cur in
                     -*)
                         COMPREPLY=( $( compgen -W '-help -all -enable -ftp
-                            -rsync -timeout' -- "$cur" ) )
+                            -local -rsync -timeout -only -dry-run
+                            -force-rehash' -- "$cur" ) )
                         ;;
                     *)
                         COMPREPLY=( $( compgen -W "$( _mirrorbits_list $port )" -- "$cur" ) )
@@ -168,16 +285,17 @@ _mirrorbits() {
             stats)
                 case $cur in
                     -*)
-                        COMPREPLY=( $( compgen -W '-help -end-date -h -start-date
+                        COMPREPLY=( $( compgen -W '-help -end-date -h -start-date -reset -all -f
+                            -period -date -limit -format
                             ' -- "$cur" ) )
                         ;;
                     *)
                         if _in_array mirror "${words[@]:2}"; then
                             COMPREPLY=( $( compgen -W "$( _mirrorbits_list $port )" -- "$cur" ) )
-                        elif _in_array file "${words[@]:2}"; then
+                        elif _in_array file "${words[@]:2}" || _in_array files "${words[@]:2}" || _in_array rates "${words[@]:2}" || _in_array export "${words[@]:2}"; then
                             COMPREPLY=()
                         else
-                            COMPREPLY=( $( compgen -W 'file mirror' -- "$cur" ) )
+                            COMPREPLY=( $( compgen -W 'export file files mirror rates' -- "$cur" ) )
                         fi
                         ;;
                 esac
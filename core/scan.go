@@ -10,6 +10,8 @@ const (
 	RSYNC ScannerType = iota
 	// FTP represents an ftp scanner
 	FTP
+	// LOCAL represents a scanner of a local directory
+	LOCAL
 )
 
 // Precision is used to compute the precision of the mod time (millisecond, second)
@@ -6,14 +6,20 @@ package core
 import (
 	"fmt"
 	"runtime"
+	"runtime/debug"
+	"time"
 )
 
 var (
-	VERSION = ""
-	BUILD   = ""
-	DEV     = ""
+	VERSION   = ""
+	BUILD     = ""
+	DEV       = ""
+	COMMIT    = ""
+	BUILDDATE = ""
 )
 
+var startTime = time.Now()
+
 // VersionInfo is a struct containing version related informations
 type VersionInfo struct {
 	Version    string
@@ -22,6 +28,11 @@ type VersionInfo struct {
 	OS         string
 	Arch       string
 	GoMaxProcs int
+	Commit     string
+	BuildDate  string
+	Daemon     bool
+	Uptime     int64  `json:",omitempty"` // in seconds, only known for a running daemon
+	ConfigFile string `json:",omitempty"`
 }
 
 // GetVersionInfo returns the details of the current build
@@ -33,9 +44,33 @@ func GetVersionInfo() VersionInfo {
 		OS:         runtime.GOOS,
 		Arch:       runtime.GOARCH,
 		GoMaxProcs: runtime.GOMAXPROCS(0),
+		Commit:     commit(),
+		BuildDate:  BUILDDATE,
+		Daemon:     Daemon,
 	}
 }
 
+// Uptime returns the time elapsed since the process started
+func Uptime() time.Duration {
+	return time.Since(startTime)
+}
+
+// commit returns the git commit the binary was built from, as given at
+// build time or as recorded by the go tool otherwise
+func commit() string {
+	if COMMIT != "" {
+		return COMMIT
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" {
+				return s.Value
+			}
+		}
+	}
+	return ""
+}
+
 // PrintVersion prints the versions contained in a VersionReply
 func PrintVersion(info VersionInfo) {
 	fmt.Printf(" %-17s %s\n", "Version:", info.Version)
@@ -44,4 +79,16 @@ func PrintVersion(info VersionInfo) {
 	fmt.Printf(" %-17s %s\n", "Operating System:", info.OS)
 	fmt.Printf(" %-17s %s\n", "Architecture:", info.Arch)
 	fmt.Printf(" %-17s %d\n", "Gomaxprocs:", info.GoMaxProcs)
+	if info.Commit != "" {
+		fmt.Printf(" %-17s %s\n", "Commit:", info.Commit)
+	}
+	if info.BuildDate != "" {
+		fmt.Printf(" %-17s %s\n", "Build date:", info.BuildDate)
+	}
+	if info.Uptime > 0 {
+		fmt.Printf(" %-17s %s\n", "Uptime:", time.Duration(info.Uptime)*time.Second)
+	}
+	if info.ConfigFile != "" {
+		fmt.Printf(" %-17s %s\n", "Config file:", info.ConfigFile)
+	}
 }
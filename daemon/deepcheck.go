@@ -0,0 +1,233 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package daemon
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/etix/mirrorbits/core"
+	"github.com/etix/mirrorbits/mirrors"
+	"github.com/etix/mirrorbits/utils"
+)
+
+var (
+	deepCheckThreads = 2
+	deepCheckAgent   = "Mirrorbits/" + core.VERSION + " DEEP CHECK"
+
+	// Maximum amount of data read from the mirror's sentinel file
+	maxSentinelSize int64 = 10 << 20
+)
+
+// Main deep health check loop
+func (m *monitor) deepCheckLoop() {
+	defer m.wg.Done()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case id := <-m.deepCheckChan:
+			if utils.IsStopped(m.stop) {
+				return
+			}
+
+			m.mapLock.Lock()
+			mptr, ok := m.mirrors[id]
+			if !ok {
+				m.mapLock.Unlock()
+				continue
+			}
+			mirror := *mptr
+			m.mapLock.Unlock()
+
+			matched, err := m.deepCheck(mirror.Mirror)
+			if err != nil && !utils.IsStopped(m.stop) {
+				log.Warningf("[%s] deep health check failed: %s", mirror.Name, err)
+			}
+
+			m.mapLock.Lock()
+			if mptr, ok := m.mirrors[id]; ok {
+				if err == nil {
+					if mptr.sentinelMismatch && matched {
+						// Let the next health check mark the mirror as up
+						mptr.nextCheck = time.Time{}
+					}
+					mptr.sentinelMismatch = !matched
+				}
+				mptr.lastDeepCheck = time.Now().UTC()
+				mptr.deepChecking = false
+			}
+			m.mapLock.Unlock()
+		}
+	}
+}
+
+// sentinelChecksum returns the SHA-256 of the sentinel file of the local
+// repository
+func sentinelChecksum() (string, error) {
+	f, err := os.Open(filepath.Join(GetConfig().Repository, GetConfig().SentinelFile))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Download the sentinel file from the mirror and compare it with the local
+// one. Only the protocols currently up are checked and each one serving a
+// different content is marked as down. A mismatch is reported even if the
+// other protocol failed, an error is only returned when none of them could be
+// checked, the state of the mirror being then left to the health check.
+func (m *monitor) deepCheck(mirror mirrors.Mirror) (bool, error) {
+	checksum, err := sentinelChecksum()
+	if err != nil {
+		return false, fmt.Errorf("unable to hash the local sentinel file: %w", err)
+	}
+
+	var urls []string
+	if utils.HasAnyPrefix(mirror.HttpURL, "http://", "https://") {
+		urls = []string{mirror.HttpURL}
+	} else {
+		urls = []string{"http://" + mirror.HttpURL, "https://" + mirror.HttpURL}
+	}
+
+	matched := true
+	checked := 0
+	var lastErr error
+	for _, url := range urls {
+		if strings.HasPrefix(url, "https://") && !mirror.HttpsUp ||
+			strings.HasPrefix(url, "http://") && !mirror.HttpUp {
+			continue
+		}
+		ok, err := m.deepCheckDo(&mirror, url, checksum)
+		if err != nil {
+			if !utils.IsStopped(m.stop) {
+				log.Debugf("[%s] deep health check of %s failed: %s", mirror.Name, url, err)
+			}
+			lastErr = err
+			continue
+		}
+		checked++
+		if !ok {
+			matched = false
+		}
+	}
+	if checked == 0 {
+		if lastErr == nil {
+			lastErr = errors.New("no protocol up")
+		}
+		return false, lastErr
+	}
+	return matched, nil
+}
+
+func (m *monitor) deepCheckDo(mirror *mirrors.Mirror, url, checksum string) (bool, error) {
+	proto := mirrors.HTTP
+	if strings.HasPrefix(url, "https://") {
+		proto = mirrors.HTTPS
+	}
+
+	format := "%-" + fmt.Sprintf("%d.%ds %-5s ", m.formatLongestID+4, m.formatLongestID+4, proto)
+
+	client, transport, err := m.mirrorClient(mirror)
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequest("GET", strings.TrimRight(url, "/")+GetConfig().SentinelFile, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("User-Agent", deepCheckAgent)
+	mirror.HTTPHeaders.Apply(req)
+	req.Close = true
+
+	ctx, cancel := context.WithTimeout(req.Context(), clientDeadline)
+	ctx = context.WithValue(ctx, core.ContextMirrorID, mirror.ID)
+	ctx = context.WithValue(ctx, core.ContextMirrorName, mirror.Name)
+	ctx = context.WithValue(ctx, core.ContextAllowRedirects, mirror.AllowRedirects)
+	req = req.WithContext(ctx)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-m.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var statusCode int
+	var remote string
+	var size int64
+	elapsed, err := m.httpDo(ctx, client, transport, req, func(resp *http.Response, err error) error {
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		statusCode = resp.StatusCode
+		if statusCode != http.StatusOK {
+			return nil
+		}
+		h := sha256.New()
+		n, err := io.Copy(h, io.LimitReader(resp.Body, maxSentinelSize))
+		if err != nil {
+			return err
+		}
+		size = n
+		remote = hex.EncodeToString(h.Sum(nil))
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if statusCode == http.StatusOK && size > 0 && elapsed > 0 {
+		m.recordThroughput(mirror, float64(size)/elapsed.Seconds(), format)
+	}
+
+	var reason string
+	switch {
+	case statusCode != http.StatusOK:
+		reason = fmt.Sprintf("Sentinel file unavailable (error %d)", statusCode)
+	case remote != checksum:
+		reason = "Sentinel checksum mismatch"
+	default:
+		log.Debugf(format+"Sentinel checksum OK", mirror.Name)
+		return true, nil
+	}
+
+	if err = m.markDown(mirror, proto, reason); err != nil {
+		log.Errorf(format+"Unable to mark mirror as down: %s", mirror.Name, err)
+	}
+	log.Warningf(format+"Down! %s", mirror.Name, reason)
+	return false, nil
+}
+
+// recordThroughput merges the throughput observed while downloading the
+// sentinel file into the average of the mirror
+func (m *monitor) recordThroughput(mirror *mirrors.Mirror, throughput float64, format string) {
+	halfLife := time.Duration(GetConfig().ThroughputHalfLife) * time.Minute
+	average, err := mirrors.RecordThroughput(m.redis, mirror.ID, throughput, time.Now(), halfLife)
+	if err != nil {
+		log.Errorf(format+"Unable to record the throughput: %s", mirror.Name, err)
+		return
+	}
+	log.Debugf(format+"Throughput %s/s (average %s/s)", mirror.Name, utils.ReadableSize(int64(throughput)), utils.ReadableSize(int64(average)))
+}
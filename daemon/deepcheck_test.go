@@ -0,0 +1,130 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package daemon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/etix/mirrorbits/mirrors"
+	. "github.com/etix/mirrorbits/testing"
+)
+
+func TestMonitor_DeepCheck(t *testing.T) {
+	repo := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repo, "sentinel"), []byte("mirrorbits"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	defer SetConfiguration(GetConfig())
+	SetConfiguration(&Configuration{
+		RedisDB:           42,
+		Repository:        repo,
+		DeepHealthCheck:   true,
+		SentinelFile:      "/sentinel",
+		DeepCheckInterval: 60,
+	})
+
+	tests := map[string]struct {
+		status   int
+		content  string
+		expected bool
+	}{
+		"match":    {http.StatusOK, "mirrorbits", true},
+		"mismatch": {http.StatusOK, "tampered", false},
+		"missing":  {http.StatusNotFound, "", false},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/sentinel" {
+					t.Errorf("Unexpected request for %s", r.URL.Path)
+				}
+				w.WriteHeader(test.status)
+				w.Write([]byte(test.content))
+			}))
+			defer server.Close()
+
+			_, conn := PrepareRedisTest()
+			m := NewMonitor(conn, nil)
+			defer m.Stop()
+
+			matched, err := m.deepCheck(mirrors.Mirror{
+				ID:      1,
+				Name:    "m1",
+				HttpURL: server.URL,
+				HttpUp:  true,
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if matched != test.expected {
+				t.Fatalf("Expected %t, got %t", test.expected, matched)
+			}
+		})
+	}
+}
+
+func TestMonitor_DeepCheckProtocols(t *testing.T) {
+	repo := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repo, "sentinel"), []byte("mirrorbits"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	defer SetConfiguration(GetConfig())
+	SetConfiguration(&Configuration{
+		RedisDB:           42,
+		Repository:        repo,
+		DeepHealthCheck:   true,
+		SentinelFile:      "/sentinel",
+		DeepCheckInterval: 60,
+	})
+
+	// The server only speaks HTTP, any HTTPS request to it fails
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("tampered"))
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	tests := map[string]struct {
+		httpUp   bool
+		httpsUp  bool
+		expected bool
+		err      bool
+	}{
+		"http only":           {true, false, false, false},
+		"https failing":       {true, true, false, false},
+		"https only, failing": {false, true, false, true},
+		"all down":            {false, false, false, true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, conn := PrepareRedisTest()
+			m := NewMonitor(conn, nil)
+			defer m.Stop()
+
+			matched, err := m.deepCheck(mirrors.Mirror{
+				ID:      1,
+				Name:    "m1",
+				HttpURL: host,
+				HttpUp:  test.httpUp,
+				HttpsUp: test.httpsUp,
+			})
+			if test.err != (err != nil) {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if matched != test.expected {
+				t.Fatalf("Expected %t, got %t", test.expected, matched)
+			}
+		})
+	}
+}
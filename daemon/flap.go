@@ -0,0 +1,66 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package daemon
+
+import (
+	"time"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/etix/mirrorbits/mirrors"
+)
+
+// markDown marks the mirror as down for the given protocol and, if it was
+// up, records the transition to detect the flapping mirrors
+func (m *monitor) markDown(mirror *mirrors.Mirror, proto mirrors.Protocol, reason string) error {
+	wasUp := mirror.HttpUp
+	if proto == mirrors.HTTPS {
+		wasUp = mirror.HttpsUp
+	}
+	err := mirrors.MarkMirrorDown(m.redis, mirror.ID, proto, reason)
+	if err == nil && wasUp {
+		m.recordFlap(mirror.ID, time.Now())
+	}
+	return err
+}
+
+// recordFlap records an up to down transition of the mirror. Once the
+// mirror went down more than FlapThreshold times within the FlapWindow it
+// is put in cooldown for FlapCooldown and true is returned.
+func (m *monitor) recordFlap(id int, now time.Time) bool {
+	threshold := GetConfig().FlapThreshold
+	if threshold <= 0 {
+		return false
+	}
+	window := time.Duration(GetConfig().FlapWindow) * time.Minute
+
+	m.mapLock.Lock()
+	mptr, ok := m.mirrors[id]
+	if !ok {
+		m.mapLock.Unlock()
+		return false
+	}
+	flaps := append(mptr.flaps, now)
+	for len(flaps) > 0 && now.Sub(flaps[0]) > window {
+		flaps = flaps[1:]
+	}
+	cooldown := len(flaps) > threshold
+	if cooldown {
+		flaps = nil
+	}
+	mptr.flaps = flaps
+	name := mptr.Name
+	m.mapLock.Unlock()
+
+	if !cooldown {
+		return false
+	}
+
+	until := now.Add(time.Duration(GetConfig().FlapCooldown) * time.Minute)
+	if err := mirrors.SetMirrorCooldown(m.redis, id, until); err != nil {
+		log.Errorf("[%s] Unable to put the mirror in cooldown: %s", name, err)
+	} else {
+		log.Warningf("[%s] Flapping! Excluded from the selection until %s", name, until.Local().Format(time.RFC1123))
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/etix/mirrorbits/database"
+	"github.com/etix/mirrorbits/mirrors"
+	. "github.com/etix/mirrorbits/testing"
+	"github.com/rafaeljusto/redigomock"
+)
+
+func TestMonitor_RecordFlap(t *testing.T) {
+	defer SetConfiguration(GetConfig())
+	SetConfiguration(&Configuration{
+		RedisDB:       42,
+		FlapThreshold: 2,
+		FlapWindow:    10,
+		FlapCooldown:  30,
+	})
+
+	now := time.Now()
+
+	tests := map[string]struct {
+		flaps    []time.Duration
+		expected []bool
+	}{
+		"below threshold": {[]time.Duration{0, time.Minute}, []bool{false, false}},
+		"flapping":        {[]time.Duration{0, time.Minute, 2 * time.Minute}, []bool{false, false, true}},
+		"reset":           {[]time.Duration{0, time.Minute, 2 * time.Minute, 3 * time.Minute}, []bool{false, false, true, false}},
+		"outside window":  {[]time.Duration{0, 6 * time.Minute, 12 * time.Minute}, []bool{false, false, false}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mock, conn := PrepareRedisTest()
+			cmdCooldown := mock.Command("HSET", "MIRROR_1", "cooldownUntil", redigomock.NewAnyData()).Expect("ok")
+			mock.Command("PUBLISH", string(database.MIRROR_UPDATE), redigomock.NewAnyData()).Expect("ok")
+
+			m := NewMonitor(conn, nil)
+			defer m.Stop()
+			m.mirrors[1] = &mirror{Mirror: mirrors.Mirror{ID: 1, Name: "m1"}}
+
+			cooldowns := 0
+			for i, d := range test.flaps {
+				if r := m.recordFlap(1, now.Add(d)); r != test.expected[i] {
+					t.Fatalf("Expected %t for flap %d, got %t", test.expected[i], i, r)
+				} else if r {
+					cooldowns++
+				}
+			}
+			if mock.Stats(cmdCooldown) != cooldowns {
+				t.Fatalf("Expected the cooldown to be set %d times, got %d", cooldowns, mock.Stats(cmdCooldown))
+			}
+		})
+	}
+
+	// Disabled
+	SetConfiguration(&Configuration{RedisDB: 42})
+	_, conn := PrepareRedisTest()
+	m := NewMonitor(conn, nil)
+	defer m.Stop()
+	m.mirrors[1] = &mirror{Mirror: mirrors.Mirror{ID: 1, Name: "m1"}}
+	for i := 0; i < 10; i++ {
+		if m.recordFlap(1, now) {
+			t.Fatalf("No cooldown expected when FlapThreshold is 0")
+		}
+	}
+}
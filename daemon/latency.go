@@ -0,0 +1,72 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package daemon
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+const (
+	// latencyHalfLife is the time after which a latency sample weighs half
+	// as much in the average of the mirror
+	latencyHalfLife = time.Hour
+)
+
+// connLatency measures the time spent establishing the first connection of
+// a request: the TCP connection and the TLS handshake, if any
+type connLatency struct {
+	sync.Mutex
+	connectStart time.Time
+	connect      time.Duration
+	tlsStart     time.Time
+	handshake    time.Duration
+}
+
+// trace returns the hooks recording the latency of the request
+func (l *connLatency) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		ConnectStart: func(network, addr string) {
+			l.Lock()
+			defer l.Unlock()
+			if l.connectStart.IsZero() {
+				l.connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(network, addr string, err error) {
+			l.Lock()
+			defer l.Unlock()
+			// Keep the first successful attempt (i.e. IPv6 vs IPv4)
+			if err == nil && l.connect == 0 {
+				l.connect = time.Since(l.connectStart)
+			}
+		},
+		TLSHandshakeStart: func() {
+			l.Lock()
+			defer l.Unlock()
+			if l.tlsStart.IsZero() {
+				l.tlsStart = time.Now()
+			}
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			l.Lock()
+			defer l.Unlock()
+			if err == nil && l.handshake == 0 {
+				l.handshake = time.Since(l.tlsStart)
+			}
+		},
+	}
+}
+
+// total returns the measured latency, or 0 if no connection was established
+func (l *connLatency) total() time.Duration {
+	l.Lock()
+	defer l.Unlock()
+	if l.connect == 0 {
+		return 0
+	}
+	return l.connect + l.handshake
+}
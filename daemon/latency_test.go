@@ -0,0 +1,86 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package daemon
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"testing"
+
+	"github.com/etix/mirrorbits/mirrors"
+)
+
+func TestConnLatency(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, useTLS := range []bool{false, true} {
+		var server *httptest.Server
+		if useTLS {
+			server = httptest.NewTLSServer(handler)
+		} else {
+			server = httptest.NewServer(handler)
+		}
+		defer server.Close()
+
+		latency := &connLatency{}
+		req, _ := http.NewRequest("HEAD", server.URL, nil)
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), latency.trace()))
+		resp, err := server.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if latency.connect <= 0 || latency.total() < latency.connect {
+			t.Fatalf("TLS %t: unexpected latency: %+v", useTLS, latency)
+		}
+		if useTLS != (latency.handshake > 0) {
+			t.Fatalf("TLS %t: unexpected handshake duration: %s", useTLS, latency.handshake)
+		}
+	}
+
+	if l := (&connLatency{}).total(); l != 0 {
+		t.Fatalf("Expected no latency without connection, got %s", l)
+	}
+}
+
+func TestConnLatencyMonitorClient(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, useTLS := range []bool{false, true} {
+		var server *httptest.Server
+		if useTLS {
+			server = httptest.NewTLSServer(handler)
+		} else {
+			server = httptest.NewServer(handler)
+		}
+		defer server.Close()
+
+		m := &monitor{}
+		m.initHTTPClient()
+		client, transport, err := m.mirrorClient(&mirrors.Mirror{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+
+		latency := &connLatency{}
+		req, _ := http.NewRequest("HEAD", server.URL, nil)
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), latency.trace()))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if latency.total() <= 0 {
+			t.Fatalf("TLS %t: no latency recorded through the monitor transport: %+v", useTLS, latency)
+		}
+		if useTLS != (latency.handshake > 0) {
+			t.Fatalf("TLS %t: unexpected handshake duration: %s", useTLS, latency.handshake)
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"math/rand"
 	"net"
 	"net/http"
+	"net/http/httptrace"
 	"strconv"
 	"strings"
 	"sync"
@@ -44,6 +45,7 @@ type monitor struct {
 	httpClient      http.Client
 	httpTransport   http.Transport
 	healthCheckChan chan int
+	deepCheckChan   chan int
 	syncChan        chan int
 	stop            chan struct{}
 	configNotifier  chan bool
@@ -56,13 +58,41 @@ type monitor struct {
 
 type mirror struct {
 	mirrors.Mirror
-	checking  bool
-	scanning  bool
-	lastCheck time.Time
+	checking bool
+	scanning bool
+	// nextCheck is when the health check is due, delayed by a random
+	// jitter so the checks of the mirrors are spread out
+	nextCheck time.Time
+
+	deepChecking     bool
+	lastDeepCheck    time.Time
+	sentinelMismatch bool
+
+	// Recent up to down transitions, see recordFlap
+	flaps []time.Time
 }
 
 func (m *mirror) NeedHealthCheck() bool {
-	return time.Since(m.lastCheck) > time.Duration(GetConfig().CheckInterval)*time.Minute
+	return !time.Now().Before(m.nextCheck)
+}
+
+// scheduleHealthCheck sets the next health check of the mirror after the
+// CheckInterval
+func (m *mirror) scheduleHealthCheck(now time.Time) {
+	m.nextCheck = now.Add(time.Duration(GetConfig().CheckInterval)*time.Minute + healthCheckJitter())
+}
+
+// healthCheckJitter returns a random delay of up to HealthCheckJitter
+func healthCheckJitter() time.Duration {
+	jitter := time.Duration(GetConfig().HealthCheckJitter) * time.Second
+	if jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(jitter)))
+}
+
+func (m *mirror) NeedDeepCheck() bool {
+	return time.Since(m.lastDeepCheck) > time.Duration(GetConfig().DeepCheckInterval)*time.Minute
 }
 
 func (m *mirror) NeedSync() bool {
@@ -85,6 +115,7 @@ func NewMonitor(r *database.Redis, c *mirrors.Cache) *monitor {
 	m.cluster = NewCluster(r)
 	m.mirrors = make(map[int]*mirror)
 	m.healthCheckChan = make(chan int, healthCheckThreads*5)
+	m.deepCheckChan = make(chan int, deepCheckThreads*5)
 	m.syncChan = make(chan int)
 	m.stop = make(chan struct{})
 	m.configNotifier = make(chan bool, 1)
@@ -94,12 +125,24 @@ func NewMonitor(r *database.Redis, c *mirrors.Cache) *monitor {
 
 	rand.Seed(time.Now().UnixNano())
 
+	m.initHTTPClient()
+	return m
+}
+
+// initHTTPClient sets up the HTTP client used to check the mirrors. The
+// connections are established through the context of the request so the
+// httptrace hooks measuring the latency are fired.
+func (m *monitor) initHTTPClient() {
+	dialer := &net.Dialer{
+		Timeout: clientTimeout,
+	}
+
 	m.httpTransport = http.Transport{
 		DisableKeepAlives:   true,
 		MaxIdleConnsPerHost: 0,
-		Dial: func(network, addr string) (net.Conn, error) {
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
 			deadline := time.Now().Add(clientDeadline)
-			c, err := net.DialTimeout(network, addr, clientTimeout)
+			c, err := dialer.DialContext(ctx, network, addr)
 			if err != nil {
 				return nil, err
 			}
@@ -112,7 +155,6 @@ func NewMonitor(r *database.Redis, c *mirrors.Cache) *monitor {
 		CheckRedirect: checkRedirect,
 		Transport:     &m.httpTransport,
 	}
-	return m
 }
 
 func (m *monitor) Stop() {
@@ -209,6 +251,12 @@ func (m *monitor) MonitorLoop() {
 		go m.healthCheckLoop()
 	}
 
+	// Start the deep health check routines
+	for i := 0; i < deepCheckThreads; i++ {
+		m.wg.Add(1)
+		go m.deepCheckLoop()
+	}
+
 	// Start the mirror sync routines
 	for i := 0; i < GetConfig().ConcurrentSync; i++ {
 		m.wg.Add(1)
@@ -268,14 +316,25 @@ func (m *monitor) MonitorLoop() {
 				if !m.cluster.IsHandled(id) {
 					continue
 				}
-				if v.NeedHealthCheck() && !v.IsChecking() {
+				if !GetConfig().DeepHealthCheck {
+					v.sentinelMismatch = false
+				}
+				if v.NeedHealthCheck() && !v.IsChecking() && !v.IsForced() && !v.sentinelMismatch {
 					select {
 					case m.healthCheckChan <- id:
 						m.mirrors[id].checking = true
 					default:
 					}
 				}
-				if v.NeedSync() && !v.IsScanning() {
+				if GetConfig().DeepHealthCheck && v.NeedDeepCheck() && !v.deepChecking && !v.IsForced() && (v.IsUp() || v.sentinelMismatch) {
+					select {
+					case m.deepCheckChan <- id:
+						m.mirrors[id].deepChecking = true
+					default:
+					}
+				}
+				// Mirrors with a scan schedule are only scanned during their time windows
+				if v.NeedSync() && !v.IsScanning() && v.InScanWindow(time.Now()) {
 					select {
 					case m.syncChan <- id:
 						m.mirrors[id].scanning = true
@@ -332,8 +391,10 @@ func (m *monitor) syncMirrorList(mirrorsIDs ...int) error {
 			m.mirrors[mir.ID] = tmp
 		} else {
 			// Add new mirror
+			// The first checks are spread out as well
 			m.mirrors[mir.ID] = &mirror{
-				Mirror: mir,
+				Mirror:    mir,
+				nextCheck: time.Now().Add(healthCheckJitter()),
 			}
 		}
 		m.mapLock.Unlock()
@@ -370,7 +431,16 @@ func (m *monitor) healthCheckLoop() {
 			mirror = *mptr
 			m.mapLock.Unlock()
 
-			err := m.healthCheck(mirror.Mirror)
+			var err error
+			if mirror.IsForced() {
+				// The state was forced after the check was scheduled
+				log.Debugf("[%s] state forced until %s, skipping the health check", mirror.Name, mirror.ForcedUntil.Local())
+			} else if mirror.sentinelMismatch {
+				// The mirror stays down until the deep health check passes
+				log.Debugf("[%s] sentinel checksum mismatch, skipping the health check", mirror.Name)
+			} else {
+				err = m.healthCheck(mirror.Mirror)
+			}
 
 			if err == errMirrorNotScanned {
 				// Not removing the 'checking' lock is intended here so the mirror won't
@@ -381,7 +451,7 @@ func (m *monitor) healthCheckLoop() {
 			m.mapLock.Lock()
 			if mirror, ok := m.mirrors[id]; ok {
 				if !database.RedisIsLoading(err) {
-					mirror.lastCheck = time.Now().UTC()
+					mirror.scheduleHealthCheck(time.Now())
 				}
 				mirror.checking = false
 			}
@@ -403,6 +473,7 @@ func (m *monitor) syncLoop() {
 			var mir mirror
 			var mirrorPtr *mirror
 			var ok bool
+			var release func()
 
 			m.mapLock.Lock()
 			if mirrorPtr, ok = m.mirrors[id]; !ok {
@@ -445,10 +516,18 @@ func (m *monitor) syncLoop() {
 				}
 			}()
 
+			release, err = scan.Acquire(mir.Name, m.stop)
+			if err != nil {
+				goto end
+			}
+
 			err = scan.ErrNoSyncMethod
 
-			// First try to scan with rsync
-			if mir.RsyncURL != "" {
+			// First try to scan the local directory, or with rsync
+			if mir.LocalURL != "" {
+				_, err = scan.Scan(core.LOCAL, m.redis, m.cache, mir.LocalURL, id, m.stop)
+			}
+			if err != nil && err != scan.ErrScanAborted && mir.RsyncURL != "" {
 				_, err = scan.Scan(core.RSYNC, m.redis, m.cache, mir.RsyncURL, id, m.stop)
 			}
 			// If it failed or rsync wasn't supported
@@ -456,13 +535,14 @@ func (m *monitor) syncLoop() {
 			if err != nil && err != scan.ErrScanAborted && mir.FtpURL != "" {
 				_, err = scan.Scan(core.FTP, m.redis, m.cache, mir.FtpURL, id, m.stop)
 			}
+			release()
 
 			if err == scan.ErrScanInProgress {
 				log.Warningf("%-30.30s Scan already in progress", mir.Name)
 				goto end
 			}
 
-			if err == nil && mir.Enabled == true && mir.IsUp() == false {
+			if err == nil && mir.Enabled == true && mir.IsUp() == false && !mir.IsForced() {
 				m.healthCheckChan <- id
 			}
 
@@ -488,13 +568,16 @@ func (m *monitor) healthCheck(mirror mirrors.Mirror) error {
 		}
 		return err
 	}
+	// The files are indexed by their path in the repository
+	file = strings.TrimPrefix(file, GetConfig().MirrorPrefix(mirror.Name))
 
-	// Perform health check(s)
+	// Perform health check(s), the latency is the one of the HTTPS
+	// check for the mirrors serving both protocols
 	if utils.HasAnyPrefix(mirror.HttpURL, "http://", "https://") {
-		err = m.healthCheckDo(&mirror, mirror.HttpURL, file, size)
+		err = m.healthCheckDo(&mirror, mirror.HttpURL, file, size, true)
 	} else {
-		err = m.healthCheckDo(&mirror, "http://"+mirror.HttpURL, file, size)
-		err2 := m.healthCheckDo(&mirror, "https://"+mirror.HttpURL, file, size)
+		err = m.healthCheckDo(&mirror, "http://"+mirror.HttpURL, file, size, false)
+		err2 := m.healthCheckDo(&mirror, "https://"+mirror.HttpURL, file, size, true)
 		if err2 != nil {
 			err = err2
 		}
@@ -503,7 +586,7 @@ func (m *monitor) healthCheck(mirror mirrors.Mirror) error {
 	return err
 }
 
-func (m *monitor) healthCheckDo(mirror *mirrors.Mirror, url string, file string, size int64) error {
+func (m *monitor) healthCheckDo(mirror *mirrors.Mirror, url string, file string, size int64, withLatency bool) error {
 	// Get protocol
 	proto := mirrors.HTTP
 	if strings.HasPrefix(url, "https://") {
@@ -513,15 +596,28 @@ func (m *monitor) healthCheckDo(mirror *mirrors.Mirror, url string, file string,
 	// Format log output
 	format := "%-" + fmt.Sprintf("%d.%ds %-5s ", m.formatLongestID+4, m.formatLongestID+4, proto)
 
+	client, transport, err := m.mirrorClient(mirror)
+	if err != nil {
+		markErr := m.markDown(mirror, proto, "Invalid client certificate")
+		if markErr != nil {
+			log.Errorf(format+"Unable to mark mirror as down: %s", mirror.Name, markErr)
+		}
+		log.Errorf(format+"Error: %s", mirror.Name, err.Error())
+		return err
+	}
+
 	// Prepare the HTTP request
 	req, err := http.NewRequest("HEAD", strings.TrimRight(url, "/")+file, nil)
 	req.Header.Set("User-Agent", userAgent)
+	mirror.HTTPHeaders.Apply(req)
 	req.Close = true
 
 	ctx, cancel := context.WithTimeout(req.Context(), clientDeadline)
 	ctx = context.WithValue(ctx, core.ContextMirrorID, mirror.ID)
 	ctx = context.WithValue(ctx, core.ContextMirrorName, mirror.Name)
 	ctx = context.WithValue(ctx, core.ContextAllowRedirects, mirror.AllowRedirects)
+	latency := &connLatency{}
+	ctx = httptrace.WithClientTrace(ctx, latency.trace())
 	req = req.WithContext(ctx)
 	defer cancel()
 
@@ -536,7 +632,7 @@ func (m *monitor) healthCheckDo(mirror *mirrors.Mirror, url string, file string,
 
 	var contentLength string
 	var statusCode int
-	elapsed, err := m.httpDo(ctx, req, func(resp *http.Response, err error) error {
+	elapsed, err := m.httpDo(ctx, client, transport, req, func(resp *http.Response, err error) error {
 		if err != nil {
 			return err
 		}
@@ -550,6 +646,10 @@ func (m *monitor) healthCheckDo(mirror *mirrors.Mirror, url string, file string,
 		return nil
 	}
 
+	if withLatency && err == nil {
+		m.recordLatency(mirror, latency.total(), format)
+	}
+
 	if err != nil {
 		var opErr *net.OpError
 		if errors.As(err, &opErr) {
@@ -559,7 +659,7 @@ func (m *monitor) healthCheckDo(mirror *mirrors.Mirror, url string, file string,
 		if strings.Contains(err.Error(), errRedirect.Error()) {
 			reason = "Unauthorized redirect"
 		}
-		markErr := mirrors.MarkMirrorDown(m.redis, mirror.ID, proto, reason)
+		markErr := m.markDown(mirror, proto, reason)
 		if markErr != nil {
 			log.Errorf(format+"Unable to mark mirror as down: %s", mirror.Name, markErr)
 		}
@@ -580,7 +680,7 @@ func (m *monitor) healthCheckDo(mirror *mirrors.Mirror, url string, file string,
 			log.Noticef(format+"Up! (%dms)", mirror.Name, elapsed/time.Millisecond)
 		}
 	case 404:
-		err = mirrors.MarkMirrorDown(m.redis, mirror.ID, proto, fmt.Sprintf("File not found %s (error 404)", file))
+		err = m.markDown(mirror, proto, fmt.Sprintf("File not found %s (error 404)", file))
 		if err != nil {
 			log.Errorf(format+"Unable to mark mirror as down: %s", mirror.Name, err)
 		}
@@ -592,7 +692,7 @@ func (m *monitor) healthCheckDo(mirror *mirrors.Mirror, url string, file string,
 		}
 		log.Errorf(format+"Error: File %s not found (error 404)", mirror.Name, file)
 	default:
-		err = mirrors.MarkMirrorDown(m.redis, mirror.ID, proto, fmt.Sprintf("Got status code %d", statusCode))
+		err = m.markDown(mirror, proto, fmt.Sprintf("Got status code %d", statusCode))
 		if err != nil {
 			log.Errorf(format+"Unable to mark mirror as down: %s", mirror.Name, err)
 		}
@@ -601,20 +701,53 @@ func (m *monitor) healthCheckDo(mirror *mirrors.Mirror, url string, file string,
 	return nil
 }
 
-func (m *monitor) httpDo(ctx context.Context, req *http.Request, f func(*http.Response, error) error) (time.Duration, error) {
+// recordLatency merges the latency of the connection to the mirror into its
+// average
+func (m *monitor) recordLatency(mirror *mirrors.Mirror, latency time.Duration, format string) {
+	if latency <= 0 {
+		return
+	}
+	average, err := mirrors.RecordLatency(m.redis, mirror.ID, latency, time.Now(), latencyHalfLife)
+	if err != nil {
+		log.Errorf(format+"Unable to record the latency: %s", mirror.Name, err)
+		return
+	}
+	log.Debugf(format+"Latency %s (average %s)", mirror.Name, latency.Round(time.Millisecond), average.Round(time.Millisecond))
+}
+
+// mirrorClient returns the HTTP client to use for the given mirror, mirrors
+// requiring a client certificate get their own transport
+func (m *monitor) mirrorClient(mirror *mirrors.Mirror) (*http.Client, *http.Transport, error) {
+	tlsConfig, err := mirror.TLSConfig()
+	if err != nil {
+		return nil, nil, err
+	} else if tlsConfig == nil {
+		return &m.httpClient, &m.httpTransport, nil
+	}
+
+	transport := m.httpTransport.Clone()
+	transport.TLSClientConfig = tlsConfig
+	client := &http.Client{
+		CheckRedirect: checkRedirect,
+		Transport:     transport,
+	}
+	return client, transport, nil
+}
+
+func (m *monitor) httpDo(ctx context.Context, client *http.Client, transport *http.Transport, req *http.Request, f func(*http.Response, error) error) (time.Duration, error) {
 	var elapsed time.Duration
 	c := make(chan error, 1)
 
 	go func() {
 		start := time.Now()
-		err := f(m.httpClient.Do(req))
+		err := f(client.Do(req))
 		elapsed = time.Since(start)
 		c <- err
 	}()
 
 	select {
 	case <-ctx.Done():
-		m.httpTransport.CancelRequest(req)
+		transport.CancelRequest(req)
 		<-c // Wait for f to return.
 		return elapsed, ctx.Err()
 	case err := <-c:
@@ -0,0 +1,48 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/etix/mirrorbits/config"
+)
+
+func TestMirror_ScheduleHealthCheck(t *testing.T) {
+	defer SetConfiguration(GetConfig())
+
+	now := time.Now()
+	tests := map[string]struct {
+		interval int
+		jitter   int
+	}{
+		"no jitter":   {1, 0},
+		"with jitter": {1, 10},
+		"long":        {5, 120},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			SetConfiguration(&Configuration{CheckInterval: tt.interval, HealthCheckJitter: tt.jitter})
+			base := now.Add(time.Duration(tt.interval) * time.Minute)
+			max := base.Add(time.Duration(tt.jitter) * time.Second)
+
+			var m mirror
+			for i := 0; i < 100; i++ {
+				m.scheduleHealthCheck(now)
+				if m.nextCheck.Before(base) || (tt.jitter > 0 && !m.nextCheck.Before(max)) || (tt.jitter == 0 && !m.nextCheck.Equal(base)) {
+					t.Fatalf("Expected the next check in [%s, %s), got %s", base, max, m.nextCheck)
+				}
+			}
+			if m.NeedHealthCheck() {
+				t.Fatalf("Expected no health check before the interval")
+			}
+			m.nextCheck = time.Time{}
+			if !m.NeedHealthCheck() {
+				t.Fatalf("Expected a health check once due")
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// GetContext returns a redis connection from the pool bound to the given
+// context: waiting for a connection and the commands fail with the error of
+// the context once it is done, and the commands can't outlive its deadline.
+func (r *Redis) GetContext(ctx context.Context) redis.Conn {
+	select {
+	case <-r.ready:
+	default:
+		return &NotReadyError{}
+	}
+	r.checkExhaustion()
+	conn, err := getFromPool(ctx, r.pool)
+	if err != nil {
+		return conn
+	}
+	return &contextConn{Conn: conn, ctx: ctx}
+}
+
+// getFromPool returns a connection from the given pool, waiting for it at
+// most until the context is done
+func getFromPool(ctx context.Context, pool redisPool) (redis.Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return &errorConn{err}, err
+	}
+	if p, ok := pool.(*redis.Pool); ok {
+		return p.GetContext(ctx)
+	}
+	return pool.Get(), nil
+}
+
+// contextConn is a connection whose commands are bound to a context
+type contextConn struct {
+	redis.Conn
+	ctx context.Context
+}
+
+// Do sends the command, it fails once the context is done
+func (c *contextConn) Do(commandName string, args ...any) (any, error) {
+	deadline, ok := c.ctx.Deadline()
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	} else if !ok {
+		return c.Conn.Do(commandName, args...)
+	}
+	timeout := time.Until(deadline)
+	if timeout <= 0 {
+		return nil, context.DeadlineExceeded
+	}
+	reply, err := doWithTimeout(c.Conn, timeout, commandName, args...)
+	if err != nil && c.ctx.Err() != nil {
+		// Report the deadline rather than the read timeout
+		return nil, c.ctx.Err()
+	}
+	return reply, err
+}
+
+// Receive returns the next pending reply, it fails once the context is done
+func (c *contextConn) Receive() (any, error) {
+	deadline, ok := c.ctx.Deadline()
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	} else if !ok {
+		return c.Conn.Receive()
+	}
+	timeout := time.Until(deadline)
+	if timeout <= 0 {
+		return nil, context.DeadlineExceeded
+	}
+	reply, err := receiveWithTimeout(c.Conn, timeout)
+	if err != nil && c.ctx.Err() != nil {
+		return nil, c.ctx.Err()
+	}
+	return reply, err
+}
+
+// doWithTimeout sends the command with the given read timeout, or without
+// any timeout if the connection doesn't support it
+func doWithTimeout(c redis.Conn, timeout time.Duration, commandName string, args ...any) (any, error) {
+	if cwt, ok := c.(redis.ConnWithTimeout); ok {
+		return cwt.DoWithTimeout(timeout, commandName, args...)
+	}
+	return c.Do(commandName, args...)
+}
+
+// receiveWithTimeout returns the next pending reply with the given read
+// timeout, or without any timeout if the connection doesn't support it
+func receiveWithTimeout(c redis.Conn, timeout time.Duration) (any, error) {
+	if cwt, ok := c.(redis.ConnWithTimeout); ok {
+		return cwt.ReceiveWithTimeout(timeout)
+	}
+	return c.Receive()
+}
+
+// errorConn is a connection failing with the given error
+type errorConn struct {
+	err error
+}
+
+func (e *errorConn) Close() error                   { return nil }
+func (e *errorConn) Err() error                     { return e.err }
+func (e *errorConn) Do(string, ...any) (any, error) { return nil, e.err }
+func (e *errorConn) Send(string, ...any) error      { return e.err }
+func (e *errorConn) Flush() error                   { return e.err }
+func (e *errorConn) Receive() (any, error)          { return nil, e.err }
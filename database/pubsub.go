@@ -47,6 +47,17 @@ func NewPubsub(r *Redis) *Pubsub {
 	return pubsub
 }
 
+// reconnect forces the pubsub to reconnect to the redis master
+func (p *Pubsub) reconnect() {
+	p.connlock.Lock()
+	defer p.connlock.Unlock()
+	if p.rconn != nil {
+		// Unsubscribing from all the channels makes the loop reconnect
+		p.rconn.Send("UNSUBSCRIBE")
+		p.rconn.Flush()
+	}
+}
+
 // Close all the connections to the pubsub server
 func (p *Pubsub) Close() {
 	close(p.stop)
@@ -84,7 +95,7 @@ connect:
 		default:
 		}
 		p.connlock.Lock()
-		p.rconn = p.r.Get()
+		p.rconn = p.r.getPubsub()
 		if _, err := p.rconn.Do("PING"); err != nil {
 			disconnected = true
 			p.rconn.Close()
@@ -121,6 +132,18 @@ connect:
 				p.handleMessage(v.Channel, v.Data)
 			case redis.Subscription:
 				log.Debugf("Redis subscription on channel %s: %s (%d)", v.Channel, v.Kind, v.Count)
+				if v.Kind == "unsubscribe" && v.Count == 0 {
+					select {
+					case <-p.stop:
+						return
+					default:
+					}
+					log.Notice("Pubsub reconnecting to the redis master")
+					psc.Close()
+					p.rconn.Close()
+					disconnected = true
+					goto connect
+				}
 			case error:
 				select {
 				case <-p.stop:
@@ -13,6 +13,7 @@ import (
 
 	. "github.com/etix/mirrorbits/config"
 	"github.com/etix/mirrorbits/core"
+	"github.com/etix/mirrorbits/metrics"
 	"github.com/gomodule/redigo/redis"
 	"github.com/rafaeljusto/redigomock"
 )
@@ -20,6 +21,9 @@ import (
 const (
 	redisConnectionTimeout = 200 * time.Millisecond
 	redisReadWriteTimeout  = 300 * time.Second
+	redisExhaustedLogDelay = time.Minute
+
+	redisSentinelCheckInterval = 5 * time.Second
 )
 
 var (
@@ -34,7 +38,12 @@ type redisPool interface {
 
 // Redis is the instance object of the redis database
 type Redis struct {
+	replicaRetry    int64 // first for the 64-bit alignment of atomic accesses
 	pool            redisPool
+	pubsubPool      redisPool
+	replicaPool     redisPool
+	maxActive       int
+	exhausted       exhaustionCounter
 	Pubsub          *Pubsub
 	failure         bool
 	failureState    sync.RWMutex
@@ -52,6 +61,9 @@ func NewRedis() *Redis {
 	// Asynchronous db update handler
 	go r.dbUpdateHandler()
 
+	// Follow the master when using sentinels
+	go r.watchSentinels()
+
 	return r
 }
 
@@ -70,34 +82,25 @@ func NewRedisCustomPool(pool redisPool) *Redis {
 			close(r.ready)
 		}
 		r.pool = pool
+		r.pubsubPool = pool
 	} else {
+		r.maxActive = GetConfig().RedisMaxConnections
 		r.pool = &redis.Pool{
-			MaxIdle:     10,
-			IdleTimeout: 240 * time.Second,
-			Dial: func() (redis.Conn, error) {
-				conn, err := r.Connect()
-
-				switch err {
-				case nil:
-					r.setFailureState(false)
-				default:
-					r.setFailureState(true)
-				}
-
-				if r.version != "" && ! r.IsAtLeastVersion(core.RedisMinimumVersion) {
-					log.Fatalf("Unsupported Redis version, please upgrade to Redis >= %s", core.RedisMinimumVersion)
-				}
-
-				return conn, err
-			},
-			TestOnBorrow: func(c redis.Conn, t time.Time) error {
-				_, err := c.Do("PING")
-				if RedisIsLoading(err) {
-					return nil
-				}
-				return err
-			},
+			MaxIdle:      GetConfig().RedisMaxIdleConnections,
+			MaxActive:    r.maxActive,
+			Wait:         true,
+			IdleTimeout:  time.Duration(GetConfig().RedisIdleTimeout) * time.Second,
+			Dial:         r.dial,
+			TestOnBorrow: r.testOnBorrow,
 		}
+		// The pubsub holds its connection for its whole lifetime, keep it
+		// out of the command pool so it doesn't count against its limit
+		r.pubsubPool = &redis.Pool{
+			MaxIdle:      1,
+			Dial:         r.dial,
+			TestOnBorrow: r.testOnBorrow,
+		}
+		r.replicaPool = r.newReplicaPool()
 	}
 
 	go r.connRecover()
@@ -105,6 +108,47 @@ func NewRedisCustomPool(pool redisPool) *Redis {
 	return r
 }
 
+func (r *Redis) dial() (redis.Conn, error) {
+	conn, err := r.Connect()
+
+	switch err {
+	case nil:
+		r.setFailureState(false)
+	default:
+		r.setFailureState(true)
+	}
+
+	if r.version != "" && ! r.IsAtLeastVersion(core.RedisMinimumVersion) {
+		log.Fatalf("Unsupported Redis version, please upgrade to Redis >= %s", core.RedisMinimumVersion)
+	}
+
+	return conn, err
+}
+
+func (r *Redis) testOnBorrow(c redis.Conn, t time.Time) error {
+	if len(GetConfig().RedisSentinels) == 0 {
+		_, err := c.Do("PING")
+		if RedisIsLoading(err) {
+			return nil
+		}
+		return err
+	}
+
+	// Discard the connections to a master demoted by a failover,
+	// the pool will connect to the new one through the sentinels
+	role, err := r.askRole(c)
+	if err != nil {
+		if RedisIsLoading(err) {
+			return nil
+		}
+		return err
+	}
+	if role != "master" {
+		return fmt.Errorf("redis server is not a master anymore but a %s", role)
+	}
+	return nil
+}
+
 // Get returns a redis connection from the pool
 func (r *Redis) Get() redis.Conn {
 	select {
@@ -112,9 +156,64 @@ func (r *Redis) Get() redis.Conn {
 	default:
 		return &NotReadyError{}
 	}
+	r.checkExhaustion()
 	return r.pool.Get()
 }
 
+// exhaustionCounter tracks the number of times the command pool
+// was exhausted since the last warning
+type exhaustionCounter struct {
+	sync.Mutex
+	count   int
+	lastLog time.Time
+}
+
+// checkExhaustion accounts for the requests that will have to wait for
+// a connection to be returned to the pool
+func (r *Redis) checkExhaustion() {
+	if r.maxActive <= 0 {
+		return
+	}
+	pool, ok := r.pool.(*redis.Pool)
+	if !ok {
+		return
+	}
+	stats := pool.Stats()
+	if stats.ActiveCount < r.maxActive || stats.IdleCount > 0 {
+		return
+	}
+
+	metrics.RedisPoolExhausted.Inc()
+
+	r.exhausted.Lock()
+	defer r.exhausted.Unlock()
+	r.exhausted.count++
+	if time.Since(r.exhausted.lastLog) >= redisExhaustedLogDelay {
+		log.Warningf("Redis connection pool exhausted %d times (%d connections), consider raising RedisMaxConnections", r.exhausted.count, r.maxActive)
+		r.exhausted.count = 0
+		r.exhausted.lastLog = time.Now()
+	}
+}
+
+// PoolStats returns the statistics of the command pool, they are
+// always zero when using a custom pool
+func (r *Redis) PoolStats() redis.PoolStats {
+	if pool, ok := r.pool.(*redis.Pool); ok {
+		return pool.Stats()
+	}
+	return redis.PoolStats{}
+}
+
+// getPubsub returns a connection dedicated to the pubsub
+func (r *Redis) getPubsub() redis.Conn {
+	select {
+	case <-r.ready:
+	default:
+		return &NotReadyError{}
+	}
+	return r.pubsubPool.Get()
+}
+
 // UnblockedGet returns a redis connection from the pool even
 // if the database checks and/or upgrade are not finished.
 func (r *Redis) UnblockedGet() redis.Conn {
@@ -129,6 +228,12 @@ func (r *Redis) Close() {
 	default:
 		log.Debug("Closing databases connections")
 		r.Pubsub.Close()
+		if r.pubsubPool != r.pool {
+			r.pubsubPool.Close()
+		}
+		if r.replicaPool != nil {
+			r.replicaPool.Close()
+		}
 		r.pool.Close()
 		close(r.stop)
 	}
@@ -170,74 +275,48 @@ func (r *Redis) Connect() (redis.Conn, error) {
 		}
 
 		for _, s := range sentinels {
-			log.Debugf("Connecting to redis sentinel %s", s.Host)
-			var master []string
-			var masterhost string
-			var cm redis.Conn
-
-			c, err := r.connectTo(s.Host)
+			masterhost, err := r.sentinelMaster(s.Host)
 			if err != nil {
 				r.logError("Sentinel: %s", err.Error())
 				continue
 			}
-			//AUTH?
-			role, err := r.askRole(c)
-			if err != nil {
-				r.logError("Sentinel: %s", err.Error())
-				goto closeSentinel
-			}
-			if role != "sentinel" {
-				r.logError("Sentinel: %s is not a sentinel but a %s", s.Host, role)
-				goto closeSentinel
-			}
-
-			master, err = redis.Strings(c.Do("SENTINEL", "get-master-addr-by-name", GetConfig().RedisSentinelMasterName))
-			if err == redis.ErrNil {
-				r.logError("Sentinel: %s doesn't know the master-name %s", s.Host, GetConfig().RedisSentinelMasterName)
-				goto closeSentinel
-			} else if err != nil {
-				r.logError("Sentinel: %s", err.Error())
-				goto closeSentinel
-			}
-
-			masterhost = fmt.Sprintf("%s:%s", master[0], master[1])
 
-			cm, err = r.connectTo(masterhost)
+			cm, err := r.connectTo(masterhost)
 			if err != nil {
 				r.logError("Redis master: %s", err.Error())
-				goto closeSentinel
+				continue
 			}
 
 			if r.auth(cm) != nil {
 				r.logError("Redis master: auth failed")
-				goto closeMaster
+				cm.Close()
+				continue
 			}
 			if err = r.selectDB(cm); err != nil {
-				c.Close()
+				cm.Close()
 				return nil, err
 			}
 
-			role, err = r.askRole(cm)
+			role, err := r.askRole(cm)
 			if err != nil {
 				r.logError("Redis master: %s", err.Error())
-				goto closeMaster
+				cm.Close()
+				continue
 			}
 			if role != "master" {
 				r.logError("Redis master: %s is not a master but a %s", masterhost, role)
-				goto closeMaster
+				cm.Close()
+				continue
 			}
 
-			// Close the connection to the sentinel
-			c.Close()
+			r.version, err = r.askVersion(cm)
+			if err != nil {
+				cm.Close()
+				return nil, err
+			}
 
 			r.printConnectedMaster(masterhost)
 			return cm, nil
-
-		closeMaster:
-			cm.Close()
-
-		closeSentinel:
-			c.Close()
 		}
 	}
 
@@ -284,6 +363,76 @@ single:
 
 }
 
+// sentinelMaster asks the given sentinel for the address of the master
+func (r *Redis) sentinelMaster(sentinel string) (string, error) {
+	log.Debugf("Connecting to redis sentinel %s", sentinel)
+
+	c, err := r.connectTo(sentinel)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+
+	//AUTH?
+	role, err := r.askRole(c)
+	if err != nil {
+		return "", err
+	}
+	if role != "sentinel" {
+		return "", fmt.Errorf("%s is not a sentinel but a %s", sentinel, role)
+	}
+
+	master, err := redis.Strings(c.Do("SENTINEL", "get-master-addr-by-name", GetConfig().RedisSentinelMasterName))
+	if err == redis.ErrNil {
+		return "", fmt.Errorf("%s doesn't know the master-name %s", sentinel, GetConfig().RedisSentinelMasterName)
+	} else if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s:%s", master[0], master[1]), nil
+}
+
+// watchSentinels periodically asks the sentinels for the current master
+// and forces the pubsub to reconnect after a failover
+func (r *Redis) watchSentinels() {
+	ticker := time.NewTicker(redisSentinelCheckInterval)
+	defer ticker.Stop()
+
+	var current string
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+		}
+
+		if len(GetConfig().RedisSentinels) == 0 || len(GetConfig().RedisSentinelMasterName) == 0 {
+			current = ""
+			continue
+		}
+
+		var master string
+		for _, s := range GetConfig().RedisSentinels {
+			var err error
+			if master, err = r.sentinelMaster(s.Host); err == nil {
+				break
+			}
+			log.Debugf("Sentinel: %s", err.Error())
+		}
+		if master == "" {
+			continue
+		}
+
+		if current != "" && master != current {
+			log.Warningf("Redis master switched from %s to %s", current, master)
+			if r.Pubsub != nil {
+				r.Pubsub.reconnect()
+			}
+		}
+		current = master
+	}
+}
+
 func (r *Redis) connectTo(address string) (redis.Conn, error) {
 	return redis.Dial("tcp", address,
 		redis.DialConnectTimeout(redisConnectionTimeout),
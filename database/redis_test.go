@@ -4,10 +4,23 @@
 package database
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"testing"
+	"time"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
 )
 
+func TestMain(m *testing.M) {
+	SetConfiguration(&Configuration{})
+	m.Run()
+}
+
 func TestIsAtLeastVersion(t *testing.T) {
 	testsFalse := [] struct {
 		have string
@@ -50,3 +63,163 @@ func TestIsAtLeastVersion(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckExhaustion(t *testing.T) {
+	pool := &redis.Pool{
+		MaxActive: 1,
+		Dial: func() (redis.Conn, error) {
+			return redigomock.NewConn(), nil
+		},
+	}
+	r := Redis{
+		pool:      pool,
+		maxActive: 1,
+	}
+
+	r.checkExhaustion()
+	if !r.exhausted.lastLog.IsZero() {
+		t.Fatalf("The pool should not be exhausted yet")
+	}
+
+	conn := pool.Get()
+	defer conn.Close()
+
+	// The first exhaustion is reported right away
+	r.checkExhaustion()
+	if r.exhausted.lastLog.IsZero() || r.exhausted.count != 0 {
+		t.Fatalf("Expected the exhaustion to be reported, got count %d", r.exhausted.count)
+	}
+
+	// The following ones are accumulated until the next report
+	r.checkExhaustion()
+	if r.exhausted.count != 1 {
+		t.Fatalf("Expected count 1, got %d", r.exhausted.count)
+	}
+
+	if stats := r.PoolStats(); stats.ActiveCount != 1 || stats.IdleCount != 0 {
+		t.Fatalf("Unexpected pool stats %+v", stats)
+	}
+}
+
+// brokenConn is a connection failing on a network error
+type brokenConn struct {
+	*redigomock.Conn
+}
+
+func (c brokenConn) Do(string, ...any) (any, error) { return nil, io.EOF }
+func (c brokenConn) Err() error                      { return io.EOF }
+func (c brokenConn) Receive() (any, error)           { return nil, io.EOF }
+
+func TestGetReplica(t *testing.T) {
+	defer SetConfiguration(GetConfig())
+	SetConfiguration(&Configuration{RedisReplicaAddress: "replica:6379"})
+
+	primary := redigomock.NewConn()
+	primary.Command("GET", "key").Expect("primary")
+
+	var dials int
+	var dialErr error
+	r := &Redis{
+		pool:  &redis.Pool{Dial: func() (redis.Conn, error) { return primary, nil }},
+		ready: make(chan struct{}),
+	}
+	close(r.ready)
+	r.replicaPool = &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			dials++
+			if dialErr != nil {
+				return nil, dialErr
+			}
+			return brokenConn{redigomock.NewConn()}, nil
+		},
+	}
+
+	get := func() string {
+		conn := r.GetReplica()
+		defer conn.Close()
+		reply, err := redis.String(conn.Do("GET", "key"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		return reply
+	}
+
+	// A connection broken on the replica is replaced by the primary
+	if reply := get(); reply != "primary" || dials != 1 {
+		t.Fatalf("Expected the reply of the primary after 1 dial, got %q after %d", reply, dials)
+	}
+
+	// The replica is skipped for a while after failing
+	if reply := get(); reply != "primary" || dials != 1 {
+		t.Fatalf("Expected the replica to be skipped, got %q after %d dials", reply, dials)
+	}
+
+	// An unreachable replica is replaced by the primary
+	r.replicaRetry = 0
+	dialErr = errors.New("connection refused")
+	if reply := get(); reply != "primary" || dials != 2 {
+		t.Fatalf("Expected the reply of the primary after 2 dials, got %q after %d", reply, dials)
+	}
+}
+
+func TestGetReplicaPipeline(t *testing.T) {
+	defer SetConfiguration(GetConfig())
+	SetConfiguration(&Configuration{RedisReplicaAddress: "replica:6379"})
+
+	primary := redigomock.NewConn()
+	primary.Command("GET", "a").Expect("1")
+	primary.Command("GET", "b").Expect("2")
+
+	r := &Redis{
+		pool:  &redis.Pool{Dial: func() (redis.Conn, error) { return primary, nil }},
+		ready: make(chan struct{}),
+	}
+	close(r.ready)
+	r.replicaPool = &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return brokenConn{redigomock.NewConn()}, nil
+		},
+	}
+
+	conn := r.GetReplica()
+	defer conn.Close()
+
+	// The commands pipelined on a broken replica are sent to the primary
+	conn.Send("GET", "a")
+	conn.Send("GET", "b")
+	if err := conn.Flush(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, expected := range []string{"1", "2"} {
+		reply, err := redis.String(conn.Receive())
+		if err != nil || reply != expected {
+			t.Fatalf("Expected %q, got %q (%v)", expected, reply, err)
+		}
+	}
+}
+
+func TestGetContext(t *testing.T) {
+	mock := redigomock.NewConn()
+	mock.Command("GET", "key").Expect("value")
+
+	r := &Redis{
+		pool:  &redis.Pool{Dial: func() (redis.Conn, error) { return mock, nil }},
+		ready: make(chan struct{}),
+	}
+	close(r.ready)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	conn := r.GetContext(ctx)
+	defer conn.Close()
+	if reply, err := redis.String(conn.Do("GET", "key")); err != nil || reply != "value" {
+		t.Fatalf("Expected value, got %q (%v)", reply, err)
+	}
+
+	cancel()
+	if _, err := conn.Do("GET", "key"); err != context.Canceled {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if conn := r.GetContext(ctx); conn.Err() != context.Canceled {
+		t.Fatalf("Expected context.Canceled, got %v", conn.Err())
+	}
+}
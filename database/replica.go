@@ -0,0 +1,240 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package database
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	// redisReplicaRetryDelay is the time during which an unreachable
+	// replica is skipped before trying to connect to it again
+	redisReplicaRetryDelay = 5 * time.Second
+)
+
+// newReplicaPool returns the pool of connections to the read-only replica
+func (r *Redis) newReplicaPool() *redis.Pool {
+	return &redis.Pool{
+		MaxIdle:     GetConfig().RedisMaxIdleConnections,
+		MaxActive:   r.maxActive,
+		Wait:        true,
+		IdleTimeout: time.Duration(GetConfig().RedisIdleTimeout) * time.Second,
+		Dial:        r.dialReplica,
+		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			// A replica loading its dataset must not be used
+			_, err := c.Do("PING")
+			return err
+		},
+	}
+}
+
+func (r *Redis) dialReplica() (redis.Conn, error) {
+	address := GetConfig().RedisReplicaAddress
+	if address == "" {
+		return nil, ErrUnreachable
+	}
+	c, err := r.connectTo(address)
+	if err != nil {
+		return nil, err
+	}
+	if err = r.auth(c); err != nil {
+		c.Close()
+		return nil, err
+	}
+	if err = r.selectDB(c); err != nil {
+		c.Close()
+		return nil, err
+	}
+	log.Debugf("Connected to redis replica %s", address)
+	return c, nil
+}
+
+// GetReplica returns a connection for read-only requests, they are sent to
+// the RedisReplicaAddress if any and to the primary otherwise. The primary is
+// also used while the replica is unreachable, and for the requests failing
+// on a connection error on the replica.
+//
+// The replica lags behind the primary: the data read through this connection
+// may not include the latest writes yet.
+func (r *Redis) GetReplica() redis.Conn {
+	return r.GetReplicaContext(context.Background())
+}
+
+// GetReplicaContext is GetReplica, the connection being bound to the given
+// context as with GetContext
+func (r *Redis) GetReplicaContext(ctx context.Context) redis.Conn {
+	if r.replicaPool == nil || GetConfig().RedisReplicaAddress == "" {
+		return r.GetContext(ctx)
+	}
+	select {
+	case <-r.ready:
+	default:
+		return &NotReadyError{}
+	}
+	if time.Now().UnixNano() < atomic.LoadInt64(&r.replicaRetry) {
+		return r.GetContext(ctx)
+	}
+	conn, err := getFromPool(ctx, r.replicaPool)
+	if err != nil && ctx.Err() != nil {
+		return conn
+	}
+	if err == nil {
+		err = conn.Err()
+	}
+	if err != nil {
+		conn.Close()
+		r.replicaFailed(err)
+		return r.GetContext(ctx)
+	}
+	return &contextConn{Conn: &replicaConn{Conn: conn, r: r, ctx: ctx}, ctx: ctx}
+}
+
+// replicaFailed skips the replica for redisReplicaRetryDelay
+func (r *Redis) replicaFailed(err error) {
+	retry := time.Now().Add(redisReplicaRetryDelay).UnixNano()
+	if atomic.SwapInt64(&r.replicaRetry, retry) < time.Now().UnixNano() {
+		log.Warningf("Redis replica unreachable, using the primary: %s", err)
+	}
+}
+
+// replicaConn is a connection to the replica whose commands are sent to the
+// primary once the replica failed. The pipelined commands not replied yet are
+// kept to be sent again to the primary.
+type replicaConn struct {
+	redis.Conn
+	r       *Redis
+	ctx     context.Context
+	primary redis.Conn
+	pending []command
+}
+
+// command is a command pipelined on the replica
+type command struct {
+	name string
+	args []any
+}
+
+// Do sends the command to the replica, or to the primary if the connection
+// to the replica is broken
+func (c *replicaConn) Do(commandName string, args ...any) (any, error) {
+	return c.do(func(conn redis.Conn) (any, error) {
+		return conn.Do(commandName, args...)
+	})
+}
+
+// DoWithTimeout is Do with a read timeout, which includes the time spent
+// on the replica if it failed
+func (c *replicaConn) DoWithTimeout(timeout time.Duration, commandName string, args ...any) (any, error) {
+	deadline := time.Now().Add(timeout)
+	return c.do(func(conn redis.Conn) (any, error) {
+		return doWithTimeout(conn, time.Until(deadline), commandName, args...)
+	})
+}
+
+// Send pipelines the command on the replica, or on the primary if the
+// connection to the replica is broken
+func (c *replicaConn) Send(commandName string, args ...any) error {
+	if c.primary != nil {
+		return c.primary.Send(commandName, args...)
+	}
+	err := c.Conn.Send(commandName, args...)
+	if c.broken(err) {
+		c.failover(err)
+		return c.primary.Send(commandName, args...)
+	}
+	if err == nil {
+		c.pending = append(c.pending, command{commandName, args})
+	}
+	return err
+}
+
+// Flush sends the pipelined commands
+func (c *replicaConn) Flush() error {
+	if c.primary != nil {
+		return c.primary.Flush()
+	}
+	err := c.Conn.Flush()
+	if c.broken(err) {
+		c.failover(err)
+		return c.primary.Flush()
+	}
+	return err
+}
+
+// Receive returns the next pending reply
+func (c *replicaConn) Receive() (any, error) {
+	return c.receive(func(conn redis.Conn) (any, error) {
+		return conn.Receive()
+	})
+}
+
+// ReceiveWithTimeout returns the next pending reply with a read timeout
+func (c *replicaConn) ReceiveWithTimeout(timeout time.Duration) (any, error) {
+	deadline := time.Now().Add(timeout)
+	return c.receive(func(conn redis.Conn) (any, error) {
+		return receiveWithTimeout(conn, time.Until(deadline))
+	})
+}
+
+func (c *replicaConn) do(cmd func(redis.Conn) (any, error)) (any, error) {
+	if c.primary != nil {
+		return cmd(c.primary)
+	}
+	reply, err := cmd(c.Conn)
+	if !c.broken(err) {
+		// Do receives the replies of all the pipelined commands
+		c.pending = nil
+		return reply, err
+	}
+	c.failover(err)
+	return cmd(c.primary)
+}
+
+func (c *replicaConn) receive(recv func(redis.Conn) (any, error)) (any, error) {
+	if c.primary != nil {
+		return recv(c.primary)
+	}
+	reply, err := recv(c.Conn)
+	if !c.broken(err) {
+		if len(c.pending) > 0 {
+			c.pending = c.pending[1:]
+		}
+		return reply, err
+	}
+	c.failover(err)
+	if err = c.primary.Flush(); err != nil {
+		return nil, err
+	}
+	return recv(c.primary)
+}
+
+// broken returns true if the command failed on a broken connection to the
+// replica rather than on an error reply or on the context
+func (c *replicaConn) broken(err error) bool {
+	return err != nil && c.Conn.Err() != nil && c.ctx.Err() == nil
+}
+
+// failover switches to the primary, the commands pipelined on the replica
+// and not replied yet are sent to it again
+func (c *replicaConn) failover(err error) {
+	c.r.replicaFailed(err)
+	c.primary = c.r.GetContext(c.ctx)
+	for _, cmd := range c.pending {
+		c.primary.Send(cmd.name, cmd.args...)
+	}
+	c.pending = nil
+}
+
+// Close releases the connections to the replica and to the primary
+func (c *replicaConn) Close() error {
+	if c.primary != nil {
+		c.primary.Close()
+	}
+	return c.Conn.Close()
+}
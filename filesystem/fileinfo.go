@@ -15,6 +15,7 @@ type FileInfo struct {
 	ModTime time.Time `redis:"modTime" json:",omitempty"`
 	Sha1    string    `redis:"sha1" json:",omitempty"`
 	Sha256  string    `redis:"sha256" json:",omitempty"`
+	Sha512  string    `redis:"sha512" json:",omitempty"`
 	Md5     string    `redis:"md5" json:",omitempty"`
 }
 
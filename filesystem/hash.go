@@ -8,6 +8,7 @@ import (
 	"crypto/md5"
 	"crypto/sha1"
 	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/hex"
 	"hash"
 	"io"
@@ -38,6 +39,11 @@ func HashFile(path string) (hashes FileInfo, err error) {
 		defer hsha256.Close()
 		writers = append(writers, hsha256)
 	}
+	if GetConfig().Hashes.SHA512 {
+		hsha512 := newHasher(sha512.New(), &hashes.Sha512)
+		defer hsha512.Close()
+		writers = append(writers, hsha512)
+	}
 	if GetConfig().Hashes.MD5 {
 		hmd5 := newHasher(md5.New(), &hashes.Md5)
 		defer hmd5.Close()
@@ -0,0 +1,40 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package filesystem
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"io"
+	"os"
+)
+
+// PieceHashes returns the concatenated SHA-1 hashes of the consecutive
+// pieces of pieceLength bytes of the given file, the last piece being
+// possibly shorter. This is the pieces field of a BitTorrent info
+// dictionary.
+func PieceHashes(path string, pieceLength int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+
+	var pieces []byte
+	h := sha1.New()
+	for {
+		h.Reset()
+		n, err := io.CopyN(h, reader, pieceLength)
+		if n > 0 {
+			pieces = h.Sum(pieces)
+		}
+		if err == io.EOF {
+			return pieces, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package filesystem
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPieceHashes(t *testing.T) {
+	// SHA-1 of "abc" and "d"
+	abc := "a9993e364706816aba3e25717850c26c9cd0d89d"
+	d := "3c363836cf4e16666669a25da280a1865c2d2874"
+
+	tests := map[string]struct {
+		content     string
+		pieceLength int64
+		expected    string
+	}{
+		"empty":         {"", 3, ""},
+		"single piece":  {"abc", 16, abc},
+		"exact pieces":  {"abcabc", 3, abc + abc},
+		"shorter piece": {"abcd", 3, abc + d},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "file")
+			if err := os.WriteFile(path, []byte(test.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			pieces, err := PieceHashes(path, test.pieceLength)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if got := hex.EncodeToString(pieces); got != test.expected {
+				t.Fatalf("Expected %s, got %s", test.expected, got)
+			}
+		})
+	}
+
+	if _, err := PieceHashes(filepath.Join(t.TempDir(), "missing"), 3); err == nil {
+		t.Fatalf("Expected an error for a missing file")
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package filesystem
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"math"
+	"os"
+
+	"golang.org/x/crypto/md4"
+)
+
+// ZsyncHashLengths returns the number of consecutive blocks to match and the
+// number of bytes kept from the rolling and the MD4 checksums of each block,
+// following the heuristics of zsyncmake for a file of the given length
+func ZsyncHashLengths(length int64, blockSize int) (seqMatches, rsumBytes, checksumBytes int) {
+	if length < 1 {
+		length = 1
+	}
+	seqMatches = 1
+	if length > int64(blockSize) {
+		seqMatches = 2
+	}
+	l := float64(length)
+	blocks := float64(length / int64(blockSize))
+
+	rsumBytes = int(math.Ceil(((math.Log(l)+math.Log(float64(blockSize)))/math.Log(2) - 8.6) / float64(seqMatches) / 8))
+	if rsumBytes > 4 {
+		rsumBytes = 4
+	}
+	if rsumBytes < 2 {
+		rsumBytes = 2
+	}
+
+	checksumBytes = int(math.Ceil((20 + (math.Log(l)+math.Log(1+blocks))/math.Log(2)) / float64(seqMatches) / 8))
+	if min := int((7.9 + (20 + math.Log(1+blocks)/math.Log(2))) / 8); checksumBytes < min {
+		checksumBytes = min
+	}
+	if checksumBytes > 16 {
+		checksumBytes = 16
+	}
+	return
+}
+
+// ZsyncBlockSums returns the checksums of the blocks of the given file as
+// stored in a zsync control file, along with the SHA-1 of the whole file.
+// The last block is padded with zeros.
+func ZsyncBlockSums(path string, blockSize int, length int64) (sums []byte, sha1sum string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+
+	_, rsumBytes, checksumBytes := ZsyncHashLengths(length, blockSize)
+
+	sh := sha1.New()
+	reader := bufio.NewReader(io.TeeReader(f, sh))
+	block := make([]byte, blockSize)
+	md := md4.New()
+	for {
+		n, err := io.ReadFull(reader, block)
+		if n == 0 && (err == io.EOF || err == io.ErrUnexpectedEOF) {
+			break
+		} else if err != nil && err != io.ErrUnexpectedEOF {
+			return nil, "", err
+		}
+		for i := n; i < blockSize; i++ {
+			block[i] = 0
+		}
+
+		a, b := zsyncRsum(block)
+		rsum := []byte{byte(a >> 8), byte(a), byte(b >> 8), byte(b)}
+		sums = append(sums, rsum[4-rsumBytes:]...)
+
+		md.Reset()
+		md.Write(block)
+		sums = append(sums, md.Sum(nil)[:checksumBytes]...)
+
+		if n < blockSize {
+			break
+		}
+	}
+	return sums, hex.EncodeToString(sh.Sum(nil)), nil
+}
+
+// zsyncRsum returns the rolling checksum of a block
+func zsyncRsum(block []byte) (a, b uint16) {
+	l := len(block)
+	for i, c := range block {
+		a += uint16(c)
+		b += uint16(l-i) * uint16(c)
+	}
+	return
+}
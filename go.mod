@@ -2,13 +2,14 @@ module github.com/etix/mirrorbits
 
 require (
 	github.com/coreos/go-systemd v0.0.0-20190719114852-fd7a80b32e1f
-	github.com/etix/goftp v0.0.0-20170217140226-0c13163a1028
 	github.com/golang/protobuf v1.3.2
 	github.com/gomodule/redigo v0.0.0-20181026001555-e8fc0692a7e2
+	github.com/jlaffaye/ftp v0.2.0
 	github.com/op/go-logging v0.0.0-20160315200505-970db520ece7
 	github.com/oschwald/maxminddb-golang v1.5.0
 	github.com/rafaeljusto/redigomock v0.0.0-20190202135759-257e089e14a1
 	github.com/youtube/vitess v0.0.0-20181105031612-54855ec7b369
+	golang.org/x/crypto v0.0.0-20190911031432-227b76d455e7
 	golang.org/x/net v0.0.0-20190912160710-24e19bdeb0f2
 	golang.org/x/term v0.1.0
 	google.golang.org/grpc v1.27.1
@@ -17,8 +18,9 @@ require (
 )
 
 require (
+	github.com/hashicorp/errwrap v1.0.0 // indirect
+	github.com/hashicorp/go-multierror v1.1.1 // indirect
 	github.com/kr/pretty v0.1.0 // indirect
-	github.com/stretchr/testify v1.4.0 // indirect
 	golang.org/x/sys v0.0.0-20210615035016-665e8c7367d1 // indirect
 	golang.org/x/text v0.3.2 // indirect
 	google.golang.org/genproto v0.0.0-20190911173649-1774047e7e51 // indirect
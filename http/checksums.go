@@ -0,0 +1,152 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/etix/mirrorbits/filesystem"
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	// checksumsBatch is the number of files whose hashes are fetched at
+	// once from the database while streaming a checksums manifest
+	checksumsBatch = 1000
+)
+
+// checksumsHandler streams the manifest of the hashes of the files under the
+// requested directory, as written by the sha256sum family of tools. The paths
+// are relative to the directory, the files are listed directory by directory
+// in lexical order and the ones not hashed yet are omitted.
+func (h *HTTP) checksumsHandler(w http.ResponseWriter, r *http.Request, ctx *Context) {
+
+	// Sanitize path
+	urlPath, err := normalizePath(r.URL.Path)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	urlPath, err = evaluatePath(ctx.vhost, urlPath)
+	if err != nil {
+		if err == filesystem.ErrOutsideRepo {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	if fi, err := os.Stat(GetConfig().Repository + urlPath); err != nil || !fi.IsDir() {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	if status := pathStatus(urlPath); status != http.StatusOK {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	var enabled bool
+	hash := strings.ToLower(ctx.QueryParam("checksums"))
+	switch hash {
+	case "md5":
+		enabled = GetConfig().Hashes.MD5
+	case "sha1":
+		enabled = GetConfig().Hashes.SHA1
+	case "", "sha256":
+		hash, enabled = "sha256", GetConfig().Hashes.SHA256
+	case "sha512":
+		enabled = GetConfig().Hashes.SHA512
+	}
+	if !enabled {
+		http.Error(w, "Hash type not supported", http.StatusNotFound)
+		return
+	}
+
+	conn := h.redis.GetReplicaContext(ctx.Context())
+	defer conn.Close()
+
+	// The local repository is the index of the directory, the cost of a
+	// manifest is bound to the size of the directory and not the archive
+	prefix := strings.TrimSuffix(urlPath, "/") + "/"
+	root := GetConfig().Repository + prefix
+	files := make([]string, 0, checksumsBatch)
+	started := false
+
+	flush := func() error {
+		hashes, err := checksumsOf(conn, files, hash)
+		if err != nil {
+			return err
+		}
+		if !started {
+			w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		for i, file := range files {
+			if hashes[i] == "" || pathStatus(file) != http.StatusOK {
+				continue
+			}
+			fmt.Fprintf(w, "%s  %s\n", hashes[i], file[len(prefix):])
+		}
+		files = files[:0]
+		return nil
+	}
+
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || d.Type()&fs.ModeSymlink != 0 {
+			// Unreadable entries are skipped like during the scans
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		files = append(files, prefix+filepath.ToSlash(rel))
+		if len(files) < checksumsBatch {
+			return nil
+		}
+		return flush()
+	})
+	if err == nil && (len(files) > 0 || !started) {
+		err = flush()
+	}
+	if err != nil {
+		if started {
+			// The status is already sent, the client gets a truncated manifest
+			log.Errorf("Checksums manifest of %s interrupted: %s", urlPath, err)
+			return
+		}
+		log.Errorf("Error while fetching the checksums of %s: %s", urlPath, err)
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+	}
+}
+
+// checksumsOf returns the hashes of the given type of the files, an empty
+// string stands for a file not hashed yet
+func checksumsOf(conn redis.Conn, files []string, hash string) ([]string, error) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	conn.Send("MULTI")
+	for _, file := range files {
+		conn.Send("HGET", fmt.Sprintf("FILE_%s", file), hash)
+	}
+	hashes, err := redis.Strings(conn.Do("EXEC"))
+	if err != nil {
+		return nil, err
+	}
+	if len(hashes) != len(files) {
+		return nil, fmt.Errorf("expected %d hashes, got %d", len(files), len(hashes))
+	}
+	return hashes, nil
+}
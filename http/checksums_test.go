@@ -0,0 +1,112 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	. "github.com/etix/mirrorbits/config"
+)
+
+func TestChecksumsHandler(t *testing.T) {
+	ctx, err := prepareTest(t, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.MkdirAll(ctx.RepoDir+"/dir/sub", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	config := *GetConfig()
+	config.Hashes.SHA256 = true
+	config.PathBlocklist = []string{"/dir/hidden.iso"}
+	SetConfiguration(&config)
+
+	// The files are looked up in the local repository, not in the archive
+	files := []string{"/dir/a.iso", "/dir/hidden.iso", "/dir/sub/b.iso", "/dir/unhashed.iso"}
+	for _, file := range files {
+		if err = makeEmptyFile(ctx.RepoDir, file); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = os.Symlink(ctx.RepoDir+"/dir/a.iso", ctx.RepoDir+"/dir/link.iso"); err != nil {
+		t.Fatal(err)
+	}
+	ctx.MockedConn.Command("MULTI")
+	for _, file := range files {
+		ctx.MockedConn.Command("HGET", "FILE_"+file, "sha256")
+	}
+	ctx.MockedConn.Command("EXEC").Expect([]any{[]byte("aaaa"), []byte("cccc"), []byte("bbbb"), nil})
+
+	resp := doRequest(ctx.Server, "GET", "/dir/?checksums=sha256", map[string]string{})
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain") {
+		t.Fatalf("Expected a text/plain 200, got: %s", dump(resp))
+	}
+	if want := "aaaa  a.iso\nbbbb  sub/b.iso\n"; string(body) != want {
+		t.Fatalf("Expected %q, got %q", want, body)
+	}
+
+	// Disabled hash type
+	resp = doRequest(ctx.Server, "GET", "/dir/?checksums=md5", map[string]string{})
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("Expected a 404, got: %s", dump(resp))
+	}
+
+	// Not a directory
+	if err = makeEmptyFile(ctx.RepoDir, "/file.iso"); err != nil {
+		t.Fatal(err)
+	}
+	resp = doRequest(ctx.Server, "GET", "/file.iso?checksums", map[string]string{})
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("Expected a 404, got: %s", dump(resp))
+	}
+
+	for _, err := range getMockErrors(ctx.MockedConn) {
+		t.Errorf("Unexpected database access: %s", err)
+	}
+}
+
+func TestChecksumsHandlerLimit(t *testing.T) {
+	ctx, err := prepareTest(t, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.MkdirAll(ctx.RepoDir+"/dir", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	config := *GetConfig()
+	config.Hashes.SHA256 = true
+	config.MaxConcurrentRedirects = 1
+	SetConfiguration(&config)
+
+	// The manifests share the slots of the file requests
+	if !ctx.Server.acquireRedirect() {
+		t.Fatalf("Expected a free slot")
+	}
+	resp := doRequest(ctx.Server, "GET", "/dir/?checksums", map[string]string{})
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") != "1" {
+		t.Fatalf("Expected a 503 with Retry-After, got: %s", dump(resp))
+	}
+
+	ctx.Server.releaseRedirect()
+	resp = doRequest(ctx.Server, "GET", "/dir/?checksums", map[string]string{})
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || len(body) != 0 {
+		t.Fatalf("Expected an empty manifest, got: %s", dump(resp))
+	}
+	if n := atomic.LoadInt64(&ctx.Server.redirects); n != 0 {
+		t.Fatalf("Expected the slot to be released, got %d in flight", n)
+	}
+
+	for _, err := range getMockErrors(ctx.MockedConn) {
+		t.Errorf("Unexpected database access: %s", err)
+	}
+}
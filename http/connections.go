@@ -0,0 +1,93 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/etix/mirrorbits/mirrors"
+)
+
+// connectionTracker estimates the number of downloads in progress on each
+// mirror from the redirects sent to it. The completion of the downloads
+// can't be observed, each redirect is instead accounted for on a decaying
+// counter whose time constant is the average duration of a download. The
+// counters are local to each instance, the cap is a best-effort one.
+type connectionTracker struct {
+	sync.Mutex
+	counters map[int]*connectionCounter
+}
+
+type connectionCounter struct {
+	value float64
+	last  time.Time
+}
+
+func newConnectionTracker() *connectionTracker {
+	return &connectionTracker{
+		counters: make(map[int]*connectionCounter),
+	}
+}
+
+// decayed returns the value of the counter at the given time
+func (c *connectionCounter) decayed(now time.Time, duration time.Duration) float64 {
+	elapsed := now.Sub(c.last)
+	if elapsed <= 0 {
+		return c.value
+	}
+	return c.value * math.Exp(-float64(elapsed)/float64(duration))
+}
+
+// add accounts for a redirect to the given mirror
+func (t *connectionTracker) add(id int, now time.Time) {
+	if t == nil {
+		return
+	}
+	duration := time.Duration(GetConfig().ConnectionDuration) * time.Second
+	t.Lock()
+	defer t.Unlock()
+	c, ok := t.counters[id]
+	if !ok {
+		c = &connectionCounter{}
+		t.counters[id] = c
+	}
+	c.value = c.decayed(now, duration) + 1
+	c.last = now
+}
+
+// estimate returns the approximate number of downloads in progress on the
+// given mirror
+func (t *connectionTracker) estimate(id int, now time.Time) float64 {
+	if t == nil {
+		return 0
+	}
+	duration := time.Duration(GetConfig().ConnectionDuration) * time.Second
+	t.Lock()
+	defer t.Unlock()
+	c, ok := t.counters[id]
+	if !ok {
+		return 0
+	}
+	return c.decayed(now, duration)
+}
+
+// split separates the mirrors a new redirect would take above their
+// MaxConnections from the others
+func (t *connectionTracker) split(mlist mirrors.Mirrors, now time.Time) (available, capped mirrors.Mirrors) {
+	if t == nil {
+		return mlist, nil
+	}
+	available = make(mirrors.Mirrors, 0, len(mlist))
+	for _, m := range mlist {
+		if m.MaxConnections > 0 && t.estimate(m.ID, now)+1 > float64(m.MaxConnections) {
+			capped = append(capped, m)
+		} else {
+			available = append(available, m)
+		}
+	}
+	return available, capped
+}
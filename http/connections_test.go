@@ -0,0 +1,156 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/etix/mirrorbits/filesystem"
+	"github.com/etix/mirrorbits/mirrors"
+)
+
+func TestConnectionTracker(t *testing.T) {
+	defer SetConfiguration(GetConfig())
+	SetConfiguration(&Configuration{
+		ConnectionDuration: 60,
+	})
+
+	c := newConnectionTracker()
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		c.add(1, now)
+	}
+	if e := c.estimate(1, now); e != 3 {
+		t.Fatalf("Expected 3 connections, got %f", e)
+	}
+	if e := c.estimate(2, now); e != 0 {
+		t.Fatalf("Expected no connection on an unknown mirror, got %f", e)
+	}
+
+	// The redirects decay over the average duration of a download
+	if e := c.estimate(1, now.Add(time.Minute)); math.Abs(e-3/math.E) > 0.001 {
+		t.Fatalf("Expected %f connections, got %f", 3/math.E, e)
+	}
+	c.add(1, now.Add(time.Minute))
+	if e := c.estimate(1, now.Add(time.Minute)); math.Abs(e-(3/math.E+1)) > 0.001 {
+		t.Fatalf("Expected %f connections, got %f", 3/math.E+1, e)
+	}
+	if e := c.estimate(1, now.Add(time.Hour)); e > 0.001 {
+		t.Fatalf("Expected the connections to be gone, got %f", e)
+	}
+
+	// A nil tracker doesn't cap anything
+	var n *connectionTracker
+	n.add(1, now)
+	if e := n.estimate(1, now); e != 0 {
+		t.Fatalf("Expected no connection on a nil tracker, got %f", e)
+	}
+}
+
+func TestConnectionTrackerSplit(t *testing.T) {
+	defer SetConfiguration(GetConfig())
+	SetConfiguration(&Configuration{
+		ConnectionDuration: 60,
+	})
+
+	c := newConnectionTracker()
+	now := time.Now()
+	c.add(1, now)
+	c.add(1, now)
+	c.add(2, now)
+	c.add(3, now)
+
+	mlist := mirrors.Mirrors{
+		{ID: 1, Name: "capped", MaxConnections: 2},
+		{ID: 2, Name: "below", MaxConnections: 2},
+		{ID: 3, Name: "unlimited"},
+	}
+
+	available, capped := c.split(mlist, now)
+	if len(capped) != 1 || capped[0].Name != "capped" {
+		t.Fatalf("Expected the first mirror to be capped, got %v", capped)
+	}
+	if len(available) != 2 || available[0].Name != "below" || available[1].Name != "unlimited" {
+		t.Fatalf("Unexpected available mirrors %v", available)
+	}
+
+	// The cap is lifted as the connections decay
+	available, capped = c.split(mlist, now.Add(time.Minute))
+	if len(capped) != 0 || len(available) != 3 {
+		t.Fatalf("Expected no capped mirror, got %v", capped)
+	}
+}
+
+func TestFilterCapped(t *testing.T) {
+	defer SetConfiguration(GetConfig())
+	SetConfiguration(&Configuration{
+		ConnectionDuration: 60,
+	})
+
+	testfile := &filesystem.FileInfo{
+		Path:    "/test/file.tgz",
+		Size:    43000,
+		ModTime: time.Now(),
+	}
+
+	mirror := func(id, tier int) mirrors.Mirror {
+		return mirrors.Mirror{
+			ID:             id,
+			HttpURL:        fmt.Sprintf("https://m%d.mirror", id),
+			Enabled:        true,
+			HttpsUp:        true,
+			Tier:           tier,
+			MaxConnections: 1,
+			FileInfo: &filesystem.FileInfo{
+				Path:    testfile.Path,
+				Size:    testfile.Size,
+				ModTime: testfile.ModTime,
+			},
+		}
+	}
+
+	tests := map[string]struct {
+		mlist    mirrors.Mirrors
+		capped   []int
+		accepted []int
+	}{
+		"none capped":   {mirrors.Mirrors{mirror(1, 0), mirror(2, 0)}, nil, []int{1, 2}},
+		"next mirror":   {mirrors.Mirrors{mirror(1, 0), mirror(2, 0)}, []int{1}, []int{2}},
+		"backup mirror": {mirrors.Mirrors{mirror(1, 0), mirror(2, 1)}, []int{1}, []int{2}},
+		"all capped":    {mirrors.Mirrors{mirror(1, 0), mirror(2, 1)}, []int{1, 2}, []int{1}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			e := DefaultEngine{connections: newConnectionTracker()}
+			for _, id := range test.capped {
+				e.connections.add(id, time.Now())
+			}
+			a, x, _, _ := e.filterCapped(test.mlist, WITHTLS, testfile, noClientInfo)
+			if len(a) != len(test.accepted) {
+				t.Fatalf("Expected %d mirrors accepted, got %d", len(test.accepted), len(a))
+			}
+			for i, id := range test.accepted {
+				if a[i].ID != id {
+					t.Fatalf("Expected mirror %d at position %d, got %d", id, i, a[i].ID)
+				}
+			}
+			if len(a)+len(x) != len(test.mlist) {
+				t.Fatalf("Expected %d mirrors excluded, got %d", len(test.mlist)-len(a), len(x))
+			}
+			if len(test.capped) < len(test.mlist) {
+				for _, m := range x {
+					if m.ID == test.capped[0] && m.ExcludeReason != "Connection limit reached" {
+						t.Fatalf("Invalid ExcludeReason for mirror %d: '%s'", m.ID, m.ExcludeReason)
+					}
+				}
+			}
+		})
+	}
+}
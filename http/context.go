@@ -4,9 +4,11 @@
 package http
 
 import (
+	"context"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	. "github.com/etix/mirrorbits/config"
 )
@@ -23,7 +25,10 @@ const (
 	FILESTATS
 	MIRRORSTATS
 	CHECKSUM
+	CHECKSUMS
 	METALINK
+	TORRENT
+	ZSYNC
 
 	UNDEFINED SecureOption = iota
 	WITHTLS
@@ -33,18 +38,25 @@ const (
 // Context represents the context of a request
 type Context struct {
 	r             *http.Request
+	ctx           context.Context
+	servedBy      string // name of the mirror serving the file, for the access log
 	w             http.ResponseWriter
 	t             Templates
 	v             url.Values
 	typ           RequestType
 	isMirrorList  bool
+	isPlainList   bool
 	isMirrorStats bool
 	isFileStats   bool
 	isChecksum    bool
+	isChecksums   bool
 	isMetalink    bool
 	isMetalink3   bool
+	isTorrent     bool
+	isZsync       bool
 	isPretty      bool
 	secureOption  SecureOption
+	vhost         *VirtualHost // nil when no VirtualHosts are configured
 }
 
 // NewContext returns a new instance of Context
@@ -54,15 +66,21 @@ func NewContext(w http.ResponseWriter, r *http.Request, t Templates) *Context {
 	if c.paramBool("mirrorlist") {
 		c.typ = MIRRORLIST
 		c.isMirrorList = true
+		// ?mirrorlist=txt is the plain text version, for scripts
+		c.isPlainList = c.v.Get("mirrorlist") == "txt"
 	} else if c.paramBool("stats") {
 		c.typ = FILESTATS
 		c.isFileStats = true
 	} else if c.paramBool("mirrorstats") {
 		c.typ = MIRRORSTATS
 		c.isMirrorStats = true
-	} else if c.paramBool("md5") || c.paramBool("sha1") || c.paramBool("sha256") {
+	} else if c.paramBool("md5") || c.paramBool("sha1") || c.paramBool("sha256") || c.paramBool("sha512") {
 		c.typ = CHECKSUM
 		c.isChecksum = true
+	} else if c.paramBool("checksums") {
+		// ?checksums=<hash> is the manifest of the files of a directory
+		c.typ = CHECKSUMS
+		c.isChecksums = true
 	} else if c.paramBool("meta4") || strings.Contains(strings.ToLower(r.Header.Get("Accept")), "application/metalink4+xml") {
 		c.typ = METALINK
 		c.isMetalink = true
@@ -70,6 +88,12 @@ func NewContext(w http.ResponseWriter, r *http.Request, t Templates) *Context {
 		// Metalink 3.0 (metalinker.org), the format consumed by dnf/librepo
 		c.typ = METALINK
 		c.isMetalink3 = true
+	} else if c.paramBool("torrent") {
+		c.typ = TORRENT
+		c.isTorrent = true
+	} else if c.paramBool("zsync") {
+		c.typ = ZSYNC
+		c.isZsync = true
 	} else {
 		c.typ = STANDARD
 	}
@@ -104,6 +128,24 @@ func (c *Context) Request() *http.Request {
 	return c.r
 }
 
+// Context returns the context bounding the processing of the current request,
+// the one of the underlying http.Request unless a timeout has been set
+func (c *Context) Context() context.Context {
+	if c.ctx != nil {
+		return c.ctx
+	}
+	return c.r.Context()
+}
+
+// SetTimeout bounds the processing of the current request to the given
+// duration. The underlying http.Request keeps its own context: the timeout
+// doesn't apply to the transfer of the response.
+func (c *Context) SetTimeout(timeout time.Duration) context.CancelFunc {
+	var cancel context.CancelFunc
+	c.ctx, cancel = context.WithTimeout(c.r.Context(), timeout)
+	return cancel
+}
+
 // ResponseWriter returns the underlying http.ResponseWriter of the current request
 func (c *Context) ResponseWriter() http.ResponseWriter {
 	return c.w
@@ -124,6 +166,12 @@ func (c *Context) IsMirrorlist() bool {
 	return c.isMirrorList
 }
 
+// IsPlainMirrorlist returns true if the mirror list has been requested in
+// plain text, one URL per line
+func (c *Context) IsPlainMirrorlist() bool {
+	return c.isPlainList
+}
+
 // IsFileStats returns true if the file stats has been requested
 func (c *Context) IsFileStats() bool {
 	return c.isFileStats
@@ -139,6 +187,12 @@ func (c *Context) IsChecksum() bool {
 	return c.isChecksum
 }
 
+// IsChecksums returns true if the checksums manifest of a directory has been
+// requested
+func (c *Context) IsChecksums() bool {
+	return c.isChecksums
+}
+
 // IsMetalink returns true if a Metalink 4 (RFC 5854) document has been requested
 func (c *Context) IsMetalink() bool {
 	return c.isMetalink
@@ -150,6 +204,16 @@ func (c *Context) IsMetalink3() bool {
 	return c.isMetalink3
 }
 
+// IsTorrent returns true if a torrent has been requested
+func (c *Context) IsTorrent() bool {
+	return c.isTorrent
+}
+
+// IsZsync returns true if a zsync control file has been requested
+func (c *Context) IsZsync() bool {
+	return c.isZsync
+}
+
 // IsPretty returns true if the pretty json has been requested
 func (c *Context) IsPretty() bool {
 	return c.isPretty
@@ -0,0 +1,54 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/etix/mirrorbits/config"
+)
+
+// writePage writes a page rendered from the templates along with an ETag
+// computed from its content and the modification time of the templates.
+// A 304 is returned instead if the client already has the same content.
+// The pages being dynamic, only the ETag is used to validate the client's
+// copy, a matching If-Modified-Since alone isn't enough.
+func writePage(w http.ResponseWriter, r *http.Request, content []byte, modtime time.Time) {
+	sum := sha256.Sum256(content)
+	// The content may be compressed on the fly, hence the weak validator
+	w.Header().Set("Etag", `W/"`+hex.EncodeToString(sum[:16])+`"`)
+	setLastModified(w, modtime)
+
+	if checkIfNoneMatch(w, r) == condFalse {
+		writeNotModified(w)
+		return
+	}
+	w.Write(content)
+}
+
+// templatesModTime returns the modification time of the most recently
+// modified template or message file
+func templatesModTime() (modtime time.Time) {
+	if GetConfig().TemplatesPath == "" {
+		// Only the embedded templates are used
+		return modtime
+	}
+	files := []string{}
+	for _, name := range []string{"base", "mirrorlist", "mirrorstats"} {
+		files = append(files, filepath.Join(GetConfig().TemplatesPath, name+".html"))
+	}
+	locales, _ := filepath.Glob(filepath.Join(localesDir(), "*.yaml"))
+	for _, file := range append(files, locales...) {
+		fi, err := os.Stat(file)
+		if err == nil && fi.ModTime().After(modtime) {
+			modtime = fi.ModTime()
+		}
+	}
+	return modtime
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWritePage(t *testing.T) {
+	content := []byte("<html>mirrorbits</html>")
+	modtime := time.Date(2019, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// Get the ETag of the page
+	w := httptest.NewRecorder()
+	writePage(w, httptest.NewRequest("GET", "/", nil), content, modtime)
+	etag := w.Header().Get("Etag")
+	if w.Code != http.StatusOK || w.Body.String() != string(content) {
+		t.Fatalf("Expected the page to be served, got %d", w.Code)
+	}
+	if etag == "" {
+		t.Fatalf("Expected an ETag")
+	}
+	if lm := w.Header().Get("Last-Modified"); lm != "Wed, 01 May 2019 12:00:00 GMT" {
+		t.Fatalf("Invalid Last-Modified, got '%s'", lm)
+	}
+
+	tests := map[string]struct {
+		headers  map[string]string
+		expected int
+	}{
+		"same etag":          {map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+		"etag in list":       {map[string]string{"If-None-Match": `"foo", ` + etag}, http.StatusNotModified},
+		"other etag":         {map[string]string{"If-None-Match": `W/"foo"`}, http.StatusOK},
+		"modified since":     {map[string]string{"If-Modified-Since": "Wed, 01 May 2019 12:00:00 GMT"}, http.StatusOK},
+		"other etag and ims": {map[string]string{"If-None-Match": `"foo"`, "If-Modified-Since": "Wed, 01 May 2019 12:00:00 GMT"}, http.StatusOK},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			for k, v := range test.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			writePage(w, r, content, modtime)
+			if w.Code != test.expected {
+				t.Fatalf("Expected %d, got %d", test.expected, w.Code)
+			}
+			if w.Code == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Fatalf("Expected an empty body")
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/etix/mirrorbits/config"
+	"golang.org/x/net/websocket"
+)
+
+var (
+	// Maximum time allowed to send an event to a client
+	eventWriteTimeout = 10 * time.Second
+)
+
+// RedirectEvent is sent to the clients of the event stream for each redirect
+type RedirectEvent struct {
+	Time    time.Time
+	Country string
+	Mirror  string
+	Path    string
+}
+
+// eventBroker broadcasts the redirect events to the clients of the event
+// stream. Each client has a bounded buffer, a client not keeping up is
+// dropped instead of slowing down the redirects.
+type eventBroker struct {
+	sync.Mutex
+	clients map[chan []byte]struct{}
+	count   int32
+}
+
+func newEventBroker() *eventBroker {
+	return &eventBroker{
+		clients: make(map[chan []byte]struct{}),
+	}
+}
+
+// active returns true if at least one client is connected
+func (b *eventBroker) active() bool {
+	return atomic.LoadInt32(&b.count) > 0
+}
+
+// subscribe registers a new client with a buffer of size events
+func (b *eventBroker) subscribe(size int) chan []byte {
+	ch := make(chan []byte, size)
+	b.Lock()
+	b.clients[ch] = struct{}{}
+	atomic.StoreInt32(&b.count, int32(len(b.clients)))
+	b.Unlock()
+	return ch
+}
+
+// unsubscribe removes a client, its channel is closed
+func (b *eventBroker) unsubscribe(ch chan []byte) {
+	b.Lock()
+	b.removeLocked(ch)
+	b.Unlock()
+}
+
+func (b *eventBroker) removeLocked(ch chan []byte) {
+	if _, ok := b.clients[ch]; !ok {
+		return
+	}
+	delete(b.clients, ch)
+	close(ch)
+	atomic.StoreInt32(&b.count, int32(len(b.clients)))
+}
+
+// publish sends the event to all the clients, the clients whose buffer is
+// full are dropped
+func (b *eventBroker) publish(event RedirectEvent) {
+	if !b.active() {
+		return
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	b.Lock()
+	defer b.Unlock()
+	for ch := range b.clients {
+		select {
+		case ch <- data:
+		default:
+			b.removeLocked(ch)
+		}
+	}
+}
+
+// close drops all the clients
+func (b *eventBroker) close() {
+	b.Lock()
+	defer b.Unlock()
+	for ch := range b.clients {
+		b.removeLocked(ch)
+	}
+}
+
+// eventsHandler streams the redirect events over a WebSocket when
+// EventStream is enabled
+func (h *HTTP) eventsHandler(w http.ResponseWriter, r *http.Request) {
+	if !GetConfig().EventStream {
+		h.requestDispatcher(w, r)
+		return
+	}
+
+	if token := GetConfig().EventStreamToken; token != "" && !validEventToken(r, token) {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	// The handshake doesn't check the origin, the access is restricted
+	// by the token instead
+	websocket.Server{Handler: h.streamEvents}.ServeHTTP(w, r)
+}
+
+// validEventToken returns true if the request carries the expected token,
+// either as a bearer token or in the token parameter of the query string
+// (browsers can't set headers on a WebSocket)
+func validEventToken(r *http.Request, token string) bool {
+	given := r.URL.Query().Get("token")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		given = strings.TrimPrefix(auth, "Bearer ")
+	}
+	return subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+func (h *HTTP) streamEvents(ws *websocket.Conn) {
+	defer ws.Close()
+
+	ch := h.events.subscribe(GetConfig().EventStreamBuffer)
+	defer h.events.unsubscribe(ch)
+
+	// Nothing is expected from the client, reading is only needed to
+	// notice when the connection is closed
+	closed := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, ws)
+		close(closed)
+	}()
+
+	for {
+		select {
+		case data, ok := <-ch:
+			if !ok {
+				// Dropped by the broker
+				return
+			}
+			ws.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+			if err := websocket.Message.Send(ws, string(data)); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
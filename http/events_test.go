@@ -0,0 +1,99 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/etix/mirrorbits/config"
+	"golang.org/x/net/websocket"
+)
+
+func TestEventBroker(t *testing.T) {
+	b := newEventBroker()
+
+	// Nobody is listening
+	b.publish(RedirectEvent{Path: "/test"})
+
+	fast := b.subscribe(2)
+	slow := b.subscribe(1)
+	if !b.active() {
+		t.Fatalf("Expected the broker to be active")
+	}
+
+	b.publish(RedirectEvent{Path: "/first"})
+	<-fast
+	b.publish(RedirectEvent{Path: "/second"})
+
+	if _, ok := <-slow; !ok {
+		t.Fatalf("Expected the first event to be delivered to the slow client")
+	}
+	if _, ok := <-slow; ok {
+		t.Fatalf("Expected the slow client to be dropped")
+	}
+
+	data, ok := <-fast
+	if !ok {
+		t.Fatalf("Expected the fast client to be kept")
+	}
+	var event RedirectEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		t.Fatalf("Unable to decode the event: %s", err)
+	}
+	if event.Path != "/second" {
+		t.Fatalf("Expected /second, got %s", event.Path)
+	}
+
+	b.close()
+	if b.active() {
+		t.Fatalf("Expected the broker to be inactive once closed")
+	}
+	if _, ok := <-fast; ok {
+		t.Fatalf("Expected the channel to be closed")
+	}
+}
+
+func TestEventsHandler(t *testing.T) {
+	defer SetConfiguration(GetConfig())
+	SetConfiguration(&Configuration{
+		EventStream:       true,
+		EventStreamToken:  "secret",
+		EventStreamBuffer: 10,
+	})
+
+	h := &HTTP{events: newEventBroker()}
+	server := httptest.NewServer(http.HandlerFunc(h.eventsHandler))
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	if _, err := websocket.Dial(url, "", server.URL); err == nil {
+		t.Fatalf("Expected the connection to be refused without token")
+	}
+
+	ws, err := websocket.Dial(url+"?token=secret", "", server.URL)
+	if err != nil {
+		t.Fatalf("Unable to connect: %s", err)
+	}
+	defer ws.Close()
+
+	for !h.events.active() {
+		time.Sleep(time.Millisecond)
+	}
+	h.events.publish(RedirectEvent{Country: "FR", Mirror: "m1", Path: "/test"})
+
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var event RedirectEvent
+	if err := websocket.JSON.Receive(ws, &event); err != nil {
+		t.Fatalf("Unable to receive the event: %s", err)
+	}
+	if event.Country != "FR" || event.Mirror != "m1" || event.Path != "/test" {
+		t.Fatalf("Unexpected event %+v", event)
+	}
+}
@@ -5,6 +5,7 @@ package http
 
 import (
 	"io"
+	"mime"
 	"net/http"
 	"strings"
 
@@ -12,32 +13,148 @@ import (
 	"github.com/youtube/vitess/go/cgzip"
 )
 
+// gzipResponseWriter compresses the body of the response once it reaches
+// minSize bytes. Smaller bodies, redirects, partial contents, resources
+// accepting ranges and types that don't benefit from the compression are sent
+// as is.
 type gzipResponseWriter struct {
-	io.Writer
 	http.ResponseWriter
-	typeGuessed bool
+	minSize     int
+	status      int
+	buf         []byte
+	gz          io.WriteCloser
+	passthrough bool
+}
+
+func (w *gzipResponseWriter) WriteHeader(code int) {
+	if w.passthrough || w.gz != nil || w.status != 0 {
+		return
+	}
+	w.status = code
+	if !compressibleStatus(code) {
+		w.passthrough = true
+		w.ResponseWriter.WriteHeader(code)
+	}
 }
 
 func (w *gzipResponseWriter) Write(b []byte) (int, error) {
-	if !w.typeGuessed {
-		if w.Header().Get("Content-Type") == "" {
-			w.Header().Set("Content-Type", http.DetectContentType(b))
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+	w.buf = append(w.buf, b...)
+	if len(w.buf) < w.minSize {
+		return len(b), nil
+	}
+	if err := w.start(); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// start sends the headers and the buffered part of the body, compressed if
+// the response is worth it
+func (w *gzipResponseWriter) start() error {
+	h := w.Header()
+	if h.Get("Content-Type") == "" {
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	buf := w.buf
+	w.buf = nil
+
+	// The ranges of a resource apply to its uncompressed body
+	if h.Get("Content-Encoding") != "" || h.Get("Accept-Ranges") == "bytes" || !compressibleType(h.Get("Content-Type")) {
+		w.passthrough = true
+		w.ResponseWriter.WriteHeader(w.status)
+		_, err := w.ResponseWriter.Write(buf)
+		return err
+	}
+
+	h.Set("Content-Encoding", "gzip")
+	h.Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+	gz, err := cgzip.NewWriterLevel(w.ResponseWriter, cgzip.Z_BEST_SPEED)
+	if err != nil {
+		return err
+	}
+	w.gz = gz
+	_, err = w.gz.Write(buf)
+	return err
+}
+
+// close terminates the response, a body smaller than minSize is sent
+// uncompressed
+func (w *gzipResponseWriter) close() {
+	if w.gz != nil {
+		w.gz.Close()
+		return
+	}
+	if w.passthrough {
+		return
+	}
+	if len(w.buf) == 0 {
+		if w.status != 0 {
+			w.ResponseWriter.WriteHeader(w.status)
 		}
-		w.typeGuessed = true
+		return
+	}
+	w.passthrough = true
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	w.ResponseWriter.Write(w.buf)
+}
+
+// compressibleStatus returns false for the responses that have no body
+// worth compressing or whose body must be sent as is
+func compressibleStatus(code int) bool {
+	switch {
+	case code < http.StatusOK:
+		return false
+	case code >= 300 && code < 400:
+		return false
+	case code == http.StatusNoContent, code == http.StatusPartialContent:
+		return false
+	}
+	return true
+}
+
+// compressibleType returns true for the textual content types
+func compressibleType(contentType string) bool {
+	t, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
 	}
-	return w.Writer.Write(b)
+	return strings.HasPrefix(t, "text/") ||
+		t == "application/json" ||
+		t == "application/javascript" ||
+		t == "application/xml" ||
+		strings.HasSuffix(t, "+xml")
 }
 
 // NewGzipHandler is an HTTP handler used to compress responses if supported by the client
 func NewGzipHandler(fn http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if !GetConfig().Gzip || !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		if !GetConfig().Gzip {
+			fn(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") || r.Method == http.MethodHead {
 			fn(w, r)
 			return
 		}
-		w.Header().Set("Content-Encoding", "gzip")
-		gz, _ := cgzip.NewWriterLevel(w, cgzip.Z_BEST_SPEED)
-		defer gz.Close()
-		fn(&gzipResponseWriter{Writer: gz, ResponseWriter: w}, r)
+		gw := &gzipResponseWriter{ResponseWriter: w, minSize: GetConfig().GzipMinSize}
+		defer gw.close()
+		fn(gw, r)
 	}
 }
@@ -0,0 +1,105 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/etix/mirrorbits/config"
+)
+
+func TestGzipHandler(t *testing.T) {
+	defer SetConfiguration(GetConfig())
+	SetConfiguration(&Configuration{
+		Gzip:        true,
+		GzipMinSize: 100,
+	})
+
+	large := strings.Repeat(`{"mirror": "test"}`, 50)
+
+	tests := map[string]struct {
+		handler    http.HandlerFunc
+		body       string
+		compressed bool
+	}{
+		"large json": {func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(large))
+		}, large, true},
+		"small body": {func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{}`))
+		}, `{}`, false},
+		"chunked writes": {func(w http.ResponseWriter, r *http.Request) {
+			for i := 0; i < 50; i++ {
+				io.WriteString(w, `{"mirror": "test"}`)
+			}
+		}, large, true},
+		"binary": {func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte(large))
+		}, large, false},
+		"redirect": {func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Location", "https://mirror/")
+			w.WriteHeader(http.StatusFound)
+			w.Write([]byte(large))
+		}, large, false},
+		"accept ranges": {func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.Write([]byte(large))
+		}, large, false},
+		"partial content": {func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write([]byte(large))
+		}, large, false},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Header.Set("Accept-Encoding", "gzip, deflate")
+			w := httptest.NewRecorder()
+			NewGzipHandler(test.handler)(w, r)
+
+			compressed := w.Header().Get("Content-Encoding") == "gzip"
+			if compressed != test.compressed {
+				t.Fatalf("Expected compressed to be %t", test.compressed)
+			}
+
+			var body io.Reader = w.Body
+			if compressed {
+				gz, err := gzip.NewReader(w.Body)
+				if err != nil {
+					t.Fatalf("Invalid gzip stream: %s", err)
+				}
+				body = gz
+			}
+			b, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatalf("Unable to read the body: %s", err)
+			}
+			if string(b) != test.body {
+				t.Fatalf("Unexpected body %q", b)
+			}
+		})
+	}
+
+	// The client doesn't support compression
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	NewGzipHandler(tests["large json"].handler)(w, r)
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != large {
+		t.Fatalf("Expected an uncompressed response")
+	}
+	if w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("Expected a Vary header")
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"fmt"
+	"net/http"
+
+	. "github.com/etix/mirrorbits/config"
+)
+
+// responseWriter adds the ResponseHeaders of the configuration to the
+// response once its status is known, overriding the ones set by the handlers.
+// It records the status and the size of the response for the access log.
+type responseWriter struct {
+	http.ResponseWriter
+	status  int
+	written int64
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+		applyResponseHeaders(w.Header(), code)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.written += int64(n)
+	return n, err
+}
+
+// applyResponseHeaders sets the ResponseHeaders then the ones configured for
+// the class of the status code, an empty value removing the header
+func applyResponseHeaders(h http.Header, code int) {
+	set := func(headers map[string]string) {
+		for key, value := range headers {
+			if value == "" {
+				h.Del(key)
+			} else {
+				h.Set(key, value)
+			}
+		}
+	}
+	set(GetConfig().ResponseHeaders)
+	set(GetConfig().ResponseHeadersByStatus[fmt.Sprintf("%dxx", code/100)])
+}
@@ -0,0 +1,42 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"net/http"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// livenessHandler tells the load balancers the process is up
+func (h *HTTP) livenessHandler(w http.ResponseWriter, r *http.Request) {
+	writeProbe(w, http.StatusOK, "OK")
+}
+
+// readinessHandler tells the load balancers whether the requests can be
+// served, that is the database is reachable and at least one mirror is up
+func (h *HTTP) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	conn := h.redis.Get()
+	ids, err := redis.Ints(conn.Do("HKEYS", "MIRRORS"))
+	conn.Close()
+	if err != nil {
+		writeProbe(w, http.StatusServiceUnavailable, "database unreachable")
+		return
+	}
+	for _, id := range ids {
+		mirror, err := h.cache.GetMirror(id)
+		if err == nil && mirror.Enabled && mirror.IsUp() {
+			writeProbe(w, http.StatusOK, "OK")
+			return
+		}
+	}
+	writeProbe(w, http.StatusServiceUnavailable, "no mirror up")
+}
+
+func writeProbe(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(status)
+	w.Write([]byte(body + "\n"))
+}
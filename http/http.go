@@ -4,7 +4,10 @@
 package http
 
 import (
+	"bytes"
+	"context"
 	"crypto/sha256"
+	"crypto/tls"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -19,6 +22,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	systemd "github.com/coreos/go-systemd/daemon"
@@ -27,6 +31,7 @@ import (
 	"github.com/etix/mirrorbits/database"
 	"github.com/etix/mirrorbits/filesystem"
 	"github.com/etix/mirrorbits/logs"
+	"github.com/etix/mirrorbits/metrics"
 	"github.com/etix/mirrorbits/mirrors"
 	"github.com/etix/mirrorbits/network"
 	"github.com/etix/mirrorbits/utils"
@@ -39,20 +44,42 @@ var (
 	log = logging.MustGetLogger("main")
 )
 
+const (
+	// geoipCheckInterval is the interval between two checks of the
+	// GeoIP database files when GeoIPAutoReload is enabled
+	geoipCheckInterval = time.Minute
+)
+
 // HTTP represents an instance of the HTTP webserver
 type HTTP struct {
 	geoip          *network.GeoIP
 	redis          *database.Redis
 	templates      Templates
 	Listener       *net.Listener
+	TLSListener    *net.Listener
 	server         *graceful.Server
+	tlsServer      *graceful.Server
 	serverStopChan <-chan struct{}
+	tlsStopChan    <-chan struct{}
+	certificate    certificateLoader
 	stats          *Stats
 	cache          *mirrors.Cache
 	engine         mirrorSelection
+	connections    *connectionTracker
+	limiter        *rateLimiter
+	events         *eventBroker
+	maintenance    maintenanceState
+	rates          *rateTracker
+	geoipStop      chan struct{}
 	Restarting     bool
 	stopped        bool
 	stoppedMutex   sync.Mutex
+	inFlight       int64
+	redirects      int64
+	// shutdown is the parent context of the requests, canceled when the
+	// ShutdownTimeout expires
+	shutdown       context.Context
+	cancelRequests context.CancelFunc
 }
 
 // Templates is a struct embedding instances of the precompiled templates
@@ -61,6 +88,13 @@ type Templates struct {
 
 	mirrorlist  *template.Template
 	mirrorstats *template.Template
+	// catalog holds the translations of the templates
+	catalog Catalog
+	// localized holds the templates translated in each locale
+	localized localizedTemplates
+	// modTime is the modification time of the templates, sent along
+	// with the pages rendered from them
+	modTime time.Time
 }
 
 // HTTPServer is the constructor of the HTTP server
@@ -71,10 +105,26 @@ func HTTPServer(redis *database.Redis, cache *mirrors.Cache) *HTTP {
 	h.templates.RWMutex = new(sync.RWMutex)
 	h.templates.mirrorlist = template.Must(h.LoadTemplates("mirrorlist"))
 	h.templates.mirrorstats = template.Must(h.LoadTemplates("mirrorstats"))
+	h.templates.catalog = loadCatalog()
+	h.templates.prepareLocales()
+	h.templates.modTime = templatesModTime()
 	h.cache = cache
 	h.stats = NewStats(redis)
-	h.engine = DefaultEngine{}
+	h.connections = newConnectionTracker()
+	h.rates = newRateTracker()
+	popularity := newPopularityTracker(redis)
+	h.engine = DefaultEngine{
+		connections: h.connections,
+		popularity:  popularity,
+		strategies:  newStrategies(h.connections, popularity),
+	}
+	h.limiter = newRateLimiter()
+	h.events = newEventBroker()
+	h.geoipStop = make(chan struct{})
+	h.shutdown, h.cancelRequests = context.WithCancel(context.Background())
 	http.Handle("/", NewGzipHandler(h.requestDispatcher))
+	http.HandleFunc("/metrics", h.metricsHandler)
+	http.HandleFunc("/events", h.eventsHandler)
 
 	// Load the GeoIP databases
 	if err := h.geoip.LoadGeoIP(); err != nil {
@@ -83,6 +133,9 @@ func HTTPServer(redis *database.Redis, cache *mirrors.Cache) *HTTP {
 			for _, e := range gerr.Errors {
 				log.Critical(e.Error())
 			}
+			if gerr.IsStrict() {
+				log.Fatal("Can't load the GeoIP databases of the configured provider")
+			}
 			if gerr.IsFatal() {
 				if len(GetConfig().Fallbacks) == 0 {
 					log.Fatal("Can't load the GeoIP databases, please set a valid path in the mirrorbits configuration")
@@ -106,8 +159,14 @@ func (h *HTTP) SetListener(l net.Listener) {
 	h.Listener = &l
 }
 
+// SetTLSListener is the equivalent of SetListener for the HTTPS server
+func (h *HTTP) SetTLSListener(l net.Listener) {
+	h.TLSListener = &l
+}
+
 // Stop gracefully stops the HTTP server with a timeout to let
-// the remaining connections finish
+// the remaining connections finish. It returns once all the requests
+// being processed are done or the timeout expired.
 func (h *HTTP) Stop(timeout time.Duration) {
 	/* Close the server and process remaining connections */
 	h.stoppedMutex.Lock()
@@ -116,29 +175,87 @@ func (h *HTTP) Stop(timeout time.Duration) {
 		return
 	}
 	h.stopped = true
-	h.server.Stop(timeout)
+	if h.server != nil {
+		h.server.Stop(timeout)
+	}
+	if h.tlsServer != nil {
+		h.tlsServer.Stop(timeout)
+	}
+	/* Disconnect the clients of the event stream */
+	h.events.close()
+
+	/* Wait for the running handlers */
+	deadline := time.Now().Add(timeout)
+	for atomic.LoadInt64(&h.inFlight) > 0 {
+		if timeout > 0 && time.Now().After(deadline) {
+			pending := atomic.LoadInt64(&h.inFlight)
+			log.Warningf("Shutdown timeout reached with %d request%s still pending", pending, utils.Plural(int(pending)))
+			// Abort the database requests of the remaining handlers
+			if h.cancelRequests != nil {
+				h.cancelRequests()
+			}
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// trackInFlight keeps count of the requests being processed
+func (h *HTTP) trackInFlight(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&h.inFlight, 1)
+		defer atomic.AddInt64(&h.inFlight, -1)
+		next.ServeHTTP(w, r)
+	})
 }
 
 // Terminate terminates the current HTTP server gracefully
 func (h *HTTP) Terminate() {
-	/* Wait for the server to stop */
-	select {
-	case <-h.serverStopChan:
+	/* Wait for the servers to stop */
+	if h.serverStopChan != nil {
+		<-h.serverStopChan
+	}
+	if h.tlsStopChan != nil {
+		<-h.tlsStopChan
 	}
+	/* Wait for Stop to be done draining the requests */
+	h.stoppedMutex.Lock()
+	h.stoppedMutex.Unlock()
+	/* Stop watching the GeoIP databases */
+	close(h.geoipStop)
 	/* Commit the latest recorded stats to the database */
 	h.stats.Terminate()
 }
 
-// StopChan returns a channel that notifies when the server is stopped
+// WatchGeoIP reloads the GeoIP databases when their files are updated and
+// GeoIPAutoReload is enabled, until the server is terminated
+func (h *HTTP) WatchGeoIP() {
+	h.geoip.WatchDatabases(geoipCheckInterval, h.geoipStop)
+}
+
+// StopChan returns a channel that notifies when the plain HTTP server
+// is stopped, it is nil when only the HTTPS server is enabled
 func (h *HTTP) StopChan() <-chan struct{} {
 	return h.serverStopChan
 }
 
 // Reload the configuration
 func (h *HTTP) Reload() {
+	// The configuration takes over the maintenance mode again
+	h.resetMaintenance()
+
 	// Reload the GeoIP database
 	h.geoip.LoadGeoIP()
 
+	// Reload the TLS certificate
+	if h.tlsServer != nil {
+		if err := h.certificate.load(); err != nil {
+			log.Errorf("could not reload the TLS certificate: %s", err.Error())
+		} else {
+			log.Notice("TLS certificate reloaded")
+		}
+	}
+
 	// Reload the templates
 	h.templates.Lock()
 	if t, err := h.LoadTemplates("mirrorlist"); err == nil {
@@ -151,68 +268,171 @@ func (h *HTTP) Reload() {
 	} else {
 		log.Errorf("could not reload templates 'mirrorstats': %s", err.Error())
 	}
+	h.templates.catalog = loadCatalog()
+	h.templates.prepareLocales()
+	h.templates.modTime = templatesModTime()
 	h.templates.Unlock()
 }
 
 // RunServer is the main function used to start the HTTP server
+// and the HTTPS one when enabled. It returns once both are stopped.
 func (h *HTTP) RunServer() (err error) {
-	// If listener isn't nil that means that we're running a seamless
+	// If a listener isn't nil that means that we're running a seamless
 	// binary upgrade and we have recovered an already running listener
-	if h.Listener == nil {
-		proto := "tcp"
-		address := GetConfig().ListenAddress
-		if strings.HasPrefix(address, "unix:") {
-			proto = "unix"
-			address = strings.TrimPrefix(address, "unix:")
-		}
-		listener, err := net.Listen(proto, address)
-		if err != nil {
-			log.Fatal("Listen: ", err)
+	if h.Listener == nil && GetConfig().ListenAddress != "" {
+		h.SetListener(listen(GetConfig().ListenAddress))
+	}
+	if h.TLSListener == nil && GetConfig().TLSListenAddress != "" {
+		h.SetTLSListener(listen(GetConfig().TLSListenAddress))
+	}
+
+	serve := make(chan error, 2)
+	running := 0
+
+	if h.Listener != nil {
+		h.server = h.newServer()
+		h.serverStopChan = h.server.StopChan()
+		log.Infof("Service listening on %s", GetConfig().ListenAddress)
+		go func(l net.Listener) {
+			serve <- h.server.Serve(l)
+		}(*h.Listener)
+		running++
+	}
+
+	if h.TLSListener != nil {
+		if err := h.certificate.load(); err != nil {
+			log.Fatal("TLS: ", err)
 		}
-		h.SetListener(listener)
+		h.tlsServer = h.newTLSServer()
+		h.tlsStopChan = h.tlsServer.StopChan()
+		log.Infof("Service listening on %s (TLS)", GetConfig().TLSListenAddress)
+		go func(l net.Listener) {
+			serve <- h.tlsServer.Serve(tls.NewListener(l, h.tlsServer.TLSConfig))
+		}(*h.TLSListener)
+		running++
 	}
 
-	h.server = &graceful.Server{
+	// Since main blocks here until completion, tell systemd we're ready.
+	// This is a no-op if NOTIFY_SOCKET isn't set.
+	if os.Getenv("NOTIFY_SOCKET") != "" {
+		log.Debug("Notifying systemd of readiness")
+		systemd.SdNotify(false, systemd.SdNotifyReady)
+	}
+
+	/* Serve until we receive a SIGTERM */
+	for ; running > 0; running-- {
+		if e := <-serve; e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// listen opens a listener on the given address, addresses starting
+// with "unix:" are unix sockets
+func listen(address string) net.Listener {
+	proto := "tcp"
+	if strings.HasPrefix(address, "unix:") {
+		proto = "unix"
+		address = strings.TrimPrefix(address, "unix:")
+	}
+	listener, err := net.Listen(proto, address)
+	if err != nil {
+		log.Fatal("Listen: ", err)
+	}
+	return listener
+}
+
+func (h *HTTP) newServer() *graceful.Server {
+	return &graceful.Server{
 		// http
 		Server: &http.Server{
-			Handler:        nil,
+			Handler:        h.trackInFlight(http.DefaultServeMux),
 			ReadTimeout:    10 * time.Second,
 			WriteTimeout:   10 * time.Second,
 			MaxHeaderBytes: 1 << 20,
+			BaseContext: func(net.Listener) context.Context {
+				if h.shutdown == nil {
+					return context.Background()
+				}
+				return h.shutdown
+			},
 		},
 
 		// graceful
 		Timeout:          10 * time.Second,
 		NoSignalHandling: true,
 	}
-	h.serverStopChan = h.server.StopChan()
-
-	log.Infof("Service listening on %s", GetConfig().ListenAddress)
+}
 
-	// Since main blocks here until completion, tell systemd we're ready.
-	// This is a no-op if NOTIFY_SOCKET isn't set.
-	if os.Getenv("NOTIFY_SOCKET") != "" {
-		log.Debug("Notifying systemd of readiness")
-		systemd.SdNotify(false, systemd.SdNotifyReady)
+func (h *HTTP) requestDispatcher(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rw := &responseWriter{ResponseWriter: w}
+	w = rw
+
+	// The probes of the load balancers are neither rate limited nor
+	// counted in the statistics
+	switch r.URL.Path {
+	case GetConfig().LivenessPath:
+		h.livenessHandler(w, r)
+		return
+	case GetConfig().ReadinessPath:
+		h.readinessHandler(w, r)
+		return
 	}
 
-	/* Serve until we receive a SIGTERM */
-	return h.server.Serve(*h.Listener)
-}
+	vhost, ok := virtualHost(r)
+	if !ok {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
 
-func (h *HTTP) requestDispatcher(w http.ResponseWriter, r *http.Request) {
 	h.templates.RLock()
 	ctx := NewContext(w, r, h.templates)
 	h.templates.RUnlock()
+	ctx.vhost = vhost
 
 	w.Header().Set("Server", "Mirrorbits/"+core.VERSION)
 
+	// The file requests are logged whatever their outcome, including the
+	// ones rejected by the rate limiting
+	switch ctx.Type() {
+	case STANDARD, MIRRORLIST, METALINK, TORRENT, ZSYNC:
+		if GetConfig().AccessLogFormat != "" {
+			defer func() {
+				logs.LogAccess(r, remoteIP(r), start, rw.status, rw.written, ctx.servedBy)
+			}()
+		}
+	}
+
+	if rate := GetConfig().RateLimitPerSecond; rate > 0 {
+		allowed, wait := h.limiter.allow(remoteIP(r), time.Now(), float64(rate), GetConfig().RateLimitBurst)
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter(wait)))
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+	}
+
 	switch ctx.Type() {
 	case MIRRORLIST:
 		fallthrough
 	case METALINK:
 		fallthrough
+	case TORRENT:
+		fallthrough
+	case ZSYNC:
+		fallthrough
 	case STANDARD:
+		if on, message, _ := h.Maintenance(); on {
+			h.maintenanceHandler(w, r, message)
+			return
+		}
+		if !h.acquireRedirect() {
+			rejectRedirect(w)
+			return
+		}
+		defer h.releaseRedirect()
 		h.mirrorHandler(w, r, ctx)
 	case MIRRORSTATS:
 		h.mirrorStatsHandler(w, r, ctx)
@@ -220,14 +440,109 @@ func (h *HTTP) requestDispatcher(w http.ResponseWriter, r *http.Request) {
 		h.fileStatsHandler(w, r, ctx)
 	case CHECKSUM:
 		h.checksumHandler(w, r, ctx)
+	case CHECKSUMS:
+		// A manifest is as costly as a file request
+		if !h.acquireRedirect() {
+			rejectRedirect(w)
+			return
+		}
+		defer h.releaseRedirect()
+		h.checksumsHandler(w, r, ctx)
+	}
+}
+
+// pathStatus checks the path against the PathBlocklist and PathAllowlist.
+// Blocked paths return a 404, the ones outside of a non-empty allowlist
+// return a 403, the blocklist has precedence.
+func pathStatus(urlPath string) int {
+	for _, pattern := range GetConfig().PathBlocklist {
+		if matched, _ := utils.MatchPathPattern(pattern, urlPath); matched {
+			return http.StatusNotFound
+		}
+	}
+	allowlist := GetConfig().PathAllowlist
+	if len(allowlist) == 0 {
+		return http.StatusOK
+	}
+	for _, pattern := range allowlist {
+		if matched, _ := utils.MatchPathPattern(pattern, urlPath); matched {
+			return http.StatusOK
+		}
+	}
+	return http.StatusForbidden
+}
+
+// setMirrorHeader discloses the selected mirror, its distance and its score
+// in the X-Mirrorbits-Mirror header when ExposeMirrorHeader is enabled
+func setMirrorHeader(w http.ResponseWriter, m mirrors.Mirror, fallback bool) {
+	if !GetConfig().ExposeMirrorHeader {
+		return
+	}
+	value := m.Name
+	if fallback {
+		value += "; fallback"
+	} else {
+		value += fmt.Sprintf("; distance=%d; score=%d", int(m.Distance), m.ComputedScore)
+	}
+	w.Header().Set("X-Mirrorbits-Mirror", value)
+}
+
+// setNoMirrorHeader sets the X-Mirrorbits-Mirror header of the responses
+// not served by a mirror when ExposeMirrorHeader is enabled
+func setNoMirrorHeader(w http.ResponseWriter, fallback bool) {
+	if !GetConfig().ExposeMirrorHeader {
+		return
+	}
+	value := "none"
+	if fallback {
+		value += "; fallback"
 	}
+	w.Header().Set("X-Mirrorbits-Mirror", value)
+}
+
+// remoteIP returns the address of the client. The ClientIPHeader is only
+// honored if the request comes from one of the TrustedProxies (or from
+// anywhere if the list is empty).
+func remoteIP(r *http.Request) string {
+	addr, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		addr = r.RemoteAddr
+	}
+
+	header := GetConfig().ClientIPHeader
+	proxies := GetConfig().TrustedProxies
+	if header == "" || (len(proxies) > 0 && !network.IsTrustedProxy(addr, proxies)) {
+		return addr
+	}
+
+	var ip string
+	value := r.Header.Get(header)
+	if strings.EqualFold(header, "X-Forwarded-For") {
+		if len(proxies) > 0 {
+			ip = network.ExtractUntrustedIP(value, proxies)
+		} else {
+			ip = network.ExtractRemoteIP(value)
+		}
+	} else {
+		ip = strings.TrimSpace(value)
+	}
+
+	if net.ParseIP(ip) == nil {
+		return addr
+	}
+	return ip
 }
 
 func (h *HTTP) mirrorHandler(w http.ResponseWriter, r *http.Request, ctx *Context) {
 	//XXX it would be safer to recover in case of panic
 
 	// Sanitize path
-	urlPath, err := filesystem.EvaluateFilePath(GetConfig().Repository, r.URL.Path)
+	urlPath, err := normalizePath(r.URL.Path)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	urlPath, err = evaluatePath(ctx.vhost, urlPath)
 	if err != nil {
 		if err == filesystem.ErrOutsideRepo {
 			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
@@ -237,23 +552,57 @@ func (h *HTTP) mirrorHandler(w http.ResponseWriter, r *http.Request, ctx *Contex
 		return
 	}
 
+	// Refuse the filtered paths before the mirror selection so we
+	// don't leak which mirrors carry them
+	if status := pathStatus(urlPath); status != http.StatusOK {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	// Bound the time spent on the database and the selection, the client
+	// reading the request or the response slowly is not accounted
+	if timeout := GetConfig().RedirectTimeout; timeout > 0 {
+		cancel := ctx.SetTimeout(time.Duration(timeout) * time.Second)
+		defer cancel()
+	}
+
 	// Get details about the requested file. Errors are not fatal, and
 	// expected when the database is not ready: fallbacks will handle it.
-	fileInfo, err := h.cache.GetFileInfo(urlPath)
+	fileInfo, err := h.cache.GetFileInfo(ctx.Context(), urlPath)
 	if err != nil {
 		//log.Debugf("Error while fetching Fileinfo: %s", err.Error())
 	}
 
-	if checkIfModifiedSince(r, fileInfo.ModTime) == condFalse {
+	// Only serve the torrents already hashed by the repository scan
+	var torrentRenderer *TorrentRenderer
+	if ctx.IsTorrent() {
+		pieceLength, pieces, err := torrentPieces(ctx.Context(), h.redis, urlPath)
+		if err != nil || GetConfig().TorrentMinSize == 0 || len(pieces) == 0 {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+		torrentRenderer = &TorrentRenderer{pieceLength: pieceLength, pieces: pieces}
+	}
+
+	// Likewise for the zsync control files
+	var zsyncRenderer *ZsyncRenderer
+	if ctx.IsZsync() {
+		blockSize, sha1, sums, err := zsyncSums(ctx.Context(), h.redis, urlPath, fileInfo.Size)
+		if err != nil || GetConfig().ZsyncMinSize == 0 || len(sums) == 0 {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+		zsyncRenderer = &ZsyncRenderer{blockSize: blockSize, sha1: sha1, sums: sums}
+	}
+
+	// The mirrorlist page is validated with its ETag instead
+	if !ctx.IsMirrorlist() && checkIfModifiedSince(r, fileInfo.ModTime) == condFalse {
 		setLastModified(w, fileInfo.ModTime)
 		writeNotModified(w)
 		return
 	}
 
-	remoteIP := network.ExtractRemoteIP(r.Header.Get("X-Forwarded-For"))
-	if len(remoteIP) == 0 {
-		remoteIP = network.RemoteIPFromAddr(r.RemoteAddr)
-	}
+	remoteIP := remoteIP(r)
 
 	if ctx.IsMirrorlist() {
 		fromip := ctx.QueryParam("fromip")
@@ -287,42 +636,54 @@ func (h *HTTP) mirrorHandler(w http.ResponseWriter, r *http.Request, ctx *Contex
 
 	mlist, excluded, err := h.engine.Selection(ctx, h.cache, &fileInfo, clientInfo)
 
+	// The deadline is reported rather than served from the fallbacks as the
+	// database is likely overloaded, and the client is gone if canceled
+	if ctxErr := ctx.Context().Err(); ctxErr != nil && err != nil {
+		if errors.Is(ctxErr, context.DeadlineExceeded) {
+			metrics.RedirectsTimedOut.Inc()
+		}
+		countResult(r.Method, clientInfo.CountryCode, metrics.ResultError)
+		setNoMirrorHeader(w, false)
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+
 	/* Handle errors */
 	fallback := false
 	var netErr net.Error
 	if errors.As(err, &netErr) || len(mlist) == 0 {
+		switch GetConfig().FallbackMode {
+		case "notfound":
+			countResult(r.Method, clientInfo.CountryCode, metrics.ResultFallback)
+			setNoMirrorHeader(w, true)
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		case "proxy":
+			// Mirror lists and metalinks still use the fallback mirrors
+			if ctx.Type() == STANDARD {
+				countResult(r.Method, clientInfo.CountryCode, metrics.ResultFallback)
+				setMirrorHeader(w, mirrors.Mirror{Name: "origin"}, true)
+				ctx.servedBy = "origin"
+				proxyFile(w, r, urlPath)
+				return
+			}
+		}
+
 		/* Handle fallbacks */
-		fallbacks := GetConfig().Fallbacks
-		if len(fallbacks) > 0 {
+		if len(GetConfig().Fallbacks) > 0 {
 			fallback = true
-			for i, f := range fallbacks {
-				// Set the absolute URL
-				var absURL string
-				if utils.HasAnyPrefix(f.URL, "http://", "https://") {
-					absURL = f.URL
-				} else if ctx.SecureOption() == WITHOUTTLS {
-					absURL = "http://" + f.URL
-				} else {
-					absURL = "https://" + f.URL
-				}
-
-				// Create a mirror object and add it to the result
-				mlist = append(mlist, mirrors.Mirror{
-					ID:            i * -1,
-					Name:          fmt.Sprintf("fallback%d", i),
-					HttpURL:       f.URL,
-					CountryCodes:  strings.ToUpper(f.CountryCode),
-					CountryFields: []string{strings.ToUpper(f.CountryCode)},
-					ContinentCode: strings.ToUpper(f.ContinentCode),
-					AbsoluteURL:   absURL})
-			}
+			mlist = append(mlist, fallbackMirrors(ctx.SecureOption(), vhostPrefix(ctx.vhost))...)
 			sort.Sort(mirrors.ByRank{Mirrors: mlist, ClientInfo: clientInfo})
 		} else {
 			// No fallback in stock, there's nothing else we can do
+			countResult(r.Method, clientInfo.CountryCode, metrics.ResultError)
+			setNoMirrorHeader(w, true)
 			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
 			return
 		}
 	} else if err != nil {
+		setNoMirrorHeader(w, false)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -335,16 +696,23 @@ func (h *HTTP) mirrorHandler(w http.ResponseWriter, r *http.Request, ctx *Contex
 		IP:           remoteIP,
 		Fallback:     fallback,
 		LocalJSPath:  GetConfig().LocalJSPath,
+		Prefix:       vhostPrefix(ctx.vhost),
 	}
 
 	var resultRenderer resultsRenderer
 
-	if ctx.IsMirrorlist() {
+	if ctx.IsPlainMirrorlist() {
+		resultRenderer = &PlainMirrorListRenderer{}
+	} else if ctx.IsMirrorlist() {
 		resultRenderer = &MirrorListRenderer{}
 	} else if ctx.IsMetalink3() {
 		resultRenderer = &Metalink3Renderer{}
 	} else if ctx.IsMetalink() {
 		resultRenderer = &MetalinkRenderer{}
+	} else if ctx.IsTorrent() {
+		resultRenderer = torrentRenderer
+	} else if ctx.IsZsync() {
+		resultRenderer = zsyncRenderer
 	} else {
 		switch GetConfig().OutputMode {
 		case "json":
@@ -369,77 +737,121 @@ func (h *HTTP) mirrorHandler(w http.ResponseWriter, r *http.Request, ctx *Contex
 	status, err := resultRenderer.Write(ctx, results)
 	if err != nil {
 		http.Error(w, err.Error(), status)
+	} else if len(mlist) > 0 && resultRenderer.Type() == "REDIRECT" {
+		ctx.servedBy = mlist[0].Name
 	}
 
 	if !ctx.IsMirrorlist() {
 		logs.LogDownload(resultRenderer.Type(), r.Method, status, results, err)
+		countRequest(resultRenderer.Type(), r.Method, status, results, err)
 		if len(mlist) > 0 && r.Method == "GET" && resultRenderer.Type() == "REDIRECT" {
-			timeout := GetConfig().SameDownloadInterval
-			if r.Header.Get("Range") == "" || timeout == 0 {
-				h.stats.CountDownload(mlist[0], fileInfo)
-			} else {
-				downloaderID := remoteIP+"/"+r.Header.Get("User-Agent")
-				hash := sha256.New()
-				hash.Write([]byte(downloaderID))
-				chk := hex.EncodeToString(hash.Sum(nil))
+			if h.isNewDownload(r, remoteIP, urlPath) {
+				h.stats.CountDownload(mlist[0], fileInfo, clientInfo)
+			}
+			if !fallback {
+				h.connections.add(mlist[0].ID, time.Now())
+			}
+		}
+		if len(mlist) > 0 && err == nil && resultRenderer.Type() == "REDIRECT" {
+			id := mlist[0].ID
+			if fallback {
+				id = 0
+			}
+			h.rates.add(id, time.Now())
+		}
+		if len(mlist) > 0 && err == nil && resultRenderer.Type() == "REDIRECT" && sampleSelection() {
+			logSelection(urlPath, clientInfo, mlist, excluded, fallback)
+		}
+		if len(mlist) > 0 && err == nil && resultRenderer.Type() == "REDIRECT" && h.events.active() {
+			h.events.publish(RedirectEvent{
+				Time:    time.Now().UTC(),
+				Country: clientInfo.CountryCode,
+				Mirror:  mlist[0].Name,
+				Path:    urlPath,
+			})
+		}
+	}
 
-				rconn := h.redis.Get()
-				defer rconn.Close()
+	return
+}
 
-				tempKey := "DOWNLOADED_"+chk+"_"+urlPath
+// fallbackMirrors returns the fallback mirrors of the configuration. Their
+// URL points to the given directory of the repository, the one served by the
+// virtual host of the request.
+func fallbackMirrors(secureOption SecureOption, prefix string) (mlist mirrors.Mirrors) {
+	for i, f := range GetConfig().Fallbacks {
+		// Set the absolute URL
+		var absURL string
+		if utils.HasAnyPrefix(f.URL, "http://", "https://") {
+			absURL = f.URL
+		} else if secureOption == WITHOUTTLS {
+			absURL = "http://" + f.URL
+		} else {
+			absURL = "https://" + f.URL
+		}
+		if prefix != "" {
+			absURL += strings.TrimPrefix(prefix, "/") + "/"
+		}
 
-				prev := ""
-				if h.redis.IsAtLeastVersion("6.2.0") {
-					// Get and set the key in one command.
-					prev, _ = redis.String(rconn.Do("SET", tempKey, 1, "GET", "EX", timeout))
-				} else {
-					prev, _ = redis.String(rconn.Do("GET", tempKey))
-				}
-				if prev == "" {
-					// Only count partial requests as a new download if
-					// we haven't had any recently from the same client
-					// (i.e. same (IP, user-agent) hash). This prevents
-					// from counting multiple times a single client
-					// downloading a single file in pieces, such as
-					// torrent clients when files are used as web seeds.
-					h.stats.CountDownload(mlist[0], fileInfo)
-				}
+		// Create a mirror object and add it to the result
+		mlist = append(mlist, mirrors.Mirror{
+			ID:            i * -1,
+			Name:          fmt.Sprintf("fallback%d", i),
+			HttpURL:       f.URL,
+			CountryCodes:  strings.ToUpper(f.CountryCode),
+			CountryFields: []string{strings.ToUpper(f.CountryCode)},
+			ContinentCode: strings.ToUpper(f.ContinentCode),
+			AbsoluteURL:   absURL})
+	}
+	return
+}
 
-				if ! h.redis.IsAtLeastVersion("6.2.0") {
-					// Set the key anyway to reset the timer.
-					rconn.Send("SET", tempKey, 1, "EX", timeout)
-				}
-			}
-		}
+// isNewDownload returns false when a range request is part of a download
+// already counted for the same client (i.e. same (IP, user-agent) hash)
+// during the last SameDownloadInterval, unless CountRangeRequests is set.
+func (h *HTTP) isNewDownload(r *http.Request, remoteIP, urlPath string) bool {
+	timeout := GetConfig().SameDownloadInterval
+	if r.Header.Get("Range") == "" || timeout == 0 || GetConfig().CountRangeRequests {
+		return true
 	}
 
-	return
+	downloaderID := remoteIP+"/"+r.Header.Get("User-Agent")
+	hash := sha256.New()
+	hash.Write([]byte(downloaderID))
+	chk := hex.EncodeToString(hash.Sum(nil))
+
+	rconn := h.redis.Get()
+	defer rconn.Close()
+
+	tempKey := "DOWNLOADED_"+chk+"_"+urlPath
+
+	prev := ""
+	if h.redis.IsAtLeastVersion("6.2.0") {
+		// Get and set the key in one command.
+		prev, _ = redis.String(rconn.Do("SET", tempKey, 1, "GET", "EX", timeout))
+	} else {
+		prev, _ = redis.String(rconn.Do("GET", tempKey))
+		// Set the key anyway to reset the timer.
+		rconn.Send("SET", tempKey, 1, "EX", timeout)
+	}
+
+	// Only count partial requests as a new download if we haven't had
+	// any recently from the same client. This prevents from counting
+	// multiple times a single client downloading a single file in pieces,
+	// such as torrent clients when files are used as web seeds.
+	return prev == ""
 }
 
-// LoadTemplates pre-loads templates from the configured template directory
+// LoadTemplates pre-loads the templates of the given page, from the
+// TemplatesPath or the embedded defaults
 func (h *HTTP) LoadTemplates(name string) (t *template.Template, err error) {
-	t = template.New("t")
-	t.Funcs(template.FuncMap{
-		"add":       utils.Add,
-		"sizeof":    utils.ReadableSize,
-		"version":   utils.Version,
-		"hostname":  utils.Hostname,
-		"concaturl": utils.ConcatURL,
-		"dateutc":   utils.FormattedDateUTC,
-		"iszero":    utils.IsZero,
-	})
-	t, err = t.ParseFiles(
-		filepath.Clean(GetConfig().Templates+"/base.html"),
-		filepath.Clean(fmt.Sprintf("%s/%s.html", GetConfig().Templates, name)))
-	if err != nil {
-		var e *os.PathError
-		if errors.As(err, &e) {
-			log.Fatalf(fmt.Sprintf("Cannot load template %s: %s", e.Path, e.Err.Error()))
-		} else {
-			log.Fatal(err.Error())
+	t = template.New("t").Funcs(templateFuncs())
+	for _, file := range []string{"base.html", name + ".html"} {
+		if err = parseTemplate(t, file); err != nil {
+			return nil, err
 		}
 	}
-	return t, err
+	return t, nil
 }
 
 // StatsFileNow is the structure containing the latest stats of a file
@@ -460,6 +872,14 @@ type StatsFilePeriod struct {
 func (h *HTTP) fileStatsHandler(w http.ResponseWriter, r *http.Request, ctx *Context) {
 	var output []byte
 
+	// The downloads are counted under the normalized path
+	urlPath, err := normalizePath(r.URL.Path)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	statsPath := repositoryPath(ctx.vhost, urlPath)
+
 	rconn := h.redis.Get()
 	defer rconn.Close()
 
@@ -482,7 +902,7 @@ func (h *HTTP) fileStatsHandler(w http.ResponseWriter, r *http.Request, ctx *Con
 		rconn.Send("MULTI")
 
 		for i := 0; i < 4; i++ {
-			rconn.Send("HGET", fkey, r.URL.Path)
+			rconn.Send("HGET", fkey, statsPath)
 			fkey = fkey[:strings.LastIndex(fkey, "_")]
 		}
 
@@ -508,7 +928,7 @@ func (h *HTTP) fileStatsHandler(w http.ResponseWriter, r *http.Request, ctx *Con
 		}
 		dkey = dkey[:len(dkey)-1]
 
-		v, err := redis.Int64(rconn.Do("HGET", dkey, r.URL.Path))
+		v, err := redis.Int64(rconn.Do("HGET", dkey, statsPath))
 		if err != nil && err != redis.ErrNil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -524,7 +944,12 @@ func (h *HTTP) fileStatsHandler(w http.ResponseWriter, r *http.Request, ctx *Con
 func (h *HTTP) checksumHandler(w http.ResponseWriter, r *http.Request, ctx *Context) {
 
 	// Sanitize path
-	urlPath, err := filesystem.EvaluateFilePath(GetConfig().Repository, r.URL.Path)
+	urlPath, err := normalizePath(r.URL.Path)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	urlPath, err = evaluatePath(ctx.vhost, urlPath)
 	if err != nil {
 		if err == filesystem.ErrOutsideRepo {
 			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
@@ -534,8 +959,13 @@ func (h *HTTP) checksumHandler(w http.ResponseWriter, r *http.Request, ctx *Cont
 		return
 	}
 
+	if status := pathStatus(urlPath); status != http.StatusOK {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
 	// Get details about the requested file
-	fileInfo, err := h.cache.GetFileInfo(urlPath)
+	fileInfo, err := h.cache.GetFileInfo(ctx.Context(), urlPath)
 	if err != nil {
 		log.Errorf("Error while fetching Fileinfo: %s", err.Error())
 		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
@@ -543,17 +973,25 @@ func (h *HTTP) checksumHandler(w http.ResponseWriter, r *http.Request, ctx *Cont
 	}
 
 	var hash string
+	var enabled bool
 
 	if ctx.paramBool("md5") {
-		hash = fileInfo.Md5
+		hash, enabled = fileInfo.Md5, GetConfig().Hashes.MD5
 	} else if ctx.paramBool("sha1") {
-		hash = fileInfo.Sha1
+		hash, enabled = fileInfo.Sha1, GetConfig().Hashes.SHA1
 	} else if ctx.paramBool("sha256") {
-		hash = fileInfo.Sha256
+		hash, enabled = fileInfo.Sha256, GetConfig().Hashes.SHA256
+	} else if ctx.paramBool("sha512") {
+		hash, enabled = fileInfo.Sha512, GetConfig().Hashes.SHA512
 	}
 
 	if len(hash) == 0 {
-		http.Error(w, "Hash type not supported", http.StatusNotFound)
+		if enabled {
+			// The file was indexed before this hash type was enabled
+			http.Error(w, "Hash not available for this file yet", http.StatusNotFound)
+		} else {
+			http.Error(w, "Hash type not supported", http.StatusNotFound)
+		}
 		return
 	}
 
@@ -621,7 +1059,11 @@ func (h *HTTP) mirrorStatsHandler(w http.ResponseWriter, r *http.Request, ctx *C
 	}
 
 	var mirrorsIDs []int
-	for id := range mirrorsMap {
+	for id, name := range mirrorsMap {
+		// Only list the mirrors of the virtual host
+		if ctx.vhost != nil && !ctx.vhost.HasMirror(name) {
+			continue
+		}
 		// We need a common order to iterate the
 		// results from Redis.
 		mirrorsIDs = append(mirrorsIDs, id)
@@ -707,11 +1149,18 @@ func (h *HTTP) mirrorStatsHandler(w http.ResponseWriter, r *http.Request, ctx *C
 		results[i].PercentB = float32(results[i].Bytes) * 100 / float32(maxbytes)
 	}
 
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	err = ctx.Templates().mirrorstats.ExecuteTemplate(w, "base", MirrorStatsPage{results, mlist, GetConfig().LocalJSPath, hasTZAdjustement})
+	var buf bytes.Buffer
+	t, err := ctx.localize(ctx.Templates().mirrorstats)
+	if err == nil {
+		err = t.ExecuteTemplate(&buf, "base", MirrorStatsPage{results, mlist, GetConfig().LocalJSPath, hasTZAdjustement})
+	}
 	if err != nil {
 		log.Errorf("HTTP error: %s", err.Error())
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, no-cache")
+	writePage(w, ctx.Request(), buf.Bytes(), ctx.Templates().modTime)
 }
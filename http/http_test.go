@@ -4,21 +4,30 @@
 package http
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/http/httputil"
+	"net/url"
 	"os"
 	"path"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"testing"
+	"time"
 
 	. "github.com/etix/mirrorbits/config"
 	"github.com/etix/mirrorbits/core"
 	"github.com/etix/mirrorbits/mirrors"
+	"github.com/etix/mirrorbits/signedurl"
 	. "github.com/etix/mirrorbits/testing"
 	"github.com/rafaeljusto/redigomock"
 )
@@ -89,6 +98,20 @@ func makeResponse(code int, headers map[string]string) *http.Response {
 			},
 			ContentLength: -1,
 		}
+	case 400:
+		resp = http.Response{
+			Status:	    "400 Bad Request",
+			StatusCode: 400,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header: http.Header{
+				"Content-Type": {"text/plain; charset=utf-8"},
+				"Server": {"Mirrorbits/"+core.VERSION},
+				"X-Content-Type-Options": {"nosniff"},
+			},
+			ContentLength: -1,
+		}
 	case 403:
 		resp = http.Response{
 			Status:	    "403 Forbidden",
@@ -268,7 +291,7 @@ func prepareTest(t *testing.T, filenames []string) (testContext, error) {
 	// Set mirrorbits configuration
 	SetConfiguration(&Configuration{
 		Repository: repoDir,
-		Templates: templatesDir,
+		TemplatesPath: templatesDir,
 		OutputMode: "redirect",
 		MaxLinkHeaders: 5,
 		Fallbacks: []Fallback{
@@ -323,9 +346,9 @@ func TestMirrorHandler4xx(t *testing.T) {
 	}
 
 	// Request a file outside of the local repo
-	// -> return 403 "Forbidden"
+	// -> return 400 "Bad Request"
 	resp = doRequest(ctx.Server, "GET", "/../foobar", noHeader)
-	want = makeResponse(403, noHeader)
+	want = makeResponse(400, noHeader)
 	if !respEqual(want, resp) {
 		t.Fatalf("Expected: %v, got: %v", want, resp)
 	}
@@ -346,14 +369,14 @@ var mockedCmds302Fallback = [][]mockedCmd{
 	// Database is unreachable (redis error "connection refused")
 	{
 		{
-			Cmd: []string{"HMGET", "FILE_"+testFile, "size", "modTime", "sha1", "sha256", "md5"},
+			Cmd: []string{"HMGET", "FILE_"+testFile, "size", "modTime", "sha1", "sha256", "md5", "sha512"},
 			Res: connectionRefusedError(),
 		},
 	},
 	// Database is loading
 	{
 		{
-			Cmd: []string{"HMGET", "FILE_"+testFile, "size", "modTime", "sha1", "sha256", "md5"},
+			Cmd: []string{"HMGET", "FILE_"+testFile, "size", "modTime", "sha1", "sha256", "md5", "sha512"},
 			Res: redisIsLoadingError(),
 		},
 	},
@@ -362,7 +385,7 @@ var mockedCmds302Fallback = [][]mockedCmd{
 	// was updated with new files, but mirrorbits didn't rescan it yet)
 	{
 		{
-			Cmd: []string{"HMGET", "FILE_"+testFile, "size", "modTime", "sha1", "sha256", "md5"},
+			Cmd: []string{"HMGET", "FILE_"+testFile, "size", "modTime", "sha1", "sha256", "md5", "sha512"},
 			Res: []string{"", "", "", "", "", ""},
 		},
 	},
@@ -370,8 +393,8 @@ var mockedCmds302Fallback = [][]mockedCmd{
 	// present in the database, however no mirror have this file yet
 	{
 		{
-			Cmd: []string{"HMGET", "FILE_"+testFile, "size", "modTime", "sha1", "sha256", "md5"},
-			Res: []string{testFileSize, testFileModTime, "", testFileSha256, ""},
+			Cmd: []string{"HMGET", "FILE_"+testFile, "size", "modTime", "sha1", "sha256", "md5", "sha512"},
+			Res: []string{testFileSize, testFileModTime, "", testFileSha256, "", ""},
 		},
 		{
 			Cmd: []string{"SMEMBERS", "FILEMIRRORS_"+testFile},
@@ -389,8 +412,8 @@ var mockedCmds302Mirror = [][]mockedCmd{
 	// doesn't seem to be true, as this test case shows.
 	{
 		{
-			Cmd: []string{"HMGET", "FILE_"+testFile, "size", "modTime", "sha1", "sha256", "md5"},
-			Res: []string{testFileSize, testFileModTime, "", testFileSha256, ""},
+			Cmd: []string{"HMGET", "FILE_"+testFile, "size", "modTime", "sha1", "sha256", "md5", "sha512"},
+			Res: []string{testFileSize, testFileModTime, "", testFileSha256, "", ""},
 		},
 		{
 			Cmd: []string{"SMEMBERS", "FILEMIRRORS_"+testFile},
@@ -406,8 +429,8 @@ var mockedCmds302Mirror = [][]mockedCmd{
 			},
 		},
 		{
-			Cmd: []string{"HMGET", "FILEINFO_42_"+testFile, "size", "modTime", "sha1", "sha256", "md5"},
-			Res: []string{testFileSize, testFileModTime, "", "", ""},
+			Cmd: []string{"HMGET", "FILEINFO_42_"+testFile, "size", "modTime", "sha1", "sha256", "md5", "sha512"},
+			Res: []string{testFileSize, testFileModTime, "", "", "", ""},
 		},
 	},
 }
@@ -418,8 +441,8 @@ var mockedCmds304 = [][]mockedCmd{
 	// if mirrors have the file.
 	{
 		{
-			Cmd: []string{"HMGET", "FILE_"+testFile, "size", "modTime", "sha1", "sha256", "md5"},
-			Res: []string{testFileSize, testFileModTime, "", testFileSha256, ""},
+			Cmd: []string{"HMGET", "FILE_"+testFile, "size", "modTime", "sha1", "sha256", "md5", "sha512"},
+			Res: []string{testFileSize, testFileModTime, "", testFileSha256, "", ""},
 		},
 	},
 }
@@ -529,3 +552,655 @@ func TestMirrorHandler3xx(t *testing.T) {
 		})
 	}
 }
+
+func TestRemoteIP(t *testing.T) {
+	tests := map[string]struct {
+		header  string
+		proxies []string
+		remote  string
+		headers map[string]string
+		ip      string
+	}{
+		"no header": {"", nil, "192.0.2.1:1234", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "192.0.2.1"},
+		"xff": {"X-Forwarded-For", nil, "192.0.2.1:1234", map[string]string{"X-Forwarded-For": "198.51.100.1, 192.0.2.1"}, "198.51.100.1"},
+		"xff missing": {"X-Forwarded-For", nil, "192.0.2.1:1234", nil, "192.0.2.1"},
+		"ipv6 remote": {"X-Forwarded-For", nil, "[2001:db8::1]:1234", nil, "2001:db8::1"},
+		"real ip": {"X-Real-IP", []string{"192.0.2.0/24"}, "192.0.2.1:1234", map[string]string{"X-Real-IP": "198.51.100.1"}, "198.51.100.1"},
+		"untrusted proxy": {"X-Real-IP", []string{"10.0.0.0/8"}, "192.0.2.1:1234", map[string]string{"X-Real-IP": "198.51.100.1"}, "192.0.2.1"},
+		"xff spoofed": {"X-Forwarded-For", []string{"192.0.2.0/24"}, "192.0.2.1:1234", map[string]string{"X-Forwarded-For": "203.0.113.1, 198.51.100.1, 192.0.2.2"}, "198.51.100.1"},
+		"invalid header": {"X-Real-IP", nil, "192.0.2.1:1234", map[string]string{"X-Real-IP": "unknown"}, "192.0.2.1"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			SetConfiguration(&Configuration{
+				ClientIPHeader: test.header,
+				TrustedProxies: test.proxies,
+			})
+			r := makeRequest("GET", "/", test.headers)
+			r.RemoteAddr = test.remote
+			if ip := remoteIP(r); ip != test.ip {
+				t.Fatalf("Expected %s, got %s", test.ip, ip)
+			}
+		})
+	}
+}
+
+func TestTrackInFlight(t *testing.T) {
+	h := &HTTP{}
+	running := make(chan struct{})
+	release := make(chan struct{})
+
+	handler := h.trackInFlight(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(running)
+		<-release
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(httptest.NewRecorder(), makeRequest("GET", "/", nil))
+		close(done)
+	}()
+
+	<-running
+	if n := atomic.LoadInt64(&h.inFlight); n != 1 {
+		t.Fatalf("Expected 1 request in flight, got %d", n)
+	}
+	close(release)
+	<-done
+	if n := atomic.LoadInt64(&h.inFlight); n != 0 {
+		t.Fatalf("Expected no request in flight, got %d", n)
+	}
+}
+
+func TestPathStatus(t *testing.T) {
+	tests := map[string]struct {
+		allow  []string
+		block  []string
+		path   string
+		status int
+	}{
+		"no rules":             {nil, nil, "/staging/foo.iso", http.StatusOK},
+		"blocked":              {nil, []string{"/staging"}, "/staging/foo.iso", http.StatusNotFound},
+		"not blocked":          {nil, []string{"/staging"}, "/release/foo.iso", http.StatusOK},
+		"allowed":              {[]string{"/release"}, nil, "/release/foo.iso", http.StatusOK},
+		"not allowed":          {[]string{"/release"}, nil, "/staging/foo.iso", http.StatusForbidden},
+		"block over allow":     {[]string{"/release"}, []string{"/release/internal"}, "/release/internal/foo.iso", http.StatusNotFound},
+		"allowed beside block": {[]string{"/release"}, []string{"/release/internal"}, "/release/foo.iso", http.StatusOK},
+		"regexp block":         {[]string{"/release"}, []string{`regexp:\.part$`}, "/release/foo.iso.part", http.StatusNotFound},
+		"regexp allow":         {[]string{`regexp:\.iso$`}, nil, "/release/foo.iso", http.StatusOK},
+		"blocked not allowed":  {[]string{"/release"}, []string{"/staging"}, "/staging/foo.iso", http.StatusNotFound},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			SetConfiguration(&Configuration{
+				PathAllowlist: test.allow,
+				PathBlocklist: test.block,
+			})
+			if status := pathStatus(test.path); status != test.status {
+				t.Fatalf("Expected %d, got %d", test.status, status)
+			}
+		})
+	}
+}
+
+// Test that the filtered paths are refused before querying the database
+func TestMirrorHandlerPathFilter(t *testing.T) {
+	ctx, err := prepareTest(t, []string{testFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	noHeader := map[string]string{}
+
+	config := *GetConfig()
+	config.PathBlocklist = []string{testFile}
+	SetConfiguration(&config)
+
+	resp := doRequest(ctx.Server, "GET", testFile, noHeader)
+	want := makeResponse(404, noHeader)
+	if !respEqual(want, resp) {
+		t.Fatalf("Expected: %v, got: %v", want, resp)
+	}
+
+	config.PathBlocklist = nil
+	config.PathAllowlist = []string{"/nonexistent"}
+	SetConfiguration(&config)
+
+	resp = doRequest(ctx.Server, "GET", testFile, noHeader)
+	want = makeResponse(403, noHeader)
+	if !respEqual(want, resp) {
+		t.Fatalf("Expected: %v, got: %v", want, resp)
+	}
+
+	for _, err := range getMockErrors(ctx.MockedConn) {
+		t.Errorf("Unexpected database access: %s", err)
+	}
+}
+
+// Test that the traversals are refused before querying the database
+func TestMirrorHandlerInvalidPath(t *testing.T) {
+	ctx, err := prepareTest(t, []string{testFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	noHeader := map[string]string{}
+
+	paths := []string{
+		"/%2e%2e/etc/passwd",
+		"/foo/%2E%2E/%2e%2e/etc/passwd",
+		"/%252e%252e/etc/passwd",
+		"/..%5c..%5cetc/passwd",
+		"/testy.tgz%00.html",
+	}
+
+	for _, path := range paths {
+		resp := doRequest(ctx.Server, "GET", path, noHeader)
+		want := makeResponse(400, noHeader)
+		if !respEqual(want, resp) {
+			t.Fatalf("%s: expected: %v, got: %v", path, want, resp)
+		}
+	}
+
+	for _, err := range getMockErrors(ctx.MockedConn) {
+		t.Errorf("Unexpected database access: %s", err)
+	}
+}
+
+// Test the FallbackMode when no mirror can serve the file
+func TestMirrorHandlerFallbackMode(t *testing.T) {
+	ctx, err := prepareTest(t, []string{testFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := "0123456789"
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repo"+testFile {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.Header.Get("X-Broken-Range") {
+		case "length":
+			// A partial content not matching its length
+			w.Header().Set("Content-Range", "bytes 0-1/10")
+			w.WriteHeader(http.StatusPartialContent)
+			io.WriteString(w, content[2:6])
+			return
+		case "shifted":
+			// A partial content not matching the requested range
+			w.Header().Set("Content-Range", "bytes 0-3/10")
+			w.WriteHeader(http.StatusPartialContent)
+			io.WriteString(w, content[0:4])
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, testFile, time.Unix(1500000000, 0), strings.NewReader(content))
+	}))
+	defer origin.Close()
+
+	config := *GetConfig()
+
+	// The file is not found on any mirror -> return 404 "Not Found"
+	config.FallbackMode = "notfound"
+	SetConfiguration(&config)
+
+	for i, commands := range mockedCmds302Fallback {
+		mockCommands(ctx.MockedConn, commands)
+		resp := doRequest(ctx.Server, "GET", testFile, map[string]string{})
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("#%d: Expected 404, got %d", i, resp.StatusCode)
+		}
+		ctx.MockedConn.Clear()
+		ctx.MirrorCache.Clear()
+	}
+
+	// The file is streamed from the origin, including partial requests
+	config.FallbackMode = "proxy"
+	config.FallbackOrigin = origin.URL + "/repo/"
+	SetConfiguration(&config)
+
+	tests := map[string]struct {
+		headers      map[string]string
+		status       int
+		body         string
+		contentRange string
+	}{
+		"full":              {map[string]string{}, http.StatusOK, content, ""},
+		"range":             {map[string]string{"Range": "bytes=2-5"}, http.StatusPartialContent, "2345", "bytes 2-5/10"},
+		"suffix range":      {map[string]string{"Range": "bytes=-3"}, http.StatusPartialContent, "789", "bytes 7-9/10"},
+		"if-range match":    {map[string]string{"Range": "bytes=4-", "If-Range": `"v1"`}, http.StatusPartialContent, "456789", "bytes 4-9/10"},
+		"if-range mismatch": {map[string]string{"Range": "bytes=4-", "If-Range": `"v0"`}, http.StatusOK, content, ""},
+		"if-none-match":     {map[string]string{"If-None-Match": `"v1"`}, http.StatusNotModified, "", ""},
+		"if-modified-since": {map[string]string{"If-Modified-Since": time.Unix(1500000000, 0).UTC().Format(http.TimeFormat)}, http.StatusNotModified, "", ""},
+		"invalid range":     {map[string]string{"Range": "bytes=20-30"}, http.StatusRequestedRangeNotSatisfiable, "", "bytes */10"},
+		"broken length":     {map[string]string{"Range": "bytes=2-5", "X-Broken-Range": "length"}, http.StatusBadGateway, "", ""},
+		"shifted range":     {map[string]string{"Range": "bytes=2-5", "X-Broken-Range": "shifted"}, http.StatusBadGateway, "", ""},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockCommands(ctx.MockedConn, mockedCmds302Fallback[0])
+			defer ctx.MockedConn.Clear()
+			defer ctx.MirrorCache.Clear()
+
+			resp := doRequest(ctx.Server, "GET", testFile, tt.headers)
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.status {
+				t.Fatalf("Expected %d, got %d", tt.status, resp.StatusCode)
+			}
+			if resp.Header.Get("Content-Range") != tt.contentRange {
+				t.Fatalf("Expected Content-Range %q, got %q", tt.contentRange, resp.Header.Get("Content-Range"))
+			}
+			if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+				return
+			}
+			if string(body) != tt.body {
+				t.Fatalf("Expected body %q, got %q", tt.body, body)
+			}
+			if resp.Header.Get("Content-Length") != strconv.Itoa(len(tt.body)) {
+				t.Fatalf("Invalid Content-Length %s", resp.Header.Get("Content-Length"))
+			}
+			if resp.Header.Get("Accept-Ranges") != "bytes" {
+				t.Fatalf("Expected the Accept-Ranges of the origin")
+			}
+			if len(resp.Header.Values("Server")) > 1 {
+				t.Fatalf("Expected a single Server header, got %v", resp.Header.Values("Server"))
+			}
+		})
+	}
+}
+
+func TestIsNewDownload(t *testing.T) {
+	tests := map[string]struct {
+		interval   int
+		countRange bool
+		headers    map[string]string
+	}{
+		"no range":    {600, false, nil},
+		"no interval": {0, false, map[string]string{"Range": "bytes=0-10"}},
+		"count range": {600, true, map[string]string{"Range": "bytes=0-10"}},
+	}
+
+	h := &HTTP{}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			SetConfiguration(&Configuration{
+				SameDownloadInterval: test.interval,
+				CountRangeRequests:   test.countRange,
+			})
+			// The database is not reached in those cases
+			r := makeRequest("GET", testFile, test.headers)
+			if !h.isNewDownload(r, "192.0.2.1", testFile) {
+				t.Fatalf("Expected a new download")
+			}
+		})
+	}
+}
+
+func TestSetMirrorHeader(t *testing.T) {
+	m := mirrors.Mirror{Name: "M1", Distance: 123.4, ComputedScore: 42}
+
+	tests := map[string]struct {
+		expose   bool
+		fallback bool
+		none     bool
+		expected string
+	}{
+		"disabled":    {false, false, false, ""},
+		"mirror":      {true, false, false, "M1; distance=123; score=42"},
+		"fallback":    {true, true, false, "M1; fallback"},
+		"error":       {true, false, true, "none"},
+		"no fallback": {true, true, true, "none; fallback"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			SetConfiguration(&Configuration{
+				ExposeMirrorHeader: test.expose,
+			})
+			w := httptest.NewRecorder()
+			if test.none {
+				setNoMirrorHeader(w, test.fallback)
+			} else {
+				setMirrorHeader(w, m, test.fallback)
+			}
+			if v := w.Header().Get("X-Mirrorbits-Mirror"); v != test.expected {
+				t.Fatalf("Expected %q, got %q", test.expected, v)
+			}
+		})
+	}
+}
+
+func TestProbes(t *testing.T) {
+	ctx, err := prepareTest(t, []string{testFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := *GetConfig()
+	config.LivenessPath = "/healthz"
+	config.ReadinessPath = "/readyz"
+	SetConfiguration(&config)
+
+	mirrorDown := map[string]string{"ID": "1", "name": "m1", "http": mirrorURL, "enabled": "1"}
+	mirrorUp := map[string]string{"ID": "1", "name": "m1", "http": mirrorURL, "enabled": "1", "httpUp": "1"}
+
+	tests := map[string]struct {
+		path     string
+		commands []mockedCmd
+		status   int
+	}{
+		"liveness": {"/healthz", nil, http.StatusOK},
+		"database unreachable": {"/readyz", []mockedCmd{
+			{Cmd: []string{"HKEYS", "MIRRORS"}, Res: connectionRefusedError()},
+		}, http.StatusServiceUnavailable},
+		"no mirror up": {"/readyz", []mockedCmd{
+			{Cmd: []string{"HKEYS", "MIRRORS"}, Res: []string{"1"}},
+			{Cmd: []string{"HGETALL", "MIRROR_1"}, Res: mirrorDown},
+		}, http.StatusServiceUnavailable},
+		"ready": {"/readyz", []mockedCmd{
+			{Cmd: []string{"HKEYS", "MIRRORS"}, Res: []string{"1"}},
+			{Cmd: []string{"HGETALL", "MIRROR_1"}, Res: mirrorUp},
+		}, http.StatusOK},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockCommands(ctx.MockedConn, tt.commands)
+			defer ctx.MockedConn.Clear()
+			defer ctx.MirrorCache.Clear()
+
+			resp := doRequest(ctx.Server, "GET", tt.path, map[string]string{})
+			if resp.StatusCode != tt.status {
+				t.Fatalf("Expected %d, got %d", tt.status, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestHeadRequest(t *testing.T) {
+	ctx, err := prepareTest(t, []string{testFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	modTime := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+
+	tests := map[string]struct {
+		headers  map[string]string
+		status   int
+		location string
+	}{
+		"redirect":     {map[string]string{}, http.StatusFound, mirrorURL + testFile[1:]},
+		"range":        {map[string]string{"Range": "bytes=0-10"}, http.StatusFound, mirrorURL + testFile[1:]},
+		"not modified": {map[string]string{"If-Modified-Since": modTime}, http.StatusNotModified, ""},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockCommands(ctx.MockedConn, mockedCmds302Mirror[0])
+			defer ctx.MockedConn.Clear()
+			defer ctx.MirrorCache.Clear()
+
+			resp := doRequest(ctx.Server, "HEAD", testFile, tt.headers)
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.status {
+				t.Fatalf("Expected %d, got %d", tt.status, resp.StatusCode)
+			}
+			if l := resp.Header.Get("Location"); l != tt.location {
+				t.Fatalf("Expected location %q, got %q", tt.location, l)
+			}
+			if len(body) > 0 {
+				t.Fatalf("Expected no body, got %q", body)
+			}
+		})
+	}
+}
+
+// Test that the redirects are signed with SignedURLs
+func TestSignedRedirect(t *testing.T) {
+	ctx, err := prepareTest(t, []string{testFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := *GetConfig()
+	config.SignedURLs = true
+	config.SignedURLSecret = "s3cr3t"
+	config.SignedURLExpiry = 60
+	SetConfiguration(&config)
+
+	mockCommands(ctx.MockedConn, mockedCmds302Mirror[0])
+	defer ctx.MockedConn.Clear()
+	defer ctx.MirrorCache.Clear()
+
+	resp := doRequest(ctx.Server, "GET", testFile, map[string]string{})
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("Expected %d, got %d", http.StatusFound, resp.StatusCode)
+	}
+	l := resp.Header.Get("Location")
+	if !strings.HasPrefix(l, mirrorURL+testFile[1:]+"?") {
+		t.Fatalf("Unexpected location %q", l)
+	}
+	location, err := url.Parse(l)
+	if err != nil {
+		t.Fatalf("Invalid location: %s", err)
+	}
+	if err := signedurl.Verify(location, "s3cr3t", time.Now()); err != nil {
+		t.Fatalf("Expected a valid signature, got %s", err)
+	}
+	if err := signedurl.Verify(location, "s3cr3t", time.Now().Add(2*time.Minute)); err != signedurl.ErrExpired {
+		t.Fatalf("Expected the location to expire, got %v", err)
+	}
+}
+
+// Test that the maintenance mode refuses the file requests only
+func TestMaintenanceMode(t *testing.T) {
+	ctx, err := prepareTest(t, []string{testFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := *GetConfig()
+	config.MaintenanceMode = true
+	config.MaintenanceMessage = "Back soon"
+	config.MaintenanceRetryAfter = 120
+	config.LivenessPath = "/healthz"
+	SetConfiguration(&config)
+
+	resp := doRequest(ctx.Server, "GET", testFile, map[string]string{})
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Expected %d, got %d", http.StatusServiceUnavailable, resp.StatusCode)
+	}
+	if r := resp.Header.Get("Retry-After"); r != "120" {
+		t.Fatalf("Expected Retry-After 120, got %q", r)
+	}
+	if !strings.Contains(string(body), "Back soon") {
+		t.Fatalf("Expected the maintenance message, got %q", body)
+	}
+	for _, err := range getMockErrors(ctx.MockedConn) {
+		t.Errorf("Unexpected database access: %s", err)
+	}
+
+	// The probes keep working
+	resp = doRequest(ctx.Server, "GET", "/healthz", map[string]string{})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected the liveness probe to succeed, got %d", resp.StatusCode)
+	}
+
+	// The runtime switch overrides the configuration until the reload
+	ctx.Server.SetMaintenance(false, "")
+	mockCommands(ctx.MockedConn, mockedCmds302Mirror[0])
+	resp = doRequest(ctx.Server, "GET", testFile, map[string]string{})
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("Expected %d, got %d", http.StatusFound, resp.StatusCode)
+	}
+	ctx.MockedConn.Clear()
+	ctx.MirrorCache.Clear()
+
+	config.MaintenanceMode = false
+	SetConfiguration(&config)
+	ctx.Server.SetMaintenance(true, "Migrating")
+	if on, message, override := ctx.Server.Maintenance(); !on || message != "Migrating" || !override {
+		t.Fatalf("Unexpected maintenance state: %t %q %t", on, message, override)
+	}
+	ctx.Server.resetMaintenance()
+	if on, _, override := ctx.Server.Maintenance(); on || override {
+		t.Fatalf("Expected the configuration to take over on reload")
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	ctx, err := prepareTest(t, []string{testFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ctx.Server.Simulate(context.Background(), "not an ip", testFile); err != ErrInvalidIP {
+		t.Fatalf("Expected ErrInvalidIP, got %v", err)
+	}
+
+	tests := map[string]struct {
+		commands []mockedCmd
+		fallback bool
+		url      string
+	}{
+		"mirror":   {mockedCmds302Mirror[0], false, mirrorURL},
+		"fallback": {mockedCmds302Fallback[3], true, fallbackURL},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockCommands(ctx.MockedConn, tt.commands)
+			defer ctx.MockedConn.Clear()
+			defer ctx.MirrorCache.Clear()
+
+			results, err := ctx.Server.Simulate(context.Background(), "192.0.2.1", testFile)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if errs := getMockErrors(ctx.MockedConn); len(errs) > 0 {
+				t.Fatalf("Unexpected redis errors: %v", errs)
+			}
+			if results.Fallback != tt.fallback {
+				t.Fatalf("Expected fallback to be %t", tt.fallback)
+			}
+			if len(results.MirrorList) == 0 || results.MirrorList[0].AbsoluteURL != tt.url {
+				t.Fatalf("Expected %s to be chosen, got %v", tt.url, results.MirrorList)
+			}
+		})
+	}
+}
+
+// Test that a request reaching its deadline is not served from the fallbacks
+func TestMirrorHandlerTimeout(t *testing.T) {
+	ctx, err := prepareTest(t, []string{testFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := *GetConfig()
+	config.RedirectTimeout = 5
+	SetConfiguration(&config)
+
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	req := makeRequest("GET", testFile, map[string]string{}).WithContext(expired)
+	recorder := httptest.NewRecorder()
+	ctx.Server.requestDispatcher(recorder, req)
+	resp := recorder.Result()
+
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") == "" {
+		t.Fatalf("Expected a 503 with a Retry-After header, got: %s", dump(resp))
+	}
+	for _, err := range getMockErrors(ctx.MockedConn) {
+		t.Errorf("Unexpected database access: %s", err)
+	}
+}
+
+func TestResponseHeaders(t *testing.T) {
+	ctx, err := prepareTest(t, []string{testFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := *GetConfig()
+	config.ResponseHeaders = map[string]string{
+		"Cache-Control":          "no-store",
+		"X-Content-Type-Options": "nosniff",
+		"Server":                 "",
+	}
+	config.ResponseHeadersByStatus = map[string]map[string]string{
+		"4xx": {"Cache-Control": "max-age=60"},
+	}
+	SetConfiguration(&config)
+
+	mockCommands(ctx.MockedConn, mockedCmds302Fallback[0])
+	defer ctx.MockedConn.Clear()
+	defer ctx.MirrorCache.Clear()
+
+	tests := map[string]struct {
+		path         string
+		status       int
+		cacheControl string
+	}{
+		"fallback": {testFile, http.StatusFound, "no-store"},
+		"error":    {"/foobar", http.StatusNotFound, "max-age=60"},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp := doRequest(ctx.Server, "GET", tt.path, map[string]string{})
+			if resp.StatusCode != tt.status {
+				t.Fatalf("Expected %d, got: %s", tt.status, dump(resp))
+			}
+			if resp.Header.Get("Cache-Control") != tt.cacheControl || resp.Header.Get("X-Content-Type-Options") != "nosniff" {
+				t.Fatalf("Expected the configured headers, got: %s", dump(resp))
+			}
+			if _, ok := resp.Header["Server"]; ok {
+				t.Fatalf("Expected the Server header to be removed, got: %s", dump(resp))
+			}
+		})
+	}
+}
+
+func TestFileStatsHandler(t *testing.T) {
+	ctx, err := prepareTest(t, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.MockedConn.Clear()
+
+	fkey := fmt.Sprintf("STATS_FILE_%s", time.Now().Format("2006_01_02"))
+	ctx.MockedConn.Command("MULTI")
+	for i := 0; i < 4; i++ {
+		ctx.MockedConn.Command("HGET", fkey, "/a/b.iso")
+		fkey = fkey[:strings.LastIndex(fkey, "_")]
+	}
+	ctx.MockedConn.Command("EXEC").Expect([]any{int64(1), int64(2), int64(3), int64(4)})
+
+	// The stats are read under the normalized path
+	for _, path := range []string{"/a//b.iso", "/a/./b.iso"} {
+		resp := doRequest(ctx.Server, "GET", path+"?stats", map[string]string{})
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: expected a 200, got: %s", path, dump(resp))
+		}
+		var stats StatsFileNow
+		if err := json.Unmarshal(body, &stats); err != nil || stats.Total != 4 {
+			t.Fatalf("%s: unexpected stats %q (%v)", path, body, err)
+		}
+	}
+
+	for _, path := range []string{"/../b.iso", "/a/%00b.iso"} {
+		resp := doRequest(ctx.Server, "GET", path+"?stats", map[string]string{})
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s: expected a 400, got: %s", path, dump(resp))
+		}
+	}
+}
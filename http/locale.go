@@ -0,0 +1,200 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	. "github.com/etix/mirrorbits/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Catalog holds the translations of the messages of the web pages, by
+// locale. The messages are identified by their English text.
+type Catalog map[string]map[string]string
+
+// localesDir returns the directory of the custom message files, empty if
+// there is no TemplatesPath
+func localesDir() string {
+	if GetConfig().TemplatesPath == "" {
+		return ""
+	}
+	return filepath.Join(GetConfig().TemplatesPath, "locales")
+}
+
+// LoadCatalog loads the message files of the locales directory of the
+// templates, one YAML file per locale (i.e. locales/fr.yaml) mapping the
+// English messages to their translation. A missing directory isn't an
+// error, the pages are then rendered as written in the templates.
+func LoadCatalog(dir string) (Catalog, error) {
+	return loadCatalogFS(os.DirFS(dir))
+}
+
+// loadCatalogFS loads the message files found at the root of fsys
+func loadCatalogFS(fsys fs.FS) (Catalog, error) {
+	files, err := fs.Glob(fsys, "*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	catalog := make(Catalog, len(files))
+	for _, file := range files {
+		locale, messages, err := loadLocale(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		catalog[locale] = messages
+	}
+	return catalog, nil
+}
+
+// loadLocale loads the given message file, named after its locale
+func loadLocale(fsys fs.FS, file string) (locale string, messages map[string]string, err error) {
+	content, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return "", nil, err
+	}
+	messages = make(map[string]string)
+	if err = yaml.Unmarshal(content, &messages); err != nil {
+		return "", nil, fmt.Errorf("%s: %s", file, err)
+	}
+	locale = strings.ToLower(strings.TrimSuffix(path.Base(file), ".yaml"))
+	return locale, messages, nil
+}
+
+// translate returns the translation of the message in the given locale,
+// or in the DefaultLocale, or the message itself if there is none
+func (c Catalog) translate(locale, message string) string {
+	if s, ok := c[locale][message]; ok && s != "" {
+		return s
+	}
+	if s, ok := c[defaultLocale()][message]; ok && s != "" {
+		return s
+	}
+	return message
+}
+
+// negotiate returns the locale of the catalog best matching the given
+// Accept-Language header, or the DefaultLocale
+func (c Catalog) negotiate(header string) string {
+	type tag struct {
+		name string
+		q    float64
+	}
+	var tags []tag
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		if name == "" || name == "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			tags = append(tags, tag{name, q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].q > tags[j].q
+	})
+
+	for _, t := range tags {
+		for _, name := range []string{t.name, strings.SplitN(t.name, "-", 2)[0]} {
+			if _, ok := c[name]; ok || name == defaultLocale() {
+				return name
+			}
+		}
+	}
+	return defaultLocale()
+}
+
+// defaultLocale returns the locale the pages are rendered in when none of
+// the locales accepted by the client is available
+func defaultLocale() string {
+	return strings.ToLower(GetConfig().DefaultLocale)
+}
+
+// translateFunc returns the template function translating the messages in
+// the given locale. Extra arguments are formatted into the message.
+func translateFunc(catalog Catalog, locale string) func(string, ...interface{}) string {
+	return func(message string, args ...interface{}) string {
+		s := catalog.translate(locale, message)
+		if len(args) > 0 {
+			return fmt.Sprintf(s, args...)
+		}
+		return s
+	}
+}
+
+// localizedTemplates holds the copies of the templates translated in each
+// locale, by template and then by locale
+type localizedTemplates map[*template.Template]map[string]*template.Template
+
+// localizeTemplates translates the given templates in every locale of the
+// catalog and in the DefaultLocale, once and for all the requests
+func localizeTemplates(catalog Catalog, templates ...*template.Template) (localizedTemplates, error) {
+	if len(catalog) == 0 {
+		return nil, nil
+	}
+	locales := []string{defaultLocale()}
+	for locale := range catalog {
+		locales = append(locales, locale)
+	}
+
+	localized := make(localizedTemplates, len(templates))
+	for _, t := range templates {
+		localized[t] = make(map[string]*template.Template, len(locales))
+		for _, locale := range locales {
+			lt, err := translateTemplate(t, catalog, locale)
+			if err != nil {
+				return nil, err
+			}
+			localized[t][locale] = lt
+		}
+	}
+	return localized, nil
+}
+
+// translateTemplate returns a copy of the template with its messages
+// translated in the given locale
+func translateTemplate(t *template.Template, catalog Catalog, locale string) (*template.Template, error) {
+	t, err := t.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return t.Funcs(template.FuncMap{"T": translateFunc(catalog, locale)}), nil
+}
+
+// localize returns the given template with its messages translated in
+// the locale negotiated with the client. The template is returned as is
+// if there are no translations.
+func (c *Context) localize(t *template.Template) (*template.Template, error) {
+	catalog := c.t.catalog
+	if len(catalog) == 0 {
+		return t, nil
+	}
+	locale := catalog.negotiate(c.r.Header.Get("Accept-Language"))
+	c.w.Header().Set("Content-Language", locale)
+	c.w.Header().Add("Vary", "Accept-Language")
+
+	if lt, ok := c.t.localized[t][locale]; ok {
+		return lt, nil
+	}
+	// The same templates are shared by all the requests, work on a copy
+	return translateTemplate(t, catalog, locale)
+}
@@ -0,0 +1,175 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"bytes"
+	"html/template"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/etix/mirrorbits/config"
+)
+
+func TestLoadCatalog(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "FR.yaml"), []byte("Mirrors: Miroirs\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	catalog, err := LoadCatalog(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if catalog["fr"]["Mirrors"] != "Miroirs" {
+		t.Fatalf("Expected the French messages to be loaded, got %v", catalog)
+	}
+
+	if catalog, err = LoadCatalog(filepath.Join(dir, "missing")); err != nil || len(catalog) != 0 {
+		t.Fatalf("Expected an empty catalog, got %v (%v)", catalog, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "de.yaml"), []byte("- invalid"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = LoadCatalog(dir); err == nil {
+		t.Fatalf("Expected an error on an invalid message file")
+	}
+}
+
+func TestCatalogNegotiate(t *testing.T) {
+	defer SetConfiguration(GetConfig())
+	SetConfiguration(&Configuration{
+		DefaultLocale: "en",
+	})
+
+	catalog := Catalog{
+		"fr":    {},
+		"pt-br": {},
+	}
+
+	tests := map[string]struct {
+		header   string
+		expected string
+	}{
+		"none":         {"", "en"},
+		"exact":        {"fr", "fr"},
+		"region":       {"pt-BR,pt;q=0.8", "pt-br"},
+		"base":         {"fr-CA", "fr"},
+		"quality":      {"de;q=0.9,fr;q=0.5,en;q=0.7", "en"},
+		"unavailable":  {"de, it;q=0.5", "en"},
+		"refused":      {"fr;q=0, de", "en"},
+		"wildcard":     {"*", "en"},
+		"default only": {"EN-us", "en"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if locale := catalog.negotiate(test.header); locale != test.expected {
+				t.Fatalf("Expected %q, got %q", test.expected, locale)
+			}
+		})
+	}
+}
+
+func TestLocalize(t *testing.T) {
+	defer SetConfiguration(GetConfig())
+	SetConfiguration(&Configuration{
+		DefaultLocale: "en",
+	})
+
+	catalog := Catalog{
+		"en": {"Hello %s": "Hi %s"},
+		"fr": {"Mirrors": "Miroirs"},
+	}
+
+	tests := map[string]struct {
+		catalog  Catalog
+		header   string
+		expected string
+		language string
+	}{
+		"translated": {catalog, "fr", "Miroirs Hi you", "fr"},
+		"default":    {catalog, "de", "Mirrors Hi you", "en"},
+		"no catalog": {nil, "fr", "Mirrors Hello you", ""},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tmpl := template.Must(template.New("t").Funcs(template.FuncMap{
+				"T": translateFunc(nil, ""),
+			}).Parse(`{{define "base"}}{{T "Mirrors"}} {{T "Hello %s" "you"}}{{end}}`))
+
+			w := httptest.NewRecorder()
+			r := makeRequest("GET", "/", map[string]string{"Accept-Language": test.header})
+			ctx := NewContext(w, r, Templates{catalog: test.catalog})
+
+			localized, err := ctx.localize(tmpl)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			var buf bytes.Buffer
+			if err = localized.ExecuteTemplate(&buf, "base", nil); err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if buf.String() != test.expected {
+				t.Fatalf("Expected %q, got %q", test.expected, buf.String())
+			}
+			if l := w.Header().Get("Content-Language"); l != test.language {
+				t.Fatalf("Expected Content-Language %q, got %q", test.language, l)
+			}
+		})
+	}
+}
+
+func TestLocalizeTemplates(t *testing.T) {
+	defer SetConfiguration(GetConfig())
+	SetConfiguration(&Configuration{
+		DefaultLocale: "en",
+	})
+
+	catalog := Catalog{
+		"fr": {"Mirrors": "Miroirs"},
+		"de": {"Mirrors": "Spiegel"},
+	}
+	tmpl := template.Must(template.New("t").Funcs(template.FuncMap{
+		"T": translateFunc(nil, ""),
+	}).Parse(`{{define "base"}}{{T "Mirrors"}}{{end}}`))
+
+	localized, err := localizeTemplates(catalog, tmpl)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(localized[tmpl]) != 3 {
+		t.Fatalf("Expected the template in 3 locales, got %d", len(localized[tmpl]))
+	}
+
+	expected := map[string]string{"fr": "Miroirs", "de": "Spiegel", "en": "Mirrors"}
+	for header, text := range expected {
+		ctx := NewContext(httptest.NewRecorder(), makeRequest("GET", "/", map[string]string{"Accept-Language": header}),
+			Templates{catalog: catalog, localized: localized})
+
+		// The requests share the prepared copies
+		lt, err := ctx.localize(tmpl)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if lt != localized[tmpl][header] {
+			t.Fatalf("Expected the prepared template of %s", header)
+		}
+		var buf bytes.Buffer
+		if err = lt.ExecuteTemplate(&buf, "base", nil); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if buf.String() != text {
+			t.Fatalf("Expected %q, got %q", text, buf.String())
+		}
+	}
+
+	if localized, err = localizeTemplates(nil, tmpl); err != nil || localized != nil {
+		t.Fatalf("Expected no translations without catalog, got %v %v", localized, err)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// sizeMismatchLogInterval is the minimum interval between two warnings
+	// about the size mismatch of the same file on the same mirror
+	sizeMismatchLogInterval = 10 * time.Minute
+)
+
+// sizeMismatchLog throttles the warnings logged on the redirect path when
+// a mirror is skipped for a size mismatch, the ExcludeReason of the mirror
+// being available from the mirrorlist anyway
+var sizeMismatchLog = newLogThrottle(sizeMismatchLogInterval)
+
+// logThrottle tells whether a message about a given key may be logged,
+// allowing one message per key and per interval
+type logThrottle struct {
+	sync.Mutex
+	interval    time.Duration
+	logged      map[string]time.Time
+	lastCleanup time.Time
+}
+
+func newLogThrottle(interval time.Duration) *logThrottle {
+	return &logThrottle{
+		interval:    interval,
+		logged:      make(map[string]time.Time),
+		lastCleanup: time.Now(),
+	}
+}
+
+// allow returns true if nothing was logged about the key during the last
+// interval, the message being then considered as logged
+func (l *logThrottle) allow(key string, now time.Time) bool {
+	l.Lock()
+	defer l.Unlock()
+
+	if now.Sub(l.lastCleanup) >= l.interval {
+		for k, t := range l.logged {
+			if now.Sub(t) >= l.interval {
+				delete(l.logged, k)
+			}
+		}
+		l.lastCleanup = now
+	}
+
+	if t, ok := l.logged[key]; ok && now.Sub(t) < l.interval {
+		return false
+	}
+	l.logged[key] = now
+	return true
+}
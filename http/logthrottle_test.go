@@ -0,0 +1,33 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLogThrottle(t *testing.T) {
+	l := newLogThrottle(time.Minute)
+	now := time.Now()
+
+	if !l.allow("m1|/file", now) {
+		t.Fatalf("The first message should be allowed")
+	}
+	if l.allow("m1|/file", now.Add(30*time.Second)) {
+		t.Fatalf("The message should be throttled within the interval")
+	}
+	if !l.allow("m2|/file", now.Add(30*time.Second)) {
+		t.Fatalf("Other keys should not be throttled")
+	}
+	if !l.allow("m1|/file", now.Add(time.Minute)) {
+		t.Fatalf("The message should be allowed after the interval")
+	}
+
+	// The expired keys are removed
+	l.allow("m3|/file", now.Add(3*time.Minute))
+	if len(l.logged) != 1 {
+		t.Fatalf("Expected the expired keys to be removed, got %v", l.logged)
+	}
+}
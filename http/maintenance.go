@@ -0,0 +1,81 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/etix/mirrorbits/metrics"
+)
+
+// maintenanceState holds the maintenance mode set at runtime, which takes
+// precedence over the MaintenanceMode of the configuration until the next
+// reload
+type maintenanceState struct {
+	sync.RWMutex
+	override bool
+	enabled  bool
+	message  string
+}
+
+// SetMaintenance enables or disables the maintenance mode until the next
+// reload of the configuration. An empty message uses the
+// MaintenanceMessage of the configuration.
+func (h *HTTP) SetMaintenance(enabled bool, message string) {
+	h.maintenance.Lock()
+	h.maintenance.override = true
+	h.maintenance.enabled = enabled
+	h.maintenance.message = message
+	h.maintenance.Unlock()
+
+	if enabled {
+		log.Notice("Maintenance mode enabled")
+	} else {
+		log.Notice("Maintenance mode disabled")
+	}
+}
+
+// Maintenance returns whether the maintenance mode is enabled, the message
+// sent to the clients and whether the mode was set at runtime
+func (h *HTTP) Maintenance() (enabled bool, message string, override bool) {
+	h.maintenance.RLock()
+	defer h.maintenance.RUnlock()
+
+	enabled = GetConfig().MaintenanceMode
+	message = GetConfig().MaintenanceMessage
+	if h.maintenance.override {
+		enabled = h.maintenance.enabled
+		if h.maintenance.message != "" {
+			message = h.maintenance.message
+		}
+	}
+	return enabled, message, h.maintenance.override
+}
+
+// resetMaintenance gives the control of the maintenance mode back to the
+// configuration
+func (h *HTTP) resetMaintenance() {
+	h.maintenance.Lock()
+	h.maintenance.override = false
+	h.maintenance.enabled = false
+	h.maintenance.message = ""
+	h.maintenance.Unlock()
+}
+
+// maintenanceHandler refuses the request with a 503 while the maintenance
+// mode is enabled
+func (h *HTTP) maintenanceHandler(w http.ResponseWriter, r *http.Request, message string) {
+	metrics.RedirectsMaintenance.Inc()
+	if retry := GetConfig().MaintenanceRetryAfter; retry > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(retry))
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	if message == "" {
+		message = http.StatusText(http.StatusServiceUnavailable)
+	}
+	http.Error(w, message, http.StatusServiceUnavailable)
+}
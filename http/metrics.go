@@ -0,0 +1,97 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/etix/mirrorbits/metrics"
+	"github.com/etix/mirrorbits/mirrors"
+)
+
+// metricsHandler exposes the metrics in the Prometheus text format. When the
+// endpoint is disabled the request is served as any other file request.
+func (h *HTTP) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	if !GetConfig().PrometheusEndpoint {
+		h.requestDispatcher(w, r)
+		return
+	}
+
+	h.updateMirrorMetrics()
+	h.updateRedisMetrics()
+	metrics.RedirectsInFlight.Set(float64(atomic.LoadInt64(&h.redirects)))
+
+	var buf bytes.Buffer
+	if err := metrics.WriteText(&buf); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// updateMirrorMetrics refreshes the gauges reporting the state of the mirrors
+func (h *HTTP) updateMirrorMetrics() {
+	mirrorsMap, err := h.redis.GetListOfMirrors()
+	if err != nil {
+		// Keep the latest known values
+		return
+	}
+
+	metrics.MirrorUp.Reset()
+	metrics.MirrorThroughput.Reset()
+	up := 0
+	for id := range mirrorsMap {
+		mirror, err := h.cache.GetMirror(id)
+		if err != nil || !mirror.Enabled {
+			continue
+		}
+		if mirror.IsUp() {
+			metrics.MirrorUp.Set(1, mirror.Name)
+			up++
+		} else {
+			metrics.MirrorUp.Set(0, mirror.Name)
+		}
+		if mirror.Throughput > 0 {
+			metrics.MirrorThroughput.Set(mirror.Throughput, mirror.Name)
+		}
+	}
+	metrics.MirrorsUp.Set(float64(up))
+}
+
+// updateRedisMetrics refreshes the gauges reporting the state of the redis pool
+func (h *HTTP) updateRedisMetrics() {
+	stats := h.redis.PoolStats()
+	metrics.RedisPoolActive.Set(float64(stats.ActiveCount))
+	metrics.RedisPoolIdle.Set(float64(stats.IdleCount))
+}
+
+// countResult counts a file request made with the given method. The HEAD
+// requests are counted apart, and only if CountHeadRequests is set.
+func countResult(method, country, result string) {
+	if method != http.MethodHead {
+		metrics.Requests.Inc(country, result)
+	} else if GetConfig().CountHeadRequests {
+		metrics.HeadRequests.Inc(country, result)
+	}
+}
+
+// countRequest updates the request counters for the given results
+func countRequest(typ, method string, status int, results *mirrors.Results, err error) {
+	country := results.ClientInfo.CountryCode
+	result := metrics.ResultHit
+	if err != nil {
+		result = metrics.ResultError
+	} else if results.Fallback {
+		result = metrics.ResultFallback
+	}
+	countResult(method, country, result)
+	if typ == "REDIRECT" && method != http.MethodHead && len(results.MirrorList) > 0 {
+		metrics.Redirects.Inc(results.MirrorList[0].Name, country, result, strconv.Itoa(status))
+	}
+}
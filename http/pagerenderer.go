@@ -19,6 +19,7 @@ import (
 	. "github.com/etix/mirrorbits/config"
 	"github.com/etix/mirrorbits/core"
 	"github.com/etix/mirrorbits/mirrors"
+	"github.com/etix/mirrorbits/signedurl"
 )
 
 var (
@@ -76,7 +77,7 @@ func (w *RedirectRenderer) Write(ctx *Context, results *mirrors.Results) (status
 	if len(results.MirrorList) > 0 {
 		ctx.ResponseWriter().Header().Set("Content-Type", "text/html; charset=utf-8")
 
-		path := strings.TrimPrefix(results.FileInfo.Path, "/")
+		path := strings.TrimPrefix(results.MirrorPath(), "/")
 
 		mh := len(results.MirrorList)
 		maxheaders := GetConfig().MaxLinkHeaders
@@ -91,19 +92,52 @@ func (w *RedirectRenderer) Write(ctx *Context, results *mirrors.Results) (status
 				if len(m.CountryFields) > 0 {
 					countryCode = strings.ToLower(m.CountryFields[0])
 				}
-				ctx.ResponseWriter().Header().Add("Link", fmt.Sprintf("<%s>; rel=duplicate; pri=%d; geo=%s", m.AbsoluteURL+path, i+1, countryCode))
+				ctx.ResponseWriter().Header().Add("Link", fmt.Sprintf("<%s>; rel=duplicate; pri=%d; geo=%s", signURL(m.AbsoluteURL+path), i+1, countryCode))
 			}
 		}
 
+		setMirrorHeader(ctx.ResponseWriter(), results.MirrorList[0], results.Fallback)
+
 		// Finally issue the redirect
-		http.Redirect(ctx.ResponseWriter(), ctx.Request(), results.MirrorList[0].AbsoluteURL+path, http.StatusFound)
-		return http.StatusFound, nil
+		code := redirectStatusCode(results.FileInfo.Path)
+		http.Redirect(ctx.ResponseWriter(), ctx.Request(), signURL(results.MirrorList[0].AbsoluteURL+path), code)
+		return code, nil
 	}
 	// No mirror returned for this request
+	setNoMirrorHeader(ctx.ResponseWriter(), results.Fallback)
 	http.NotFound(ctx.ResponseWriter(), ctx.Request())
 	return http.StatusNotFound, nil
 }
 
+// signURL returns the URL signed to expire after SignedURLExpiry seconds if
+// SignedURLs is enabled, see the signedurl package
+func signURL(rawurl string) string {
+	if !GetConfig().SignedURLs {
+		return rawurl
+	}
+	expires := time.Now().Add(time.Duration(GetConfig().SignedURLExpiry) * time.Second)
+	signed, err := signedurl.Sign(rawurl, GetConfig().SignedURLSecret, expires)
+	if err != nil {
+		log.Errorf("Unable to sign %s: %s", rawurl, err)
+		return rawurl
+	}
+	return signed
+}
+
+// redirectStatusCode returns the status code used to redirect the client
+// to a mirror for the given file
+func redirectStatusCode(path string) int {
+	for _, rule := range GetConfig().RedirectStatusOverrides {
+		if strings.HasPrefix(path, rule.Prefix) {
+			return rule.StatusCode
+		}
+	}
+	if code := GetConfig().RedirectStatusCode; code != 0 {
+		return code
+	}
+	return http.StatusFound
+}
+
 // Metalink 4.0 (RFC 5854) document structures. The XML namespace on the root
 // element produces the required xmlns="urn:ietf:params:xml:ns:metalink".
 type metalink struct {
@@ -152,7 +186,7 @@ func (w *MetalinkRenderer) Write(ctx *Context, results *mirrors.Results) (status
 
 	// Path of the file relative to the repository root (no leading slash),
 	// used to build the per-mirror URLs.
-	path := strings.TrimPrefix(results.FileInfo.Path, "/")
+	path := strings.TrimPrefix(results.MirrorPath(), "/")
 
 	file := metalinkFile{
 		// The "name" is the file basename, not the full path. librepo (dnf)
@@ -166,6 +200,9 @@ func (w *MetalinkRenderer) Write(ctx *Context, results *mirrors.Results) (status
 
 	// Source file hashes, identical across all mirrors. Hash type names follow
 	// the IANA registry as required by RFC 5854.
+	if results.FileInfo.Sha512 != "" {
+		file.Hashes = append(file.Hashes, metalinkHash{Type: "sha-512", Value: results.FileInfo.Sha512})
+	}
 	if results.FileInfo.Sha256 != "" {
 		file.Hashes = append(file.Hashes, metalinkHash{Type: "sha-256", Value: results.FileInfo.Sha256})
 	}
@@ -178,7 +215,7 @@ func (w *MetalinkRenderer) Write(ctx *Context, results *mirrors.Results) (status
 
 	// Candidate mirrors, already ordered by preference by the selection engine.
 	// priority 1 is the most preferred (RFC 5854 §4.1.6).
-	for i, m := range results.MirrorList {
+	for i, m := range metalinkMirrors(results.MirrorList) {
 		var location string
 		if len(m.CountryFields) > 0 {
 			location = strings.ToLower(m.CountryFields[0])
@@ -209,6 +246,48 @@ func (w *MetalinkRenderer) Write(ctx *Context, results *mirrors.Results) (status
 	return http.StatusOK, nil
 }
 
+// PlainMirrorListRenderer is used to render the candidate mirrors as a
+// plain text list of URLs, best first
+type PlainMirrorListRenderer struct{}
+
+// Type returns the type of renderer
+func (w *PlainMirrorListRenderer) Type() string {
+	return "MIRRORLIST_TXT"
+}
+
+// Write is used to write the result to the ResponseWriter
+func (w *PlainMirrorListRenderer) Write(ctx *Context, results *mirrors.Results) (statusCode int, err error) {
+	if len(results.MirrorList) == 0 {
+		http.NotFound(ctx.ResponseWriter(), ctx.Request())
+		return http.StatusNotFound, nil
+	}
+
+	path := strings.TrimPrefix(results.MirrorPath(), "/")
+
+	mlist := results.MirrorList
+	if max := GetConfig().MirrorListMax; max > 0 && len(mlist) > max {
+		mlist = mlist[:max]
+	}
+
+	var buf bytes.Buffer
+	for _, m := range mlist {
+		buf.WriteString(m.AbsoluteURL + path + "\n")
+	}
+
+	ctx.ResponseWriter().Header().Set("Content-Type", "text/plain; charset=utf-8")
+	ctx.ResponseWriter().Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	buf.WriteTo(ctx.ResponseWriter())
+	return http.StatusOK, nil
+}
+
+// metalinkMirrors returns the mirrors to list in a metalink document
+func metalinkMirrors(mlist mirrors.Mirrors) mirrors.Mirrors {
+	if max := GetConfig().MetalinkMirrors; max > 0 && len(mlist) > max {
+		return mlist[:max]
+	}
+	return mlist
+}
+
 // Metalink 3.0 (metalinker.org) document structures. This is the legacy format
 // consumed by dnf/librepo, which does NOT understand the RFC 5854 (v4) layout:
 // it expects <files>/<file>/<resources>/<url> with a "preference" attribute and
@@ -259,7 +338,7 @@ func (w *Metalink3Renderer) Write(ctx *Context, results *mirrors.Results) (statu
 		return http.StatusNotFound, nil
 	}
 
-	path := strings.TrimPrefix(results.FileInfo.Path, "/")
+	path := strings.TrimPrefix(results.MirrorPath(), "/")
 
 	file := metalink3File{
 		// Basename only: librepo matches it by exact equality (e.g. "repomd.xml")
@@ -268,6 +347,9 @@ func (w *Metalink3Renderer) Write(ctx *Context, results *mirrors.Results) (statu
 	}
 
 	// Metalink 3.0 uses dashless hash type names inside <verification>.
+	if results.FileInfo.Sha512 != "" {
+		file.Hashes = append(file.Hashes, metalink3Hash{Type: "sha512", Value: results.FileInfo.Sha512})
+	}
 	if results.FileInfo.Sha256 != "" {
 		file.Hashes = append(file.Hashes, metalink3Hash{Type: "sha256", Value: results.FileInfo.Sha256})
 	}
@@ -280,7 +362,7 @@ func (w *Metalink3Renderer) Write(ctx *Context, results *mirrors.Results) (statu
 
 	// Candidate mirrors. In Metalink 3.0 "preference" is 0-100, higher is more
 	// preferred (the opposite of v4's "priority"), so map the rank accordingly.
-	for i, m := range results.MirrorList {
+	for i, m := range metalinkMirrors(results.MirrorList) {
 		var location string
 		if len(m.CountryFields) > 0 {
 			location = strings.ToLower(m.CountryFields[0])
@@ -344,13 +426,17 @@ func (w *MirrorListRenderer) Write(ctx *Context, results *mirrors.Results) (stat
 	ctx.ResponseWriter().Header().Set("Content-Type", "text/html; charset=utf-8")
 
 	// Render the page into the buffer
-	err = ctx.Templates().mirrorlist.ExecuteTemplate(&buf, "base", results)
+	t, err := ctx.localize(ctx.Templates().mirrorlist)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	err = t.ExecuteTemplate(&buf, "base", results)
 	if err != nil {
 		// Something went wrong, discard the buffer
 		return http.StatusInternalServerError, err
 	}
 
 	// Write the buffer to the socket
-	buf.WriteTo(ctx.ResponseWriter())
+	writePage(ctx.ResponseWriter(), ctx.Request(), buf.Bytes(), ctx.Templates().modTime)
 	return http.StatusOK, nil
 }
@@ -0,0 +1,92 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/etix/mirrorbits/filesystem"
+	"github.com/etix/mirrorbits/mirrors"
+)
+
+func TestRedirectStatusCode(t *testing.T) {
+	overrides := []RedirectStatusConfig{
+		{Prefix: "/stable/", StatusCode: 301},
+		{Prefix: "/stable/old/", StatusCode: 308},
+	}
+
+	tests := map[string]struct {
+		code      int
+		overrides []RedirectStatusConfig
+		path      string
+		expected  int
+	}{
+		"unset":           {0, nil, "/file.iso", 302},
+		"default":         {302, nil, "/file.iso", 302},
+		"configured":      {307, nil, "/file.iso", 307},
+		"override":        {307, overrides, "/stable/file.iso", 301},
+		"first override":  {307, overrides, "/stable/old/file.iso", 301},
+		"no override":     {307, overrides, "/testing/file.iso", 307},
+		"partial segment": {302, overrides, "/stable", 302},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			SetConfiguration(&Configuration{
+				RedirectStatusCode:      test.code,
+				RedirectStatusOverrides: test.overrides,
+			})
+			defer SetConfiguration(&Configuration{})
+
+			if code := redirectStatusCode(test.path); code != test.expected {
+				t.Fatalf("Expected %d, got %d", test.expected, code)
+			}
+		})
+	}
+}
+
+func TestPlainMirrorListRenderer(t *testing.T) {
+	SetConfiguration(&Configuration{
+		MirrorListMax: 2,
+	})
+	defer SetConfiguration(&Configuration{})
+
+	results := &mirrors.Results{
+		FileInfo: filesystem.FileInfo{Path: "/dir/file.iso"},
+		MirrorList: mirrors.Mirrors{
+			{ID: 1, AbsoluteURL: "https://m1.mirror/"},
+			{ID: 2, AbsoluteURL: "http://m2.mirror/pub/"},
+			{ID: 3, AbsoluteURL: "https://m3.mirror/"},
+		},
+	}
+
+	r := httptest.NewRequest("GET", "/dir/file.iso?mirrorlist=txt", nil)
+	w := httptest.NewRecorder()
+	ctx := NewContext(w, r, Templates{})
+	if !ctx.IsMirrorlist() || !ctx.IsPlainMirrorlist() {
+		t.Fatalf("Expected a plain mirror list request")
+	}
+
+	status, err := (&PlainMirrorListRenderer{}).Write(ctx, results)
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("Unexpected result: %d %v", status, err)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Fatalf("Invalid content type %s", ct)
+	}
+	expected := "https://m1.mirror/dir/file.iso\nhttp://m2.mirror/pub/dir/file.iso\n"
+	if body := w.Body.String(); body != expected {
+		t.Fatalf("Expected %q, got %q", expected, body)
+	}
+
+	results.MirrorList = nil
+	w = httptest.NewRecorder()
+	ctx = NewContext(w, r, Templates{})
+	if status, _ := (&PlainMirrorListRenderer{}).Write(ctx, results); status != http.StatusNotFound {
+		t.Fatalf("Expected 404 without mirrors, got %d", status)
+	}
+}
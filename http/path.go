@@ -0,0 +1,79 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"errors"
+	"net/url"
+	"path"
+	"strings"
+)
+
+const (
+	// maxPathDecoding is the number of levels of percent-encoding looked
+	// through when searching for a traversal in the requested path
+	maxPathDecoding = 3
+)
+
+var (
+	// ErrInvalidPath is returned when the requested path contains a null
+	// byte, a backslash or attempts to go above the root of the repository
+	ErrInvalidPath = errors.New("invalid path")
+)
+
+// normalizePath returns the cleaned version of the requested path, as
+// decoded by net/http, to be used for the lookups in the database and the
+// stats. The path is refused if it contains a null byte or a backslash, if
+// it goes above the root, or if one of its further decoded forms (e.g.
+// %252e%252e) contains any of them or a dot segment, as such paths would be
+// interpreted differently by the mirrors.
+func normalizePath(p string) (string, error) {
+	if hasInvalidChars(p) || escapesRoot(p) {
+		return "", ErrInvalidPath
+	}
+	decoded := p
+	for i := 0; ; i++ {
+		unescaped, err := url.PathUnescape(decoded)
+		if err != nil || unescaped == decoded {
+			break
+		}
+		if i == maxPathDecoding || hasInvalidChars(unescaped) || hasDotSegment(unescaped) {
+			return "", ErrInvalidPath
+		}
+		decoded = unescaped
+	}
+	return path.Clean("/" + p), nil
+}
+
+func hasInvalidChars(p string) bool {
+	return strings.ContainsAny(p, "\x00\\")
+}
+
+// escapesRoot returns true if the dot-dot segments of the path go above
+// its root
+func escapesRoot(p string) bool {
+	depth := 0
+	for _, segment := range strings.Split(p, "/") {
+		switch segment {
+		case "", ".":
+		case "..":
+			depth--
+			if depth < 0 {
+				return true
+			}
+		default:
+			depth++
+		}
+	}
+	return false
+}
+
+func hasDotSegment(p string) bool {
+	for _, segment := range strings.Split(p, "/") {
+		if segment == "." || segment == ".." {
+			return true
+		}
+	}
+	return false
+}
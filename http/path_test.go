@@ -0,0 +1,47 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"testing"
+)
+
+func TestNormalizePath(t *testing.T) {
+	tests := map[string]struct {
+		path string
+		want string
+		err  error
+	}{
+		"plain":             {path: "/foo/bar.iso", want: "/foo/bar.iso"},
+		"no_leading_slash":  {path: "foo/bar.iso", want: "/foo/bar.iso"},
+		"double_slashes":    {path: "//foo//bar.iso", want: "/foo/bar.iso"},
+		"dot_segments":      {path: "/foo/./baz/../bar.iso", want: "/foo/bar.iso"},
+		"trailing_slash":    {path: "/foo/", want: "/foo"},
+		"root":              {path: "/", want: "/"},
+		"percent":           {path: "/foo/100%.iso", want: "/foo/100%.iso"},
+		"encoded_space":     {path: "/foo/a%20b.iso", want: "/foo/a%20b.iso"},
+		"traversal":         {path: "/../etc/passwd", err: ErrInvalidPath},
+		"deep_traversal":    {path: "/foo/bar/../../../etc/passwd", err: ErrInvalidPath},
+		"encoded_dots":      {path: "/%2e%2e/etc/passwd", err: ErrInvalidPath},
+		"encoded_dot":       {path: "/foo/%2e/bar.iso", err: ErrInvalidPath},
+		"double_encoded":    {path: "/foo/%252e%252e/bar.iso", err: ErrInvalidPath},
+		"too_many_levels":   {path: "/foo/%2525252541.iso", err: ErrInvalidPath},
+		"backslash":         {path: "/foo\\..\\..\\etc\\passwd", err: ErrInvalidPath},
+		"encoded_backslash": {path: "/foo/%5cbar.iso", err: ErrInvalidPath},
+		"null_byte":         {path: "/foo/bar.iso\x00.html", err: ErrInvalidPath},
+		"encoded_null_byte": {path: "/foo/bar.iso%00.html", err: ErrInvalidPath},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := normalizePath(test.path)
+			if err != test.err {
+				t.Fatalf("Expected error %v, got %v", test.err, err)
+			}
+			if got != test.want {
+				t.Fatalf("Expected %q, got %q", test.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/etix/mirrorbits/database"
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	// popularityTTL is the time the download counter of a file is cached
+	popularityTTL = time.Minute
+	// popularityMaxEntries bounds the number of cached counters
+	popularityMaxEntries = 10000
+)
+
+// popularityTracker tells whether a file is popular from its download
+// counter of the day (see stats.go). The counters are cached for a minute
+// to not hit the database on every request.
+type popularityTracker struct {
+	sync.Mutex
+	redis    *database.Redis
+	counters map[string]popularityCounter
+}
+
+type popularityCounter struct {
+	downloads int64
+	fetched   time.Time
+}
+
+func newPopularityTracker(r *database.Redis) *popularityTracker {
+	return &popularityTracker{
+		redis:    r,
+		counters: make(map[string]popularityCounter),
+	}
+}
+
+// isHot returns true if PopularityAwareSelection is enabled and the file
+// was downloaded at least PopularityThreshold times today
+func (p *popularityTracker) isHot(path string) bool {
+	if p == nil || !GetConfig().PopularityAwareSelection {
+		return false
+	}
+	return p.downloads(path, time.Now()) >= int64(GetConfig().PopularityThreshold)
+}
+
+// downloads returns the number of downloads of the file today
+func (p *popularityTracker) downloads(path string, now time.Time) int64 {
+	p.Lock()
+	c, ok := p.counters[path]
+	p.Unlock()
+	if ok && now.Sub(c.fetched) < popularityTTL {
+		return c.downloads
+	}
+
+	rconn := p.redis.Get()
+	defer rconn.Close()
+	key := fmt.Sprintf("STATS_FILE_%s", now.UTC().Format("2006_01_02"))
+	n, err := redis.Int64(rconn.Do("HGET", key, path))
+	if err != nil {
+		// Unknown or unreachable, the file is considered cold
+		n = 0
+	}
+
+	p.Lock()
+	if len(p.counters) >= popularityMaxEntries {
+		p.counters = make(map[string]popularityCounter)
+	}
+	p.counters[path] = popularityCounter{n, now}
+	p.Unlock()
+	return n
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/etix/mirrorbits/mirrors"
+	"github.com/etix/mirrorbits/network"
+	. "github.com/etix/mirrorbits/testing"
+)
+
+func TestPopularityTracker(t *testing.T) {
+	defer SetConfiguration(GetConfig())
+	SetConfiguration(&Configuration{
+		PopularityAwareSelection: true,
+		PopularityThreshold:      100,
+	})
+
+	mock, conn := PrepareRedisTest()
+	p := newPopularityTracker(conn)
+
+	key := fmt.Sprintf("STATS_FILE_%s", time.Now().UTC().Format("2006_01_02"))
+	cmdHot := mock.Command("HGET", key, "/hot.iso").Expect(int64(150))
+	mock.Command("HGET", key, "/cold.iso").Expect(int64(3))
+
+	if !p.isHot("/hot.iso") {
+		t.Fatalf("Expected /hot.iso to be hot")
+	}
+	if p.isHot("/cold.iso") {
+		t.Fatalf("Expected /cold.iso to be cold")
+	}
+	if p.isHot("/unknown.iso") {
+		t.Fatalf("Expected a file never downloaded to be cold")
+	}
+
+	// The counters are cached
+	p.isHot("/hot.iso")
+	if mock.Stats(cmdHot) != 1 {
+		t.Fatalf("Expected the counter to be fetched once, got %d", mock.Stats(cmdHot))
+	}
+
+	// Nothing is hot when the mode is disabled
+	SetConfiguration(&Configuration{PopularityThreshold: 100})
+	if p.isHot("/hot.iso") {
+		t.Fatalf("Expected no hot file when PopularityAwareSelection is disabled")
+	}
+}
+
+func TestComputeWeightsPopularity(t *testing.T) {
+	clientInfo := network.GeoIPRecord{CountryCode: "FR", ContinentCode: "EU", ASNum: 1234}
+
+	mlist := func() mirrors.Mirrors {
+		var mlist mirrors.Mirrors
+		for i, distance := range []float32{100, 140, 180, 250, 350, 2000} {
+			mlist = append(mlist, mirrors.Mirror{ID: i + 1, Distance: distance})
+		}
+		return mlist
+	}
+
+	// A cold file uses the nearest mirrors, a hot one is spread wider
+	cold, _ := computeWeights(mlist(), clientInfo, 100, 2000, 1.5)
+	hot, _ := computeWeights(mlist(), clientInfo, 100, 2000, 1.5*2)
+
+	if len(cold) != 2 {
+		t.Fatalf("Expected the cold file on 2 mirrors, got %d", len(cold))
+	}
+	if len(hot) != 4 {
+		t.Fatalf("Expected the hot file on 4 mirrors, got %d", len(hot))
+	}
+	for id := range cold {
+		if _, ok := hot[id]; !ok {
+			t.Fatalf("Expected mirror %d to serve the hot file as well", id)
+		}
+	}
+}
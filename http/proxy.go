@@ -0,0 +1,200 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+
+	. "github.com/etix/mirrorbits/config"
+)
+
+// proxyFile streams the requested file from the FallbackOrigin. The Range and
+// conditional headers are passed through to the origin as well as the
+// Content-Length, Content-Range and Accept-Ranges of its response. The partial
+// contents of the origin are checked before being relayed, and the body is
+// never buffered.
+func proxyFile(w http.ResponseWriter, r *http.Request, urlPath string) {
+	origin, err := url.Parse(GetConfig().FallbackOrigin)
+	if err != nil {
+		log.Errorf("Invalid fallback origin: %s", err)
+		http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+		return
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = origin.Scheme
+			req.URL.Host = origin.Host
+			req.URL.Path = strings.TrimSuffix(origin.Path, "/") + urlPath
+			req.URL.RawPath = ""
+			req.URL.RawQuery = ""
+			req.Host = origin.Host
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			if err := checkPartialContent(resp); err != nil {
+				return err
+			}
+			// The origin tells which server it is
+			w.Header().Del("Server")
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			log.Errorf("Proxying %s from the fallback origin failed: %s", urlPath, err)
+			http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+		},
+	}
+	proxy.ServeHTTP(w, r)
+}
+
+// errInvalidPartialContent is returned for a 206 of the origin that doesn't
+// match the request
+var errInvalidPartialContent = errors.New("invalid partial content")
+
+// checkPartialContent makes sure a partial content of the origin is the
+// answer to the range request, its Content-Range matching the requested
+// range and its Content-Length, so that a resumed download is never corrupted
+func checkPartialContent(resp *http.Response) error {
+	if resp.StatusCode != http.StatusPartialContent {
+		return nil
+	}
+	header := resp.Request.Header.Get("Range")
+	if header == "" {
+		return fmt.Errorf("%w: no range requested", errInvalidPartialContent)
+	}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "multipart/byteranges") {
+		// One Content-Range per part
+		return nil
+	}
+	first, last, size, err := parseContentRange(resp.Header.Get("Content-Range"))
+	if err != nil {
+		return fmt.Errorf("%w: %s", errInvalidPartialContent, err)
+	}
+	if resp.ContentLength >= 0 && resp.ContentLength != last-first+1 {
+		return fmt.Errorf("%w: %d bytes for the range %d-%d", errInvalidPartialContent, resp.ContentLength, first, last)
+	}
+	ranges, err := parseRange(header)
+	if err != nil {
+		return fmt.Errorf("%w: %s", errInvalidPartialContent, err)
+	}
+	// The origin may coalesce several requested ranges into one, the range
+	// must then start and end like some of them
+	var firstOK, lastOK bool
+	for _, r := range ranges {
+		f, l := r.bounds(size)
+		firstOK = firstOK || f < 0 || f == first
+		lastOK = lastOK || l < 0 || l == last
+	}
+	if !firstOK || !lastOK {
+		return fmt.Errorf("%w: range %d-%d for %q", errInvalidPartialContent, first, last, header)
+	}
+	return nil
+}
+
+// byteRange is one of the ranges of a Range header. A suffix range has a
+// negative first byte and its length as last byte, an open-ended range has a
+// negative last byte.
+type byteRange struct {
+	first, last int64
+}
+
+// bounds returns the first and last bytes of the range within a file of the
+// given size, a bound being negative if it can't be known without the size
+func (r byteRange) bounds(size int64) (first, last int64) {
+	switch {
+	case r.first < 0:
+		if size < 0 {
+			return -1, -1
+		}
+		if first = size - r.last; first < 0 {
+			first = 0
+		}
+		return first, size - 1
+	case r.last < 0:
+		return r.first, size - 1
+	case size >= 0 && r.last >= size:
+		return r.first, size - 1
+	}
+	return r.first, r.last
+}
+
+// parseRange returns the ranges of a Range header of the form
+// "bytes=first-last, first-, -suffix"
+func parseRange(value string) ([]byteRange, error) {
+	if !strings.HasPrefix(value, "bytes=") {
+		return nil, fmt.Errorf("unsupported Range %q", value)
+	}
+	var ranges []byteRange
+	for _, spec := range strings.Split(strings.TrimPrefix(value, "bytes="), ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		i := strings.IndexByte(spec, '-')
+		if i < 0 {
+			return nil, fmt.Errorf("malformed Range %q", value)
+		}
+		r := byteRange{first: -1, last: -1}
+		var err error
+		if i > 0 {
+			if r.first, err = strconv.ParseInt(spec[:i], 10, 64); err != nil || r.first < 0 {
+				return nil, fmt.Errorf("malformed Range %q", value)
+			}
+		}
+		if i < len(spec)-1 {
+			if r.last, err = strconv.ParseInt(spec[i+1:], 10, 64); err != nil || r.last < 0 {
+				return nil, fmt.Errorf("malformed Range %q", value)
+			}
+		}
+		if (r.first < 0 && r.last < 0) || (r.first >= 0 && r.last >= 0 && r.last < r.first) {
+			return nil, fmt.Errorf("invalid Range %q", value)
+		}
+		ranges = append(ranges, r)
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("malformed Range %q", value)
+	}
+	return ranges, nil
+}
+
+// parseContentRange returns the first and last bytes and the size of a
+// Content-Range header of the form "bytes first-last/size", the size being
+// possibly "*", returned as -1
+func parseContentRange(value string) (first, last, size int64, err error) {
+	if !strings.HasPrefix(value, "bytes ") {
+		return 0, 0, 0, fmt.Errorf("unsupported Content-Range %q", value)
+	}
+	value = strings.TrimPrefix(value, "bytes ")
+	i := strings.IndexByte(value, '/')
+	if i < 0 {
+		return 0, 0, 0, fmt.Errorf("malformed Content-Range %q", value)
+	}
+	bounds, total := value[:i], value[i+1:]
+	j := strings.IndexByte(bounds, '-')
+	if j < 0 {
+		return 0, 0, 0, fmt.Errorf("malformed Content-Range %q", value)
+	}
+	if first, err = strconv.ParseInt(bounds[:j], 10, 64); err != nil {
+		return 0, 0, 0, fmt.Errorf("malformed Content-Range %q", value)
+	}
+	if last, err = strconv.ParseInt(bounds[j+1:], 10, 64); err != nil {
+		return 0, 0, 0, fmt.Errorf("malformed Content-Range %q", value)
+	}
+	if first < 0 || last < first {
+		return 0, 0, 0, fmt.Errorf("invalid Content-Range %q", value)
+	}
+	size = -1
+	if total != "*" {
+		size, err = strconv.ParseInt(total, 10, 64)
+		if err != nil || last >= size {
+			return 0, 0, 0, fmt.Errorf("invalid Content-Range %q", value)
+		}
+	}
+	return first, last, size, nil
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseContentRange(t *testing.T) {
+	tests := map[string]struct {
+		value string
+		first int64
+		last  int64
+		size  int64
+		fail  bool
+	}{
+		"range":          {"bytes 2-5/10", 2, 5, 10, false},
+		"unknown size":   {"bytes 0-99/*", 0, 99, -1, false},
+		"single byte":    {"bytes 9-9/10", 9, 9, 10, false},
+		"unsatisfiable":  {"bytes */10", 0, 0, 0, true},
+		"beyond size":    {"bytes 5-10/10", 0, 0, 0, true},
+		"reversed":       {"bytes 5-2/10", 0, 0, 0, true},
+		"other unit":     {"items 2-5/10", 0, 0, 0, true},
+		"missing size":   {"bytes 2-5", 0, 0, 0, true},
+		"missing bounds": {"bytes 2/10", 0, 0, 0, true},
+		"empty":          {"", 0, 0, 0, true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			first, last, size, err := parseContentRange(test.value)
+			if test.fail {
+				if err == nil {
+					t.Fatalf("Expected an error for %q", test.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if first != test.first || last != test.last || size != test.size {
+				t.Fatalf("Expected %d-%d/%d, got %d-%d/%d", test.first, test.last, test.size, first, last, size)
+			}
+		})
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	tests := map[string]struct {
+		value    string
+		expected []byteRange
+		fail     bool
+	}{
+		"range":      {"bytes=2-5", []byteRange{{2, 5}}, false},
+		"open ended": {"bytes=4-", []byteRange{{4, -1}}, false},
+		"suffix":     {"bytes=-3", []byteRange{{-1, 3}}, false},
+		"multiple":   {"bytes=0-1, 4-", []byteRange{{0, 1}, {4, -1}}, false},
+		"reversed":   {"bytes=5-2", nil, true},
+		"no bounds":  {"bytes=-", nil, true},
+		"malformed":  {"bytes=a-b", nil, true},
+		"other unit": {"items=2-5", nil, true},
+		"empty":      {"bytes=", nil, true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ranges, err := parseRange(test.value)
+			if test.fail {
+				if err == nil {
+					t.Fatalf("Expected an error for %q", test.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(ranges, test.expected) {
+				t.Fatalf("Expected %v, got %v", test.expected, ranges)
+			}
+		})
+	}
+}
+
+func TestCheckPartialContent(t *testing.T) {
+	tests := map[string]struct {
+		rangeHeader  string
+		contentRange string
+		length       int64
+		valid        bool
+	}{
+		"range":                 {"bytes=2-5", "bytes 2-5/10", 4, true},
+		"range beyond the end":  {"bytes=8-20", "bytes 8-9/10", 2, true},
+		"open ended":            {"bytes=4-", "bytes 4-9/10", 6, true},
+		"suffix":                {"bytes=-3", "bytes 7-9/10", 3, true},
+		"suffix beyond start":   {"bytes=-20", "bytes 0-9/10", 10, true},
+		"unknown size":          {"bytes=4-", "bytes 4-7/*", 4, true},
+		"coalesced ranges":      {"bytes=0-1,2-5", "bytes 0-5/10", 6, true},
+		"shifted":               {"bytes=2-5", "bytes 0-3/10", 4, false},
+		"truncated":             {"bytes=2-5", "bytes 2-4/10", 3, false},
+		"open ended truncated":  {"bytes=4-", "bytes 4-7/10", 4, false},
+		"suffix shifted":        {"bytes=-3", "bytes 6-8/10", 3, false},
+		"length mismatch":       {"bytes=2-5", "bytes 2-5/10", 2, false},
+		"no range requested":    {"", "bytes 2-5/10", 4, false},
+		"invalid requested one": {"bytes=x", "bytes 2-5/10", 4, false},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/file", nil)
+			if test.rangeHeader != "" {
+				r.Header.Set("Range", test.rangeHeader)
+			}
+			resp := &http.Response{
+				StatusCode:    http.StatusPartialContent,
+				Header:        http.Header{"Content-Range": {test.contentRange}},
+				ContentLength: test.length,
+				Request:       r,
+			}
+			err := checkPartialContent(resp)
+			if test.valid && err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if !test.valid && !errors.Is(err, errInvalidPartialContent) {
+				t.Fatalf("Expected an invalid partial content, got %v", err)
+			}
+		})
+	}
+}
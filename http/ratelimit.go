@@ -0,0 +1,112 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"math"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/etix/mirrorbits/metrics"
+)
+
+const (
+	// rateLimitCleanupInterval is the minimum interval between two
+	// removals of the idle buckets
+	rateLimitCleanupInterval = time.Minute
+)
+
+// rateLimiter is an in-memory token bucket rate limiter keyed by client IP
+type rateLimiter struct {
+	sync.Mutex
+	buckets     map[string]*bucket
+	lastCleanup time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		buckets:     make(map[string]*bucket),
+		lastCleanup: time.Now(),
+	}
+}
+
+// allow consumes a token from the bucket of the given key. If the bucket is
+// empty it returns false along with the time to wait for the next token.
+func (l *rateLimiter) allow(key string, now time.Time, rate float64, burst int) (bool, time.Duration) {
+	l.Lock()
+	defer l.Unlock()
+
+	if now.Sub(l.lastCleanup) >= rateLimitCleanupInterval {
+		l.cleanup(now, rate, burst)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{
+			tokens: float64(burst),
+			last:   now,
+		}
+		l.buckets[key] = b
+	} else {
+		// Refill the bucket according to the elapsed time
+		b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+		b.last = now
+	}
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+		return false, wait
+	}
+	b.tokens--
+	return true, 0
+}
+
+// cleanup removes the buckets that had enough time to be refilled since they
+// were last used, they are identical to a new bucket. The caller must hold
+// the lock.
+func (l *rateLimiter) cleanup(now time.Time, rate float64, burst int) {
+	idle := time.Duration(float64(burst) / rate * float64(time.Second))
+	for k, b := range l.buckets {
+		if now.Sub(b.last) >= idle {
+			delete(l.buckets, k)
+		}
+	}
+	l.lastCleanup = now
+}
+
+// retryAfter converts a duration into a Retry-After value in seconds
+func retryAfter(wait time.Duration) int {
+	return int(math.Max(1, math.Ceil(wait.Seconds())))
+}
+
+// acquireRedirect reserves a slot for a file request, false is returned if
+// MaxConcurrentRedirects requests are already being handled
+func (h *HTTP) acquireRedirect() bool {
+	n := atomic.AddInt64(&h.redirects, 1)
+	if max := GetConfig().MaxConcurrentRedirects; max > 0 && n > int64(max) {
+		atomic.AddInt64(&h.redirects, -1)
+		return false
+	}
+	return true
+}
+
+// releaseRedirect frees the slot reserved by acquireRedirect
+func (h *HTTP) releaseRedirect() {
+	atomic.AddInt64(&h.redirects, -1)
+}
+
+// rejectRedirect answers a request refused by acquireRedirect
+func rejectRedirect(w http.ResponseWriter) {
+	metrics.RedirectsRejected.Inc()
+	w.Header().Set("Retry-After", "1")
+	http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+}
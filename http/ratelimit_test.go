@@ -0,0 +1,153 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/etix/mirrorbits/logs"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	l := newRateLimiter()
+	now := time.Now()
+
+	// The burst is available right away
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.allow("192.0.2.1", now, 1, 3); !ok {
+			t.Fatalf("Request %d should have been allowed", i)
+		}
+	}
+	ok, wait := l.allow("192.0.2.1", now, 1, 3)
+	if ok {
+		t.Fatalf("Request should have been limited")
+	}
+	if wait != time.Second {
+		t.Fatalf("Expected to wait 1s, got %s", wait)
+	}
+
+	// Other clients are not affected
+	if ok, _ := l.allow("192.0.2.2", now, 1, 3); !ok {
+		t.Fatalf("Request from another client should have been allowed")
+	}
+
+	// The bucket is refilled over time
+	now = now.Add(500 * time.Millisecond)
+	if ok, wait := l.allow("192.0.2.1", now, 1, 3); ok || wait != 500*time.Millisecond {
+		t.Fatalf("Expected to wait 500ms, got %t %s", ok, wait)
+	}
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := l.allow("192.0.2.1", now, 1, 3); !ok {
+		t.Fatalf("Request should have been allowed after the refill")
+	}
+}
+
+func TestRateLimiter_Cleanup(t *testing.T) {
+	l := newRateLimiter()
+	now := time.Now()
+
+	l.allow("192.0.2.1", now, 10, 100)
+	l.allow("192.0.2.2", now.Add(rateLimitCleanupInterval-time.Second), 10, 100)
+
+	// Trigger the cleanup, only the bucket of the first
+	// client had the time to be fully refilled
+	l.allow("192.0.2.3", now.Add(rateLimitCleanupInterval), 10, 100)
+
+	if _, ok := l.buckets["192.0.2.1"]; ok {
+		t.Fatalf("Idle bucket should have been removed")
+	}
+	if _, ok := l.buckets["192.0.2.2"]; !ok {
+		t.Fatalf("Active bucket should have been kept")
+	}
+	if len(l.buckets) != 2 {
+		t.Fatalf("Expected 2 buckets, got %d", len(l.buckets))
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := map[time.Duration]int{
+		0:                       1,
+		100 * time.Millisecond:  1,
+		time.Second:             1,
+		1500 * time.Millisecond: 2,
+	}
+	for wait, expected := range tests {
+		if r := retryAfter(wait); r != expected {
+			t.Fatalf("Expected %d for %s, got %d", expected, wait, r)
+		}
+	}
+}
+
+func TestAcquireRedirect(t *testing.T) {
+	defer SetConfiguration(GetConfig())
+	SetConfiguration(&Configuration{
+		MaxConcurrentRedirects: 2,
+	})
+
+	h := &HTTP{}
+	if !h.acquireRedirect() || !h.acquireRedirect() {
+		t.Fatalf("Expected the requests below the limit to be accepted")
+	}
+	if h.acquireRedirect() {
+		t.Fatalf("Expected the request above the limit to be rejected")
+	}
+	if n := atomic.LoadInt64(&h.redirects); n != 2 {
+		t.Fatalf("Expected 2 requests in flight, got %d", n)
+	}
+
+	h.releaseRedirect()
+	if !h.acquireRedirect() {
+		t.Fatalf("Expected a request to be accepted once a slot is freed")
+	}
+
+	// No limit
+	SetConfiguration(&Configuration{})
+	for i := 0; i < 10; i++ {
+		if !h.acquireRedirect() {
+			t.Fatalf("Expected the requests to be accepted without limit")
+		}
+	}
+}
+
+func TestRequestDispatcherRateLimitAccessLog(t *testing.T) {
+	ctx, err := prepareTest(t, []string{"/file.iso"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer logs.ReloadAccessLogs()
+	defer SetConfiguration(GetConfig())
+
+	logFile := ctx.TestDir + "/access.log"
+	config := *GetConfig()
+	config.RateLimitPerSecond = 1
+	config.AccessLogFormat = "combined"
+	config.AccessLogFile = logFile
+	SetConfiguration(&config)
+	logs.ReloadAccessLogs()
+
+	// Every request is rejected without RateLimitBurst, only the file
+	// requests are logged
+	for _, url := range []string{"/file.iso", "/file.iso?mirrorstats"} {
+		resp := doRequest(ctx.Server, "GET", url, map[string]string{})
+		if resp.StatusCode != http.StatusTooManyRequests {
+			t.Fatalf("Expected a 429 for %s, got: %s", url, dump(resp))
+		}
+	}
+
+	content, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], `"GET /file.iso HTTP/1.1" 429 `) {
+		t.Fatalf("Expected the rejected file request in the access log, got:\n%s", content)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"sync"
+	"time"
+)
+
+// rateWindows are the windows over which the request rates are averaged
+var rateWindows = []time.Duration{time.Minute, 5 * time.Minute}
+
+// rateSlots is the number of one second slots of the rings, enough for the
+// longest window
+const rateSlots = 300
+
+// rateTracker counts the redirects of the last minutes, overall and per
+// mirror, in rings of one second slots. It lives in memory and is local to
+// each instance.
+type rateTracker struct {
+	sync.Mutex
+	started time.Time
+	total   rateRing
+	mirrors map[int]*rateRing
+}
+
+// rateRing holds the number of events of each second, the slot of a second
+// being reused once it is older than the ring
+type rateRing struct {
+	counts  [rateSlots]int64
+	seconds [rateSlots]int64
+}
+
+func newRateTracker() *rateTracker {
+	return &rateTracker{
+		started: time.Now(),
+		mirrors: make(map[int]*rateRing),
+	}
+}
+
+func (r *rateRing) add(sec int64) {
+	i := sec % rateSlots
+	if sec < r.seconds[i] {
+		// Older than the ring
+		return
+	}
+	if r.seconds[i] != sec {
+		r.seconds[i] = sec
+		r.counts[i] = 0
+	}
+	r.counts[i]++
+}
+
+// sum returns the number of events of the window ending at the given second
+func (r *rateRing) sum(now, window int64) (n int64) {
+	for i := range r.seconds {
+		if age := now - r.seconds[i]; age >= 0 && age < window {
+			n += r.counts[i]
+		}
+	}
+	return n
+}
+
+// add accounts for a redirect, to the given mirror if id > 0
+func (t *rateTracker) add(id int, now time.Time) {
+	if t == nil {
+		return
+	}
+	sec := now.Unix()
+	t.Lock()
+	defer t.Unlock()
+	t.total.add(sec)
+	if id <= 0 {
+		return
+	}
+	ring, ok := t.mirrors[id]
+	if !ok {
+		ring = &rateRing{}
+		t.mirrors[id] = ring
+	}
+	ring.add(sec)
+}
+
+// rates returns the number of redirects per second over each of the
+// rateWindows, overall and per mirror. The windows are shortened to the
+// uptime right after the start.
+func (t *rateTracker) rates(now time.Time) (total []float64, perMirror map[int][]float64) {
+	sec := now.Unix()
+	uptime := now.Unix() - t.started.Unix() + 1
+
+	t.Lock()
+	defer t.Unlock()
+
+	compute := func(ring *rateRing) []float64 {
+		rates := make([]float64, len(rateWindows))
+		for i, w := range rateWindows {
+			window := int64(w / time.Second)
+			if window > uptime {
+				window = uptime
+			}
+			rates[i] = float64(ring.sum(sec, window)) / float64(window)
+		}
+		return rates
+	}
+
+	total = compute(&t.total)
+	perMirror = make(map[int][]float64, len(t.mirrors))
+	for id, ring := range t.mirrors {
+		if ring.sum(sec, rateSlots) == 0 {
+			// Nothing recently, forget about the mirror
+			delete(t.mirrors, id)
+			continue
+		}
+		perMirror[id] = compute(ring)
+	}
+	return total, perMirror
+}
+
+// RequestRates returns the number of redirects per second over the last
+// minute and the last five minutes, overall and for each mirror having
+// received redirects recently
+func (h *HTTP) RequestRates() (windows []time.Duration, total []float64, perMirror map[int][]float64) {
+	total, perMirror = h.rates.rates(time.Now())
+	return rateWindows, total, perMirror
+}
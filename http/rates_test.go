@@ -0,0 +1,50 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateTracker(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	r := newRateTracker()
+	r.started = now.Add(-time.Hour)
+
+	// 120 redirects to mirror 1 over the last two minutes, 30 to the
+	// fallbacks during the last minute
+	for i := 0; i < 120; i++ {
+		r.add(1, now.Add(-time.Duration(i)*time.Second))
+	}
+	for i := 0; i < 30; i++ {
+		r.add(0, now.Add(-time.Duration(i)*time.Second))
+	}
+	// Out of the windows
+	r.add(2, now.Add(-10*time.Minute))
+
+	total, perMirror := r.rates(now)
+	if total[0] != 1.5 || total[1] != 150.0/300 {
+		t.Fatalf("Unexpected total rates %v", total)
+	}
+	if rates := perMirror[1]; rates[0] != 1 || rates[1] != 120.0/300 {
+		t.Fatalf("Unexpected rates of mirror 1: %v", rates)
+	}
+	if _, ok := perMirror[2]; ok {
+		t.Fatalf("Expected no rate for a mirror without recent redirects")
+	}
+	if _, ok := perMirror[0]; ok {
+		t.Fatalf("Expected the fallbacks to only count in the total")
+	}
+
+	// The windows are shortened right after the start
+	r = newRateTracker()
+	r.started = now.Add(-9 * time.Second)
+	for i := 0; i < 10; i++ {
+		r.add(1, now.Add(-time.Duration(i)*time.Second))
+	}
+	if total, _ := r.rates(now); total[0] != 1 || total[1] != 1 {
+		t.Fatalf("Expected 1 redirect/s, got %v", total)
+	}
+}
@@ -17,20 +17,33 @@ import (
 	"github.com/etix/mirrorbits/mirrors"
 	"github.com/etix/mirrorbits/network"
 	"github.com/etix/mirrorbits/utils"
+	"github.com/op/go-logging"
 )
 
 var (
 	ErrInvalidFileInfo = errors.New("Invalid file info (modtime is zero)")
 )
 
+const (
+	// Bounds of the factor applied to the weight of a mirror by the dynamic
+	// scoring, a single measurement can't starve or flood a mirror
+	minThroughputFactor = 0.25
+	maxThroughputFactor = 4
+)
+
 type mirrorSelection interface {
 	// Selection must return an ordered list of selected mirror,
 	// a list of rejected mirrors and and an error code.
 	Selection(*Context, *mirrors.Cache, *filesystem.FileInfo, network.GeoIPRecord) (mirrors.Mirrors, mirrors.Mirrors, error)
 }
 
-// DefaultEngine is the default algorithm used for mirror selection
-type DefaultEngine struct{}
+// DefaultEngine is the default algorithm used for mirror selection, the
+// eligible mirrors are ordered by the SelectionStrategy
+type DefaultEngine struct {
+	connections *connectionTracker
+	popularity  *popularityTracker
+	strategies  map[string]selectionStrategy
+}
 
 // Selection returns an ordered list of selected mirror, a list of rejected mirrors and and an error code
 func (h DefaultEngine) Selection(ctx *Context, cache *mirrors.Cache, fileInfo *filesystem.FileInfo, clientInfo network.GeoIPRecord) (mlist mirrors.Mirrors, excluded mirrors.Mirrors, err error) {
@@ -41,32 +54,97 @@ func (h DefaultEngine) Selection(ctx *Context, cache *mirrors.Cache, fileInfo *f
 	}
 
 	// Prepare and return the list of all potential mirrors
-	mlist, err = cache.GetMirrors(fileInfo.Path, clientInfo)
+	mlist, err = cache.GetMirrors(ctx.Context(), fileInfo.Path, clientInfo)
 	if err != nil {
 		return
 	}
 
+	// The mirrors of the other virtual hosts may carry the same paths
+	mlist = vhostMirrors(mlist, ctx.vhost)
+
 	// Filter the list of mirrors
-	mlist, excluded, closestMirror, farthestMirror := Filter(mlist, ctx.SecureOption(), fileInfo, clientInfo)
+	mlist, excluded, _, _ = h.filterCapped(mlist, ctx.SecureOption(), fileInfo, clientInfo)
+
+	// Not enough mirrors, let the caller use the fallbacks instead
+	if requireFallback(len(mlist)) {
+		for _, m := range mlist {
+			m.ExcludeReason = fmt.Sprintf("Not enough mirrors (%d < %d)", len(mlist), GetConfig().MinMirrorsForRedirect)
+			excluded = append(excluded, m)
+		}
+		return nil, excluded, nil
+	}
+
+	// Serve the clients of the pinned countries from their preferred
+	// mirrors first, if any of them can serve the file
+	if pinned := pinMirrors(mlist, clientInfo); len(pinned) > 0 {
+		if ctx.IsMirrorlist() || ctx.IsMetalink() || ctx.IsMetalink3() || ctx.IsTorrent() || ctx.IsZsync() {
+			return pinned, excluded, nil
+		}
+		return pinned[:utils.Min(5, len(pinned))], excluded, nil
+	}
+
+	// Keep the client on the mirrors of its region, if any of them can
+	// serve the file
+	if GetConfig().RegionAffinity && clientInfo.IsValid() {
+		if regional, others := regionMirrors(mlist, clientRegion(clientInfo)); len(regional) > 0 {
+			for _, m := range others {
+				m.ExcludeReason = "Outside of the client region"
+				excluded = append(excluded, m)
+			}
+			mlist = regional
+		}
+	}
+
+	mlist = h.strategy().Order(ctx, mlist, fileInfo, clientInfo)
+	return
+}
+
+// strategy returns the selectionStrategy set in the configuration
+func (h DefaultEngine) strategy() selectionStrategy {
+	if s, ok := h.strategies[GetConfig().SelectionStrategy]; ok {
+		return s
+	}
+	return weightedStrategy{popularity: h.popularity}
+}
+
+// weightedStrategy orders the mirrors randomly, weighted by their distance,
+// their country, their AS number and their score
+type weightedStrategy struct {
+	popularity *popularityTracker
+}
 
+// Order returns the mirrors to use for the request, the mirrors outside of
+// the weight distribution being dropped from the redirects
+func (s weightedStrategy) Order(ctx *Context, mlist mirrors.Mirrors, fileInfo *filesystem.FileInfo, clientInfo network.GeoIPRecord) mirrors.Mirrors {
 	if !clientInfo.IsValid() {
-		// Shuffle the list
-		//XXX Should we use the fallbacks instead?
-		for i := range mlist {
-			j := rand.Intn(i + 1)
-			mlist[i], mlist[j] = mlist[j], mlist[i]
+		if GetConfig().StickySelection {
+			// Give the same weight to all the mirrors
+			weights := make(map[int]int, len(mlist))
+			for _, m := range mlist {
+				weights[m.ID] = 1
+			}
+			mlist = orderByID(mlist, stickyOrder(weights, stickyKey(ctx.Request(), time.Now())))
+		} else {
+			// Shuffle the list
+			//XXX Should we use the fallbacks instead?
+			for i := range mlist {
+				j := rand.Intn(i + 1)
+				mlist[i], mlist[j] = mlist[j], mlist[i]
+			}
 		}
 
 		// Shortcut: the redirect/json path only needs a handful of mirrors,
 		// but mirrorlist and metalink want the full candidate list so the
 		// client can fail over across all of them.
-		if !ctx.IsMirrorlist() && !ctx.IsMetalink() && !ctx.IsMetalink3() {
+		if !ctx.IsMirrorlist() && !ctx.IsMetalink() && !ctx.IsMetalink3() && !ctx.IsTorrent() && !ctx.IsZsync() {
 			// Reduce the number of mirrors to process
 			mlist = mlist[:utils.Min(5, len(mlist))]
 		}
-		return
+		return mlist
 	}
 
+	closestMirror, farthestMirror := distanceBounds(mlist)
+
 	// We're not interested in divisions by zero
 	if closestMirror == 0 {
 		closestMirror = math.SmallestNonzeroFloat32
@@ -76,43 +154,27 @@ func (h DefaultEngine) Selection(ctx *Context, cache *mirrors.Cache, fileInfo *f
 
 	// Compute score for each mirror and return the mirrors eligible for weight distribution.
 	// This includes:
-	// - mirrors found in a 1.5x (configurable) range from the closest mirror
+	// - mirrors found in a 1.5x (configurable) range from the closest mirror,
+	//   widened for the popular files to spread their load
 	// - mirrors targeting the given country (as primary or secondary)
 	// - mirrors being in the same AS number
-	totalScore := 0
-	baseScore := int(farthestMirror)
-	weights := map[int]int{}
-	for i := 0; i < len(mlist); i++ {
-		m := &mlist[i]
-
-		m.ComputedScore = baseScore - int(m.Distance) + 1
-
-		if m.Distance <= closestMirror*GetConfig().WeightDistributionRange {
-			score := (float32(baseScore) - m.Distance)
-			if !network.IsPrimaryCountry(clientInfo, m.CountryFields) {
-				score /= 2
-			}
-			m.ComputedScore += int(score)
-		} else if network.IsPrimaryCountry(clientInfo, m.CountryFields) {
-			m.ComputedScore += int(float32(baseScore) - (m.Distance * 5))
-		} else if network.IsAdditionalCountry(clientInfo, m.CountryFields) {
-			m.ComputedScore += int(float32(baseScore) - closestMirror)
-		}
-
-		if m.Asnum == clientInfo.ASNum {
-			m.ComputedScore += baseScore / 2
-		}
+	distanceRange := GetConfig().WeightDistributionRange
+	if s.popularity.isHot(fileInfo.Path) {
+		distanceRange *= GetConfig().PopularityWindowFactor
+	}
+	weights, totalScore := computeWeights(mlist, clientInfo, closestMirror, farthestMirror, distanceRange)
 
-		floatingScore := float64(m.ComputedScore) + (float64(m.ComputedScore) * (float64(m.Score) / 100)) + 0.5
+	// Favor the mirrors with the most bandwidth among the closest ones
+	totalScore = applyBandwidthCapacity(mlist, weights, closestMirror, GetConfig().BandwidthDistanceRange, totalScore)
 
-		// The minimum allowed score is 1
-		m.ComputedScore = int(math.Max(floatingScore, 1))
+	// Demote the mirrors served over plain HTTP
+	if GetConfig().PreferHTTPS {
+		totalScore = applyHTTPOnlyPenalty(mlist, weights, GetConfig().HTTPOnlyPenalty, totalScore)
+	}
 
-		if m.ComputedScore > baseScore {
-			// The weight must always be > 0 to not break the randomization below
-			totalScore += m.ComputedScore - baseScore
-			weights[m.ID] = m.ComputedScore - baseScore
-		}
+	// Favor the mirrors performing the best during the deep health checks
+	if GetConfig().DynamicScoring {
+		totalScore = applyDynamicScoring(mlist, weights, GetConfig().DynamicScoringWeight, totalScore)
 	}
 
 	// Get the final number of mirrors selected for weight distribution
@@ -123,7 +185,7 @@ func (h DefaultEngine) Selection(ctx *Context, cache *mirrors.Cache, fileInfo *f
 
 	if selected > 1 {
 
-		if ctx.IsMirrorlist() || ctx.IsMetalink() || ctx.IsMetalink3() {
+		if (ctx.IsMirrorlist() && !ctx.IsPlainMirrorlist()) || ctx.IsMetalink() || ctx.IsMetalink3() || ctx.IsTorrent() || ctx.IsZsync() {
 			// Don't reorder the results, just set the percentage
 			for i := 0; i < selected; i++ {
 				id := mlist[i].ID
@@ -135,19 +197,20 @@ func (h DefaultEngine) Selection(ctx *Context, cache *mirrors.Cache, fileInfo *f
 				}
 			}
 		} else {
-			// Randomize the order of the selected mirrors considering their weights
+			// Randomize the order of the selected mirrors considering their
+			// weights, or order them by client in the sticky mode
+			var order []int
+			if GetConfig().StickySelection {
+				order = stickyOrder(weights, stickyKey(ctx.Request(), time.Now()))
+			}
 			weightedMirrors := make([]mirrors.Mirror, selected)
 			rest := totalScore
 			for i := 0; i < selected; i++ {
 				var id int
-				rv := rand.Int31n(int32(rest))
-				s := 0
-				for k, v := range weights {
-					s += v
-					if int32(s) > rv {
-						id = k
-						break
-					}
+				if order != nil {
+					id = order[i]
+				} else {
+					id = pickWeighted(weights, rest)
 				}
 				for _, m := range mlist {
 					if m.ID == id {
@@ -163,19 +226,326 @@ func (h DefaultEngine) Selection(ctx *Context, cache *mirrors.Cache, fileInfo *f
 			// Replace the head of the list by its reordered counterpart
 			mlist = append(weightedMirrors, mlist[selected:]...)
 
-			// Reduce the number of mirrors to return
-			v := math.Min(math.Min(5, float64(selected)), float64(len(mlist)))
-			mlist = mlist[:int(v)]
+			// Reduce the number of mirrors to return, the plain mirror list
+			// keeps the others as fallbacks in the same order
+			if !ctx.IsPlainMirrorlist() {
+				v := math.Min(math.Min(5, float64(selected)), float64(len(mlist)))
+				mlist = mlist[:int(v)]
+			}
 		}
 	} else if selected == 1 && len(mlist) > 0 {
 		mlist[0].Weight = 100
 	}
+	return mlist
+}
+
+// orderByID returns the mirrors in the order of the given IDs
+func orderByID(mlist mirrors.Mirrors, ids []int) mirrors.Mirrors {
+	byID := make(map[int]mirrors.Mirror, len(mlist))
+	for _, m := range mlist {
+		byID[m.ID] = m
+	}
+	ordered := make(mirrors.Mirrors, 0, len(ids))
+	for _, id := range ids {
+		ordered = append(ordered, byID[id])
+	}
+	return ordered
+}
+
+// pinMirrors returns the mirrors pinned to the country of the client in the
+// order of the CountryPins, followed by the other mirrors sorted by rank.
+// It returns nil if none of the pinned mirrors is in the list.
+func pinMirrors(mlist mirrors.Mirrors, clientInfo network.GeoIPRecord) mirrors.Mirrors {
+	pins := GetConfig().CountryPins[clientInfo.CountryCode]
+	if len(pins) == 0 {
+		return nil
+	}
+
+	var pinned, others mirrors.Mirrors
+	for _, name := range pins {
+		for _, m := range mlist {
+			if m.Name == name {
+				pinned = append(pinned, m)
+				break
+			}
+		}
+	}
+	if len(pinned) == 0 {
+		return nil
+	}
+	for _, m := range mlist {
+		if !utils.IsInSlice(m.Name, pins) {
+			others = append(others, m)
+		}
+	}
+	sort.Sort(mirrors.ByRank{Mirrors: others, ClientInfo: clientInfo})
+	return append(pinned, others...)
+}
+
+// computeWeights computes the score of each mirror and returns the weights
+// of the mirrors eligible for the weighted random selection, along with
+// their sum. The eligible mirrors are those found in the given range from the
+// closest mirror, and those with a bonus for the country or the AS number
+// of the client.
+func computeWeights(mlist mirrors.Mirrors, clientInfo network.GeoIPRecord, closestMirror, farthestMirror, distanceRange float32) (weights map[int]int, totalScore int) {
+	baseScore := int(farthestMirror)
+	weights = map[int]int{}
+	for i := 0; i < len(mlist); i++ {
+		m := &mlist[i]
+
+		m.ComputedScore = baseScore - int(m.Distance) + 1
+
+		if m.Distance <= closestMirror*distanceRange {
+			score := (float32(baseScore) - m.Distance)
+			if !network.IsPrimaryCountry(clientInfo, m.CountryFields) {
+				score /= 2
+			}
+			m.ComputedScore += int(score)
+		} else if network.IsPrimaryCountry(clientInfo, m.CountryFields) {
+			m.ComputedScore += int(float32(baseScore) - (m.Distance * 5))
+		} else if network.IsAdditionalCountry(clientInfo, m.CountryFields) {
+			m.ComputedScore += int(float32(baseScore) - closestMirror)
+		}
+
+		if m.Asnum == clientInfo.ASNum {
+			m.ComputedScore += baseScore / 2
+		}
+
+		floatingScore := float64(m.ComputedScore) + (float64(m.ComputedScore) * (float64(m.Score) / 100)) + 0.5
+
+		// The minimum allowed score is 1
+		m.ComputedScore = int(math.Max(floatingScore, 1))
+
+		if m.ComputedScore > baseScore {
+			// The weight must always be > 0 to not break the randomization below
+			totalScore += m.ComputedScore - baseScore
+			weights[m.ID] = m.ComputedScore - baseScore
+		}
+	}
+	return weights, totalScore
+}
+
+// clientRegion returns the region of the client, matching its country
+// before its continent, or an empty string if it's in none of the Regions
+func clientRegion(clientInfo network.GeoIPRecord) string {
+	regions := GetConfig().Regions
+	for name, region := range regions {
+		if clientInfo.CountryCode != "" && utils.IsInSlice(clientInfo.CountryCode, region.Countries) {
+			return name
+		}
+	}
+	for name, region := range regions {
+		if clientInfo.ContinentCode != "" && utils.IsInSlice(clientInfo.ContinentCode, region.Continents) {
+			return name
+		}
+	}
+	return ""
+}
+
+// regionMirrors separates the mirrors of the given region from the others
+func regionMirrors(mlist mirrors.Mirrors, region string) (regional, others mirrors.Mirrors) {
+	if region == "" {
+		return nil, mlist
+	}
+	for _, m := range mlist {
+		if strings.EqualFold(m.Region, region) {
+			regional = append(regional, m)
+		} else {
+			others = append(others, m)
+		}
+	}
+	return regional, others
+}
+
+// distanceBounds returns the distances of the closest and the farthest
+// mirrors of the list
+func distanceBounds(mlist mirrors.Mirrors) (closest, farthest float32) {
+	for i, m := range mlist {
+		if i == 0 || m.Distance < closest {
+			closest = m.Distance
+		}
+		if m.Distance > farthest {
+			farthest = m.Distance
+		}
+	}
+	return
+}
+
+// pickWeighted randomly picks one of the given mirror IDs, with a probability
+// proportional to its weight. Total must be the sum of all the weights.
+func pickWeighted(weights map[int]int, total int) (id int) {
+	rv := rand.Int31n(int32(total))
+	s := 0
+	for k, v := range weights {
+		s += v
+		if int32(s) > rv {
+			return k
+		}
+	}
+	return
+}
+
+// applyBandwidthCapacity scales the weights of the mirrors found within
+// distanceRange km of the closest mirror proportionally to their bandwidth
+// capacity. Mirrors with an unknown capacity are assumed to have the average
+// capacity of the others. The weights are left untouched if less than two
+// mirrors are concerned or if none of them has a known capacity. It returns
+// the new total of the weights.
+func applyBandwidthCapacity(mlist mirrors.Mirrors, weights map[int]int, closestMirror, distanceRange float32, totalScore int) int {
+	var candidates []*mirrors.Mirror
+	var known, capacity int
+	for i := range mlist {
+		m := &mlist[i]
+		if _, ok := weights[m.ID]; !ok || m.Distance > closestMirror+distanceRange {
+			continue
+		}
+		candidates = append(candidates, m)
+		if m.BandwidthCapacity > 0 {
+			known++
+			capacity += m.BandwidthCapacity
+		}
+	}
+	if len(candidates) < 2 || known == 0 {
+		return totalScore
+	}
+
+	average := float64(capacity) / float64(known)
+	for _, m := range candidates {
+		c := average
+		if m.BandwidthCapacity > 0 {
+			c = float64(m.BandwidthCapacity)
+		}
+		w := weights[m.ID]
+		nw := int(math.Max(math.Round(float64(w)*c/average), 1))
+		weights[m.ID] = nw
+		m.ComputedScore += nw - w
+		totalScore += nw - w
+	}
+	return totalScore
+}
+
+// applyHTTPOnlyPenalty reduces by the given fraction (0 to 1) the weights of
+// the eligible mirrors served over plain HTTP, as long as some of the others
+// are served over HTTPS. It returns the new total of the weights.
+func applyHTTPOnlyPenalty(mlist mirrors.Mirrors, weights map[int]int, penalty float32, totalScore int) int {
+	if penalty <= 0 {
+		return totalScore
+	}
+	var plain []*mirrors.Mirror
+	secure := false
+	for i := range mlist {
+		m := &mlist[i]
+		if _, ok := weights[m.ID]; !ok {
+			continue
+		}
+		if strings.HasPrefix(m.AbsoluteURL, "http://") {
+			plain = append(plain, m)
+		} else {
+			secure = true
+		}
+	}
+	if !secure || len(plain) == 0 {
+		// The client asked for HTTP, or nothing to compare with
+		return totalScore
+	}
+
+	for _, m := range plain {
+		w := weights[m.ID]
+		nw := int(math.Max(math.Round(float64(w)*(1-float64(penalty))), 1))
+		weights[m.ID] = nw
+		m.ComputedScore += nw - w
+		totalScore += nw - w
+	}
+	return totalScore
+}
+
+// applyDynamicScoring scales the weights of the mirrors by their measured
+// throughput relative to the average throughput of the others. The factor
+// is clamped to [minThroughputFactor, maxThroughputFactor] and then blended
+// with the static score according to blend (0 to 1). Mirrors not measured yet
+// are assumed to be average. It returns the new total of the weights.
+func applyDynamicScoring(mlist mirrors.Mirrors, weights map[int]int, blend float32, totalScore int) int {
+	var candidates []*mirrors.Mirror
+	var known int
+	var throughput float64
+	for i := range mlist {
+		m := &mlist[i]
+		if _, ok := weights[m.ID]; !ok {
+			continue
+		}
+		candidates = append(candidates, m)
+		if m.Throughput > 0 {
+			known++
+			throughput += m.Throughput
+		}
+	}
+	if len(candidates) < 2 || known == 0 || blend <= 0 {
+		return totalScore
+	}
+
+	average := throughput / float64(known)
+	for _, m := range candidates {
+		factor := 1.0
+		if m.Throughput > 0 {
+			factor = math.Min(math.Max(m.Throughput/average, minThroughputFactor), maxThroughputFactor)
+		}
+		factor = 1 - float64(blend) + float64(blend)*factor
+		w := weights[m.ID]
+		nw := int(math.Max(math.Round(float64(w)*factor), 1))
+		weights[m.ID] = nw
+		m.ComputedScore += nw - w
+		totalScore += nw - w
+	}
+	return totalScore
+}
+
+// referenceSize returns the size of the file on the reference mirror, if it
+// has the file, or else the size found on most of the mirrors, the largest
+// one in case of a tie. It returns -1 if no mirror has the file.
+func referenceSize(mlist mirrors.Mirrors) int64 {
+	reference := GetConfig().VerifySizeReference
+	sizes := make(map[int64]int)
+	for _, m := range mlist {
+		if m.FileInfo == nil || !m.Enabled {
+			continue
+		}
+		if reference != "" && m.Name == reference {
+			return m.FileInfo.Size
+		}
+		sizes[m.FileInfo.Size]++
+	}
+	size, count := int64(-1), 0
+	for s, c := range sizes {
+		if c > count || (c == count && s > size) {
+			size, count = s, c
+		}
+	}
+	return size
+}
+
+// filterCapped filters the list of mirrors like Filter, setting aside the
+// mirrors having reached their maximum number of connections. The cap is a
+// soft one: they're still used if no other mirror can serve the file.
+func (h DefaultEngine) filterCapped(mlist mirrors.Mirrors, secureOption SecureOption, fileInfo *filesystem.FileInfo, clientInfo network.GeoIPRecord) (accepted mirrors.Mirrors, excluded mirrors.Mirrors, closestMirror float32, farthestMirror float32) {
+	available, capped := h.connections.split(mlist, time.Now())
+	accepted, excluded, closestMirror, farthestMirror = Filter(available, secureOption, fileInfo, clientInfo)
+	if len(capped) == 0 {
+		return
+	}
+	if len(accepted) == 0 {
+		return Filter(mlist, secureOption, fileInfo, clientInfo)
+	}
+	for _, m := range capped {
+		m.ExcludeReason = "Connection limit reached"
+		excluded = append(excluded, m)
+	}
 	return
 }
 
 // Filter mirror list, return the list of mirrors candidates for redirection,
-// and the list of mirrors that were excluded. Also return the distance of the
-// closest and farthest mirrors.
+// and the list of mirrors that were excluded. Only the candidates of the
+// lowest tier are returned. Also return the distance of the closest and
+// farthest mirrors.
 func Filter(mlist mirrors.Mirrors, secureOption SecureOption, fileInfo *filesystem.FileInfo, clientInfo network.GeoIPRecord) (accepted mirrors.Mirrors, excluded mirrors.Mirrors, closestMirror float32, farthestMirror float32) {
 	// Check if this file is allowed to be outdated
 	checkSize := true
@@ -189,6 +559,17 @@ func Filter(mlist mirrors.Mirrors, secureOption SecureOption, fileInfo *filesyst
 		}
 	}
 
+	// The size of the local file isn't authoritative for the files allowed
+	// to be outdated, compare the mirrors with a reference size instead
+	verifySize := GetConfig().VerifySizeOnRedirect
+	refSize := int64(-1)
+	if !checkSize && verifySize {
+		refSize = referenceSize(mlist)
+		checkSize = refSize >= 0
+	} else if fileInfo != nil {
+		refSize = fileInfo.Size
+	}
+
 	accepted = make([]mirrors.Mirror, 0, len(mlist))
 	excluded = make([]mirrors.Mirror, 0, len(mlist))
 
@@ -227,29 +608,27 @@ func Filter(mlist mirrors.Mirrors, secureOption SecureOption, fileInfo *filesyst
 			}
 			goto discard
 		default:
-			// Any protocol will do - favor HTTPS if avail
-			var httpReason, httpsReason string
-
-			m.AbsoluteURL = ensureAbsolute(m.HttpURL, "https")
-			httpsSupported := !strings.HasPrefix(m.HttpURL, "http://")
-			if !httpsSupported {
-				httpsReason = "Not HTTPS"
-			} else if !m.HttpsUp {
-				httpsReason = either(m.HttpsDownReason, "Down")
-			} else {
-				break
+			// Any protocol will do - favor HTTPS if avail, unless
+			// PreferHTTPS is disabled
+			schemes := []string{"https", "http"}
+			if !GetConfig().PreferHTTPS {
+				schemes = []string{"http", "https"}
 			}
-
-			m.AbsoluteURL = ensureAbsolute(m.HttpURL, "http")
-			httpSupported := !strings.HasPrefix(m.HttpURL, "https://")
-			if !httpSupported {
-				httpReason = "Not HTTP"
-			} else if !m.HttpUp {
-				httpReason = either(m.HttpDownReason, "Down")
-			} else {
+			reasons := make(map[string]string, 2)
+			available := false
+			for _, scheme := range schemes {
+				m.AbsoluteURL = ensureAbsolute(m.HttpURL, scheme)
+				if reasons[scheme] = unavailableReason(&m, scheme); reasons[scheme] == "" {
+					available = true
+					break
+				}
+			}
+			if available {
 				break
 			}
 
+			m.AbsoluteURL = ensureAbsolute(m.HttpURL, "http")
+			httpReason, httpsReason := reasons["http"], reasons["https"]
 			if httpReason == httpsReason {
 				m.ExcludeReason = httpReason
 			} else {
@@ -258,10 +637,25 @@ func Filter(mlist mirrors.Mirrors, secureOption SecureOption, fileInfo *filesyst
 			goto discard
 		}
 
+		// Can the client reach it with its IP version?
+		if !m.IsReachableOver(clientInfo.IPVersion) {
+			m.ExcludeReason = fmt.Sprintf("No IPv%d address", clientInfo.IPVersion)
+			goto discard
+		}
+
+		// Is it flapping?
+		if m.InCooldown() {
+			m.ExcludeReason = "Flapping (cooldown)"
+			goto discard
+		}
+
 		// Is it the same size / modtime as source?
 		if m.FileInfo != nil {
-			if checkSize && m.FileInfo.Size != fileInfo.Size {
+			if checkSize && m.FileInfo.Size != refSize {
 				m.ExcludeReason = "File size mismatch"
+				if verifySize && sizeMismatchLog.allow(m.Name+"|"+fileInfo.Path, time.Now()) {
+					log.Warningf("Mirror %s skipped for %s: size mismatch (%d instead of %d)", m.Name, fileInfo.Path, m.FileInfo.Size, refSize)
+				}
 				goto discard
 			}
 			if !m.FileInfo.ModTime.IsZero() {
@@ -306,24 +700,96 @@ func Filter(mlist mirrors.Mirrors, secureOption SecureOption, fileInfo *filesyst
 			m.ExcludeReason = "User's country restriction"
 			goto discard
 		}
-		// Keep track of the closest and farthest mirrors
-		if len(accepted) == 0 {
-			closestMirror = m.Distance
-		} else if m.Distance < closestMirror {
-			closestMirror = m.Distance
-		}
-		if m.Distance > farthestMirror {
-			farthestMirror = m.Distance
-		}
 		accepted = append(accepted, m)
 		continue
 	discard:
 		excluded = append(excluded, m)
 	}
 
+	// The mirrors not scanned for a while are only used when none of the
+	// recently scanned ones can serve the file
+	if maxAge := GetConfig().MaxScanAge; maxAge > 0 {
+		var fresh, stale mirrors.Mirrors
+		limit := time.Now().Add(-time.Duration(maxAge) * time.Minute)
+		for _, m := range accepted {
+			if m.LastSuccessfulSync.Before(limit) {
+				stale = append(stale, m)
+			} else {
+				fresh = append(fresh, m)
+			}
+		}
+		if len(fresh) > 0 {
+			accepted = fresh
+			for _, m := range stale {
+				m.ExcludeReason = "Last scan too old"
+				excluded = append(excluded, m)
+			}
+		}
+	}
+
+	// The backup tiers are only used when none of the mirrors of the
+	// lower tiers can serve the file
+	accepted, backups := accepted.LowestTier()
+	if GetConfig().BelowMinMirrors == "proceed" {
+		// Complete the candidates with the next tiers until the minimum
+		// number of mirrors is reached
+		for len(accepted) < GetConfig().MinMirrorsForRedirect && len(backups) > 0 {
+			var next mirrors.Mirrors
+			next, backups = backups.LowestTier()
+			accepted = append(accepted, next...)
+		}
+	}
+	for _, m := range backups {
+		m.ExcludeReason = fmt.Sprintf("Backup mirror (tier %d)", m.Tier)
+		excluded = append(excluded, m)
+	}
+
+	// Keep track of the closest and farthest mirrors
+	closestMirror, farthestMirror = distanceBounds(accepted)
+
 	return
 }
 
+// sampleSelection returns true for a random SelectionDebugSampleRate
+// fraction of the requests, provided the debug messages are logged
+func sampleSelection() bool {
+	rate := GetConfig().SelectionDebugSampleRate
+	return rate > 0 && rand.Float32() < rate && log.IsEnabledFor(logging.DEBUG)
+}
+
+// logSelection logs the ranked candidates of a redirect, the first one
+// being the mirror the client was sent to
+func logSelection(path string, clientInfo network.GeoIPRecord, mlist, excluded mirrors.Mirrors, fallback bool) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Selection for %s (country: %s, AS: %d", path, either(clientInfo.CountryCode, "unknown"), clientInfo.ASNum)
+	if fallback {
+		b.WriteString(", fallback")
+	}
+	b.WriteString(")")
+	for i, m := range mlist {
+		fmt.Fprintf(&b, "\n  %d. %s: distance=%.0fkm score=%d weight=%.3f", i+1, m.Name, m.Distance, m.ComputedScore, m.Weight)
+		if i == 0 {
+			b.WriteString(" (chosen)")
+		}
+	}
+	for _, m := range excluded {
+		fmt.Fprintf(&b, "\n  -  %s: %s", m.Name, m.ExcludeReason)
+	}
+	log.Debug(b.String())
+}
+
+// requireFallback returns true if the given number of candidate mirrors is
+// below MinMirrorsForRedirect and the request must be handled by the
+// fallbacks
+func requireFallback(candidates int) bool {
+	config := GetConfig()
+	if config.BelowMinMirrors != "fallback" || candidates == 0 || candidates >= config.MinMirrorsForRedirect {
+		return false
+	}
+	// Without fallbacks the client would get an error instead
+	return config.FallbackMode != "redirect" || len(config.Fallbacks) > 0
+}
+
 // ensureAbsolute returns the url 'as is' if it's absolute (ie. it starts with
 // a scheme), otherwise it prepends '<scheme>://' and returns the result.
 func ensureAbsolute(url string, scheme string) string {
@@ -333,6 +799,25 @@ func ensureAbsolute(url string, scheme string) string {
 	return scheme + "://" + url
 }
 
+// unavailableReason returns why the mirror can't be used over the given
+// scheme, or an empty string if it can
+func unavailableReason(m *mirrors.Mirror, scheme string) string {
+	if scheme == "https" {
+		if strings.HasPrefix(m.HttpURL, "http://") {
+			return "Not HTTPS"
+		} else if !m.HttpsUp {
+			return either(m.HttpsDownReason, "Down")
+		}
+		return ""
+	}
+	if strings.HasPrefix(m.HttpURL, "https://") {
+		return "Not HTTP"
+	} else if !m.HttpUp {
+		return either(m.HttpDownReason, "Down")
+	}
+	return ""
+}
+
 // either returns s if it's not empty, d otherwise
 func either(s string, d string) string {
 	if s != "" {
@@ -401,6 +401,70 @@ func TestFilterAllowOutdatedFiles(t *testing.T) {
 	}
 }
 
+func TestFilterVerifySizeOnRedirect(t *testing.T) {
+	testfile := &filesystem.FileInfo{
+		Path:    "/test/file.tgz",
+		Size:    43000,
+		ModTime: time.Now(),
+	}
+
+	mirror := func(id int, size int64) mirrors.Mirror {
+		return mirrors.Mirror{
+			ID:      id,
+			Name:    fmt.Sprintf("m%d", id),
+			HttpURL: fmt.Sprintf("https://m%d.mirror", id),
+			Enabled: true,
+			HttpsUp: true,
+			FileInfo: &filesystem.FileInfo{
+				Path:    testfile.Path,
+				Size:    size,
+				ModTime: testfile.ModTime,
+			},
+		}
+	}
+
+	tests := map[string]struct {
+		reference string
+		mlist     mirrors.Mirrors
+		size      int64
+		accepted  []int
+	}{
+		"consensus":         {"", mirrors.Mirrors{mirror(1, 100), mirror(2, 200), mirror(3, 200)}, 200, []int{2, 3}},
+		"largest consensus": {"", mirrors.Mirrors{mirror(1, 100), mirror(2, 200)}, 200, []int{2}},
+		"reference mirror":  {"m1", mirrors.Mirrors{mirror(1, 100), mirror(2, 200), mirror(3, 200)}, 100, []int{1}},
+		"missing reference": {"m4", mirrors.Mirrors{mirror(1, 100), mirror(2, 200), mirror(3, 200)}, 200, []int{2, 3}},
+		"no mirror":         {"", mirrors.Mirrors{}, -1, nil},
+	}
+
+	defer SetConfiguration(GetConfig())
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			SetConfiguration(&Configuration{
+				AllowOutdatedFiles:   []OutdatedFilesConfig{{Prefix: "/test/", Minutes: 1}},
+				VerifySizeOnRedirect: true,
+				VerifySizeReference:  test.reference,
+			})
+			if size := referenceSize(test.mlist); size != test.size {
+				t.Fatalf("Expected a reference size of %d, got %d", test.size, size)
+			}
+			a, x, _, _ := Filter(test.mlist, WITHTLS, testfile, noClientInfo)
+			if len(a) != len(test.accepted) {
+				t.Fatalf("Expected %d mirrors accepted, got %d", len(test.accepted), len(a))
+			}
+			for i, id := range test.accepted {
+				if a[i].ID != id {
+					t.Fatalf("Expected mirror %d at position %d, got %d", id, i, a[i].ID)
+				}
+			}
+			for _, m := range x {
+				if m.ExcludeReason != "File size mismatch" {
+					t.Fatalf("Invalid ExcludeReason for mirror %d: '%s'", m.ID, m.ExcludeReason)
+				}
+			}
+		})
+	}
+}
+
 func TestFilterFixTimezoneOffsets(t *testing.T) {
 	// Given a mirror with a 1-hour timezone offset, test that the mirror
 	// is rejected unless 1) the TZOffset of the mirror is set correctly,
@@ -463,3 +527,625 @@ func TestFilterFixTimezoneOffsets(t *testing.T) {
 		}
 	}
 }
+
+func TestApplyBandwidthCapacity(t *testing.T) {
+	tests := map[string]struct {
+		capacities []int
+		distances  []float32
+		expected   []int
+	}{
+		"no_capacity": {
+			capacities: []int{0, 0},
+			distances:  []float32{10, 10},
+			expected:   []int{100, 100},
+		},
+		"same_distance": {
+			capacities: []int{1000, 100},
+			distances:  []float32{10, 10},
+			expected:   []int{182, 18},
+		},
+		"unknown_capacity_is_average": {
+			capacities: []int{1000, 0, 100},
+			distances:  []float32{10, 10, 10},
+			expected:   []int{182, 100, 18},
+		},
+		"out_of_range": {
+			capacities: []int{1000, 100},
+			distances:  []float32{10, 500},
+			expected:   []int{100, 100},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mlist := mirrors.Mirrors{}
+			weights := map[int]int{}
+			total := 0
+			for i := range test.capacities {
+				mlist = append(mlist, mirrors.Mirror{
+					ID:                i + 1,
+					Distance:          test.distances[i],
+					BandwidthCapacity: test.capacities[i],
+				})
+				weights[i+1] = 100
+				total += 100
+			}
+			total = applyBandwidthCapacity(mlist, weights, 10, 100, total)
+			sum := 0
+			for i, w := range test.expected {
+				if weights[i+1] != w {
+					t.Fatalf("Invalid weight for mirror %d, expected %d, got %d", i+1, w, weights[i+1])
+				}
+				sum += w
+			}
+			if total != sum {
+				t.Fatalf("Invalid total, expected %d, got %d", sum, total)
+			}
+		})
+	}
+}
+
+func TestApplyDynamicScoring(t *testing.T) {
+	tests := map[string]struct {
+		throughputs []float64
+		blend       float32
+		expected    []int
+	}{
+		"not_measured": {
+			throughputs: []float64{0, 0},
+			blend:       1,
+			expected:    []int{100, 100},
+		},
+		"full_blend": {
+			throughputs: []float64{3000, 1000},
+			blend:       1,
+			expected:    []int{150, 50},
+		},
+		"half_blend": {
+			throughputs: []float64{3000, 1000},
+			blend:       0.5,
+			expected:    []int{125, 75},
+		},
+		"no_blend": {
+			throughputs: []float64{3000, 1000},
+			blend:       0,
+			expected:    []int{100, 100},
+		},
+		"not_measured_is_average": {
+			throughputs: []float64{3000, 0, 1000},
+			blend:       1,
+			expected:    []int{150, 100, 50},
+		},
+		"clamped": {
+			throughputs: []float64{10000, 100},
+			blend:       1,
+			expected:    []int{198, 25},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mlist := mirrors.Mirrors{}
+			weights := map[int]int{}
+			total := 0
+			for i := range test.throughputs {
+				mlist = append(mlist, mirrors.Mirror{
+					ID:         i + 1,
+					Throughput: test.throughputs[i],
+				})
+				weights[i+1] = 100
+				total += 100
+			}
+			total = applyDynamicScoring(mlist, weights, test.blend, total)
+			sum := 0
+			for i, w := range test.expected {
+				if weights[i+1] != w {
+					t.Fatalf("Invalid weight for mirror %d, expected %d, got %d", i+1, w, weights[i+1])
+				}
+				sum += w
+			}
+			if total != sum {
+				t.Fatalf("Invalid total, expected %d, got %d", sum, total)
+			}
+		})
+	}
+}
+
+func TestPickWeightedBandwidthCapacity(t *testing.T) {
+	// Two geographically equivalent mirrors, one having 10x the capacity
+	// of the other, should receive roughly 10x the traffic.
+	mlist := mirrors.Mirrors{
+		{ID: 1, Distance: 42, BandwidthCapacity: 10000},
+		{ID: 2, Distance: 42, BandwidthCapacity: 1000},
+	}
+	weights := map[int]int{1: 50, 2: 50}
+	total := applyBandwidthCapacity(mlist, weights, 42, 100, 100)
+
+	hits := map[int]int{}
+	for i := 0; i < 100000; i++ {
+		hits[pickWeighted(weights, total)]++
+	}
+
+	ratio := float64(hits[1]) / float64(hits[2])
+	if ratio < 9 || ratio > 11 {
+		t.Fatalf("Expected a ratio of about 10, got %.2f (%d / %d)", ratio, hits[1], hits[2])
+	}
+}
+
+func TestPinMirrors(t *testing.T) {
+	mlist := mirrors.Mirrors{
+		{ID: 1, Name: "M1", Distance: 10},
+		{ID: 2, Name: "M2", Distance: 20},
+		{ID: 3, Name: "M3", Distance: 30},
+	}
+	fr := network.GeoIPRecord{CountryCode: "FR"}
+
+	tests := map[string]struct {
+		pins     map[string][]string
+		client   network.GeoIPRecord
+		expected []int
+	}{
+		"no pins":        {nil, fr, nil},
+		"other country":  {map[string][]string{"DE": {"M3"}}, fr, nil},
+		"pinned":         {map[string][]string{"FR": {"M3"}}, fr, []int{3, 1, 2}},
+		"pin order":      {map[string][]string{"FR": {"M3", "M2"}}, fr, []int{3, 2, 1}},
+		"pin missing":    {map[string][]string{"FR": {"M4", "M2"}}, fr, []int{2, 1, 3}},
+		"none available": {map[string][]string{"FR": {"M4"}}, fr, nil},
+		"no country":     {map[string][]string{"FR": {"M3"}}, noClientInfo, nil},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			SetConfiguration(&Configuration{
+				CountryPins: test.pins,
+			})
+			defer SetConfiguration(&Configuration{})
+
+			result := pinMirrors(mlist, test.client)
+			if len(result) != len(test.expected) {
+				t.Fatalf("Expected %d mirrors, got %d", len(test.expected), len(result))
+			}
+			for i, id := range test.expected {
+				if result[i].ID != id {
+					t.Fatalf("Expected mirror %d at position %d, got %d", id, i, result[i].ID)
+				}
+			}
+		})
+	}
+}
+
+func TestRegionMirrors(t *testing.T) {
+	SetConfiguration(&Configuration{
+		Regions: map[string]Region{
+			"EU":   {Continents: []string{"EU"}},
+			"APAC": {Continents: []string{"AS", "OC"}, Countries: []string{"RU"}},
+		},
+	})
+	defer SetConfiguration(&Configuration{})
+
+	mlist := mirrors.Mirrors{
+		{ID: 1, Name: "M1", Region: "EU"},
+		{ID: 2, Name: "M2", Region: "apac"},
+		{ID: 3, Name: "M3"},
+	}
+
+	tests := map[string]struct {
+		client   network.GeoIPRecord
+		region   string
+		expected []int
+	}{
+		"continent":  {network.GeoIPRecord{CountryCode: "FR", ContinentCode: "EU"}, "EU", []int{1}},
+		"country":    {network.GeoIPRecord{CountryCode: "RU", ContinentCode: "EU"}, "APAC", []int{2}},
+		"no region":  {network.GeoIPRecord{CountryCode: "US", ContinentCode: "NA"}, "", nil},
+		"unresolved": {noClientInfo, "", nil},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			region := clientRegion(test.client)
+			if region != test.region {
+				t.Fatalf("Expected region %q, got %q", test.region, region)
+			}
+			regional, others := regionMirrors(mlist, region)
+			if len(regional) != len(test.expected) || len(regional)+len(others) != len(mlist) {
+				t.Fatalf("Expected %d mirrors in the region, got %d", len(test.expected), len(regional))
+			}
+			for i, id := range test.expected {
+				if regional[i].ID != id {
+					t.Fatalf("Expected mirror %d at position %d, got %d", id, i, regional[i].ID)
+				}
+			}
+		})
+	}
+}
+
+func TestFilterTiers(t *testing.T) {
+	testfile := &filesystem.FileInfo{
+		Path:    "/test/file.tgz",
+		Size:    43000,
+		ModTime: time.Now(),
+	}
+
+	mirror := func(id, tier int, distance float32, up bool) mirrors.Mirror {
+		return mirrors.Mirror{
+			ID:       id,
+			HttpURL:  fmt.Sprintf("https://m%d.mirror", id),
+			Enabled:  true,
+			HttpsUp:  up,
+			Tier:     tier,
+			Distance: distance,
+			FileInfo: &filesystem.FileInfo{
+				Path:    testfile.Path,
+				Size:    testfile.Size,
+				ModTime: testfile.ModTime,
+			},
+		}
+	}
+
+	tests := map[string]struct {
+		mlist    mirrors.Mirrors
+		accepted []int
+		closest  float32
+		farthest float32
+	}{
+		"primary only":     {mirrors.Mirrors{mirror(1, 0, 10, true), mirror(2, 0, 20, true)}, []int{1, 2}, 10, 20},
+		"primary first":    {mirrors.Mirrors{mirror(1, 1, 10, true), mirror(2, 0, 20, true), mirror(3, 0, 30, true)}, []int{2, 3}, 20, 30},
+		"primary down":     {mirrors.Mirrors{mirror(1, 0, 10, false), mirror(2, 1, 20, true), mirror(3, 2, 30, true)}, []int{2}, 20, 20},
+		"fall through":     {mirrors.Mirrors{mirror(1, 0, 10, false), mirror(2, 1, 20, false), mirror(3, 2, 30, true)}, []int{3}, 30, 30},
+		"same backup tier": {mirrors.Mirrors{mirror(1, 2, 10, true), mirror(2, 3, 20, true), mirror(3, 2, 30, true)}, []int{1, 3}, 10, 30},
+		"none available":   {mirrors.Mirrors{mirror(1, 0, 10, false), mirror(2, 1, 20, false)}, nil, 0, 0},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			a, x, closest, farthest := Filter(test.mlist, WITHTLS, testfile, noClientInfo)
+			if len(a) != len(test.accepted) {
+				t.Fatalf("Expected %d mirrors accepted, got %d", len(test.accepted), len(a))
+			}
+			for i, id := range test.accepted {
+				if a[i].ID != id {
+					t.Fatalf("Expected mirror %d at position %d, got %d", id, i, a[i].ID)
+				}
+			}
+			if len(a)+len(x) != len(test.mlist) {
+				t.Fatalf("Expected %d mirrors excluded, got %d", len(test.mlist)-len(a), len(x))
+			}
+			for _, m := range x {
+				if m.HttpsUp && m.ExcludeReason != fmt.Sprintf("Backup mirror (tier %d)", m.Tier) {
+					t.Fatalf("Invalid ExcludeReason for mirror %d: '%s'", m.ID, m.ExcludeReason)
+				}
+			}
+			if closest != test.closest || farthest != test.farthest {
+				t.Fatalf("Expected distances %.0f/%.0f, got %.0f/%.0f", test.closest, test.farthest, closest, farthest)
+			}
+		})
+	}
+}
+
+func TestFilterMaxScanAge(t *testing.T) {
+	defer SetConfiguration(GetConfig())
+	SetConfiguration(&Configuration{
+		MaxScanAge: 60,
+	})
+
+	testfile := &filesystem.FileInfo{
+		Path:    "/test/file.tgz",
+		Size:    43000,
+		ModTime: time.Now(),
+	}
+
+	mirror := func(id int, scanned time.Duration) mirrors.Mirror {
+		m := mirrors.Mirror{
+			ID:      id,
+			HttpURL: fmt.Sprintf("https://m%d.mirror", id),
+			Enabled: true,
+			HttpsUp: true,
+			FileInfo: &filesystem.FileInfo{
+				Path:    testfile.Path,
+				Size:    testfile.Size,
+				ModTime: testfile.ModTime,
+			},
+		}
+		if scanned > 0 {
+			m.LastSuccessfulSync = mirrors.Time{}.FromTime(time.Now().Add(-scanned))
+		}
+		return m
+	}
+
+	tests := map[string]struct {
+		mlist    mirrors.Mirrors
+		accepted []int
+	}{
+		"all fresh":     {mirrors.Mirrors{mirror(1, time.Minute), mirror(2, time.Minute)}, []int{1, 2}},
+		"stale":         {mirrors.Mirrors{mirror(1, 2*time.Hour), mirror(2, time.Minute)}, []int{2}},
+		"never scanned": {mirrors.Mirrors{mirror(1, 0), mirror(2, time.Minute)}, []int{2}},
+		"all stale":     {mirrors.Mirrors{mirror(1, 2*time.Hour), mirror(2, 0)}, []int{1, 2}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			a, x, _, _ := Filter(test.mlist, WITHTLS, testfile, noClientInfo)
+			if len(a) != len(test.accepted) {
+				t.Fatalf("Expected %d mirrors accepted, got %d", len(test.accepted), len(a))
+			}
+			for i, id := range test.accepted {
+				if a[i].ID != id {
+					t.Fatalf("Expected mirror %d at position %d, got %d", id, i, a[i].ID)
+				}
+			}
+			for _, m := range x {
+				if m.ExcludeReason != "Last scan too old" {
+					t.Fatalf("Invalid ExcludeReason for mirror %d: '%s'", m.ID, m.ExcludeReason)
+				}
+			}
+		})
+	}
+}
+
+func TestFilterMinMirrorsForRedirect(t *testing.T) {
+	defer SetConfiguration(GetConfig())
+	SetConfiguration(&Configuration{
+		MinMirrorsForRedirect: 3,
+		BelowMinMirrors:       "proceed",
+	})
+
+	testfile := &filesystem.FileInfo{
+		Path:    "/test/file.tgz",
+		Size:    43000,
+		ModTime: time.Now(),
+	}
+
+	mirror := func(id, tier int) mirrors.Mirror {
+		return mirrors.Mirror{
+			ID:       id,
+			HttpURL:  fmt.Sprintf("https://m%d.mirror", id),
+			Enabled:  true,
+			HttpsUp:  true,
+			Tier:     tier,
+			FileInfo: &filesystem.FileInfo{
+				Path:    testfile.Path,
+				Size:    testfile.Size,
+				ModTime: testfile.ModTime,
+			},
+		}
+	}
+
+	tests := map[string]struct {
+		mlist    mirrors.Mirrors
+		accepted int
+	}{
+		"enough mirrors":   {mirrors.Mirrors{mirror(1, 0), mirror(2, 0), mirror(3, 0), mirror(4, 1)}, 3},
+		"next tier":        {mirrors.Mirrors{mirror(1, 0), mirror(2, 1), mirror(3, 1), mirror(4, 2)}, 3},
+		"whole tiers":      {mirrors.Mirrors{mirror(1, 0), mirror(2, 0), mirror(3, 1), mirror(4, 1), mirror(5, 2)}, 4},
+		"all tiers":        {mirrors.Mirrors{mirror(1, 0), mirror(2, 2)}, 2},
+		"single available": {mirrors.Mirrors{mirror(1, 0)}, 1},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			a, x, _, _ := Filter(test.mlist, WITHTLS, testfile, noClientInfo)
+			if len(a) != test.accepted {
+				t.Fatalf("Expected %d mirrors accepted, got %d", test.accepted, len(a))
+			}
+			if len(a)+len(x) != len(test.mlist) {
+				t.Fatalf("Expected %d mirrors excluded, got %d", len(test.mlist)-len(a), len(x))
+			}
+		})
+	}
+}
+
+func TestRequireFallback(t *testing.T) {
+	defer SetConfiguration(GetConfig())
+
+	tests := map[string]struct {
+		config     Configuration
+		candidates int
+		expected   bool
+	}{
+		"disabled":     {Configuration{BelowMinMirrors: "fallback", FallbackMode: "notfound"}, 1, false},
+		"proceed":      {Configuration{MinMirrorsForRedirect: 2, BelowMinMirrors: "proceed", FallbackMode: "notfound"}, 1, false},
+		"below":        {Configuration{MinMirrorsForRedirect: 2, BelowMinMirrors: "fallback", FallbackMode: "notfound"}, 1, true},
+		"enough":       {Configuration{MinMirrorsForRedirect: 2, BelowMinMirrors: "fallback", FallbackMode: "notfound"}, 2, false},
+		"none":         {Configuration{MinMirrorsForRedirect: 2, BelowMinMirrors: "fallback", FallbackMode: "notfound"}, 0, false},
+		"no fallbacks": {Configuration{MinMirrorsForRedirect: 2, BelowMinMirrors: "fallback", FallbackMode: "redirect"}, 1, false},
+		"fallbacks":    {Configuration{MinMirrorsForRedirect: 2, BelowMinMirrors: "fallback", FallbackMode: "redirect", Fallbacks: []Fallback{{URL: "https://fallback.mirror/"}}}, 1, true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := test.config
+			SetConfiguration(&config)
+			if r := requireFallback(test.candidates); r != test.expected {
+				t.Fatalf("Expected %t, got %t", test.expected, r)
+			}
+		})
+	}
+}
+
+func TestFilterExcludedCountries(t *testing.T) {
+	testfile := &filesystem.FileInfo{
+		Path:    "/test/file.tgz",
+		Size:    43000,
+		ModTime: time.Now(),
+	}
+
+	mirror := func(id int, excluded string) mirrors.Mirror {
+		m := mirrors.Mirror{
+			ID:                   id,
+			HttpURL:              fmt.Sprintf("https://m%d.mirror", id),
+			Enabled:              true,
+			HttpsUp:              true,
+			ExcludedCountryCodes: excluded,
+			FileInfo:             testfile,
+		}
+		m.Prepare()
+		return m
+	}
+
+	mlist := mirrors.Mirrors{mirror(1, "FR"), mirror(2, "IR KP"), mirror(3, "")}
+
+	tests := map[string]struct {
+		client   network.GeoIPRecord
+		accepted []int
+	}{
+		"excluded country": {network.GeoIPRecord{CountryCode: "FR", ContinentCode: "EU"}, []int{2, 3}},
+		"excluded by list": {network.GeoIPRecord{CountryCode: "KP", ContinentCode: "AS"}, []int{1, 3}},
+		"other country":    {network.GeoIPRecord{CountryCode: "DE", ContinentCode: "EU"}, []int{1, 2, 3}},
+		"unknown location": {noClientInfo, []int{1, 2, 3}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			list := make(mirrors.Mirrors, len(mlist))
+			copy(list, mlist)
+			a, _, _, _ := Filter(list, WITHTLS, testfile, test.client)
+			if len(a) != len(test.accepted) {
+				t.Fatalf("Expected %d mirrors accepted, got %d", len(test.accepted), len(a))
+			}
+			for i, id := range test.accepted {
+				if a[i].ID != id {
+					t.Fatalf("Expected mirror %d at position %d, got %d", id, i, a[i].ID)
+				}
+			}
+		})
+	}
+}
+
+func TestFilterIPVersion(t *testing.T) {
+	testfile := &filesystem.FileInfo{
+		Path:    "/test/file.tgz",
+		Size:    43000,
+		ModTime: time.Now(),
+	}
+
+	mirror := func(id int, hasIPv4, hasIPv6 bool) mirrors.Mirror {
+		return mirrors.Mirror{
+			ID:       id,
+			HttpURL:  fmt.Sprintf("https://m%d.mirror", id),
+			Enabled:  true,
+			HttpsUp:  true,
+			HasIPv4:  hasIPv4,
+			HasIPv6:  hasIPv6,
+			FileInfo: testfile,
+		}
+	}
+
+	// IPv4 only, IPv6 only, dual stack and unknown
+	mlist := mirrors.Mirrors{mirror(1, true, false), mirror(2, false, true), mirror(3, true, true), mirror(4, false, false)}
+
+	tests := map[string]struct {
+		client   network.GeoIPRecord
+		accepted []int
+		reason   string
+	}{
+		"ipv4 client":    {network.GeoIPRecord{CountryCode: "FR", IPVersion: 4}, []int{1, 3, 4}, "No IPv4 address"},
+		"ipv6 client":    {network.GeoIPRecord{CountryCode: "FR", IPVersion: 6}, []int{2, 3, 4}, "No IPv6 address"},
+		"unknown family": {noClientInfo, []int{1, 2, 3, 4}, ""},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			list := make(mirrors.Mirrors, len(mlist))
+			copy(list, mlist)
+			a, x, _, _ := Filter(list, WITHTLS, testfile, test.client)
+			if len(a) != len(test.accepted) {
+				t.Fatalf("Expected %d mirrors accepted, got %d", len(test.accepted), len(a))
+			}
+			for i, id := range test.accepted {
+				if a[i].ID != id {
+					t.Fatalf("Expected mirror %d at position %d, got %d", id, i, a[i].ID)
+				}
+			}
+			for _, m := range x {
+				if m.ExcludeReason != test.reason {
+					t.Fatalf("Invalid ExcludeReason for mirror %d: '%s'", m.ID, m.ExcludeReason)
+				}
+			}
+		})
+	}
+}
+
+func TestFilterPreferHTTPS(t *testing.T) {
+	defer SetConfiguration(GetConfig())
+
+	tests := map[string]struct {
+		preferHTTPS  bool
+		secureOption SecureOption
+		httpsUp      bool
+		absoluteURL  string
+	}{
+		"prefer https":          {true, UNDEFINED, true, "https://m1.mirror"},
+		"prefer https but down": {true, UNDEFINED, false, "http://m1.mirror"},
+		"no preference":         {false, UNDEFINED, true, "http://m1.mirror"},
+		"http requested":        {true, WITHOUTTLS, true, "http://m1.mirror"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			SetConfiguration(&Configuration{PreferHTTPS: test.preferHTTPS})
+			m := mirrors.Mirror{
+				HttpURL: "m1.mirror",
+				Enabled: true,
+				HttpUp:  true,
+				HttpsUp: test.httpsUp,
+			}
+			testFilterSingleAbsoluteURL(t, m, test.secureOption, noFileInfo, noClientInfo, "", test.absoluteURL)
+		})
+	}
+}
+
+func TestApplyHTTPOnlyPenalty(t *testing.T) {
+	tests := map[string]struct {
+		urls     []string
+		penalty  float32
+		expected []int
+	}{
+		"no penalty": {
+			urls:     []string{"https://m1.mirror", "http://m2.mirror"},
+			penalty:  0,
+			expected: []int{100, 100},
+		},
+		"half": {
+			urls:     []string{"https://m1.mirror", "http://m2.mirror", "http://m3.mirror"},
+			penalty:  0.5,
+			expected: []int{100, 50, 50},
+		},
+		"full": {
+			urls:     []string{"https://m1.mirror", "http://m2.mirror"},
+			penalty:  1,
+			expected: []int{100, 1},
+		},
+		"http only": {
+			urls:     []string{"http://m1.mirror", "http://m2.mirror"},
+			penalty:  0.5,
+			expected: []int{100, 100},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mlist := mirrors.Mirrors{}
+			weights := map[int]int{}
+			total := 0
+			for i, url := range test.urls {
+				mlist = append(mlist, mirrors.Mirror{
+					ID:          i + 1,
+					AbsoluteURL: url,
+				})
+				weights[i+1] = 100
+				total += 100
+			}
+			total = applyHTTPOnlyPenalty(mlist, weights, test.penalty, total)
+			sum := 0
+			for i, w := range test.expected {
+				if weights[i+1] != w {
+					t.Fatalf("Invalid weight for mirror %d, expected %d, got %d", i+1, w, weights[i+1])
+				}
+				sum += w
+			}
+			if total != sum {
+				t.Fatalf("Invalid total, expected %d, got %d", sum, total)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/etix/mirrorbits/filesystem"
+	"github.com/etix/mirrorbits/mirrors"
+)
+
+var (
+	// ErrInvalidIP is returned when the IP address to simulate is invalid
+	ErrInvalidIP = errors.New("invalid IP address")
+	// ErrFilteredPath is returned when the path is refused by the path filters
+	ErrFilteredPath = errors.New("path refused by the path filters")
+)
+
+// Simulate runs the geolocation and the mirror selection of a redirect for
+// the given client IP and file, like mirrorHandler does, without serving
+// the file nor recording any stats. The selection being randomized, the
+// chosen mirror may differ between two runs. The database requests are bound
+// to the given context.
+func (h *HTTP) Simulate(ctx context.Context, ip, path string) (*mirrors.Results, error) {
+	if net.ParseIP(ip) == nil {
+		return nil, ErrInvalidIP
+	}
+
+	urlPath, err := normalizePath(path)
+	if err != nil {
+		return nil, err
+	}
+	urlPath, err = filesystem.EvaluateFilePath(GetConfig().Repository, urlPath)
+	if err != nil {
+		return nil, err
+	}
+	if pathStatus(urlPath) != http.StatusOK {
+		return nil, ErrFilteredPath
+	}
+
+	// Errors are not fatal, the fallbacks are used instead
+	fileInfo, _ := h.cache.GetFileInfo(ctx, urlPath)
+
+	r := &http.Request{Method: "GET", URL: &url.URL{Path: urlPath}, Header: http.Header{}, RemoteAddr: ip}
+	rctx := NewContext(nil, r.WithContext(ctx), h.templates)
+	clientInfo := h.geoip.GetRecord(ip)
+
+	mlist, excluded, err := h.engine.Selection(rctx, h.cache, &fileInfo, clientInfo)
+
+	fallback := false
+	var netErr net.Error
+	if errors.As(err, &netErr) || len(mlist) == 0 {
+		fallback = true
+		switch GetConfig().FallbackMode {
+		case "notfound":
+			mlist = nil
+		case "proxy":
+			mlist = mirrors.Mirrors{{Name: "origin"}}
+		default:
+			mlist = append(mlist, fallbackMirrors(rctx.SecureOption(), "")...)
+			sort.Sort(mirrors.ByRank{Mirrors: mlist, ClientInfo: clientInfo})
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &mirrors.Results{
+		FileInfo:     fileInfo,
+		MirrorList:   mlist,
+		ExcludedList: excluded,
+		ClientInfo:   clientInfo,
+		IP:           ip,
+		Fallback:     fallback,
+	}, nil
+}
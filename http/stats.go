@@ -11,9 +11,12 @@ import (
 	"sync"
 	"time"
 
+	. "github.com/etix/mirrorbits/config"
 	"github.com/etix/mirrorbits/database"
 	"github.com/etix/mirrorbits/filesystem"
 	"github.com/etix/mirrorbits/mirrors"
+	"github.com/etix/mirrorbits/network"
+	"github.com/gomodule/redigo/redis"
 )
 
 /*
@@ -26,6 +29,10 @@ import (
 	STATS_FILE_[year]_[month]			= path -> value		By month
 	STATS_FILE_[year]_[month]_[day]		= path -> value		By day
 
+	The file hashes hold one field per downloaded file and are kept
+	forever, except the daily ones which expire after
+	FileStatsRetention days when configured.
+
 	List of hashes for a mirror:
 	STATS_MIRROR						= mirror -> value	All time
 	STATS_MIRROR_[year]					= mirror -> value	By year
@@ -46,6 +53,7 @@ type Stats struct {
 	stop       chan bool
 	wg         sync.WaitGroup
 	downgraded bool
+	sinks      []StatsSink
 }
 
 type countItem struct {
@@ -62,6 +70,7 @@ func NewStats(redis *database.Redis) *Stats {
 		countChan: make(chan countItem, 1000),
 		mapStats:  make(map[string]int64),
 		stop:      make(chan bool),
+		sinks:     []StatsSink{newWebhookSink()},
 	}
 	go s.processCountDownload()
 	return s
@@ -72,10 +81,13 @@ func (s *Stats) Terminate() {
 	close(s.stop)
 	log.Notice("Saving stats")
 	s.wg.Wait()
+	for _, sink := range s.sinks {
+		sink.Close()
+	}
 }
 
 // CountDownload is a lightweight method used to count a new download for a specific file and mirror
-func (s *Stats) CountDownload(m mirrors.Mirror, fileinfo filesystem.FileInfo) error {
+func (s *Stats) CountDownload(m mirrors.Mirror, fileinfo filesystem.FileInfo, clientInfo network.GeoIPRecord) error {
 	if m.Name == "" {
 		return errUnknownMirror
 	}
@@ -83,7 +95,21 @@ func (s *Stats) CountDownload(m mirrors.Mirror, fileinfo filesystem.FileInfo) er
 		return errEmptyFileError
 	}
 
-	s.countChan <- countItem{m.ID, fileinfo.Path, fileinfo.Size, time.Now().UTC()}
+	now := time.Now().UTC()
+	s.countChan <- countItem{m.ID, fileinfo.Path, fileinfo.Size, now}
+
+	// Forward the download to the external sinks as well
+	for _, sink := range s.sinks {
+		sink.Send(DownloadEvent{
+			Time:     now,
+			Path:     fileinfo.Path,
+			Size:     fileinfo.Size,
+			MirrorID: m.ID,
+			Mirror:   m.Name,
+			Country:  clientInfo.CountryCode,
+			ASNum:    clientInfo.ASNum,
+		})
+	}
 	return nil
 }
 
@@ -145,16 +171,7 @@ func (s *Stats) pushStats() {
 
 		if typ == "f" {
 			// File
-
-			fkey := fmt.Sprintf("STATS_FILE_%s", date)
-
-			for i := 0; i < 4; i++ {
-				rconn.Send("HINCRBY", fkey, object, v)
-				fkey = fkey[:strings.LastIndex(fkey, "_")]
-			}
-
-			// Increase the total too
-			rconn.Send("INCRBY", "STATS_TOTAL", v)
+			sendFileStats(rconn, date, object, v)
 		} else if typ == "m" {
 			// Mirror
 
@@ -190,3 +207,24 @@ func (s *Stats) pushStats() {
 	// Clear the map
 	s.mapStats = make(map[string]int64)
 }
+
+// sendFileStats adds the downloads of a file on the given date to the
+// pending transaction
+func sendFileStats(rconn redis.Conn, date, path string, v int64) {
+	daily := fmt.Sprintf("STATS_FILE_%s", date)
+
+	fkey := daily
+	for i := 0; i < 4; i++ {
+		rconn.Send("HINCRBY", fkey, path, v)
+		fkey = fkey[:strings.LastIndex(fkey, "_")]
+	}
+
+	// EXPIRE is a no-op on a missing key, it must follow the increments
+	// creating the key of the day
+	if days := GetConfig().FileStatsRetention; days > 0 {
+		rconn.Send("EXPIRE", daily, days*24*3600)
+	}
+
+	// Increase the total too
+	rconn.Send("INCRBY", "STATS_TOTAL", v)
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/rafaeljusto/redigomock"
+)
+
+// sendRecorder records the pipelined commands
+type sendRecorder struct {
+	*redigomock.Conn
+	sent []string
+}
+
+func (c *sendRecorder) Send(commandName string, args ...any) error {
+	c.sent = append(c.sent, fmt.Sprintf("%v", append([]any{commandName}, args...)))
+	return nil
+}
+
+func TestSendFileStats(t *testing.T) {
+	defer SetConfiguration(GetConfig())
+	SetConfiguration(&Configuration{FileStatsRetention: 2})
+
+	conn := &sendRecorder{Conn: redigomock.NewConn()}
+	sendFileStats(conn, "2025_06_01", "/file", 3)
+
+	expected := []string{
+		"[HINCRBY STATS_FILE_2025_06_01 /file 3]",
+		"[HINCRBY STATS_FILE_2025_06 /file 3]",
+		"[HINCRBY STATS_FILE_2025 /file 3]",
+		"[HINCRBY STATS_FILE /file 3]",
+		"[EXPIRE STATS_FILE_2025_06_01 172800]",
+		"[INCRBY STATS_TOTAL 3]",
+	}
+	if fmt.Sprint(conn.sent) != fmt.Sprint(expected) {
+		t.Fatalf("Expected %q, got %q", expected, conn.sent)
+	}
+
+	// The daily hash is kept forever without retention
+	SetConfiguration(&Configuration{})
+	conn = &sendRecorder{Conn: redigomock.NewConn()}
+	sendFileStats(conn, "2025_06_01", "/file", 3)
+	for _, cmd := range conn.sent {
+		if strings.HasPrefix(cmd, "[EXPIRE") {
+			t.Fatalf("Unexpected %q", cmd)
+		}
+	}
+}
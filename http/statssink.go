@@ -0,0 +1,205 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/etix/mirrorbits/core"
+	"github.com/etix/mirrorbits/metrics"
+)
+
+const (
+	// webhookQueueSize is the number of download events waiting to be sent
+	// to the webhook before the new ones are dropped
+	webhookQueueSize = 10000
+	// webhookAttempts is the number of tries to send a batch
+	webhookAttempts = 5
+	// webhookBackoff is the delay before the first retry, doubled each time
+	webhookBackoff = time.Second
+	webhookTimeout = 10 * time.Second
+)
+
+// DownloadEvent is a download forwarded to the stats sinks
+type DownloadEvent struct {
+	Time     time.Time `json:"time"`
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	MirrorID int       `json:"mirrorID"`
+	Mirror   string    `json:"mirror"`
+	Country  string    `json:"country,omitempty"`
+	ASNum    uint      `json:"asnum,omitempty"`
+}
+
+// StatsSink receives the download events counted in the stats. Send is
+// called from the request handlers and must never block.
+type StatsSink interface {
+	Send(event DownloadEvent)
+	// Close flushes the pending events
+	Close()
+}
+
+// webhookSink posts the download events as JSON arrays to the
+// StatsWebhookURL, in batches of up to StatsWebhookBatchSize events sent
+// at least every StatsWebhookInterval seconds. The events are dropped when
+// the queue is full, the redirects never wait for the webhook.
+type webhookSink struct {
+	events  chan DownloadEvent
+	stop    chan struct{}
+	wg      sync.WaitGroup
+	client  *http.Client
+	dropped int64
+}
+
+// newWebhookSink starts the sender of the webhook
+func newWebhookSink() *webhookSink {
+	w := &webhookSink{
+		events: make(chan DownloadEvent, webhookQueueSize),
+		stop:   make(chan struct{}),
+		client: &http.Client{Timeout: webhookTimeout},
+	}
+	w.wg.Add(1)
+	go w.run()
+	return w
+}
+
+// Send queues the event, or drops it if the queue is full
+func (w *webhookSink) Send(event DownloadEvent) {
+	if GetConfig().StatsWebhookURL == "" {
+		return
+	}
+	select {
+	case w.events <- event:
+	default:
+		atomic.AddInt64(&w.dropped, 1)
+		metrics.StatsEventsDropped.Inc()
+	}
+}
+
+// Close sends the pending events and stops the sender
+func (w *webhookSink) Close() {
+	close(w.stop)
+	w.wg.Wait()
+}
+
+func (w *webhookSink) run() {
+	defer w.wg.Done()
+
+	interval := webhookInterval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var batch []DownloadEvent
+	for {
+		select {
+		case <-w.stop:
+			// Drain the queue, without retrying
+		drain:
+			for {
+				select {
+				case e := <-w.events:
+					batch = append(batch, e)
+				default:
+					break drain
+				}
+			}
+			if len(batch) > 0 {
+				if err := w.post(batch); err != nil {
+					log.Warningf("Stats webhook: %d download events lost: %s", len(batch), err)
+				}
+			}
+			return
+		case e := <-w.events:
+			batch = append(batch, e)
+			if len(batch) < GetConfig().StatsWebhookBatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if d := webhookInterval(); d != interval {
+				// The configuration was reloaded
+				interval = d
+				ticker.Reset(interval)
+			}
+		}
+
+		if n := atomic.SwapInt64(&w.dropped, 0); n > 0 {
+			log.Warningf("Stats webhook: %d download events dropped, the queue is full", n)
+		}
+		if len(batch) > 0 {
+			w.sendWithRetry(batch)
+			batch = nil
+		}
+	}
+}
+
+// webhookInterval returns the maximum delay between two batches
+func webhookInterval() time.Duration {
+	if d := time.Duration(GetConfig().StatsWebhookInterval) * time.Second; d > 0 {
+		return d
+	}
+	return 5 * time.Second
+}
+
+// sendWithRetry posts the batch, retrying with an exponential backoff. The
+// batch is dropped once all the attempts failed.
+func (w *webhookSink) sendWithRetry(batch []DownloadEvent) {
+	backoff := webhookBackoff
+	for attempt := 1; ; attempt++ {
+		err := w.post(batch)
+		if err == nil {
+			return
+		}
+		if attempt == webhookAttempts {
+			log.Errorf("Stats webhook: %d download events dropped after %d attempts: %s", len(batch), attempt, err)
+			metrics.StatsEventsDropped.Add(float64(len(batch)))
+			return
+		}
+		log.Debugf("Stats webhook: %s, retrying in %s", err, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-w.stop:
+			// Make a last attempt while stopping
+			if err = w.post(batch); err != nil {
+				log.Warningf("Stats webhook: %d download events lost: %s", len(batch), err)
+			}
+			return
+		}
+		backoff *= 2
+	}
+}
+
+// post sends the batch to the webhook
+func (w *webhookSink) post(batch []DownloadEvent) error {
+	url := GetConfig().StatsWebhookURL
+	if url == "" {
+		return nil
+	}
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Mirrorbits/"+core.VERSION)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("HTTP error: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/etix/mirrorbits/config"
+)
+
+func TestWebhookSink(t *testing.T) {
+	batches := make(chan []DownloadEvent, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []DownloadEvent
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			t.Errorf("Invalid batch: %s", err)
+		}
+		batches <- batch
+	}))
+	defer server.Close()
+
+	defer SetConfiguration(GetConfig())
+	SetConfiguration(&Configuration{
+		StatsWebhookURL:       server.URL,
+		StatsWebhookBatchSize: 2,
+		StatsWebhookInterval:  60,
+	})
+
+	w := newWebhookSink()
+	for _, path := range []string{"/a", "/b", "/c"} {
+		w.Send(DownloadEvent{Path: path, Mirror: "m1"})
+	}
+
+	// A full batch is sent right away
+	select {
+	case batch := <-batches:
+		if len(batch) != 2 || batch[0].Path != "/a" || batch[1].Path != "/b" {
+			t.Fatalf("Unexpected batch %v", batch)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Batch not sent")
+	}
+
+	// The rest is sent when closing
+	w.Close()
+	select {
+	case batch := <-batches:
+		if len(batch) != 1 || batch[0].Path != "/c" {
+			t.Fatalf("Unexpected batch %v", batch)
+		}
+	default:
+		t.Fatalf("Pending events not sent")
+	}
+}
+
+func TestWebhookSinkDisabled(t *testing.T) {
+	defer SetConfiguration(GetConfig())
+	SetConfiguration(&Configuration{})
+
+	w := newWebhookSink()
+	w.Send(DownloadEvent{Path: "/a"})
+	if len(w.events) != 0 {
+		t.Fatalf("Expected no event queued without a webhook")
+	}
+	w.Close()
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"hash/fnv"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	. "github.com/etix/mirrorbits/config"
+)
+
+// stickyKey returns the key identifying the client for the sticky
+// selection: its address, or the value of the StickySelectionCookie, and
+// the current period of StickySelectionTTL seconds. The periods are shifted
+// by client so that they don't all move to other mirrors at the same time.
+func stickyKey(r *http.Request, now time.Time) string {
+	client := remoteIP(r)
+	if name := GetConfig().StickySelectionCookie; name != "" {
+		if cookie, err := r.Cookie(name); err == nil && cookie.Value != "" {
+			client = cookie.Value
+		}
+	}
+	ttl := int64(GetConfig().StickySelectionTTL)
+	if ttl <= 0 {
+		ttl = 3600
+	}
+	offset := int64(stickyHash(client) % uint64(ttl))
+	return client + "/" + strconv.FormatInt((now.Unix()+offset)/ttl, 10)
+}
+
+// stickyOrder returns the IDs of the weighted mirrors ordered with the
+// weighted rendezvous hashing of the key: each client gets its own order,
+// the first mirror being picked in proportion to the weights as with
+// pickWeighted. Removing a mirror only moves the clients it was the first
+// choice of.
+func stickyOrder(weights map[int]int, key string) []int {
+	ids := make([]int, 0, len(weights))
+	scores := make(map[int]float64, len(weights))
+	for id, weight := range weights {
+		ids = append(ids, id)
+		scores[id] = stickyScore(key, id, weight)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// stickyScore returns the rendezvous score of the mirror for the key
+func stickyScore(key string, id int, weight int) float64 {
+	if weight <= 0 {
+		return 0
+	}
+	// Uniform in (0, 1)
+	u := (float64(stickyHash(key+"#"+strconv.Itoa(id))>>11) + 0.5) / (1 << 53)
+	return -float64(weight) / math.Log(u)
+}
+
+// stickyHash is FNV-1a followed by the finalizer of SplitMix64, so that the
+// close keys are spread evenly
+func stickyHash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	. "github.com/etix/mirrorbits/config"
+)
+
+func TestStickyKey(t *testing.T) {
+	defer SetConfiguration(GetConfig())
+	SetConfiguration(&Configuration{
+		StickySelectionTTL:    3600,
+		StickySelectionCookie: "session",
+	})
+
+	now := time.Unix(1700000000, 0)
+	r := &http.Request{RemoteAddr: "192.0.2.1:1234", Header: http.Header{}}
+
+	key := stickyKey(r, now)
+	if stickyKey(r, now.Add(time.Second)) != key && stickyKey(r, now.Add(-time.Second)) != key {
+		t.Fatalf("Expected the key to last for the TTL")
+	}
+	if stickyKey(r, now.Add(time.Hour)) == key {
+		t.Fatalf("Expected the key to change after the TTL")
+	}
+
+	r.Header.Set("Cookie", "session=abcd")
+	if k := stickyKey(r, now); k == key {
+		t.Fatalf("Expected the cookie to identify the client, got %s", k)
+	}
+}
+
+func TestStickyOrder(t *testing.T) {
+	weights := map[int]int{1: 100, 2: 100, 3: 200, 4: 400}
+
+	// The order is the same for a given client
+	order := stickyOrder(weights, "192.0.2.1/1")
+	for i := 0; i < 10; i++ {
+		if o := stickyOrder(weights, "192.0.2.1/1"); fmt.Sprint(o) != fmt.Sprint(order) {
+			t.Fatalf("Expected the order %v, got %v", order, o)
+		}
+	}
+	if len(order) != len(weights) {
+		t.Fatalf("Expected %d mirrors, got %d", len(weights), len(order))
+	}
+
+	// The clients are spread according to the weights, and removing a
+	// mirror only moves its own clients
+	const clients = 8000
+	first := make(map[string]int, clients)
+	counts := make(map[int]int)
+	for i := 0; i < clients; i++ {
+		key := fmt.Sprintf("client%d", i)
+		first[key] = stickyOrder(weights, key)[0]
+		counts[first[key]]++
+	}
+	for id, weight := range weights {
+		expected := clients * weight / 800
+		if counts[id] < expected*8/10 || counts[id] > expected*12/10 {
+			t.Fatalf("Expected about %d clients on mirror %d, got %d", expected, id, counts[id])
+		}
+	}
+
+	delete(weights, 3)
+	for key, id := range first {
+		if next := stickyOrder(weights, key)[0]; id != 3 && next != id {
+			t.Fatalf("Expected %s to stay on mirror %d, got %d", key, id, next)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/etix/mirrorbits/filesystem"
+	"github.com/etix/mirrorbits/mirrors"
+	"github.com/etix/mirrorbits/network"
+	"github.com/etix/mirrorbits/utils"
+)
+
+// selectionStrategy orders the mirrors able to serve a file, as chosen by
+// the SelectionStrategy of the configuration
+type selectionStrategy interface {
+	// Order must return the given mirrors in the order they should be
+	// used by the client, it may drop some of them.
+	Order(*Context, mirrors.Mirrors, *filesystem.FileInfo, network.GeoIPRecord) mirrors.Mirrors
+}
+
+// newStrategies returns the selection strategies indexed by their name
+func newStrategies(connections *connectionTracker, popularity *popularityTracker) map[string]selectionStrategy {
+	return map[string]selectionStrategy{
+		"weighted":    weightedStrategy{popularity: popularity},
+		"nearest":     nearestStrategy{},
+		"roundrobin":  &roundRobinStrategy{},
+		"leastloaded": leastLoadedStrategy{connections: connections},
+	}
+}
+
+// nearestStrategy orders the mirrors by rank: AS number, country, continent
+// then distance. The order is random if the client can't be located.
+type nearestStrategy struct{}
+
+// Order returns the mirrors sorted by rank
+func (s nearestStrategy) Order(ctx *Context, mlist mirrors.Mirrors, fileInfo *filesystem.FileInfo, clientInfo network.GeoIPRecord) mirrors.Mirrors {
+	sort.Sort(mirrors.ByRank{Mirrors: mlist, ClientInfo: clientInfo})
+	return limitMirrors(ctx, mlist)
+}
+
+// roundRobinStrategy starts each selection with the mirror following the
+// first one of the previous selection, regardless of the client location
+type roundRobinStrategy struct {
+	next uint64
+}
+
+// Order returns the mirrors sorted by ID, rotated by one position more than
+// in the previous selection
+func (s *roundRobinStrategy) Order(ctx *Context, mlist mirrors.Mirrors, fileInfo *filesystem.FileInfo, clientInfo network.GeoIPRecord) mirrors.Mirrors {
+	if len(mlist) == 0 {
+		return mlist
+	}
+	sort.Slice(mlist, func(i, j int) bool {
+		return mlist[i].ID < mlist[j].ID
+	})
+	offset := int((atomic.AddUint64(&s.next, 1) - 1) % uint64(len(mlist)))
+	ordered := append(append(make(mirrors.Mirrors, 0, len(mlist)), mlist[offset:]...), mlist[:offset]...)
+	return limitMirrors(ctx, ordered)
+}
+
+// leastLoadedStrategy orders the mirrors by their estimated number of
+// downloads in progress, relative to their MaxConnections if set
+type leastLoadedStrategy struct {
+	connections *connectionTracker
+}
+
+// Order returns the least loaded mirrors first, the mirrors equally loaded
+// being sorted by rank
+func (s leastLoadedStrategy) Order(ctx *Context, mlist mirrors.Mirrors, fileInfo *filesystem.FileInfo, clientInfo network.GeoIPRecord) mirrors.Mirrors {
+	now := time.Now()
+	load := make(map[int]float64, len(mlist))
+	for _, m := range mlist {
+		l := s.connections.estimate(m.ID, now)
+		if m.MaxConnections > 0 {
+			l /= float64(m.MaxConnections)
+		}
+		load[m.ID] = l
+	}
+	sort.Sort(mirrors.ByRank{Mirrors: mlist, ClientInfo: clientInfo})
+	sort.SliceStable(mlist, func(i, j int) bool {
+		return load[mlist[i].ID] < load[mlist[j].ID]
+	})
+	return limitMirrors(ctx, mlist)
+}
+
+// limitMirrors reduces the number of mirrors to the handful needed by the
+// redirects, the mirrorlist and the metalink get the full list so the
+// client can fail over across all of them
+func limitMirrors(ctx *Context, mlist mirrors.Mirrors) mirrors.Mirrors {
+	if ctx.IsMirrorlist() || ctx.IsMetalink() || ctx.IsMetalink3() || ctx.IsTorrent() || ctx.IsZsync() {
+		return mlist
+	}
+	return mlist[:utils.Min(5, len(mlist))]
+}
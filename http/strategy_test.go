@@ -0,0 +1,170 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/etix/mirrorbits/filesystem"
+	"github.com/etix/mirrorbits/mirrors"
+	"github.com/etix/mirrorbits/network"
+)
+
+// The candidates of the strategy tests, the client being in France
+var strategyClient = network.GeoIPRecord{ContinentCode: "EU", CountryCode: "FR", ASNum: 4444}
+
+func strategyCandidates() mirrors.Mirrors {
+	return mirrors.Mirrors{
+		{ID: 1, Name: "M1", ContinentCode: "EU", CountryFields: []string{"FR"}, Asnum: 100, Distance: 500},
+		{ID: 2, Name: "M2", ContinentCode: "EU", CountryFields: []string{"DE"}, Asnum: 200, Distance: 200},
+		{ID: 3, Name: "M3", ContinentCode: "NA", CountryFields: []string{"US"}, Asnum: 300, Distance: 5000, MaxConnections: 10},
+		{ID: 4, Name: "M4", ContinentCode: "EU", CountryFields: []string{"FR"}, Asnum: 400, Distance: 100},
+	}
+}
+
+func strategyContext(url string) *Context {
+	return NewContext(httptest.NewRecorder(), httptest.NewRequest("GET", url, nil), Templates{})
+}
+
+func mirrorIDs(mlist mirrors.Mirrors) []int {
+	ids := make([]int, len(mlist))
+	for i, m := range mlist {
+		ids[i] = m.ID
+	}
+	return ids
+}
+
+func checkOrder(t *testing.T, mlist mirrors.Mirrors, expected []int) {
+	t.Helper()
+	ids := mirrorIDs(mlist)
+	if len(ids) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, ids)
+	}
+	for i := range ids {
+		if ids[i] != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected, ids)
+		}
+	}
+}
+
+func TestWeightedStrategy(t *testing.T) {
+	SetConfiguration(&Configuration{
+		WeightDistributionRange: 1.5,
+	})
+	fileInfo := &filesystem.FileInfo{Path: "/file.iso"}
+
+	// The mirrorlist keeps the mirrors sorted by score, with their weight
+	mlist := weightedStrategy{}.Order(strategyContext("/file.iso?mirrorlist"), strategyCandidates(), fileInfo, strategyClient)
+	checkOrder(t, mlist, []int{4, 1, 2, 3})
+	var total float32
+	for _, m := range mlist {
+		total += m.Weight
+	}
+	if mlist[0].Weight <= mlist[1].Weight || total < 99 || total > 101 {
+		t.Fatalf("Unexpected weights: %v", mlist)
+	}
+
+	// The redirects only get the mirrors of the weight distribution
+	for i := 0; i < 10; i++ {
+		mlist = weightedStrategy{}.Order(strategyContext("/file.iso"), strategyCandidates(), fileInfo, strategyClient)
+		if len(mlist) == 0 || len(mlist) > 3 {
+			t.Fatalf("Unexpected selection: %v", mirrorIDs(mlist))
+		}
+		for _, m := range mlist {
+			if m.ID == 3 {
+				t.Fatalf("M3 is out of the weight distribution: %v", mirrorIDs(mlist))
+			}
+		}
+	}
+}
+
+func TestNearestStrategy(t *testing.T) {
+	mlist := nearestStrategy{}.Order(strategyContext("/file.iso"), strategyCandidates(), nil, strategyClient)
+	checkOrder(t, mlist, []int{4, 1, 2, 3})
+
+	// The AS number of the client comes first
+	client := strategyClient
+	client.ASNum = 300
+	mlist = nearestStrategy{}.Order(strategyContext("/file.iso"), strategyCandidates(), nil, client)
+	checkOrder(t, mlist, []int{3, 4, 1, 2})
+}
+
+func TestRoundRobinStrategy(t *testing.T) {
+	s := &roundRobinStrategy{}
+	expected := [][]int{
+		{1, 2, 3, 4},
+		{2, 3, 4, 1},
+		{3, 4, 1, 2},
+		{4, 1, 2, 3},
+		{1, 2, 3, 4},
+	}
+	for _, e := range expected {
+		mlist := s.Order(strategyContext("/file.iso"), strategyCandidates(), nil, strategyClient)
+		checkOrder(t, mlist, e)
+	}
+
+	if mlist := s.Order(strategyContext("/file.iso"), nil, nil, strategyClient); len(mlist) != 0 {
+		t.Fatalf("Expected no mirrors, got %v", mirrorIDs(mlist))
+	}
+}
+
+func TestLeastLoadedStrategy(t *testing.T) {
+	SetConfiguration(&Configuration{
+		ConnectionDuration: 3600,
+	})
+	connections := newConnectionTracker()
+	s := leastLoadedStrategy{connections: connections}
+
+	// Without any download in progress, the mirrors are sorted by rank
+	mlist := s.Order(strategyContext("/file.iso"), strategyCandidates(), nil, strategyClient)
+	checkOrder(t, mlist, []int{4, 1, 2, 3})
+
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		connections.add(4, now)
+	}
+	connections.add(1, now)
+	for i := 0; i < 5; i++ {
+		// Half of the MaxConnections of M3
+		connections.add(3, now)
+	}
+	mlist = s.Order(strategyContext("/file.iso"), strategyCandidates(), nil, strategyClient)
+	checkOrder(t, mlist, []int{2, 3, 1, 4})
+}
+
+func TestLimitMirrors(t *testing.T) {
+	mlist := append(strategyCandidates(), strategyCandidates()...)
+
+	if got := limitMirrors(strategyContext("/file.iso"), mlist); len(got) != 5 {
+		t.Fatalf("Expected 5 mirrors, got %d", len(got))
+	}
+	if got := limitMirrors(strategyContext("/file.iso?mirrorlist"), mlist); len(got) != len(mlist) {
+		t.Fatalf("Expected %d mirrors, got %d", len(mlist), len(got))
+	}
+}
+
+func TestSelectionStrategy(t *testing.T) {
+	e := DefaultEngine{strategies: newStrategies(nil, nil)}
+
+	tests := map[string]string{
+		"":            "weighted",
+		"weighted":    "weighted",
+		"nearest":     "nearest",
+		"roundrobin":  "roundrobin",
+		"leastloaded": "leastloaded",
+	}
+	for name, expected := range tests {
+		SetConfiguration(&Configuration{SelectionStrategy: name})
+		got := e.strategy()
+		if _, ok := got.(weightedStrategy); ok && expected == "weighted" {
+			continue
+		}
+		if got != e.strategies[expected] {
+			t.Fatalf("%q: expected the %s strategy, got %T", name, expected, got)
+		}
+	}
+}
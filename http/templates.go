@@ -0,0 +1,109 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"html/template"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/etix/mirrorbits/templates"
+	"github.com/etix/mirrorbits/utils"
+)
+
+// templateFuncs returns the functions available to the templates
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"add":       utils.Add,
+		"sizeof":    utils.ReadableSize,
+		"version":   utils.Version,
+		"hostname":  utils.Hostname,
+		"concaturl": utils.ConcatURL,
+		"dateutc":   utils.FormattedDateUTC,
+		"iszero":    utils.IsZero,
+		"T":         translateFunc(nil, ""),
+	}
+}
+
+// customFile returns the content of the given file of the TemplatesPath, ok
+// being false if there is none
+func customFile(name string) (content []byte, file string, ok bool) {
+	dir := GetConfig().TemplatesPath
+	if dir == "" {
+		return nil, "", false
+	}
+	file = filepath.Join(dir, filepath.FromSlash(name))
+	content, err := os.ReadFile(file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Errorf("Cannot read %s, using the embedded one: %s", file, err)
+		}
+		return nil, file, false
+	}
+	return content, file, true
+}
+
+// parseTemplate adds the given template file to t, as found in the
+// TemplatesPath or else the embedded one. A custom template failing to parse
+// is replaced by the embedded one.
+func parseTemplate(t *template.Template, name string) error {
+	if content, file, ok := customFile(name); ok {
+		// Check the template alone first, a failed parse would leave t
+		// in an undefined state
+		_, err := template.New(name).Funcs(templateFuncs()).Parse(string(content))
+		if err == nil {
+			_, err = t.New(name).Parse(string(content))
+			return err
+		}
+		log.Errorf("Invalid template %s, using the embedded one: %s", file, err)
+	}
+
+	content, err := templates.FS.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	_, err = t.New(name).Parse(string(content))
+	return err
+}
+
+// loadCatalog returns the embedded translations, each locale being replaced
+// by its message file in the TemplatesPath if any. The invalid message files
+// are reported and ignored.
+func loadCatalog() Catalog {
+	embedded, _ := fs.Sub(templates.FS, "locales")
+	catalog, err := loadCatalogFS(embedded)
+	if err != nil {
+		log.Errorf("could not load the embedded translations: %s", err.Error())
+		catalog = make(Catalog)
+	}
+
+	dir := localesDir()
+	if dir == "" {
+		return catalog
+	}
+	custom := os.DirFS(dir)
+	files, _ := fs.Glob(custom, "*.yaml")
+	for _, name := range files {
+		locale, messages, err := loadLocale(custom, name)
+		if err != nil {
+			log.Errorf("could not load the translations of %s: %s", path.Join(dir, name), err)
+			continue
+		}
+		catalog[locale] = messages
+	}
+	return catalog
+}
+
+// prepareLocales translates the templates in the locales of the catalog.
+// On failure the requests translate a copy of their template instead.
+func (t *Templates) prepareLocales() {
+	localized, err := localizeTemplates(t.catalog, t.mirrorlist, t.mirrorstats)
+	if err != nil {
+		log.Errorf("could not translate the templates: %s", err.Error())
+	}
+	t.localized = localized
+}
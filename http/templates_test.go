@@ -0,0 +1,99 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/etix/mirrorbits/config"
+)
+
+func TestLoadTemplates(t *testing.T) {
+	dir := t.TempDir()
+	defer SetConfiguration(GetConfig())
+
+	h := &HTTP{}
+	title := func(t *testing.T) string {
+		tmpl, err := h.LoadTemplates("mirrorstats")
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if tmpl.Lookup("base") == nil {
+			t.Fatalf("Expected the base template to be defined")
+		}
+		title := tmpl.Lookup("title")
+		if title == nil {
+			t.Fatalf("Expected the title template to be defined")
+		}
+		return title.Tree.Root.String()
+	}
+
+	// Embedded templates
+	SetConfiguration(&Configuration{})
+	if s := title(t); s != "Mirrorstats" {
+		t.Fatalf("Expected the embedded title, got %q", s)
+	}
+
+	// Missing files use the embedded templates
+	SetConfiguration(&Configuration{TemplatesPath: dir})
+	if s := title(t); s != "Mirrorstats" {
+		t.Fatalf("Expected the embedded title, got %q", s)
+	}
+
+	// Custom template
+	custom := filepath.Join(dir, "mirrorstats.html")
+	if err := os.WriteFile(custom, []byte(`{{define "title"}}Our mirrors{{end}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if s := title(t); s != "Our mirrors" {
+		t.Fatalf("Expected the custom title, got %q", s)
+	}
+
+	// A broken template falls back to the embedded one
+	if err := os.WriteFile(custom, []byte(`{{define "title"}}Our mirrors`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if s := title(t); s != "Mirrorstats" {
+		t.Fatalf("Expected the embedded title, got %q", s)
+	}
+}
+
+func TestLoadCatalogOverride(t *testing.T) {
+	dir := t.TempDir()
+	defer SetConfiguration(GetConfig())
+
+	SetConfiguration(&Configuration{})
+	catalog := loadCatalog()
+	if catalog["fr"]["Mirrors"] != "Miroirs" {
+		t.Fatalf("Expected the embedded French messages, got %v", catalog["fr"])
+	}
+
+	if err := os.Mkdir(filepath.Join(dir, "locales"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"fr.yaml": "Mirrors: Nos miroirs\n",
+		"de.yaml": "Mirrors: Spiegelserver\n",
+		"it.yaml": "- invalid",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, "locales", name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	SetConfiguration(&Configuration{TemplatesPath: dir})
+	catalog = loadCatalog()
+	if catalog["fr"]["Mirrors"] != "Nos miroirs" {
+		t.Fatalf("Expected the custom French messages, got %v", catalog["fr"])
+	}
+	if catalog["de"]["Mirrors"] != "Spiegelserver" {
+		t.Fatalf("Expected the German messages, got %v", catalog["de"])
+	}
+	if _, ok := catalog["it"]; ok {
+		t.Fatalf("Expected the invalid message file to be ignored")
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"sync"
+
+	. "github.com/etix/mirrorbits/config"
+	"gopkg.in/tylerb/graceful.v1"
+)
+
+var (
+	// ErrNoCertificate is returned when no TLS certificate is loaded
+	ErrNoCertificate = errors.New("no TLS certificate loaded")
+)
+
+// certificateLoader holds the TLS certificate of the HTTPS server and
+// allows it to be replaced while the server is running
+type certificateLoader struct {
+	sync.RWMutex
+	cert *tls.Certificate
+}
+
+// load reads the configured TLSCertFile and TLSKeyFile, the current
+// certificate is kept if they can't be loaded
+func (c *certificateLoader) load() error {
+	cert, err := tls.LoadX509KeyPair(GetConfig().TLSCertFile, GetConfig().TLSKeyFile)
+	if err != nil {
+		return err
+	}
+	c.Lock()
+	c.cert = &cert
+	c.Unlock()
+	return nil
+}
+
+// getCertificate implements tls.Config.GetCertificate
+func (c *certificateLoader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.RLock()
+	defer c.RUnlock()
+	if c.cert == nil {
+		return nil, ErrNoCertificate
+	}
+	return c.cert, nil
+}
+
+// tlsConfig returns the configuration of the HTTPS server
+func (c *certificateLoader) tlsConfig() *tls.Config {
+	protos := []string{"http/1.1"}
+	if GetConfig().EnableHTTP2 {
+		protos = append([]string{"h2"}, protos...)
+	}
+	return &tls.Config{
+		GetCertificate: c.getCertificate,
+		MinVersion:     tls.VersionTLS12,
+		NextProtos:     protos,
+	}
+}
+
+// newTLSServer returns the HTTPS server, HTTP/2 is negotiated with the
+// clients supporting it unless EnableHTTP2 is disabled
+func (h *HTTP) newTLSServer() *graceful.Server {
+	srv := h.newServer()
+	srv.TLSConfig = h.certificate.tlsConfig()
+	if !GetConfig().EnableHTTP2 {
+		// A non-nil map prevents net/http from configuring HTTP/2
+		srv.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+		return srv
+	}
+	// graceful only closes the idle connections, ask the HTTP/2 clients
+	// to stop opening new streams (GOAWAY) so they go idle as well
+	srv.ShutdownInitiated = func() {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), srv.Timeout)
+			defer cancel()
+			srv.Server.Shutdown(ctx)
+		}()
+	}
+	return srv
+}
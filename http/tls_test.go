@@ -0,0 +1,162 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/etix/mirrorbits/config"
+)
+
+func writeCertificate(t *testing.T, dir, name string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := filepath.Join(dir, name+".pem")
+	keyFile := filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestCertificateLoader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCertificate(t, dir, "first")
+	newCertFile, newKeyFile := writeCertificate(t, dir, "second")
+
+	defer SetConfiguration(GetConfig())
+	SetConfiguration(&Configuration{
+		TLSCertFile: certFile,
+		TLSKeyFile:  keyFile,
+	})
+
+	var c certificateLoader
+	if _, err := c.getCertificate(nil); err != ErrNoCertificate {
+		t.Fatalf("Expected ErrNoCertificate, got %v", err)
+	}
+
+	if err := c.load(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	first, err := c.getCertificate(nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// A failed reload keeps the current certificate
+	SetConfiguration(&Configuration{
+		TLSCertFile: newCertFile,
+		TLSKeyFile:  keyFile,
+	})
+	if err := c.load(); err == nil {
+		t.Fatalf("Expected an error with a mismatching key")
+	}
+	if cert, _ := c.getCertificate(nil); cert != first {
+		t.Fatalf("The certificate should not have been replaced")
+	}
+
+	// The certificate is rotated on reload
+	SetConfiguration(&Configuration{
+		TLSCertFile: newCertFile,
+		TLSKeyFile:  newKeyFile,
+	})
+	if err := c.load(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if cert, _ := c.getCertificate(nil); cert == first {
+		t.Fatalf("The certificate should have been replaced")
+	}
+}
+
+func TestTLSServer_HTTP2(t *testing.T) {
+	certFile, keyFile := writeCertificate(t, t.TempDir(), "localhost")
+
+	defer SetConfiguration(GetConfig())
+
+	tests := map[string]struct {
+		enable bool
+		proto  int
+	}{
+		"enabled":  {true, 2},
+		"disabled": {false, 1},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			SetConfiguration(&Configuration{
+				TLSCertFile: certFile,
+				TLSKeyFile:  keyFile,
+				EnableHTTP2: test.enable,
+			})
+
+			h := &HTTP{}
+			if err := h.certificate.load(); err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			srv := h.newTLSServer()
+
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			go srv.Serve(tls.NewListener(l, srv.TLSConfig))
+
+			client := &http.Client{
+				Transport: &http.Transport{
+					TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+					ForceAttemptHTTP2: true,
+				},
+			}
+			resp, err := client.Get("https://" + l.Addr().String() + "/")
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			resp.Body.Close()
+			if resp.ProtoMajor != test.proto {
+				t.Fatalf("Expected HTTP/%d, got %s", test.proto, resp.Proto)
+			}
+
+			// The server stops with the client connection still open
+			srv.Stop(time.Second)
+			select {
+			case <-srv.StopChan():
+			case <-time.After(5 * time.Second):
+				t.Fatalf("The server didn't stop")
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/etix/mirrorbits/core"
+	"github.com/etix/mirrorbits/database"
+	"github.com/etix/mirrorbits/mirrors"
+	"github.com/gomodule/redigo/redis"
+)
+
+// TorrentRenderer is used to render a BitTorrent metainfo file with the
+// selected mirrors as web seeds (BEP 19)
+type TorrentRenderer struct {
+	pieceLength int
+	pieces      []byte
+}
+
+// Type returns the type of renderer
+func (w *TorrentRenderer) Type() string {
+	return "TORRENT"
+}
+
+// Write is used to write the result to the ResponseWriter
+func (w *TorrentRenderer) Write(ctx *Context, results *mirrors.Results) (statusCode int, err error) {
+	path := strings.TrimPrefix(results.MirrorPath(), "/")
+
+	var seeds []any
+	for _, m := range torrentWebSeeds(results.MirrorList) {
+		seeds = append(seeds, m.AbsoluteURL+path)
+	}
+
+	var buf bytes.Buffer
+	err = bencode(&buf, map[string]any{
+		"created by":    "mirrorbits/" + core.VERSION,
+		"creation date": time.Now().Unix(),
+		"url-list":      seeds,
+		"info": map[string]any{
+			"length":       results.FileInfo.Size,
+			"name":         filepath.Base(results.FileInfo.Path),
+			"piece length": w.pieceLength,
+			"pieces":       w.pieces,
+		},
+	})
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	ctx.ResponseWriter().Header().Set("Content-Type", "application/x-bittorrent")
+	ctx.ResponseWriter().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(results.FileInfo.Path)+".torrent"))
+	ctx.ResponseWriter().Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	buf.WriteTo(ctx.ResponseWriter())
+	return http.StatusOK, nil
+}
+
+// torrentWebSeeds returns the mirrors to list as web seeds in a torrent
+func torrentWebSeeds(mlist mirrors.Mirrors) mirrors.Mirrors {
+	if max := GetConfig().TorrentWebSeeds; max > 0 && len(mlist) > max {
+		return mlist[:max]
+	}
+	return mlist
+}
+
+// torrentPieces returns the piece length and the piece hashes computed
+// while scanning the local repository for the given file. The pieces are
+// empty if the file has not been hashed (yet).
+func torrentPieces(ctx context.Context, r *database.Redis, path string) (int, []byte, error) {
+	conn := r.GetContext(ctx)
+	defer conn.Close()
+
+	values, err := redis.Values(conn.Do("HMGET", fmt.Sprintf("FILE_%s", path), "pieceLength", "pieces"))
+	if err != nil {
+		return 0, nil, err
+	}
+	pieceLength, _ := redis.Int(values[0], nil)
+	pieces, _ := redis.Bytes(values[1], nil)
+	if pieceLength <= 0 || len(pieces) == 0 || len(pieces)%20 != 0 {
+		return 0, nil, nil
+	}
+	return pieceLength, pieces, nil
+}
+
+// bencode writes the bencoded form of v, dictionaries are written with
+// their keys sorted as required by the BitTorrent specification
+func bencode(buf *bytes.Buffer, v any) error {
+	switch v := v.(type) {
+	case int:
+		fmt.Fprintf(buf, "i%de", v)
+	case int64:
+		fmt.Fprintf(buf, "i%de", v)
+	case string:
+		fmt.Fprintf(buf, "%d:%s", len(v), v)
+	case []byte:
+		fmt.Fprintf(buf, "%d:", len(v))
+		buf.Write(v)
+	case []any:
+		buf.WriteByte('l')
+		for _, e := range v {
+			if err := bencode(buf, e); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('e')
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		buf.WriteByte('d')
+		for _, k := range keys {
+			bencode(buf, k)
+			if err := bencode(buf, v[k]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('e')
+	default:
+		return fmt.Errorf("bencode: unsupported type %T", v)
+	}
+	return nil
+}
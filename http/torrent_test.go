@@ -0,0 +1,138 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"testing"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/etix/mirrorbits/core"
+	"github.com/etix/mirrorbits/filesystem"
+	"github.com/etix/mirrorbits/mirrors"
+	. "github.com/etix/mirrorbits/testing"
+)
+
+func TestBencode(t *testing.T) {
+	tests := map[string]struct {
+		value    any
+		expected string
+	}{
+		"int":        {42, "i42e"},
+		"negative":   {int64(-3), "i-3e"},
+		"string":     {"spam", "4:spam"},
+		"bytes":      {[]byte{0, 1}, "2:\x00\x01"},
+		"empty list": {[]any(nil), "le"},
+		"list":       {[]any{"spam", 42}, "l4:spami42ee"},
+		"dict":       {map[string]any{"spam": "eggs", "cow": "moo"}, "d3:cow3:moo4:spam4:eggse"},
+		"nested":     {map[string]any{"info": map[string]any{"length": 1}}, "d4:infod6:lengthi1eee"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := bencode(&buf, test.value); err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if buf.String() != test.expected {
+				t.Fatalf("Expected %q, got %q", test.expected, buf.String())
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := bencode(&buf, 1.5); err == nil {
+		t.Fatalf("Expected an error for an unsupported type")
+	}
+}
+
+func TestTorrentRenderer(t *testing.T) {
+	defer SetConfiguration(GetConfig())
+	SetConfiguration(&Configuration{TorrentWebSeeds: 2})
+
+	pieces := bytes.Repeat([]byte{0xab}, 40)
+	results := &mirrors.Results{
+		FileInfo: filesystem.FileInfo{Path: "/pub/dir/file.iso", Size: 20000},
+		Prefix:   "/pub",
+		MirrorList: mirrors.Mirrors{
+			{ID: 1, AbsoluteURL: "https://m1.mirror/"},
+			{ID: 2, AbsoluteURL: "http://m2.mirror/pub/"},
+			{ID: 3, AbsoluteURL: "https://m3.mirror/"},
+		},
+	}
+
+	r := httptest.NewRequest("GET", "/pub/dir/file.iso?torrent", nil)
+	w := httptest.NewRecorder()
+	ctx := NewContext(w, r, Templates{})
+
+	status, err := (&TorrentRenderer{pieceLength: 16384, pieces: pieces}).Write(ctx, results)
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("Unexpected result: %d %v", status, err)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-bittorrent" {
+		t.Fatalf("Invalid content type %s", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename="file.iso.torrent"` {
+		t.Fatalf("Invalid content disposition %s", cd)
+	}
+	if cl := w.Header().Get("Content-Length"); cl != strconv.Itoa(w.Body.Len()) {
+		t.Fatalf("Invalid content length %s for %d bytes", cl, w.Body.Len())
+	}
+
+	// The creation date is the only varying field
+	body := regexp.MustCompile(`13:creation datei[0-9]+e`).ReplaceAllString(w.Body.String(), "13:creation datei0e")
+	expected := "d" +
+		"10:created by" + fmt.Sprintf("%d:mirrorbits/%s", len("mirrorbits/"+core.VERSION), core.VERSION) +
+		"13:creation datei0e" +
+		"4:infod" +
+		"6:lengthi20000e" +
+		"4:name8:file.iso" +
+		"12:piece lengthi16384e" +
+		"6:pieces40:" + string(pieces) +
+		"e" +
+		"8:url-listl" +
+		"30:https://m1.mirror/dir/file.iso" +
+		"33:http://m2.mirror/pub/dir/file.iso" +
+		"e" +
+		"e"
+	if body != expected {
+		t.Fatalf("Expected %q, got %q", expected, body)
+	}
+}
+
+func TestTorrentPieces(t *testing.T) {
+	pieces := bytes.Repeat([]byte{0xab}, 40)
+
+	tests := map[string]struct {
+		reply       []any
+		pieceLength int
+		pieces      []byte
+	}{
+		"hashed":     {[]any{[]byte("16384"), pieces}, 16384, pieces},
+		"not hashed": {[]any{nil, nil}, 0, nil},
+		"truncated":  {[]any{[]byte("16384"), pieces[:30]}, 0, nil},
+		"no length":  {[]any{[]byte("0"), pieces}, 0, nil},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mock, conn := PrepareRedisTest()
+			mock.Command("HMGET", "FILE_/file.iso", "pieceLength", "pieces").Expect(test.reply)
+
+			pieceLength, got, err := torrentPieces(context.Background(), conn, "/file.iso")
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if pieceLength != test.pieceLength || !bytes.Equal(got, test.pieces) {
+				t.Fatalf("Expected %d %x, got %d %x", test.pieceLength, test.pieces, pieceLength, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/etix/mirrorbits/filesystem"
+	"github.com/etix/mirrorbits/mirrors"
+)
+
+// virtualHost returns the virtual host the request is made to, or nil if no
+// VirtualHosts are configured. The requests made to an unknown host are
+// served by the DefaultVirtualHost, false is returned if there is none.
+func virtualHost(r *http.Request) (*VirtualHost, bool) {
+	vhosts := GetConfig().VirtualHosts
+	if len(vhosts) == 0 {
+		return nil, true
+	}
+
+	host := requestHost(r.Host)
+	for _, name := range []string{host, GetConfig().DefaultVirtualHost} {
+		if name == "" {
+			continue
+		}
+		for i := range vhosts {
+			if vhosts[i].Host == name {
+				return &vhosts[i], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// requestHost returns the Host header of a request without its port, in
+// lower case
+func requestHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// evaluatePath returns the path of the repository the requested path of the
+// virtual host resolves to, as done by filesystem.EvaluateFilePath. The path
+// can't lead out of the directory of the virtual host, even via a symlink.
+func evaluatePath(vhost *VirtualHost, urlPath string) (string, error) {
+	prefix := vhostPrefix(vhost)
+	p, err := filesystem.EvaluateFilePath(GetConfig().Repository+prefix, urlPath)
+	if err != nil {
+		return "", err
+	}
+	return prefix + p, nil
+}
+
+// repositoryPath returns the path of the repository the requested path of
+// the virtual host maps to
+func repositoryPath(vhost *VirtualHost, urlPath string) string {
+	if vhost == nil {
+		return urlPath
+	}
+	return vhost.Prefix + urlPath
+}
+
+// vhostPrefix returns the directory of the repository served by the virtual
+// host, empty for the whole repository
+func vhostPrefix(vhost *VirtualHost) string {
+	if vhost == nil {
+		return ""
+	}
+	return vhost.Prefix
+}
+
+// vhostMirrors returns the mirrors of the list serving the virtual host
+func vhostMirrors(mlist mirrors.Mirrors, vhost *VirtualHost) mirrors.Mirrors {
+	if vhost == nil {
+		return mlist
+	}
+	kept := mlist[:0]
+	for _, m := range mlist {
+		if vhost.HasMirror(m.Name) {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,165 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/etix/mirrorbits/filesystem"
+	"github.com/etix/mirrorbits/mirrors"
+)
+
+var testVirtualHosts = []VirtualHost{
+	{Host: "a.example.org", Prefix: "/a", Mirrors: []string{"m1", "m2"}},
+	{Host: "b.example.org", Prefix: "/b", Mirrors: []string{"m3"}},
+}
+
+func TestVirtualHost(t *testing.T) {
+	tests := map[string]struct {
+		vhosts      []VirtualHost
+		defaultHost string
+		host        string
+		want        string
+		ok          bool
+	}{
+		"none configured": {nil, "", "a.example.org", "", true},
+		"known":           {testVirtualHosts, "", "a.example.org", "a.example.org", true},
+		"with port":       {testVirtualHosts, "", "b.example.org:8080", "b.example.org", true},
+		"case and dot":    {testVirtualHosts, "", "A.Example.Org.", "a.example.org", true},
+		"unknown":         {testVirtualHosts, "", "c.example.org", "", false},
+		"default":         {testVirtualHosts, "b.example.org", "c.example.org", "b.example.org", true},
+		"ip":              {testVirtualHosts, "", "[::1]:80", "", false},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			SetConfiguration(&Configuration{VirtualHosts: tt.vhosts, DefaultVirtualHost: tt.defaultHost})
+			r := httptest.NewRequest("GET", "/file.iso", nil)
+			r.Host = tt.host
+
+			vhost, ok := virtualHost(r)
+			if ok != tt.ok {
+				t.Fatalf("Expected %v, got %v", tt.ok, ok)
+			}
+			var got string
+			if vhost != nil {
+				got = vhost.Host
+			}
+			if got != tt.want {
+				t.Fatalf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEvaluatePath(t *testing.T) {
+	ctx, err := prepareTest(t, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{"/a", "/b"} {
+		if err = os.Mkdir(ctx.RepoDir+dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"/a/file.iso", "/b/file.iso"} {
+		if err = makeEmptyFile(ctx.RepoDir, file); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = os.Symlink(ctx.RepoDir+"/b/file.iso", ctx.RepoDir+"/a/link.iso"); err != nil {
+		t.Fatal(err)
+	}
+
+	vhost := &testVirtualHosts[0]
+	if p, err := evaluatePath(vhost, "/file.iso"); err != nil || p != "/a/file.iso" {
+		t.Fatalf("Expected /a/file.iso, got %q (%v)", p, err)
+	}
+	if p, err := evaluatePath(nil, "/b/file.iso"); err != nil || p != "/b/file.iso" {
+		t.Fatalf("Expected /b/file.iso, got %q (%v)", p, err)
+	}
+	// The archive of another virtual host can't be reached
+	if _, err := evaluatePath(vhost, "/link.iso"); err != filesystem.ErrOutsideRepo {
+		t.Fatalf("Expected ErrOutsideRepo, got %v", err)
+	}
+}
+
+func TestVhostMirrors(t *testing.T) {
+	mlist := mirrors.Mirrors{{ID: 1, Name: "m1"}, {ID: 2, Name: "m3"}, {ID: 3, Name: "m2"}}
+
+	if got := vhostMirrors(mlist, nil); len(got) != 3 {
+		t.Fatalf("Expected all the mirrors, got %v", got)
+	}
+	got := vhostMirrors(mlist, &testVirtualHosts[0])
+	if len(got) != 2 || got[0].Name != "m1" || got[1].Name != "m2" {
+		t.Fatalf("Expected m1 and m2, got %v", got)
+	}
+}
+
+func TestFallbackMirrorsPrefix(t *testing.T) {
+	SetConfiguration(&Configuration{Fallbacks: []Fallback{{URL: "fallback.mirror/repo/"}}})
+
+	mlist := fallbackMirrors(WITHTLS, "/a/b")
+	if len(mlist) != 1 || mlist[0].AbsoluteURL != "https://fallback.mirror/repo/a/b/" {
+		t.Fatalf("Unexpected fallbacks: %v", mlist)
+	}
+
+	results := mirrors.Results{FileInfo: filesystem.FileInfo{Path: "/a/b/file.iso"}, Prefix: "/a/b"}
+	if got := results.MirrorPath(); got != "/file.iso" {
+		t.Fatalf("Expected /file.iso, got %q", got)
+	}
+}
+
+func TestVirtualHostRequests(t *testing.T) {
+	ctx, err := prepareTest(t, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.MkdirAll(ctx.RepoDir+"/a/dir", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	config := *GetConfig()
+	config.Hashes.SHA256 = true
+	config.VirtualHosts = testVirtualHosts
+	SetConfiguration(&config)
+
+	// Unknown host
+	resp := doRequest(ctx.Server, "GET", "http://c.example.org/dir/?checksums", map[string]string{})
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("Expected a 404, got: %s", dump(resp))
+	}
+	for _, err := range getMockErrors(ctx.MockedConn) {
+		t.Errorf("Unexpected database access: %s", err)
+	}
+
+	// The requested path is looked up in the archive of the virtual host
+	file := "/a/dir/file.iso"
+	if err = makeEmptyFile(ctx.RepoDir, file); err != nil {
+		t.Fatal(err)
+	}
+	ctx.MockedConn.Command("MULTI")
+	ctx.MockedConn.Command("HGET", "FILE_"+file, "sha256")
+	ctx.MockedConn.Command("EXEC").Expect([]any{[]byte("aaaa")})
+
+	resp = doRequest(ctx.Server, "GET", "http://a.example.org/dir/?checksums", map[string]string{})
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected a 200, got: %s", dump(resp))
+	}
+	if want := "aaaa  file.iso\n"; string(body) != want {
+		t.Fatalf("Expected %q, got %q", want, body)
+	}
+
+	// The archive of the other virtual host is not reachable
+	resp = doRequest(ctx.Server, "GET", "http://b.example.org/dir/?checksums", map[string]string{})
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("Expected a 404, got: %s", dump(resp))
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/etix/mirrorbits/database"
+	"github.com/etix/mirrorbits/filesystem"
+	"github.com/etix/mirrorbits/mirrors"
+	"github.com/gomodule/redigo/redis"
+)
+
+// ZsyncRenderer is used to render a zsync control file with the selected
+// mirrors as sources
+type ZsyncRenderer struct {
+	blockSize int
+	sha1      string
+	sums      []byte
+}
+
+// Type returns the type of renderer
+func (w *ZsyncRenderer) Type() string {
+	return "ZSYNC"
+}
+
+// Write is used to write the result to the ResponseWriter
+func (w *ZsyncRenderer) Write(ctx *Context, results *mirrors.Results) (statusCode int, err error) {
+	path := strings.TrimPrefix(results.MirrorPath(), "/")
+	seqMatches, rsumBytes, checksumBytes := filesystem.ZsyncHashLengths(results.FileInfo.Size, w.blockSize)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "zsync: 0.6.2\n")
+	fmt.Fprintf(&buf, "Filename: %s\n", filepath.Base(results.FileInfo.Path))
+	fmt.Fprintf(&buf, "MTime: %s\n", results.FileInfo.ModTime.UTC().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Blocksize: %d\n", w.blockSize)
+	fmt.Fprintf(&buf, "Length: %d\n", results.FileInfo.Size)
+	fmt.Fprintf(&buf, "Hash-Lengths: %d,%d,%d\n", seqMatches, rsumBytes, checksumBytes)
+	for _, m := range results.MirrorList {
+		fmt.Fprintf(&buf, "URL: %s\n", m.AbsoluteURL+path)
+	}
+	fmt.Fprintf(&buf, "SHA-1: %s\n\n", w.sha1)
+	buf.Write(w.sums)
+
+	ctx.ResponseWriter().Header().Set("Content-Type", "application/x-zsync")
+	ctx.ResponseWriter().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(results.FileInfo.Path)+".zsync"))
+	ctx.ResponseWriter().Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	buf.WriteTo(ctx.ResponseWriter())
+	return http.StatusOK, nil
+}
+
+// zsyncSums returns the block size, the SHA-1 and the block checksums
+// computed while scanning the local repository for the given file. The
+// checksums are empty if the file has not been processed (yet).
+func zsyncSums(ctx context.Context, r *database.Redis, path string, size int64) (int, string, []byte, error) {
+	conn := r.GetContext(ctx)
+	defer conn.Close()
+
+	values, err := redis.Values(conn.Do("HMGET", fmt.Sprintf("FILE_%s", path), "zsyncBlockSize", "zsyncSha1", "zsync"))
+	if err != nil {
+		return 0, "", nil, err
+	}
+	blockSize, _ := redis.Int(values[0], nil)
+	sha1, _ := redis.String(values[1], nil)
+	sums, _ := redis.Bytes(values[2], nil)
+	if blockSize <= 0 || len(sums) == 0 {
+		return 0, "", nil, nil
+	}
+
+	// Reject the checksums not matching the current size of the file
+	_, rsumBytes, checksumBytes := filesystem.ZsyncHashLengths(size, blockSize)
+	blocks := (size + int64(blockSize) - 1) / int64(blockSize)
+	if int64(len(sums)) != blocks*int64(rsumBytes+checksumBytes) {
+		return 0, "", nil, nil
+	}
+	return blockSize, sha1, sums, nil
+}
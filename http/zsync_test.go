@@ -0,0 +1,97 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package http
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/etix/mirrorbits/filesystem"
+	"github.com/etix/mirrorbits/mirrors"
+)
+
+func TestZsyncRenderer(t *testing.T) {
+	content := bytes.Repeat([]byte("mirrorbits"), 500)
+	path := filepath.Join(t.TempDir(), "file.iso")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	blockSize := 1024
+	sums, sha1sum, err := filesystem.ZsyncBlockSums(path, blockSize, int64(len(content)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if sum := sha1.Sum(content); sha1sum != hex.EncodeToString(sum[:]) {
+		t.Fatalf("Invalid SHA-1 %s", sha1sum)
+	}
+	seqMatches, rsumBytes, checksumBytes := filesystem.ZsyncHashLengths(int64(len(content)), blockSize)
+	if seqMatches != 2 || rsumBytes < 2 || rsumBytes > 4 || checksumBytes < 3 || checksumBytes > 16 {
+		t.Fatalf("Invalid hash lengths %d,%d,%d", seqMatches, rsumBytes, checksumBytes)
+	}
+	// 5000 bytes, the last block is padded
+	if len(sums) != 5*(rsumBytes+checksumBytes) {
+		t.Fatalf("Expected 5 blocks, got %d bytes", len(sums))
+	}
+
+	results := &mirrors.Results{
+		FileInfo: filesystem.FileInfo{
+			Path:    "/dir/file.iso",
+			Size:    int64(len(content)),
+			ModTime: time.Date(2019, 6, 1, 6, 0, 0, 0, time.UTC),
+		},
+		MirrorList: mirrors.Mirrors{
+			{ID: 1, AbsoluteURL: "https://m1.mirror/"},
+			{ID: 2, AbsoluteURL: "http://m2.mirror/pub/"},
+		},
+	}
+
+	r := httptest.NewRequest("GET", "/dir/file.iso?zsync", nil)
+	w := httptest.NewRecorder()
+	ctx := NewContext(w, r, Templates{})
+	if !ctx.IsZsync() {
+		t.Fatalf("Expected a zsync request")
+	}
+
+	renderer := &ZsyncRenderer{blockSize: blockSize, sha1: sha1sum, sums: sums}
+	status, err := renderer.Write(ctx, results)
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("Unexpected result: %d %v", status, err)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-zsync" {
+		t.Fatalf("Invalid content type %s", ct)
+	}
+
+	body := w.Body.Bytes()
+	i := bytes.Index(body, []byte("\n\n"))
+	if i < 0 {
+		t.Fatalf("Missing the end of the headers")
+	}
+	header := string(body[:i+1])
+	for _, line := range []string{
+		"zsync: 0.6.2\n",
+		"Filename: file.iso\n",
+		"MTime: Sat, 01 Jun 2019 06:00:00 +0000\n",
+		"Blocksize: 1024\n",
+		"Length: 5000\n",
+		"URL: https://m1.mirror/dir/file.iso\n",
+		"URL: http://m2.mirror/pub/dir/file.iso\n",
+		"SHA-1: " + sha1sum + "\n",
+	} {
+		if !strings.Contains(header, line) {
+			t.Fatalf("Missing %q in the headers:\n%s", line, header)
+		}
+	}
+	if !bytes.Equal(body[i+2:], sums) {
+		t.Fatalf("Invalid block checksums")
+	}
+}
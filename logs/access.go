@@ -0,0 +1,104 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package logs
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/etix/mirrorbits/config"
+)
+
+var alogger accessLogger
+
+type accessLogger struct {
+	sync.Mutex
+	f io.WriteCloser
+}
+
+func (a *accessLogger) Close() {
+	if a.f != nil {
+		a.f.Close()
+		a.f = nil
+	}
+}
+
+// ReloadAccessLogs reopens the access log for writing
+func ReloadAccessLogs() {
+	alogger.Lock()
+	defer alogger.Unlock()
+
+	alogger.Close()
+
+	if GetConfig().AccessLogFormat == "" || GetConfig().AccessLogFile == "" {
+		return
+	}
+
+	logfile := GetConfig().AccessLogFile
+	f, _, err := openLogFile(logfile)
+	if err != nil {
+		log.Criticalf("Cannot open log file %s", logfile)
+		return
+	}
+	alogger.f = f
+}
+
+// LogAccess writes a file request to the access log in the Combined Log
+// Format, followed by the name of the mirror the client was sent to:
+//
+//	ip - - [time] "request" status size "referer" "user-agent" "mirror"
+func LogAccess(r *http.Request, ip string, t time.Time, status int, size int64, mirror string) {
+	alogger.Lock()
+	defer alogger.Unlock()
+
+	if alogger.f == nil {
+		// Logs are disabled
+		return
+	}
+
+	if status == 0 {
+		status = http.StatusOK
+	}
+	bytes := "-"
+	if size > 0 {
+		bytes = strconv.FormatInt(size, 10)
+	}
+
+	fmt.Fprintf(alogger.f, "%s - - [%s] \"%s %s %s\" %d %s \"%s\" \"%s\" \"%s\"\n",
+		clfField(ip),
+		t.Format("02/Jan/2006:15:04:05 -0700"),
+		clfEscape(r.Method), clfEscape(r.RequestURI), clfEscape(r.Proto),
+		status, bytes,
+		clfField(r.Referer()),
+		clfField(r.UserAgent()),
+		clfField(mirror))
+}
+
+// clfField returns the escaped value, or "-" if empty
+func clfField(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return clfEscape(s)
+}
+
+// clfEscape escapes the quotes, the backslashes and the control characters
+// as done by Apache and NGINX
+func clfEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '"' || c == '\\' || c < 0x20 || c >= 0x7f {
+			fmt.Fprintf(&b, "\\x%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -5,10 +5,12 @@ package logs
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	stdlog "log"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
@@ -25,16 +27,21 @@ var (
 	log     = logging.MustGetLogger("main")
 	rlogger runtimeLogger
 	dlogger downloadsLogger
+
+	// logFormat is the format of the logs, either "text" or "json"
+	logFormat = "text"
 )
 
 type runtimeLogger struct {
-	f *os.File
+	f      *os.File
+	format string
 }
 
 type downloadsLogger struct {
 	sync.RWMutex
-	l *stdlog.Logger
-	f io.WriteCloser
+	l    *stdlog.Logger
+	f    io.WriteCloser
+	json bool
 }
 
 func (d *downloadsLogger) Close() {
@@ -47,9 +54,11 @@ func (d *downloadsLogger) Close() {
 
 // ReloadLogs will reopen the logs to allow rotations
 func ReloadLogs() {
+	logFormat = GetConfig().LogFormat
 	ReloadRuntimeLogs()
 	if core.Daemon {
 		ReloadDownloadLogs()
+		ReloadAccessLogs()
 	}
 }
 
@@ -63,7 +72,7 @@ func isTerminal(f *os.File) bool {
 
 // ReloadRuntimeLogs reopens the runtime logs for writing
 func ReloadRuntimeLogs() {
-	if rlogger.f == os.Stderr && core.RunLog == "" {
+	if rlogger.f == os.Stderr && core.RunLog == "" && rlogger.format == logFormat {
 		// Logger already set up and connected to the console.
 		// Don't reload to avoid breaking journald.
 		return
@@ -84,10 +93,16 @@ func ReloadRuntimeLogs() {
 		rlogger.f = os.Stderr
 	}
 
-	logBackend := logging.NewLogBackend(rlogger.f, "", 0)
-	logBackend.Color = isTerminal(rlogger.f) //TODO make color optional
+	rlogger.format = logFormat
+
+	if logFormat == "json" {
+		logging.SetBackend(&jsonBackend{w: rlogger.f})
+	} else {
+		logBackend := logging.NewLogBackend(rlogger.f, "", 0)
+		logBackend.Color = isTerminal(rlogger.f) //TODO make color optional
 
-	logging.SetBackend(logBackend)
+		logging.SetBackend(logBackend)
+	}
 
 	if core.Debug {
 		logging.SetFormatter(logging.MustStringFormatter("%{shortfile:-20s}%{time:2006/01/02 15:04:05.000 MST} %{message}"))
@@ -115,6 +130,12 @@ func openLogFile(logfile string) (*os.File, bool, error) {
 }
 
 func setDownloadLogWriter(writer io.Writer, createHeader bool) {
+	if dlogger.json {
+		// Each line is a self-contained JSON object, including the time
+		dlogger.l = stdlog.New(writer, "", 0)
+		return
+	}
+
 	dlogger.l = stdlog.New(writer, "", stdlog.Ldate|stdlog.Lmicroseconds)
 
 	if createHeader {
@@ -133,6 +154,7 @@ func ReloadDownloadLogs() {
 	defer dlogger.Unlock()
 
 	dlogger.Close()
+	dlogger.json = GetConfig().LogFormat == "json"
 
 	if GetConfig().LogDir == "" {
 		return
@@ -148,6 +170,11 @@ func ReloadDownloadLogs() {
 	setDownloadLogWriter(f, createHeader)
 }
 
+// isRedirect returns true if the status code redirects the client to a mirror
+func isRedirect(statuscode int) bool {
+	return statuscode >= 300 && statuscode < 400
+}
+
 // LogDownload writes a download result to the logs
 func LogDownload(typ string, method string, statuscode int, p *mirrors.Results, err error) {
 	dlogger.RLock()
@@ -169,9 +196,14 @@ func LogDownload(typ string, method string, statuscode int, p *mirrors.Results,
 		errstr = err.Error()
 	}
 
+	if dlogger.json {
+		logDownloadJSON(typ, method, statuscode, p, path, ip, errstr)
+		return
+	}
+
 	line := fmt.Sprintf("%s %d %s \"%s\" ip:%s", typ, statuscode, method, path, ip)
 
-	if (statuscode == 302 || statuscode == 200) && p != nil && len(p.MirrorList) > 0 {
+	if (isRedirect(statuscode) || statuscode == 200) && p != nil && len(p.MirrorList) > 0 {
 		var distance, countries string
 		m := p.MirrorList[0]
 		distance = strconv.FormatFloat(float64(m.Distance), 'f', 2, 32)
@@ -200,3 +232,99 @@ func LogDownload(typ string, method string, statuscode int, p *mirrors.Results,
 
 	dlogger.l.Print(line)
 }
+
+// downloadRecord is the structure of a download log entry in JSON
+type downloadRecord struct {
+	Time      string   `json:"time"`
+	Type      string   `json:"type"`
+	Status    int      `json:"status"`
+	Method    string   `json:"method"`
+	Path      string   `json:"path"`
+	IP        string   `json:"ip"`
+	Mirror    string   `json:"mirror,omitempty"`
+	Fallback  bool     `json:"fallback,omitempty"`
+	ASNum     uint     `json:"asn,omitempty"`
+	SameASN   bool     `json:"sameASN,omitempty"`
+	Distance  float32  `json:"distance,omitempty"`
+	Countries []string `json:"countries,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// logDownloadJSON writes a download result as a JSON object, the caller
+// must hold the lock
+func logDownloadJSON(typ string, method string, statuscode int, p *mirrors.Results, path, ip, errstr string) {
+	r := downloadRecord{
+		Time:   time.Now().Format(time.RFC3339Nano),
+		Type:   typ,
+		Status: statuscode,
+		Method: method,
+		Path:   path,
+		IP:     ip,
+	}
+
+	if (isRedirect(statuscode) || statuscode == 200) && p != nil && len(p.MirrorList) > 0 {
+		m := p.MirrorList[0]
+		r.Mirror = m.Name
+		r.Fallback = p.Fallback
+		r.ASNum = m.Asnum
+		r.SameASN = m.Asnum > 0 && m.Asnum == p.ClientInfo.ASNum
+		r.Distance = m.Distance
+		r.Countries = m.CountryFields
+	} else if statuscode == 404 && p != nil {
+		// nothing to add to the log entry
+	} else if statuscode == 500 && p != nil {
+		r.Mirror = "unknown"
+		if len(p.MirrorList) > 0 {
+			r.Mirror = p.MirrorList[0].Name
+		}
+		r.Error = errstr
+	} else {
+		r.Error = errstr
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		return
+	}
+	dlogger.l.Print(string(b))
+}
+
+// jsonBackend is a logging backend writing each record as a JSON object
+type jsonBackend struct {
+	sync.Mutex
+	w io.Writer
+}
+
+type jsonRecord struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	Module  string `json:"module"`
+	Message string `json:"message"`
+	File    string `json:"file,omitempty"`
+}
+
+// Log implements the logging.Backend interface
+func (b *jsonBackend) Log(level logging.Level, calldepth int, rec *logging.Record) error {
+	r := jsonRecord{
+		Time:    rec.Time.Format(time.RFC3339Nano),
+		Level:   level.String(),
+		Module:  rec.Module,
+		Message: rec.Message(),
+	}
+	if core.Debug {
+		if _, file, line, ok := runtime.Caller(calldepth + 1); ok {
+			r.File = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+		}
+	}
+
+	buf, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	buf = append(buf, '\n')
+
+	b.Lock()
+	defer b.Unlock()
+	_, err = b.w.Write(buf)
+	return err
+}
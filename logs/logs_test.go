@@ -5,12 +5,15 @@ package logs
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"io"
+	"net/http/httptest"
 	"os"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/etix/mirrorbits/core"
 	"github.com/etix/mirrorbits/filesystem"
@@ -289,6 +292,16 @@ func TestLogDownload(t *testing.T) {
 
 	buf.Reset()
 
+	/* Test a log line with another redirect status code */
+	LogDownload("REDIRECT", "GET", 307, p, nil)
+
+	expected = "REDIRECT 307 GET \"/test/file.tgz\" ip:192.168.0.1 mirror:m1 fallback:true sameasn:444 distance:99.00km countries:FR,UK,DE\n"
+	if !strings.HasSuffix(buf.String(), expected) {
+		t.Fatalf("Invalid log line:\nGot:\n%#vs\nExpected:\n%#v", buf.String(), expected)
+	}
+
+	buf.Reset()
+
 	/* Test a log line with 404 status code */
 	p = &mirrors.Results{
 		FileInfo: filesystem.FileInfo{
@@ -363,3 +376,116 @@ func TestLogDownload(t *testing.T) {
 
 	buf.Reset()
 }
+
+func TestLogDownloadJSON(t *testing.T) {
+	var buf bytes.Buffer
+
+	dlogger.Close()
+	dlogger.json = true
+	defer func() { dlogger.json = false }()
+
+	setDownloadLogWriter(&buf, true)
+
+	if buf.Len() != 0 {
+		t.Fatalf("No header is expected in JSON mode, got %s", buf.String())
+	}
+
+	p := &mirrors.Results{
+		FileInfo: filesystem.FileInfo{
+			Path: "/test/file.tgz",
+		},
+		MirrorList: mirrors.Mirrors{
+			mirrors.Mirror{
+				ID:            1,
+				Name:          "m1",
+				Asnum:         444,
+				Distance:      99,
+				CountryFields: []string{"FR", "UK"},
+			},
+		},
+		IP: "192.168.0.1",
+		ClientInfo: network.GeoIPRecord{
+			ASNum: 444,
+		},
+	}
+
+	LogDownload("REDIRECT", "GET", 302, p, nil)
+
+	var r downloadRecord
+	if err := json.Unmarshal(buf.Bytes(), &r); err != nil {
+		t.Fatalf("Invalid JSON %s: %s", buf.String(), err)
+	}
+	if r.Time == "" || r.Type != "REDIRECT" || r.Status != 302 || r.Method != "GET" ||
+		r.Path != "/test/file.tgz" || r.IP != "192.168.0.1" || r.Mirror != "m1" ||
+		r.ASNum != 444 || !r.SameASN || r.Distance != 99 ||
+		!reflect.DeepEqual(r.Countries, []string{"FR", "UK"}) || r.Error != "" {
+		t.Fatalf("Invalid log entry: %+v", r)
+	}
+
+	buf.Reset()
+
+	LogDownload("REDIRECT", "GET", 500, p, errors.New("test error"))
+
+	r = downloadRecord{}
+	if err := json.Unmarshal(buf.Bytes(), &r); err != nil {
+		t.Fatalf("Invalid JSON %s: %s", buf.String(), err)
+	}
+	if r.Status != 500 || r.Mirror != "m1" || r.Error != "test error" {
+		t.Fatalf("Invalid log entry: %+v", r)
+	}
+}
+
+func TestJSONBackend(t *testing.T) {
+	var buf bytes.Buffer
+
+	logging.SetBackend(&jsonBackend{w: &buf})
+	logging.SetLevel(logging.INFO, "main")
+	defer func() {
+		rlogger.f = nil
+		ReloadRuntimeLogs()
+	}()
+
+	log.Warningf("Testing %d", 42)
+
+	var r jsonRecord
+	if err := json.Unmarshal(buf.Bytes(), &r); err != nil {
+		t.Fatalf("Invalid JSON %s: %s", buf.String(), err)
+	}
+	if r.Level != "WARNING" || r.Module != "main" || r.Message != "Testing 42" || r.Time == "" {
+		t.Fatalf("Invalid log record: %+v", r)
+	}
+}
+
+// bufferCloser is a bytes.Buffer implementing io.Closer
+type bufferCloser struct {
+	bytes.Buffer
+}
+
+func (b *bufferCloser) Close() error {
+	return nil
+}
+
+func TestLogAccess(t *testing.T) {
+	alogger.Close()
+
+	// The next line isn't supposed to crash.
+	LogAccess(httptest.NewRequest("GET", "/file", nil), "192.0.2.1", time.Now(), 302, 0, "")
+
+	buf := &bufferCloser{}
+	alogger.f = buf
+	defer alogger.Close()
+
+	r := httptest.NewRequest("GET", "/dir/file.iso?mirrorlist", nil)
+	r.Header.Set("User-Agent", `curl/8.0 "quoted"`)
+	r.Header.Set("Referer", "https://example.org/")
+	date := time.Date(2025, time.June, 1, 6, 0, 0, 0, time.FixedZone("", 2*3600))
+
+	LogAccess(r, "192.0.2.1", date, 302, 42, "mirror1")
+	LogAccess(httptest.NewRequest("HEAD", "/file", nil), "2001:db8::1", date, 0, 0, "")
+
+	expected := `192.0.2.1 - - [01/Jun/2025:06:00:00 +0200] "GET /dir/file.iso?mirrorlist HTTP/1.1" 302 42 "https://example.org/" "curl/8.0 \x22quoted\x22" "mirror1"` + "\n" +
+		`2001:db8::1 - - [01/Jun/2025:06:00:00 +0200] "HEAD /file HTTP/1.1" 200 - "-" "-" "-"` + "\n"
+	if buf.String() != expected {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
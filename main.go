@@ -5,6 +5,7 @@ package main
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"os/signal"
 	"runtime/pprof"
@@ -64,6 +65,10 @@ func main() {
 		c := mirrors.NewCache(r)
 		rpcs.SetCache(c)
 		h := http.HTTPServer(r, c)
+		rpcs.SetSimulator(h)
+		rpcs.SetMaintenanceSwitch(h)
+		rpcs.SetRequestRater(h)
+		go h.WatchGeoIP()
 
 		/* Start the background monitor */
 		m := daemon.NewMonitor(r, c)
@@ -94,21 +99,22 @@ func main() {
 				case syscall.SIGQUIT:
 					m.Stop()
 					rpcs.Close()
-					if h.Listener != nil {
+					if h.Listener != nil || h.TLSListener != nil {
 						log.Notice("Waiting for running tasks to finish...")
-						h.Stop(5 * time.Second)
+						h.Stop(time.Duration(GetConfig().ShutdownTimeout) * time.Second)
 					} else {
 						process.RemovePidFile()
 						os.Exit(0)
 					}
 				case syscall.SIGHUP:
 					listenAddress := GetConfig().ListenAddress
+					tlsListenAddress := GetConfig().TLSListenAddress
 					if err := ReloadConfig(); err != nil {
 						log.Warningf("SIGHUP Received: %s\n", err)
 					} else {
 						log.Notice("SIGHUP Received: Reloading configuration...")
 					}
-					if GetConfig().ListenAddress != listenAddress {
+					if GetConfig().ListenAddress != listenAddress || GetConfig().TLSListenAddress != tlsListenAddress {
 						h.Restarting = true
 						h.Stop(1 * time.Second)
 					}
@@ -120,7 +126,14 @@ func main() {
 				case syscall.SIGUSR2:
 					log.Notice("SIGUSR2 Received: Seamless binary upgrade...")
 					rpcs.Close()
-					err := process.Relaunch(*h.Listener)
+					var l, tl net.Listener
+					if h.Listener != nil {
+						l = *h.Listener
+					}
+					if h.TLSListener != nil {
+						tl = *h.TLSListener
+					}
+					err := process.Relaunch(l, tl)
 					if err != nil {
 						log.Errorf("Relaunch failed: %s\n", err)
 					}
@@ -129,8 +142,13 @@ func main() {
 		}()
 
 		// Recover an existing listener (see process.go)
-		if l, ppid, err := process.Recover(); err == nil {
-			h.SetListener(l)
+		if l, tl, ppid, err := process.Recover(); err == nil {
+			if l != nil {
+				h.SetListener(l)
+			}
+			if tl != nil {
+				h.SetTLSListener(tl)
+			}
 			go func() {
 				time.Sleep(100 * time.Millisecond)
 				process.KillParent(ppid)
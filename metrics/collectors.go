@@ -0,0 +1,95 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package metrics
+
+var (
+	// Requests counts the file requests handled by the HTTP server
+	Requests = NewCounterVec("mirrorbits_requests_total",
+		"Number of file requests, by client country and result.",
+		"country", "result")
+
+	// HeadRequests counts the HEAD file requests, if CountHeadRequests is set
+	HeadRequests = NewCounterVec("mirrorbits_head_requests_total",
+		"Number of HEAD file requests, by client country and result.",
+		"country", "result")
+
+	// StatsEventsDropped counts the download events not sent to the stats
+	// webhook
+	StatsEventsDropped = NewCounterVec("mirrorbits_stats_events_dropped_total",
+		"Number of download events dropped by the stats webhook.")
+
+	// Redirects counts the clients sent to a given mirror
+	Redirects = NewCounterVec("mirrorbits_redirects_total",
+		"Number of redirects, by mirror, client country, result and status code.",
+		"mirror", "country", "result", "code")
+
+	// RedirectsInFlight reports the number of file requests being handled
+	RedirectsInFlight = NewGaugeVec("mirrorbits_redirects_in_flight",
+		"Number of file requests currently being handled.")
+
+	// RedirectsRejected counts the file requests rejected by the
+	// concurrency limit
+	RedirectsRejected = NewCounterVec("mirrorbits_redirects_rejected_total",
+		"Number of file requests rejected because of MaxConcurrentRedirects.")
+
+	// RedirectsTimedOut counts the file requests that reached the
+	// RedirectTimeout
+	RedirectsTimedOut = NewCounterVec("mirrorbits_redirects_timed_out_total",
+		"Number of file requests aborted because of RedirectTimeout.")
+
+	// RedirectsMaintenance counts the file requests refused by the
+	// maintenance mode
+	RedirectsMaintenance = NewCounterVec("mirrorbits_redirects_maintenance_total",
+		"Number of file requests refused while in maintenance mode.")
+
+	// SelectionCacheHits counts the file requests served from the selection
+	// cache while the database was unavailable
+	SelectionCacheHits = NewCounterVec("mirrorbits_selection_cache_hits_total",
+		"Number of file requests served from the selection cache while the database was unavailable.")
+
+	// SelectionCacheMisses counts the file requests the selection cache
+	// couldn't serve while the database was unavailable
+	SelectionCacheMisses = NewCounterVec("mirrorbits_selection_cache_misses_total",
+		"Number of file requests not found in the selection cache while the database was unavailable.")
+
+	// MirrorUp reports the state of each mirror
+	MirrorUp = NewGaugeVec("mirrorbits_mirror_up",
+		"Whether the mirror is up (1) or down (0).",
+		"mirror")
+
+	// MirrorThroughput reports the average throughput measured by the deep
+	// health checks
+	MirrorThroughput = NewGaugeVec("mirrorbits_mirror_throughput_bytes",
+		"Average throughput of the mirror in bytes per second.",
+		"mirror")
+
+	// MirrorsUp reports the number of mirrors currently up
+	MirrorsUp = NewGaugeVec("mirrorbits_mirrors_up",
+		"Number of enabled mirrors currently up.")
+
+	// ScanDuration records the time taken by the mirror scans
+	ScanDuration = NewHistogramVec("mirrorbits_scan_duration_seconds",
+		"Duration of the mirror scans, by mirror, protocol and result.",
+		[]float64{1, 5, 15, 30, 60, 120, 300, 600, 1800, 3600},
+		"mirror", "protocol", "result")
+
+	// RedisPoolActive reports the number of connections of the redis pool
+	RedisPoolActive = NewGaugeVec("mirrorbits_redis_pool_active",
+		"Number of connections in the redis pool, idle or in use.")
+
+	// RedisPoolIdle reports the number of idle connections of the redis pool
+	RedisPoolIdle = NewGaugeVec("mirrorbits_redis_pool_idle",
+		"Number of idle connections in the redis pool.")
+
+	// RedisPoolExhausted counts the requests waiting for a redis connection
+	RedisPoolExhausted = NewCounterVec("mirrorbits_redis_pool_exhausted_total",
+		"Number of times a redis connection was requested while the pool was exhausted.")
+)
+
+// Values of the result label
+const (
+	ResultHit      = "hit"
+	ResultFallback = "fallback"
+	ResultError    = "error"
+)
@@ -0,0 +1,283 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+// Package metrics implements a minimal set of Prometheus-compatible
+// collectors. Collectors are registered globally when created and are never
+// reset, so their values survive configuration reloads.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	registryLock sync.RWMutex
+	registry     []collector
+)
+
+type collector interface {
+	write(w io.Writer) error
+}
+
+func register(c collector) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	registry = append(registry, c)
+}
+
+// WriteText writes all the registered collectors to w using the Prometheus
+// text exposition format
+func WriteText(w io.Writer) error {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	for _, c := range registry {
+		if err := c.write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// vec holds the values of a collector indexed by their label values
+type vec struct {
+	sync.Mutex
+	name   string
+	help   string
+	typ    string
+	labels []string
+	values map[string]*sample
+}
+
+type sample struct {
+	labelValues []string
+	value       float64
+	// histograms only
+	buckets []uint64
+	count   uint64
+}
+
+func newVec(name, help, typ string, labels []string) *vec {
+	return &vec{
+		name:   name,
+		help:   help,
+		typ:    typ,
+		labels: labels,
+		values: make(map[string]*sample),
+	}
+}
+
+// get returns the sample matching the given label values, the caller
+// must hold the lock
+func (v *vec) get(labelValues []string) *sample {
+	if len(labelValues) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.name, len(v.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := v.values[key]
+	if !ok {
+		s = &sample{labelValues: append([]string(nil), labelValues...)}
+		v.values[key] = s
+	}
+	return s
+}
+
+// sorted returns the samples ordered by label values, the caller
+// must hold the lock
+func (v *vec) sorted() []*sample {
+	keys := make([]string, 0, len(v.values))
+	for k := range v.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	samples := make([]*sample, len(keys))
+	for i, k := range keys {
+		samples[i] = v.values[k]
+	}
+	return samples
+}
+
+func (v *vec) writeHeader(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, escapeHelp(v.help), v.name, v.typ)
+	return err
+}
+
+func (v *vec) write(w io.Writer) error {
+	v.Lock()
+	defer v.Unlock()
+	if err := v.writeHeader(w); err != nil {
+		return err
+	}
+	for _, s := range v.sorted() {
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", v.name, formatLabels(v.labels, s.labelValues), formatFloat(s.value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CounterVec is a set of monotonically increasing counters partitioned by labels
+type CounterVec struct {
+	*vec
+}
+
+// NewCounterVec creates and registers a new CounterVec
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{newVec(name, help, "counter", labels)}
+	register(c)
+	return c
+}
+
+// Inc increments the counter matching the given label values by one
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds the given value to the counter matching the given label values
+func (c *CounterVec) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		panic("metrics: counters cannot decrease")
+	}
+	c.Lock()
+	c.get(labelValues).value += value
+	c.Unlock()
+}
+
+// GaugeVec is a set of values partitioned by labels that can go up and down
+type GaugeVec struct {
+	*vec
+}
+
+// NewGaugeVec creates and registers a new GaugeVec
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{newVec(name, help, "gauge", labels)}
+	register(g)
+	return g
+}
+
+// Set sets the gauge matching the given label values
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	g.Lock()
+	g.get(labelValues).value = value
+	g.Unlock()
+}
+
+// Reset removes all the values of the gauge
+func (g *GaugeVec) Reset() {
+	g.Lock()
+	g.values = make(map[string]*sample)
+	g.Unlock()
+}
+
+// HistogramVec is a set of histograms partitioned by labels
+type HistogramVec struct {
+	*vec
+	bounds []float64
+}
+
+// NewHistogramVec creates and registers a new HistogramVec using the
+// given upper bounds for its buckets
+func NewHistogramVec(name, help string, bounds []float64, labels ...string) *HistogramVec {
+	b := append([]float64(nil), bounds...)
+	sort.Float64s(b)
+	h := &HistogramVec{
+		vec:    newVec(name, help, "histogram", labels),
+		bounds: b,
+	}
+	register(h)
+	return h
+}
+
+// Observe adds a single observation to the histogram matching the
+// given label values
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	h.Lock()
+	defer h.Unlock()
+	s := h.get(labelValues)
+	if s.buckets == nil {
+		s.buckets = make([]uint64, len(h.bounds))
+	}
+	for i, b := range h.bounds {
+		if value <= b {
+			s.buckets[i]++
+		}
+	}
+	s.count++
+	s.value += value
+}
+
+func (h *HistogramVec) write(w io.Writer) error {
+	h.Lock()
+	defer h.Unlock()
+	if err := h.writeHeader(w); err != nil {
+		return err
+	}
+	labels := append(append([]string(nil), h.labels...), "le")
+	for _, s := range h.sorted() {
+		values := append(append([]string(nil), s.labelValues...), "")
+		for i, b := range h.bounds {
+			values[len(values)-1] = formatFloat(b)
+			if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(labels, values), s.buckets[i]); err != nil {
+				return err
+			}
+		}
+		values[len(values)-1] = "+Inf"
+		if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(labels, values), s.count); err != nil {
+			return err
+		}
+		l := formatLabels(h.labels, s.labelValues)
+		if _, err := fmt.Fprintf(w, "%s_sum%s %s\n%s_count%s %d\n", h.name, l, formatFloat(s.value), h.name, l, s.count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, n := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(n)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabel(values[i]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, +1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+var (
+	helpReplacer  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpReplacer.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelReplacer.Replace(s)
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCounterVec(t *testing.T) {
+	c := NewCounterVec("test_counter_total", "A test counter.", "mirror", "result")
+	c.Inc("m2", "hit")
+	c.Inc("m1", "hit")
+	c.Add(2, "m1", "hit")
+	c.Inc("m\"1", "error")
+
+	var buf bytes.Buffer
+	if err := c.write(&buf); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expected := `# HELP test_counter_total A test counter.
+# TYPE test_counter_total counter
+test_counter_total{mirror="m\"1",result="error"} 1
+test_counter_total{mirror="m1",result="hit"} 3
+test_counter_total{mirror="m2",result="hit"} 1
+`
+	if buf.String() != expected {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestGaugeVec(t *testing.T) {
+	g := NewGaugeVec("test_gauge", "A test gauge.")
+	g.Set(4)
+	g.Set(2)
+
+	var buf bytes.Buffer
+	g.write(&buf)
+	if !strings.HasSuffix(buf.String(), "\ntest_gauge 2\n") {
+		t.Fatalf("Unexpected output:\n%s", buf.String())
+	}
+
+	g.Reset()
+	buf.Reset()
+	g.write(&buf)
+	if strings.Contains(buf.String(), "test_gauge 2") {
+		t.Fatalf("Expected the gauge to be reset, got:\n%s", buf.String())
+	}
+}
+
+func TestHistogramVec(t *testing.T) {
+	h := NewHistogramVec("test_duration_seconds", "A test histogram.", []float64{10, 1}, "mirror")
+	h.Observe(0.5, "m1")
+	h.Observe(5, "m1")
+	h.Observe(50, "m1")
+
+	var buf bytes.Buffer
+	if err := h.write(&buf); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expected := `# HELP test_duration_seconds A test histogram.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{mirror="m1",le="1"} 1
+test_duration_seconds_bucket{mirror="m1",le="10"} 2
+test_duration_seconds_bucket{mirror="m1",le="+Inf"} 3
+test_duration_seconds_sum{mirror="m1"} 55.5
+test_duration_seconds_count{mirror="m1"} 3
+`
+	if buf.String() != expected {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteText(&buf); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !strings.Contains(buf.String(), "# TYPE mirrorbits_redirects_total counter\n") {
+		t.Fatalf("Expected the registered collectors in the output, got:\n%s", buf.String())
+	}
+}
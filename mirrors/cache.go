@@ -4,27 +4,43 @@
 package mirrors
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 	"unsafe"
 
+	. "github.com/etix/mirrorbits/config"
 	"github.com/etix/mirrorbits/database"
 	"github.com/etix/mirrorbits/filesystem"
+	"github.com/etix/mirrorbits/metrics"
 	"github.com/etix/mirrorbits/network"
 	"github.com/etix/mirrorbits/utils"
 	"github.com/gomodule/redigo/redis"
 )
 
+// replicaLagGrace is the time during which a key is read from the primary
+// after its invalidation, leaving the replica time to catch up
+const replicaLagGrace = 5 * time.Second
+
 // Cache implements a local caching mechanism of type LRU for content available in the
 // redis database that is automatically invalidated if the object is updated in Redis.
 type Cache struct {
+	invalidated int64 // first for the 64-bit alignment of atomic accesses
+
+	invalidatedKeys map[string]time.Time
+	lastSweep       time.Time
+	invalidatedLock sync.Mutex
+
 	r        *database.Redis
 	fiCache  *LRUCache
 	fmCache  *LRUCache
 	mCache   *LRUCache
 	fimCache *LRUCache
+	sCache   *LRUCache
 
 	mirrorUpdateEvent      chan string
 	fileUpdateEvent        chan string
@@ -57,6 +73,16 @@ func (f *mirrorValue) Size() int {
 	return int(unsafe.Sizeof(f.value))
 }
 
+type selectionValue struct {
+	value   []Mirror
+	fetched time.Time
+}
+
+// Size returns 1, the capacity of the selection cache is a number of entries
+func (f *selectionValue) Size() int {
+	return 1
+}
+
 // NewCache constructs a new instance of Cache
 func NewCache(r *database.Redis) *Cache {
 	if r == nil || r.Pubsub == nil {
@@ -64,7 +90,8 @@ func NewCache(r *database.Redis) *Cache {
 	}
 
 	c := &Cache{
-		r: r,
+		r:               r,
+		invalidatedKeys: make(map[string]time.Time),
 	}
 
 	// Create the LRU
@@ -72,6 +99,7 @@ func NewCache(r *database.Redis) *Cache {
 	c.fmCache = NewLRUCache(2048000)
 	c.mCache = NewLRUCache(1024000)
 	c.fimCache = NewLRUCache(4096000)
+	c.sCache = NewLRUCache(0) // see SelectionCacheMaxEntries
 
 	// Create event channels
 	c.mirrorUpdateEvent = make(chan string, 10)
@@ -90,22 +118,33 @@ func NewCache(r *database.Redis) *Cache {
 	go func() {
 		for {
 			//FIXME add a close channel
+			var data string
 			select {
-			case data := <-c.mirrorUpdateEvent:
-				c.mCache.Delete(data)
-				select {
-				case c.invalidationEvent <- data:
-				default:
-					// Non-blocking
+			case data = <-c.mirrorUpdateEvent:
+				// The event may carry several space separated IDs
+				for _, id := range strings.Fields(data) {
+					c.mCache.Delete(id)
+					c.invalidate("MIRROR_" + id)
+					select {
+					case c.invalidationEvent <- id:
+					default:
+						// Non-blocking
+					}
 				}
-			case data := <-c.fileUpdateEvent:
+			case data = <-c.fileUpdateEvent:
 				c.fiCache.Delete(data)
-			case data := <-c.mirrorFileUpdateEvent:
+				c.sCache.Delete(data)
+				c.invalidate("FILE_" + data)
+			case data = <-c.mirrorFileUpdateEvent:
 				s := strings.SplitN(data, " ", 2)
 				c.fmCache.Delete(s[1])
 				c.fimCache.Delete(fmt.Sprintf("%s|%s", s[0], s[1]))
+				c.sCache.Delete(s[1])
+				c.invalidate("FILEMIRRORS_"+s[1], fmt.Sprintf("FILEINFO_%s_%s", s[0], s[1]))
 			case <-c.pubsubReconnectedEvent:
+				// Events may have been missed, everything is invalidated
 				c.Clear()
+				atomic.StoreInt64(&c.invalidated, time.Now().UnixNano())
 			}
 		}
 	}()
@@ -113,12 +152,58 @@ func NewCache(r *database.Redis) *Cache {
 	return c
 }
 
+// invalidate records the invalidation of the given database keys
+func (c *Cache) invalidate(keys ...string) {
+	now := time.Now()
+
+	c.invalidatedLock.Lock()
+	defer c.invalidatedLock.Unlock()
+
+	for _, key := range keys {
+		c.invalidatedKeys[key] = now
+	}
+	if now.Sub(c.lastSweep) < replicaLagGrace {
+		return
+	}
+	for key, t := range c.invalidatedKeys {
+		if now.Sub(t) >= replicaLagGrace {
+			delete(c.invalidatedKeys, key)
+		}
+	}
+	c.lastSweep = now
+}
+
+// getConn returns a connection to read the given database key, from the
+// replica if any. The events are published by the primary and may be
+// received before the replica has the new values, which would then be cached
+// until the next invalidation: a key is read from the primary for a while
+// after its invalidation.
+func (c *Cache) getConn(ctx context.Context, key string) redis.Conn {
+	if c.recentlyInvalidated(key) {
+		return c.r.GetContext(ctx)
+	}
+	return c.r.GetReplicaContext(ctx)
+}
+
+// recentlyInvalidated returns true if the given key, or the whole cache, was
+// invalidated less than replicaLagGrace ago
+func (c *Cache) recentlyInvalidated(key string) bool {
+	if time.Since(time.Unix(0, atomic.LoadInt64(&c.invalidated))) < replicaLagGrace {
+		return true
+	}
+	c.invalidatedLock.Lock()
+	defer c.invalidatedLock.Unlock()
+	t, ok := c.invalidatedKeys[key]
+	return ok && time.Since(t) < replicaLagGrace
+}
+
 // Clear clears the local cache
 func (c *Cache) Clear() {
 	c.fiCache.Clear()
 	c.fmCache.Clear()
 	c.mCache.Clear()
 	c.fimCache.Clear()
+	c.sCache.Clear()
 }
 
 // GetMirrorInvalidationEvent returns a channel that contains ID of mirrors
@@ -131,22 +216,24 @@ func (c *Cache) GetMirrorInvalidationEvent() <-chan string {
 
 // GetFileInfo returns file information for a given file either from the cache
 // or directly from the database if the object is not yet stored in the cache.
-func (c *Cache) GetFileInfo(path string) (f filesystem.FileInfo, err error) {
+// The database requests are bound to the given context.
+func (c *Cache) GetFileInfo(ctx context.Context, path string) (f filesystem.FileInfo, err error) {
 	v, ok := c.fiCache.Get(path)
 	if ok {
 		f = v.(*fileInfoValue).value
 	} else {
-		f, err = c.fetchFileInfo(path)
+		f, err = c.fetchFileInfo(ctx, path)
 	}
 	return
 }
 
-func (c *Cache) fetchFileInfo(path string) (f filesystem.FileInfo, err error) {
-	rconn := c.r.Get()
+func (c *Cache) fetchFileInfo(ctx context.Context, path string) (f filesystem.FileInfo, err error) {
+	key := fmt.Sprintf("FILE_%s", path)
+	rconn := c.getConn(ctx, key)
 	defer rconn.Close()
 	f.Path = path // Path is not stored in the object instance in redis
 
-	reply, err := redis.Strings(rconn.Do("HMGET", fmt.Sprintf("FILE_%s", path), "size", "modTime", "sha1", "sha256", "md5"))
+	reply, err := redis.Strings(rconn.Do("HMGET", key, "size", "modTime", "sha1", "sha256", "md5", "sha512"))
 	if err != nil {
 		return
 	}
@@ -156,19 +243,82 @@ func (c *Cache) fetchFileInfo(path string) (f filesystem.FileInfo, err error) {
 	f.Sha1 = reply[2]
 	f.Sha256 = reply[3]
 	f.Md5 = reply[4]
+	f.Sha512 = reply[5]
 	c.fiCache.Set(path, &fileInfoValue{value: f})
 	return
 }
 
 // GetMirrors returns all the mirrors serving a given file either from the cache
 // or directly from the database if the object is not yet stored in the cache.
-func (c *Cache) GetMirrors(path string, clientInfo network.GeoIPRecord) (mirrors []Mirror, err error) {
+// If the database is unavailable, the mirrors returned for the file during
+// the last SelectionCacheTTL seconds are used instead, as when the context is
+// done before the database replies.
+func (c *Cache) GetMirrors(ctx context.Context, path string, clientInfo network.GeoIPRecord) (mirrors []Mirror, err error) {
+	mirrors, err = c.getMirrors(ctx, path)
+	if err != nil {
+		if !isUnavailable(err) {
+			return nil, err
+		}
+		var ok bool
+		if mirrors, ok = c.getStaleMirrors(path); !ok {
+			metrics.SelectionCacheMisses.Inc()
+			return nil, err
+		}
+		metrics.SelectionCacheHits.Inc()
+		err = nil
+	} else if GetConfig().SelectionCacheTTL > 0 {
+		c.sCache.SetCapacity(uint64(GetConfig().SelectionCacheMaxEntries))
+		c.sCache.Set(path, &selectionValue{
+			value:   append([]Mirror(nil), mirrors...),
+			fetched: time.Now(),
+		})
+	}
+
+	for i := range mirrors {
+		mirror := &mirrors[i]
+		if clientInfo.IsValid() {
+			mirror.Distance = utils.RoundDistance(utils.GetDistanceKm(clientInfo.Latitude,
+				clientInfo.Longitude,
+				mirror.Latitude,
+				mirror.Longitude), GetConfig().DistanceRoundingKm)
+		} else {
+			mirror.Distance = 0
+		}
+	}
+	return
+}
+
+// getStaleMirrors returns a copy of the mirrors recently returned for the
+// given file, if any
+func (c *Cache) getStaleMirrors(path string) ([]Mirror, bool) {
+	ttl := time.Duration(GetConfig().SelectionCacheTTL) * time.Second
+	v, ok := c.sCache.Get(path)
+	if !ok || ttl == 0 {
+		return nil, false
+	}
+	s := v.(*selectionValue)
+	if time.Since(s.fetched) > ttl {
+		return nil, false
+	}
+	return append([]Mirror(nil), s.value...), true
+}
+
+// isUnavailable returns true if the error isn't a reply of the database
+func isUnavailable(err error) bool {
+	if err == redis.ErrNil {
+		return false
+	}
+	_, ok := err.(redis.Error)
+	return !ok
+}
+
+func (c *Cache) getMirrors(ctx context.Context, path string) (mirrors []Mirror, err error) {
 	var mirrorsIDs []int
 	v, ok := c.fmCache.Get(path)
 	if ok {
 		mirrorsIDs = v.(*fileMirrorValue).value
 	} else {
-		mirrorsIDs, err = c.fetchFileMirrors(path)
+		mirrorsIDs, err = c.fetchFileMirrors(ctx, path)
 		if err != nil {
 			return
 		}
@@ -182,7 +332,7 @@ func (c *Cache) GetMirrors(path string, clientInfo network.GeoIPRecord) (mirrors
 			mirror = v.(*mirrorValue).value
 		} else {
 			//TODO execute missing items in a MULTI query
-			mirror, err = c.fetchMirror(id)
+			mirror, err = c.fetchMirror(ctx, id)
 			if err != nil {
 				return
 			}
@@ -191,7 +341,7 @@ func (c *Cache) GetMirrors(path string, clientInfo network.GeoIPRecord) (mirrors
 		if ok {
 			fileInfo = v.(*fileInfoValue).value
 		} else {
-			fileInfo, err = c.fetchFileInfoMirror(id, path)
+			fileInfo, err = c.fetchFileInfoMirror(ctx, id, path)
 			if err != nil {
 				return
 			}
@@ -203,23 +353,16 @@ func (c *Cache) GetMirrors(path string, clientInfo network.GeoIPRecord) (mirrors
 		// Add the path in the results so we can access it from the templates
 		mirror.FileInfo.Path = path
 
-		if clientInfo.IsValid() {
-			mirror.Distance = utils.GetDistanceKm(clientInfo.Latitude,
-				clientInfo.Longitude,
-				mirror.Latitude,
-				mirror.Longitude)
-		} else {
-			mirror.Distance = 0
-		}
 		mirrors = append(mirrors, mirror)
 	}
 	return
 }
 
-func (c *Cache) fetchFileMirrors(path string) (ids []int, err error) {
-	rconn := c.r.Get()
+func (c *Cache) fetchFileMirrors(ctx context.Context, path string) (ids []int, err error) {
+	key := fmt.Sprintf("FILEMIRRORS_%s", path)
+	rconn := c.getConn(ctx, key)
 	defer rconn.Close()
-	ids, err = redis.Ints(rconn.Do("SMEMBERS", fmt.Sprintf("FILEMIRRORS_%s", path)))
+	ids, err = redis.Ints(rconn.Do("SMEMBERS", key))
 	if err != nil {
 		return
 	}
@@ -227,10 +370,11 @@ func (c *Cache) fetchFileMirrors(path string) (ids []int, err error) {
 	return
 }
 
-func (c *Cache) fetchMirror(mirrorID int) (mirror Mirror, err error) {
-	rconn := c.r.Get()
+func (c *Cache) fetchMirror(ctx context.Context, mirrorID int) (mirror Mirror, err error) {
+	key := fmt.Sprintf("MIRROR_%d", mirrorID)
+	rconn := c.getConn(ctx, key)
 	defer rconn.Close()
-	reply, err := redis.Values(rconn.Do("HGETALL", fmt.Sprintf("MIRROR_%d", mirrorID)))
+	reply, err := redis.Values(rconn.Do("HGETALL", key))
 	if err != nil {
 		return
 	}
@@ -254,7 +398,7 @@ func (c *Cache) GetFileInfoMirror(mirrorID int, path string) (f filesystem.FileI
 	if ok {
 		fileInfo = v.(*fileInfoValue).value
 	} else {
-		fileInfo, err = c.fetchFileInfoMirror(mirrorID, path)
+		fileInfo, err = c.fetchFileInfoMirror(context.Background(), mirrorID, path)
 		if err != nil {
 			return
 		}
@@ -262,12 +406,13 @@ func (c *Cache) GetFileInfoMirror(mirrorID int, path string) (f filesystem.FileI
 	return fileInfo, nil
 }
 
-func (c *Cache) fetchFileInfoMirror(id int, path string) (f filesystem.FileInfo, err error) {
-	rconn := c.r.Get()
+func (c *Cache) fetchFileInfoMirror(ctx context.Context, id int, path string) (f filesystem.FileInfo, err error) {
+	key := fmt.Sprintf("FILEINFO_%d_%s", id, path)
+	rconn := c.getConn(ctx, key)
 	defer rconn.Close()
 	f.Path = path // Path is not stored in the object instance in redis
 
-	reply, err := redis.Strings(rconn.Do("HMGET", fmt.Sprintf("FILEINFO_%d_%s", id, path), "size", "modTime", "sha1", "sha256", "md5"))
+	reply, err := redis.Strings(rconn.Do("HMGET", key, "size", "modTime", "sha1", "sha256", "md5", "sha512"))
 	if err != nil {
 		return
 	}
@@ -280,6 +425,7 @@ func (c *Cache) fetchFileInfoMirror(id int, path string) (f filesystem.FileInfo,
 	f.Sha1 = reply[2]
 	f.Sha256 = reply[3]
 	f.Md5 = reply[4]
+	f.Sha512 = reply[5]
 
 	c.fimCache.Set(fmt.Sprintf("%d|%s", id, path), &fileInfoValue{value: f})
 	return
@@ -292,7 +438,7 @@ func (c *Cache) GetMirror(id int) (mirror Mirror, err error) {
 	if ok {
 		mirror = v.(*mirrorValue).value
 	} else {
-		mirror, err = c.fetchMirror(id)
+		mirror, err = c.fetchMirror(context.Background(), id)
 		if err != nil {
 			return
 		}
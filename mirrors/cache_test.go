@@ -4,6 +4,7 @@
 package mirrors
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"strconv"
@@ -11,12 +12,18 @@ import (
 	"time"
 	"unsafe"
 
+	. "github.com/etix/mirrorbits/config"
 	"github.com/etix/mirrorbits/filesystem"
 	"github.com/etix/mirrorbits/network"
 	. "github.com/etix/mirrorbits/testing"
 	_ "github.com/rafaeljusto/redigomock"
 )
 
+func TestMain(m *testing.M) {
+	SetConfiguration(&Configuration{})
+	m.Run()
+}
+
 func TestNewCache(t *testing.T) {
 	_, conn := PrepareRedisTest()
 	conn.ConnectPubsub()
@@ -67,6 +74,36 @@ func TestCache_Clear(t *testing.T) {
 	}
 }
 
+func TestCache_recentlyInvalidated(t *testing.T) {
+	_, conn := PrepareRedisTest()
+	conn.ConnectPubsub()
+
+	c := NewCache(conn)
+
+	if c.recentlyInvalidated("MIRROR_1") {
+		t.Fatalf("No key should be invalidated")
+	}
+
+	c.invalidate("MIRROR_1", "FILE_/test")
+	if !c.recentlyInvalidated("MIRROR_1") || !c.recentlyInvalidated("FILE_/test") {
+		t.Fatalf("The keys should be invalidated")
+	}
+	if c.recentlyInvalidated("MIRROR_2") {
+		t.Fatalf("Only the invalidated keys should be read from the primary")
+	}
+
+	// The expired keys are swept on the next invalidation
+	c.invalidatedKeys["MIRROR_1"] = time.Now().Add(-replicaLagGrace)
+	c.lastSweep = time.Time{}
+	c.invalidate("MIRROR_3")
+	if c.recentlyInvalidated("MIRROR_1") {
+		t.Fatalf("The grace of the key should be over")
+	}
+	if _, ok := c.invalidatedKeys["MIRROR_1"]; ok {
+		t.Fatalf("The expired key should have been swept")
+	}
+}
+
 func assertFileInfoEqual(t *testing.T, actual *filesystem.FileInfo, expected *filesystem.FileInfo) {
 	t.Helper()
 	if actual.Path != expected.Path {
@@ -87,6 +124,9 @@ func assertFileInfoEqual(t *testing.T, actual *filesystem.FileInfo, expected *fi
 	if actual.Md5 != expected.Md5 {
 		t.Fatalf("Md5 doesn't match, expected %#v got %#v", expected.Md5, actual.Md5)
 	}
+	if actual.Sha512 != expected.Sha512 {
+		t.Fatalf("Sha512 doesn't match, expected %#v got %#v", expected.Sha512, actual.Sha512)
+	}
 }
 
 func TestCache_fetchFileInfo(t *testing.T) {
@@ -101,23 +141,25 @@ func TestCache_fetchFileInfo(t *testing.T) {
 		ModTime: time.Now(),
 		Sha1:    "3ce963aea2d6f23fe915063f8bba21888db0ddfa",
 		Sha256:  "1c8e38c7e03e4d117eba4f82afaf6631a9b79f4c1e9dec144d4faf1d109aacda",
+		Sha512:  "7d7e781b7d9cd5ab8ff0ece4ee317a2d1b1b1855118cc0ae7cb1dc7fdb97a3e5ad7e3c2fd3b6e9c5428ee4f8cd8524909bc4c88bc5102ee71d4ec1bc20ac5ec0",
 		Md5:     "2c98ec39f49da6ddd9cfa7b1d7342afe",
 	}
 
-	f, err := c.fetchFileInfo(testfile.Path)
+	f, err := c.fetchFileInfo(context.Background(), testfile.Path)
 	if err == nil {
 		t.Fatalf("Error expected, mock command not yet registered")
 	}
 
-	cmdGetFileinfo := mock.Command("HMGET", "FILE_"+testfile.Path, "size", "modTime", "sha1", "sha256", "md5").Expect([]any{
+	cmdGetFileinfo := mock.Command("HMGET", "FILE_"+testfile.Path, "size", "modTime", "sha1", "sha256", "md5", "sha512").Expect([]any{
 		[]byte(strconv.FormatInt(testfile.Size, 10)),
 		[]byte(testfile.ModTime.Format("2006-01-02 15:04:05.999999999 -0700 MST")),
 		[]byte(testfile.Sha1),
 		[]byte(testfile.Sha256),
 		[]byte(testfile.Md5),
+		[]byte(testfile.Sha512),
 	})
 
-	f, err = c.fetchFileInfo(testfile.Path)
+	f, err = c.fetchFileInfo(context.Background(), testfile.Path)
 	if err != nil {
 		t.Fatalf("Unexpected error: %s", err.Error())
 	}
@@ -149,12 +191,13 @@ func TestCache_fetchFileInfo_non_existing(t *testing.T) {
 		Md5:     "",
 	}
 
-	f, err := c.fetchFileInfo(testfile.Path)
+	f, err := c.fetchFileInfo(context.Background(), testfile.Path)
 	if err == nil {
 		t.Fatalf("Error expected, mock command not yet registered")
 	}
 
-	cmdGetFileinfo := mock.Command("HMGET", "FILE_"+testfile.Path, "size", "modTime", "sha1", "sha256", "md5").Expect([]any{
+	cmdGetFileinfo := mock.Command("HMGET", "FILE_"+testfile.Path, "size", "modTime", "sha1", "sha256", "md5", "sha512").Expect([]any{
+		[]byte(""),
 		[]byte(""),
 		[]byte(""),
 		[]byte(""),
@@ -162,7 +205,7 @@ func TestCache_fetchFileInfo_non_existing(t *testing.T) {
 		[]byte(""),
 	})
 
-	f, err = c.fetchFileInfo(testfile.Path)
+	f, err = c.fetchFileInfo(context.Background(), testfile.Path)
 	// fetchFileInfo on a non-existing file doesn't yield Redis.ErrNil
 	if err != nil {
 		t.Fatalf("Unexpected error: %s", err.Error())
@@ -193,23 +236,25 @@ func TestCache_GetFileInfo(t *testing.T) {
 		ModTime: time.Now(),
 		Sha1:    "3ce963aea2d6f23fe915063f8bba21888db0ddfa",
 		Sha256:  "1c8e38c7e03e4d117eba4f82afaf6631a9b79f4c1e9dec144d4faf1d109aacda",
+		Sha512:  "7d7e781b7d9cd5ab8ff0ece4ee317a2d1b1b1855118cc0ae7cb1dc7fdb97a3e5ad7e3c2fd3b6e9c5428ee4f8cd8524909bc4c88bc5102ee71d4ec1bc20ac5ec0",
 		Md5:     "2c98ec39f49da6ddd9cfa7b1d7342afe",
 	}
 
-	_, err := c.GetFileInfo(testfile.Path)
+	_, err := c.GetFileInfo(context.Background(), testfile.Path)
 	if err == nil {
 		t.Fatalf("Error expected, mock command not yet registered")
 	}
 
-	cmdGetFileinfo := mock.Command("HMGET", "FILE_"+testfile.Path, "size", "modTime", "sha1", "sha256", "md5").Expect([]any{
+	cmdGetFileinfo := mock.Command("HMGET", "FILE_"+testfile.Path, "size", "modTime", "sha1", "sha256", "md5", "sha512").Expect([]any{
 		[]byte(strconv.FormatInt(testfile.Size, 10)),
 		[]byte(testfile.ModTime.Format("2006-01-02 15:04:05.999999999 -0700 MST")),
 		[]byte(testfile.Sha1),
 		[]byte(testfile.Sha256),
 		[]byte(testfile.Md5),
+		[]byte(testfile.Sha512),
 	})
 
-	f, err := c.GetFileInfo(testfile.Path)
+	f, err := c.GetFileInfo(context.Background(), testfile.Path)
 	if err != nil {
 		t.Fatalf("Unexpected error: %s", err.Error())
 	}
@@ -220,7 +265,7 @@ func TestCache_GetFileInfo(t *testing.T) {
 
 	assertFileInfoEqual(t, &f, &testfile)
 
-	f, err = c.GetFileInfo(testfile.Path)
+	f, err = c.GetFileInfo(context.Background(), testfile.Path)
 	if err != nil {
 		t.Fatalf("Unexpected error: %s", err.Error())
 	}
@@ -246,12 +291,13 @@ func TestCache_GetFileInfo_non_existing(t *testing.T) {
 		Md5:     "",
 	}
 
-	_, err := c.GetFileInfo(testfile.Path)
+	_, err := c.GetFileInfo(context.Background(), testfile.Path)
 	if err == nil {
 		t.Fatalf("Error expected, mock command not yet registered")
 	}
 
-	cmdGetFileinfo := mock.Command("HMGET", "FILE_"+testfile.Path, "size", "modTime", "sha1", "sha256", "md5").Expect([]any{
+	cmdGetFileinfo := mock.Command("HMGET", "FILE_"+testfile.Path, "size", "modTime", "sha1", "sha256", "md5", "sha512").Expect([]any{
+		[]byte(""),
 		[]byte(""),
 		[]byte(""),
 		[]byte(""),
@@ -259,7 +305,7 @@ func TestCache_GetFileInfo_non_existing(t *testing.T) {
 		[]byte(""),
 	})
 
-	f, err := c.GetFileInfo(testfile.Path)
+	f, err := c.GetFileInfo(context.Background(), testfile.Path)
 	// GetFileInfo on a non-existing file doesn't yield Redis.ErrNil
 	if err != nil {
 		t.Fatalf("Unexpected error: %s", err.Error())
@@ -271,7 +317,7 @@ func TestCache_GetFileInfo_non_existing(t *testing.T) {
 
 	assertFileInfoEqual(t, &f, &testfile)
 
-	f, err = c.GetFileInfo(testfile.Path)
+	f, err = c.GetFileInfo(context.Background(), testfile.Path)
 	if err != nil {
 		t.Fatalf("Unexpected error: %s", err.Error())
 	}
@@ -290,7 +336,7 @@ func TestCache_fetchFileMirrors(t *testing.T) {
 	c := NewCache(conn)
 	filename := "/test/file.tgz"
 
-	_, err := c.fetchFileMirrors(filename)
+	_, err := c.fetchFileMirrors(context.Background(), filename)
 	if err == nil {
 		t.Fatalf("Error expected, mock command not yet registered")
 	}
@@ -301,7 +347,7 @@ func TestCache_fetchFileMirrors(t *testing.T) {
 		[]byte("5"),
 	})
 
-	ids, err := c.fetchFileMirrors(filename)
+	ids, err := c.fetchFileMirrors(context.Background(), filename)
 	if err != nil {
 		t.Fatalf("Unexpected error: %s", err.Error())
 	}
@@ -353,7 +399,7 @@ func TestCache_fetchMirror(t *testing.T) {
 		HttpsUp:        true,
 	}
 
-	_, err := c.fetchMirror(testmirror.ID)
+	_, err := c.fetchMirror(context.Background(), testmirror.ID)
 	if err == nil {
 		t.Fatalf("Error expected, mock command not yet registered")
 	}
@@ -385,7 +431,7 @@ func TestCache_fetchMirror(t *testing.T) {
 		"httpsUp":       strconv.FormatBool(testmirror.HttpsUp),
 	})
 
-	m, err := c.fetchMirror(testmirror.ID)
+	m, err := c.fetchMirror(context.Background(), testmirror.ID)
 	if err != nil {
 		t.Fatalf("Unexpected error: %s", err.Error())
 	}
@@ -419,23 +465,25 @@ func TestCache_fetchFileInfoMirror(t *testing.T) {
 		ModTime: time.Now(),
 		Sha1:    "3ce963aea2d6f23fe915063f8bba21888db0ddfa",
 		Sha256:  "1c8e38c7e03e4d117eba4f82afaf6631a9b79f4c1e9dec144d4faf1d109aacda",
+		Sha512:  "7d7e781b7d9cd5ab8ff0ece4ee317a2d1b1b1855118cc0ae7cb1dc7fdb97a3e5ad7e3c2fd3b6e9c5428ee4f8cd8524909bc4c88bc5102ee71d4ec1bc20ac5ec0",
 		Md5:     "2c98ec39f49da6ddd9cfa7b1d7342afe",
 	}
 
-	_, err := c.fetchFileInfoMirror(1, testfile.Path)
+	_, err := c.fetchFileInfoMirror(context.Background(), 1, testfile.Path)
 	if err == nil {
 		t.Fatalf("Error expected, mock command not yet registered")
 	}
 
-	cmdGetFileinfomirror := mock.Command("HMGET", "FILEINFO_1_"+testfile.Path, "size", "modTime", "sha1", "sha256", "md5").Expect([]any{
+	cmdGetFileinfomirror := mock.Command("HMGET", "FILEINFO_1_"+testfile.Path, "size", "modTime", "sha1", "sha256", "md5", "sha512").Expect([]any{
 		[]byte(strconv.FormatInt(testfile.Size, 10)),
 		[]byte(testfile.ModTime.String()),
 		[]byte(testfile.Sha1),
 		[]byte(testfile.Sha256),
 		[]byte(testfile.Md5),
+		[]byte(testfile.Sha512),
 	})
 
-	_, err = c.fetchFileInfoMirror(1, testfile.Path)
+	_, err = c.fetchFileInfoMirror(context.Background(), 1, testfile.Path)
 	if err != nil {
 		t.Fatalf("Unexpected error: %s", err.Error())
 	}
@@ -494,6 +542,10 @@ func TestCache_GetMirrors(t *testing.T) {
 
 	c := NewCache(conn)
 
+	SetConfiguration(&Configuration{
+		DistanceRoundingKm: 10,
+	})
+
 	filename := "/test/file.tgz"
 
 	clientInfo := network.GeoIPRecord{
@@ -502,7 +554,7 @@ func TestCache_GetMirrors(t *testing.T) {
 		Longitude:   2.3508,
 	}
 
-	_, err := c.GetMirrors(filename, clientInfo)
+	_, err := c.GetMirrors(context.Background(), filename, clientInfo)
 	if err == nil {
 		t.Fatalf("Error expected, mock command not yet registered")
 	}
@@ -524,23 +576,25 @@ func TestCache_GetMirrors(t *testing.T) {
 		"longitude": "0.1275",
 	})
 
-	cmdGetFileinfomirrorM1 := mock.Command("HMGET", "FILEINFO_1_"+filename, "size", "modTime", "sha1", "sha256", "md5").Expect([]any{
+	cmdGetFileinfomirrorM1 := mock.Command("HMGET", "FILEINFO_1_"+filename, "size", "modTime", "sha1", "sha256", "md5", "sha512").Expect([]any{
 		[]byte("44000"),
 		[]byte(""),
 		[]byte(""),
 		[]byte(""),
 		[]byte(""),
+		[]byte(""),
 	})
 
-	cmdGetFileinfomirrorM2 := mock.Command("HMGET", "FILEINFO_2_"+filename, "size", "modTime", "sha1", "sha256", "md5").Expect([]any{
+	cmdGetFileinfomirrorM2 := mock.Command("HMGET", "FILEINFO_2_"+filename, "size", "modTime", "sha1", "sha256", "md5", "sha512").Expect([]any{
 		[]byte("44000"),
 		[]byte(""),
 		[]byte(""),
 		[]byte(""),
 		[]byte(""),
+		[]byte(""),
 	})
 
-	mirrors, err := c.GetMirrors(filename, clientInfo)
+	mirrors, err := c.GetMirrors(context.Background(), filename, clientInfo)
 	if err != nil {
 		t.Fatalf("Unexpected error: %s", err.Error())
 	}
@@ -565,11 +619,105 @@ func TestCache_GetMirrors(t *testing.T) {
 		t.Fatalf("Invalid number of mirrors returned")
 	}
 
-	if int(mirrors[0].Distance) != int(876) {
-		t.Fatalf("Distance between user and m1 is wrong, got %d, expected 876", int(mirrors[0].Distance))
+	// The distances are rounded to the nearest 10km
+	if int(mirrors[0].Distance) != int(880) {
+		t.Fatalf("Distance between user and m1 is wrong, got %d, expected 880", int(mirrors[0].Distance))
 	}
 
-	if int(mirrors[1].Distance) != int(334) {
-		t.Fatalf("Distance between user and m2 is wrong, got %d, expected 334", int(mirrors[1].Distance))
+	if int(mirrors[1].Distance) != int(330) {
+		t.Fatalf("Distance between user and m2 is wrong, got %d, expected 330", int(mirrors[1].Distance))
+	}
+}
+
+func TestCache_GetMirrors_unavailable(t *testing.T) {
+	defer SetConfiguration(GetConfig())
+	SetConfiguration(&Configuration{
+		SelectionCacheTTL:        60,
+		SelectionCacheMaxEntries: 10,
+	})
+
+	mock, conn := PrepareRedisTest()
+	conn.ConnectPubsub()
+
+	c := NewCache(conn)
+
+	filename := "/test/file.tgz"
+
+	// populate fills the selection cache and simulates an outage of the
+	// database right after
+	populate := func() {
+		mock.Command("SMEMBERS", "FILEMIRRORS_"+filename).Expect([]any{
+			[]byte("1"),
+		})
+		mock.Command("HGETALL", "MIRROR_1").ExpectMap(map[string]string{
+			"ID": "1",
+		})
+		mock.Command("HMGET", "FILEINFO_1_"+filename, "size", "modTime", "sha1", "sha256", "md5", "sha512").Expect([]any{
+			[]byte("44000"),
+			[]byte(""),
+			[]byte(""),
+			[]byte(""),
+			[]byte(""),
+			[]byte(""),
+		})
+
+		if _, err := c.GetMirrors(context.Background(), filename, network.GeoIPRecord{}); err != nil {
+			t.Fatalf("Unexpected error: %s", err.Error())
+		}
+
+		mock.Clear()
+		c.fmCache.Clear()
+		c.mCache.Clear()
+		c.fimCache.Clear()
+	}
+
+	populate()
+
+	mirrors, err := c.GetMirrors(context.Background(), filename, network.GeoIPRecord{})
+	if err != nil {
+		t.Fatalf("Expected the mirrors to be served from the selection cache, got %s", err.Error())
+	}
+	if len(mirrors) != 1 || mirrors[0].ID != 1 {
+		t.Fatalf("Invalid mirrors returned from the selection cache")
+	}
+
+	if _, err := c.GetMirrors(context.Background(), "/test/other.tgz", network.GeoIPRecord{}); err == nil {
+		t.Fatalf("Error expected, the file is not in the selection cache")
+	}
+
+	// The entries are invalidated by the events of the file, the stale
+	// selection is then no longer served
+	events := map[string]struct {
+		channel chan string
+		data    string
+	}{
+		"file update":        {c.fileUpdateEvent, filename},
+		"mirror file update": {c.mirrorFileUpdateEvent, "1 " + filename},
+	}
+	for name, event := range events {
+		populate()
+		event.channel <- event.data
+
+		deadline := time.Now().Add(time.Second)
+		for {
+			if _, ok := c.sCache.Get(filename); !ok {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%s: expected the selection to be invalidated", name)
+			}
+			time.Sleep(time.Millisecond)
+		}
+		if _, err := c.GetMirrors(context.Background(), filename, network.GeoIPRecord{}); err == nil {
+			t.Fatalf("%s: error expected, the selection was invalidated", name)
+		}
+	}
+
+	// The entries expire after SelectionCacheTTL
+	populate()
+	v, _ := c.sCache.Get(filename)
+	v.(*selectionValue).fetched = time.Now().Add(-61 * time.Second)
+	if _, err := c.GetMirrors(context.Background(), filename, network.GeoIPRecord{}); err == nil {
+		t.Fatalf("Error expected, the entry has expired")
 	}
 }
@@ -0,0 +1,177 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package mirrors
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/etix/mirrorbits/core"
+	"github.com/etix/mirrorbits/database"
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	// DiffSampleSize is the maximum number of paths reported for each kind
+	// of difference, unless the full lists are requested
+	DiffSampleSize = 10
+
+	// DiffFullMaxSize is the maximum number of paths reported for each kind
+	// of difference when the full lists are requested, keeping the reply
+	// within the size limit of the RPC messages
+	DiffFullMaxSize = 5000
+
+	// diffBatch is the number of files whose properties are fetched at once
+	// from the database
+	diffBatch = 1000
+)
+
+// IndexDiff holds the differences between the file indexes of two mirrors
+type IndexDiff struct {
+	Common        int64
+	OnlyA         int64
+	OnlyB         int64
+	Changed       int64
+	SampleOnlyA   []string
+	SampleOnlyB   []string
+	SampleChanged []string
+}
+
+// indexedMirror is the part of a mirror needed to compare its files
+type indexedMirror struct {
+	TZOffset  int64
+	Precision core.Precision
+}
+
+// DiffMirrors compares the file indexes of the mirrors a and b, as recorded
+// by their last scans: the files found on one of them only, and the files
+// found on both with a different size or modification time. Up to
+// DiffSampleSize paths are returned for each, or DiffFullMaxSize if full is
+// set.
+func DiffMirrors(r *database.Redis, a, b int, full bool) (*IndexDiff, error) {
+	conn := r.Get()
+	defer conn.Close()
+
+	var infos [2]indexedMirror
+	for i, id := range []int{a, b} {
+		values, err := redis.Values(conn.Do("HMGET", fmt.Sprintf("MIRROR_%d", id), "tzoffset", "lastSuccessfulSyncPrecision"))
+		if err != nil {
+			return nil, err
+		}
+		// Both are unset until the first scan
+		infos[i].TZOffset, _ = redis.Int64(values[0], nil)
+		precision, _ := redis.Int64(values[1], nil)
+		infos[i].Precision = core.Precision(precision)
+	}
+
+	filesA, err := redis.Strings(conn.Do("SMEMBERS", fmt.Sprintf("MIRRORFILES_%d", a)))
+	if err != nil {
+		return nil, err
+	}
+	filesB, err := redis.Strings(conn.Do("SMEMBERS", fmt.Sprintf("MIRRORFILES_%d", b)))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(filesA)
+	sort.Strings(filesB)
+
+	sample := func(list []string, p string) []string {
+		if len(list) < DiffSampleSize || full && len(list) < DiffFullMaxSize {
+			return append(list, p)
+		}
+		return list
+	}
+
+	diff := &IndexDiff{}
+	inA := make(map[string]bool, len(filesA))
+	for _, p := range filesA {
+		inA[p] = true
+	}
+	inB := make(map[string]bool, len(filesB))
+	var common []string
+	for _, p := range filesB {
+		inB[p] = true
+		if inA[p] {
+			common = append(common, p)
+		} else {
+			diff.OnlyB++
+			diff.SampleOnlyB = sample(diff.SampleOnlyB, p)
+		}
+	}
+	for _, p := range filesA {
+		if !inB[p] {
+			diff.OnlyA++
+			diff.SampleOnlyA = sample(diff.SampleOnlyA, p)
+		}
+	}
+	diff.Common = int64(len(common))
+
+	// Compare the properties of the files found on both
+	for len(common) > 0 {
+		batch := common
+		if len(batch) > diffBatch {
+			batch = batch[:diffBatch]
+		}
+		common = common[len(batch):]
+
+		for _, p := range batch {
+			conn.Send("HMGET", fmt.Sprintf("FILEINFO_%d_%s", a, p), "size", "modTime")
+			conn.Send("HMGET", fmt.Sprintf("FILEINFO_%d_%s", b, p), "size", "modTime")
+		}
+		if err = conn.Flush(); err != nil {
+			return nil, err
+		}
+		for _, p := range batch {
+			fileA, err := redis.Strings(conn.Receive())
+			if err != nil {
+				return nil, err
+			}
+			fileB, err := redis.Strings(conn.Receive())
+			if err != nil {
+				return nil, err
+			}
+			if filesDiffer(fileA, fileB, infos) {
+				diff.Changed++
+				diff.SampleChanged = sample(diff.SampleChanged, p)
+			}
+		}
+	}
+
+	return diff, nil
+}
+
+// filesDiffer returns true if the sizes or the modification times of a file
+// differ on two mirrors, the times being compared with the precision of the
+// least precise mirror
+func filesDiffer(a, b []string, infos [2]indexedMirror) bool {
+	if len(a) < 2 || len(b) < 2 {
+		return true
+	}
+	if a[0] != b[0] {
+		return true
+	}
+
+	var precision time.Duration
+	var modTimes [2]time.Time
+	for i, v := range []string{a[1], b[1]} {
+		modTimes[i], _ = time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", v)
+		if modTimes[i].IsZero() {
+			// Unknown, only the size can be compared
+			return false
+		}
+		if GetConfig().FixTimezoneOffsets {
+			modTimes[i] = modTimes[i].Add(time.Duration(infos[i].TZOffset) * time.Millisecond)
+		}
+		p := infos[i].Precision.Duration()
+		if p == 0 {
+			p = time.Second
+		}
+		if p > precision {
+			precision = p
+		}
+	}
+	return !modTimes[0].Truncate(precision).Equal(modTimes[1].Truncate(precision))
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package mirrors
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/etix/mirrorbits/core"
+	. "github.com/etix/mirrorbits/testing"
+)
+
+func TestDiffMirrors(t *testing.T) {
+	mock, conn := PrepareRedisTest()
+
+	modTime := "2025-06-01 06:00:00.123456789 +0000 UTC"
+	mock.Command("HMGET", "MIRROR_1", "tzoffset", "lastSuccessfulSyncPrecision").Expect([]any{nil, []byte("1000000000")})
+	mock.Command("HMGET", "MIRROR_2", "tzoffset", "lastSuccessfulSyncPrecision").Expect([]any{nil, nil})
+	mock.Command("SMEMBERS", "MIRRORFILES_1").Expect([]any{[]byte("/same"), []byte("/size"), []byte("/a1"), []byte("/a2")})
+	mock.Command("SMEMBERS", "MIRRORFILES_2").Expect([]any{[]byte("/b1"), []byte("/size"), []byte("/same")})
+	for _, id := range []int{1, 2} {
+		mock.Command("HMGET", fmt.Sprintf("FILEINFO_%d_/same", id), "size", "modTime").Expect([]any{[]byte("42"), []byte(modTime)})
+		mock.Command("HMGET", fmt.Sprintf("FILEINFO_%d_/size", id), "size", "modTime").Expect([]any{[]byte(fmt.Sprint(id)), []byte(modTime)})
+	}
+
+	diff, err := DiffMirrors(conn, 1, 2, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if diff.Common != 2 || diff.Changed != 1 || diff.OnlyA != 2 || diff.OnlyB != 1 {
+		t.Fatalf("Unexpected diff %+v", diff)
+	}
+	if fmt.Sprint(diff.SampleOnlyA) != "[/a1 /a2]" || fmt.Sprint(diff.SampleOnlyB) != "[/b1]" || fmt.Sprint(diff.SampleChanged) != "[/size]" {
+		t.Fatalf("Unexpected samples %+v", diff)
+	}
+}
+
+func TestFilesDiffer(t *testing.T) {
+	seconds := [2]indexedMirror{{Precision: core.Precision(time.Second)}, {Precision: core.Precision(time.Second)}}
+	millis := [2]indexedMirror{{Precision: core.Precision(time.Millisecond)}, {Precision: core.Precision(time.Millisecond)}}
+
+	t1 := "2025-06-01 06:00:00.123 +0000 UTC"
+	t2 := "2025-06-01 06:00:00.456 +0000 UTC"
+	t3 := "2025-06-01 06:00:01 +0000 UTC"
+
+	tests := map[string]struct {
+		a, b     []string
+		infos    [2]indexedMirror
+		expected bool
+	}{
+		"same":              {[]string{"42", t1}, []string{"42", t1}, millis, false},
+		"size":              {[]string{"42", t1}, []string{"43", t1}, seconds, true},
+		"within precision":  {[]string{"42", t1}, []string{"42", t2}, seconds, false},
+		"beyond precision":  {[]string{"42", t1}, []string{"42", t2}, millis, true},
+		"mod time":          {[]string{"42", t1}, []string{"42", t3}, seconds, true},
+		"unknown mod time":  {[]string{"42", ""}, []string{"42", t3}, seconds, false},
+		"missing file info": {[]string{"42", t1}, nil, seconds, true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if differ := filesDiffer(test.a, test.b, test.infos); differ != test.expected {
+				t.Fatalf("Expected %t, got %t", test.expected, differ)
+			}
+		})
+	}
+}
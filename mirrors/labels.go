@@ -0,0 +1,110 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package mirrors
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Labels holds the free-form key/value labels of a mirror. They are only
+// meant to sort the mirrors out (i.e. by provider), the selection ignores
+// them.
+type Labels map[string]string
+
+// ParseLabels parses a list of labels separated by commas, like
+// "provider=acme,rack=b2"
+func ParseLabels(s string) (Labels, error) {
+	labels := make(Labels)
+	for _, l := range strings.Split(s, ",") {
+		l = strings.TrimSpace(l)
+		if l == "" {
+			continue
+		}
+		key, value, found := strings.Cut(l, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid label %q, expected key=value", l)
+		}
+		key = strings.TrimSpace(key)
+		if err := checkLabelKey(key); err != nil {
+			return nil, err
+		}
+		labels[key] = strings.TrimSpace(value)
+	}
+	if len(labels) == 0 {
+		return nil, nil
+	}
+	return labels, nil
+}
+
+// Validate checks the keys of the labels
+func (l Labels) Validate() error {
+	for key := range l {
+		if err := checkLabelKey(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Match returns true if the labels hold all the given ones
+func (l Labels) Match(filter Labels) bool {
+	for key, value := range filter {
+		if v, ok := l[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the labels sorted by key in the format of ParseLabels
+func (l Labels) String() string {
+	keys := make([]string, 0, len(l))
+	for key := range l {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for i, key := range keys {
+		keys[i] = key + "=" + l[key]
+	}
+	return strings.Join(keys, ",")
+}
+
+// checkLabelKey validates the key of a label: letters, digits, dots,
+// dashes and underscores only
+func checkLabelKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("empty label key")
+	}
+	for _, r := range key {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '.' && r != '-' && r != '_' {
+			return fmt.Errorf("invalid label key %q, only letters, digits and . - _ are allowed", key)
+		}
+	}
+	return nil
+}
+
+// RedisArg serialize the labels
+func (l Labels) RedisArg() any {
+	if len(l) == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(map[string]string(l))
+	return string(b)
+}
+
+// RedisScan deserialize the labels
+func (l *Labels) RedisScan(src any) error {
+	b, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("cannot convert from %T to %T", src, l)
+	}
+	if len(b) == 0 {
+		*l = nil
+		return nil
+	}
+	return json.Unmarshal(b, (*map[string]string)(l))
+}
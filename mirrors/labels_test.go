@@ -0,0 +1,65 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package mirrors
+
+import (
+	"testing"
+)
+
+func TestParseLabels(t *testing.T) {
+	tests := map[string]struct {
+		labels string
+		valid  bool
+		want   string
+	}{
+		"empty":       {"", true, ""},
+		"single":      {"provider=acme", true, "provider=acme"},
+		"multiple":    {"rack=b2, provider=acme", true, "provider=acme,rack=b2"},
+		"empty value": {"tag=", true, "tag="},
+		"charset":     {"k8s.io_zone-1=eu west", true, "k8s.io_zone-1=eu west"},
+		"no value":    {"provider", false, ""},
+		"no key":      {"=acme", false, ""},
+		"bad key":     {"pro vider=acme", false, ""},
+		"bad char":    {"provider/x=acme", false, ""},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			l, err := ParseLabels(test.labels)
+			if test.valid && err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if !test.valid && err == nil {
+				t.Fatalf("Expected an error for %q", test.labels)
+			}
+			if got := l.String(); got != test.want {
+				t.Fatalf("Expected %q, got %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestLabels_Match(t *testing.T) {
+	labels := Labels{"provider": "acme", "rack": "b2"}
+
+	tests := map[string]struct {
+		filter Labels
+		match  bool
+	}{
+		"no filter":     {nil, true},
+		"single":        {Labels{"provider": "acme"}, true},
+		"all":           {Labels{"provider": "acme", "rack": "b2"}, true},
+		"other value":   {Labels{"provider": "other"}, false},
+		"missing label": {Labels{"zone": "eu"}, false},
+		"empty value":   {Labels{"zone": ""}, false},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := labels.Match(test.filter); got != test.match {
+				t.Fatalf("Expected %v, got %v", test.match, got)
+			}
+		})
+	}
+}
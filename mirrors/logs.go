@@ -225,6 +225,8 @@ func (l *LogScanStarted) GetOutput() string {
 		return "RSYNC scan started"
 	case core.FTP:
 		return "FTP scan started"
+	case core.LOCAL:
+		return "Local scan started"
 	default:
 		return "Scan started using a unknown protocol"
 	}
@@ -246,11 +248,20 @@ type LogScanCompleted struct {
 	FilesIndexed int64
 	KnownIndexed int64
 	Removed      int64
+	Suspect      int64 `json:",omitempty"`
 	TZOffset     int64
+	MaxFiles     int64 `json:",omitempty"`
+	Truncated    bool  `json:",omitempty"`
 }
 
 func (l *LogScanCompleted) GetOutput() string {
 	output := fmt.Sprintf("Scan completed: %d files (%d known), %d removed", l.FilesIndexed, l.KnownIndexed, l.Removed)
+	if l.Suspect > 0 {
+		output += fmt.Sprintf(", %d suspect", l.Suspect)
+	}
+	if l.Truncated {
+		output += fmt.Sprintf(", truncated to the cap of %d files", l.MaxFiles)
+	}
 	if l.TZOffset != 0 {
 		offset, _ := time.ParseDuration(fmt.Sprintf("%dms", l.TZOffset))
 		output += fmt.Sprintf(" (corrected timezone offset: %s)", offset)
@@ -258,7 +269,7 @@ func (l *LogScanCompleted) GetOutput() string {
 	return output
 }
 
-func NewLogScanCompleted(id int, files, known, removed, tzoffset int64) LogAction {
+func NewLogScanCompleted(id int, files, known, removed, suspect, tzoffset, maxFiles int64, truncated bool) LogAction {
 	return &LogScanCompleted{
 		LogCommonAction: LogCommonAction{
 			Type:      LOGTYPE_SCANCOMPLETED,
@@ -268,7 +279,10 @@ func NewLogScanCompleted(id int, files, known, removed, tzoffset int64) LogActio
 		FilesIndexed: files,
 		KnownIndexed: known,
 		Removed:      removed,
+		Suspect:      suspect,
 		TZOffset:     tzoffset,
+		MaxFiles:     maxFiles,
+		Truncated:    truncated,
 	}
 }
 
@@ -0,0 +1,71 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package mirrors
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/etix/mirrorbits/database"
+	"github.com/gomodule/redigo/redis"
+)
+
+// RecordThroughput merges a throughput sample (in bytes/s) measured at now
+// into the decaying average of the mirror and returns the new average. The
+// weight of the previous average is halved every halfLife.
+func RecordThroughput(r *database.Redis, id int, sample float64, now time.Time, halfLife time.Duration) (float64, error) {
+	return recordAverage(r, id, "throughput", sample, now, halfLife)
+}
+
+// RecordLatency merges a latency sample (the time to connect to the mirror)
+// measured at now into the decaying average of the mirror, stored in
+// milliseconds, and returns the new average
+func RecordLatency(r *database.Redis, id int, sample time.Duration, now time.Time, halfLife time.Duration) (time.Duration, error) {
+	average, err := recordAverage(r, id, "latency", float64(sample)/float64(time.Millisecond), now, halfLife)
+	return time.Duration(average * float64(time.Millisecond)), err
+}
+
+// recordAverage merges the sample into the decaying average stored in the
+// given field of the mirror, the time of its last update being stored in
+// the field suffixed with Updated
+func recordAverage(r *database.Redis, id int, field string, sample float64, now time.Time, halfLife time.Duration) (float64, error) {
+	conn := r.Get()
+	defer conn.Close()
+
+	key := fmt.Sprintf("MIRROR_%d", id)
+	values, err := redis.Values(conn.Do("HMGET", key, field, field+"Updated"))
+	if err != nil {
+		return 0, err
+	}
+	average, _ := redis.Float64(values[0], nil)
+	var updated time.Time
+	if ts, _ := redis.Int64(values[1], nil); ts > 0 {
+		updated = time.Unix(ts, 0)
+	}
+
+	average = decayAverage(average, updated, sample, now, halfLife)
+
+	_, err = conn.Do("HMSET", key, field, average, field+"Updated", Time{}.FromTime(now))
+	if err == nil {
+		database.Publish(conn, database.MIRROR_UPDATE, strconv.Itoa(id))
+	}
+	return average, err
+}
+
+// decayAverage returns the average updated at now with the given sample.
+// The previous average loses half of its weight every halfLife, the first
+// sample is taken as is.
+func decayAverage(average float64, updated time.Time, sample float64, now time.Time, halfLife time.Duration) float64 {
+	if updated.IsZero() || average <= 0 || halfLife <= 0 {
+		return sample
+	}
+	elapsed := now.Sub(updated)
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	weight := math.Pow(0.5, float64(elapsed)/float64(halfLife))
+	return average*weight + sample*(1-weight)
+}
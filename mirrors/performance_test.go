@@ -0,0 +1,40 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package mirrors
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDecayAverage(t *testing.T) {
+	now := time.Now()
+	halfLife := time.Hour
+
+	tests := []struct {
+		name     string
+		average  float64
+		updated  time.Time
+		sample   float64
+		expected float64
+	}{
+		{"first sample", 0, time.Time{}, 1000, 1000},
+		{"no elapsed time", 1000, now, 3000, 1000},
+		{"one half-life", 1000, now.Add(-time.Hour), 3000, 2000},
+		{"two half-lives", 1000, now.Add(-2 * time.Hour), 3000, 2500},
+		{"clock skew", 1000, now.Add(time.Hour), 3000, 1000},
+	}
+
+	for _, test := range tests {
+		avg := decayAverage(test.average, test.updated, test.sample, now, halfLife)
+		if math.Abs(avg-test.expected) > 0.001 {
+			t.Fatalf("%s: expected %f, got %f", test.name, test.expected, avg)
+		}
+	}
+
+	if avg := decayAverage(1000, now.Add(-time.Hour), 3000, now, 0); avg != 3000 {
+		t.Fatalf("Expected the sample to be used without half-life, got %f", avg)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package mirrors
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ScanSchedule is a list of daily time windows during which a mirror can be
+// scanned. An empty schedule allows the scans at any time.
+type ScanSchedule []scanWindow
+
+// scanWindow is a time window expressed in minutes since midnight, the
+// window wraps around midnight if the end is before the start
+type scanWindow struct {
+	start, end int
+}
+
+// ParseScanSchedule parses a schedule made of time windows separated by
+// commas, like "22:00-06:00, 12:00-13:30". The times are in the local time
+// of the server.
+func ParseScanSchedule(s string) (ScanSchedule, error) {
+	var schedule ScanSchedule
+	for _, w := range strings.Split(s, ",") {
+		w = strings.TrimSpace(w)
+		if w == "" {
+			continue
+		}
+		bounds := strings.Split(w, "-")
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("invalid time window %q, expected HH:MM-HH:MM", w)
+		}
+		start, err := parseClock(bounds[0])
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseClock(bounds[1])
+		if err != nil {
+			return nil, err
+		}
+		if start == end {
+			return nil, fmt.Errorf("empty time window %q", w)
+		}
+		schedule = append(schedule, scanWindow{start: start, end: end})
+	}
+	return schedule, nil
+}
+
+// parseClock returns the number of minutes since midnight of a HH:MM time
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", strings.TrimSpace(s))
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Allows returns true if a scan can be started at the given time
+func (s ScanSchedule) Allows(t time.Time) bool {
+	if len(s) == 0 {
+		return true
+	}
+	now := t.Hour()*60 + t.Minute()
+	for _, w := range s {
+		if w.start < w.end {
+			if now >= w.start && now < w.end {
+				return true
+			}
+		} else if now >= w.start || now < w.end {
+			return true
+		}
+	}
+	return false
+}
+
+// InScanWindow returns true if the schedule of the mirror allows a scan at
+// the given time. An invalid schedule doesn't prevent the scans.
+func (m *Mirror) InScanWindow(t time.Time) bool {
+	schedule, err := ParseScanSchedule(m.ScanSchedule)
+	if err != nil {
+		return true
+	}
+	return schedule.Allows(t)
+}
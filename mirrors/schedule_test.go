@@ -0,0 +1,76 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package mirrors
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseScanSchedule(t *testing.T) {
+	tests := map[string]struct {
+		schedule string
+		valid    bool
+		windows  int
+	}{
+		"empty":      {"", true, 0},
+		"single":     {"22:00-06:00", true, 1},
+		"multiple":   {"22:00-06:00, 12:00-13:30", true, 2},
+		"spaces":     {" 01:00 - 02:00 ,", true, 1},
+		"no end":     {"22:00", false, 0},
+		"bad time":   {"25:00-06:00", false, 0},
+		"bad format": {"10pm-6am", false, 0},
+		"too many":   {"01:00-02:00-03:00", false, 0},
+		"zero width": {"01:00-01:00", false, 0},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, err := ParseScanSchedule(test.schedule)
+			if test.valid && err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if !test.valid && err == nil {
+				t.Fatalf("Expected an error for %q", test.schedule)
+			}
+			if len(s) != test.windows {
+				t.Fatalf("Expected %d windows, got %d", test.windows, len(s))
+			}
+		})
+	}
+}
+
+func TestScanScheduleAllows(t *testing.T) {
+	at := func(hour, min int) time.Time {
+		return time.Date(2019, 1, 1, hour, min, 0, 0, time.Local)
+	}
+
+	tests := map[string]struct {
+		schedule string
+		time     time.Time
+		expected bool
+	}{
+		"always":          {"", at(15, 0), true},
+		"inside":          {"12:00-13:30", at(13, 0), true},
+		"start included":  {"12:00-13:30", at(12, 0), true},
+		"end excluded":    {"12:00-13:30", at(13, 30), false},
+		"outside":         {"12:00-13:30", at(14, 0), false},
+		"before midnight": {"22:00-06:00", at(23, 0), true},
+		"after midnight":  {"22:00-06:00", at(2, 0), true},
+		"daytime":         {"22:00-06:00", at(12, 0), false},
+		"second window":   {"22:00-06:00, 12:00-13:30", at(12, 15), true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			s, err := ParseScanSchedule(test.schedule)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if r := s.Allows(test.time); r != test.expected {
+				t.Fatalf("Expected %t, got %t", test.expected, r)
+			}
+		})
+	}
+}
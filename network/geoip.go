@@ -5,6 +5,8 @@ package network
 
 import (
 	"errors"
+	"fmt"
+	"io"
 	"net"
 	"os"
 	"strings"
@@ -29,9 +31,11 @@ const (
 // GeoIP contains methods to query the GeoIP database
 type GeoIP struct {
 	sync.RWMutex
+	loadMutex sync.Mutex
 
-	city *geoipDB
-	asn  *geoipDB
+	provider GeoIPProvider
+	city     *geoipDB
+	asn      *geoipDB
 }
 
 // GeoIPRecord defines a GeoIP record for a given IP address
@@ -47,6 +51,9 @@ type GeoIPRecord struct {
 	// ASN DB
 	ASName string
 	ASNum  uint
+
+	// IPVersion is the version of the client address, 0 if unknown
+	IPVersion int
 }
 
 // Geolocalizer is an interface representing a GeoIP library
@@ -59,8 +66,8 @@ func NewGeoIP() *GeoIP {
 	return &GeoIP{}
 }
 
-// Open the GeoIP database
-func (g *GeoIP) openDatabase(file string) (*maxminddb.Reader, error) {
+// databasePath returns the path of the given GeoIP database
+func (g *GeoIP) databasePath(file string) string {
 	dbpath := GetConfig().GeoipDatabasePath
 	if dbpath != "" && !strings.HasSuffix(dbpath, "/") {
 		dbpath += "/"
@@ -68,54 +75,89 @@ func (g *GeoIP) openDatabase(file string) (*maxminddb.Reader, error) {
 
 	filename := dbpath + file
 
-	var err error
-
-	if _, err = os.Stat(filename + geoipUpdatedExt); !os.IsNotExist(err) {
+	if _, err := os.Stat(filename + geoipUpdatedExt); !os.IsNotExist(err) {
 		filename += geoipUpdatedExt
 	}
 
-	return maxminddb.Open(filename)
+	return filename
 }
 
 type geoipDB struct {
-	filename string
-	modTime  time.Time
-	db       Geolocalizer
+	filename    string
+	modTime     time.Time // build date of the database
+	fileModTime time.Time // modification time of the file
+	db          Geolocalizer
+}
+
+// close releases the resources of the database, if any
+func (d *geoipDB) close() {
+	if c, ok := d.db.(io.Closer); ok {
+		c.Close()
+	}
 }
 
-func (g *GeoIP) loadDB(filename string, geodb **geoipDB, geoiperror *GeoIPError) error {
+// loadDB opens the given database and returns it if it differs from the
+// current one. The current database is returned as is otherwise.
+func (g *GeoIP) loadDB(provider GeoIPProvider, filename, kind string, current *geoipDB, geoiperror *GeoIPError) *geoipDB {
 	// Increase the loaded counter
 	geoiperror.loaded++
 
-	if *geodb == nil {
-		*geodb = &geoipDB{
+	if current == nil || current.filename != filename {
+		current = &geoipDB{
 			filename: filename,
 		}
 	}
 
-	db, err := g.openDatabase(filename)
+	path := g.databasePath(filename)
+	fi, err := os.Stat(path)
 	if err != nil {
 		geoiperror.Errors = append(geoiperror.Errors, err)
-		return err
+		return current
+	}
+
+	db, err := maxminddb.Open(path)
+	if err != nil {
+		geoiperror.Errors = append(geoiperror.Errors, err)
+		return current
+	}
+
+	if !provider.IsValidType(kind, db.Metadata.DatabaseType) {
+		db.Close()
+		err = fmt.Errorf("%s is not a %s %s database (found %s)", filename, provider.Name(), kind, db.Metadata.DatabaseType)
+		geoiperror.Errors = append(geoiperror.Errors, err)
+		geoiperror.invalid = true
+		return current
 	}
 
 	modTime := time.Unix(int64(db.Metadata.BuildEpoch), 0)
 
-	if (*geodb).modTime.Equal(modTime) {
-		return nil
+	if current.db != nil && current.modTime.Equal(modTime) {
+		db.Close()
+		unchanged := *current
+		unchanged.fileModTime = fi.ModTime()
+		return &unchanged
 	}
 
-	(*geodb).db = db
-	(*geodb).modTime = modTime
+	if current.db != nil {
+		log.Infof("Reloading %s database (built on %s, previously %s)", filename, modTime, current.modTime)
+	} else {
+		log.Infof("Loading %s database (built on %s)", filename, modTime)
+	}
 
-	log.Infof("Loading %s database (built on %s)", filename, (*geodb).modTime)
-	return nil
+	return &geoipDB{
+		filename:    filename,
+		modTime:     modTime,
+		fileModTime: fi.ModTime(),
+		db:          db,
+	}
 }
 
 // GeoIPError holds errors while loading the different databases
 type GeoIPError struct {
-	Errors []error
-	loaded int
+	Errors  []error
+	loaded  int
+	invalid bool
+	strict  bool
 }
 
 func (e GeoIPError) Error() string {
@@ -127,21 +169,102 @@ func (e GeoIPError) IsFatal() bool {
 	return e.loaded == len(e.Errors)
 }
 
-// LoadGeoIP loads the GeoIP databases into memory
+// IsStrict returns true if the error must prevent the startup, i.e. a
+// database has the wrong format or a GeoIPProvider is explicitly configured
+func (e GeoIPError) IsStrict() bool {
+	return e.invalid || (e.strict && len(e.Errors) > 0)
+}
+
+// LoadGeoIP loads the GeoIP databases into memory. The databases are
+// opened first then swapped, the lookups are only blocked during the swap.
 func (g *GeoIP) LoadGeoIP() error {
 	var ret GeoIPError
 
+	provider, err := NewGeoIPProvider(GetConfig().GeoIPProvider)
+	if err != nil {
+		return err
+	}
+	ret.strict = GetConfig().GeoIPProvider != ""
+
+	g.loadMutex.Lock()
+	defer g.loadMutex.Unlock()
+
+	g.RLock()
+	oldCity, oldASN := g.city, g.asn
+	g.RUnlock()
+
+	city := g.loadDB(provider, provider.CityDatabase(), "City", oldCity, &ret)
+	asn := g.loadDB(provider, provider.ASNDatabase(), "ASN", oldASN, &ret)
+
 	g.Lock()
-	g.loadDB("GeoLite2-City.mmdb", &g.city, &ret)
-	g.loadDB("GeoLite2-ASN.mmdb", &g.asn, &ret)
+	g.provider = provider
+	g.city = city
+	g.asn = asn
 	g.Unlock()
 
+	// No lookup can use the previous databases anymore
+	for _, pair := range [][2]*geoipDB{{oldCity, city}, {oldASN, asn}} {
+		if pair[0] != nil && pair[0].db != nil && pair[0].db != pair[1].db {
+			pair[0].close()
+		}
+	}
+
 	if len(ret.Errors) > 0 {
 		return ret
 	}
 	return nil
 }
 
+// databasesChanged returns true if the file of a database was modified
+// since it was loaded
+func (g *GeoIP) databasesChanged() bool {
+	g.RLock()
+	dbs := []*geoipDB{g.city, g.asn}
+	g.RUnlock()
+
+	for _, d := range dbs {
+		if d == nil {
+			continue
+		}
+		fi, err := os.Stat(g.databasePath(d.filename))
+		if err != nil {
+			continue
+		}
+		if !fi.ModTime().Equal(d.fileModTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// WatchDatabases reloads the databases whenever their files are modified,
+// as long as GeoIPAutoReload is enabled. It returns when stop is closed.
+func (g *GeoIP) WatchDatabases(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if !GetConfig().GeoIPAutoReload || !g.databasesChanged() {
+				continue
+			}
+			if err := g.LoadGeoIP(); err != nil {
+				var gerr GeoIPError
+				if errors.As(err, &gerr) {
+					for _, e := range gerr.Errors {
+						log.Errorf("GeoIP reload: %s", e)
+					}
+				} else {
+					log.Errorf("GeoIP reload: %s", err)
+				}
+			}
+		}
+	}
+}
+
 // GetRecord return informations about the given ip address
 // (works in IPv4 and v6)
 func (g *GeoIP) GetRecord(ip string) (ret GeoIPRecord) {
@@ -150,63 +273,81 @@ func (g *GeoIP) GetRecord(ip string) (ret GeoIPRecord) {
 		return GeoIPRecord{}
 	}
 
-	type CityDb struct {
-		City struct {
-			Names struct {
-				English string `maxminddb:"en"`
-			} `maxminddb:"names"`
-		} `maxminddb:"city"`
-		Country struct {
-			IsoCode string `maxminddb:"iso_code"`
-			Names   struct {
-				English string `maxminddb:"en"`
-			} `maxminddb:"names"`
-		} `maxminddb:"country"`
-		Continent struct {
-			Code string `maxminddb:"code"`
-		} `maxminddb:"continent"`
-		Location struct {
-			Latitude  float64 `maxminddb:"latitude"`
-			Longitude float64 `maxminddb:"longitude"`
-		} `maxminddb:"location"`
-	}
-
-	type ASNDb struct {
-		AutonomousSystemNumber uint   `maxminddb:"autonomous_system_number"`
-		AutonomousSystemOrg    string `maxminddb:"autonomous_system_organization"`
-	}
-
-	var err error
-	var cityDb CityDb
-	var asnDb ASNDb
-
 	g.RLock()
 	defer g.RUnlock()
 
+	ret = g.lookup(addr)
+
+	// Retry with the IPv4 address embedded in the IPv6 one, if any
+	if !ret.IsValid() && addr.To4() == nil && GetConfig().GeoIPv6Fallback {
+		if v4, kind := EmbeddedIPv4(addr); v4 != nil {
+			if r := g.lookup(v4); r.IsValid() {
+				log.Debugf("GeoIP: using %s address %s for %s", kind, v4, ip)
+				ret = r
+			}
+		}
+	}
+
+	ret.IPVersion = IPVersion(addr)
+	return ret
+}
+
+// lookup queries the databases for the given address, the caller
+// must hold the lock
+func (g *GeoIP) lookup(addr net.IP) (ret GeoIPRecord) {
+	provider := g.provider
+	if provider == nil {
+		provider = maxmindProvider{}
+	}
+
 	if g.city != nil && g.city.db != nil {
-		err = g.city.db.Lookup(addr, &cityDb)
-		if err != nil {
+		if err := provider.LookupCity(g.city.db, addr, &ret); err != nil {
 			return GeoIPRecord{}
 		}
-		ret.CountryCode = cityDb.Country.IsoCode
-		ret.ContinentCode = cityDb.Continent.Code
-		ret.City = cityDb.City.Names.English
-		ret.Country = cityDb.Country.Names.English
-		ret.Latitude = float32(cityDb.Location.Latitude)
-		ret.Longitude = float32(cityDb.Location.Longitude)
 	}
 	if g.asn != nil && g.asn.db != nil {
-		err = g.asn.db.Lookup(addr, &asnDb)
-		if err != nil {
+		if err := provider.LookupASN(g.asn.db, addr, &ret); err != nil {
 			return GeoIPRecord{}
 		}
-		ret.ASName = asnDb.AutonomousSystemOrg
-		ret.ASNum = asnDb.AutonomousSystemNumber
 	}
 
 	return ret
 }
 
+var (
+	_, nat64Net, _     = net.ParseCIDR("64:ff9b::/96")
+	_, sixToFourNet, _ = net.ParseCIDR("2002::/16")
+	_, teredoNet, _    = net.ParseCIDR("2001::/32")
+)
+
+// EmbeddedIPv4 returns the IPv4 address embedded in the given IPv6 address
+// along with the name of the transition mechanism (IPv4-compatible, NAT64,
+// 6to4 or Teredo), or nil if there is none.
+func EmbeddedIPv4(ip net.IP) (net.IP, string) {
+	ip = ip.To16()
+	if ip == nil || ip.To4() != nil {
+		return nil, ""
+	}
+	switch {
+	case nat64Net.Contains(ip):
+		return net.IPv4(ip[12], ip[13], ip[14], ip[15]), "NAT64"
+	case sixToFourNet.Contains(ip):
+		return net.IPv4(ip[2], ip[3], ip[4], ip[5]), "6to4"
+	case teredoNet.Contains(ip):
+		// The client address is stored obfuscated in the last 32 bits
+		return net.IPv4(^ip[12], ^ip[13], ^ip[14], ^ip[15]), "Teredo"
+	case ip.Equal(net.IPv6zero) || ip.Equal(net.IPv6loopback):
+		return nil, ""
+	}
+	for _, b := range ip[:12] {
+		if b != 0 {
+			return nil, ""
+		}
+	}
+	// Deprecated IPv4-compatible address (::a.b.c.d)
+	return net.IPv4(ip[12], ip[13], ip[14], ip[15]), "IPv4-compatible"
+}
+
 // IsIPv6 returns true if the given address is of version 6
 func (g *GeoIP) IsIPv6(ip string) bool {
 	return strings.Contains(ip, ":")
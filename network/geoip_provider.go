@@ -0,0 +1,123 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package network
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// GeoIPProvider maps the records of the databases of a GeoIP vendor into
+// a GeoIPRecord
+type GeoIPProvider interface {
+	// Name returns the name of the provider as used in the configuration
+	Name() string
+	// CityDatabase returns the filename of the city database
+	CityDatabase() string
+	// ASNDatabase returns the filename of the ASN database
+	ASNDatabase() string
+	// IsValidType returns true if the database_type found in the metadata
+	// of a database matches the expected kind ("City" or "ASN")
+	IsValidType(kind, dbType string) bool
+	// LookupCity fills the location fields of the record
+	LookupCity(db Geolocalizer, addr net.IP, rec *GeoIPRecord) error
+	// LookupASN fills the autonomous system fields of the record
+	LookupASN(db Geolocalizer, addr net.IP, rec *GeoIPRecord) error
+}
+
+// NewGeoIPProvider returns the provider of the given name, an empty name
+// selects MaxMind
+func NewGeoIPProvider(name string) (GeoIPProvider, error) {
+	switch name {
+	case "", "maxmind":
+		return maxmindProvider{}, nil
+	case "dbip":
+		return dbipProvider{}, nil
+	}
+	return nil, fmt.Errorf("unknown GeoIP provider %s", name)
+}
+
+// maxmindProvider reads the MaxMind GeoLite2 (or GeoIP2) databases
+type maxmindProvider struct{}
+
+func (p maxmindProvider) Name() string         { return "maxmind" }
+func (p maxmindProvider) CityDatabase() string { return "GeoLite2-City.mmdb" }
+func (p maxmindProvider) ASNDatabase() string  { return "GeoLite2-ASN.mmdb" }
+
+func (p maxmindProvider) IsValidType(kind, dbType string) bool {
+	return (strings.HasPrefix(dbType, "GeoLite2-") || strings.HasPrefix(dbType, "GeoIP2-")) &&
+		strings.Contains(dbType, kind)
+}
+
+func (p maxmindProvider) LookupCity(db Geolocalizer, addr net.IP, rec *GeoIPRecord) error {
+	type CityDb struct {
+		City struct {
+			Names struct {
+				English string `maxminddb:"en"`
+			} `maxminddb:"names"`
+		} `maxminddb:"city"`
+		Country struct {
+			IsoCode string `maxminddb:"iso_code"`
+			Names   struct {
+				English string `maxminddb:"en"`
+			} `maxminddb:"names"`
+		} `maxminddb:"country"`
+		Continent struct {
+			Code string `maxminddb:"code"`
+		} `maxminddb:"continent"`
+		Location struct {
+			Latitude  float64 `maxminddb:"latitude"`
+			Longitude float64 `maxminddb:"longitude"`
+		} `maxminddb:"location"`
+	}
+
+	var cityDb CityDb
+	if err := db.Lookup(addr, &cityDb); err != nil {
+		return err
+	}
+	rec.CountryCode = cityDb.Country.IsoCode
+	rec.ContinentCode = cityDb.Continent.Code
+	rec.City = cityDb.City.Names.English
+	rec.Country = cityDb.Country.Names.English
+	rec.Latitude = float32(cityDb.Location.Latitude)
+	rec.Longitude = float32(cityDb.Location.Longitude)
+	return nil
+}
+
+func (p maxmindProvider) LookupASN(db Geolocalizer, addr net.IP, rec *GeoIPRecord) error {
+	type ASNDb struct {
+		AutonomousSystemNumber uint   `maxminddb:"autonomous_system_number"`
+		AutonomousSystemOrg    string `maxminddb:"autonomous_system_organization"`
+	}
+
+	var asnDb ASNDb
+	if err := db.Lookup(addr, &asnDb); err != nil {
+		return err
+	}
+	rec.ASName = asnDb.AutonomousSystemOrg
+	rec.ASNum = asnDb.AutonomousSystemNumber
+	return nil
+}
+
+// dbipProvider reads the DB-IP Lite databases. Their records follow the
+// GeoLite2 layout, only the filenames and the database types differ.
+type dbipProvider struct {
+	maxmindProvider
+}
+
+func (p dbipProvider) Name() string         { return "dbip" }
+func (p dbipProvider) CityDatabase() string { return "dbip-city-lite.mmdb" }
+func (p dbipProvider) ASNDatabase() string  { return "dbip-asn-lite.mmdb" }
+
+func (p dbipProvider) IsValidType(kind, dbType string) bool {
+	if !strings.HasPrefix(dbType, "DBIP-") {
+		return false
+	}
+	if kind == "City" {
+		// The commercial databases are named DBIP-Location-*
+		return strings.Contains(dbType, "City") || strings.Contains(dbType, "Location")
+	}
+	return strings.Contains(dbType, kind)
+}
@@ -5,10 +5,14 @@ package network
 
 import (
 	"net"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
 	"time"
+
+	. "github.com/etix/mirrorbits/config"
 )
 
 type CityDb struct {
@@ -90,6 +94,175 @@ func TestGeoIP_GetRecord(t *testing.T) {
 	}
 }
 
+func TestGeoIP_GetRecordDBIP(t *testing.T) {
+	g := NewGeoIP()
+	g.provider = dbipProvider{}
+	g.city = &geoipDB{
+		filename: "dbip-city-lite.mmdb",
+		modTime:  time.Now(),
+		db:       &GeoIPMockCity{},
+	}
+	g.asn = &geoipDB{
+		filename: "dbip-asn-lite.mmdb",
+		modTime:  time.Now(),
+		db:       &GeoIPMockASN{},
+	}
+
+	SetConfiguration(&Configuration{})
+
+	r := g.GetRecord("127.0.0.1")
+	if r.CountryCode != "test2" || r.City != "test1" || r.Latitude != 24 || r.Longitude != 42 {
+		t.Fatalf("Invalid location %+v", r)
+	}
+	if r.ASNum != 42 || r.ASName != "forty two" {
+		t.Fatalf("Invalid autonomous system %+v", r)
+	}
+}
+
+func TestNewGeoIPProvider(t *testing.T) {
+	tests := map[string]string{
+		"":        "maxmind",
+		"maxmind": "maxmind",
+		"dbip":    "dbip",
+	}
+	for name, expected := range tests {
+		p, err := NewGeoIPProvider(name)
+		if err != nil {
+			t.Fatalf("Unexpected error for %q: %s", name, err)
+		}
+		if p.Name() != expected {
+			t.Fatalf("Expected %s for %q, got %s", expected, name, p.Name())
+		}
+	}
+	if _, err := NewGeoIPProvider("foo"); err == nil {
+		t.Fatalf("Expected an error for an unknown provider")
+	}
+}
+
+func TestGeoIPProvider_IsValidType(t *testing.T) {
+	tests := []struct {
+		provider GeoIPProvider
+		kind     string
+		dbType   string
+		valid    bool
+	}{
+		{maxmindProvider{}, "City", "GeoLite2-City", true},
+		{maxmindProvider{}, "City", "GeoIP2-City", true},
+		{maxmindProvider{}, "ASN", "GeoLite2-ASN", true},
+		{maxmindProvider{}, "City", "GeoLite2-ASN", false},
+		{maxmindProvider{}, "City", "DBIP-City-Lite", false},
+		{dbipProvider{}, "City", "DBIP-City-Lite", true},
+		{dbipProvider{}, "City", "DBIP-Location-ISP (compat=Enterprise)", true},
+		{dbipProvider{}, "ASN", "DBIP-ASN-Lite (compat=GeoLite2-ASN)", true},
+		{dbipProvider{}, "ASN", "DBIP-City-Lite", false},
+		{dbipProvider{}, "City", "GeoLite2-City", false},
+	}
+	for _, test := range tests {
+		if v := test.provider.IsValidType(test.kind, test.dbType); v != test.valid {
+			t.Fatalf("Expected %t for %s %s with %s, got %t", test.valid, test.provider.Name(), test.kind, test.dbType, v)
+		}
+	}
+}
+
+func TestEmbeddedIPv4(t *testing.T) {
+	tests := map[string]struct {
+		ip   string
+		ipv4 string
+		kind string
+	}{
+		"native":     {"2a01:e0a::1", "", ""},
+		"loopback":   {"::1", "", ""},
+		"nat64":      {"64:ff9b::c000:201", "192.0.2.1", "NAT64"},
+		"6to4":       {"2002:c000:201::1", "192.0.2.1", "6to4"},
+		"teredo":     {"2001:0:4136:e378:8000:63bf:3fff:fdd2", "192.0.2.45", "Teredo"},
+		"compatible": {"::192.0.2.1", "192.0.2.1", "IPv4-compatible"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			ipv4, kind := EmbeddedIPv4(net.ParseIP(test.ip))
+			if test.ipv4 == "" {
+				if ipv4 != nil {
+					t.Fatalf("Expected no IPv4 address, got %s", ipv4)
+				}
+				return
+			}
+			if ipv4.String() != test.ipv4 || kind != test.kind {
+				t.Fatalf("Expected %s (%s), got %s (%s)", test.ipv4, test.kind, ipv4, kind)
+			}
+		})
+	}
+}
+
+func TestGeoIP_GetRecordIPv6Fallback(t *testing.T) {
+	g := NewGeoIP()
+	g.city = &geoipDB{
+		filename: "city.mmdb",
+		modTime:  time.Now(),
+		db:       &GeoIPMockCityIPv4{},
+	}
+
+	SetConfiguration(&Configuration{
+		GeoIPv6Fallback: true,
+	})
+
+	r := g.GetRecord("2002:c000:201::1")
+	if r.CountryCode != "test2" {
+		t.Fatalf("Expected the record of the embedded address, got %+v", r)
+	}
+	r = g.GetRecord("2a01:e0a::1")
+	if r.IsValid() {
+		t.Fatalf("Expected an invalid record, got %+v", r)
+	}
+
+	SetConfiguration(&Configuration{
+		GeoIPv6Fallback: false,
+	})
+
+	r = g.GetRecord("2002:c000:201::1")
+	if r.IsValid() {
+		t.Fatalf("Expected an invalid record, got %+v", r)
+	}
+}
+
+func TestGeoIP_DatabasesChanged(t *testing.T) {
+	dir := t.TempDir()
+	filename := "GeoLite2-City.mmdb"
+	if err := os.WriteFile(filepath.Join(dir, filename), []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(filepath.Join(dir, filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	SetConfiguration(&Configuration{
+		GeoipDatabasePath: dir,
+	})
+
+	g := NewGeoIP()
+	if g.databasesChanged() {
+		t.Fatalf("No database loaded, nothing expected to change")
+	}
+
+	g.city = &geoipDB{
+		filename:    filename,
+		fileModTime: fi.ModTime(),
+		db:          &GeoIPMockCity{},
+	}
+	if g.databasesChanged() {
+		t.Fatalf("The database file wasn't modified")
+	}
+
+	mtime := fi.ModTime().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, filename), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if !g.databasesChanged() {
+		t.Fatalf("The database file was modified")
+	}
+}
+
 func TestIsIPv6(t *testing.T) {
 	g := NewGeoIP()
 	if g.IsIPv6("192.168.0.1") == true {
@@ -138,6 +311,18 @@ func (g *GeoIPMockCity) Lookup(ipAddress net.IP, result any) error {
 	return nil
 }
 
+// GeoIPMockCityIPv4 only knows about IPv4 addresses
+type GeoIPMockCityIPv4 struct {
+	GeoIPMockCity
+}
+
+func (g *GeoIPMockCityIPv4) Lookup(ipAddress net.IP, result any) error {
+	if ipAddress.To4() == nil {
+		return nil
+	}
+	return g.GeoIPMockCity.Lookup(ipAddress, result)
+}
+
 type GeoIPMockASN struct {
 }
 
@@ -0,0 +1,164 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package network
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/etix/mirrorbits/core"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+var (
+	// ErrNoUpdateURL is returned when the GeoIPUpdateURL is not configured
+	ErrNoUpdateURL = errors.New("GeoIPUpdateURL is not configured")
+	// ErrNoMMDB is returned when an archive doesn't contain any mmdb file
+	ErrNoMMDB = errors.New("no mmdb file found in the archive")
+
+	geoipUpdateTimeout = 5 * time.Minute
+)
+
+// UpdateGeoIPDatabases downloads the databases of the configured provider
+// from the GeoIPUpdateURL and atomically replaces the ones used by the
+// daemon. It returns the paths of the updated databases.
+func UpdateGeoIPDatabases() ([]string, error) {
+	if GetConfig().GeoIPUpdateURL == "" {
+		return nil, ErrNoUpdateURL
+	}
+
+	provider, err := NewGeoIPProvider(GetConfig().GeoIPProvider)
+	if err != nil {
+		return nil, err
+	}
+
+	g := NewGeoIP()
+	client := &http.Client{
+		Timeout: geoipUpdateTimeout,
+	}
+
+	var paths []string
+	for _, db := range [][2]string{
+		{provider.CityDatabase(), "City"},
+		{provider.ASNDatabase(), "ASN"},
+	} {
+		path := g.databasePath(db[0])
+		if err := updateDatabase(client, provider, db[0], db[1], path); err != nil {
+			return paths, fmt.Errorf("%s: %w", db[0], err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// geoipUpdateURL returns the download URL of the given database. The
+// {edition}, {license_key} and {month} placeholders are replaced by the
+// name of the database (without extension), the GeoIPLicenseKey and the
+// current month (YYYY-MM) respectively.
+func geoipUpdateURL(filename string) string {
+	return strings.NewReplacer(
+		"{edition}", strings.TrimSuffix(filename, ".mmdb"),
+		"{license_key}", GetConfig().GeoIPLicenseKey,
+		"{month}", time.Now().UTC().Format("2006-01"),
+	).Replace(GetConfig().GeoIPUpdateURL)
+}
+
+func updateDatabase(client *http.Client, provider GeoIPProvider, filename, kind, path string) error {
+	req, err := http.NewRequest("GET", geoipUpdateURL(filename), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "Mirrorbits/"+core.VERSION)
+	if account := GetConfig().GeoIPAccountID; account != "" {
+		req.SetBasicAuth(account, GetConfig().GeoIPLicenseKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download failed: %s", resp.Status)
+	}
+
+	// Write the database next to the final one so it can be renamed
+	tmp, err := os.CreateTemp(filepath.Dir(path), filename+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	err = extractMMDB(resp.Body, tmp)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	// Verify the database before installing it
+	db, err := maxminddb.Open(tmp.Name())
+	if err != nil {
+		return err
+	}
+	dbType := db.Metadata.DatabaseType
+	db.Close()
+	if !provider.IsValidType(kind, dbType) {
+		return fmt.Errorf("not a %s %s database (found %s)", provider.Name(), kind, dbType)
+	}
+
+	if err = os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// extractMMDB copies the mmdb database found in r to w. The database can be
+// gzipped and packaged in a tar archive, in which case the first file with
+// the .mmdb extension is extracted.
+func extractMMDB(r io.Reader, w io.Writer) error {
+	br := bufio.NewReader(r)
+
+	if magic, err := br.Peek(2); err == nil && bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		br = bufio.NewReader(gz)
+	}
+
+	// A tar header has the "ustar" magic at offset 257
+	if header, err := br.Peek(262); err == nil && bytes.Equal(header[257:262], []byte("ustar")) {
+		tr := tar.NewReader(br)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				return ErrNoMMDB
+			} else if err != nil {
+				return err
+			}
+			if hdr.Typeflag == tar.TypeReg && strings.HasSuffix(hdr.Name, ".mmdb") {
+				_, err = io.Copy(w, tr)
+				return err
+			}
+		}
+	}
+
+	_, err := io.Copy(w, br)
+	return err
+}
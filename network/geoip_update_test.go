@@ -0,0 +1,88 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package network
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+	"time"
+
+	. "github.com/etix/mirrorbits/config"
+)
+
+func makeTarGz(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		err := tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func TestExtractMMDB(t *testing.T) {
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	gz.Write([]byte("database"))
+	gz.Close()
+
+	tests := map[string]struct {
+		input  []byte
+		output string
+		err    error
+	}{
+		"plain":   {[]byte("database"), "database", nil},
+		"gzipped": {gzipped.Bytes(), "database", nil},
+		"tar.gz": {makeTarGz(t, map[string]string{
+			"GeoLite2-City_20250601/GeoLite2-City.mmdb": "database",
+		}), "database", nil},
+		"no mmdb": {makeTarGz(t, map[string]string{
+			"GeoLite2-City_20250601/LICENSE.txt": "license",
+		}), "", ErrNoMMDB},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := extractMMDB(bytes.NewReader(test.input), &out)
+			if err != test.err {
+				t.Fatalf("Expected error %v, got %v", test.err, err)
+			}
+			if out.String() != test.output {
+				t.Fatalf("Expected %q, got %q", test.output, out.String())
+			}
+		})
+	}
+}
+
+func TestGeoIPUpdateURL(t *testing.T) {
+	SetConfiguration(&Configuration{
+		GeoIPUpdateURL:  "https://example.org/{edition}-{month}.tar.gz?key={license_key}",
+		GeoIPLicenseKey: "secret",
+	})
+
+	url := geoipUpdateURL("GeoLite2-City.mmdb")
+	expected := "https://example.org/GeoLite2-City-" + time.Now().UTC().Format("2006-01") + ".tar.gz?key=secret"
+	if url != expected {
+		t.Fatalf("Expected %s, got %s", expected, url)
+	}
+
+	SetConfiguration(&Configuration{})
+	if _, err := UpdateGeoIPDatabases(); err != ErrNoUpdateURL {
+		t.Fatalf("Expected ErrNoUpdateURL, got %v", err)
+	}
+}
@@ -25,6 +25,35 @@ func LookupMirrorIP(host string) (string, error) {
 	return addrs[0].String(), err
 }
 
+// LookupIPVersions returns whether the host has IPv4 and IPv6 addresses
+func LookupIPVersions(host string) (hasIPv4, hasIPv6 bool, err error) {
+	addrs, err := net.LookupIP(host)
+	if err != nil {
+		return false, false, err
+	}
+	for _, addr := range addrs {
+		switch IPVersion(addr) {
+		case 4:
+			hasIPv4 = true
+		case 6:
+			hasIPv6 = true
+		}
+	}
+	return hasIPv4, hasIPv6, nil
+}
+
+// IPVersion returns the version of the address, 4 or 6, or 0 if it is
+// invalid. The IPv4-mapped IPv6 addresses are of version 4.
+func IPVersion(ip net.IP) int {
+	if ip.To4() != nil {
+		return 4
+	}
+	if ip.To16() != nil {
+		return 6
+	}
+	return 0
+}
+
 // RemoteIPFromAddr removes the port from a remote address (x.x.x.x:yyyy)
 func RemoteIPFromAddr(remoteAddr string) string {
 	return remoteAddr[:strings.LastIndex(remoteAddr, ":")]
@@ -43,6 +72,38 @@ func ExtractRemoteIP(XForwardedFor string) string {
 	return ""
 }
 
+// IsTrustedProxy returns true if the given IP belongs to one of the networks
+// of the list (in CIDR notation)
+func IsTrustedProxy(ip string, cidrs []string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, c := range cidrs {
+		_, network, err := net.ParseCIDR(c)
+		if err == nil && network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtractUntrustedIP extracts the remote IP from an X-Forwarded-For header
+// by skipping, from the right, the addresses of the trusted proxies. Unlike
+// ExtractRemoteIP the result can't be spoofed by the client since it is the
+// address seen by the first trusted proxy.
+func ExtractUntrustedIP(XForwardedFor string, cidrs []string) string {
+	addresses := strings.Split(XForwardedFor, ",")
+	for i := len(addresses) - 1; i >= 0; i-- {
+		addr := strings.TrimSpace(addresses[i])
+		if !IsTrustedProxy(addr, cidrs) {
+			return addr
+		}
+	}
+	// All the addresses are trusted
+	return strings.TrimSpace(addresses[0])
+}
+
 // IsPrimaryCountry returns true if the clientInfo country is the primary country
 func IsPrimaryCountry(clientInfo GeoIPRecord, list []string) bool {
 	if !clientInfo.IsValid() {
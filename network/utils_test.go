@@ -4,6 +4,7 @@
 package network
 
 import (
+	"net"
 	"testing"
 )
 
@@ -24,6 +25,21 @@ func TestRemoteIpFromAddr(t *testing.T) {
 	}
 }
 
+func TestIPVersion(t *testing.T) {
+	tests := map[string]int{
+		"192.168.0.1":      4,
+		"::ffff:192.0.2.1": 4,
+		"2001:db8::1":      6,
+		"::1":              6,
+		"invalid":          0,
+	}
+	for ip, version := range tests {
+		if v := IPVersion(net.ParseIP(ip)); v != version {
+			t.Fatalf("%s: expected version %d, got %d", ip, version, v)
+		}
+	}
+}
+
 func TestExtractRemoteIP(t *testing.T) {
 	r := ExtractRemoteIP("192.168.0.1, 192.168.0.2, 192.168.0.3")
 	if r != "192.168.0.1" {
@@ -36,6 +52,38 @@ func TestExtractRemoteIP(t *testing.T) {
 	}
 }
 
+func TestIsTrustedProxy(t *testing.T) {
+	cidrs := []string{"10.0.0.0/8", "2001:db8::/32"}
+
+	tests := map[string]bool{
+		"10.1.2.3":    true,
+		"192.168.0.1": false,
+		"2001:db8::1": true,
+		"2a01:e0a::1": false,
+		"invalid":     false,
+		"":            false,
+	}
+	for ip, expected := range tests {
+		if r := IsTrustedProxy(ip, cidrs); r != expected {
+			t.Fatalf("Expected %t for %q, got %t", expected, ip, r)
+		}
+	}
+}
+
+func TestExtractUntrustedIP(t *testing.T) {
+	cidrs := []string{"10.0.0.0/8"}
+
+	r := ExtractUntrustedIP("192.168.0.1, 192.168.0.2, 10.0.0.1, 10.0.0.2", cidrs)
+	if r != "192.168.0.2" {
+		t.Fatalf("Expected '192.168.0.2', got %s", r)
+	}
+
+	r = ExtractUntrustedIP("10.0.0.1,10.0.0.2", cidrs)
+	if r != "10.0.0.1" {
+		t.Fatalf("Expected '10.0.0.1', got %s", r)
+	}
+}
+
 func TestIsPrimaryCountry(t *testing.T) {
 	var b bool
 	list := []string{"FR", "DE", "GR"}
@@ -30,8 +30,9 @@ var (
 )
 
 // Relaunch launches {self} as a child process passing listener details
-// to provide a seamless binary upgrade.
-func Relaunch(l net.Listener) error {
+// to provide a seamless binary upgrade. Either listener can be nil when
+// the corresponding server is disabled.
+func Relaunch(l net.Listener, tl net.Listener) error {
 	argv0, err := exec.LookPath(os.Args[0])
 	if err != nil {
 		return err
@@ -44,52 +45,60 @@ func Relaunch(l net.Listener) error {
 		return err
 	}
 
-	var file *os.File
+	files := make([]*os.File, syscall.Stderr+1)
+	files[syscall.Stdin] = os.Stdin
+	files[syscall.Stdout] = os.Stdout
+	files[syscall.Stderr] = os.Stderr
 
-	switch t := l.(type) {
-	case *net.TCPListener:
-		file, err = t.File()
-	case *net.UnixListener:
-		file, err = t.File()
-	default:
-		return ErrInvalidfd
-	}
-	if err != nil {
-		return err
-	}
+	for _, e := range []struct {
+		listener net.Listener
+		prefix   string
+	}{
+		{l, "OLD_"},
+		{tl, "OLD_TLS_"},
+	} {
+		if e.listener == nil {
+			// Don't leak the descriptor of a previous upgrade
+			os.Unsetenv(e.prefix + "FD")
+			continue
+		}
 
-	fd := file.Fd()
-	sysfile := file.Name()
+		var file *os.File
 
-	listener, ok := l.(*net.TCPListener)
-	if ok {
-		listenerFile, err := listener.File()
+		switch t := e.listener.(type) {
+		case *net.TCPListener:
+			file, err = t.File()
+		case *net.UnixListener:
+			file, err = t.File()
+		default:
+			return ErrInvalidfd
+		}
 		if err != nil {
 			return err
 		}
-		fd = listenerFile.Fd()
-		sysfile = listenerFile.Name()
-	}
 
-	if fd < uintptr(syscall.Stderr) {
-		return ErrInvalidfd
-	}
+		fd := file.Fd()
+		if fd < uintptr(syscall.Stderr) {
+			return ErrInvalidfd
+		}
 
-	if err := os.Setenv("OLD_FD", fmt.Sprint(fd)); err != nil {
-		return err
-	}
-	if err := os.Setenv("OLD_NAME", fmt.Sprintf("tcp:%s->", l.Addr().String())); err != nil {
-		return err
+		if err := os.Setenv(e.prefix+"FD", fmt.Sprint(fd)); err != nil {
+			return err
+		}
+		if err := os.Setenv(e.prefix+"NAME", fmt.Sprintf("tcp:%s->", e.listener.Addr().String())); err != nil {
+			return err
+		}
+
+		for uintptr(len(files)) <= fd {
+			files = append(files, nil)
+		}
+		files[fd] = file
 	}
+
 	if err := os.Setenv("OLD_PPID", fmt.Sprint(syscall.Getpid())); err != nil {
 		return err
 	}
 
-	files := make([]*os.File, fd+1)
-	files[syscall.Stdin] = os.Stdin
-	files[syscall.Stdout] = os.Stdout
-	files[syscall.Stderr] = os.Stderr
-	files[fd] = os.NewFile(fd, sysfile)
 	p, err := os.StartProcess(argv0, os.Args, &os.ProcAttr{
 		Dir:   wd,
 		Env:   os.Environ(),
@@ -103,16 +112,40 @@ func Relaunch(l net.Listener) error {
 	return nil
 }
 
-// Recover from a seamless binary upgrade and use an already
-// existing listener to take over the connections
-func Recover() (l net.Listener, ppid int, err error) {
+// Recover from a seamless binary upgrade and use the already
+// existing listeners to take over the connections. The TLS listener
+// is nil if the parent wasn't serving HTTPS, the plain one is nil if
+// it was only serving HTTPS.
+func Recover() (l net.Listener, tl net.Listener, ppid int, err error) {
+	if os.Getenv("OLD_FD") == "" && os.Getenv("OLD_TLS_FD") == "" {
+		err = ErrInvalidfd
+		return
+	}
+	if os.Getenv("OLD_FD") != "" {
+		if l, err = recoverListener("OLD_"); err != nil {
+			return
+		}
+	}
+	if os.Getenv("OLD_TLS_FD") != "" {
+		if tl, err = recoverListener("OLD_TLS_"); err != nil {
+			return
+		}
+	}
+	_, err = fmt.Sscan(os.Getenv("OLD_PPID"), &ppid)
+	if err != nil {
+		return
+	}
+	return
+}
+
+func recoverListener(prefix string) (l net.Listener, err error) {
 	var fd uintptr
-	_, err = fmt.Sscan(os.Getenv("OLD_FD"), &fd)
+	_, err = fmt.Sscan(os.Getenv(prefix+"FD"), &fd)
 	if err != nil {
 		return
 	}
 	var i net.Listener
-	i, err = net.FileListener(os.NewFile(fd, os.Getenv("OLD_NAME")))
+	i, err = net.FileListener(os.NewFile(fd, os.Getenv(prefix+"NAME")))
 	if err != nil {
 		return
 	}
@@ -128,10 +161,6 @@ func Recover() (l net.Listener, ppid int, err error) {
 	if err = syscall.Close(int(fd)); err != nil {
 		return
 	}
-	_, err = fmt.Sscan(os.Getenv("OLD_PPID"), &ppid)
-	if err != nil {
-		return
-	}
 	return
 }
 
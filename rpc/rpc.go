@@ -4,18 +4,20 @@
 package rpc
 
 import (
+	"container/heap"
 	"errors"
 	"fmt"
-	"log"
 	"net"
 	"net/url"
 	"os"
+	"path/filepath"
 	"regexp"
-	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	. "github.com/etix/mirrorbits/config"
 	"github.com/etix/mirrorbits/core"
@@ -27,9 +29,11 @@ import (
 	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/empty"
 	"github.com/gomodule/redigo/redis"
+	"github.com/op/go-logging"
 	context "golang.org/x/net/context"
 	grpc "google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
 	"gopkg.in/yaml.v3"
@@ -38,6 +42,8 @@ import (
 var (
 	// ErrNameAlreadyTaken is returned when the request name is already taken by another mirror
 	ErrNameAlreadyTaken = errors.New("name already taken")
+
+	log = logging.MustGetLogger("main")
 )
 
 // CLI object handles the server side RPC of the CLI
@@ -47,6 +53,26 @@ type CLI struct {
 	sig      chan<- os.Signal
 	redis    *database.Redis
 	cache    *mirrors.Cache
+	sim      Simulator
+	maint    MaintenanceSwitch
+	rates    RequestRater
+}
+
+// Simulator simulates the mirror selection for a given client and file
+type Simulator interface {
+	Simulate(ctx context.Context, ip, path string) (*mirrors.Results, error)
+}
+
+// RequestRater returns the number of redirects per second over the given
+// windows, overall and per mirror
+type RequestRater interface {
+	RequestRates() (windows []time.Duration, total []float64, perMirror map[int][]float64)
+}
+
+// MaintenanceSwitch toggles the maintenance mode of the HTTP server
+type MaintenanceSwitch interface {
+	SetMaintenance(enabled bool, message string)
+	Maintenance() (enabled bool, message string, override bool)
 }
 
 func (c *CLI) Start() error {
@@ -86,18 +112,40 @@ func (c *CLI) SetCache(cache *mirrors.Cache) {
 	c.cache = cache
 }
 
+func (c *CLI) SetSimulator(sim Simulator) {
+	c.sim = sim
+}
+
+func (c *CLI) SetMaintenanceSwitch(maint MaintenanceSwitch) {
+	c.maint = maint
+}
+
+func (c *CLI) SetRequestRater(rates RequestRater) {
+	c.rates = rates
+}
+
 func (c *CLI) Ping(context.Context, *empty.Empty) (*empty.Empty, error) {
 	return &empty.Empty{}, nil
 }
 
 func (c *CLI) GetVersion(context.Context, *empty.Empty) (*VersionReply, error) {
+	info := core.GetVersionInfo()
+	configFile, err := filepath.Abs(core.ConfigFile)
+	if err != nil {
+		configFile = core.ConfigFile
+	}
 	return &VersionReply{
-		Version:    core.VERSION,
-		Build:      core.BUILD + core.DEV,
-		GoVersion:  runtime.Version(),
-		OS:         runtime.GOOS,
-		Arch:       runtime.GOARCH,
-		GoMaxProcs: int32(runtime.GOMAXPROCS(0)),
+		Version:    info.Version,
+		Build:      info.Build,
+		GoVersion:  info.GoVersion,
+		OS:         info.OS,
+		Arch:       info.Arch,
+		GoMaxProcs: int32(info.GoMaxProcs),
+		Commit:     info.Commit,
+		BuildDate:  info.BuildDate,
+		Daemon:     info.Daemon,
+		Uptime:     int64(core.Uptime() / time.Second),
+		ConfigFile: configFile,
 	}, nil
 }
 
@@ -110,7 +158,65 @@ func (c *CLI) Upgrade(ctx context.Context, in *empty.Empty) (*empty.Empty, error
 	return &empty.Empty{}, nil
 }
 
+func (c *CLI) RequestRates(ctx context.Context, in *empty.Empty) (*RequestRatesReply, error) {
+	if c.rates == nil {
+		return nil, status.Error(codes.Unavailable, "http server not ready")
+	}
+	windows, total, perMirror := c.rates.RequestRates()
+
+	reply := &RequestRatesReply{Total: total}
+	for _, w := range windows {
+		reply.WindowSeconds = append(reply.WindowSeconds, int64(w/time.Second))
+	}
+	// The mirrors are named from a single lookup, the rates are still
+	// returned by ID if it fails
+	var names map[int]string
+	if c.redis != nil {
+		names, _ = c.redis.GetListOfMirrors()
+	}
+	for id, rates := range perMirror {
+		name, ok := names[id]
+		if !ok {
+			name = strconv.Itoa(id)
+		}
+		reply.Mirrors = append(reply.Mirrors, &MirrorRequestRates{
+			ID:    int32(id),
+			Name:  name,
+			Rates: rates,
+		})
+	}
+	sort.Slice(reply.Mirrors, func(i, j int) bool {
+		return reply.Mirrors[i].Name < reply.Mirrors[j].Name
+	})
+	return reply, nil
+}
+
+func (c *CLI) SetMaintenance(ctx context.Context, in *SetMaintenanceRequest) (*empty.Empty, error) {
+	if c.maint == nil {
+		return nil, status.Error(codes.Unavailable, "http server not ready")
+	}
+	c.maint.SetMaintenance(in.Enabled, in.Message)
+	return &empty.Empty{}, nil
+}
+
+func (c *CLI) GetMaintenance(ctx context.Context, in *empty.Empty) (*MaintenanceReply, error) {
+	if c.maint == nil {
+		return nil, status.Error(codes.Unavailable, "http server not ready")
+	}
+	enabled, message, override := c.maint.Maintenance()
+	return &MaintenanceReply{
+		Enabled:  enabled,
+		Message:  message,
+		Override: override,
+	}, nil
+}
+
 func (c *CLI) Reload(ctx context.Context, in *empty.Empty) (*empty.Empty, error) {
+	// The reload is asynchronous, report configuration errors now
+	if err := CheckConfig(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid configuration: "+err.Error())
+	}
+
 	select {
 	case c.sig <- syscall.SIGHUP:
 	default:
@@ -160,6 +266,83 @@ func (c *CLI) ChangeStatus(ctx context.Context, in *ChangeStatusRequest) (*empty
 	return &empty.Empty{}, err
 }
 
+// SetMirrorsEnabledByTag enables or disables all the mirrors of the given
+// region at once and returns their names
+func (c *CLI) SetMirrorsEnabledByTag(ctx context.Context, in *SetMirrorsEnabledByTagRequest) (*SetMirrorsEnabledByTagReply, error) {
+	if in.Tag == "" {
+		return nil, status.Error(codes.FailedPrecondition, "invalid tag")
+	}
+
+	conn, err := c.redis.Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	mirrorsIDs, err := c.redis.GetListOfMirrors()
+	if err != nil {
+		return nil, fmt.Errorf("can't fetch the list of mirrors: %w", err)
+	}
+	ids := make([]int, 0, len(mirrorsIDs))
+	conn.Send("MULTI")
+	for id := range mirrorsIDs {
+		ids = append(ids, id)
+		conn.Send("HGET", fmt.Sprintf("MIRROR_%d", id), "region")
+	}
+	regions, err := redis.Strings(conn.Do("EXEC"))
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	reply := &SetMirrorsEnabledByTagReply{}
+	var tagged []int
+	for i, region := range regions {
+		if strings.EqualFold(region, in.Tag) {
+			tagged = append(tagged, ids[i])
+			reply.Names = append(reply.Names, mirrorsIDs[ids[i]])
+		}
+	}
+	sort.Strings(reply.Names)
+
+	if err = mirrors.SetMirrorsEnabled(c.redis, tagged, in.Enabled); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+func (c *CLI) ForceMirrorState(ctx context.Context, in *ForceMirrorStateRequest) (*empty.Empty, error) {
+	if in.ID <= 0 {
+		return nil, status.Error(codes.FailedPrecondition, "invalid mirror id")
+	}
+
+	conn, err := c.redis.Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	m, err := redis.Values(conn.Do("HGETALL", fmt.Sprintf("MIRROR_%d", in.ID)))
+	if err != nil {
+		return nil, err
+	}
+	if len(m) == 0 {
+		return nil, status.Error(codes.NotFound, "mirror not found")
+	}
+
+	var mirror mirrors.Mirror
+	err = redis.ScanStruct(m, &mirror)
+	if err != nil {
+		return nil, err
+	}
+
+	var until time.Time
+	if in.TTL > 0 {
+		until = time.Now().Add(time.Duration(in.TTL) * time.Second)
+	}
+
+	return &empty.Empty{}, mirrors.ForceMirrorState(c.redis, &mirror, in.Up, until)
+}
+
 func (c *CLI) List(ctx context.Context, in *empty.Empty) (*MirrorListReply, error) {
 	conn, err := c.redis.Connect()
 	if err != nil {
@@ -231,6 +414,12 @@ func (c *CLI) MirrorInfo(ctx context.Context, in *MirrorIDRequest) (*Mirror, err
 		return nil, err
 	}
 
+	rpcm.HashMismatches, err = redis.Strings(conn.Do("SMEMBERS", scan.HashMismatchKey(int(in.ID))))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(rpcm.HashMismatches)
+
 	return rpcm, nil
 }
 
@@ -344,6 +533,11 @@ func (c *CLI) AddMirror(ctx context.Context, in *Mirror) (*AddMirrorReply, error
 		return nil, fmt.Errorf("IP lookup failed: %w", err)
 	}
 
+	// The scans keep the IP versions up to date afterwards
+	if !mirror.ManualIPFamilies {
+		mirror.HasIPv4, mirror.HasIPv6, _ = network.LookupIPVersions(u.Hostname())
+	}
+
 	geo := network.NewGeoIP()
 	if err := geo.LoadGeoIP(); err != nil {
 		return nil, err
@@ -421,6 +615,13 @@ func createDiff(mirror1, mirror2 *mirrors.Mirror) (out string) {
 }
 
 func (c *CLI) setMirror(mirror *mirrors.Mirror) error {
+	if _, err := mirrors.ParseScanSchedule(mirror.ScanSchedule); err != nil {
+		return status.Error(codes.InvalidArgument, "invalid scan schedule: "+err.Error())
+	}
+	if err := mirror.Labels.Validate(); err != nil {
+		return status.Error(codes.InvalidArgument, "invalid labels: "+err.Error())
+	}
+
 	conn, err := c.redis.Connect()
 	if err != nil {
 		return err
@@ -462,6 +663,7 @@ func (c *CLI) setMirror(mirror *mirrors.Mirror) error {
 	mirror.HttpURL = utils.NormalizeURL(mirror.HttpURL)
 	mirror.RsyncURL = utils.NormalizeURL(mirror.RsyncURL)
 	mirror.FtpURL = utils.NormalizeURL(mirror.FtpURL)
+	mirror.LocalURL = utils.NormalizeURL(mirror.LocalURL)
 
 	// Save the values back into redis
 	conn.Send("MULTI")
@@ -471,6 +673,7 @@ func (c *CLI) setMirror(mirror *mirrors.Mirror) error {
 		"http", mirror.HttpURL,
 		"rsync", mirror.RsyncURL,
 		"ftp", mirror.FtpURL,
+		"local", mirror.LocalURL,
 		"sponsorName", mirror.SponsorName,
 		"sponsorURL", mirror.SponsorURL,
 		"sponsorLogo", mirror.SponsorLogoURL,
@@ -489,6 +692,21 @@ func (c *CLI) setMirror(mirror *mirrors.Mirror) error {
 		"asnum", mirror.Asnum,
 		"comment", mirror.Comment,
 		"allowredirects", mirror.AllowRedirects,
+		"bandwidthCapacity", mirror.BandwidthCapacity,
+		"tier", mirror.Tier,
+		"maxConnections", mirror.MaxConnections,
+		"region", mirror.Region,
+		"hasIPv4", mirror.HasIPv4,
+		"hasIPv6", mirror.HasIPv6,
+		"manualIPFamilies", mirror.ManualIPFamilies,
+		"httpHeaders", mirror.HTTPHeaders,
+		"clientCertFile", mirror.ClientCertFile,
+		"clientKeyFile", mirror.ClientKeyFile,
+		"ftpUseTLS", mirror.FtpUseTLS,
+		"ftpInsecureSkipVerify", mirror.FtpInsecureSkipVerify,
+		"scanSchedule", mirror.ScanSchedule,
+		"scanProxy", mirror.ScanProxy,
+		"labels", mirror.Labels,
 		"enabled", mirror.Enabled)
 
 	// Reset state to down for unsupported protocol
@@ -560,6 +778,8 @@ func (c *CLI) RemoveMirror(ctx context.Context, in *MirrorIDRequest) (*empty.Emp
 		fmt.Sprintf("MIRRORFILES_%d", in.ID),
 		fmt.Sprintf("MIRRORFILESTMP_%d", in.ID),
 		fmt.Sprintf("HANDLEDFILES_%d", in.ID),
+		fmt.Sprintf("MIRRORSUSPECT_%d", in.ID),
+		scan.HashMismatchKey(int(in.ID)),
 		fmt.Sprintf("SCANNING_%d", in.ID),
 		fmt.Sprintf("MIRRORLOGS_%d", in.ID))
 
@@ -585,6 +805,12 @@ func (c *CLI) ScanMirror(ctx context.Context, in *ScanMirrorRequest) (*ScanMirro
 	if in.ID <= 0 {
 		return nil, status.Error(codes.FailedPrecondition, "invalid mirror id")
 	}
+	if _, err := scan.CleanScanPath(in.Only); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if in.ForceRehash && !GetConfig().CrossCheckHashes {
+		return nil, status.Error(codes.FailedPrecondition, "the files of the mirrors are only hashed with CrossCheckHashes enabled")
+	}
 
 	conn, err := c.redis.Connect()
 	if err != nil {
@@ -613,6 +839,30 @@ func (c *CLI) ScanMirror(ctx context.Context, in *ScanMirrorRequest) (*ScanMirro
 		return nil, err
 	}
 
+	if scanning, err := scan.IsScanning(conn, mirror.ID); err != nil {
+		return nil, err
+	} else if scanning && !in.DryRun {
+		return nil, status.Error(codes.AlreadyExists, scan.ErrScanInProgress.Error())
+	}
+
+	release, err := scan.Acquire(mirror.Name, ctx.Done())
+	if err != nil {
+		return nil, status.Error(codes.Canceled, err.Error())
+	}
+	defer release()
+
+	// Manual scans bypass the schedule of the mirror, the client is
+	// expected to warn about it
+	outside := !mirror.InScanWindow(time.Now())
+
+	if in.DryRun {
+		reply, err := c.scanMirrorDryRun(ctx, in, mirror)
+		if reply != nil {
+			reply.OutsideSchedule = outside
+		}
+		return reply, err
+	}
+
 	var wg sync.WaitGroup
 	trace := scan.NewTraceHandler(c.redis, make(<-chan struct{}))
 
@@ -637,31 +887,43 @@ func (c *CLI) ScanMirror(ctx context.Context, in *ScanMirrorRequest) (*ScanMirro
 	var res *scan.ScanResult
 
 	if in.Protocol == ScanMirrorRequest_ALL {
-		// Use rsync (if applicable) and fallback to FTP
-		if mirror.RsyncURL != "" {
-			res, err = scan.Scan(core.RSYNC, c.redis, c.cache, mirror.RsyncURL, mirror.ID, ctx.Done())
+		// Use the local directory or rsync (if applicable) and fallback to FTP
+		if mirror.LocalURL != "" {
+			res, err = scan.ScanPath(core.LOCAL, c.redis, c.cache, mirror.LocalURL, mirror.ID, in.Only, in.ForceRehash, ctx.Done())
+		}
+		if err != nil && mirror.RsyncURL != "" {
+			res, err = scan.ScanPath(core.RSYNC, c.redis, c.cache, mirror.RsyncURL, mirror.ID, in.Only, in.ForceRehash, ctx.Done())
 		}
 		if err != nil && mirror.FtpURL != "" {
-			res, err = scan.Scan(core.FTP, c.redis, c.cache, mirror.FtpURL, mirror.ID, ctx.Done())
+			res, err = scan.ScanPath(core.FTP, c.redis, c.cache, mirror.FtpURL, mirror.ID, in.Only, in.ForceRehash, ctx.Done())
 		}
 	} else {
 		// Use the requested protocol
 		if in.Protocol == ScanMirrorRequest_RSYNC && mirror.RsyncURL != "" {
-			res, err = scan.Scan(core.RSYNC, c.redis, c.cache, mirror.RsyncURL, mirror.ID, ctx.Done())
+			res, err = scan.ScanPath(core.RSYNC, c.redis, c.cache, mirror.RsyncURL, mirror.ID, in.Only, in.ForceRehash, ctx.Done())
 		} else if in.Protocol == ScanMirrorRequest_FTP && mirror.FtpURL != "" {
-			res, err = scan.Scan(core.FTP, c.redis, c.cache, mirror.FtpURL, mirror.ID, ctx.Done())
+			res, err = scan.ScanPath(core.FTP, c.redis, c.cache, mirror.FtpURL, mirror.ID, in.Only, in.ForceRehash, ctx.Done())
+		} else if in.Protocol == ScanMirrorRequest_LOCAL && mirror.LocalURL != "" {
+			res, err = scan.ScanPath(core.LOCAL, c.redis, c.cache, mirror.LocalURL, mirror.ID, in.Only, in.ForceRehash, ctx.Done())
 		}
 	}
 
-	if err != nil {
+	if errors.Is(err, scan.ErrScanInProgress) {
+		return nil, status.Error(codes.AlreadyExists, err.Error())
+	} else if err != nil {
 		return nil, errors.New(fmt.Sprintf("scanning %s failed: %s", mirror.Name, err))
 	}
 
 	reply := &ScanMirrorReply{
-		FilesIndexed: res.FilesIndexed,
-		KnownIndexed: res.KnownIndexed,
-		Removed:      res.Removed,
-		TZOffsetMs:   res.TZOffsetMs,
+		FilesIndexed:    res.FilesIndexed,
+		KnownIndexed:    res.KnownIndexed,
+		Removed:         res.Removed,
+		Suspect:         res.Suspect,
+		TZOffsetMs:      res.TZOffsetMs,
+		OutsideSchedule: outside,
+		HashMismatches:  res.Mismatches,
+		MaxFiles:        res.MaxFiles,
+		Truncated:       res.Truncated,
 	}
 
 	// Finally enable the mirror if requested
@@ -677,6 +939,76 @@ func (c *CLI) ScanMirror(ctx context.Context, in *ScanMirrorRequest) (*ScanMirro
 	return reply, nil
 }
 
+// ScanStatus reports the progress of the scan of a mirror started by this
+// server, either over RPC or by the daemon
+func (c *CLI) ScanStatus(ctx context.Context, in *MirrorIDRequest) (*ScanStatusReply, error) {
+	if in.ID <= 0 {
+		return nil, status.Error(codes.FailedPrecondition, "invalid mirror id")
+	}
+
+	p, ok := scan.ScanProgress(int(in.ID))
+	if !ok {
+		return &ScanStatusReply{}, nil
+	}
+
+	started, err := ptypes.TimestampProto(p.Started)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ScanStatusReply{
+		Running:  true,
+		Protocol: p.Protocol,
+		Phase:    p.Phase,
+		Started:  started,
+		Files:    p.Files,
+	}, nil
+}
+
+// scanMirrorDryRun reports the changes a scan would apply to the index of
+// the mirror. The trace file is not fetched and the mirror is left untouched.
+func (c *CLI) scanMirrorDryRun(ctx context.Context, in *ScanMirrorRequest, mirror mirrors.Mirror) (*ScanMirrorReply, error) {
+	err := scan.ErrNoSyncMethod
+	var res *scan.DryRunResult
+
+	if in.Protocol == ScanMirrorRequest_ALL {
+		// Use the local directory or rsync (if applicable) and fallback to FTP
+		if mirror.LocalURL != "" {
+			res, err = scan.ScanDryRun(core.LOCAL, c.redis, c.cache, mirror.LocalURL, mirror.ID, in.Only, ctx.Done())
+		}
+		if err != nil && mirror.RsyncURL != "" {
+			res, err = scan.ScanDryRun(core.RSYNC, c.redis, c.cache, mirror.RsyncURL, mirror.ID, in.Only, ctx.Done())
+		}
+		if err != nil && mirror.FtpURL != "" {
+			res, err = scan.ScanDryRun(core.FTP, c.redis, c.cache, mirror.FtpURL, mirror.ID, in.Only, ctx.Done())
+		}
+	} else {
+		// Use the requested protocol
+		if in.Protocol == ScanMirrorRequest_RSYNC && mirror.RsyncURL != "" {
+			res, err = scan.ScanDryRun(core.RSYNC, c.redis, c.cache, mirror.RsyncURL, mirror.ID, in.Only, ctx.Done())
+		} else if in.Protocol == ScanMirrorRequest_FTP && mirror.FtpURL != "" {
+			res, err = scan.ScanDryRun(core.FTP, c.redis, c.cache, mirror.FtpURL, mirror.ID, in.Only, ctx.Done())
+		} else if in.Protocol == ScanMirrorRequest_LOCAL && mirror.LocalURL != "" {
+			res, err = scan.ScanDryRun(core.LOCAL, c.redis, c.cache, mirror.LocalURL, mirror.ID, in.Only, ctx.Done())
+		}
+	}
+
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("scanning %s failed: %s", mirror.Name, err))
+	}
+
+	return &ScanMirrorReply{
+		FilesIndexed:  res.FilesFound,
+		KnownIndexed:  res.KnownFound,
+		Removed:       res.Removed,
+		Added:         res.Added,
+		Changed:       res.Changed,
+		SampleAdded:   res.SampleAdded,
+		SampleRemoved: res.SampleRemoved,
+		SampleChanged: res.SampleChanged,
+	}, nil
+}
+
 func (c *CLI) StatsFile(ctx context.Context, in *StatsFileRequest) (*StatsFileReply, error) {
 	conn, err := c.redis.Connect()
 	if err != nil {
@@ -738,6 +1070,102 @@ func (c *CLI) StatsFile(ctx context.Context, in *StatsFileRequest) (*StatsFileRe
 	return reply, nil
 }
 
+// TopFiles returns the most downloaded files during the day, month or year
+// of the given date, or since the beginning
+func (c *CLI) TopFiles(ctx context.Context, in *TopFilesRequest) (*TopFilesReply, error) {
+	date := time.Now().UTC()
+	if in.Date != nil {
+		var err error
+		if date, err = ptypes.Timestamp(in.Date); err != nil {
+			return nil, err
+		}
+	}
+
+	var key string
+	switch in.Period {
+	case "daily":
+		key = "STATS_FILE_" + date.Format("2006_01_02")
+	case "monthly":
+		key = "STATS_FILE_" + date.Format("2006_01")
+	case "yearly":
+		key = "STATS_FILE_" + date.Format("2006")
+	case "all":
+		key = "STATS_FILE"
+	default:
+		return nil, status.Error(codes.InvalidArgument, "period must be daily, monthly, yearly or all")
+	}
+
+	limit := int(in.Limit)
+	if limit <= 0 {
+		limit = 10
+	}
+
+	conn, err := c.redis.Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	files, err := topFiles(conn, key, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &TopFilesReply{Files: files}, nil
+}
+
+// topFiles returns the limit files with the most downloads in the given
+// stats hash, iterating over it to only keep the top files in memory
+func topFiles(conn redis.Conn, key string, limit int) ([]*TopFile, error) {
+	top := &topFilesHeap{}
+	cursor := 0
+	for {
+		values, err := redis.Values(conn.Do("HSCAN", key, cursor, "COUNT", 1000))
+		if err != nil {
+			return nil, fmt.Errorf("can't fetch stats: %w", err)
+		}
+		var fields []string
+		if _, err = redis.Scan(values, &cursor, &fields); err != nil {
+			return nil, err
+		}
+		for i := 0; i+1 < len(fields); i += 2 {
+			downloads, _ := strconv.ParseInt(fields[i+1], 10, 64)
+			heap.Push(top, &TopFile{Path: fields[i], Downloads: downloads})
+			if top.Len() > limit {
+				heap.Pop(top)
+			}
+		}
+		if cursor == 0 {
+			break
+		}
+	}
+
+	files := make([]*TopFile, top.Len())
+	for i := len(files) - 1; i >= 0; i-- {
+		files[i] = heap.Pop(top).(*TopFile)
+	}
+	return files, nil
+}
+
+// topFilesHeap is a min-heap of files ordered by downloads, ties are
+// broken by path so the results are stable
+type topFilesHeap []*TopFile
+
+func (h topFilesHeap) Len() int { return len(h) }
+func (h topFilesHeap) Less(i, j int) bool {
+	if h[i].Downloads == h[j].Downloads {
+		return h[i].Path > h[j].Path
+	}
+	return h[i].Downloads < h[j].Downloads
+}
+func (h topFilesHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *topFilesHeap) Push(x any)   { *h = append(*h, x.(*TopFile)) }
+func (h *topFilesHeap) Pop() any {
+	old := *h
+	f := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return f
+}
+
 func (c *CLI) StatsMirror(ctx context.Context, in *StatsMirrorRequest) (*StatsMirrorReply, error) {
 	if in.ID <= 0 {
 		return nil, status.Error(codes.FailedPrecondition, "invalid mirror id")
@@ -804,6 +1232,160 @@ func (c *CLI) StatsMirror(ctx context.Context, in *StatsMirrorRequest) (*StatsMi
 	return reply, nil
 }
 
+// ExportStats returns the download stats of every mirror for each day,
+// month or year of the date range. The stats of a period are computed like
+// StatsMirror does for the part of the range within this period.
+func (c *CLI) ExportStats(ctx context.Context, in *ExportStatsRequest) (*ExportStatsReply, error) {
+	start, err := ptypes.Timestamp(in.DateStart)
+	if err != nil {
+		return nil, err
+	}
+	end, err := ptypes.Timestamp(in.DateEnd)
+	if err != nil {
+		return nil, err
+	}
+	if end.Before(start) {
+		return nil, status.Error(codes.InvalidArgument, "the end date is before the start date")
+	}
+	periods, err := utils.SplitStatsPeriods(start, end, in.Period)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	mirrorsMap, err := c.redis.GetListOfMirrors()
+	if err != nil {
+		return nil, fmt.Errorf("can't fetch the list of mirrors: %w", err)
+	}
+
+	ids := make([]int, 0, len(mirrorsMap))
+	for id := range mirrorsMap {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return mirrorsMap[ids[i]] < mirrorsMap[ids[j]]
+	})
+
+	// Generate the list of redis keys of each period
+	coverage := make([][]string, len(periods))
+	for i, p := range periods {
+		coverage[i] = utils.TimeKeyCoverage(p.Start, p.End)
+	}
+
+	conn, err := c.redis.Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	reply := &ExportStatsReply{}
+	conn.Send("MULTI")
+	for _, id := range ids {
+		for i, p := range periods {
+			reply.Stats = append(reply.Stats, &MirrorPeriodStats{
+				ID:     int32(id),
+				Name:   mirrorsMap[id],
+				Period: p.Label,
+			})
+			for _, k := range coverage[i] {
+				conn.Send("HGET", "STATS_MIRROR_"+k, id)
+				conn.Send("HGET", "STATS_MIRROR_BYTES_"+k, id)
+			}
+		}
+	}
+	stats, err := redis.Strings(conn.Do("EXEC"))
+	if err != nil {
+		return nil, fmt.Errorf("can't fetch stats: %w", err)
+	}
+
+	index := 0
+	for i, s := range reply.Stats {
+		for range coverage[i%len(periods)] {
+			v1, _ := strconv.ParseInt(stats[index], 10, 64)
+			v2, _ := strconv.ParseInt(stats[index+1], 10, 64)
+			s.Requests += v1
+			s.Bytes += v2
+			index += 2
+		}
+	}
+
+	return reply, nil
+}
+
+// ResetStats clears the download counters of a mirror, or of all the
+// mirrors, for every period. The mirrors themselves are left untouched.
+func (c *CLI) ResetStats(ctx context.Context, in *ResetStatsRequest) (*ResetStatsReply, error) {
+	if !in.All && in.ID <= 0 {
+		return nil, status.Error(codes.FailedPrecondition, "invalid mirror id")
+	}
+
+	conn, err := c.redis.Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var name string
+	if !in.All {
+		name, err = redis.String(conn.Do("HGET", "MIRRORS", in.ID))
+		if err == redis.ErrNil {
+			return nil, status.Error(codes.NotFound, "mirror not found")
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	// The all-time counters and the ones of each year, month and day
+	keys := []string{"STATS_MIRROR", "STATS_MIRROR_BYTES"}
+	cursor := 0
+	for {
+		values, err := redis.Values(conn.Do("SCAN", cursor, "MATCH", "STATS_MIRROR_*", "COUNT", 1000))
+		if err != nil {
+			return nil, err
+		}
+		var batch []string
+		if _, err = redis.Scan(values, &cursor, &batch); err != nil {
+			return nil, err
+		}
+		keys = append(keys, batch...)
+		if cursor == 0 {
+			break
+		}
+	}
+
+	conn.Send("MULTI")
+	for _, key := range keys {
+		if in.All {
+			conn.Send("DEL", key)
+		} else {
+			conn.Send("HDEL", key, in.ID)
+		}
+	}
+	removed, err := redis.Int64s(conn.Do("EXEC"))
+	if err != nil {
+		return nil, fmt.Errorf("can't reset stats: %w", err)
+	}
+
+	reply := &ResetStatsReply{}
+	for _, n := range removed {
+		reply.Counters += n
+	}
+
+	operator := in.Operator
+	if operator == "" {
+		operator = "unknown user"
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		operator += " from " + p.Addr.String()
+	}
+	if in.All {
+		log.Noticef("Download stats of all mirrors reset by %s", operator)
+	} else {
+		log.Noticef("Download stats of %s reset by %s", name, operator)
+	}
+
+	return reply, nil
+}
+
 func (c *CLI) GetMirrorLogs(ctx context.Context, in *GetMirrorLogsRequest) (*GetMirrorLogsReply, error) {
 	if in.ID <= 0 {
 		return nil, status.Error(codes.FailedPrecondition, "invalid mirror id")
@@ -816,3 +1398,121 @@ func (c *CLI) GetMirrorLogs(ctx context.Context, in *GetMirrorLogsRequest) (*Get
 
 	return &GetMirrorLogsReply{Line: lines}, nil
 }
+
+func (c *CLI) FileMirrors(ctx context.Context, in *FileMirrorsRequest) (*FileMirrorsReply, error) {
+	if c.cache == nil {
+		return nil, status.Error(codes.Unavailable, "cache not ready")
+	}
+
+	path := in.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	// Read the same index as the redirector does
+	mlist, err := c.cache.GetMirrors(ctx, path, network.GeoIPRecord{})
+	if err != nil {
+		return nil, fmt.Errorf("can't fetch the file mirrors: %w", err)
+	}
+
+	sort.Slice(mlist, func(i, j int) bool {
+		return mlist[i].Name < mlist[j].Name
+	})
+
+	reply := &FileMirrorsReply{}
+	for _, m := range mlist {
+		// Only keep the mirrors eligible for the selection
+		if !m.Enabled || (!m.HttpUp && !m.HttpsUp) {
+			continue
+		}
+		fm := &FileMirror{
+			ID:   int32(m.ID),
+			Name: m.Name,
+		}
+		if m.FileInfo != nil {
+			fm.Size = m.FileInfo.Size
+			if !m.FileInfo.ModTime.IsZero() {
+				fm.ModTime, err = ptypes.TimestampProto(m.FileInfo.ModTime)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		reply.Mirrors = append(reply.Mirrors, fm)
+	}
+
+	return reply, nil
+}
+
+func (c *CLI) DiffMirrors(ctx context.Context, in *DiffMirrorsRequest) (*DiffMirrorsReply, error) {
+	if in.IDA <= 0 || in.IDB <= 0 {
+		return nil, status.Error(codes.FailedPrecondition, "invalid mirror id")
+	}
+	if in.IDA == in.IDB {
+		return nil, status.Error(codes.InvalidArgument, "can't compare a mirror with itself")
+	}
+
+	diff, err := mirrors.DiffMirrors(c.redis, int(in.IDA), int(in.IDB), in.Full)
+	if err != nil {
+		return nil, fmt.Errorf("can't compare the mirrors: %w", err)
+	}
+
+	return &DiffMirrorsReply{
+		Common:        diff.Common,
+		OnlyA:         diff.OnlyA,
+		OnlyB:         diff.OnlyB,
+		Changed:       diff.Changed,
+		SampleOnlyA:   diff.SampleOnlyA,
+		SampleOnlyB:   diff.SampleOnlyB,
+		SampleChanged: diff.SampleChanged,
+	}, nil
+}
+
+func (c *CLI) Simulate(ctx context.Context, in *SimulateRequest) (*SimulateReply, error) {
+	if c.sim == nil {
+		return nil, status.Error(codes.Unavailable, "http server not ready")
+	}
+
+	path := in.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	results, err := c.sim.Simulate(ctx, in.IP, path)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	geo := results.ClientInfo
+	reply := &SimulateReply{
+		Located:  geo.IsValid(),
+		Fallback: results.Fallback,
+	}
+	if geo.IsValid() {
+		reply.Country = geo.Country
+		reply.CountryCode = geo.CountryCode
+		reply.Continent = geo.ContinentCode
+		reply.Latitude = geo.Latitude
+		reply.Longitude = geo.Longitude
+		reply.ASN = fmt.Sprintf("%s (%d)", geo.ASName, geo.ASNum)
+	}
+
+	simulated := func(m mirrors.Mirror) *SimulatedMirror {
+		return &SimulatedMirror{
+			ID:            int32(m.ID),
+			Name:          m.Name,
+			URL:           m.AbsoluteURL,
+			Distance:      m.Distance,
+			Score:         int32(m.ComputedScore),
+			ExcludeReason: m.ExcludeReason,
+		}
+	}
+	for _, m := range results.MirrorList {
+		reply.Mirrors = append(reply.Mirrors, simulated(m))
+	}
+	for _, m := range results.ExcludedList {
+		reply.Excluded = append(reply.Excluded, simulated(m))
+	}
+
+	return reply, nil
+}
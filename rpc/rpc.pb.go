@@ -32,18 +32,21 @@ const (
 	ScanMirrorRequest_ALL   ScanMirrorRequest_Method = 0
 	ScanMirrorRequest_FTP   ScanMirrorRequest_Method = 1
 	ScanMirrorRequest_RSYNC ScanMirrorRequest_Method = 2
+	ScanMirrorRequest_LOCAL ScanMirrorRequest_Method = 3
 )
 
 var ScanMirrorRequest_Method_name = map[int32]string{
 	0: "ALL",
 	1: "FTP",
 	2: "RSYNC",
+	3: "LOCAL",
 }
 
 var ScanMirrorRequest_Method_value = map[string]int32{
 	"ALL":   0,
 	"FTP":   1,
 	"RSYNC": 2,
+	"LOCAL": 3,
 }
 
 func (x ScanMirrorRequest_Method) String() string {
@@ -51,7 +54,7 @@ func (x ScanMirrorRequest_Method) String() string {
 }
 
 func (ScanMirrorRequest_Method) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_77a6da22d6a3feb1, []int{12, 0}
+	return fileDescriptor_77a6da22d6a3feb1, []int{19, 0}
 }
 
 type VersionReply struct {
@@ -61,6 +64,11 @@ type VersionReply struct {
 	OS                   string   `protobuf:"bytes,4,opt,name=OS,proto3" json:"OS,omitempty"`
 	Arch                 string   `protobuf:"bytes,5,opt,name=Arch,proto3" json:"Arch,omitempty"`
 	GoMaxProcs           int32    `protobuf:"varint,6,opt,name=GoMaxProcs,proto3" json:"GoMaxProcs,omitempty"`
+	Commit               string   `protobuf:"bytes,7,opt,name=Commit,proto3" json:"Commit,omitempty"`
+	BuildDate            string   `protobuf:"bytes,8,opt,name=BuildDate,proto3" json:"BuildDate,omitempty"`
+	Daemon               bool     `protobuf:"varint,9,opt,name=Daemon,proto3" json:"Daemon,omitempty"`
+	Uptime               int64    `protobuf:"varint,10,opt,name=Uptime,proto3" json:"Uptime,omitempty"`
+	ConfigFile           string   `protobuf:"bytes,11,opt,name=ConfigFile,proto3" json:"ConfigFile,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -133,6 +141,41 @@ func (m *VersionReply) GetGoMaxProcs() int32 {
 	return 0
 }
 
+func (m *VersionReply) GetCommit() string {
+	if m != nil {
+		return m.Commit
+	}
+	return ""
+}
+
+func (m *VersionReply) GetBuildDate() string {
+	if m != nil {
+		return m.BuildDate
+	}
+	return ""
+}
+
+func (m *VersionReply) GetDaemon() bool {
+	if m != nil {
+		return m.Daemon
+	}
+	return false
+}
+
+func (m *VersionReply) GetUptime() int64 {
+	if m != nil {
+		return m.Uptime
+	}
+	return 0
+}
+
+func (m *VersionReply) GetConfigFile() string {
+	if m != nil {
+		return m.ConfigFile
+	}
+	return ""
+}
+
 type MatchRequest struct {
 	Pattern              string   `protobuf:"bytes,1,opt,name=Pattern,proto3" json:"Pattern,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -173,41 +216,65 @@ func (m *MatchRequest) GetPattern() string {
 }
 
 type Mirror struct {
-	ID                   int32                `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
-	Name                 string               `protobuf:"bytes,2,opt,name=Name,proto3" json:"Name,omitempty"`
-	HttpURL              string               `protobuf:"bytes,3,opt,name=HttpURL,proto3" json:"HttpURL,omitempty"`
-	RsyncURL             string               `protobuf:"bytes,4,opt,name=RsyncURL,proto3" json:"RsyncURL,omitempty"`
-	FtpURL               string               `protobuf:"bytes,5,opt,name=FtpURL,proto3" json:"FtpURL,omitempty"`
-	SponsorName          string               `protobuf:"bytes,6,opt,name=SponsorName,proto3" json:"SponsorName,omitempty"`
-	SponsorURL           string               `protobuf:"bytes,7,opt,name=SponsorURL,proto3" json:"SponsorURL,omitempty"`
-	SponsorLogoURL       string               `protobuf:"bytes,8,opt,name=SponsorLogoURL,proto3" json:"SponsorLogoURL,omitempty"`
-	AdminName            string               `protobuf:"bytes,9,opt,name=AdminName,proto3" json:"AdminName,omitempty"`
-	AdminEmail           string               `protobuf:"bytes,10,opt,name=AdminEmail,proto3" json:"AdminEmail,omitempty"`
-	CustomData           string               `protobuf:"bytes,11,opt,name=CustomData,proto3" json:"CustomData,omitempty"`
-	ContinentOnly        bool                 `protobuf:"varint,12,opt,name=ContinentOnly,proto3" json:"ContinentOnly,omitempty"`
-	CountryOnly          bool                 `protobuf:"varint,13,opt,name=CountryOnly,proto3" json:"CountryOnly,omitempty"`
-	ASOnly               bool                 `protobuf:"varint,14,opt,name=ASOnly,proto3" json:"ASOnly,omitempty"`
-	Score                int32                `protobuf:"varint,15,opt,name=Score,proto3" json:"Score,omitempty"`
-	Latitude             float32              `protobuf:"fixed32,16,opt,name=Latitude,proto3" json:"Latitude,omitempty"`
-	Longitude            float32              `protobuf:"fixed32,17,opt,name=Longitude,proto3" json:"Longitude,omitempty"`
-	ContinentCode        string               `protobuf:"bytes,18,opt,name=ContinentCode,proto3" json:"ContinentCode,omitempty"`
-	CountryCodes         string               `protobuf:"bytes,19,opt,name=CountryCodes,proto3" json:"CountryCodes,omitempty"`
-	ExcludedCountryCodes string               `protobuf:"bytes,20,opt,name=ExcludedCountryCodes,proto3" json:"ExcludedCountryCodes,omitempty"`
-	Asnum                uint32               `protobuf:"varint,21,opt,name=Asnum,proto3" json:"Asnum,omitempty"`
-	Comment              string               `protobuf:"bytes,22,opt,name=Comment,proto3" json:"Comment,omitempty"`
-	Enabled              bool                 `protobuf:"varint,23,opt,name=Enabled,proto3" json:"Enabled,omitempty"`
-	HttpUp               bool                 `protobuf:"varint,24,opt,name=HttpUp,proto3" json:"HttpUp,omitempty"`
-	HttpDownReason       string               `protobuf:"bytes,25,opt,name=HttpDownReason,proto3" json:"HttpDownReason,omitempty"`
-	StateSince           *timestamp.Timestamp `protobuf:"bytes,26,opt,name=StateSince,proto3" json:"StateSince,omitempty"`
-	AllowRedirects       int32                `protobuf:"varint,27,opt,name=AllowRedirects,proto3" json:"AllowRedirects,omitempty"`
-	LastSync             *timestamp.Timestamp `protobuf:"bytes,28,opt,name=LastSync,proto3" json:"LastSync,omitempty"`
-	LastSuccessfulSync   *timestamp.Timestamp `protobuf:"bytes,29,opt,name=LastSuccessfulSync,proto3" json:"LastSuccessfulSync,omitempty"`
-	LastModTime          *timestamp.Timestamp `protobuf:"bytes,30,opt,name=LastModTime,proto3" json:"LastModTime,omitempty"`
-	HttpsUp              bool                 `protobuf:"varint,31,opt,name=HttpsUp,proto3" json:"HttpsUp,omitempty"`
-	HttpsDownReason      string               `protobuf:"bytes,32,opt,name=HttpsDownReason,proto3" json:"HttpsDownReason,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
-	XXX_unrecognized     []byte               `json:"-"`
-	XXX_sizecache        int32                `json:"-"`
+	ID                    int32                `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	Name                  string               `protobuf:"bytes,2,opt,name=Name,proto3" json:"Name,omitempty"`
+	HttpURL               string               `protobuf:"bytes,3,opt,name=HttpURL,proto3" json:"HttpURL,omitempty"`
+	RsyncURL              string               `protobuf:"bytes,4,opt,name=RsyncURL,proto3" json:"RsyncURL,omitempty"`
+	FtpURL                string               `protobuf:"bytes,5,opt,name=FtpURL,proto3" json:"FtpURL,omitempty"`
+	SponsorName           string               `protobuf:"bytes,6,opt,name=SponsorName,proto3" json:"SponsorName,omitempty"`
+	SponsorURL            string               `protobuf:"bytes,7,opt,name=SponsorURL,proto3" json:"SponsorURL,omitempty"`
+	SponsorLogoURL        string               `protobuf:"bytes,8,opt,name=SponsorLogoURL,proto3" json:"SponsorLogoURL,omitempty"`
+	AdminName             string               `protobuf:"bytes,9,opt,name=AdminName,proto3" json:"AdminName,omitempty"`
+	AdminEmail            string               `protobuf:"bytes,10,opt,name=AdminEmail,proto3" json:"AdminEmail,omitempty"`
+	CustomData            string               `protobuf:"bytes,11,opt,name=CustomData,proto3" json:"CustomData,omitempty"`
+	ContinentOnly         bool                 `protobuf:"varint,12,opt,name=ContinentOnly,proto3" json:"ContinentOnly,omitempty"`
+	CountryOnly           bool                 `protobuf:"varint,13,opt,name=CountryOnly,proto3" json:"CountryOnly,omitempty"`
+	ASOnly                bool                 `protobuf:"varint,14,opt,name=ASOnly,proto3" json:"ASOnly,omitempty"`
+	Score                 int32                `protobuf:"varint,15,opt,name=Score,proto3" json:"Score,omitempty"`
+	Latitude              float32              `protobuf:"fixed32,16,opt,name=Latitude,proto3" json:"Latitude,omitempty"`
+	Longitude             float32              `protobuf:"fixed32,17,opt,name=Longitude,proto3" json:"Longitude,omitempty"`
+	ContinentCode         string               `protobuf:"bytes,18,opt,name=ContinentCode,proto3" json:"ContinentCode,omitempty"`
+	CountryCodes          string               `protobuf:"bytes,19,opt,name=CountryCodes,proto3" json:"CountryCodes,omitempty"`
+	ExcludedCountryCodes  string               `protobuf:"bytes,20,opt,name=ExcludedCountryCodes,proto3" json:"ExcludedCountryCodes,omitempty"`
+	Asnum                 uint32               `protobuf:"varint,21,opt,name=Asnum,proto3" json:"Asnum,omitempty"`
+	Comment               string               `protobuf:"bytes,22,opt,name=Comment,proto3" json:"Comment,omitempty"`
+	Enabled               bool                 `protobuf:"varint,23,opt,name=Enabled,proto3" json:"Enabled,omitempty"`
+	HttpUp                bool                 `protobuf:"varint,24,opt,name=HttpUp,proto3" json:"HttpUp,omitempty"`
+	HttpDownReason        string               `protobuf:"bytes,25,opt,name=HttpDownReason,proto3" json:"HttpDownReason,omitempty"`
+	StateSince            *timestamp.Timestamp `protobuf:"bytes,26,opt,name=StateSince,proto3" json:"StateSince,omitempty"`
+	AllowRedirects        int32                `protobuf:"varint,27,opt,name=AllowRedirects,proto3" json:"AllowRedirects,omitempty"`
+	LastSync              *timestamp.Timestamp `protobuf:"bytes,28,opt,name=LastSync,proto3" json:"LastSync,omitempty"`
+	LastSuccessfulSync    *timestamp.Timestamp `protobuf:"bytes,29,opt,name=LastSuccessfulSync,proto3" json:"LastSuccessfulSync,omitempty"`
+	LastModTime           *timestamp.Timestamp `protobuf:"bytes,30,opt,name=LastModTime,proto3" json:"LastModTime,omitempty"`
+	HttpsUp               bool                 `protobuf:"varint,31,opt,name=HttpsUp,proto3" json:"HttpsUp,omitempty"`
+	HttpsDownReason       string               `protobuf:"bytes,32,opt,name=HttpsDownReason,proto3" json:"HttpsDownReason,omitempty"`
+	BandwidthCapacity     int32                `protobuf:"varint,33,opt,name=BandwidthCapacity,proto3" json:"BandwidthCapacity,omitempty"`
+	HTTPHeaders           map[string]string    `protobuf:"bytes,34,rep,name=HTTPHeaders,proto3" json:"HTTPHeaders,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	ForcedUp              bool                 `protobuf:"varint,35,opt,name=ForcedUp,proto3" json:"ForcedUp,omitempty"`
+	ForcedUntil           *timestamp.Timestamp `protobuf:"bytes,36,opt,name=ForcedUntil,proto3" json:"ForcedUntil,omitempty"`
+	ClientCertFile        string               `protobuf:"bytes,37,opt,name=ClientCertFile,proto3" json:"ClientCertFile,omitempty"`
+	ClientKeyFile         string               `protobuf:"bytes,38,opt,name=ClientKeyFile,proto3" json:"ClientKeyFile,omitempty"`
+	Tier                  int32                `protobuf:"varint,39,opt,name=Tier,proto3" json:"Tier,omitempty"`
+	CooldownUntil         *timestamp.Timestamp `protobuf:"bytes,40,opt,name=CooldownUntil,proto3" json:"CooldownUntil,omitempty"`
+	Throughput            float64              `protobuf:"fixed64,41,opt,name=Throughput,proto3" json:"Throughput,omitempty"`
+	ThroughputUpdated     *timestamp.Timestamp `protobuf:"bytes,42,opt,name=ThroughputUpdated,proto3" json:"ThroughputUpdated,omitempty"`
+	FtpUseTLS             bool                 `protobuf:"varint,43,opt,name=FtpUseTLS,proto3" json:"FtpUseTLS,omitempty"`
+	FtpInsecureSkipVerify bool                 `protobuf:"varint,44,opt,name=FtpInsecureSkipVerify,proto3" json:"FtpInsecureSkipVerify,omitempty"`
+	ScanSchedule          string               `protobuf:"bytes,45,opt,name=ScanSchedule,proto3" json:"ScanSchedule,omitempty"`
+	MaxConnections        int32                `protobuf:"varint,46,opt,name=MaxConnections,proto3" json:"MaxConnections,omitempty"`
+	HashMismatches        []string             `protobuf:"bytes,47,rep,name=HashMismatches,proto3" json:"HashMismatches,omitempty"`
+	Region                string               `protobuf:"bytes,48,opt,name=Region,proto3" json:"Region,omitempty"`
+	HasIPv4               bool                 `protobuf:"varint,49,opt,name=HasIPv4,proto3" json:"HasIPv4,omitempty"`
+	HasIPv6               bool                 `protobuf:"varint,50,opt,name=HasIPv6,proto3" json:"HasIPv6,omitempty"`
+	ManualIPFamilies      bool                 `protobuf:"varint,51,opt,name=ManualIPFamilies,proto3" json:"ManualIPFamilies,omitempty"`
+	LocalURL              string               `protobuf:"bytes,52,opt,name=LocalURL,proto3" json:"LocalURL,omitempty"`
+	ScanProxy             string               `protobuf:"bytes,53,opt,name=ScanProxy,proto3" json:"ScanProxy,omitempty"`
+	Latency               float64              `protobuf:"fixed64,54,opt,name=Latency,proto3" json:"Latency,omitempty"`
+	LatencyUpdated        *timestamp.Timestamp `protobuf:"bytes,55,opt,name=LatencyUpdated,proto3" json:"LatencyUpdated,omitempty"`
+	Labels                map[string]string    `protobuf:"bytes,56,rep,name=Labels,proto3" json:"Labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral  struct{}             `json:"-"`
+	XXX_unrecognized      []byte               `json:"-"`
+	XXX_sizecache         int32                `json:"-"`
 }
 
 func (m *Mirror) Reset()         { *m = Mirror{} }
@@ -459,6 +526,174 @@ func (m *Mirror) GetHttpsDownReason() string {
 	return ""
 }
 
+func (m *Mirror) GetBandwidthCapacity() int32 {
+	if m != nil {
+		return m.BandwidthCapacity
+	}
+	return 0
+}
+
+func (m *Mirror) GetHTTPHeaders() map[string]string {
+	if m != nil {
+		return m.HTTPHeaders
+	}
+	return nil
+}
+
+func (m *Mirror) GetForcedUp() bool {
+	if m != nil {
+		return m.ForcedUp
+	}
+	return false
+}
+
+func (m *Mirror) GetForcedUntil() *timestamp.Timestamp {
+	if m != nil {
+		return m.ForcedUntil
+	}
+	return nil
+}
+
+func (m *Mirror) GetClientCertFile() string {
+	if m != nil {
+		return m.ClientCertFile
+	}
+	return ""
+}
+
+func (m *Mirror) GetClientKeyFile() string {
+	if m != nil {
+		return m.ClientKeyFile
+	}
+	return ""
+}
+
+func (m *Mirror) GetTier() int32 {
+	if m != nil {
+		return m.Tier
+	}
+	return 0
+}
+
+func (m *Mirror) GetCooldownUntil() *timestamp.Timestamp {
+	if m != nil {
+		return m.CooldownUntil
+	}
+	return nil
+}
+
+func (m *Mirror) GetThroughput() float64 {
+	if m != nil {
+		return m.Throughput
+	}
+	return 0
+}
+
+func (m *Mirror) GetThroughputUpdated() *timestamp.Timestamp {
+	if m != nil {
+		return m.ThroughputUpdated
+	}
+	return nil
+}
+
+func (m *Mirror) GetFtpUseTLS() bool {
+	if m != nil {
+		return m.FtpUseTLS
+	}
+	return false
+}
+
+func (m *Mirror) GetFtpInsecureSkipVerify() bool {
+	if m != nil {
+		return m.FtpInsecureSkipVerify
+	}
+	return false
+}
+
+func (m *Mirror) GetScanSchedule() string {
+	if m != nil {
+		return m.ScanSchedule
+	}
+	return ""
+}
+
+func (m *Mirror) GetMaxConnections() int32 {
+	if m != nil {
+		return m.MaxConnections
+	}
+	return 0
+}
+
+func (m *Mirror) GetHashMismatches() []string {
+	if m != nil {
+		return m.HashMismatches
+	}
+	return nil
+}
+
+func (m *Mirror) GetRegion() string {
+	if m != nil {
+		return m.Region
+	}
+	return ""
+}
+
+func (m *Mirror) GetHasIPv4() bool {
+	if m != nil {
+		return m.HasIPv4
+	}
+	return false
+}
+
+func (m *Mirror) GetHasIPv6() bool {
+	if m != nil {
+		return m.HasIPv6
+	}
+	return false
+}
+
+func (m *Mirror) GetManualIPFamilies() bool {
+	if m != nil {
+		return m.ManualIPFamilies
+	}
+	return false
+}
+
+func (m *Mirror) GetLocalURL() string {
+	if m != nil {
+		return m.LocalURL
+	}
+	return ""
+}
+
+func (m *Mirror) GetScanProxy() string {
+	if m != nil {
+		return m.ScanProxy
+	}
+	return ""
+}
+
+func (m *Mirror) GetLatency() float64 {
+	if m != nil {
+		return m.Latency
+	}
+	return 0
+}
+
+func (m *Mirror) GetLatencyUpdated() *timestamp.Timestamp {
+	if m != nil {
+		return m.LatencyUpdated
+	}
+	return nil
+}
+
+func (m *Mirror) GetLabels() map[string]string {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
 type MirrorListReply struct {
 	Mirrors              []*Mirror `protobuf:"bytes,1,rep,name=Mirrors,proto3" json:"Mirrors,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
@@ -584,147 +819,502 @@ func (m *MatchReply) GetMirrors() []*MirrorID {
 	return nil
 }
 
-type ChangeStatusRequest struct {
-	ID                   int32    `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
-	Enabled              bool     `protobuf:"varint,2,opt,name=Enabled,proto3" json:"Enabled,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+type MirrorRequestRates struct {
+	ID                   int32     `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	Name                 string    `protobuf:"bytes,2,opt,name=Name,proto3" json:"Name,omitempty"`
+	Rates                []float64 `protobuf:"fixed64,3,rep,packed,name=Rates,proto3" json:"Rates,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
 }
 
-func (m *ChangeStatusRequest) Reset()         { *m = ChangeStatusRequest{} }
-func (m *ChangeStatusRequest) String() string { return proto.CompactTextString(m) }
-func (*ChangeStatusRequest) ProtoMessage()    {}
-func (*ChangeStatusRequest) Descriptor() ([]byte, []int) {
+func (m *MirrorRequestRates) Reset()         { *m = MirrorRequestRates{} }
+func (m *MirrorRequestRates) String() string { return proto.CompactTextString(m) }
+func (*MirrorRequestRates) ProtoMessage()    {}
+func (*MirrorRequestRates) Descriptor() ([]byte, []int) {
 	return fileDescriptor_77a6da22d6a3feb1, []int{6}
 }
 
-func (m *ChangeStatusRequest) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_ChangeStatusRequest.Unmarshal(m, b)
+func (m *MirrorRequestRates) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MirrorRequestRates.Unmarshal(m, b)
 }
-func (m *ChangeStatusRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_ChangeStatusRequest.Marshal(b, m, deterministic)
+func (m *MirrorRequestRates) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MirrorRequestRates.Marshal(b, m, deterministic)
 }
-func (m *ChangeStatusRequest) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_ChangeStatusRequest.Merge(m, src)
+func (m *MirrorRequestRates) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MirrorRequestRates.Merge(m, src)
 }
-func (m *ChangeStatusRequest) XXX_Size() int {
-	return xxx_messageInfo_ChangeStatusRequest.Size(m)
+func (m *MirrorRequestRates) XXX_Size() int {
+	return xxx_messageInfo_MirrorRequestRates.Size(m)
 }
-func (m *ChangeStatusRequest) XXX_DiscardUnknown() {
-	xxx_messageInfo_ChangeStatusRequest.DiscardUnknown(m)
+func (m *MirrorRequestRates) XXX_DiscardUnknown() {
+	xxx_messageInfo_MirrorRequestRates.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_ChangeStatusRequest proto.InternalMessageInfo
+var xxx_messageInfo_MirrorRequestRates proto.InternalMessageInfo
 
-func (m *ChangeStatusRequest) GetID() int32 {
+func (m *MirrorRequestRates) GetID() int32 {
 	if m != nil {
 		return m.ID
 	}
 	return 0
 }
 
-func (m *ChangeStatusRequest) GetEnabled() bool {
+func (m *MirrorRequestRates) GetName() string {
 	if m != nil {
-		return m.Enabled
+		return m.Name
 	}
-	return false
+	return ""
 }
 
-type MirrorIDRequest struct {
-	ID                   int32    `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+func (m *MirrorRequestRates) GetRates() []float64 {
+	if m != nil {
+		return m.Rates
+	}
+	return nil
 }
 
-func (m *MirrorIDRequest) Reset()         { *m = MirrorIDRequest{} }
-func (m *MirrorIDRequest) String() string { return proto.CompactTextString(m) }
-func (*MirrorIDRequest) ProtoMessage()    {}
-func (*MirrorIDRequest) Descriptor() ([]byte, []int) {
+type RequestRatesReply struct {
+	WindowSeconds        []int64               `protobuf:"varint,1,rep,packed,name=WindowSeconds,proto3" json:"WindowSeconds,omitempty"`
+	Total                []float64             `protobuf:"fixed64,2,rep,packed,name=Total,proto3" json:"Total,omitempty"`
+	Mirrors              []*MirrorRequestRates `protobuf:"bytes,3,rep,name=Mirrors,proto3" json:"Mirrors,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
+	XXX_unrecognized     []byte                `json:"-"`
+	XXX_sizecache        int32                 `json:"-"`
+}
+
+func (m *RequestRatesReply) Reset()         { *m = RequestRatesReply{} }
+func (m *RequestRatesReply) String() string { return proto.CompactTextString(m) }
+func (*RequestRatesReply) ProtoMessage()    {}
+func (*RequestRatesReply) Descriptor() ([]byte, []int) {
 	return fileDescriptor_77a6da22d6a3feb1, []int{7}
 }
 
-func (m *MirrorIDRequest) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_MirrorIDRequest.Unmarshal(m, b)
+func (m *RequestRatesReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RequestRatesReply.Unmarshal(m, b)
 }
-func (m *MirrorIDRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_MirrorIDRequest.Marshal(b, m, deterministic)
+func (m *RequestRatesReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RequestRatesReply.Marshal(b, m, deterministic)
 }
-func (m *MirrorIDRequest) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_MirrorIDRequest.Merge(m, src)
+func (m *RequestRatesReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RequestRatesReply.Merge(m, src)
 }
-func (m *MirrorIDRequest) XXX_Size() int {
-	return xxx_messageInfo_MirrorIDRequest.Size(m)
+func (m *RequestRatesReply) XXX_Size() int {
+	return xxx_messageInfo_RequestRatesReply.Size(m)
 }
-func (m *MirrorIDRequest) XXX_DiscardUnknown() {
-	xxx_messageInfo_MirrorIDRequest.DiscardUnknown(m)
+func (m *RequestRatesReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_RequestRatesReply.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_MirrorIDRequest proto.InternalMessageInfo
+var xxx_messageInfo_RequestRatesReply proto.InternalMessageInfo
 
-func (m *MirrorIDRequest) GetID() int32 {
+func (m *RequestRatesReply) GetWindowSeconds() []int64 {
 	if m != nil {
-		return m.ID
+		return m.WindowSeconds
 	}
-	return 0
+	return nil
 }
 
-type AddMirrorReply struct {
-	Latitude             float32  `protobuf:"fixed32,1,opt,name=Latitude,proto3" json:"Latitude,omitempty"`
-	Longitude            float32  `protobuf:"fixed32,2,opt,name=Longitude,proto3" json:"Longitude,omitempty"`
-	Country              string   `protobuf:"bytes,3,opt,name=Country,proto3" json:"Country,omitempty"`
-	Continent            string   `protobuf:"bytes,4,opt,name=Continent,proto3" json:"Continent,omitempty"`
-	ASN                  string   `protobuf:"bytes,5,opt,name=ASN,proto3" json:"ASN,omitempty"`
-	Warnings             []string `protobuf:"bytes,6,rep,name=Warnings,proto3" json:"Warnings,omitempty"`
+func (m *RequestRatesReply) GetTotal() []float64 {
+	if m != nil {
+		return m.Total
+	}
+	return nil
+}
+
+func (m *RequestRatesReply) GetMirrors() []*MirrorRequestRates {
+	if m != nil {
+		return m.Mirrors
+	}
+	return nil
+}
+
+type SetMaintenanceRequest struct {
+	Enabled              bool     `protobuf:"varint,1,opt,name=Enabled,proto3" json:"Enabled,omitempty"`
+	Message              string   `protobuf:"bytes,2,opt,name=Message,proto3" json:"Message,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *AddMirrorReply) Reset()         { *m = AddMirrorReply{} }
-func (m *AddMirrorReply) String() string { return proto.CompactTextString(m) }
-func (*AddMirrorReply) ProtoMessage()    {}
-func (*AddMirrorReply) Descriptor() ([]byte, []int) {
+func (m *SetMaintenanceRequest) Reset()         { *m = SetMaintenanceRequest{} }
+func (m *SetMaintenanceRequest) String() string { return proto.CompactTextString(m) }
+func (*SetMaintenanceRequest) ProtoMessage()    {}
+func (*SetMaintenanceRequest) Descriptor() ([]byte, []int) {
 	return fileDescriptor_77a6da22d6a3feb1, []int{8}
 }
 
-func (m *AddMirrorReply) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_AddMirrorReply.Unmarshal(m, b)
+func (m *SetMaintenanceRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SetMaintenanceRequest.Unmarshal(m, b)
 }
-func (m *AddMirrorReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_AddMirrorReply.Marshal(b, m, deterministic)
+func (m *SetMaintenanceRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SetMaintenanceRequest.Marshal(b, m, deterministic)
 }
-func (m *AddMirrorReply) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_AddMirrorReply.Merge(m, src)
+func (m *SetMaintenanceRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SetMaintenanceRequest.Merge(m, src)
 }
-func (m *AddMirrorReply) XXX_Size() int {
-	return xxx_messageInfo_AddMirrorReply.Size(m)
+func (m *SetMaintenanceRequest) XXX_Size() int {
+	return xxx_messageInfo_SetMaintenanceRequest.Size(m)
 }
-func (m *AddMirrorReply) XXX_DiscardUnknown() {
-	xxx_messageInfo_AddMirrorReply.DiscardUnknown(m)
+func (m *SetMaintenanceRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SetMaintenanceRequest.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_AddMirrorReply proto.InternalMessageInfo
+var xxx_messageInfo_SetMaintenanceRequest proto.InternalMessageInfo
 
-func (m *AddMirrorReply) GetLatitude() float32 {
+func (m *SetMaintenanceRequest) GetEnabled() bool {
 	if m != nil {
-		return m.Latitude
+		return m.Enabled
 	}
-	return 0
+	return false
 }
 
-func (m *AddMirrorReply) GetLongitude() float32 {
+func (m *SetMaintenanceRequest) GetMessage() string {
 	if m != nil {
-		return m.Longitude
+		return m.Message
 	}
-	return 0
+	return ""
 }
 
-func (m *AddMirrorReply) GetCountry() string {
-	if m != nil {
-		return m.Country
-	}
+type MaintenanceReply struct {
+	Enabled bool   `protobuf:"varint,1,opt,name=Enabled,proto3" json:"Enabled,omitempty"`
+	Message string `protobuf:"bytes,2,opt,name=Message,proto3" json:"Message,omitempty"`
+	// Override is true if the mode was set by SetMaintenance rather than
+	// by the configuration
+	Override             bool     `protobuf:"varint,3,opt,name=Override,proto3" json:"Override,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MaintenanceReply) Reset()         { *m = MaintenanceReply{} }
+func (m *MaintenanceReply) String() string { return proto.CompactTextString(m) }
+func (*MaintenanceReply) ProtoMessage()    {}
+func (*MaintenanceReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{9}
+}
+
+func (m *MaintenanceReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MaintenanceReply.Unmarshal(m, b)
+}
+func (m *MaintenanceReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MaintenanceReply.Marshal(b, m, deterministic)
+}
+func (m *MaintenanceReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MaintenanceReply.Merge(m, src)
+}
+func (m *MaintenanceReply) XXX_Size() int {
+	return xxx_messageInfo_MaintenanceReply.Size(m)
+}
+func (m *MaintenanceReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_MaintenanceReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MaintenanceReply proto.InternalMessageInfo
+
+func (m *MaintenanceReply) GetEnabled() bool {
+	if m != nil {
+		return m.Enabled
+	}
+	return false
+}
+
+func (m *MaintenanceReply) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+func (m *MaintenanceReply) GetOverride() bool {
+	if m != nil {
+		return m.Override
+	}
+	return false
+}
+
+type ChangeStatusRequest struct {
+	ID                   int32    `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	Enabled              bool     `protobuf:"varint,2,opt,name=Enabled,proto3" json:"Enabled,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ChangeStatusRequest) Reset()         { *m = ChangeStatusRequest{} }
+func (m *ChangeStatusRequest) String() string { return proto.CompactTextString(m) }
+func (*ChangeStatusRequest) ProtoMessage()    {}
+func (*ChangeStatusRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{10}
+}
+
+func (m *ChangeStatusRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ChangeStatusRequest.Unmarshal(m, b)
+}
+func (m *ChangeStatusRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ChangeStatusRequest.Marshal(b, m, deterministic)
+}
+func (m *ChangeStatusRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ChangeStatusRequest.Merge(m, src)
+}
+func (m *ChangeStatusRequest) XXX_Size() int {
+	return xxx_messageInfo_ChangeStatusRequest.Size(m)
+}
+func (m *ChangeStatusRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ChangeStatusRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ChangeStatusRequest proto.InternalMessageInfo
+
+func (m *ChangeStatusRequest) GetID() int32 {
+	if m != nil {
+		return m.ID
+	}
+	return 0
+}
+
+func (m *ChangeStatusRequest) GetEnabled() bool {
+	if m != nil {
+		return m.Enabled
+	}
+	return false
+}
+
+type SetMirrorsEnabledByTagRequest struct {
+	Tag                  string   `protobuf:"bytes,1,opt,name=Tag,proto3" json:"Tag,omitempty"`
+	Enabled              bool     `protobuf:"varint,2,opt,name=Enabled,proto3" json:"Enabled,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SetMirrorsEnabledByTagRequest) Reset()         { *m = SetMirrorsEnabledByTagRequest{} }
+func (m *SetMirrorsEnabledByTagRequest) String() string { return proto.CompactTextString(m) }
+func (*SetMirrorsEnabledByTagRequest) ProtoMessage()    {}
+func (*SetMirrorsEnabledByTagRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{11}
+}
+
+func (m *SetMirrorsEnabledByTagRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SetMirrorsEnabledByTagRequest.Unmarshal(m, b)
+}
+func (m *SetMirrorsEnabledByTagRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SetMirrorsEnabledByTagRequest.Marshal(b, m, deterministic)
+}
+func (m *SetMirrorsEnabledByTagRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SetMirrorsEnabledByTagRequest.Merge(m, src)
+}
+func (m *SetMirrorsEnabledByTagRequest) XXX_Size() int {
+	return xxx_messageInfo_SetMirrorsEnabledByTagRequest.Size(m)
+}
+func (m *SetMirrorsEnabledByTagRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SetMirrorsEnabledByTagRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SetMirrorsEnabledByTagRequest proto.InternalMessageInfo
+
+func (m *SetMirrorsEnabledByTagRequest) GetTag() string {
+	if m != nil {
+		return m.Tag
+	}
+	return ""
+}
+
+func (m *SetMirrorsEnabledByTagRequest) GetEnabled() bool {
+	if m != nil {
+		return m.Enabled
+	}
+	return false
+}
+
+type SetMirrorsEnabledByTagReply struct {
+	Names                []string `protobuf:"bytes,1,rep,name=Names,proto3" json:"Names,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SetMirrorsEnabledByTagReply) Reset()         { *m = SetMirrorsEnabledByTagReply{} }
+func (m *SetMirrorsEnabledByTagReply) String() string { return proto.CompactTextString(m) }
+func (*SetMirrorsEnabledByTagReply) ProtoMessage()    {}
+func (*SetMirrorsEnabledByTagReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{12}
+}
+
+func (m *SetMirrorsEnabledByTagReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SetMirrorsEnabledByTagReply.Unmarshal(m, b)
+}
+func (m *SetMirrorsEnabledByTagReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SetMirrorsEnabledByTagReply.Marshal(b, m, deterministic)
+}
+func (m *SetMirrorsEnabledByTagReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SetMirrorsEnabledByTagReply.Merge(m, src)
+}
+func (m *SetMirrorsEnabledByTagReply) XXX_Size() int {
+	return xxx_messageInfo_SetMirrorsEnabledByTagReply.Size(m)
+}
+func (m *SetMirrorsEnabledByTagReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_SetMirrorsEnabledByTagReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SetMirrorsEnabledByTagReply proto.InternalMessageInfo
+
+func (m *SetMirrorsEnabledByTagReply) GetNames() []string {
+	if m != nil {
+		return m.Names
+	}
+	return nil
+}
+
+type ForceMirrorStateRequest struct {
+	ID                   int32    `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	Up                   bool     `protobuf:"varint,2,opt,name=Up,proto3" json:"Up,omitempty"`
+	TTL                  int64    `protobuf:"varint,3,opt,name=TTL,proto3" json:"TTL,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ForceMirrorStateRequest) Reset()         { *m = ForceMirrorStateRequest{} }
+func (m *ForceMirrorStateRequest) String() string { return proto.CompactTextString(m) }
+func (*ForceMirrorStateRequest) ProtoMessage()    {}
+func (*ForceMirrorStateRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{13}
+}
+
+func (m *ForceMirrorStateRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ForceMirrorStateRequest.Unmarshal(m, b)
+}
+func (m *ForceMirrorStateRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ForceMirrorStateRequest.Marshal(b, m, deterministic)
+}
+func (m *ForceMirrorStateRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ForceMirrorStateRequest.Merge(m, src)
+}
+func (m *ForceMirrorStateRequest) XXX_Size() int {
+	return xxx_messageInfo_ForceMirrorStateRequest.Size(m)
+}
+func (m *ForceMirrorStateRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ForceMirrorStateRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ForceMirrorStateRequest proto.InternalMessageInfo
+
+func (m *ForceMirrorStateRequest) GetID() int32 {
+	if m != nil {
+		return m.ID
+	}
+	return 0
+}
+
+func (m *ForceMirrorStateRequest) GetUp() bool {
+	if m != nil {
+		return m.Up
+	}
+	return false
+}
+
+func (m *ForceMirrorStateRequest) GetTTL() int64 {
+	if m != nil {
+		return m.TTL
+	}
+	return 0
+}
+
+type MirrorIDRequest struct {
+	ID                   int32    `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MirrorIDRequest) Reset()         { *m = MirrorIDRequest{} }
+func (m *MirrorIDRequest) String() string { return proto.CompactTextString(m) }
+func (*MirrorIDRequest) ProtoMessage()    {}
+func (*MirrorIDRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{14}
+}
+
+func (m *MirrorIDRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MirrorIDRequest.Unmarshal(m, b)
+}
+func (m *MirrorIDRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MirrorIDRequest.Marshal(b, m, deterministic)
+}
+func (m *MirrorIDRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MirrorIDRequest.Merge(m, src)
+}
+func (m *MirrorIDRequest) XXX_Size() int {
+	return xxx_messageInfo_MirrorIDRequest.Size(m)
+}
+func (m *MirrorIDRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_MirrorIDRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MirrorIDRequest proto.InternalMessageInfo
+
+func (m *MirrorIDRequest) GetID() int32 {
+	if m != nil {
+		return m.ID
+	}
+	return 0
+}
+
+type AddMirrorReply struct {
+	Latitude             float32  `protobuf:"fixed32,1,opt,name=Latitude,proto3" json:"Latitude,omitempty"`
+	Longitude            float32  `protobuf:"fixed32,2,opt,name=Longitude,proto3" json:"Longitude,omitempty"`
+	Country              string   `protobuf:"bytes,3,opt,name=Country,proto3" json:"Country,omitempty"`
+	Continent            string   `protobuf:"bytes,4,opt,name=Continent,proto3" json:"Continent,omitempty"`
+	ASN                  string   `protobuf:"bytes,5,opt,name=ASN,proto3" json:"ASN,omitempty"`
+	Warnings             []string `protobuf:"bytes,6,rep,name=Warnings,proto3" json:"Warnings,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AddMirrorReply) Reset()         { *m = AddMirrorReply{} }
+func (m *AddMirrorReply) String() string { return proto.CompactTextString(m) }
+func (*AddMirrorReply) ProtoMessage()    {}
+func (*AddMirrorReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{15}
+}
+
+func (m *AddMirrorReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AddMirrorReply.Unmarshal(m, b)
+}
+func (m *AddMirrorReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AddMirrorReply.Marshal(b, m, deterministic)
+}
+func (m *AddMirrorReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AddMirrorReply.Merge(m, src)
+}
+func (m *AddMirrorReply) XXX_Size() int {
+	return xxx_messageInfo_AddMirrorReply.Size(m)
+}
+func (m *AddMirrorReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_AddMirrorReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AddMirrorReply proto.InternalMessageInfo
+
+func (m *AddMirrorReply) GetLatitude() float32 {
+	if m != nil {
+		return m.Latitude
+	}
+	return 0
+}
+
+func (m *AddMirrorReply) GetLongitude() float32 {
+	if m != nil {
+		return m.Longitude
+	}
+	return 0
+}
+
+func (m *AddMirrorReply) GetCountry() string {
+	if m != nil {
+		return m.Country
+	}
 	return ""
 }
 
@@ -744,556 +1334,1651 @@ func (m *AddMirrorReply) GetASN() string {
 
 func (m *AddMirrorReply) GetWarnings() []string {
 	if m != nil {
-		return m.Warnings
+		return m.Warnings
+	}
+	return nil
+}
+
+type UpdateMirrorReply struct {
+	Diff                 string   `protobuf:"bytes,1,opt,name=Diff,proto3" json:"Diff,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UpdateMirrorReply) Reset()         { *m = UpdateMirrorReply{} }
+func (m *UpdateMirrorReply) String() string { return proto.CompactTextString(m) }
+func (*UpdateMirrorReply) ProtoMessage()    {}
+func (*UpdateMirrorReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{16}
+}
+
+func (m *UpdateMirrorReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpdateMirrorReply.Unmarshal(m, b)
+}
+func (m *UpdateMirrorReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpdateMirrorReply.Marshal(b, m, deterministic)
+}
+func (m *UpdateMirrorReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpdateMirrorReply.Merge(m, src)
+}
+func (m *UpdateMirrorReply) XXX_Size() int {
+	return xxx_messageInfo_UpdateMirrorReply.Size(m)
+}
+func (m *UpdateMirrorReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpdateMirrorReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpdateMirrorReply proto.InternalMessageInfo
+
+func (m *UpdateMirrorReply) GetDiff() string {
+	if m != nil {
+		return m.Diff
+	}
+	return ""
+}
+
+type GeoUpdateMirrorReply struct {
+	Mirror               *Mirror  `protobuf:"bytes,1,opt,name=Mirror,proto3" json:"Mirror,omitempty"`
+	Diff                 string   `protobuf:"bytes,2,opt,name=Diff,proto3" json:"Diff,omitempty"`
+	Warnings             []string `protobuf:"bytes,3,rep,name=Warnings,proto3" json:"Warnings,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GeoUpdateMirrorReply) Reset()         { *m = GeoUpdateMirrorReply{} }
+func (m *GeoUpdateMirrorReply) String() string { return proto.CompactTextString(m) }
+func (*GeoUpdateMirrorReply) ProtoMessage()    {}
+func (*GeoUpdateMirrorReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{17}
+}
+
+func (m *GeoUpdateMirrorReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GeoUpdateMirrorReply.Unmarshal(m, b)
+}
+func (m *GeoUpdateMirrorReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GeoUpdateMirrorReply.Marshal(b, m, deterministic)
+}
+func (m *GeoUpdateMirrorReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GeoUpdateMirrorReply.Merge(m, src)
+}
+func (m *GeoUpdateMirrorReply) XXX_Size() int {
+	return xxx_messageInfo_GeoUpdateMirrorReply.Size(m)
+}
+func (m *GeoUpdateMirrorReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_GeoUpdateMirrorReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GeoUpdateMirrorReply proto.InternalMessageInfo
+
+func (m *GeoUpdateMirrorReply) GetMirror() *Mirror {
+	if m != nil {
+		return m.Mirror
+	}
+	return nil
+}
+
+func (m *GeoUpdateMirrorReply) GetDiff() string {
+	if m != nil {
+		return m.Diff
+	}
+	return ""
+}
+
+func (m *GeoUpdateMirrorReply) GetWarnings() []string {
+	if m != nil {
+		return m.Warnings
+	}
+	return nil
+}
+
+type RefreshRepositoryRequest struct {
+	Rehash               bool     `protobuf:"varint,1,opt,name=Rehash,proto3" json:"Rehash,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RefreshRepositoryRequest) Reset()         { *m = RefreshRepositoryRequest{} }
+func (m *RefreshRepositoryRequest) String() string { return proto.CompactTextString(m) }
+func (*RefreshRepositoryRequest) ProtoMessage()    {}
+func (*RefreshRepositoryRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{18}
+}
+
+func (m *RefreshRepositoryRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RefreshRepositoryRequest.Unmarshal(m, b)
+}
+func (m *RefreshRepositoryRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RefreshRepositoryRequest.Marshal(b, m, deterministic)
+}
+func (m *RefreshRepositoryRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RefreshRepositoryRequest.Merge(m, src)
+}
+func (m *RefreshRepositoryRequest) XXX_Size() int {
+	return xxx_messageInfo_RefreshRepositoryRequest.Size(m)
+}
+func (m *RefreshRepositoryRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RefreshRepositoryRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RefreshRepositoryRequest proto.InternalMessageInfo
+
+func (m *RefreshRepositoryRequest) GetRehash() bool {
+	if m != nil {
+		return m.Rehash
+	}
+	return false
+}
+
+type ScanMirrorRequest struct {
+	ID                   int32                    `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	AutoEnable           bool                     `protobuf:"varint,2,opt,name=AutoEnable,proto3" json:"AutoEnable,omitempty"`
+	Protocol             ScanMirrorRequest_Method `protobuf:"varint,3,opt,name=Protocol,proto3,enum=ScanMirrorRequest_Method" json:"Protocol,omitempty"`
+	Only                 string                   `protobuf:"bytes,4,opt,name=Only,proto3" json:"Only,omitempty"`
+	DryRun               bool                     `protobuf:"varint,5,opt,name=DryRun,proto3" json:"DryRun,omitempty"`
+	ForceRehash          bool                     `protobuf:"varint,6,opt,name=ForceRehash,proto3" json:"ForceRehash,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                 `json:"-"`
+	XXX_unrecognized     []byte                   `json:"-"`
+	XXX_sizecache        int32                    `json:"-"`
+}
+
+func (m *ScanMirrorRequest) Reset()         { *m = ScanMirrorRequest{} }
+func (m *ScanMirrorRequest) String() string { return proto.CompactTextString(m) }
+func (*ScanMirrorRequest) ProtoMessage()    {}
+func (*ScanMirrorRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{19}
+}
+
+func (m *ScanMirrorRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ScanMirrorRequest.Unmarshal(m, b)
+}
+func (m *ScanMirrorRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ScanMirrorRequest.Marshal(b, m, deterministic)
+}
+func (m *ScanMirrorRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ScanMirrorRequest.Merge(m, src)
+}
+func (m *ScanMirrorRequest) XXX_Size() int {
+	return xxx_messageInfo_ScanMirrorRequest.Size(m)
+}
+func (m *ScanMirrorRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ScanMirrorRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ScanMirrorRequest proto.InternalMessageInfo
+
+func (m *ScanMirrorRequest) GetID() int32 {
+	if m != nil {
+		return m.ID
+	}
+	return 0
+}
+
+func (m *ScanMirrorRequest) GetAutoEnable() bool {
+	if m != nil {
+		return m.AutoEnable
+	}
+	return false
+}
+
+func (m *ScanMirrorRequest) GetProtocol() ScanMirrorRequest_Method {
+	if m != nil {
+		return m.Protocol
+	}
+	return ScanMirrorRequest_ALL
+}
+
+func (m *ScanMirrorRequest) GetOnly() string {
+	if m != nil {
+		return m.Only
+	}
+	return ""
+}
+
+func (m *ScanMirrorRequest) GetDryRun() bool {
+	if m != nil {
+		return m.DryRun
+	}
+	return false
+}
+
+func (m *ScanMirrorRequest) GetForceRehash() bool {
+	if m != nil {
+		return m.ForceRehash
+	}
+	return false
+}
+
+type ScanMirrorReply struct {
+	Enabled              bool     `protobuf:"varint,1,opt,name=Enabled,proto3" json:"Enabled,omitempty"`
+	FilesIndexed         int64    `protobuf:"varint,2,opt,name=FilesIndexed,proto3" json:"FilesIndexed,omitempty"`
+	KnownIndexed         int64    `protobuf:"varint,3,opt,name=KnownIndexed,proto3" json:"KnownIndexed,omitempty"`
+	Removed              int64    `protobuf:"varint,4,opt,name=Removed,proto3" json:"Removed,omitempty"`
+	TZOffsetMs           int64    `protobuf:"varint,5,opt,name=TZOffsetMs,proto3" json:"TZOffsetMs,omitempty"`
+	Added                int64    `protobuf:"varint,6,opt,name=Added,proto3" json:"Added,omitempty"`
+	Changed              int64    `protobuf:"varint,7,opt,name=Changed,proto3" json:"Changed,omitempty"`
+	SampleAdded          []string `protobuf:"bytes,8,rep,name=SampleAdded,proto3" json:"SampleAdded,omitempty"`
+	SampleRemoved        []string `protobuf:"bytes,9,rep,name=SampleRemoved,proto3" json:"SampleRemoved,omitempty"`
+	SampleChanged        []string `protobuf:"bytes,10,rep,name=SampleChanged,proto3" json:"SampleChanged,omitempty"`
+	Suspect              int64    `protobuf:"varint,11,opt,name=Suspect,proto3" json:"Suspect,omitempty"`
+	OutsideSchedule      bool     `protobuf:"varint,12,opt,name=OutsideSchedule,proto3" json:"OutsideSchedule,omitempty"`
+	HashMismatches       int64    `protobuf:"varint,13,opt,name=HashMismatches,proto3" json:"HashMismatches,omitempty"`
+	MaxFiles             int64    `protobuf:"varint,14,opt,name=MaxFiles,proto3" json:"MaxFiles,omitempty"`
+	Truncated            bool     `protobuf:"varint,15,opt,name=Truncated,proto3" json:"Truncated,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ScanMirrorReply) Reset()         { *m = ScanMirrorReply{} }
+func (m *ScanMirrorReply) String() string { return proto.CompactTextString(m) }
+func (*ScanMirrorReply) ProtoMessage()    {}
+func (*ScanMirrorReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{20}
+}
+
+func (m *ScanMirrorReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ScanMirrorReply.Unmarshal(m, b)
+}
+func (m *ScanMirrorReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ScanMirrorReply.Marshal(b, m, deterministic)
+}
+func (m *ScanMirrorReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ScanMirrorReply.Merge(m, src)
+}
+func (m *ScanMirrorReply) XXX_Size() int {
+	return xxx_messageInfo_ScanMirrorReply.Size(m)
+}
+func (m *ScanMirrorReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_ScanMirrorReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ScanMirrorReply proto.InternalMessageInfo
+
+func (m *ScanMirrorReply) GetEnabled() bool {
+	if m != nil {
+		return m.Enabled
+	}
+	return false
+}
+
+func (m *ScanMirrorReply) GetFilesIndexed() int64 {
+	if m != nil {
+		return m.FilesIndexed
+	}
+	return 0
+}
+
+func (m *ScanMirrorReply) GetKnownIndexed() int64 {
+	if m != nil {
+		return m.KnownIndexed
+	}
+	return 0
+}
+
+func (m *ScanMirrorReply) GetRemoved() int64 {
+	if m != nil {
+		return m.Removed
+	}
+	return 0
+}
+
+func (m *ScanMirrorReply) GetTZOffsetMs() int64 {
+	if m != nil {
+		return m.TZOffsetMs
+	}
+	return 0
+}
+
+func (m *ScanMirrorReply) GetAdded() int64 {
+	if m != nil {
+		return m.Added
+	}
+	return 0
+}
+
+func (m *ScanMirrorReply) GetChanged() int64 {
+	if m != nil {
+		return m.Changed
+	}
+	return 0
+}
+
+func (m *ScanMirrorReply) GetSampleAdded() []string {
+	if m != nil {
+		return m.SampleAdded
+	}
+	return nil
+}
+
+func (m *ScanMirrorReply) GetSampleRemoved() []string {
+	if m != nil {
+		return m.SampleRemoved
+	}
+	return nil
+}
+
+func (m *ScanMirrorReply) GetSampleChanged() []string {
+	if m != nil {
+		return m.SampleChanged
+	}
+	return nil
+}
+
+func (m *ScanMirrorReply) GetSuspect() int64 {
+	if m != nil {
+		return m.Suspect
+	}
+	return 0
+}
+
+func (m *ScanMirrorReply) GetOutsideSchedule() bool {
+	if m != nil {
+		return m.OutsideSchedule
+	}
+	return false
+}
+
+func (m *ScanMirrorReply) GetHashMismatches() int64 {
+	if m != nil {
+		return m.HashMismatches
+	}
+	return 0
+}
+
+func (m *ScanMirrorReply) GetMaxFiles() int64 {
+	if m != nil {
+		return m.MaxFiles
+	}
+	return 0
+}
+
+func (m *ScanMirrorReply) GetTruncated() bool {
+	if m != nil {
+		return m.Truncated
+	}
+	return false
+}
+
+type ScanStatusReply struct {
+	Running              bool                 `protobuf:"varint,1,opt,name=Running,proto3" json:"Running,omitempty"`
+	Protocol             string               `protobuf:"bytes,2,opt,name=Protocol,proto3" json:"Protocol,omitempty"`
+	Phase                string               `protobuf:"bytes,3,opt,name=Phase,proto3" json:"Phase,omitempty"`
+	Started              *timestamp.Timestamp `protobuf:"bytes,4,opt,name=Started,proto3" json:"Started,omitempty"`
+	Files                int64                `protobuf:"varint,5,opt,name=Files,proto3" json:"Files,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *ScanStatusReply) Reset()         { *m = ScanStatusReply{} }
+func (m *ScanStatusReply) String() string { return proto.CompactTextString(m) }
+func (*ScanStatusReply) ProtoMessage()    {}
+func (*ScanStatusReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{21}
+}
+
+func (m *ScanStatusReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ScanStatusReply.Unmarshal(m, b)
+}
+func (m *ScanStatusReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ScanStatusReply.Marshal(b, m, deterministic)
+}
+func (m *ScanStatusReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ScanStatusReply.Merge(m, src)
+}
+func (m *ScanStatusReply) XXX_Size() int {
+	return xxx_messageInfo_ScanStatusReply.Size(m)
+}
+func (m *ScanStatusReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_ScanStatusReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ScanStatusReply proto.InternalMessageInfo
+
+func (m *ScanStatusReply) GetRunning() bool {
+	if m != nil {
+		return m.Running
+	}
+	return false
+}
+
+func (m *ScanStatusReply) GetProtocol() string {
+	if m != nil {
+		return m.Protocol
+	}
+	return ""
+}
+
+func (m *ScanStatusReply) GetPhase() string {
+	if m != nil {
+		return m.Phase
+	}
+	return ""
+}
+
+func (m *ScanStatusReply) GetStarted() *timestamp.Timestamp {
+	if m != nil {
+		return m.Started
+	}
+	return nil
+}
+
+func (m *ScanStatusReply) GetFiles() int64 {
+	if m != nil {
+		return m.Files
+	}
+	return 0
+}
+
+type StatsFileRequest struct {
+	Pattern              string               `protobuf:"bytes,1,opt,name=Pattern,proto3" json:"Pattern,omitempty"`
+	DateStart            *timestamp.Timestamp `protobuf:"bytes,2,opt,name=DateStart,proto3" json:"DateStart,omitempty"`
+	DateEnd              *timestamp.Timestamp `protobuf:"bytes,3,opt,name=DateEnd,proto3" json:"DateEnd,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *StatsFileRequest) Reset()         { *m = StatsFileRequest{} }
+func (m *StatsFileRequest) String() string { return proto.CompactTextString(m) }
+func (*StatsFileRequest) ProtoMessage()    {}
+func (*StatsFileRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{22}
+}
+
+func (m *StatsFileRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StatsFileRequest.Unmarshal(m, b)
+}
+func (m *StatsFileRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StatsFileRequest.Marshal(b, m, deterministic)
+}
+func (m *StatsFileRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StatsFileRequest.Merge(m, src)
+}
+func (m *StatsFileRequest) XXX_Size() int {
+	return xxx_messageInfo_StatsFileRequest.Size(m)
+}
+func (m *StatsFileRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_StatsFileRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StatsFileRequest proto.InternalMessageInfo
+
+func (m *StatsFileRequest) GetPattern() string {
+	if m != nil {
+		return m.Pattern
+	}
+	return ""
+}
+
+func (m *StatsFileRequest) GetDateStart() *timestamp.Timestamp {
+	if m != nil {
+		return m.DateStart
+	}
+	return nil
+}
+
+func (m *StatsFileRequest) GetDateEnd() *timestamp.Timestamp {
+	if m != nil {
+		return m.DateEnd
+	}
+	return nil
+}
+
+type StatsFileReply struct {
+	Files                map[string]int64 `protobuf:"bytes,1,rep,name=files,proto3" json:"files,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *StatsFileReply) Reset()         { *m = StatsFileReply{} }
+func (m *StatsFileReply) String() string { return proto.CompactTextString(m) }
+func (*StatsFileReply) ProtoMessage()    {}
+func (*StatsFileReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{23}
+}
+
+func (m *StatsFileReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StatsFileReply.Unmarshal(m, b)
+}
+func (m *StatsFileReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StatsFileReply.Marshal(b, m, deterministic)
+}
+func (m *StatsFileReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StatsFileReply.Merge(m, src)
+}
+func (m *StatsFileReply) XXX_Size() int {
+	return xxx_messageInfo_StatsFileReply.Size(m)
+}
+func (m *StatsFileReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_StatsFileReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StatsFileReply proto.InternalMessageInfo
+
+func (m *StatsFileReply) GetFiles() map[string]int64 {
+	if m != nil {
+		return m.Files
+	}
+	return nil
+}
+
+type TopFilesRequest struct {
+	Period               string               `protobuf:"bytes,1,opt,name=Period,proto3" json:"Period,omitempty"`
+	Date                 *timestamp.Timestamp `protobuf:"bytes,2,opt,name=Date,proto3" json:"Date,omitempty"`
+	Limit                int32                `protobuf:"varint,3,opt,name=Limit,proto3" json:"Limit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *TopFilesRequest) Reset()         { *m = TopFilesRequest{} }
+func (m *TopFilesRequest) String() string { return proto.CompactTextString(m) }
+func (*TopFilesRequest) ProtoMessage()    {}
+func (*TopFilesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{24}
+}
+
+func (m *TopFilesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TopFilesRequest.Unmarshal(m, b)
+}
+func (m *TopFilesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TopFilesRequest.Marshal(b, m, deterministic)
+}
+func (m *TopFilesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TopFilesRequest.Merge(m, src)
+}
+func (m *TopFilesRequest) XXX_Size() int {
+	return xxx_messageInfo_TopFilesRequest.Size(m)
+}
+func (m *TopFilesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_TopFilesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TopFilesRequest proto.InternalMessageInfo
+
+func (m *TopFilesRequest) GetPeriod() string {
+	if m != nil {
+		return m.Period
+	}
+	return ""
+}
+
+func (m *TopFilesRequest) GetDate() *timestamp.Timestamp {
+	if m != nil {
+		return m.Date
+	}
+	return nil
+}
+
+func (m *TopFilesRequest) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+type TopFile struct {
+	Path                 string   `protobuf:"bytes,1,opt,name=Path,proto3" json:"Path,omitempty"`
+	Downloads            int64    `protobuf:"varint,2,opt,name=Downloads,proto3" json:"Downloads,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TopFile) Reset()         { *m = TopFile{} }
+func (m *TopFile) String() string { return proto.CompactTextString(m) }
+func (*TopFile) ProtoMessage()    {}
+func (*TopFile) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{25}
+}
+
+func (m *TopFile) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TopFile.Unmarshal(m, b)
+}
+func (m *TopFile) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TopFile.Marshal(b, m, deterministic)
+}
+func (m *TopFile) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TopFile.Merge(m, src)
+}
+func (m *TopFile) XXX_Size() int {
+	return xxx_messageInfo_TopFile.Size(m)
+}
+func (m *TopFile) XXX_DiscardUnknown() {
+	xxx_messageInfo_TopFile.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TopFile proto.InternalMessageInfo
+
+func (m *TopFile) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *TopFile) GetDownloads() int64 {
+	if m != nil {
+		return m.Downloads
+	}
+	return 0
+}
+
+type TopFilesReply struct {
+	Files                []*TopFile `protobuf:"bytes,1,rep,name=Files,proto3" json:"Files,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *TopFilesReply) Reset()         { *m = TopFilesReply{} }
+func (m *TopFilesReply) String() string { return proto.CompactTextString(m) }
+func (*TopFilesReply) ProtoMessage()    {}
+func (*TopFilesReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{26}
+}
+
+func (m *TopFilesReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TopFilesReply.Unmarshal(m, b)
+}
+func (m *TopFilesReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TopFilesReply.Marshal(b, m, deterministic)
+}
+func (m *TopFilesReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TopFilesReply.Merge(m, src)
+}
+func (m *TopFilesReply) XXX_Size() int {
+	return xxx_messageInfo_TopFilesReply.Size(m)
+}
+func (m *TopFilesReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_TopFilesReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TopFilesReply proto.InternalMessageInfo
+
+func (m *TopFilesReply) GetFiles() []*TopFile {
+	if m != nil {
+		return m.Files
+	}
+	return nil
+}
+
+type StatsMirrorRequest struct {
+	ID                   int32                `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	DateStart            *timestamp.Timestamp `protobuf:"bytes,2,opt,name=DateStart,proto3" json:"DateStart,omitempty"`
+	DateEnd              *timestamp.Timestamp `protobuf:"bytes,3,opt,name=DateEnd,proto3" json:"DateEnd,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *StatsMirrorRequest) Reset()         { *m = StatsMirrorRequest{} }
+func (m *StatsMirrorRequest) String() string { return proto.CompactTextString(m) }
+func (*StatsMirrorRequest) ProtoMessage()    {}
+func (*StatsMirrorRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{27}
+}
+
+func (m *StatsMirrorRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StatsMirrorRequest.Unmarshal(m, b)
+}
+func (m *StatsMirrorRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StatsMirrorRequest.Marshal(b, m, deterministic)
+}
+func (m *StatsMirrorRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StatsMirrorRequest.Merge(m, src)
+}
+func (m *StatsMirrorRequest) XXX_Size() int {
+	return xxx_messageInfo_StatsMirrorRequest.Size(m)
+}
+func (m *StatsMirrorRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_StatsMirrorRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StatsMirrorRequest proto.InternalMessageInfo
+
+func (m *StatsMirrorRequest) GetID() int32 {
+	if m != nil {
+		return m.ID
+	}
+	return 0
+}
+
+func (m *StatsMirrorRequest) GetDateStart() *timestamp.Timestamp {
+	if m != nil {
+		return m.DateStart
+	}
+	return nil
+}
+
+func (m *StatsMirrorRequest) GetDateEnd() *timestamp.Timestamp {
+	if m != nil {
+		return m.DateEnd
+	}
+	return nil
+}
+
+type StatsMirrorReply struct {
+	Mirror               *Mirror  `protobuf:"bytes,1,opt,name=Mirror,proto3" json:"Mirror,omitempty"`
+	Requests             int64    `protobuf:"varint,2,opt,name=Requests,proto3" json:"Requests,omitempty"`
+	Bytes                int64    `protobuf:"varint,3,opt,name=Bytes,proto3" json:"Bytes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StatsMirrorReply) Reset()         { *m = StatsMirrorReply{} }
+func (m *StatsMirrorReply) String() string { return proto.CompactTextString(m) }
+func (*StatsMirrorReply) ProtoMessage()    {}
+func (*StatsMirrorReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{28}
+}
+
+func (m *StatsMirrorReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StatsMirrorReply.Unmarshal(m, b)
+}
+func (m *StatsMirrorReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StatsMirrorReply.Marshal(b, m, deterministic)
+}
+func (m *StatsMirrorReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StatsMirrorReply.Merge(m, src)
+}
+func (m *StatsMirrorReply) XXX_Size() int {
+	return xxx_messageInfo_StatsMirrorReply.Size(m)
+}
+func (m *StatsMirrorReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_StatsMirrorReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StatsMirrorReply proto.InternalMessageInfo
+
+func (m *StatsMirrorReply) GetMirror() *Mirror {
+	if m != nil {
+		return m.Mirror
+	}
+	return nil
+}
+
+func (m *StatsMirrorReply) GetRequests() int64 {
+	if m != nil {
+		return m.Requests
+	}
+	return 0
+}
+
+func (m *StatsMirrorReply) GetBytes() int64 {
+	if m != nil {
+		return m.Bytes
+	}
+	return 0
+}
+
+type ExportStatsRequest struct {
+	Period               string               `protobuf:"bytes,1,opt,name=Period,proto3" json:"Period,omitempty"`
+	DateStart            *timestamp.Timestamp `protobuf:"bytes,2,opt,name=DateStart,proto3" json:"DateStart,omitempty"`
+	DateEnd              *timestamp.Timestamp `protobuf:"bytes,3,opt,name=DateEnd,proto3" json:"DateEnd,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *ExportStatsRequest) Reset()         { *m = ExportStatsRequest{} }
+func (m *ExportStatsRequest) String() string { return proto.CompactTextString(m) }
+func (*ExportStatsRequest) ProtoMessage()    {}
+func (*ExportStatsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{29}
+}
+
+func (m *ExportStatsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ExportStatsRequest.Unmarshal(m, b)
+}
+func (m *ExportStatsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ExportStatsRequest.Marshal(b, m, deterministic)
+}
+func (m *ExportStatsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExportStatsRequest.Merge(m, src)
+}
+func (m *ExportStatsRequest) XXX_Size() int {
+	return xxx_messageInfo_ExportStatsRequest.Size(m)
+}
+func (m *ExportStatsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ExportStatsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ExportStatsRequest proto.InternalMessageInfo
+
+func (m *ExportStatsRequest) GetPeriod() string {
+	if m != nil {
+		return m.Period
+	}
+	return ""
+}
+
+func (m *ExportStatsRequest) GetDateStart() *timestamp.Timestamp {
+	if m != nil {
+		return m.DateStart
+	}
+	return nil
+}
+
+func (m *ExportStatsRequest) GetDateEnd() *timestamp.Timestamp {
+	if m != nil {
+		return m.DateEnd
+	}
+	return nil
+}
+
+type MirrorPeriodStats struct {
+	ID                   int32    `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	Name                 string   `protobuf:"bytes,2,opt,name=Name,proto3" json:"Name,omitempty"`
+	Period               string   `protobuf:"bytes,3,opt,name=Period,proto3" json:"Period,omitempty"`
+	Requests             int64    `protobuf:"varint,4,opt,name=Requests,proto3" json:"Requests,omitempty"`
+	Bytes                int64    `protobuf:"varint,5,opt,name=Bytes,proto3" json:"Bytes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MirrorPeriodStats) Reset()         { *m = MirrorPeriodStats{} }
+func (m *MirrorPeriodStats) String() string { return proto.CompactTextString(m) }
+func (*MirrorPeriodStats) ProtoMessage()    {}
+func (*MirrorPeriodStats) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{30}
+}
+
+func (m *MirrorPeriodStats) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MirrorPeriodStats.Unmarshal(m, b)
+}
+func (m *MirrorPeriodStats) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MirrorPeriodStats.Marshal(b, m, deterministic)
+}
+func (m *MirrorPeriodStats) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MirrorPeriodStats.Merge(m, src)
+}
+func (m *MirrorPeriodStats) XXX_Size() int {
+	return xxx_messageInfo_MirrorPeriodStats.Size(m)
+}
+func (m *MirrorPeriodStats) XXX_DiscardUnknown() {
+	xxx_messageInfo_MirrorPeriodStats.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MirrorPeriodStats proto.InternalMessageInfo
+
+func (m *MirrorPeriodStats) GetID() int32 {
+	if m != nil {
+		return m.ID
+	}
+	return 0
+}
+
+func (m *MirrorPeriodStats) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *MirrorPeriodStats) GetPeriod() string {
+	if m != nil {
+		return m.Period
+	}
+	return ""
+}
+
+func (m *MirrorPeriodStats) GetRequests() int64 {
+	if m != nil {
+		return m.Requests
+	}
+	return 0
+}
+
+func (m *MirrorPeriodStats) GetBytes() int64 {
+	if m != nil {
+		return m.Bytes
+	}
+	return 0
+}
+
+type ExportStatsReply struct {
+	Stats                []*MirrorPeriodStats `protobuf:"bytes,1,rep,name=Stats,proto3" json:"Stats,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *ExportStatsReply) Reset()         { *m = ExportStatsReply{} }
+func (m *ExportStatsReply) String() string { return proto.CompactTextString(m) }
+func (*ExportStatsReply) ProtoMessage()    {}
+func (*ExportStatsReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{31}
+}
+
+func (m *ExportStatsReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ExportStatsReply.Unmarshal(m, b)
+}
+func (m *ExportStatsReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ExportStatsReply.Marshal(b, m, deterministic)
+}
+func (m *ExportStatsReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExportStatsReply.Merge(m, src)
+}
+func (m *ExportStatsReply) XXX_Size() int {
+	return xxx_messageInfo_ExportStatsReply.Size(m)
+}
+func (m *ExportStatsReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_ExportStatsReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ExportStatsReply proto.InternalMessageInfo
+
+func (m *ExportStatsReply) GetStats() []*MirrorPeriodStats {
+	if m != nil {
+		return m.Stats
+	}
+	return nil
+}
+
+type ResetStatsRequest struct {
+	ID                   int32    `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	All                  bool     `protobuf:"varint,2,opt,name=All,proto3" json:"All,omitempty"`
+	Operator             string   `protobuf:"bytes,3,opt,name=Operator,proto3" json:"Operator,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ResetStatsRequest) Reset()         { *m = ResetStatsRequest{} }
+func (m *ResetStatsRequest) String() string { return proto.CompactTextString(m) }
+func (*ResetStatsRequest) ProtoMessage()    {}
+func (*ResetStatsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{32}
+}
+
+func (m *ResetStatsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ResetStatsRequest.Unmarshal(m, b)
+}
+func (m *ResetStatsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ResetStatsRequest.Marshal(b, m, deterministic)
+}
+func (m *ResetStatsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ResetStatsRequest.Merge(m, src)
+}
+func (m *ResetStatsRequest) XXX_Size() int {
+	return xxx_messageInfo_ResetStatsRequest.Size(m)
+}
+func (m *ResetStatsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ResetStatsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ResetStatsRequest proto.InternalMessageInfo
+
+func (m *ResetStatsRequest) GetID() int32 {
+	if m != nil {
+		return m.ID
+	}
+	return 0
+}
+
+func (m *ResetStatsRequest) GetAll() bool {
+	if m != nil {
+		return m.All
+	}
+	return false
+}
+
+func (m *ResetStatsRequest) GetOperator() string {
+	if m != nil {
+		return m.Operator
 	}
-	return nil
+	return ""
 }
 
-type UpdateMirrorReply struct {
-	Diff                 string   `protobuf:"bytes,1,opt,name=Diff,proto3" json:"Diff,omitempty"`
+type ResetStatsReply struct {
+	Counters             int64    `protobuf:"varint,1,opt,name=Counters,proto3" json:"Counters,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *UpdateMirrorReply) Reset()         { *m = UpdateMirrorReply{} }
-func (m *UpdateMirrorReply) String() string { return proto.CompactTextString(m) }
-func (*UpdateMirrorReply) ProtoMessage()    {}
-func (*UpdateMirrorReply) Descriptor() ([]byte, []int) {
-	return fileDescriptor_77a6da22d6a3feb1, []int{9}
+func (m *ResetStatsReply) Reset()         { *m = ResetStatsReply{} }
+func (m *ResetStatsReply) String() string { return proto.CompactTextString(m) }
+func (*ResetStatsReply) ProtoMessage()    {}
+func (*ResetStatsReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{33}
 }
 
-func (m *UpdateMirrorReply) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_UpdateMirrorReply.Unmarshal(m, b)
+func (m *ResetStatsReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ResetStatsReply.Unmarshal(m, b)
 }
-func (m *UpdateMirrorReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_UpdateMirrorReply.Marshal(b, m, deterministic)
+func (m *ResetStatsReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ResetStatsReply.Marshal(b, m, deterministic)
 }
-func (m *UpdateMirrorReply) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_UpdateMirrorReply.Merge(m, src)
+func (m *ResetStatsReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ResetStatsReply.Merge(m, src)
 }
-func (m *UpdateMirrorReply) XXX_Size() int {
-	return xxx_messageInfo_UpdateMirrorReply.Size(m)
+func (m *ResetStatsReply) XXX_Size() int {
+	return xxx_messageInfo_ResetStatsReply.Size(m)
 }
-func (m *UpdateMirrorReply) XXX_DiscardUnknown() {
-	xxx_messageInfo_UpdateMirrorReply.DiscardUnknown(m)
+func (m *ResetStatsReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_ResetStatsReply.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_UpdateMirrorReply proto.InternalMessageInfo
+var xxx_messageInfo_ResetStatsReply proto.InternalMessageInfo
 
-func (m *UpdateMirrorReply) GetDiff() string {
+func (m *ResetStatsReply) GetCounters() int64 {
 	if m != nil {
-		return m.Diff
+		return m.Counters
 	}
-	return ""
+	return 0
 }
 
-type GeoUpdateMirrorReply struct {
-	Mirror               *Mirror  `protobuf:"bytes,1,opt,name=Mirror,proto3" json:"Mirror,omitempty"`
-	Diff                 string   `protobuf:"bytes,2,opt,name=Diff,proto3" json:"Diff,omitempty"`
-	Warnings             []string `protobuf:"bytes,3,rep,name=Warnings,proto3" json:"Warnings,omitempty"`
+type GetMirrorLogsRequest struct {
+	ID                   int32    `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	MaxResults           int32    `protobuf:"varint,2,opt,name=MaxResults,proto3" json:"MaxResults,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *GeoUpdateMirrorReply) Reset()         { *m = GeoUpdateMirrorReply{} }
-func (m *GeoUpdateMirrorReply) String() string { return proto.CompactTextString(m) }
-func (*GeoUpdateMirrorReply) ProtoMessage()    {}
-func (*GeoUpdateMirrorReply) Descriptor() ([]byte, []int) {
-	return fileDescriptor_77a6da22d6a3feb1, []int{10}
+func (m *GetMirrorLogsRequest) Reset()         { *m = GetMirrorLogsRequest{} }
+func (m *GetMirrorLogsRequest) String() string { return proto.CompactTextString(m) }
+func (*GetMirrorLogsRequest) ProtoMessage()    {}
+func (*GetMirrorLogsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{34}
 }
 
-func (m *GeoUpdateMirrorReply) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_GeoUpdateMirrorReply.Unmarshal(m, b)
+func (m *GetMirrorLogsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetMirrorLogsRequest.Unmarshal(m, b)
 }
-func (m *GeoUpdateMirrorReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_GeoUpdateMirrorReply.Marshal(b, m, deterministic)
+func (m *GetMirrorLogsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetMirrorLogsRequest.Marshal(b, m, deterministic)
 }
-func (m *GeoUpdateMirrorReply) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_GeoUpdateMirrorReply.Merge(m, src)
+func (m *GetMirrorLogsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetMirrorLogsRequest.Merge(m, src)
 }
-func (m *GeoUpdateMirrorReply) XXX_Size() int {
-	return xxx_messageInfo_GeoUpdateMirrorReply.Size(m)
+func (m *GetMirrorLogsRequest) XXX_Size() int {
+	return xxx_messageInfo_GetMirrorLogsRequest.Size(m)
 }
-func (m *GeoUpdateMirrorReply) XXX_DiscardUnknown() {
-	xxx_messageInfo_GeoUpdateMirrorReply.DiscardUnknown(m)
+func (m *GetMirrorLogsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetMirrorLogsRequest.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_GeoUpdateMirrorReply proto.InternalMessageInfo
+var xxx_messageInfo_GetMirrorLogsRequest proto.InternalMessageInfo
 
-func (m *GeoUpdateMirrorReply) GetMirror() *Mirror {
+func (m *GetMirrorLogsRequest) GetID() int32 {
 	if m != nil {
-		return m.Mirror
+		return m.ID
 	}
-	return nil
+	return 0
 }
 
-func (m *GeoUpdateMirrorReply) GetDiff() string {
+func (m *GetMirrorLogsRequest) GetMaxResults() int32 {
 	if m != nil {
-		return m.Diff
+		return m.MaxResults
 	}
-	return ""
+	return 0
 }
 
-func (m *GeoUpdateMirrorReply) GetWarnings() []string {
+type GetMirrorLogsReply struct {
+	Line                 []string `protobuf:"bytes,1,rep,name=line,proto3" json:"line,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetMirrorLogsReply) Reset()         { *m = GetMirrorLogsReply{} }
+func (m *GetMirrorLogsReply) String() string { return proto.CompactTextString(m) }
+func (*GetMirrorLogsReply) ProtoMessage()    {}
+func (*GetMirrorLogsReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{35}
+}
+
+func (m *GetMirrorLogsReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetMirrorLogsReply.Unmarshal(m, b)
+}
+func (m *GetMirrorLogsReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetMirrorLogsReply.Marshal(b, m, deterministic)
+}
+func (m *GetMirrorLogsReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetMirrorLogsReply.Merge(m, src)
+}
+func (m *GetMirrorLogsReply) XXX_Size() int {
+	return xxx_messageInfo_GetMirrorLogsReply.Size(m)
+}
+func (m *GetMirrorLogsReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetMirrorLogsReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetMirrorLogsReply proto.InternalMessageInfo
+
+func (m *GetMirrorLogsReply) GetLine() []string {
 	if m != nil {
-		return m.Warnings
+		return m.Line
 	}
 	return nil
 }
 
-type RefreshRepositoryRequest struct {
-	Rehash               bool     `protobuf:"varint,1,opt,name=Rehash,proto3" json:"Rehash,omitempty"`
+type FileMirrorsRequest struct {
+	Path                 string   `protobuf:"bytes,1,opt,name=Path,proto3" json:"Path,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *RefreshRepositoryRequest) Reset()         { *m = RefreshRepositoryRequest{} }
-func (m *RefreshRepositoryRequest) String() string { return proto.CompactTextString(m) }
-func (*RefreshRepositoryRequest) ProtoMessage()    {}
-func (*RefreshRepositoryRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_77a6da22d6a3feb1, []int{11}
+func (m *FileMirrorsRequest) Reset()         { *m = FileMirrorsRequest{} }
+func (m *FileMirrorsRequest) String() string { return proto.CompactTextString(m) }
+func (*FileMirrorsRequest) ProtoMessage()    {}
+func (*FileMirrorsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{36}
 }
 
-func (m *RefreshRepositoryRequest) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_RefreshRepositoryRequest.Unmarshal(m, b)
+func (m *FileMirrorsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FileMirrorsRequest.Unmarshal(m, b)
 }
-func (m *RefreshRepositoryRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_RefreshRepositoryRequest.Marshal(b, m, deterministic)
+func (m *FileMirrorsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FileMirrorsRequest.Marshal(b, m, deterministic)
 }
-func (m *RefreshRepositoryRequest) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_RefreshRepositoryRequest.Merge(m, src)
+func (m *FileMirrorsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FileMirrorsRequest.Merge(m, src)
 }
-func (m *RefreshRepositoryRequest) XXX_Size() int {
-	return xxx_messageInfo_RefreshRepositoryRequest.Size(m)
+func (m *FileMirrorsRequest) XXX_Size() int {
+	return xxx_messageInfo_FileMirrorsRequest.Size(m)
 }
-func (m *RefreshRepositoryRequest) XXX_DiscardUnknown() {
-	xxx_messageInfo_RefreshRepositoryRequest.DiscardUnknown(m)
+func (m *FileMirrorsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_FileMirrorsRequest.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_RefreshRepositoryRequest proto.InternalMessageInfo
+var xxx_messageInfo_FileMirrorsRequest proto.InternalMessageInfo
 
-func (m *RefreshRepositoryRequest) GetRehash() bool {
+func (m *FileMirrorsRequest) GetPath() string {
 	if m != nil {
-		return m.Rehash
+		return m.Path
 	}
-	return false
+	return ""
 }
 
-type ScanMirrorRequest struct {
-	ID                   int32                    `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
-	AutoEnable           bool                     `protobuf:"varint,2,opt,name=AutoEnable,proto3" json:"AutoEnable,omitempty"`
-	Protocol             ScanMirrorRequest_Method `protobuf:"varint,3,opt,name=Protocol,proto3,enum=ScanMirrorRequest_Method" json:"Protocol,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}                 `json:"-"`
-	XXX_unrecognized     []byte                   `json:"-"`
-	XXX_sizecache        int32                    `json:"-"`
+type FileMirror struct {
+	ID                   int32                `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	Name                 string               `protobuf:"bytes,2,opt,name=Name,proto3" json:"Name,omitempty"`
+	Size                 int64                `protobuf:"varint,3,opt,name=Size,proto3" json:"Size,omitempty"`
+	ModTime              *timestamp.Timestamp `protobuf:"bytes,4,opt,name=ModTime,proto3" json:"ModTime,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
 }
 
-func (m *ScanMirrorRequest) Reset()         { *m = ScanMirrorRequest{} }
-func (m *ScanMirrorRequest) String() string { return proto.CompactTextString(m) }
-func (*ScanMirrorRequest) ProtoMessage()    {}
-func (*ScanMirrorRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_77a6da22d6a3feb1, []int{12}
+func (m *FileMirror) Reset()         { *m = FileMirror{} }
+func (m *FileMirror) String() string { return proto.CompactTextString(m) }
+func (*FileMirror) ProtoMessage()    {}
+func (*FileMirror) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{37}
 }
 
-func (m *ScanMirrorRequest) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_ScanMirrorRequest.Unmarshal(m, b)
+func (m *FileMirror) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FileMirror.Unmarshal(m, b)
 }
-func (m *ScanMirrorRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_ScanMirrorRequest.Marshal(b, m, deterministic)
+func (m *FileMirror) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FileMirror.Marshal(b, m, deterministic)
 }
-func (m *ScanMirrorRequest) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_ScanMirrorRequest.Merge(m, src)
+func (m *FileMirror) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FileMirror.Merge(m, src)
 }
-func (m *ScanMirrorRequest) XXX_Size() int {
-	return xxx_messageInfo_ScanMirrorRequest.Size(m)
+func (m *FileMirror) XXX_Size() int {
+	return xxx_messageInfo_FileMirror.Size(m)
 }
-func (m *ScanMirrorRequest) XXX_DiscardUnknown() {
-	xxx_messageInfo_ScanMirrorRequest.DiscardUnknown(m)
+func (m *FileMirror) XXX_DiscardUnknown() {
+	xxx_messageInfo_FileMirror.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_ScanMirrorRequest proto.InternalMessageInfo
+var xxx_messageInfo_FileMirror proto.InternalMessageInfo
 
-func (m *ScanMirrorRequest) GetID() int32 {
+func (m *FileMirror) GetID() int32 {
 	if m != nil {
 		return m.ID
 	}
 	return 0
 }
 
-func (m *ScanMirrorRequest) GetAutoEnable() bool {
+func (m *FileMirror) GetName() string {
 	if m != nil {
-		return m.AutoEnable
+		return m.Name
 	}
-	return false
+	return ""
 }
 
-func (m *ScanMirrorRequest) GetProtocol() ScanMirrorRequest_Method {
+func (m *FileMirror) GetSize() int64 {
 	if m != nil {
-		return m.Protocol
+		return m.Size
 	}
-	return ScanMirrorRequest_ALL
+	return 0
 }
 
-type ScanMirrorReply struct {
-	Enabled              bool     `protobuf:"varint,1,opt,name=Enabled,proto3" json:"Enabled,omitempty"`
-	FilesIndexed         int64    `protobuf:"varint,2,opt,name=FilesIndexed,proto3" json:"FilesIndexed,omitempty"`
-	KnownIndexed         int64    `protobuf:"varint,3,opt,name=KnownIndexed,proto3" json:"KnownIndexed,omitempty"`
-	Removed              int64    `protobuf:"varint,4,opt,name=Removed,proto3" json:"Removed,omitempty"`
-	TZOffsetMs           int64    `protobuf:"varint,5,opt,name=TZOffsetMs,proto3" json:"TZOffsetMs,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+func (m *FileMirror) GetModTime() *timestamp.Timestamp {
+	if m != nil {
+		return m.ModTime
+	}
+	return nil
 }
 
-func (m *ScanMirrorReply) Reset()         { *m = ScanMirrorReply{} }
-func (m *ScanMirrorReply) String() string { return proto.CompactTextString(m) }
-func (*ScanMirrorReply) ProtoMessage()    {}
-func (*ScanMirrorReply) Descriptor() ([]byte, []int) {
-	return fileDescriptor_77a6da22d6a3feb1, []int{13}
+type FileMirrorsReply struct {
+	Mirrors              []*FileMirror `protobuf:"bytes,1,rep,name=Mirrors,proto3" json:"Mirrors,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
 }
 
-func (m *ScanMirrorReply) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_ScanMirrorReply.Unmarshal(m, b)
+func (m *FileMirrorsReply) Reset()         { *m = FileMirrorsReply{} }
+func (m *FileMirrorsReply) String() string { return proto.CompactTextString(m) }
+func (*FileMirrorsReply) ProtoMessage()    {}
+func (*FileMirrorsReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{38}
 }
-func (m *ScanMirrorReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_ScanMirrorReply.Marshal(b, m, deterministic)
+
+func (m *FileMirrorsReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_FileMirrorsReply.Unmarshal(m, b)
 }
-func (m *ScanMirrorReply) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_ScanMirrorReply.Merge(m, src)
+func (m *FileMirrorsReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_FileMirrorsReply.Marshal(b, m, deterministic)
 }
-func (m *ScanMirrorReply) XXX_Size() int {
-	return xxx_messageInfo_ScanMirrorReply.Size(m)
+func (m *FileMirrorsReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_FileMirrorsReply.Merge(m, src)
 }
-func (m *ScanMirrorReply) XXX_DiscardUnknown() {
-	xxx_messageInfo_ScanMirrorReply.DiscardUnknown(m)
+func (m *FileMirrorsReply) XXX_Size() int {
+	return xxx_messageInfo_FileMirrorsReply.Size(m)
+}
+func (m *FileMirrorsReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_FileMirrorsReply.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_ScanMirrorReply proto.InternalMessageInfo
+var xxx_messageInfo_FileMirrorsReply proto.InternalMessageInfo
 
-func (m *ScanMirrorReply) GetEnabled() bool {
+func (m *FileMirrorsReply) GetMirrors() []*FileMirror {
 	if m != nil {
-		return m.Enabled
+		return m.Mirrors
 	}
-	return false
+	return nil
 }
 
-func (m *ScanMirrorReply) GetFilesIndexed() int64 {
-	if m != nil {
-		return m.FilesIndexed
-	}
-	return 0
+type DiffMirrorsRequest struct {
+	IDA                  int32    `protobuf:"varint,1,opt,name=IDA,proto3" json:"IDA,omitempty"`
+	IDB                  int32    `protobuf:"varint,2,opt,name=IDB,proto3" json:"IDB,omitempty"`
+	Full                 bool     `protobuf:"varint,3,opt,name=Full,proto3" json:"Full,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *ScanMirrorReply) GetKnownIndexed() int64 {
+func (m *DiffMirrorsRequest) Reset()         { *m = DiffMirrorsRequest{} }
+func (m *DiffMirrorsRequest) String() string { return proto.CompactTextString(m) }
+func (*DiffMirrorsRequest) ProtoMessage()    {}
+func (*DiffMirrorsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{39}
+}
+
+func (m *DiffMirrorsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DiffMirrorsRequest.Unmarshal(m, b)
+}
+func (m *DiffMirrorsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DiffMirrorsRequest.Marshal(b, m, deterministic)
+}
+func (m *DiffMirrorsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DiffMirrorsRequest.Merge(m, src)
+}
+func (m *DiffMirrorsRequest) XXX_Size() int {
+	return xxx_messageInfo_DiffMirrorsRequest.Size(m)
+}
+func (m *DiffMirrorsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DiffMirrorsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DiffMirrorsRequest proto.InternalMessageInfo
+
+func (m *DiffMirrorsRequest) GetIDA() int32 {
 	if m != nil {
-		return m.KnownIndexed
+		return m.IDA
 	}
 	return 0
 }
 
-func (m *ScanMirrorReply) GetRemoved() int64 {
+func (m *DiffMirrorsRequest) GetIDB() int32 {
 	if m != nil {
-		return m.Removed
+		return m.IDB
 	}
 	return 0
 }
 
-func (m *ScanMirrorReply) GetTZOffsetMs() int64 {
+func (m *DiffMirrorsRequest) GetFull() bool {
 	if m != nil {
-		return m.TZOffsetMs
+		return m.Full
 	}
-	return 0
+	return false
 }
 
-type StatsFileRequest struct {
-	Pattern              string               `protobuf:"bytes,1,opt,name=Pattern,proto3" json:"Pattern,omitempty"`
-	DateStart            *timestamp.Timestamp `protobuf:"bytes,2,opt,name=DateStart,proto3" json:"DateStart,omitempty"`
-	DateEnd              *timestamp.Timestamp `protobuf:"bytes,3,opt,name=DateEnd,proto3" json:"DateEnd,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
-	XXX_unrecognized     []byte               `json:"-"`
-	XXX_sizecache        int32                `json:"-"`
+type DiffMirrorsReply struct {
+	Common               int64    `protobuf:"varint,1,opt,name=Common,proto3" json:"Common,omitempty"`
+	OnlyA                int64    `protobuf:"varint,2,opt,name=OnlyA,proto3" json:"OnlyA,omitempty"`
+	OnlyB                int64    `protobuf:"varint,3,opt,name=OnlyB,proto3" json:"OnlyB,omitempty"`
+	Changed              int64    `protobuf:"varint,4,opt,name=Changed,proto3" json:"Changed,omitempty"`
+	SampleOnlyA          []string `protobuf:"bytes,5,rep,name=SampleOnlyA,proto3" json:"SampleOnlyA,omitempty"`
+	SampleOnlyB          []string `protobuf:"bytes,6,rep,name=SampleOnlyB,proto3" json:"SampleOnlyB,omitempty"`
+	SampleChanged        []string `protobuf:"bytes,7,rep,name=SampleChanged,proto3" json:"SampleChanged,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *StatsFileRequest) Reset()         { *m = StatsFileRequest{} }
-func (m *StatsFileRequest) String() string { return proto.CompactTextString(m) }
-func (*StatsFileRequest) ProtoMessage()    {}
-func (*StatsFileRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_77a6da22d6a3feb1, []int{14}
+func (m *DiffMirrorsReply) Reset()         { *m = DiffMirrorsReply{} }
+func (m *DiffMirrorsReply) String() string { return proto.CompactTextString(m) }
+func (*DiffMirrorsReply) ProtoMessage()    {}
+func (*DiffMirrorsReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{40}
 }
 
-func (m *StatsFileRequest) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_StatsFileRequest.Unmarshal(m, b)
+func (m *DiffMirrorsReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DiffMirrorsReply.Unmarshal(m, b)
 }
-func (m *StatsFileRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_StatsFileRequest.Marshal(b, m, deterministic)
+func (m *DiffMirrorsReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DiffMirrorsReply.Marshal(b, m, deterministic)
 }
-func (m *StatsFileRequest) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_StatsFileRequest.Merge(m, src)
+func (m *DiffMirrorsReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DiffMirrorsReply.Merge(m, src)
 }
-func (m *StatsFileRequest) XXX_Size() int {
-	return xxx_messageInfo_StatsFileRequest.Size(m)
+func (m *DiffMirrorsReply) XXX_Size() int {
+	return xxx_messageInfo_DiffMirrorsReply.Size(m)
 }
-func (m *StatsFileRequest) XXX_DiscardUnknown() {
-	xxx_messageInfo_StatsFileRequest.DiscardUnknown(m)
+func (m *DiffMirrorsReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_DiffMirrorsReply.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_StatsFileRequest proto.InternalMessageInfo
+var xxx_messageInfo_DiffMirrorsReply proto.InternalMessageInfo
 
-func (m *StatsFileRequest) GetPattern() string {
+func (m *DiffMirrorsReply) GetCommon() int64 {
 	if m != nil {
-		return m.Pattern
+		return m.Common
 	}
-	return ""
+	return 0
 }
 
-func (m *StatsFileRequest) GetDateStart() *timestamp.Timestamp {
+func (m *DiffMirrorsReply) GetOnlyA() int64 {
 	if m != nil {
-		return m.DateStart
+		return m.OnlyA
 	}
-	return nil
+	return 0
 }
 
-func (m *StatsFileRequest) GetDateEnd() *timestamp.Timestamp {
+func (m *DiffMirrorsReply) GetOnlyB() int64 {
 	if m != nil {
-		return m.DateEnd
+		return m.OnlyB
 	}
-	return nil
+	return 0
 }
 
-type StatsFileReply struct {
-	Files                map[string]int64 `protobuf:"bytes,1,rep,name=files,proto3" json:"files,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
-	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
-	XXX_unrecognized     []byte           `json:"-"`
-	XXX_sizecache        int32            `json:"-"`
+func (m *DiffMirrorsReply) GetChanged() int64 {
+	if m != nil {
+		return m.Changed
+	}
+	return 0
 }
 
-func (m *StatsFileReply) Reset()         { *m = StatsFileReply{} }
-func (m *StatsFileReply) String() string { return proto.CompactTextString(m) }
-func (*StatsFileReply) ProtoMessage()    {}
-func (*StatsFileReply) Descriptor() ([]byte, []int) {
-	return fileDescriptor_77a6da22d6a3feb1, []int{15}
+func (m *DiffMirrorsReply) GetSampleOnlyA() []string {
+	if m != nil {
+		return m.SampleOnlyA
+	}
+	return nil
 }
 
-func (m *StatsFileReply) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_StatsFileReply.Unmarshal(m, b)
-}
-func (m *StatsFileReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_StatsFileReply.Marshal(b, m, deterministic)
-}
-func (m *StatsFileReply) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_StatsFileReply.Merge(m, src)
-}
-func (m *StatsFileReply) XXX_Size() int {
-	return xxx_messageInfo_StatsFileReply.Size(m)
-}
-func (m *StatsFileReply) XXX_DiscardUnknown() {
-	xxx_messageInfo_StatsFileReply.DiscardUnknown(m)
+func (m *DiffMirrorsReply) GetSampleOnlyB() []string {
+	if m != nil {
+		return m.SampleOnlyB
+	}
+	return nil
 }
 
-var xxx_messageInfo_StatsFileReply proto.InternalMessageInfo
-
-func (m *StatsFileReply) GetFiles() map[string]int64 {
+func (m *DiffMirrorsReply) GetSampleChanged() []string {
 	if m != nil {
-		return m.Files
+		return m.SampleChanged
 	}
 	return nil
 }
 
-type StatsMirrorRequest struct {
-	ID                   int32                `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
-	DateStart            *timestamp.Timestamp `protobuf:"bytes,2,opt,name=DateStart,proto3" json:"DateStart,omitempty"`
-	DateEnd              *timestamp.Timestamp `protobuf:"bytes,3,opt,name=DateEnd,proto3" json:"DateEnd,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
-	XXX_unrecognized     []byte               `json:"-"`
-	XXX_sizecache        int32                `json:"-"`
+type SimulateRequest struct {
+	IP                   string   `protobuf:"bytes,1,opt,name=IP,proto3" json:"IP,omitempty"`
+	Path                 string   `protobuf:"bytes,2,opt,name=Path,proto3" json:"Path,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *StatsMirrorRequest) Reset()         { *m = StatsMirrorRequest{} }
-func (m *StatsMirrorRequest) String() string { return proto.CompactTextString(m) }
-func (*StatsMirrorRequest) ProtoMessage()    {}
-func (*StatsMirrorRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_77a6da22d6a3feb1, []int{16}
+func (m *SimulateRequest) Reset()         { *m = SimulateRequest{} }
+func (m *SimulateRequest) String() string { return proto.CompactTextString(m) }
+func (*SimulateRequest) ProtoMessage()    {}
+func (*SimulateRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{41}
 }
 
-func (m *StatsMirrorRequest) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_StatsMirrorRequest.Unmarshal(m, b)
+func (m *SimulateRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SimulateRequest.Unmarshal(m, b)
 }
-func (m *StatsMirrorRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_StatsMirrorRequest.Marshal(b, m, deterministic)
+func (m *SimulateRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SimulateRequest.Marshal(b, m, deterministic)
 }
-func (m *StatsMirrorRequest) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_StatsMirrorRequest.Merge(m, src)
+func (m *SimulateRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SimulateRequest.Merge(m, src)
 }
-func (m *StatsMirrorRequest) XXX_Size() int {
-	return xxx_messageInfo_StatsMirrorRequest.Size(m)
+func (m *SimulateRequest) XXX_Size() int {
+	return xxx_messageInfo_SimulateRequest.Size(m)
 }
-func (m *StatsMirrorRequest) XXX_DiscardUnknown() {
-	xxx_messageInfo_StatsMirrorRequest.DiscardUnknown(m)
+func (m *SimulateRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SimulateRequest.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_StatsMirrorRequest proto.InternalMessageInfo
-
-func (m *StatsMirrorRequest) GetID() int32 {
-	if m != nil {
-		return m.ID
-	}
-	return 0
-}
+var xxx_messageInfo_SimulateRequest proto.InternalMessageInfo
 
-func (m *StatsMirrorRequest) GetDateStart() *timestamp.Timestamp {
+func (m *SimulateRequest) GetIP() string {
 	if m != nil {
-		return m.DateStart
+		return m.IP
 	}
-	return nil
+	return ""
 }
 
-func (m *StatsMirrorRequest) GetDateEnd() *timestamp.Timestamp {
+func (m *SimulateRequest) GetPath() string {
 	if m != nil {
-		return m.DateEnd
+		return m.Path
 	}
-	return nil
+	return ""
 }
 
-type StatsMirrorReply struct {
-	Mirror               *Mirror  `protobuf:"bytes,1,opt,name=Mirror,proto3" json:"Mirror,omitempty"`
-	Requests             int64    `protobuf:"varint,2,opt,name=Requests,proto3" json:"Requests,omitempty"`
-	Bytes                int64    `protobuf:"varint,3,opt,name=Bytes,proto3" json:"Bytes,omitempty"`
+type SimulatedMirror struct {
+	ID                   int32    `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	Name                 string   `protobuf:"bytes,2,opt,name=Name,proto3" json:"Name,omitempty"`
+	URL                  string   `protobuf:"bytes,3,opt,name=URL,proto3" json:"URL,omitempty"`
+	Distance             float32  `protobuf:"fixed32,4,opt,name=Distance,proto3" json:"Distance,omitempty"`
+	Score                int32    `protobuf:"varint,5,opt,name=Score,proto3" json:"Score,omitempty"`
+	ExcludeReason        string   `protobuf:"bytes,6,opt,name=ExcludeReason,proto3" json:"ExcludeReason,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *StatsMirrorReply) Reset()         { *m = StatsMirrorReply{} }
-func (m *StatsMirrorReply) String() string { return proto.CompactTextString(m) }
-func (*StatsMirrorReply) ProtoMessage()    {}
-func (*StatsMirrorReply) Descriptor() ([]byte, []int) {
-	return fileDescriptor_77a6da22d6a3feb1, []int{17}
+func (m *SimulatedMirror) Reset()         { *m = SimulatedMirror{} }
+func (m *SimulatedMirror) String() string { return proto.CompactTextString(m) }
+func (*SimulatedMirror) ProtoMessage()    {}
+func (*SimulatedMirror) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{42}
 }
 
-func (m *StatsMirrorReply) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_StatsMirrorReply.Unmarshal(m, b)
+func (m *SimulatedMirror) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SimulatedMirror.Unmarshal(m, b)
 }
-func (m *StatsMirrorReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_StatsMirrorReply.Marshal(b, m, deterministic)
+func (m *SimulatedMirror) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SimulatedMirror.Marshal(b, m, deterministic)
 }
-func (m *StatsMirrorReply) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_StatsMirrorReply.Merge(m, src)
+func (m *SimulatedMirror) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SimulatedMirror.Merge(m, src)
 }
-func (m *StatsMirrorReply) XXX_Size() int {
-	return xxx_messageInfo_StatsMirrorReply.Size(m)
+func (m *SimulatedMirror) XXX_Size() int {
+	return xxx_messageInfo_SimulatedMirror.Size(m)
 }
-func (m *StatsMirrorReply) XXX_DiscardUnknown() {
-	xxx_messageInfo_StatsMirrorReply.DiscardUnknown(m)
+func (m *SimulatedMirror) XXX_DiscardUnknown() {
+	xxx_messageInfo_SimulatedMirror.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_StatsMirrorReply proto.InternalMessageInfo
+var xxx_messageInfo_SimulatedMirror proto.InternalMessageInfo
 
-func (m *StatsMirrorReply) GetMirror() *Mirror {
+func (m *SimulatedMirror) GetID() int32 {
 	if m != nil {
-		return m.Mirror
+		return m.ID
 	}
-	return nil
+	return 0
 }
 
-func (m *StatsMirrorReply) GetRequests() int64 {
+func (m *SimulatedMirror) GetName() string {
 	if m != nil {
-		return m.Requests
+		return m.Name
+	}
+	return ""
+}
+
+func (m *SimulatedMirror) GetURL() string {
+	if m != nil {
+		return m.URL
+	}
+	return ""
+}
+
+func (m *SimulatedMirror) GetDistance() float32 {
+	if m != nil {
+		return m.Distance
 	}
 	return 0
 }
 
-func (m *StatsMirrorReply) GetBytes() int64 {
+func (m *SimulatedMirror) GetScore() int32 {
 	if m != nil {
-		return m.Bytes
+		return m.Score
 	}
 	return 0
 }
 
-type GetMirrorLogsRequest struct {
-	ID                   int32    `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
-	MaxResults           int32    `protobuf:"varint,2,opt,name=MaxResults,proto3" json:"MaxResults,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+func (m *SimulatedMirror) GetExcludeReason() string {
+	if m != nil {
+		return m.ExcludeReason
+	}
+	return ""
 }
 
-func (m *GetMirrorLogsRequest) Reset()         { *m = GetMirrorLogsRequest{} }
-func (m *GetMirrorLogsRequest) String() string { return proto.CompactTextString(m) }
-func (*GetMirrorLogsRequest) ProtoMessage()    {}
-func (*GetMirrorLogsRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_77a6da22d6a3feb1, []int{18}
+type SimulateReply struct {
+	Country              string             `protobuf:"bytes,1,opt,name=Country,proto3" json:"Country,omitempty"`
+	CountryCode          string             `protobuf:"bytes,2,opt,name=CountryCode,proto3" json:"CountryCode,omitempty"`
+	Continent            string             `protobuf:"bytes,3,opt,name=Continent,proto3" json:"Continent,omitempty"`
+	Latitude             float32            `protobuf:"fixed32,4,opt,name=Latitude,proto3" json:"Latitude,omitempty"`
+	Longitude            float32            `protobuf:"fixed32,5,opt,name=Longitude,proto3" json:"Longitude,omitempty"`
+	ASN                  string             `protobuf:"bytes,6,opt,name=ASN,proto3" json:"ASN,omitempty"`
+	Located              bool               `protobuf:"varint,7,opt,name=Located,proto3" json:"Located,omitempty"`
+	Fallback             bool               `protobuf:"varint,8,opt,name=Fallback,proto3" json:"Fallback,omitempty"`
+	Mirrors              []*SimulatedMirror `protobuf:"bytes,9,rep,name=Mirrors,proto3" json:"Mirrors,omitempty"`
+	Excluded             []*SimulatedMirror `protobuf:"bytes,10,rep,name=Excluded,proto3" json:"Excluded,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
 }
 
-func (m *GetMirrorLogsRequest) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_GetMirrorLogsRequest.Unmarshal(m, b)
+func (m *SimulateReply) Reset()         { *m = SimulateReply{} }
+func (m *SimulateReply) String() string { return proto.CompactTextString(m) }
+func (*SimulateReply) ProtoMessage()    {}
+func (*SimulateReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_77a6da22d6a3feb1, []int{43}
 }
-func (m *GetMirrorLogsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_GetMirrorLogsRequest.Marshal(b, m, deterministic)
+
+func (m *SimulateReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SimulateReply.Unmarshal(m, b)
 }
-func (m *GetMirrorLogsRequest) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_GetMirrorLogsRequest.Merge(m, src)
+func (m *SimulateReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SimulateReply.Marshal(b, m, deterministic)
 }
-func (m *GetMirrorLogsRequest) XXX_Size() int {
-	return xxx_messageInfo_GetMirrorLogsRequest.Size(m)
+func (m *SimulateReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SimulateReply.Merge(m, src)
 }
-func (m *GetMirrorLogsRequest) XXX_DiscardUnknown() {
-	xxx_messageInfo_GetMirrorLogsRequest.DiscardUnknown(m)
+func (m *SimulateReply) XXX_Size() int {
+	return xxx_messageInfo_SimulateReply.Size(m)
+}
+func (m *SimulateReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_SimulateReply.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_GetMirrorLogsRequest proto.InternalMessageInfo
+var xxx_messageInfo_SimulateReply proto.InternalMessageInfo
 
-func (m *GetMirrorLogsRequest) GetID() int32 {
+func (m *SimulateReply) GetCountry() string {
 	if m != nil {
-		return m.ID
+		return m.Country
 	}
-	return 0
+	return ""
 }
 
-func (m *GetMirrorLogsRequest) GetMaxResults() int32 {
+func (m *SimulateReply) GetCountryCode() string {
 	if m != nil {
-		return m.MaxResults
+		return m.CountryCode
 	}
-	return 0
+	return ""
 }
 
-type GetMirrorLogsReply struct {
-	Line                 []string `protobuf:"bytes,1,rep,name=line,proto3" json:"line,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+func (m *SimulateReply) GetContinent() string {
+	if m != nil {
+		return m.Continent
+	}
+	return ""
 }
 
-func (m *GetMirrorLogsReply) Reset()         { *m = GetMirrorLogsReply{} }
-func (m *GetMirrorLogsReply) String() string { return proto.CompactTextString(m) }
-func (*GetMirrorLogsReply) ProtoMessage()    {}
-func (*GetMirrorLogsReply) Descriptor() ([]byte, []int) {
-	return fileDescriptor_77a6da22d6a3feb1, []int{19}
+func (m *SimulateReply) GetLatitude() float32 {
+	if m != nil {
+		return m.Latitude
+	}
+	return 0
 }
 
-func (m *GetMirrorLogsReply) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_GetMirrorLogsReply.Unmarshal(m, b)
-}
-func (m *GetMirrorLogsReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_GetMirrorLogsReply.Marshal(b, m, deterministic)
+func (m *SimulateReply) GetLongitude() float32 {
+	if m != nil {
+		return m.Longitude
+	}
+	return 0
 }
-func (m *GetMirrorLogsReply) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_GetMirrorLogsReply.Merge(m, src)
+
+func (m *SimulateReply) GetASN() string {
+	if m != nil {
+		return m.ASN
+	}
+	return ""
 }
-func (m *GetMirrorLogsReply) XXX_Size() int {
-	return xxx_messageInfo_GetMirrorLogsReply.Size(m)
+
+func (m *SimulateReply) GetLocated() bool {
+	if m != nil {
+		return m.Located
+	}
+	return false
 }
-func (m *GetMirrorLogsReply) XXX_DiscardUnknown() {
-	xxx_messageInfo_GetMirrorLogsReply.DiscardUnknown(m)
+
+func (m *SimulateReply) GetFallback() bool {
+	if m != nil {
+		return m.Fallback
+	}
+	return false
 }
 
-var xxx_messageInfo_GetMirrorLogsReply proto.InternalMessageInfo
+func (m *SimulateReply) GetMirrors() []*SimulatedMirror {
+	if m != nil {
+		return m.Mirrors
+	}
+	return nil
+}
 
-func (m *GetMirrorLogsReply) GetLine() []string {
+func (m *SimulateReply) GetExcluded() []*SimulatedMirror {
 	if m != nil {
-		return m.Line
+		return m.Excluded
 	}
 	return nil
 }
@@ -1303,10 +2988,19 @@ func init() {
 	proto.RegisterType((*VersionReply)(nil), "VersionReply")
 	proto.RegisterType((*MatchRequest)(nil), "MatchRequest")
 	proto.RegisterType((*Mirror)(nil), "Mirror")
+	proto.RegisterMapType((map[string]string)(nil), "Mirror.HTTPHeadersEntry")
+	proto.RegisterMapType((map[string]string)(nil), "Mirror.LabelsEntry")
 	proto.RegisterType((*MirrorListReply)(nil), "MirrorListReply")
 	proto.RegisterType((*MirrorID)(nil), "MirrorID")
 	proto.RegisterType((*MatchReply)(nil), "MatchReply")
+	proto.RegisterType((*MirrorRequestRates)(nil), "MirrorRequestRates")
+	proto.RegisterType((*RequestRatesReply)(nil), "RequestRatesReply")
+	proto.RegisterType((*SetMaintenanceRequest)(nil), "SetMaintenanceRequest")
+	proto.RegisterType((*MaintenanceReply)(nil), "MaintenanceReply")
 	proto.RegisterType((*ChangeStatusRequest)(nil), "ChangeStatusRequest")
+	proto.RegisterType((*SetMirrorsEnabledByTagRequest)(nil), "SetMirrorsEnabledByTagRequest")
+	proto.RegisterType((*SetMirrorsEnabledByTagReply)(nil), "SetMirrorsEnabledByTagReply")
+	proto.RegisterType((*ForceMirrorStateRequest)(nil), "ForceMirrorStateRequest")
 	proto.RegisterType((*MirrorIDRequest)(nil), "MirrorIDRequest")
 	proto.RegisterType((*AddMirrorReply)(nil), "AddMirrorReply")
 	proto.RegisterType((*UpdateMirrorReply)(nil), "UpdateMirrorReply")
@@ -1314,13 +3008,30 @@ func init() {
 	proto.RegisterType((*RefreshRepositoryRequest)(nil), "RefreshRepositoryRequest")
 	proto.RegisterType((*ScanMirrorRequest)(nil), "ScanMirrorRequest")
 	proto.RegisterType((*ScanMirrorReply)(nil), "ScanMirrorReply")
+	proto.RegisterType((*ScanStatusReply)(nil), "ScanStatusReply")
 	proto.RegisterType((*StatsFileRequest)(nil), "StatsFileRequest")
 	proto.RegisterType((*StatsFileReply)(nil), "StatsFileReply")
 	proto.RegisterMapType((map[string]int64)(nil), "StatsFileReply.FilesEntry")
+	proto.RegisterType((*TopFilesRequest)(nil), "TopFilesRequest")
+	proto.RegisterType((*TopFile)(nil), "TopFile")
+	proto.RegisterType((*TopFilesReply)(nil), "TopFilesReply")
 	proto.RegisterType((*StatsMirrorRequest)(nil), "StatsMirrorRequest")
 	proto.RegisterType((*StatsMirrorReply)(nil), "StatsMirrorReply")
+	proto.RegisterType((*ExportStatsRequest)(nil), "ExportStatsRequest")
+	proto.RegisterType((*MirrorPeriodStats)(nil), "MirrorPeriodStats")
+	proto.RegisterType((*ExportStatsReply)(nil), "ExportStatsReply")
+	proto.RegisterType((*ResetStatsRequest)(nil), "ResetStatsRequest")
+	proto.RegisterType((*ResetStatsReply)(nil), "ResetStatsReply")
 	proto.RegisterType((*GetMirrorLogsRequest)(nil), "GetMirrorLogsRequest")
 	proto.RegisterType((*GetMirrorLogsReply)(nil), "GetMirrorLogsReply")
+	proto.RegisterType((*FileMirrorsRequest)(nil), "FileMirrorsRequest")
+	proto.RegisterType((*FileMirror)(nil), "FileMirror")
+	proto.RegisterType((*FileMirrorsReply)(nil), "FileMirrorsReply")
+	proto.RegisterType((*DiffMirrorsRequest)(nil), "DiffMirrorsRequest")
+	proto.RegisterType((*DiffMirrorsReply)(nil), "DiffMirrorsReply")
+	proto.RegisterType((*SimulateRequest)(nil), "SimulateRequest")
+	proto.RegisterType((*SimulatedMirror)(nil), "SimulatedMirror")
+	proto.RegisterType((*SimulateReply)(nil), "SimulateReply")
 }
 
 func init() {
@@ -1328,99 +3039,196 @@ func init() {
 }
 
 var fileDescriptor_77a6da22d6a3feb1 = []byte{
-	// 1472 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x57, 0xdd, 0x72, 0x1b, 0xc5,
-	0x12, 0xd6, 0x4a, 0xb6, 0x65, 0xb5, 0x64, 0x5b, 0x1e, 0x3b, 0x3e, 0x1b, 0x25, 0x27, 0x51, 0xe6,
-	0xfc, 0x44, 0xa7, 0x4e, 0x9d, 0xcd, 0x89, 0x49, 0xc0, 0x15, 0x02, 0x94, 0x90, 0x6c, 0xc7, 0x20,
-	0xc7, 0xae, 0x55, 0x0c, 0x05, 0x77, 0x1b, 0xed, 0x48, 0xde, 0x62, 0xb5, 0x23, 0x76, 0x46, 0x89,
-	0x55, 0xc5, 0x63, 0x70, 0xc9, 0x05, 0x3c, 0x00, 0x55, 0x5c, 0xf2, 0x40, 0x3c, 0x08, 0xd5, 0x33,
-	0xb3, 0xd2, 0x6a, 0xe5, 0x1f, 0xc8, 0x05, 0x77, 0xf3, 0x7d, 0xdd, 0x33, 0xdd, 0xd3, 0xd3, 0x3f,
-	0xbb, 0x50, 0x8a, 0x47, 0x3d, 0x67, 0x14, 0x73, 0xc9, 0x6b, 0x77, 0x06, 0x9c, 0x0f, 0x42, 0xf6,
-	0x48, 0xa1, 0xd7, 0xe3, 0xfe, 0x23, 0x36, 0x1c, 0xc9, 0x89, 0x11, 0xde, 0xcf, 0x0a, 0x65, 0x30,
-	0x64, 0x42, 0x7a, 0xc3, 0x91, 0x56, 0xa0, 0x3f, 0x5a, 0x50, 0xf9, 0x82, 0xc5, 0x22, 0xe0, 0x91,
-	0xcb, 0x46, 0xe1, 0x84, 0xd8, 0x50, 0x34, 0xd8, 0xb6, 0xea, 0x56, 0xa3, 0xe4, 0x26, 0x90, 0x6c,
-	0xc3, 0xf2, 0xa7, 0xe3, 0x20, 0xf4, 0xed, 0xbc, 0xe2, 0x35, 0x20, 0x77, 0xa1, 0x74, 0xc8, 0x93,
-	0x1d, 0x05, 0x25, 0x99, 0x11, 0x64, 0x1d, 0xf2, 0x27, 0x5d, 0x7b, 0x49, 0xd1, 0xf9, 0x93, 0x2e,
-	0x21, 0xb0, 0xd4, 0x8c, 0x7b, 0xe7, 0xf6, 0xb2, 0x62, 0xd4, 0x9a, 0xdc, 0x03, 0x38, 0xe4, 0xc7,
-	0xde, 0xc5, 0x69, 0xcc, 0x7b, 0xc2, 0x5e, 0xa9, 0x5b, 0x8d, 0x65, 0x37, 0xc5, 0xd0, 0x06, 0x54,
-	0x8e, 0x3d, 0xd9, 0x3b, 0x77, 0xd9, 0xb7, 0x63, 0x26, 0x24, 0x7a, 0x78, 0xea, 0x49, 0xc9, 0xe2,
-	0xa9, 0x87, 0x06, 0xd2, 0xdf, 0x56, 0x61, 0xe5, 0x38, 0x88, 0x63, 0x1e, 0xa3, 0xe1, 0xa3, 0xb6,
-	0x92, 0x2f, 0xbb, 0xf9, 0xa3, 0x36, 0x1a, 0x7e, 0xe9, 0x0d, 0x99, 0xf1, 0x5d, 0xad, 0xf1, 0xa0,
-	0x17, 0x52, 0x8e, 0xce, 0xdc, 0x8e, 0x71, 0x3c, 0x81, 0xa4, 0x06, 0xab, 0xae, 0x98, 0x44, 0x3d,
-	0x14, 0x69, 0xe7, 0xa7, 0x98, 0xec, 0xc0, 0xca, 0x81, 0xde, 0xa4, 0x2f, 0x61, 0x10, 0xa9, 0x43,
-	0xb9, 0x3b, 0xe2, 0x91, 0xe0, 0xb1, 0x32, 0xb4, 0xa2, 0x84, 0x69, 0x0a, 0x2f, 0x6a, 0x20, 0xee,
-	0x2e, 0x2a, 0x85, 0x14, 0x43, 0xfe, 0x0d, 0xeb, 0x06, 0x75, 0xf8, 0x80, 0xa3, 0xce, 0xaa, 0xd2,
-	0xc9, 0xb0, 0x18, 0xf2, 0xa6, 0x3f, 0x0c, 0x22, 0x65, 0xa7, 0xa4, 0x43, 0x3e, 0x25, 0xd0, 0x8a,
-	0x02, 0xfb, 0x43, 0x2f, 0x08, 0x6d, 0xd0, 0x56, 0x66, 0x0c, 0xca, 0x5b, 0x63, 0x21, 0xf9, 0xb0,
-	0xed, 0x49, 0xcf, 0x2e, 0x6b, 0xf9, 0x8c, 0x21, 0xff, 0x84, 0xb5, 0x16, 0x8f, 0x64, 0x10, 0xb1,
-	0x48, 0x9e, 0x44, 0xe1, 0xc4, 0xae, 0xd4, 0xad, 0xc6, 0xaa, 0x3b, 0x4f, 0xe2, 0x6d, 0x5b, 0x7c,
-	0x1c, 0xc9, 0x78, 0xa2, 0x74, 0xd6, 0x94, 0x4e, 0x9a, 0xc2, 0x38, 0x35, 0xbb, 0x4a, 0xb8, 0xae,
-	0x84, 0x06, 0x61, 0x1a, 0x75, 0x7b, 0x3c, 0x66, 0xf6, 0x86, 0x7a, 0x1c, 0x0d, 0x30, 0xe2, 0x1d,
-	0x4f, 0x06, 0x72, 0xec, 0x33, 0xbb, 0x5a, 0xb7, 0x1a, 0x79, 0x77, 0x8a, 0xf1, 0xbe, 0x1d, 0x1e,
-	0x0d, 0xb4, 0x70, 0x53, 0x09, 0x67, 0xc4, 0x9c, 0xbf, 0x2d, 0xee, 0x33, 0x9b, 0xa8, 0x2b, 0xcd,
-	0x93, 0x84, 0x42, 0xc5, 0x38, 0x87, 0x50, 0xd8, 0x5b, 0x4a, 0x69, 0x8e, 0x23, 0xbb, 0xb0, 0xbd,
-	0x7f, 0xd1, 0x0b, 0xc7, 0x3e, 0xf3, 0xe7, 0x74, 0xb7, 0x95, 0xee, 0xa5, 0x32, 0xbc, 0x4d, 0x53,
-	0x44, 0xe3, 0xa1, 0x7d, 0xab, 0x6e, 0x35, 0xd6, 0x5c, 0x0d, 0x30, 0xb3, 0x5a, 0x7c, 0x38, 0x64,
-	0x91, 0xb4, 0x77, 0x74, 0x66, 0x19, 0x88, 0x92, 0xfd, 0xc8, 0x7b, 0x1d, 0x32, 0xdf, 0xfe, 0x9b,
-	0x0a, 0x4b, 0x02, 0x31, 0x5e, 0x2a, 0xfd, 0x46, 0xb6, 0xad, 0xe3, 0xa5, 0x11, 0x66, 0x05, 0xae,
-	0xda, 0xfc, 0x6d, 0xe4, 0x32, 0x4f, 0xf0, 0xc8, 0xbe, 0xad, 0xb3, 0x62, 0x9e, 0x25, 0xcf, 0x00,
-	0xba, 0xd2, 0x93, 0xac, 0x1b, 0x44, 0x3d, 0x66, 0xd7, 0xea, 0x56, 0xa3, 0xbc, 0x5b, 0x73, 0x74,
-	0xfd, 0x3b, 0x49, 0xfd, 0x3b, 0xaf, 0x92, 0xfa, 0x77, 0x53, 0xda, 0x68, 0xa3, 0x19, 0x86, 0xfc,
-	0xad, 0xcb, 0xfc, 0x20, 0x66, 0x3d, 0x29, 0xec, 0x3b, 0xea, 0x71, 0x32, 0x2c, 0x79, 0x1f, 0x5f,
-	0x49, 0xc8, 0xee, 0x24, 0xea, 0xd9, 0x77, 0x6f, 0xb4, 0x30, 0xd5, 0x25, 0x9f, 0x01, 0x51, 0xeb,
-	0x71, 0xaf, 0xc7, 0x84, 0xe8, 0x8f, 0x43, 0x75, 0xc2, 0xdf, 0x6f, 0x3c, 0xe1, 0x92, 0x5d, 0xe4,
-	0x39, 0x94, 0x91, 0x3d, 0xe6, 0x3e, 0xea, 0xd9, 0xf7, 0x6e, 0x3c, 0x24, 0xad, 0x9e, 0xd4, 0xbc,
-	0x38, 0x1b, 0xd9, 0xf7, 0x75, 0xfc, 0x0d, 0x24, 0x0d, 0xd8, 0x50, 0xcb, 0x54, 0xa0, 0xeb, 0x2a,
-	0xd0, 0x59, 0x9a, 0x3e, 0x81, 0x0d, 0xdd, 0x65, 0x3a, 0x81, 0x90, 0xba, 0x6b, 0x3e, 0x80, 0xa2,
-	0xa6, 0x84, 0x6d, 0xd5, 0x0b, 0x8d, 0xf2, 0x6e, 0xd1, 0xd1, 0xd8, 0x4d, 0x78, 0xea, 0xc0, 0xaa,
-	0x5e, 0x1e, 0xb5, 0xff, 0x48, 0x77, 0xa2, 0x8f, 0x01, 0x4c, 0xdb, 0x43, 0x03, 0xff, 0xc8, 0x1a,
-	0x28, 0x39, 0xc9, 0x69, 0x33, 0x13, 0x9f, 0xc0, 0x56, 0xeb, 0xdc, 0x8b, 0x06, 0x0c, 0x9f, 0x76,
-	0x2c, 0x92, 0x86, 0x99, 0xb5, 0x96, 0xca, 0xc1, 0xfc, 0x5c, 0x0e, 0xd2, 0x07, 0xc9, 0xcd, 0x8e,
-	0xda, 0x57, 0x6c, 0xa6, 0xbf, 0x58, 0xb0, 0xde, 0xf4, 0x7d, 0x73, 0x3b, 0xe5, 0x5b, 0xba, 0x76,
-	0xad, 0xeb, 0x6a, 0x37, 0x9f, 0xad, 0x5d, 0x55, 0x27, 0xaa, 0x9a, 0x92, 0x0e, 0x6c, 0x20, 0xee,
-	0x9b, 0x16, 0xb0, 0x69, 0xc1, 0x33, 0x82, 0x54, 0xa1, 0xd0, 0xec, 0xbe, 0x34, 0x0d, 0x18, 0x97,
-	0xe8, 0xc3, 0x97, 0x5e, 0x1c, 0x05, 0xd1, 0x00, 0x47, 0x48, 0x01, 0x3b, 0x76, 0x82, 0xe9, 0x43,
-	0xd8, 0x3c, 0x1b, 0xf9, 0x9e, 0x64, 0x69, 0xa7, 0x09, 0x2c, 0xb5, 0x83, 0x7e, 0xdf, 0x8c, 0x10,
-	0xb5, 0xa6, 0x03, 0xd8, 0x3e, 0x64, 0x7c, 0x51, 0xf7, 0x7e, 0x32, 0x56, 0x94, 0x76, 0xea, 0x71,
-	0x93, 0x69, 0x93, 0x1c, 0x96, 0x9f, 0x1d, 0x36, 0xe7, 0x51, 0x21, 0xe3, 0xd1, 0x2e, 0xd8, 0x2e,
-	0xeb, 0xc7, 0x4c, 0xe0, 0xeb, 0x72, 0x11, 0x48, 0x1e, 0x4f, 0x92, 0x80, 0xef, 0xc0, 0x8a, 0xcb,
-	0xce, 0x3d, 0x71, 0xae, 0x8c, 0xad, 0xba, 0x06, 0xd1, 0x9f, 0x2c, 0xd8, 0xec, 0xf6, 0xbc, 0x28,
-	0x71, 0xec, 0xf2, 0xb7, 0xc5, 0xee, 0x3f, 0x96, 0x5c, 0x3f, 0xa8, 0x79, 0xde, 0x14, 0x43, 0x9e,
-	0xc2, 0xea, 0x29, 0x96, 0x48, 0x8f, 0x87, 0x2a, 0xe4, 0xeb, 0xbb, 0xb7, 0x9d, 0x85, 0x53, 0x9d,
-	0x63, 0x26, 0xcf, 0xb9, 0xef, 0x4e, 0x55, 0xe9, 0xbf, 0x60, 0x45, 0x73, 0xa4, 0x08, 0x85, 0x66,
-	0xa7, 0x53, 0xcd, 0xe1, 0xe2, 0xe0, 0xd5, 0x69, 0xd5, 0x22, 0x25, 0x58, 0x76, 0xbb, 0x5f, 0xbd,
-	0x6c, 0x55, 0xf3, 0xf4, 0x67, 0x0b, 0x36, 0xd2, 0xa7, 0x99, 0x0f, 0x8a, 0x24, 0xdb, 0xac, 0xf9,
-	0x8e, 0x47, 0xa1, 0x72, 0x10, 0x84, 0x4c, 0x1c, 0x45, 0x3e, 0xbb, 0x30, 0xc9, 0x58, 0x70, 0xe7,
-	0x38, 0xd4, 0xf9, 0x3c, 0xe2, 0x6f, 0xa3, 0x44, 0xa7, 0xa0, 0x75, 0xd2, 0x1c, 0x5a, 0x70, 0xd9,
-	0x90, 0xbf, 0x61, 0xbe, 0xca, 0x94, 0x82, 0x9b, 0x40, 0x8c, 0xc6, 0xab, 0xaf, 0x4f, 0xfa, 0x7d,
-	0xc1, 0xe4, 0xb1, 0x50, 0xe9, 0x52, 0x70, 0x53, 0x0c, 0xfd, 0xc1, 0x82, 0x2a, 0xd6, 0x8a, 0x40,
-	0x9b, 0x37, 0x7e, 0x5f, 0x90, 0x3d, 0x28, 0xb5, 0xb1, 0x67, 0x4a, 0x2f, 0x96, 0xca, 0xdb, 0xeb,
-	0x1b, 0xcf, 0x4c, 0x99, 0x3c, 0x81, 0x22, 0x82, 0xfd, 0x48, 0xdf, 0xe0, 0xfa, 0x7d, 0x89, 0x2a,
-	0xfd, 0x0e, 0xd6, 0x53, 0xde, 0x61, 0x30, 0xff, 0x0f, 0xcb, 0x7d, 0x0c, 0x8f, 0x69, 0x02, 0x35,
-	0x67, 0x5e, 0xee, 0xa8, 0xd8, 0xed, 0x63, 0x05, 0xb9, 0x5a, 0xb1, 0xb6, 0x07, 0x30, 0x23, 0xb1,
-	0x70, 0xbe, 0x61, 0x13, 0x73, 0x2f, 0x5c, 0xe2, 0x00, 0x7b, 0xe3, 0x85, 0x63, 0x66, 0xa2, 0xaf,
-	0xc1, 0xb3, 0xfc, 0x9e, 0x45, 0xbf, 0xb7, 0x80, 0xa8, 0xe3, 0xaf, 0xcf, 0xb8, 0xbf, 0x3a, 0x28,
-	0xcc, 0x3c, 0xd9, 0x9f, 0x2a, 0x50, 0xfc, 0xa0, 0xd3, 0xfe, 0x0b, 0x73, 0xd1, 0x29, 0x56, 0xdf,
-	0xb5, 0x13, 0xc9, 0x84, 0xc9, 0x2d, 0x0d, 0xe8, 0x01, 0xf6, 0x02, 0x69, 0xfa, 0x3c, 0x1f, 0x88,
-	0x6b, 0x0a, 0xee, 0xd8, 0xbb, 0x70, 0x99, 0x18, 0x87, 0xe6, 0xec, 0x65, 0x37, 0xc5, 0xd0, 0x06,
-	0x90, 0xcc, 0x39, 0xa6, 0xfb, 0x84, 0x41, 0xc4, 0xd4, 0x33, 0x96, 0x5c, 0xb5, 0xde, 0xfd, 0xb5,
-	0x08, 0x85, 0x56, 0xe7, 0x88, 0x3c, 0x05, 0x38, 0x64, 0x32, 0xf9, 0x82, 0xde, 0x59, 0x88, 0xc9,
-	0x3e, 0x7e, 0xdf, 0xd7, 0xd6, 0x9c, 0xf4, 0x67, 0x3b, 0xcd, 0x91, 0x0f, 0xa1, 0x78, 0x36, 0x1a,
-	0xc4, 0x9e, 0xcf, 0xae, 0xdc, 0x73, 0x05, 0x4f, 0x73, 0xe4, 0x19, 0x36, 0x9d, 0x90, 0x7b, 0xfe,
-	0x3b, 0xec, 0xfd, 0x18, 0x2a, 0xe9, 0xa9, 0x43, 0xb6, 0x9d, 0x4b, 0x86, 0xd0, 0x35, 0xfb, 0x77,
-	0x61, 0x09, 0x07, 0xe9, 0x95, 0x96, 0xab, 0x4e, 0x66, 0xda, 0xd2, 0x1c, 0xf9, 0x0f, 0x80, 0x19,
-	0x54, 0x51, 0x9f, 0x93, 0xaa, 0x93, 0x99, 0x5a, 0xb5, 0x24, 0x01, 0x68, 0x8e, 0x3c, 0xc4, 0xaf,
-	0x65, 0x33, 0xaf, 0x48, 0xc2, 0xd7, 0x36, 0x9c, 0xf9, 0x21, 0x46, 0x73, 0xe4, 0x7f, 0x50, 0x49,
-	0xb7, 0xfe, 0x99, 0x2e, 0x71, 0x16, 0x46, 0x82, 0x0a, 0x59, 0x45, 0xb7, 0x19, 0xa3, 0xbe, 0xe8,
-	0xc4, 0xd5, 0x57, 0x7e, 0x0e, 0x1b, 0x99, 0x41, 0x73, 0xc9, 0xf6, 0x5b, 0xce, 0x65, 0xc3, 0x88,
-	0xe6, 0xc8, 0x0b, 0xd8, 0x5c, 0x98, 0x1e, 0xe4, 0xb6, 0x73, 0xd5, 0x44, 0xb9, 0xc6, 0x8f, 0x27,
-	0x00, 0xb3, 0x76, 0x4d, 0xc8, 0xe2, 0x24, 0xa8, 0x55, 0x9d, 0x4c, 0x3f, 0xa7, 0x39, 0xf2, 0x18,
-	0x4a, 0xd3, 0xb6, 0x43, 0x36, 0x9d, 0x6c, 0x03, 0xad, 0x6d, 0x64, 0xba, 0x12, 0xcd, 0x91, 0x0f,
-	0xa0, 0x9c, 0x2a, 0x5a, 0xb2, 0xe5, 0x2c, 0x36, 0x96, 0xda, 0xa6, 0x93, 0xad, 0x6b, 0x9a, 0x23,
-	0x7b, 0xb0, 0x74, 0x1a, 0x44, 0x83, 0x77, 0x48, 0xcb, 0x8f, 0x60, 0x6d, 0xae, 0xf0, 0x08, 0xc6,
-	0x73, 0xb1, 0xa0, 0x6b, 0x5b, 0xce, 0x62, 0x7d, 0xd2, 0x1c, 0xf9, 0x2f, 0x94, 0xd5, 0xe7, 0x97,
-	0xf1, 0x78, 0xcd, 0x49, 0xff, 0x83, 0xd6, 0xca, 0xce, 0xec, 0xdb, 0x8c, 0xe6, 0x5e, 0xaf, 0x28,
-	0xeb, 0xef, 0xfd, 0x1e, 0x00, 0x00, 0xff, 0xff, 0x8e, 0xac, 0x3b, 0x6f, 0x97, 0x0f, 0x00, 0x00,
+	// 3012 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x5a, 0x49, 0x93, 0x1b, 0xc7,
+	0xb1, 0x06, 0xd0, 0xb3, 0xe6, 0x6c, 0x40, 0x0d, 0x49, 0x95, 0x20, 0x89, 0x1a, 0xb5, 0x28, 0x09,
+	0x5a, 0xd8, 0x94, 0x46, 0x24, 0x45, 0x51, 0xcb, 0x7b, 0xb3, 0x92, 0xf3, 0x88, 0xe1, 0xe0, 0x35,
+	0x30, 0xd2, 0x7b, 0xbe, 0x35, 0xd1, 0x35, 0x98, 0x0e, 0x35, 0xba, 0xe0, 0xee, 0x06, 0x39, 0x50,
+	0x38, 0x1c, 0xe1, 0x7f, 0xe0, 0x83, 0x8f, 0x8e, 0xf0, 0xcd, 0x11, 0xbe, 0xf9, 0xe4, 0x7f, 0xe0,
+	0x3f, 0xe1, 0x7f, 0xe1, 0xab, 0x4f, 0x8e, 0xcc, 0xaa, 0x5e, 0x31, 0x1b, 0x7d, 0xd0, 0xad, 0x32,
+	0x2b, 0xab, 0x2a, 0xb7, 0xaa, 0xfe, 0x32, 0x01, 0x58, 0x0c, 0x47, 0x7d, 0x6b, 0x14, 0xca, 0x58,
+	0x36, 0xdf, 0x1a, 0x48, 0x39, 0xf0, 0xc5, 0x3d, 0xa2, 0x5e, 0x8c, 0x4f, 0xee, 0x89, 0xe1, 0x28,
+	0x9e, 0xe8, 0xc9, 0x77, 0xcb, 0x93, 0xb1, 0x37, 0x14, 0x51, 0xec, 0x0c, 0x47, 0x4a, 0xc0, 0xfc,
+	0x73, 0x0d, 0x96, 0x7f, 0x10, 0x61, 0xe4, 0xc9, 0xc0, 0x16, 0x23, 0x7f, 0xc2, 0x38, 0xcc, 0x6b,
+	0x9a, 0x57, 0x37, 0xaa, 0xad, 0x45, 0x3b, 0x21, 0xd9, 0x0d, 0x98, 0xdd, 0x1e, 0x7b, 0xbe, 0xcb,
+	0x6b, 0xc4, 0x57, 0x04, 0x7b, 0x1b, 0x16, 0x9f, 0xc8, 0x64, 0x85, 0x41, 0x33, 0x19, 0x83, 0xad,
+	0x42, 0xed, 0xa8, 0xcb, 0x67, 0x88, 0x5d, 0x3b, 0xea, 0x32, 0x06, 0x33, 0x5b, 0x61, 0xff, 0x94,
+	0xcf, 0x12, 0x87, 0xc6, 0xec, 0x36, 0xc0, 0x13, 0x79, 0xe8, 0x9c, 0x75, 0x42, 0xd9, 0x8f, 0xf8,
+	0xdc, 0x46, 0xb5, 0x35, 0x6b, 0xe7, 0x38, 0xec, 0x16, 0xcc, 0xed, 0xc8, 0xe1, 0xd0, 0x8b, 0xf9,
+	0x3c, 0xad, 0xd2, 0x14, 0x9e, 0x4c, 0x2a, 0xec, 0x3a, 0xb1, 0xe0, 0x0b, 0xea, 0xe4, 0x94, 0x81,
+	0xab, 0x76, 0x1d, 0x31, 0x94, 0x01, 0x5f, 0xdc, 0xa8, 0xb6, 0x16, 0x6c, 0x4d, 0x21, 0xff, 0x78,
+	0x84, 0x5e, 0xe0, 0xb0, 0x51, 0x6d, 0x19, 0xb6, 0xa6, 0x50, 0x8b, 0x1d, 0x19, 0x9c, 0x78, 0x83,
+	0x7d, 0xcf, 0x17, 0x7c, 0x89, 0xb6, 0xcb, 0x71, 0xcc, 0x16, 0x2c, 0x1f, 0x3a, 0x71, 0xff, 0xd4,
+	0x16, 0xbf, 0x1e, 0x8b, 0x28, 0x46, 0x3f, 0x75, 0x9c, 0x38, 0x16, 0x61, 0xea, 0x27, 0x4d, 0x9a,
+	0x7f, 0x6b, 0xc0, 0xdc, 0xa1, 0x17, 0x86, 0x32, 0x44, 0xf3, 0x0f, 0x76, 0x69, 0x7e, 0xd6, 0xae,
+	0x1d, 0xec, 0xa2, 0xf9, 0xcf, 0x9d, 0xa1, 0xd0, 0x1e, 0xa4, 0x31, 0x6e, 0xf4, 0x34, 0x8e, 0x47,
+	0xc7, 0x76, 0x5b, 0xbb, 0x2f, 0x21, 0x59, 0x13, 0x16, 0xec, 0x68, 0x12, 0xf4, 0x71, 0x4a, 0xb9,
+	0x30, 0xa5, 0xd1, 0x8c, 0x7d, 0xb5, 0x48, 0xb9, 0x52, 0x53, 0x6c, 0x03, 0x96, 0xba, 0x23, 0x19,
+	0x44, 0x32, 0xa4, 0x83, 0xe6, 0x68, 0x32, 0xcf, 0x42, 0x43, 0x35, 0x89, 0xab, 0x95, 0x4b, 0x73,
+	0x1c, 0xf6, 0x21, 0xac, 0x6a, 0xaa, 0x2d, 0x07, 0x12, 0x65, 0x94, 0x6f, 0x4b, 0x5c, 0x74, 0xff,
+	0x96, 0x3b, 0xf4, 0x02, 0x3a, 0x67, 0x51, 0xb9, 0x3f, 0x65, 0xe0, 0x29, 0x44, 0xec, 0x0d, 0x1d,
+	0xcf, 0x27, 0x57, 0x2f, 0xda, 0x39, 0x0e, 0xb9, 0x7b, 0x1c, 0xc5, 0x72, 0xb8, 0xeb, 0xc4, 0x4e,
+	0xea, 0xee, 0x94, 0xc3, 0xee, 0xc0, 0xca, 0x8e, 0x0c, 0x62, 0x2f, 0x10, 0x41, 0x7c, 0x14, 0xf8,
+	0x13, 0xbe, 0x4c, 0x51, 0x2c, 0x32, 0xd1, 0xda, 0x1d, 0x39, 0x0e, 0xe2, 0x70, 0x42, 0x32, 0x2b,
+	0x24, 0x93, 0x67, 0xa1, 0x9f, 0xb6, 0xba, 0x34, 0xb9, 0xaa, 0xd2, 0x40, 0x51, 0x98, 0xcc, 0xdd,
+	0xbe, 0x0c, 0x05, 0x5f, 0xa3, 0xe0, 0x28, 0x02, 0x3d, 0xde, 0x76, 0x62, 0x2f, 0x1e, 0xbb, 0x82,
+	0xd7, 0x37, 0xaa, 0xad, 0x9a, 0x9d, 0xd2, 0x68, 0x6f, 0x5b, 0x06, 0x03, 0x35, 0xd9, 0xa0, 0xc9,
+	0x8c, 0x51, 0xd0, 0x77, 0x47, 0xba, 0x82, 0x33, 0x32, 0xa9, 0xc8, 0x64, 0x26, 0x2c, 0x6b, 0xe5,
+	0x90, 0x8c, 0xf8, 0x3a, 0x09, 0x15, 0x78, 0x6c, 0x13, 0x6e, 0xec, 0x9d, 0xf5, 0xfd, 0xb1, 0x2b,
+	0xdc, 0x82, 0xec, 0x0d, 0x92, 0x3d, 0x77, 0x0e, 0xad, 0xd9, 0x8a, 0x82, 0xf1, 0x90, 0xdf, 0xdc,
+	0xa8, 0xb6, 0x56, 0x6c, 0x45, 0x60, 0x66, 0xe1, 0x55, 0x11, 0x41, 0xcc, 0x6f, 0xa9, 0xcc, 0xd2,
+	0x24, 0xce, 0xec, 0x05, 0xce, 0x0b, 0x5f, 0xb8, 0xfc, 0x0d, 0x72, 0x4b, 0x42, 0xa2, 0xbf, 0x28,
+	0xfd, 0x46, 0x9c, 0x2b, 0x7f, 0x29, 0x0a, 0xb3, 0x02, 0x47, 0xbb, 0xf2, 0x55, 0x60, 0x0b, 0x27,
+	0x92, 0x01, 0x7f, 0x53, 0x65, 0x45, 0x91, 0xcb, 0x1e, 0x03, 0x74, 0x63, 0x27, 0x16, 0x5d, 0x2f,
+	0xe8, 0x0b, 0xde, 0xdc, 0xa8, 0xb6, 0x96, 0x36, 0x9b, 0x96, 0x7a, 0x85, 0xac, 0xe4, 0x15, 0xb2,
+	0x7a, 0xc9, 0x2b, 0x64, 0xe7, 0xa4, 0xf1, 0x8c, 0x2d, 0xdf, 0x97, 0xaf, 0x6c, 0xe1, 0x7a, 0xa1,
+	0xe8, 0xc7, 0x11, 0x7f, 0x8b, 0x82, 0x53, 0xe2, 0xb2, 0x87, 0x18, 0xa5, 0x28, 0xee, 0x4e, 0x82,
+	0x3e, 0x7f, 0xfb, 0xca, 0x13, 0x52, 0x59, 0xf6, 0x3f, 0xc0, 0x68, 0x3c, 0xee, 0xf7, 0x45, 0x14,
+	0x9d, 0x8c, 0x7d, 0xda, 0xe1, 0x9d, 0x2b, 0x77, 0x38, 0x67, 0x15, 0xfb, 0x16, 0x96, 0x90, 0x7b,
+	0x28, 0x5d, 0x94, 0xe3, 0xb7, 0xaf, 0xdc, 0x24, 0x2f, 0x9e, 0xdc, 0xf9, 0xe8, 0x78, 0xc4, 0xdf,
+	0x55, 0xfe, 0xd7, 0x24, 0x6b, 0xc1, 0x1a, 0x0d, 0x73, 0x8e, 0xde, 0x20, 0x47, 0x97, 0xd9, 0xec,
+	0x33, 0x68, 0x6c, 0x3b, 0x81, 0xfb, 0xca, 0x73, 0xe3, 0xd3, 0x1d, 0x67, 0xe4, 0xf4, 0xbd, 0x78,
+	0xc2, 0xdf, 0x23, 0x87, 0x4d, 0x4f, 0xb0, 0xc7, 0xb0, 0xf4, 0xb4, 0xd7, 0xeb, 0x3c, 0x15, 0x8e,
+	0x2b, 0xc2, 0x88, 0x9b, 0x1b, 0x46, 0x6b, 0x69, 0x93, 0x5b, 0xea, 0x9d, 0xb2, 0x72, 0x53, 0x7b,
+	0x98, 0x55, 0x76, 0x5e, 0x18, 0x6f, 0xc5, 0xbe, 0x0c, 0xfb, 0xc2, 0x3d, 0x1e, 0xf1, 0xf7, 0x49,
+	0xdd, 0x94, 0x46, 0x3f, 0xe8, 0x71, 0x10, 0x7b, 0x3e, 0xbf, 0x73, 0xb5, 0x1f, 0x72, 0xe2, 0x18,
+	0xf1, 0x1d, 0xdf, 0xc3, 0xdb, 0x21, 0xc2, 0x98, 0x1e, 0xde, 0x0f, 0x54, 0x56, 0x15, 0xb9, 0x74,
+	0xbb, 0x88, 0xf3, 0x4c, 0x4c, 0x48, 0xec, 0x43, 0x7d, 0xbb, 0xf2, 0x4c, 0x7c, 0x5d, 0x7b, 0x9e,
+	0x08, 0xf9, 0x47, 0xe4, 0x04, 0x1a, 0xb3, 0xff, 0xc6, 0x7b, 0x29, 0x7d, 0x57, 0xbe, 0x0a, 0x94,
+	0x86, 0xad, 0x2b, 0x35, 0x2c, 0x2e, 0xc0, 0x97, 0xaa, 0x77, 0x1a, 0xca, 0xf1, 0xe0, 0x74, 0x34,
+	0x8e, 0xf9, 0xc7, 0x1b, 0xd5, 0x56, 0xd5, 0xce, 0x71, 0xd8, 0x53, 0x68, 0x64, 0xd4, 0xf1, 0xc8,
+	0x75, 0x62, 0xe1, 0xf2, 0x4f, 0xae, 0x3c, 0x65, 0x7a, 0x11, 0xbe, 0x30, 0xf8, 0x8a, 0x47, 0xa2,
+	0xd7, 0xee, 0xf2, 0x4f, 0xc9, 0xd1, 0x19, 0x83, 0xdd, 0x87, 0x9b, 0xfb, 0xf1, 0xe8, 0x20, 0x88,
+	0x44, 0x7f, 0x1c, 0x8a, 0xee, 0x4f, 0xde, 0xe8, 0x07, 0x11, 0x7a, 0x27, 0x13, 0xfe, 0x19, 0x49,
+	0x9e, 0x3f, 0x89, 0x2f, 0x4e, 0xb7, 0xef, 0x04, 0xdd, 0xfe, 0xa9, 0x70, 0xc7, 0xbe, 0xe0, 0x77,
+	0xd5, 0x8b, 0x93, 0xe7, 0x61, 0x14, 0x0e, 0x9d, 0xb3, 0x1d, 0x19, 0x04, 0xa2, 0x1f, 0x7b, 0x32,
+	0x88, 0xb8, 0xa5, 0xee, 0x5d, 0x91, 0x4b, 0x6f, 0x80, 0x13, 0x9d, 0x1e, 0x7a, 0xd1, 0x10, 0xbf,
+	0x84, 0x22, 0xe2, 0xf7, 0x36, 0x0c, 0x7a, 0x03, 0x0a, 0x5c, 0x7c, 0x43, 0x6c, 0x31, 0x40, 0x3c,
+	0xf0, 0xb9, 0xfa, 0x36, 0x29, 0x8a, 0xb2, 0xde, 0x89, 0x0e, 0x3a, 0x2f, 0xef, 0xf3, 0x2f, 0x74,
+	0xd6, 0x2b, 0x32, 0x9b, 0x79, 0xc8, 0x37, 0xf3, 0x33, 0x0f, 0xd9, 0x27, 0x50, 0x3f, 0x74, 0x82,
+	0xb1, 0xe3, 0x1f, 0x74, 0xf6, 0x9d, 0xa1, 0xe7, 0x7b, 0x22, 0xe2, 0x5f, 0x92, 0xc8, 0x14, 0x9f,
+	0x5e, 0x6f, 0xd9, 0x77, 0x7c, 0xfc, 0x66, 0xdd, 0x57, 0xdf, 0xcb, 0x84, 0x46, 0xdf, 0xa2, 0xcd,
+	0x9d, 0x50, 0x9e, 0x4d, 0xf8, 0x03, 0x9a, 0xcc, 0x18, 0x78, 0x7e, 0xdb, 0x89, 0x45, 0xd0, 0x9f,
+	0xf0, 0x87, 0x14, 0xe0, 0x84, 0x64, 0xdb, 0xb0, 0xaa, 0x87, 0x49, 0x68, 0xbf, 0xba, 0x32, 0xb4,
+	0xa5, 0x15, 0xec, 0x53, 0x98, 0x6b, 0x3b, 0x2f, 0x84, 0x1f, 0xf1, 0x47, 0x74, 0xed, 0xd6, 0x93,
+	0x6b, 0xa7, 0xb8, 0xea, 0xc6, 0x69, 0x91, 0xe6, 0xf7, 0x50, 0x2f, 0xdf, 0x46, 0x56, 0x07, 0xe3,
+	0x27, 0x31, 0xd1, 0x38, 0x03, 0x87, 0xf8, 0xe0, 0xbf, 0x74, 0xfc, 0x71, 0x82, 0x24, 0x14, 0xf1,
+	0xb8, 0xf6, 0xa8, 0xda, 0xfc, 0x1a, 0x96, 0xd4, 0x4e, 0xaf, 0xbd, 0xd4, 0xbc, 0x0f, 0x6b, 0x4a,
+	0xb1, 0xb6, 0x17, 0xc5, 0x0a, 0x0d, 0xbe, 0x07, 0xf3, 0x8a, 0x15, 0xf1, 0x2a, 0xe9, 0x3e, 0xaf,
+	0x75, 0xb7, 0x13, 0xbe, 0x69, 0xc1, 0x82, 0x1a, 0x1e, 0xec, 0x5e, 0x07, 0xef, 0x98, 0x5f, 0x00,
+	0x68, 0x20, 0x85, 0x07, 0xbc, 0x5f, 0x3e, 0x60, 0xd1, 0x4a, 0x76, 0xcb, 0x8e, 0x78, 0x0e, 0x4c,
+	0x9f, 0xaa, 0xc0, 0x97, 0xed, 0xc4, 0x22, 0xba, 0xce, 0x61, 0x68, 0x2c, 0x09, 0x73, 0x63, 0xc3,
+	0x68, 0x55, 0x6d, 0x45, 0x98, 0xbf, 0x85, 0x46, 0x7e, 0x27, 0xa5, 0xc9, 0x1d, 0x58, 0xf9, 0xd1,
+	0x0b, 0x5c, 0xf9, 0xaa, 0x2b, 0xfa, 0x32, 0x70, 0x95, 0x3e, 0x86, 0x5d, 0x64, 0xe2, 0x86, 0x3d,
+	0x19, 0x3b, 0x3e, 0xaf, 0xa9, 0x0d, 0x89, 0x60, 0x77, 0x33, 0x2b, 0x8c, 0x42, 0x88, 0x0b, 0xc7,
+	0xa4, 0xf6, 0x3c, 0x83, 0x9b, 0x5d, 0x11, 0x1f, 0x3a, 0x5e, 0x10, 0x8b, 0xc0, 0x09, 0xfa, 0x22,
+	0x07, 0x2a, 0x93, 0xef, 0x72, 0xb5, 0xf8, 0x5d, 0xe6, 0x30, 0x7f, 0x28, 0xa2, 0xc8, 0x19, 0x24,
+	0xf6, 0x25, 0xa4, 0xf9, 0x02, 0xea, 0x85, 0x9d, 0x34, 0x88, 0x7f, 0xdd, 0x7d, 0xf0, 0xf6, 0x1c,
+	0xbd, 0x14, 0x61, 0xe8, 0xb9, 0x82, 0x80, 0xe8, 0x82, 0x9d, 0xd2, 0xe6, 0x7f, 0xc1, 0xfa, 0xce,
+	0xa9, 0x13, 0x0c, 0x04, 0x7e, 0xad, 0xc7, 0x51, 0xa2, 0x6e, 0x39, 0x02, 0xb9, 0x63, 0x6b, 0x85,
+	0x63, 0xcd, 0x67, 0xf0, 0x0e, 0x5a, 0xac, 0xec, 0xd7, 0xcc, 0xed, 0x49, 0xcf, 0x19, 0x24, 0x5b,
+	0xd5, 0xc1, 0xe8, 0x39, 0x83, 0x24, 0x4f, 0x7b, 0xce, 0xe0, 0x92, 0xcd, 0xbe, 0x84, 0xb7, 0x2e,
+	0xda, 0x6c, 0xa4, 0xa0, 0x1d, 0xc6, 0x5e, 0x05, 0x70, 0xd1, 0x56, 0x84, 0xf9, 0x0c, 0xde, 0xa0,
+	0x2f, 0x8f, 0x5a, 0x86, 0x76, 0x88, 0x8b, 0xcc, 0x58, 0x85, 0xda, 0xf1, 0x48, 0x1f, 0x5a, 0x3b,
+	0x1e, 0x91, 0x6e, 0x3d, 0x85, 0xce, 0x0d, 0x1b, 0x87, 0xe6, 0x7b, 0xc9, 0x4d, 0x39, 0xd8, 0xbd,
+	0x60, 0x13, 0xf3, 0xaf, 0x55, 0x58, 0xdd, 0x72, 0xdd, 0x24, 0x0d, 0x50, 0xb1, 0x3c, 0xba, 0xac,
+	0x5e, 0x86, 0x2e, 0x6b, 0x65, 0x74, 0x49, 0x48, 0x8e, 0xf0, 0x5e, 0x52, 0x23, 0x68, 0x12, 0xd7,
+	0xa5, 0x10, 0x53, 0x17, 0x09, 0x19, 0x03, 0x35, 0xdf, 0xea, 0x3e, 0xd7, 0x25, 0x02, 0x0e, 0x51,
+	0x87, 0x1f, 0x9d, 0x30, 0xf0, 0x82, 0x01, 0x96, 0x5a, 0xe8, 0x9f, 0x94, 0x36, 0x3f, 0x82, 0x86,
+	0x7a, 0xb2, 0xf2, 0x4a, 0x33, 0x98, 0xd9, 0xf5, 0x4e, 0x4e, 0x74, 0x64, 0x68, 0x6c, 0x0e, 0xe0,
+	0xc6, 0x13, 0x21, 0xa7, 0x65, 0xdf, 0x4d, 0x0a, 0x1f, 0x92, 0xce, 0x3d, 0x16, 0x9a, 0x9d, 0x6e,
+	0x56, 0xcb, 0x36, 0x2b, 0x68, 0x64, 0x94, 0x34, 0xda, 0x04, 0x6e, 0x8b, 0x93, 0x50, 0x44, 0xf8,
+	0x5a, 0xc8, 0xc8, 0x8b, 0x65, 0x38, 0x49, 0x1c, 0x4e, 0x5f, 0x99, 0x53, 0x27, 0x3a, 0xd5, 0x29,
+	0xae, 0x29, 0xf3, 0x5f, 0x55, 0x68, 0xe0, 0xcb, 0x5e, 0xb8, 0x80, 0x53, 0x31, 0xc6, 0xfa, 0x64,
+	0x1c, 0x4b, 0x95, 0x3d, 0x3a, 0xd6, 0x39, 0x0e, 0x7b, 0x00, 0x0b, 0x9d, 0x50, 0xc6, 0xb2, 0x2f,
+	0x7d, 0x72, 0xf9, 0xea, 0xe6, 0x9b, 0xd6, 0xd4, 0xae, 0xd6, 0xa1, 0x88, 0x4f, 0xa5, 0x6b, 0xa7,
+	0xa2, 0x68, 0x20, 0x15, 0x1b, 0x2a, 0x12, 0x33, 0x49, 0x09, 0xb2, 0x1b, 0x4e, 0xec, 0x71, 0xc0,
+	0x67, 0x75, 0x25, 0x4a, 0x14, 0x16, 0x2f, 0x94, 0x91, 0xda, 0x8a, 0x39, 0x9a, 0xcc, 0xb3, 0xcc,
+	0xcf, 0x61, 0x4e, 0x9d, 0xc0, 0xe6, 0xc1, 0xd8, 0x6a, 0xb7, 0xeb, 0x15, 0x1c, 0xec, 0xf7, 0x3a,
+	0xf5, 0x2a, 0x5b, 0x84, 0x59, 0xbb, 0xfb, 0xff, 0xcf, 0x77, 0xea, 0x35, 0x1c, 0xb6, 0x8f, 0x76,
+	0xb6, 0xda, 0x75, 0xc3, 0xfc, 0xa7, 0x01, 0x6b, 0x79, 0x35, 0x2f, 0x7f, 0x0c, 0x4c, 0x58, 0x46,
+	0xe0, 0x14, 0x1d, 0x04, 0xae, 0x38, 0xd3, 0xf7, 0xcc, 0xb0, 0x0b, 0x3c, 0x94, 0x79, 0x16, 0xc8,
+	0x57, 0x41, 0x22, 0xa3, 0x6e, 0x41, 0x81, 0x87, 0x27, 0xd8, 0x62, 0x28, 0x5f, 0x0a, 0x97, 0x0c,
+	0x37, 0xec, 0x84, 0x24, 0xf0, 0xf4, 0xab, 0xa3, 0x93, 0x93, 0x48, 0xc4, 0x87, 0x11, 0xd9, 0x6f,
+	0xd8, 0x39, 0x0e, 0x15, 0x2e, 0xae, 0x2b, 0x5c, 0xb2, 0xde, 0xb0, 0x15, 0x41, 0xe9, 0x4e, 0xcf,
+	0x8d, 0x4b, 0xf5, 0xa9, 0x61, 0x27, 0x24, 0x95, 0xb7, 0xce, 0x70, 0xe4, 0x0b, 0xb5, 0x6a, 0x81,
+	0xf2, 0x25, 0xcf, 0xc2, 0x67, 0x5c, 0x91, 0x89, 0x46, 0x8b, 0x24, 0x53, 0x64, 0x66, 0x52, 0xc9,
+	0x39, 0x90, 0x97, 0x4a, 0x4e, 0xe3, 0x30, 0xdf, 0x1d, 0x47, 0x23, 0xd1, 0x8f, 0xa9, 0x42, 0x35,
+	0xec, 0x84, 0x44, 0x98, 0x7e, 0x34, 0x8e, 0x23, 0xcf, 0x15, 0x29, 0xb2, 0x52, 0x05, 0x6a, 0x99,
+	0x7d, 0x0e, 0x68, 0x5a, 0xa1, 0xad, 0x4a, 0x5c, 0xbc, 0x06, 0x87, 0xce, 0x19, 0xb9, 0x9e, 0x4a,
+	0x55, 0xc3, 0x4e, 0x69, 0xbc, 0xe4, 0xbd, 0x70, 0x1c, 0xf4, 0x09, 0x7f, 0xac, 0x29, 0x60, 0x98,
+	0x32, 0xcc, 0xbf, 0x54, 0x55, 0xcc, 0x93, 0xb7, 0x59, 0xc7, 0xdc, 0x1e, 0x07, 0x78, 0x89, 0x92,
+	0x98, 0x6b, 0x12, 0xcf, 0x49, 0x13, 0x5b, 0x5d, 0xc3, 0x94, 0xc6, 0x68, 0x74, 0x4e, 0x9d, 0x48,
+	0xe8, 0x47, 0x46, 0x11, 0xec, 0x3e, 0xcc, 0x77, 0x63, 0x27, 0x8c, 0x75, 0x74, 0x2f, 0xc7, 0x3e,
+	0x89, 0x28, 0xee, 0xa5, 0x8c, 0x51, 0x41, 0x57, 0x84, 0xf9, 0xc7, 0x2a, 0xd4, 0x51, 0xcf, 0x08,
+	0xc9, 0x2b, 0x5b, 0x29, 0xec, 0x11, 0x2c, 0x62, 0x33, 0x87, 0xf6, 0xe4, 0xb5, 0x2b, 0x0f, 0xcf,
+	0x84, 0x51, 0x69, 0x24, 0xf6, 0x02, 0x95, 0xb1, 0x57, 0x28, 0xad, 0x45, 0xcd, 0xdf, 0xc0, 0x6a,
+	0x4e, 0x3b, 0x74, 0xe4, 0xe7, 0x30, 0x7b, 0xe2, 0xf9, 0xfa, 0x63, 0x82, 0xbb, 0x14, 0xe7, 0x2d,
+	0x32, 0x4b, 0x21, 0x38, 0x25, 0xd8, 0x7c, 0x04, 0x90, 0x31, 0xaf, 0xc2, 0x5f, 0x46, 0x1e, 0x7f,
+	0x49, 0x58, 0xeb, 0xc9, 0x11, 0x2d, 0xce, 0x3d, 0x72, 0x1d, 0x11, 0x7a, 0xd2, 0xd5, 0x3b, 0x68,
+	0x8a, 0x59, 0x30, 0x83, 0x3a, 0x5f, 0xc3, 0x27, 0x24, 0x87, 0x87, 0xb6, 0x3d, 0x6c, 0xa1, 0x19,
+	0xaa, 0xdd, 0x41, 0x84, 0xf9, 0x0d, 0xcc, 0xeb, 0x03, 0xf1, 0xe1, 0xea, 0x38, 0xf1, 0x69, 0xf2,
+	0xcc, 0xe3, 0x18, 0xd3, 0x0e, 0xeb, 0x4d, 0x5f, 0x3a, 0x6e, 0xa4, 0xb5, 0xcd, 0x18, 0xe6, 0x3d,
+	0x58, 0xc9, 0xb4, 0x45, 0x57, 0xdd, 0x4e, 0x22, 0xae, 0x5c, 0xb5, 0x60, 0xe9, 0xe9, 0x24, 0xf6,
+	0x7f, 0xa8, 0x02, 0x23, 0xef, 0x5d, 0xfe, 0x32, 0xff, 0xd2, 0x31, 0x17, 0x50, 0x2f, 0x68, 0x75,
+	0xad, 0x0f, 0x19, 0xb6, 0xe6, 0x94, 0xfe, 0x89, 0x67, 0x52, 0x9a, 0xfa, 0xa4, 0x13, 0x85, 0x39,
+	0x29, 0xc0, 0x44, 0x60, 0xe6, 0xb3, 0xbd, 0xb3, 0x91, 0x0c, 0x63, 0x3a, 0xed, 0xaa, 0x00, 0xff,
+	0xd2, 0x5e, 0xf8, 0x5d, 0x15, 0x1a, 0xca, 0x36, 0xa5, 0x00, 0x29, 0x79, 0x2d, 0x88, 0x9d, 0x59,
+	0x60, 0x14, 0x2c, 0xc8, 0xbb, 0x68, 0xe6, 0x22, 0x17, 0xcd, 0xe6, 0x5d, 0xf4, 0x2d, 0xd4, 0x0b,
+	0x1e, 0xc2, 0x48, 0xb4, 0x60, 0x96, 0x28, 0x9d, 0x54, 0xcc, 0x9a, 0x52, 0xd2, 0x56, 0x02, 0xe6,
+	0xff, 0x22, 0xa8, 0x8f, 0x44, 0xd1, 0xbd, 0x65, 0x03, 0x10, 0x10, 0xf9, 0xbe, 0xfe, 0xde, 0xe3,
+	0x90, 0x60, 0xef, 0x48, 0x84, 0x4e, 0x2c, 0x43, 0x6d, 0x40, 0x4a, 0x9b, 0x77, 0x61, 0x2d, 0xbf,
+	0xa5, 0xc6, 0x70, 0x04, 0xbd, 0x04, 0x15, 0x2c, 0x64, 0x55, 0x42, 0x9b, 0xfb, 0x08, 0x8b, 0x34,
+	0x2e, 0x6d, 0xcb, 0x41, 0x74, 0x09, 0xf6, 0x38, 0x74, 0xce, 0x6c, 0x11, 0x8d, 0x7d, 0x9d, 0x3e,
+	0xb3, 0x76, 0x8e, 0x63, 0xb6, 0x80, 0x95, 0xf6, 0xd1, 0x40, 0xcc, 0xf7, 0x02, 0xa1, 0x51, 0x2d,
+	0x8d, 0x51, 0x12, 0xef, 0x96, 0x12, 0x4d, 0xcf, 0x3b, 0xe7, 0x2e, 0x9b, 0x3f, 0x03, 0x64, 0x92,
+	0xd7, 0x8a, 0x2b, 0x83, 0x99, 0xae, 0xf7, 0xb3, 0xd0, 0x59, 0x4c, 0x63, 0xcc, 0xad, 0xa4, 0xe3,
+	0x75, 0x8d, 0x4f, 0x81, 0x16, 0x35, 0xbf, 0x86, 0x7a, 0x41, 0x4b, 0xb4, 0xe6, 0x83, 0x72, 0xdd,
+	0xb7, 0x64, 0x65, 0x32, 0x59, 0xa5, 0xd4, 0x06, 0x86, 0x20, 0xb1, 0x64, 0x60, 0x1d, 0x8c, 0x83,
+	0xdd, 0x2d, 0xad, 0x3f, 0x0e, 0x15, 0x67, 0x5b, 0xfb, 0x12, 0x87, 0xa8, 0xfe, 0xfe, 0xd8, 0xf7,
+	0x75, 0x29, 0x43, 0x63, 0xf3, 0x1f, 0x55, 0xa8, 0x17, 0xb6, 0x1b, 0x29, 0x78, 0x86, 0x6d, 0x51,
+	0xfd, 0x7b, 0x87, 0x61, 0x6b, 0x0a, 0x73, 0x14, 0xe1, 0xdb, 0x56, 0xf2, 0x4e, 0x13, 0x91, 0x70,
+	0xb7, 0x93, 0xcb, 0x4d, 0x44, 0x1e, 0xb0, 0xcc, 0x5c, 0x00, 0x58, 0xd4, 0x5e, 0xb3, 0x79, 0xc0,
+	0xa2, 0x76, 0x2c, 0x48, 0x6c, 0x6b, 0x50, 0x9e, 0x67, 0x4d, 0x83, 0x95, 0xf9, 0x73, 0xc0, 0x8a,
+	0xf9, 0x00, 0xd6, 0xba, 0xde, 0x70, 0xec, 0x97, 0x0a, 0x9b, 0x8e, 0x4e, 0x83, 0xda, 0x41, 0x27,
+	0x4d, 0x8c, 0x5a, 0x2e, 0x31, 0xfe, 0x54, 0xcd, 0xd6, 0xb9, 0xaf, 0x91, 0x1e, 0x75, 0x30, 0xb2,
+	0x9f, 0x2c, 0x0c, 0xfd, 0x73, 0xc5, 0xae, 0x17, 0xc5, 0x58, 0x85, 0x92, 0x17, 0x6a, 0x76, 0x4a,
+	0x67, 0xed, 0xf6, 0xd9, 0x7c, 0xbb, 0xfd, 0x0e, 0xac, 0xe8, 0x76, 0xb6, 0x6e, 0x75, 0xaa, 0x9f,
+	0x2b, 0x8a, 0x4c, 0xf3, 0xef, 0x35, 0x58, 0xc9, 0x2c, 0xd3, 0xe8, 0x26, 0x29, 0x87, 0xaa, 0xc5,
+	0x72, 0x28, 0xfb, 0x41, 0x80, 0x9a, 0xf0, 0x4a, 0xe1, 0x3c, 0xab, 0x58, 0x30, 0x19, 0xe5, 0x82,
+	0x29, 0x5f, 0xa2, 0xcd, 0x5c, 0x56, 0xa2, 0xcd, 0x96, 0x4b, 0x34, 0x5d, 0x6a, 0xcd, 0x65, 0xa5,
+	0x16, 0x36, 0x95, 0xa4, 0xc2, 0x6c, 0xf3, 0x0a, 0x83, 0x69, 0x92, 0x1a, 0xaa, 0x8e, 0xef, 0xbf,
+	0x70, 0xfa, 0x3f, 0xd1, 0x8f, 0x2b, 0x0b, 0x76, 0x4a, 0xb3, 0x4f, 0xb2, 0x8b, 0xb1, 0x48, 0x17,
+	0xa3, 0x6e, 0x95, 0xc2, 0x93, 0xde, 0x0e, 0xf6, 0x19, 0x2c, 0x24, 0x3f, 0x07, 0x70, 0xb8, 0x40,
+	0x38, 0x95, 0xd8, 0xfc, 0xfd, 0x0a, 0x18, 0x3b, 0xed, 0x03, 0xf6, 0x00, 0xe0, 0x89, 0x88, 0x93,
+	0x5f, 0xe8, 0x6e, 0x4d, 0xdd, 0xe0, 0x3d, 0xfc, 0xfd, 0xb0, 0xb9, 0x62, 0xe5, 0x7f, 0x16, 0x34,
+	0x2b, 0xec, 0x1b, 0x98, 0x3f, 0x1e, 0x0d, 0x42, 0xc7, 0x15, 0x17, 0xae, 0xb9, 0x80, 0x6f, 0x56,
+	0xd8, 0x63, 0x2c, 0xd6, 0x10, 0x37, 0xfc, 0x07, 0x6b, 0xb7, 0x61, 0xb5, 0xd8, 0x2d, 0x61, 0xb7,
+	0xac, 0x73, 0xdb, 0x27, 0x97, 0xec, 0xf1, 0x1d, 0xac, 0x3e, 0x29, 0xef, 0x71, 0xbe, 0x1e, 0x0d,
+	0xab, 0xdc, 0x4d, 0x31, 0x2b, 0xec, 0x7b, 0x58, 0xce, 0xf7, 0x3f, 0xd8, 0x0d, 0xeb, 0x9c, 0x76,
+	0xc8, 0x25, 0xc7, 0xff, 0x1f, 0xdc, 0x3a, 0xbf, 0x63, 0xc1, 0x6e, 0x5b, 0x97, 0xf6, 0x45, 0x9a,
+	0x6f, 0x5b, 0x97, 0xb4, 0x3a, 0xcc, 0x0a, 0xdb, 0x87, 0x7a, 0xb9, 0xad, 0xc1, 0xb8, 0x75, 0x41,
+	0xa7, 0xe3, 0x12, 0x0d, 0x37, 0x61, 0x06, 0xbb, 0x7e, 0x17, 0xba, 0xa5, 0x6e, 0x95, 0x5a, 0x83,
+	0x66, 0x85, 0x7d, 0x0c, 0xa0, 0x98, 0x07, 0xc1, 0x89, 0x64, 0x75, 0xab, 0xd4, 0x12, 0x69, 0x26,
+	0xa8, 0xc9, 0xac, 0xb0, 0x8f, 0xf0, 0xc7, 0xc2, 0xe4, 0x79, 0x49, 0xf8, 0xcd, 0x35, 0xab, 0xd8,
+	0x21, 0x31, 0x2b, 0xec, 0x2e, 0x2c, 0xe7, 0xfb, 0x0a, 0x99, 0x2c, 0xb3, 0xa6, 0xfa, 0x0d, 0x94,
+	0x57, 0xcb, 0xaa, 0xa4, 0xd3, 0xe2, 0xd3, 0x4a, 0x5c, 0x6c, 0xf2, 0xb7, 0xb0, 0x56, 0xea, 0x62,
+	0x9c, 0xb3, 0xfc, 0xa6, 0x75, 0x5e, 0xa7, 0xc3, 0xac, 0x60, 0xdb, 0x7f, 0xaa, 0x35, 0xc1, 0xde,
+	0xb4, 0x2e, 0x6a, 0x57, 0x5c, 0xa2, 0xc7, 0x7d, 0x80, 0xac, 0x64, 0x67, 0x6c, 0xba, 0xcd, 0xd0,
+	0xac, 0x5b, 0xa5, 0x9a, 0x9e, 0x02, 0x06, 0x59, 0xd1, 0x77, 0x8e, 0xe2, 0x75, 0x2b, 0x9b, 0x4e,
+	0xd6, 0x7c, 0x01, 0x8b, 0x69, 0xf9, 0xc2, 0x1a, 0x56, 0xb9, 0x10, 0x6b, 0xae, 0x95, 0xaa, 0x1b,
+	0xb3, 0xc2, 0x2c, 0x58, 0x48, 0x50, 0x3e, 0xab, 0x5b, 0xa5, 0xf2, 0xa4, 0xb9, 0x6a, 0x15, 0x4a,
+	0x00, 0xb3, 0xc2, 0xbe, 0x82, 0xa5, 0x1c, 0x9a, 0x66, 0xeb, 0xd6, 0x34, 0xe2, 0x6f, 0x36, 0xac,
+	0x32, 0xe0, 0x56, 0x0b, 0x73, 0xe0, 0x8f, 0xad, 0x5b, 0xd3, 0x60, 0xb9, 0xd9, 0xb0, 0xca, 0xf8,
+	0x90, 0xc2, 0xb8, 0x5c, 0x68, 0x0b, 0x5f, 0x94, 0xc1, 0xcc, 0x9a, 0xea, 0xf9, 0x2a, 0xe7, 0x67,
+	0x10, 0x8f, 0x31, 0x2b, 0x23, 0x32, 0x47, 0x96, 0x30, 0xa0, 0x59, 0x61, 0x8f, 0x60, 0xa6, 0x83,
+	0xc5, 0xf4, 0xeb, 0x3f, 0x66, 0xdf, 0xc1, 0x4a, 0x01, 0xdb, 0xb1, 0x9b, 0x56, 0x81, 0x4e, 0x4e,
+	0x5d, 0xb7, 0xa6, 0x21, 0xa0, 0xf2, 0x52, 0x0e, 0x4a, 0xb1, 0x75, 0x6b, 0x1a, 0xfe, 0x35, 0x1b,
+	0x56, 0x19, 0x6d, 0xa9, 0x85, 0x39, 0xe4, 0xc3, 0xd6, 0xad, 0x69, 0x58, 0xd5, 0x6c, 0x58, 0x65,
+	0x70, 0xa4, 0x12, 0x20, 0xf9, 0xa4, 0xb0, 0xec, 0xeb, 0x92, 0x25, 0x40, 0xe1, 0xcb, 0x6c, 0x56,
+	0xd8, 0xa7, 0xb0, 0x44, 0xed, 0x7d, 0x9d, 0x00, 0x2b, 0x56, 0xfe, 0x5f, 0x13, 0xcd, 0x25, 0x2b,
+	0xeb, 0xfd, 0x9b, 0x95, 0x17, 0x73, 0xe4, 0x9f, 0x2f, 0xff, 0x3d, 0x00, 0x4e, 0x2e, 0xc8, 0x5b,
+	0xcf, 0x22, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -1438,7 +3246,11 @@ type CLIClient interface {
 	GetVersion(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*VersionReply, error)
 	Upgrade(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*empty.Empty, error)
 	Reload(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*empty.Empty, error)
+	SetMaintenance(ctx context.Context, in *SetMaintenanceRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	GetMaintenance(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*MaintenanceReply, error)
 	ChangeStatus(ctx context.Context, in *ChangeStatusRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	SetMirrorsEnabledByTag(ctx context.Context, in *SetMirrorsEnabledByTagRequest, opts ...grpc.CallOption) (*SetMirrorsEnabledByTagReply, error)
+	ForceMirrorState(ctx context.Context, in *ForceMirrorStateRequest, opts ...grpc.CallOption) (*empty.Empty, error)
 	List(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*MirrorListReply, error)
 	MirrorInfo(ctx context.Context, in *MirrorIDRequest, opts ...grpc.CallOption) (*Mirror, error)
 	AddMirror(ctx context.Context, in *Mirror, opts ...grpc.CallOption) (*AddMirrorReply, error)
@@ -1447,10 +3259,18 @@ type CLIClient interface {
 	GeoUpdateMirror(ctx context.Context, in *MirrorIDRequest, opts ...grpc.CallOption) (*GeoUpdateMirrorReply, error)
 	RefreshRepository(ctx context.Context, in *RefreshRepositoryRequest, opts ...grpc.CallOption) (*empty.Empty, error)
 	ScanMirror(ctx context.Context, in *ScanMirrorRequest, opts ...grpc.CallOption) (*ScanMirrorReply, error)
+	ScanStatus(ctx context.Context, in *MirrorIDRequest, opts ...grpc.CallOption) (*ScanStatusReply, error)
 	StatsFile(ctx context.Context, in *StatsFileRequest, opts ...grpc.CallOption) (*StatsFileReply, error)
+	TopFiles(ctx context.Context, in *TopFilesRequest, opts ...grpc.CallOption) (*TopFilesReply, error)
 	StatsMirror(ctx context.Context, in *StatsMirrorRequest, opts ...grpc.CallOption) (*StatsMirrorReply, error)
+	ExportStats(ctx context.Context, in *ExportStatsRequest, opts ...grpc.CallOption) (*ExportStatsReply, error)
+	RequestRates(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*RequestRatesReply, error)
+	ResetStats(ctx context.Context, in *ResetStatsRequest, opts ...grpc.CallOption) (*ResetStatsReply, error)
 	Ping(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*empty.Empty, error)
 	GetMirrorLogs(ctx context.Context, in *GetMirrorLogsRequest, opts ...grpc.CallOption) (*GetMirrorLogsReply, error)
+	FileMirrors(ctx context.Context, in *FileMirrorsRequest, opts ...grpc.CallOption) (*FileMirrorsReply, error)
+	DiffMirrors(ctx context.Context, in *DiffMirrorsRequest, opts ...grpc.CallOption) (*DiffMirrorsReply, error)
+	Simulate(ctx context.Context, in *SimulateRequest, opts ...grpc.CallOption) (*SimulateReply, error)
 	// Tools
 	MatchMirror(ctx context.Context, in *MatchRequest, opts ...grpc.CallOption) (*MatchReply, error)
 }
@@ -1463,36 +3283,72 @@ func NewCLIClient(cc grpc.ClientConnInterface) CLIClient {
 	return &cLIClient{cc}
 }
 
-func (c *cLIClient) GetVersion(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*VersionReply, error) {
-	out := new(VersionReply)
-	err := c.cc.Invoke(ctx, "/CLI/GetVersion", in, out, opts...)
+func (c *cLIClient) GetVersion(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*VersionReply, error) {
+	out := new(VersionReply)
+	err := c.cc.Invoke(ctx, "/CLI/GetVersion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cLIClient) Upgrade(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/CLI/Upgrade", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cLIClient) Reload(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/CLI/Reload", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cLIClient) SetMaintenance(ctx context.Context, in *SetMaintenanceRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/CLI/SetMaintenance", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cLIClient) GetMaintenance(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*MaintenanceReply, error) {
+	out := new(MaintenanceReply)
+	err := c.cc.Invoke(ctx, "/CLI/GetMaintenance", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *cLIClient) Upgrade(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*empty.Empty, error) {
+func (c *cLIClient) ChangeStatus(ctx context.Context, in *ChangeStatusRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
 	out := new(empty.Empty)
-	err := c.cc.Invoke(ctx, "/CLI/Upgrade", in, out, opts...)
+	err := c.cc.Invoke(ctx, "/CLI/ChangeStatus", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *cLIClient) Reload(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*empty.Empty, error) {
-	out := new(empty.Empty)
-	err := c.cc.Invoke(ctx, "/CLI/Reload", in, out, opts...)
+func (c *cLIClient) SetMirrorsEnabledByTag(ctx context.Context, in *SetMirrorsEnabledByTagRequest, opts ...grpc.CallOption) (*SetMirrorsEnabledByTagReply, error) {
+	out := new(SetMirrorsEnabledByTagReply)
+	err := c.cc.Invoke(ctx, "/CLI/SetMirrorsEnabledByTag", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *cLIClient) ChangeStatus(ctx context.Context, in *ChangeStatusRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+func (c *cLIClient) ForceMirrorState(ctx context.Context, in *ForceMirrorStateRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
 	out := new(empty.Empty)
-	err := c.cc.Invoke(ctx, "/CLI/ChangeStatus", in, out, opts...)
+	err := c.cc.Invoke(ctx, "/CLI/ForceMirrorState", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -1571,6 +3427,15 @@ func (c *cLIClient) ScanMirror(ctx context.Context, in *ScanMirrorRequest, opts
 	return out, nil
 }
 
+func (c *cLIClient) ScanStatus(ctx context.Context, in *MirrorIDRequest, opts ...grpc.CallOption) (*ScanStatusReply, error) {
+	out := new(ScanStatusReply)
+	err := c.cc.Invoke(ctx, "/CLI/ScanStatus", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *cLIClient) StatsFile(ctx context.Context, in *StatsFileRequest, opts ...grpc.CallOption) (*StatsFileReply, error) {
 	out := new(StatsFileReply)
 	err := c.cc.Invoke(ctx, "/CLI/StatsFile", in, out, opts...)
@@ -1580,6 +3445,15 @@ func (c *cLIClient) StatsFile(ctx context.Context, in *StatsFileRequest, opts ..
 	return out, nil
 }
 
+func (c *cLIClient) TopFiles(ctx context.Context, in *TopFilesRequest, opts ...grpc.CallOption) (*TopFilesReply, error) {
+	out := new(TopFilesReply)
+	err := c.cc.Invoke(ctx, "/CLI/TopFiles", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *cLIClient) StatsMirror(ctx context.Context, in *StatsMirrorRequest, opts ...grpc.CallOption) (*StatsMirrorReply, error) {
 	out := new(StatsMirrorReply)
 	err := c.cc.Invoke(ctx, "/CLI/StatsMirror", in, out, opts...)
@@ -1589,6 +3463,33 @@ func (c *cLIClient) StatsMirror(ctx context.Context, in *StatsMirrorRequest, opt
 	return out, nil
 }
 
+func (c *cLIClient) ExportStats(ctx context.Context, in *ExportStatsRequest, opts ...grpc.CallOption) (*ExportStatsReply, error) {
+	out := new(ExportStatsReply)
+	err := c.cc.Invoke(ctx, "/CLI/ExportStats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cLIClient) RequestRates(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*RequestRatesReply, error) {
+	out := new(RequestRatesReply)
+	err := c.cc.Invoke(ctx, "/CLI/RequestRates", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cLIClient) ResetStats(ctx context.Context, in *ResetStatsRequest, opts ...grpc.CallOption) (*ResetStatsReply, error) {
+	out := new(ResetStatsReply)
+	err := c.cc.Invoke(ctx, "/CLI/ResetStats", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *cLIClient) Ping(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*empty.Empty, error) {
 	out := new(empty.Empty)
 	err := c.cc.Invoke(ctx, "/CLI/Ping", in, out, opts...)
@@ -1607,6 +3508,33 @@ func (c *cLIClient) GetMirrorLogs(ctx context.Context, in *GetMirrorLogsRequest,
 	return out, nil
 }
 
+func (c *cLIClient) FileMirrors(ctx context.Context, in *FileMirrorsRequest, opts ...grpc.CallOption) (*FileMirrorsReply, error) {
+	out := new(FileMirrorsReply)
+	err := c.cc.Invoke(ctx, "/CLI/FileMirrors", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cLIClient) DiffMirrors(ctx context.Context, in *DiffMirrorsRequest, opts ...grpc.CallOption) (*DiffMirrorsReply, error) {
+	out := new(DiffMirrorsReply)
+	err := c.cc.Invoke(ctx, "/CLI/DiffMirrors", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cLIClient) Simulate(ctx context.Context, in *SimulateRequest, opts ...grpc.CallOption) (*SimulateReply, error) {
+	out := new(SimulateReply)
+	err := c.cc.Invoke(ctx, "/CLI/Simulate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *cLIClient) MatchMirror(ctx context.Context, in *MatchRequest, opts ...grpc.CallOption) (*MatchReply, error) {
 	out := new(MatchReply)
 	err := c.cc.Invoke(ctx, "/CLI/MatchMirror", in, out, opts...)
@@ -1621,7 +3549,11 @@ type CLIServer interface {
 	GetVersion(context.Context, *empty.Empty) (*VersionReply, error)
 	Upgrade(context.Context, *empty.Empty) (*empty.Empty, error)
 	Reload(context.Context, *empty.Empty) (*empty.Empty, error)
+	SetMaintenance(context.Context, *SetMaintenanceRequest) (*empty.Empty, error)
+	GetMaintenance(context.Context, *empty.Empty) (*MaintenanceReply, error)
 	ChangeStatus(context.Context, *ChangeStatusRequest) (*empty.Empty, error)
+	SetMirrorsEnabledByTag(context.Context, *SetMirrorsEnabledByTagRequest) (*SetMirrorsEnabledByTagReply, error)
+	ForceMirrorState(context.Context, *ForceMirrorStateRequest) (*empty.Empty, error)
 	List(context.Context, *empty.Empty) (*MirrorListReply, error)
 	MirrorInfo(context.Context, *MirrorIDRequest) (*Mirror, error)
 	AddMirror(context.Context, *Mirror) (*AddMirrorReply, error)
@@ -1630,10 +3562,18 @@ type CLIServer interface {
 	GeoUpdateMirror(context.Context, *MirrorIDRequest) (*GeoUpdateMirrorReply, error)
 	RefreshRepository(context.Context, *RefreshRepositoryRequest) (*empty.Empty, error)
 	ScanMirror(context.Context, *ScanMirrorRequest) (*ScanMirrorReply, error)
+	ScanStatus(context.Context, *MirrorIDRequest) (*ScanStatusReply, error)
 	StatsFile(context.Context, *StatsFileRequest) (*StatsFileReply, error)
+	TopFiles(context.Context, *TopFilesRequest) (*TopFilesReply, error)
 	StatsMirror(context.Context, *StatsMirrorRequest) (*StatsMirrorReply, error)
+	ExportStats(context.Context, *ExportStatsRequest) (*ExportStatsReply, error)
+	RequestRates(context.Context, *empty.Empty) (*RequestRatesReply, error)
+	ResetStats(context.Context, *ResetStatsRequest) (*ResetStatsReply, error)
 	Ping(context.Context, *empty.Empty) (*empty.Empty, error)
 	GetMirrorLogs(context.Context, *GetMirrorLogsRequest) (*GetMirrorLogsReply, error)
+	FileMirrors(context.Context, *FileMirrorsRequest) (*FileMirrorsReply, error)
+	DiffMirrors(context.Context, *DiffMirrorsRequest) (*DiffMirrorsReply, error)
+	Simulate(context.Context, *SimulateRequest) (*SimulateReply, error)
 	// Tools
 	MatchMirror(context.Context, *MatchRequest) (*MatchReply, error)
 }
@@ -1651,9 +3591,21 @@ func (*UnimplementedCLIServer) Upgrade(ctx context.Context, req *empty.Empty) (*
 func (*UnimplementedCLIServer) Reload(ctx context.Context, req *empty.Empty) (*empty.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Reload not implemented")
 }
+func (*UnimplementedCLIServer) SetMaintenance(ctx context.Context, req *SetMaintenanceRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetMaintenance not implemented")
+}
+func (*UnimplementedCLIServer) GetMaintenance(ctx context.Context, req *empty.Empty) (*MaintenanceReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMaintenance not implemented")
+}
 func (*UnimplementedCLIServer) ChangeStatus(ctx context.Context, req *ChangeStatusRequest) (*empty.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ChangeStatus not implemented")
 }
+func (*UnimplementedCLIServer) SetMirrorsEnabledByTag(ctx context.Context, req *SetMirrorsEnabledByTagRequest) (*SetMirrorsEnabledByTagReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetMirrorsEnabledByTag not implemented")
+}
+func (*UnimplementedCLIServer) ForceMirrorState(ctx context.Context, req *ForceMirrorStateRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ForceMirrorState not implemented")
+}
 func (*UnimplementedCLIServer) List(ctx context.Context, req *empty.Empty) (*MirrorListReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
 }
@@ -1678,18 +3630,42 @@ func (*UnimplementedCLIServer) RefreshRepository(ctx context.Context, req *Refre
 func (*UnimplementedCLIServer) ScanMirror(ctx context.Context, req *ScanMirrorRequest) (*ScanMirrorReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ScanMirror not implemented")
 }
+func (*UnimplementedCLIServer) ScanStatus(ctx context.Context, req *MirrorIDRequest) (*ScanStatusReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ScanStatus not implemented")
+}
 func (*UnimplementedCLIServer) StatsFile(ctx context.Context, req *StatsFileRequest) (*StatsFileReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method StatsFile not implemented")
 }
+func (*UnimplementedCLIServer) TopFiles(ctx context.Context, req *TopFilesRequest) (*TopFilesReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TopFiles not implemented")
+}
 func (*UnimplementedCLIServer) StatsMirror(ctx context.Context, req *StatsMirrorRequest) (*StatsMirrorReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method StatsMirror not implemented")
 }
+func (*UnimplementedCLIServer) ExportStats(ctx context.Context, req *ExportStatsRequest) (*ExportStatsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExportStats not implemented")
+}
+func (*UnimplementedCLIServer) RequestRates(ctx context.Context, req *empty.Empty) (*RequestRatesReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestRates not implemented")
+}
+func (*UnimplementedCLIServer) ResetStats(ctx context.Context, req *ResetStatsRequest) (*ResetStatsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResetStats not implemented")
+}
 func (*UnimplementedCLIServer) Ping(ctx context.Context, req *empty.Empty) (*empty.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Ping not implemented")
 }
 func (*UnimplementedCLIServer) GetMirrorLogs(ctx context.Context, req *GetMirrorLogsRequest) (*GetMirrorLogsReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetMirrorLogs not implemented")
 }
+func (*UnimplementedCLIServer) FileMirrors(ctx context.Context, req *FileMirrorsRequest) (*FileMirrorsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FileMirrors not implemented")
+}
+func (*UnimplementedCLIServer) DiffMirrors(ctx context.Context, req *DiffMirrorsRequest) (*DiffMirrorsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DiffMirrors not implemented")
+}
+func (*UnimplementedCLIServer) Simulate(ctx context.Context, req *SimulateRequest) (*SimulateReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Simulate not implemented")
+}
 func (*UnimplementedCLIServer) MatchMirror(ctx context.Context, req *MatchRequest) (*MatchReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method MatchMirror not implemented")
 }
@@ -1752,6 +3728,42 @@ func _CLI_Reload_Handler(srv interface{}, ctx context.Context, dec func(interfac
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CLI_SetMaintenance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetMaintenanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CLIServer).SetMaintenance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/CLI/SetMaintenance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CLIServer).SetMaintenance(ctx, req.(*SetMaintenanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CLI_GetMaintenance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CLIServer).GetMaintenance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/CLI/GetMaintenance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CLIServer).GetMaintenance(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _CLI_ChangeStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ChangeStatusRequest)
 	if err := dec(in); err != nil {
@@ -1770,6 +3782,42 @@ func _CLI_ChangeStatus_Handler(srv interface{}, ctx context.Context, dec func(in
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CLI_SetMirrorsEnabledByTag_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetMirrorsEnabledByTagRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CLIServer).SetMirrorsEnabledByTag(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/CLI/SetMirrorsEnabledByTag",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CLIServer).SetMirrorsEnabledByTag(ctx, req.(*SetMirrorsEnabledByTagRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CLI_ForceMirrorState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ForceMirrorStateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CLIServer).ForceMirrorState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/CLI/ForceMirrorState",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CLIServer).ForceMirrorState(ctx, req.(*ForceMirrorStateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _CLI_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(empty.Empty)
 	if err := dec(in); err != nil {
@@ -1914,6 +3962,24 @@ func _CLI_ScanMirror_Handler(srv interface{}, ctx context.Context, dec func(inte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CLI_ScanStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MirrorIDRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CLIServer).ScanStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/CLI/ScanStatus",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CLIServer).ScanStatus(ctx, req.(*MirrorIDRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _CLI_StatsFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(StatsFileRequest)
 	if err := dec(in); err != nil {
@@ -1932,6 +3998,24 @@ func _CLI_StatsFile_Handler(srv interface{}, ctx context.Context, dec func(inter
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CLI_TopFiles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TopFilesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CLIServer).TopFiles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/CLI/TopFiles",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CLIServer).TopFiles(ctx, req.(*TopFilesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _CLI_StatsMirror_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(StatsMirrorRequest)
 	if err := dec(in); err != nil {
@@ -1950,6 +4034,60 @@ func _CLI_StatsMirror_Handler(srv interface{}, ctx context.Context, dec func(int
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CLI_ExportStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExportStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CLIServer).ExportStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/CLI/ExportStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CLIServer).ExportStats(ctx, req.(*ExportStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CLI_RequestRates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CLIServer).RequestRates(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/CLI/RequestRates",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CLIServer).RequestRates(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CLI_ResetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResetStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CLIServer).ResetStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/CLI/ResetStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CLIServer).ResetStats(ctx, req.(*ResetStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _CLI_Ping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(empty.Empty)
 	if err := dec(in); err != nil {
@@ -1986,6 +4124,60 @@ func _CLI_GetMirrorLogs_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CLI_FileMirrors_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FileMirrorsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CLIServer).FileMirrors(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/CLI/FileMirrors",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CLIServer).FileMirrors(ctx, req.(*FileMirrorsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CLI_DiffMirrors_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DiffMirrorsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CLIServer).DiffMirrors(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/CLI/DiffMirrors",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CLIServer).DiffMirrors(ctx, req.(*DiffMirrorsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CLI_Simulate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SimulateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CLIServer).Simulate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/CLI/Simulate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CLIServer).Simulate(ctx, req.(*SimulateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _CLI_MatchMirror_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(MatchRequest)
 	if err := dec(in); err != nil {
@@ -2020,10 +4212,26 @@ var _CLI_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Reload",
 			Handler:    _CLI_Reload_Handler,
 		},
+		{
+			MethodName: "SetMaintenance",
+			Handler:    _CLI_SetMaintenance_Handler,
+		},
+		{
+			MethodName: "GetMaintenance",
+			Handler:    _CLI_GetMaintenance_Handler,
+		},
 		{
 			MethodName: "ChangeStatus",
 			Handler:    _CLI_ChangeStatus_Handler,
 		},
+		{
+			MethodName: "SetMirrorsEnabledByTag",
+			Handler:    _CLI_SetMirrorsEnabledByTag_Handler,
+		},
+		{
+			MethodName: "ForceMirrorState",
+			Handler:    _CLI_ForceMirrorState_Handler,
+		},
 		{
 			MethodName: "List",
 			Handler:    _CLI_List_Handler,
@@ -2056,14 +4264,34 @@ var _CLI_serviceDesc = grpc.ServiceDesc{
 			MethodName: "ScanMirror",
 			Handler:    _CLI_ScanMirror_Handler,
 		},
+		{
+			MethodName: "ScanStatus",
+			Handler:    _CLI_ScanStatus_Handler,
+		},
 		{
 			MethodName: "StatsFile",
 			Handler:    _CLI_StatsFile_Handler,
 		},
+		{
+			MethodName: "TopFiles",
+			Handler:    _CLI_TopFiles_Handler,
+		},
 		{
 			MethodName: "StatsMirror",
 			Handler:    _CLI_StatsMirror_Handler,
 		},
+		{
+			MethodName: "ExportStats",
+			Handler:    _CLI_ExportStats_Handler,
+		},
+		{
+			MethodName: "RequestRates",
+			Handler:    _CLI_RequestRates_Handler,
+		},
+		{
+			MethodName: "ResetStats",
+			Handler:    _CLI_ResetStats_Handler,
+		},
 		{
 			MethodName: "Ping",
 			Handler:    _CLI_Ping_Handler,
@@ -2072,6 +4300,18 @@ var _CLI_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetMirrorLogs",
 			Handler:    _CLI_GetMirrorLogs_Handler,
 		},
+		{
+			MethodName: "FileMirrors",
+			Handler:    _CLI_FileMirrors_Handler,
+		},
+		{
+			MethodName: "DiffMirrors",
+			Handler:    _CLI_DiffMirrors_Handler,
+		},
+		{
+			MethodName: "Simulate",
+			Handler:    _CLI_Simulate_Handler,
+		},
 		{
 			MethodName: "MatchMirror",
 			Handler:    _CLI_MatchMirror_Handler,
@@ -0,0 +1,110 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package rpc
+
+import (
+	"container/heap"
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/rafaeljusto/redigomock"
+)
+
+func TestTopFiles(t *testing.T) {
+	mock := redigomock.NewConn()
+	mock.Command("HSCAN", "STATS_FILE_2025", 0, "COUNT", 1000).Expect([]any{
+		[]byte("7"),
+		[]any{[]byte("/a"), []byte("5"), []byte("/b"), []byte("12"), []byte("/c"), []byte("1")},
+	})
+	mock.Command("HSCAN", "STATS_FILE_2025", 7, "COUNT", 1000).Expect([]any{
+		[]byte("0"),
+		[]any{[]byte("/d"), []byte("5"), []byte("/e"), []byte("30")},
+	})
+
+	files, err := topFiles(mock, "STATS_FILE_2025", 3)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// Ties are broken by path
+	expected := []TopFile{{Path: "/e", Downloads: 30}, {Path: "/b", Downloads: 12}, {Path: "/a", Downloads: 5}}
+	if len(files) != len(expected) {
+		t.Fatalf("Expected %d files, got %d", len(expected), len(files))
+	}
+	for i, f := range files {
+		if f.Path != expected[i].Path || f.Downloads != expected[i].Downloads {
+			t.Fatalf("Expected %s (%d) at %d, got %s (%d)", expected[i].Path, expected[i].Downloads, i, f.Path, f.Downloads)
+		}
+	}
+}
+
+func TestTopFilesEmpty(t *testing.T) {
+	mock := redigomock.NewConn()
+	mock.Command("HSCAN", "STATS_FILE", 0, "COUNT", 1000).Expect([]any{[]byte("0"), []any{}})
+
+	files, err := topFiles(mock, "STATS_FILE", 10)
+	if err != nil || len(files) != 0 {
+		t.Fatalf("Expected no files, got %v (%v)", files, err)
+	}
+}
+
+func TestTopFilesHeap(t *testing.T) {
+	h := &topFilesHeap{}
+	for _, f := range []*TopFile{
+		{Path: "/b", Downloads: 2},
+		{Path: "/a", Downloads: 2},
+		{Path: "/c", Downloads: 1},
+		{Path: "/d", Downloads: 3},
+	} {
+		heap.Push(h, f)
+	}
+
+	// The least downloaded files come first, the last paths first on ties
+	expected := []string{"/c", "/b", "/a", "/d"}
+	for _, path := range expected {
+		if f := heap.Pop(h).(*TopFile); f.Path != path {
+			t.Fatalf("Expected %s, got %s", path, f.Path)
+		}
+	}
+	if h.Len() != 0 {
+		t.Fatalf("Expected an empty heap, got %d files", h.Len())
+	}
+}
+
+type staticRater struct {
+	perMirror map[int][]float64
+}
+
+func (r staticRater) RequestRates() ([]time.Duration, []float64, map[int][]float64) {
+	return []time.Duration{time.Minute}, []float64{3}, r.perMirror
+}
+
+func TestRequestRates(t *testing.T) {
+	c := &CLI{}
+	if _, err := c.RequestRates(context.Background(), &empty.Empty{}); err == nil {
+		t.Fatalf("Expected an error without rater")
+	}
+
+	// Without the names of the database, the mirrors are returned by ID
+	c.SetRequestRater(staticRater{map[int][]float64{12: {1}, 2: {1.5}, 3: {0.5}}})
+	reply, err := c.RequestRates(context.Background(), &empty.Empty{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := []string{"12", "2", "3"}
+	if len(reply.Mirrors) != len(expected) {
+		t.Fatalf("Expected %d mirrors, got %d", len(expected), len(reply.Mirrors))
+	}
+	for i, m := range reply.Mirrors {
+		if m.Name != expected[i] || m.Name != strconv.Itoa(int(m.ID)) {
+			t.Fatalf("Expected %s at %d, got %s (%d)", expected[i], i, m.Name, m.ID)
+		}
+	}
+	if len(reply.Total) != 1 || len(reply.WindowSeconds) != 1 || reply.WindowSeconds[0] != 60 {
+		t.Fatalf("Unexpected windows %v %v", reply.WindowSeconds, reply.Total)
+	}
+}
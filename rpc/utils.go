@@ -25,39 +25,78 @@ func MirrorToRPC(m *mirrors.Mirror) (*Mirror, error) {
 	if err != nil {
 		return nil, err
 	}
+	forcedUntil, err := ptypes.TimestampProto(m.ForcedUntil.Time)
+	if err != nil {
+		return nil, err
+	}
+	cooldownUntil, err := ptypes.TimestampProto(m.CooldownUntil.Time)
+	if err != nil {
+		return nil, err
+	}
+	throughputUpdated, err := ptypes.TimestampProto(m.ThroughputUpdated.Time)
+	if err != nil {
+		return nil, err
+	}
+	latencyUpdated, err := ptypes.TimestampProto(m.LatencyUpdated.Time)
+	if err != nil {
+		return nil, err
+	}
 	return &Mirror{
-		ID:                   int32(m.ID),
-		Name:                 m.Name,
-		HttpURL:              m.HttpURL,
-		RsyncURL:             m.RsyncURL,
-		FtpURL:               m.FtpURL,
-		SponsorName:          m.SponsorName,
-		SponsorURL:           m.SponsorURL,
-		SponsorLogoURL:       m.SponsorLogoURL,
-		AdminName:            m.AdminName,
-		AdminEmail:           m.AdminEmail,
-		CustomData:           m.CustomData,
-		ContinentOnly:        m.ContinentOnly,
-		CountryOnly:          m.CountryOnly,
-		ASOnly:               m.ASOnly,
-		Score:                int32(m.Score),
-		Latitude:             m.Latitude,
-		Longitude:            m.Longitude,
-		ContinentCode:        m.ContinentCode,
-		CountryCodes:         m.CountryCodes,
-		ExcludedCountryCodes: m.ExcludedCountryCodes,
-		Asnum:                uint32(m.Asnum),
-		Comment:              m.Comment,
-		Enabled:              m.Enabled,
-		HttpUp:               m.HttpUp,
-		HttpsUp:              m.HttpsUp,
-		HttpDownReason:       m.HttpDownReason,
-		HttpsDownReason:      m.HttpsDownReason,
-		StateSince:           stateSince,
-		AllowRedirects:       int32(m.AllowRedirects),
-		LastSync:             lastSync,
-		LastSuccessfulSync:   lastSuccessfulSync,
-		LastModTime:          lastModTime,
+		ID:                    int32(m.ID),
+		Name:                  m.Name,
+		HttpURL:               m.HttpURL,
+		RsyncURL:              m.RsyncURL,
+		FtpURL:                m.FtpURL,
+		LocalURL:              m.LocalURL,
+		SponsorName:           m.SponsorName,
+		SponsorURL:            m.SponsorURL,
+		SponsorLogoURL:        m.SponsorLogoURL,
+		AdminName:             m.AdminName,
+		AdminEmail:            m.AdminEmail,
+		CustomData:            m.CustomData,
+		ContinentOnly:         m.ContinentOnly,
+		CountryOnly:           m.CountryOnly,
+		ASOnly:                m.ASOnly,
+		Score:                 int32(m.Score),
+		Latitude:              m.Latitude,
+		Longitude:             m.Longitude,
+		ContinentCode:         m.ContinentCode,
+		CountryCodes:          m.CountryCodes,
+		ExcludedCountryCodes:  m.ExcludedCountryCodes,
+		Asnum:                 uint32(m.Asnum),
+		Comment:               m.Comment,
+		Enabled:               m.Enabled,
+		HttpUp:                m.HttpUp,
+		HttpsUp:               m.HttpsUp,
+		HttpDownReason:        m.HttpDownReason,
+		HttpsDownReason:       m.HttpsDownReason,
+		StateSince:            stateSince,
+		AllowRedirects:        int32(m.AllowRedirects),
+		LastSync:              lastSync,
+		LastSuccessfulSync:    lastSuccessfulSync,
+		LastModTime:           lastModTime,
+		BandwidthCapacity:     int32(m.BandwidthCapacity),
+		HTTPHeaders:           m.HTTPHeaders,
+		Labels:                m.Labels,
+		ForcedUp:              m.ForcedUp,
+		ForcedUntil:           forcedUntil,
+		ClientCertFile:        m.ClientCertFile,
+		ClientKeyFile:         m.ClientKeyFile,
+		Tier:                  int32(m.Tier),
+		MaxConnections:        int32(m.MaxConnections),
+		Region:                m.Region,
+		HasIPv4:               m.HasIPv4,
+		HasIPv6:               m.HasIPv6,
+		ManualIPFamilies:      m.ManualIPFamilies,
+		CooldownUntil:         cooldownUntil,
+		Throughput:            m.Throughput,
+		ThroughputUpdated:     throughputUpdated,
+		Latency:               m.Latency,
+		LatencyUpdated:        latencyUpdated,
+		FtpUseTLS:             m.FtpUseTLS,
+		FtpInsecureSkipVerify: m.FtpInsecureSkipVerify,
+		ScanSchedule:          m.ScanSchedule,
+		ScanProxy:             m.ScanProxy,
 	}, nil
 }
 
@@ -78,38 +117,77 @@ func MirrorFromRPC(m *Mirror) (*mirrors.Mirror, error) {
 	if err != nil {
 		return nil, err
 	}
+	forcedUntil, err := ptypes.Timestamp(m.ForcedUntil)
+	if err != nil {
+		return nil, err
+	}
+	cooldownUntil, err := ptypes.Timestamp(m.CooldownUntil)
+	if err != nil {
+		return nil, err
+	}
+	throughputUpdated, err := ptypes.Timestamp(m.ThroughputUpdated)
+	if err != nil {
+		return nil, err
+	}
+	latencyUpdated, err := ptypes.Timestamp(m.LatencyUpdated)
+	if err != nil {
+		return nil, err
+	}
 	return &mirrors.Mirror{
-		ID:                   int(m.ID),
-		Name:                 m.Name,
-		HttpURL:              m.HttpURL,
-		RsyncURL:             m.RsyncURL,
-		FtpURL:               m.FtpURL,
-		SponsorName:          m.SponsorName,
-		SponsorURL:           m.SponsorURL,
-		SponsorLogoURL:       m.SponsorLogoURL,
-		AdminName:            m.AdminName,
-		AdminEmail:           m.AdminEmail,
-		CustomData:           m.CustomData,
-		ContinentOnly:        m.ContinentOnly,
-		CountryOnly:          m.CountryOnly,
-		ASOnly:               m.ASOnly,
-		Score:                int(m.Score),
-		Latitude:             m.Latitude,
-		Longitude:            m.Longitude,
-		ContinentCode:        m.ContinentCode,
-		CountryCodes:         m.CountryCodes,
-		ExcludedCountryCodes: m.ExcludedCountryCodes,
-		Asnum:                uint(m.Asnum),
-		Comment:              m.Comment,
-		Enabled:              m.Enabled,
-		HttpUp:               m.HttpUp,
-		HttpsUp:              m.HttpsUp,
-		HttpDownReason:       m.HttpDownReason,
-		HttpsDownReason:      m.HttpsDownReason,
-		StateSince:           mirrors.Time{}.FromTime(stateSince),
-		AllowRedirects:       mirrors.Redirects(m.AllowRedirects),
-		LastSync:             mirrors.Time{}.FromTime(lastSync),
-		LastSuccessfulSync:   mirrors.Time{}.FromTime(lastSuccessfulSync),
-		LastModTime:          mirrors.Time{}.FromTime(lastModTime),
+		ID:                    int(m.ID),
+		Name:                  m.Name,
+		HttpURL:               m.HttpURL,
+		RsyncURL:              m.RsyncURL,
+		FtpURL:                m.FtpURL,
+		LocalURL:              m.LocalURL,
+		SponsorName:           m.SponsorName,
+		SponsorURL:            m.SponsorURL,
+		SponsorLogoURL:        m.SponsorLogoURL,
+		AdminName:             m.AdminName,
+		AdminEmail:            m.AdminEmail,
+		CustomData:            m.CustomData,
+		ContinentOnly:         m.ContinentOnly,
+		CountryOnly:           m.CountryOnly,
+		ASOnly:                m.ASOnly,
+		Score:                 int(m.Score),
+		Latitude:              m.Latitude,
+		Longitude:             m.Longitude,
+		ContinentCode:         m.ContinentCode,
+		CountryCodes:          m.CountryCodes,
+		ExcludedCountryCodes:  m.ExcludedCountryCodes,
+		Asnum:                 uint(m.Asnum),
+		Comment:               m.Comment,
+		Enabled:               m.Enabled,
+		HttpUp:                m.HttpUp,
+		HttpsUp:               m.HttpsUp,
+		HttpDownReason:        m.HttpDownReason,
+		HttpsDownReason:       m.HttpsDownReason,
+		StateSince:            mirrors.Time{}.FromTime(stateSince),
+		AllowRedirects:        mirrors.Redirects(m.AllowRedirects),
+		LastSync:              mirrors.Time{}.FromTime(lastSync),
+		LastSuccessfulSync:    mirrors.Time{}.FromTime(lastSuccessfulSync),
+		LastModTime:           mirrors.Time{}.FromTime(lastModTime),
+		BandwidthCapacity:     int(m.BandwidthCapacity),
+		HTTPHeaders:           mirrors.Headers(m.HTTPHeaders),
+		Labels:                mirrors.Labels(m.Labels),
+		ForcedUp:              m.ForcedUp,
+		ForcedUntil:           mirrors.Time{}.FromTime(forcedUntil),
+		ClientCertFile:        m.ClientCertFile,
+		ClientKeyFile:         m.ClientKeyFile,
+		Tier:                  int(m.Tier),
+		MaxConnections:        int(m.MaxConnections),
+		Region:                m.Region,
+		HasIPv4:               m.HasIPv4,
+		HasIPv6:               m.HasIPv6,
+		ManualIPFamilies:      m.ManualIPFamilies,
+		CooldownUntil:         mirrors.Time{}.FromTime(cooldownUntil),
+		Throughput:            m.Throughput,
+		ThroughputUpdated:     mirrors.Time{}.FromTime(throughputUpdated),
+		Latency:               m.Latency,
+		LatencyUpdated:        mirrors.Time{}.FromTime(latencyUpdated),
+		FtpUseTLS:             m.FtpUseTLS,
+		FtpInsecureSkipVerify: m.FtpInsecureSkipVerify,
+		ScanSchedule:          m.ScanSchedule,
+		ScanProxy:             m.ScanProxy,
 	}, nil
 }
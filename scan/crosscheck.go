@@ -0,0 +1,373 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package scan
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/etix/mirrorbits/core"
+	"github.com/etix/mirrorbits/database"
+	"github.com/etix/mirrorbits/filesystem"
+	"github.com/etix/mirrorbits/mirrors"
+	"github.com/etix/mirrorbits/utils"
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	// PhaseCrossChecking is the phase of a scan hashing the files of the mirror
+	PhaseCrossChecking = "cross-checking"
+
+	crossCheckHeaderTimeout  = 30 * time.Second
+	crossCheckIdleConnection = 90 * time.Second
+
+	// crossCheckBatch is the number of files whose hashes are fetched at
+	// once from the database
+	crossCheckBatch = 1000
+)
+
+// HashMismatchKey returns the key of the set of files of the given mirror
+// not matching the reference hashes
+func HashMismatchKey(id int) string {
+	return fmt.Sprintf("MIRRORHASHMISMATCH_%d", id)
+}
+
+// loadMismatches returns the files of the mirror known to not match the
+// reference hashes
+func (s *scan) loadMismatches() (map[string]struct{}, error) {
+	files, err := redis.Strings(s.conn.Do("SMEMBERS", HashMismatchKey(s.mirrorid)))
+	if err != nil {
+		return nil, err
+	}
+	mismatched := make(map[string]struct{}, len(files))
+	for _, f := range files {
+		mismatched[f] = struct{}{}
+	}
+	return mismatched, nil
+}
+
+// crossCheckHashes downloads the files of the mirror not hashed yet, or
+// changed since they were (all of them unless TrustMtime is set), up to
+// CrossCheckMaxFiles per scan unless forceRehash is set, and compares
+// their SHA-256 with the reference ones: either the hashes of the local
+// repository or the hashes computed on CrossCheckReference. The files not
+// matching are recorded, and removed from the index of the mirror if
+// CrossCheckExclude is set. It returns the number of files not matching.
+func (s *scan) crossCheckHashes(name string, stop <-chan struct{}) (int64, error) {
+	conn := s.conn
+
+	m, err := redis.Values(conn.Do("HGETALL", fmt.Sprintf("MIRROR_%d", s.mirrorid)))
+	if err != nil {
+		return 0, err
+	}
+	var mirror mirrors.Mirror
+	if err = redis.ScanStruct(m, &mirror); err != nil {
+		return 0, err
+	}
+	if mirror.HttpURL == "" {
+		return 0, nil
+	}
+	baseURL := strings.TrimRight(mirror.HttpURL, "/")
+	if !utils.HasAnyPrefix(baseURL, "http://", "https://") {
+		baseURL = "https://" + baseURL
+	}
+
+	// Find the reference mirror, if any
+	refID := 0
+	if reference := GetConfig().CrossCheckReference; reference != "" {
+		names, err := redis.StringMap(conn.Do("HGETALL", "MIRRORS"))
+		if err != nil {
+			return 0, err
+		}
+		for id, n := range names {
+			if n == reference {
+				fmt.Sscan(id, &refID)
+				break
+			}
+		}
+		if refID == 0 {
+			log.Warningf("[%s] Skipping the hash cross-check: unknown reference mirror %s", name, reference)
+			return 0, nil
+		}
+	}
+
+	files, err := redis.Strings(conn.Do("SMEMBERS", fmt.Sprintf("HANDLEDFILES_%d", s.mirrorid)))
+	if err != nil {
+		return 0, err
+	}
+	if s.only != "" {
+		// Only consider the files of the scanned subtree
+		subtree := files[:0]
+		for _, f := range files {
+			if strings.HasPrefix(f, s.only+"/") {
+				subtree = append(subtree, f)
+			}
+		}
+		files = subtree
+	}
+
+	tlsConfig, err := mirror.TLSConfig()
+	if err != nil {
+		return 0, err
+	}
+	proxy := http.ProxyFromEnvironment
+	if proxyURL, err := mirrors.ParseScanProxy(mirror.ScanProxy); err != nil {
+		return 0, err
+	} else if proxyURL != nil {
+		proxy = http.ProxyURL(proxyURL)
+	}
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 proxy,
+			TLSClientConfig:       tlsConfig,
+			ResponseHeaderTimeout: crossCheckHeaderTimeout,
+			IdleConnTimeout:       crossCheckIdleConnection,
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	s.setPhase(PhaseCrossChecking)
+
+	maxFiles := GetConfig().CrossCheckMaxFiles
+	trustMtime := GetConfig().TrustMtime && !s.forceRehash
+	maxSize := GetConfig().CrossCheckMaxSize
+	hashed := 0
+	var mismatched, matching []string
+	for len(files) > 0 {
+		batch := files
+		if len(batch) > crossCheckBatch {
+			batch = batch[:crossCheckBatch]
+		}
+		files = files[len(batch):]
+
+		entries, err := s.crossCheckInfo(batch, refID)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, e := range entries {
+			if ctx.Err() != nil {
+				return 0, ErrScanAborted
+			}
+			if e.reference == "" && refID != s.mirrorid {
+				// Nothing to compare with
+				continue
+			}
+
+			fi := e.info
+			sum := fi[2]
+			if sum == "" || !trustMtime || !hashUpToDate(fi, s.precision) {
+				var size int64
+				fmt.Sscan(fi[0], &size)
+				if (hashed >= maxFiles && !s.forceRehash) || (maxSize > 0 && size > maxSize) {
+					continue
+				}
+				hashed++
+				if s.localRoot != "" {
+					sum, err = hashLocal(filepath.Join(s.localRoot, filepath.FromSlash(s.mirrorPath(e.path))))
+				} else {
+					sum, err = s.hashRemote(ctx, client, &mirror, baseURL+s.mirrorPath(e.path))
+				}
+				if err != nil {
+					if ctx.Err() != nil {
+						return 0, ErrScanAborted
+					}
+					log.Warningf("[%s] Unable to hash %s: %s", name, e.path, err)
+					continue
+				}
+				key := fmt.Sprintf("FILEINFO_%d_%s", s.mirrorid, e.path)
+				_, err = conn.Do("HSET", key, "sha256", sum, "hashedSize", fi[0], "hashedModTime", fi[1], "hashedPrecision", s.precision)
+				if err != nil {
+					return 0, err
+				}
+			}
+
+			if refID == s.mirrorid {
+				continue
+			}
+			if sum != e.reference {
+				log.Warningf("[%s] Hash mismatch for %s", name, e.path)
+				mismatched = append(mismatched, e.path)
+			} else {
+				matching = append(matching, e.path)
+			}
+		}
+	}
+
+	exclude := GetConfig().CrossCheckExclude
+	conn.Send("MULTI")
+	for _, path := range mismatched {
+		conn.Send("SADD", HashMismatchKey(s.mirrorid), path)
+		if exclude {
+			conn.Send("SREM", fmt.Sprintf("FILEMIRRORS_%s", path), s.mirrorid)
+			database.SendPublish(conn, database.MIRROR_FILE_UPDATE, fmt.Sprintf("%d %s", s.mirrorid, path))
+		}
+	}
+	for _, path := range matching {
+		if _, ok := s.mismatched[path]; ok {
+			// Put the fixed files back in the index
+			conn.Send("SADD", fmt.Sprintf("FILEMIRRORS_%s", path), s.mirrorid)
+			database.SendPublish(conn, database.MIRROR_FILE_UPDATE, fmt.Sprintf("%d %s", s.mirrorid, path))
+		}
+		conn.Send("SREM", HashMismatchKey(s.mirrorid), path)
+	}
+	if s.only == "" {
+		// Forget the files gone from the mirror
+		conn.Send("SINTERSTORE", HashMismatchKey(s.mirrorid), HashMismatchKey(s.mirrorid), fmt.Sprintf("HANDLEDFILES_%d", s.mirrorid))
+	}
+	if _, err = conn.Do("EXEC"); err != nil {
+		return 0, err
+	}
+
+	total, err := redis.Int64(conn.Do("SCARD", HashMismatchKey(s.mirrorid)))
+	if err != nil {
+		return 0, err
+	}
+	log.Infof("[%s] Cross-checked the hashes: %d files hashed, %d not matching the reference", name, hashed, total)
+	return total, nil
+}
+
+// crossCheckFile holds the hash of a file on the reference, and its
+// properties on the mirror as returned by HMGET size modTime sha256
+// hashedSize hashedModTime hashedPrecision
+type crossCheckFile struct {
+	path      string
+	reference string
+	info      []string
+}
+
+// crossCheckInfo fetches the reference hashes of the given files, from the
+// reference mirror or from the local repository if refID is 0, along with
+// their properties on the mirror, in a single pipeline
+func (s *scan) crossCheckInfo(paths []string, refID int) ([]crossCheckFile, error) {
+	fields := []any{"size", "modTime", "sha256", "hashedSize", "hashedModTime", "hashedPrecision"}
+	for _, path := range paths {
+		if refID == 0 {
+			s.conn.Send("HGET", fmt.Sprintf("FILE_%s", path), "sha256")
+		} else if refID != s.mirrorid {
+			s.conn.Send("HMGET", append([]any{fmt.Sprintf("FILEINFO_%d_%s", refID, path)}, fields...)...)
+		}
+		s.conn.Send("HMGET", append([]any{fmt.Sprintf("FILEINFO_%d_%s", s.mirrorid, path)}, fields...)...)
+	}
+	if err := s.conn.Flush(); err != nil {
+		return nil, err
+	}
+
+	entries := make([]crossCheckFile, 0, len(paths))
+	for _, path := range paths {
+		e := crossCheckFile{path: path}
+		if refID == 0 {
+			sum, err := redis.String(s.conn.Receive())
+			if err != nil && err != redis.ErrNil {
+				return nil, err
+			}
+			e.reference = sum
+		} else if refID != s.mirrorid {
+			fi, err := redis.Strings(s.conn.Receive())
+			if err != nil {
+				return nil, err
+			}
+			if hashUpToDate(fi, 0) {
+				// Outdated hashes are ignored
+				e.reference = fi[2]
+			}
+		}
+		fi, err := redis.Strings(s.conn.Receive())
+		if err != nil {
+			return nil, err
+		}
+		e.info = fi
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// hashUpToDate returns true if the size and the modification time of a file,
+// as returned by HMGET size modTime sha256 hashedSize hashedModTime
+// hashedPrecision, are still the ones it had when it was hashed. The times
+// are compared as instants, whatever their timezone, truncated to the
+// coarsest of the precision of the scan and the one recorded with the hash:
+// a mirror scanned over both FTP and rsync reports the same file with
+// different precisions. Unknown times leave only the sizes to compare.
+func hashUpToDate(fi []string, precision core.Precision) bool {
+	if len(fi) < 6 || fi[0] != fi[3] {
+		return false
+	}
+
+	var hashedPrecision int64
+	fmt.Sscan(fi[5], &hashedPrecision)
+	p := precision.Duration()
+	if d := time.Duration(hashedPrecision); d > p {
+		p = d
+	}
+	if p == 0 {
+		p = time.Second
+	}
+
+	modTime, err := time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", fi[1])
+	if err != nil {
+		return false
+	}
+	hashedModTime, err := time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", fi[4])
+	if err != nil {
+		return false
+	}
+	if modTime.IsZero() || hashedModTime.IsZero() {
+		return modTime.IsZero() == hashedModTime.IsZero()
+	}
+	return modTime.Truncate(p).Equal(hashedModTime.Truncate(p))
+}
+
+// hashLocal returns the SHA-256 of the given file of a mirror scanned from
+// the local filesystem
+func hashLocal(path string) (string, error) {
+	sum, err := filesystem.Sha256sum(path)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sum), nil
+}
+
+// hashRemote downloads the given file and returns its SHA-256
+func (s *scan) hashRemote(ctx context.Context, client *http.Client, mirror *mirrors.Mirror, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", "Mirrorbits/"+core.VERSION+" (hash cross-check)")
+	mirror.HTTPHeaders.Apply(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HTTP error: %s", resp.Status)
+	}
+
+	h := sha256.New()
+	if _, err = io.Copy(h, resp.Body); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,278 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package scan
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/etix/mirrorbits/core"
+	"github.com/etix/mirrorbits/database"
+	"github.com/rafaeljusto/redigomock"
+)
+
+func TestMain(m *testing.M) {
+	SetConfiguration(&Configuration{})
+	m.Run()
+}
+
+func sha256Hex(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestCrossCheckHashes(t *testing.T) {
+	const modTime = "2025-06-01 06:00:00 +0000 UTC"
+	fields := []any{"size", "modTime", "sha256", "hashedSize", "hashedModTime", "hashedPrecision"}
+
+	tests := map[string]struct {
+		reference  string
+		content    string
+		info       []string
+		mismatched bool
+		exclude    bool
+		maxSize    int64
+
+		hashed   bool
+		mismatch bool
+		restored bool
+	}{
+		"match": {
+			reference: sha256Hex("ok"), content: "ok",
+			info:   []string{"2", modTime, "", "", "", ""},
+			hashed: true,
+		},
+		"mismatch": {
+			reference: sha256Hex("ok"), content: "bad",
+			info:   []string{"3", modTime, "", "", "", ""},
+			hashed: true, mismatch: true,
+		},
+		"mismatch excluded": {
+			reference: sha256Hex("ok"), content: "bad", exclude: true,
+			info:   []string{"3", modTime, "", "", "", ""},
+			hashed: true, mismatch: true,
+		},
+		"fixed": {
+			reference: sha256Hex("ok"), content: "ok", mismatched: true, exclude: true,
+			info:   []string{"2", modTime, sha256Hex("bad"), "3", modTime, "0"},
+			hashed: true, restored: true,
+		},
+		"no reference": {
+			content: "ok",
+			info:    []string{"2", modTime, "", "", "", ""},
+		},
+		"hash up to date": {
+			reference: sha256Hex("ok"), content: "changed on disk",
+			info: []string{"2", modTime, sha256Hex("ok"), "2", modTime, "0"},
+		},
+		"hash outdated": {
+			reference: sha256Hex("ok"), content: "ok",
+			info:   []string{"2", modTime, sha256Hex("old"), "3", modTime, "0"},
+			hashed: true,
+		},
+		"too large": {
+			reference: sha256Hex("ok"), content: "ok", maxSize: 1,
+			info: []string{"2", modTime, "", "", "", ""},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer SetConfiguration(GetConfig())
+			SetConfiguration(&Configuration{
+				CrossCheckMaxFiles: 10,
+				CrossCheckMaxSize:  test.maxSize,
+				CrossCheckExclude:  test.exclude,
+				TrustMtime:         true,
+			})
+
+			root := t.TempDir()
+			if err := os.WriteFile(filepath.Join(root, "file"), []byte(test.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			mock := redigomock.NewConn()
+			s := &scan{
+				conn:       mock,
+				mirrorid:   1,
+				localRoot:  root,
+				mismatched: map[string]struct{}{},
+			}
+			if test.mismatched {
+				s.mismatched["/file"] = struct{}{}
+			}
+
+			mock.Command("HGETALL", "MIRROR_1").Expect([]any{[]byte("http"), []byte("mirror.example")})
+			mock.Command("SMEMBERS", "HANDLEDFILES_1").Expect([]any{[]byte("/file")})
+			if test.reference != "" {
+				mock.Command("HGET", "FILE_/file", "sha256").Expect(test.reference)
+			} else {
+				mock.Command("HGET", "FILE_/file", "sha256").Expect(nil)
+			}
+			info := make([]any, len(test.info))
+			for i, v := range test.info {
+				info[i] = []byte(v)
+			}
+			mock.Command("HMGET", append([]any{"FILEINFO_1_/file"}, fields...)...).Expect(info)
+			hset := mock.Command("HSET", "FILEINFO_1_/file", "sha256", sha256Hex(test.content),
+				"hashedSize", test.info[0], "hashedModTime", test.info[1], "hashedPrecision", core.Precision(0))
+			mock.Command("MULTI")
+			addMismatch := mock.Command("SADD", HashMismatchKey(1), "/file")
+			remMismatch := mock.Command("SREM", HashMismatchKey(1), "/file")
+			exclude := mock.Command("SREM", "FILEMIRRORS_/file", 1)
+			restore := mock.Command("SADD", "FILEMIRRORS_/file", 1)
+			publish := mock.Command("PUBLISH", string(database.MIRROR_FILE_UPDATE), "1 /file")
+			mock.Command("SINTERSTORE", HashMismatchKey(1), HashMismatchKey(1), "HANDLEDFILES_1")
+			mock.Command("EXEC")
+			mock.Command("SCARD", HashMismatchKey(1)).Expect(int64(0))
+
+			if _, err := s.crossCheckHashes("m1", make(chan struct{})); err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			for _, err := range mock.Errors {
+				t.Fatalf("Unexpected database access: %s", err)
+			}
+
+			checked := test.reference != "" && (test.hashed || test.info[2] != "")
+			expected := map[string]struct {
+				cmd   *redigomock.Cmd
+				calls int
+			}{
+				"hash":            {hset, btoi(test.hashed)},
+				"record mismatch": {addMismatch, btoi(test.mismatch)},
+				"clear mismatch":  {remMismatch, btoi(checked && !test.mismatch)},
+				"exclude":         {exclude, btoi(test.mismatch && test.exclude)},
+				"restore":         {restore, btoi(test.restored)},
+				"publish":         {publish, btoi(test.mismatch && test.exclude || test.restored)},
+			}
+			for what, e := range expected {
+				if calls := mock.Stats(e.cmd); calls != e.calls {
+					t.Errorf("%s: expected %d calls, got %d", what, e.calls, calls)
+				}
+			}
+		})
+	}
+}
+
+func TestCrossCheckInfoReferenceMirror(t *testing.T) {
+	const modTime = "2025-06-01 06:00:00 +0000 UTC"
+	fields := []any{"size", "modTime", "sha256", "hashedSize", "hashedModTime", "hashedPrecision"}
+
+	mock := redigomock.NewConn()
+	s := &scan{conn: mock, mirrorid: 1}
+
+	// The hash of /outdated on the reference predates its last change
+	mock.Command("HMGET", append([]any{"FILEINFO_2_/fresh"}, fields...)...).
+		Expect([]any{[]byte("2"), []byte(modTime), []byte("aaaa"), []byte("2"), []byte(modTime), []byte("0")})
+	mock.Command("HMGET", append([]any{"FILEINFO_2_/outdated"}, fields...)...).
+		Expect([]any{[]byte("3"), []byte(modTime), []byte("bbbb"), []byte("2"), []byte(modTime), []byte("0")})
+	for _, path := range []string{"/fresh", "/outdated"} {
+		mock.Command("HMGET", append([]any{"FILEINFO_1_" + path}, fields...)...).
+			Expect([]any{[]byte("2"), []byte(modTime), nil, nil, nil, nil})
+	}
+
+	entries, err := s.crossCheckInfo([]string{"/fresh", "/outdated"}, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(entries) != 2 || entries[0].reference != "aaaa" || entries[1].reference != "" {
+		t.Fatalf("Unexpected entries %+v", entries)
+	}
+	if entries[0].info[0] != "2" || entries[1].path != "/outdated" {
+		t.Fatalf("Unexpected file info %+v", entries)
+	}
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func TestHashUpToDate(t *testing.T) {
+	second := strconv.FormatInt(int64(time.Second), 10)
+	minute := strconv.FormatInt(int64(time.Minute), 10)
+
+	tests := map[string]struct {
+		fi        []string
+		precision core.Precision
+		expected  bool
+	}{
+		"unchanged": {
+			[]string{"42", "2025-06-01 06:00:42 +0000 UTC", "sum", "42", "2025-06-01 06:00:42 +0000 UTC", second},
+			core.Precision(time.Second), true,
+		},
+		"hashed over ftp, scanned over rsync": {
+			[]string{"42", "2025-06-01 06:00:42 +0000 UTC", "sum", "42", "2025-06-01 06:00:00 +0000 UTC", minute},
+			core.Precision(time.Second), true,
+		},
+		"hashed over rsync, scanned over ftp": {
+			[]string{"42", "2025-06-01 06:00:00 +0000 UTC", "sum", "42", "2025-06-01 06:00:42 +0000 UTC", second},
+			core.Precision(time.Minute), true,
+		},
+		"changed within the minute over rsync": {
+			[]string{"42", "2025-06-01 06:00:42 +0000 UTC", "sum", "42", "2025-06-01 06:00:00 +0000 UTC", second},
+			core.Precision(time.Second), false,
+		},
+		"changed beyond the precision": {
+			[]string{"42", "2025-06-01 06:02:00 +0000 UTC", "sum", "42", "2025-06-01 06:00:00 +0000 UTC", minute},
+			core.Precision(time.Minute), false,
+		},
+		"same instant in other zones": {
+			[]string{"42", "2025-06-01 08:00:42 +0200 CEST", "sum", "42", "2025-06-01 06:00:42 +0000 UTC", second},
+			core.Precision(time.Second), true,
+		},
+		"same wall clock in other zones": {
+			[]string{"42", "2025-06-01 06:00:42 +0200 CEST", "sum", "42", "2025-06-01 06:00:42 +0000 UTC", second},
+			core.Precision(time.Second), false,
+		},
+		"sub-second precision": {
+			[]string{"42", "2025-06-01 06:00:42.123 +0000 UTC", "sum", "42", "2025-06-01 06:00:42.456 +0000 UTC", "1000000"},
+			core.Precision(time.Millisecond), false,
+		},
+		"missing precision": {
+			[]string{"42", "2025-06-01 06:00:42.123 +0000 UTC", "sum", "42", "2025-06-01 06:00:42.456 +0000 UTC", ""},
+			0, true,
+		},
+		"zero times": {
+			[]string{"42", "0001-01-01 00:00:00 +0000 UTC", "sum", "42", "0001-01-01 00:00:00 +0000 UTC", second},
+			core.Precision(time.Second), true,
+		},
+		"hashed time unknown": {
+			[]string{"42", "2025-06-01 06:00:42 +0000 UTC", "sum", "42", "0001-01-01 00:00:00 +0000 UTC", second},
+			core.Precision(time.Second), false,
+		},
+		"unparsable time": {
+			[]string{"42", "yesterday", "sum", "42", "2025-06-01 06:00:42 +0000 UTC", second},
+			core.Precision(time.Second), false,
+		},
+		"never hashed": {
+			[]string{"42", "2025-06-01 06:00:42 +0000 UTC", "", "", "", ""},
+			core.Precision(time.Second), false,
+		},
+		"size changed": {
+			[]string{"43", "2025-06-01 06:00:42 +0000 UTC", "sum", "42", "2025-06-01 06:00:42 +0000 UTC", second},
+			core.Precision(time.Second), false,
+		},
+		"truncated reply": {
+			[]string{"42", "2025-06-01 06:00:42 +0000 UTC"},
+			core.Precision(time.Second), false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if upToDate := hashUpToDate(test.fi, test.precision); upToDate != test.expected {
+				t.Fatalf("Expected %t, got %t", test.expected, upToDate)
+			}
+		})
+	}
+}
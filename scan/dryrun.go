@@ -0,0 +1,177 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package scan
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/etix/mirrorbits/core"
+	"github.com/etix/mirrorbits/database"
+	"github.com/etix/mirrorbits/mirrors"
+	"github.com/gomodule/redigo/redis"
+)
+
+// DryRunSampleSize is the maximum number of paths reported for each kind
+// of change by a dry run
+const DryRunSampleSize = 10
+
+// DryRunResult holds the changes a scan would apply to the index of a mirror
+type DryRunResult struct {
+	MirrorID      int
+	MirrorName    string
+	FilesFound    int64
+	KnownFound    int64
+	Added         int64
+	Removed       int64
+	Changed       int64
+	SampleAdded   []string
+	SampleRemoved []string
+	SampleChanged []string
+}
+
+// ScanDryRun enumerates the files of the given subtree of the mirror (the
+// whole mirror if only is empty) and compares them with its index, without
+// writing anything to the database.
+func ScanDryRun(typ core.ScannerType, r *database.Redis, c *mirrors.Cache, url string, id int, only string, stop <-chan struct{}) (*DryRunResult, error) {
+	only, err := CleanScanPath(only)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := r.Get()
+	defer conn.Close()
+
+	s := &scan{
+		redis:    r,
+		mirrorid: id,
+		conn:     conn,
+		cache:    c,
+		only:     only,
+		dryRun:   true,
+	}
+	scanner := newScanner(typ, s)
+
+	name, err := redis.String(conn.Do("HGET", "MIRRORS", id))
+	if err != nil {
+		return nil, err
+	}
+	if err = s.setPrefix(name); err != nil {
+		return nil, err
+	}
+
+	defer s.track(typ)()
+
+	if _, err = s.enumerate(scanner, url, name, stop); err != nil {
+		log.Errorf("[%s] %s", name, err.Error())
+		return nil, err
+	}
+
+	previous, err := redis.Strings(conn.Do("SMEMBERS", fmt.Sprintf("MIRRORFILES_%d", id)))
+	if err != nil {
+		return nil, err
+	}
+
+	res := diffIndex(s.found, previous, only)
+	res.MirrorID = id
+	res.MirrorName = name
+
+	// Compare the files already indexed with their recorded properties
+	// and check which ones are known in the local repository
+	for _, f := range s.found {
+		conn.Send("SISMEMBER", "FILES", f.path)
+		conn.Send("HMGET", fmt.Sprintf("FILEINFO_%d_%s", id, f.path), "size", "modTime")
+	}
+	if err = conn.Flush(); err != nil {
+		return nil, err
+	}
+	indexed := make(map[string]bool, len(previous))
+	for _, p := range previous {
+		indexed[p] = true
+	}
+	for _, f := range s.found {
+		known, err := redis.Bool(conn.Receive())
+		if err != nil {
+			return nil, err
+		}
+		if known {
+			res.KnownFound++
+		}
+		info, err := redis.Strings(conn.Receive())
+		if err != nil {
+			return nil, err
+		}
+		if indexed[f.path] && fileChanged(f, info) {
+			res.Changed++
+			if len(res.SampleChanged) < DryRunSampleSize {
+				res.SampleChanged = append(res.SampleChanged, f.path)
+			}
+		}
+	}
+
+	log.Infof("[%s] Dry run: found %d files (%d known), %d added, %d changed, %d removed",
+		name, res.FilesFound, res.KnownFound, res.Added, res.Changed, res.Removed)
+
+	return res, nil
+}
+
+// diffIndex returns the files added and removed from the index, only the
+// files of the previous index under the given subtree are considered
+func diffIndex(found []filedata, previous []string, only string) *DryRunResult {
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].path < found[j].path
+	})
+	sort.Strings(previous)
+
+	res := &DryRunResult{
+		FilesFound: int64(len(found)),
+	}
+
+	present := make(map[string]bool, len(found))
+	for _, f := range found {
+		present[f.path] = true
+	}
+	indexed := make(map[string]bool, len(previous))
+	for _, p := range previous {
+		if only != "" && !strings.HasPrefix(p, only+"/") {
+			continue
+		}
+		indexed[p] = true
+		if !present[p] {
+			res.Removed++
+			if len(res.SampleRemoved) < DryRunSampleSize {
+				res.SampleRemoved = append(res.SampleRemoved, p)
+			}
+		}
+	}
+	for _, f := range found {
+		if !indexed[f.path] {
+			res.Added++
+			if len(res.SampleAdded) < DryRunSampleSize {
+				res.SampleAdded = append(res.SampleAdded, f.path)
+			}
+		}
+	}
+	return res
+}
+
+// fileChanged returns true if the size or the modification time found on
+// the mirror differ from the ones recorded by the previous scan
+func fileChanged(f filedata, info []string) bool {
+	if len(info) < 2 {
+		return true
+	}
+	size, _ := strconv.ParseInt(info[0], 10, 64)
+	if size != f.size {
+		return true
+	}
+	modTime, _ := time.Parse("2006-01-02 15:04:05.999999999 -0700 MST", info[1])
+	if !modTime.IsZero() && !f.modTime.IsZero() && !modTime.Equal(f.modTime) {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package scan
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/etix/mirrorbits/utils"
+)
+
+// ErrTooManyFiles is returned (wrapped) when a scan finds more files than
+// MaxFilesPerMirror
+var ErrTooManyFiles = errors.New("too many files")
+
+// fileCap stops the enumeration of a scan once it finds more files than
+// MaxFilesPerMirror, so that a misconfigured mirror can't fill the database
+type fileCap struct {
+	max       int64
+	truncate  bool
+	exceeded  bool
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// limitFiles sets the cap of the scan from the configuration and returns the
+// channel to give to the scanner, closed as soon as stop is or the cap is
+// exceeded. The returned function must be called once the enumeration is
+// done.
+func (s *scan) limitFiles(stop <-chan struct{}) (<-chan struct{}, func()) {
+	s.cap = fileCap{
+		max:      int64(GetConfig().MaxFilesPerMirror),
+		truncate: GetConfig().MaxFilesPerMirrorTruncate,
+	}
+	if s.cap.max <= 0 {
+		return stop, func() {}
+	}
+
+	s.cap.stop = make(chan struct{})
+	merged := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-stop:
+		case <-s.cap.stop:
+		case <-done:
+			return
+		}
+		close(merged)
+	}()
+	return merged, func() { close(done) }
+}
+
+// capReached returns true if n files are more than the cap
+func (s *scan) capReached(n int64) bool {
+	return s.cap.max > 0 && n > s.cap.max
+}
+
+// overCap returns true if n files are more than the cap, in which case the
+// enumeration is stopped
+func (s *scan) overCap(n int64) bool {
+	if !s.capReached(n) {
+		return false
+	}
+	s.cap.exceeded = true
+	s.cap.closeOnce.Do(func() {
+		close(s.cap.stop)
+	})
+	return true
+}
+
+// summary returns the cap for the logs of the scan, empty if there is none
+func (c *fileCap) summary() string {
+	switch {
+	case c.max <= 0:
+		return ""
+	case c.exceeded:
+		return fmt.Sprintf(" (truncated to the cap of %d)", c.max)
+	}
+	return fmt.Sprintf(" (cap %d)", c.max)
+}
+
+// checkFileCap returns the error of the enumeration once the cap is
+// accounted for: a scan that exceeded it fails with ErrTooManyFiles, unless
+// MaxFilesPerMirrorTruncate is set in which case the files found so far are
+// kept.
+func (s *scan) checkFileCap(name string, err error, stop <-chan struct{}) error {
+	if !s.cap.exceeded || utils.IsStopped(stop) {
+		return err
+	}
+	if !s.cap.truncate {
+		return fmt.Errorf("%w: more than %d files found (MaxFilesPerMirror), the scan was not committed", ErrTooManyFiles, s.cap.max)
+	}
+	if err != nil && !errors.Is(err, ErrScanAborted) {
+		return err
+	}
+	log.Warningf("[%s] More than %d files found (MaxFilesPerMirror), only the first ones are indexed", name, s.cap.max)
+	return nil
+}
@@ -4,15 +4,18 @@
 package scan
 
 import (
+	"crypto/tls"
 	"fmt"
+	"net"
 	"net/url"
 	"strings"
 	"time"
 
-	ftp "github.com/etix/goftp"
 	"github.com/etix/mirrorbits/core"
+	"github.com/etix/mirrorbits/mirrors"
 	"github.com/etix/mirrorbits/utils"
 	"github.com/gomodule/redigo/redis"
+	"github.com/jlaffaye/ftp"
 )
 
 const (
@@ -29,10 +32,13 @@ type FTPScanner struct {
 	precision core.Precision // Used for truncating time for comparison
 }
 
-// Scan starts an ftp scan of the given mirror
+// Scan starts an ftp scan of the given mirror. The connection is upgraded
+// to TLS (explicit AUTH TLS) if the URL uses the ftps:// scheme or if the
+// mirror has FtpUseTLS set, and goes through the ScanProxy of the mirror if
+// any.
 func (f *FTPScanner) Scan(scanurl, identifier string, conn redis.Conn, stop <-chan struct{}) (core.Precision, error) {
-	if !strings.HasPrefix(scanurl, "ftp://") {
-		return 0, fmt.Errorf("%s does not start with ftp://", scanurl)
+	if !utils.HasAnyPrefix(scanurl, "ftp://", "ftps://") {
+		return 0, fmt.Errorf("%s does not start with ftp:// or ftps://", scanurl)
 	}
 
 	ftpurl, err := url.Parse(scanurl)
@@ -41,15 +47,44 @@ func (f *FTPScanner) Scan(scanurl, identifier string, conn redis.Conn, stop <-ch
 	}
 
 	host := ftpurl.Host
-	if !strings.Contains(host, ":") {
-		host += ":21"
+	if ftpurl.Port() == "" {
+		host = net.JoinHostPort(ftpurl.Hostname(), "21")
 	}
 
 	if utils.IsStopped(stop) {
 		return 0, ErrScanAborted
 	}
 
-	c, err := ftp.DialTimeout(host, ftpConnTimeout, ftpRWTimeout)
+	useTLS, insecure, scanProxy, err := f.connSettings()
+	if err != nil {
+		return 0, err
+	}
+	proxyURL, err := mirrors.ParseScanProxy(scanProxy)
+	if err != nil {
+		return 0, err
+	}
+	dial, err := proxyDialFunc(proxyURL, ftpConnTimeout)
+	if err != nil {
+		return 0, err
+	}
+
+	var tlsConfig *tls.Config
+	if useTLS || ftpurl.Scheme == "ftps" {
+		tlsConfig = &tls.Config{
+			ServerName:         ftpurl.Hostname(),
+			InsecureSkipVerify: insecure,
+			// Most servers require the data connections to resume the
+			// TLS session of the control connection
+			ClientSessionCache: tls.NewLRUClientSessionCache(0),
+		}
+	}
+
+	options := []ftp.DialOption{ftp.DialWithDialFunc(ftpDialFunc(dial, tlsConfig))}
+	if tlsConfig != nil {
+		options = append(options, ftp.DialWithExplicitTLS(tlsConfig))
+	}
+
+	c, err := ftp.Dial(host, options...)
 	if err != nil {
 		return 0, err
 	}
@@ -70,8 +105,8 @@ func (f *FTPScanner) Scan(scanurl, identifier string, conn redis.Conn, stop <-ch
 		return 0, err
 	}
 
-	_, f.featMLST = c.Feature("MLST")
-	_, f.featMDTM = c.Feature("MDTM")
+	f.featMLST = c.IsTimePreciseInList()
+	f.featMDTM = c.IsGetTimeSupported()
 
 	if !f.featMLST || !f.featMDTM {
 		log.Warning("This server does not support some of the RFC 3659 extensions, consider using rsync instead.")
@@ -83,20 +118,20 @@ func (f *FTPScanner) Scan(scanurl, identifier string, conn redis.Conn, stop <-ch
 
 	err = c.ChangeDir(ftpurl.Path)
 	if err != nil {
-		return 0, fmt.Errorf("ftp error %s", err.Error())
+		return 0, fmt.Errorf("ftp error %w", err)
 	}
 
 	_, err = c.CurrentDir()
 	if err != nil {
-		return 0, fmt.Errorf("ftp error %s", err.Error())
+		return 0, fmt.Errorf("ftp error %w", err)
 	}
 
 	// Remove the trailing slash
 	prefix := strings.TrimRight(ftpurl.Path, "/")
 
-	files, err = f.walkFtp(c, files, prefix+"/", stop)
+	files, err = f.walkFtp(c, files, prefix+f.scan.remoteOnly+"/", stop)
 	if err != nil {
-		return 0, fmt.Errorf("ftp error %s", err.Error())
+		return 0, fmt.Errorf("ftp error %w", err)
 	}
 
 	count := 0
@@ -120,13 +155,17 @@ func (f *FTPScanner) walkFtp(c *ftp.ServerConn, files []*filedata, path string,
 		return nil, err
 	}
 	for _, e := range flist {
+		if f.scan.capReached(int64(len(files))) {
+			// No need to list more, the files are counted once added
+			break
+		}
 		if e.Type == ftp.EntryTypeFile {
 			newf := &filedata{}
 			newf.path = path + e.Name
 			newf.size = int64(e.Size)
 
 			if f.featMDTM {
-				t, _ := c.LastModificationDate(path + e.Name)
+				t, _ := c.GetTime(path + e.Name)
 				if !t.IsZero() {
 					newf.modTime = t
 
@@ -165,3 +204,55 @@ func (f *FTPScanner) walkFtp(c *ftp.ServerConn, files []*filedata, path string,
 	}
 	return files, err
 }
+
+// connSettings returns the FTPS settings and the scan proxy of the mirror
+// being scanned
+func (f *FTPScanner) connSettings() (useTLS, insecure bool, scanProxy string, err error) {
+	conn := f.scan.redis.Get()
+	defer conn.Close()
+
+	values, err := redis.Values(conn.Do("HMGET", fmt.Sprintf("MIRROR_%d", f.scan.mirrorid), "ftpUseTLS", "ftpInsecureSkipVerify", "scanProxy"))
+	if err != nil {
+		return false, false, "", err
+	}
+	useTLS, _ = redis.Bool(values[0], nil)
+	insecure, _ = redis.Bool(values[1], nil)
+	scanProxy, _ = redis.String(values[2], nil)
+	return useTLS, insecure, scanProxy, nil
+}
+
+// ftpDialFunc returns the function opening the connections to the server
+// with dial, each operation on them times out after ftpRWTimeout. The first
+// connection is the control connection, upgraded by the ftp package itself
+// when TLS is used, the next ones are the data connections.
+func ftpDialFunc(dial dialFunc, tlsConfig *tls.Config) func(network, address string) (net.Conn, error) {
+	control := true
+	return func(network, address string) (net.Conn, error) {
+		c, err := dial(network, address)
+		if err != nil {
+			return nil, err
+		}
+		var conn net.Conn = &timeoutConn{Conn: c, timeout: ftpRWTimeout}
+		if tlsConfig != nil && !control {
+			conn = tls.Client(conn, tlsConfig)
+		}
+		control = false
+		return conn, nil
+	}
+}
+
+// timeoutConn is a net.Conn whose reads and writes time out
+type timeoutConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (t *timeoutConn) Read(buf []byte) (int, error) {
+	t.Conn.SetDeadline(time.Now().Add(t.timeout))
+	return t.Conn.Read(buf)
+}
+
+func (t *timeoutConn) Write(buf []byte) (int, error) {
+	t.Conn.SetDeadline(time.Now().Add(t.timeout))
+	return t.Conn.Write(buf)
+}
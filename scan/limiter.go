@@ -0,0 +1,80 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package scan
+
+import (
+	"sync"
+
+	. "github.com/etix/mirrorbits/config"
+)
+
+var limiter scanLimiter
+
+// scanLimiter bounds the number of mirrors scanned simultaneously by this
+// process. The scans started by the daemon and over RPC share the same
+// slots, the others wait in a FIFO queue.
+type scanLimiter struct {
+	sync.Mutex
+	running int
+	waiting []chan struct{}
+}
+
+// Acquire waits for a free scan slot, as limited by MaxConcurrentScans, and
+// returns the function releasing it once the scan is done. ErrScanAborted is
+// returned if stop is closed while waiting.
+func Acquire(name string, stop <-chan struct{}) (func(), error) {
+	return limiter.acquire(name, stop)
+}
+
+func (l *scanLimiter) acquire(name string, stop <-chan struct{}) (func(), error) {
+	l.Lock()
+	max := GetConfig().MaxConcurrentScans
+	if max <= 0 || (l.running < max && len(l.waiting) == 0) {
+		l.running++
+		l.Unlock()
+		log.Infof("[%s] Scan started", name)
+		return l.release, nil
+	}
+
+	ch := make(chan struct{})
+	l.waiting = append(l.waiting, ch)
+	log.Infof("[%s] Scan queued (%d running, %d waiting)", name, l.running, len(l.waiting))
+	l.Unlock()
+
+	select {
+	case <-ch:
+		log.Infof("[%s] Scan started", name)
+		return l.release, nil
+	case <-stop:
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	for i, c := range l.waiting {
+		if c == ch {
+			l.waiting = append(l.waiting[:i], l.waiting[i+1:]...)
+			return nil, ErrScanAborted
+		}
+	}
+	// The slot was handed over in the meantime
+	l.releaseLocked()
+	return nil, ErrScanAborted
+}
+
+func (l *scanLimiter) release() {
+	l.Lock()
+	l.releaseLocked()
+	l.Unlock()
+}
+
+func (l *scanLimiter) releaseLocked() {
+	max := GetConfig().MaxConcurrentScans
+	if len(l.waiting) > 0 && (max <= 0 || l.running <= max) {
+		// Hand the slot over to the next scan in the queue
+		close(l.waiting[0])
+		l.waiting = l.waiting[1:]
+		return
+	}
+	l.running--
+}
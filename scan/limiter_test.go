@@ -0,0 +1,156 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package scan
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/etix/mirrorbits/config"
+)
+
+// acquireAsync acquires a slot in the background once the previous waiters
+// are queued, the result being sent on the returned channel
+func acquireAsync(t *testing.T, l *scanLimiter, name string, stop <-chan struct{}) <-chan error {
+	t.Helper()
+	l.Lock()
+	queued := len(l.waiting)
+	l.Unlock()
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := l.acquire(name, stop)
+		result <- err
+	}()
+
+	// Wait for the scan to be queued
+	for deadline := time.Now().Add(time.Second); ; {
+		l.Lock()
+		n := len(l.waiting)
+		l.Unlock()
+		if n > queued {
+			return result
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("[%s] not queued", name)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func expectPending(t *testing.T, name string, result <-chan error) {
+	t.Helper()
+	select {
+	case err := <-result:
+		t.Fatalf("[%s] unexpectedly started: %v", name, err)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func expectResult(t *testing.T, name string, result <-chan error, expected error) {
+	t.Helper()
+	select {
+	case err := <-result:
+		if err != expected {
+			t.Fatalf("[%s] expected %v, got %v", name, expected, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("[%s] still waiting", name)
+	}
+}
+
+func TestScanLimiterOrder(t *testing.T) {
+	defer SetConfiguration(GetConfig())
+	SetConfiguration(&Configuration{MaxConcurrentScans: 1})
+
+	l := &scanLimiter{}
+	release, err := l.acquire("a", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	b := acquireAsync(t, l, "b", nil)
+	c := acquireAsync(t, l, "c", nil)
+	expectPending(t, "b", b)
+
+	// The slots are handed over in the queue order
+	release()
+	expectResult(t, "b", b, nil)
+	expectPending(t, "c", c)
+
+	l.release()
+	expectResult(t, "c", c, nil)
+
+	l.release()
+	if l.running != 0 || len(l.waiting) != 0 {
+		t.Fatalf("Expected no scan, got %d running and %d waiting", l.running, len(l.waiting))
+	}
+}
+
+func TestScanLimiterUnlimited(t *testing.T) {
+	defer SetConfiguration(GetConfig())
+	SetConfiguration(&Configuration{MaxConcurrentScans: 0})
+
+	l := &scanLimiter{}
+	for i := 0; i < 3; i++ {
+		if _, err := l.acquire("a", nil); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+	}
+	if l.running != 3 {
+		t.Fatalf("Expected 3 scans running, got %d", l.running)
+	}
+}
+
+func TestScanLimiterCancel(t *testing.T) {
+	defer SetConfiguration(GetConfig())
+	SetConfiguration(&Configuration{MaxConcurrentScans: 1})
+
+	l := &scanLimiter{}
+	release, _ := l.acquire("a", nil)
+
+	stop := make(chan struct{})
+	b := acquireAsync(t, l, "b", stop)
+	c := acquireAsync(t, l, "c", nil)
+
+	// A scan aborted while waiting leaves the queue
+	close(stop)
+	expectResult(t, "b", b, ErrScanAborted)
+	l.Lock()
+	waiting := len(l.waiting)
+	l.Unlock()
+	if waiting != 1 {
+		t.Fatalf("Expected 1 scan waiting, got %d", waiting)
+	}
+
+	release()
+	expectResult(t, "c", c, nil)
+}
+
+func TestScanLimiterReleaseAfterCancel(t *testing.T) {
+	defer SetConfiguration(GetConfig())
+	SetConfiguration(&Configuration{MaxConcurrentScans: 1})
+
+	l := &scanLimiter{}
+	l.acquire("a", nil)
+
+	stop := make(chan struct{})
+	b := acquireAsync(t, l, "b", stop)
+
+	// The slot is handed over to b while it is being aborted
+	l.Lock()
+	close(stop)
+	time.Sleep(10 * time.Millisecond)
+	l.releaseLocked()
+	l.Unlock()
+	expectResult(t, "b", b, ErrScanAborted)
+
+	// b gave the slot back
+	if l.running != 0 || len(l.waiting) != 0 {
+		t.Fatalf("Expected no scan, got %d running and %d waiting", l.running, len(l.waiting))
+	}
+	if _, err := l.acquire("c", nil); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+}
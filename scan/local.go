@@ -0,0 +1,142 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package scan
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/etix/mirrorbits/core"
+	"github.com/etix/mirrorbits/utils"
+	"github.com/gomodule/redigo/redis"
+)
+
+// LocalScanner is the implementation of a scanner of a mirror living on
+// the same host, walking its directory instead of listing it over the
+// network. The symbolic links are skipped unless LocalScanFollowSymlinks is
+// set.
+type LocalScanner struct {
+	scan *scan
+
+	follow  bool
+	visited map[string]bool
+}
+
+// Scan walks the directory of the given file:// URL
+func (l *LocalScanner) Scan(scanurl, identifier string, conn redis.Conn, stop <-chan struct{}) (core.Precision, error) {
+	if !strings.HasPrefix(scanurl, "file://") {
+		return 0, fmt.Errorf("%s does not start with file://", scanurl)
+	}
+
+	u, err := url.Parse(scanurl)
+	if err != nil {
+		return 0, err
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return 0, fmt.Errorf("%s is not on this host", scanurl)
+	}
+	root := filepath.Clean(u.Path)
+
+	// The files are hashed from the disk during the cross-check
+	l.scan.localRoot = root
+
+	dir := root
+	if l.scan.remoteOnly != "" {
+		dir = filepath.Join(root, filepath.FromSlash(l.scan.remoteOnly))
+	}
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return 0, err
+	}
+	if !fi.IsDir() {
+		return 0, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	l.follow = GetConfig().LocalScanFollowSymlinks
+	l.visited = make(map[string]bool)
+	if real, err := filepath.EvalSymlinks(dir); err == nil {
+		l.visited[real] = true
+	}
+
+	log.Infof("[%s] Walking the local directory %s...", identifier, dir)
+
+	if err = l.walk(dir, l.scan.remoteOnly, identifier, stop); err != nil {
+		return 0, err
+	}
+
+	// Both sides come from a local filesystem, but the copies may not
+	// preserve the sub-second part of the mod times
+	return core.Precision(time.Second), nil
+}
+
+// walk adds the files found under the directory, rel being its path
+// relative to the root of the mirror
+func (l *LocalScanner) walk(dir, rel, identifier string, stop <-chan struct{}) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if rel == l.scan.remoteOnly {
+			return err
+		}
+		log.Warningf("[%s] Local scan: %s", identifier, err)
+		return nil
+	}
+
+	for _, e := range entries {
+		if utils.IsStopped(stop) {
+			return ErrScanAborted
+		}
+
+		// Skip the temporary directory of rsync, as the rsync scans do
+		if e.Name() == ".~tmp~" {
+			continue
+		}
+
+		full := filepath.Join(dir, e.Name())
+		fi, err := e.Info()
+		if err != nil {
+			// Removed in the meantime
+			continue
+		}
+
+		if fi.Mode()&os.ModeSymlink != 0 {
+			if !l.follow {
+				continue
+			}
+			if fi, err = os.Stat(full); err != nil {
+				log.Debugf("[%s] Local scan: skipping the broken link %s", identifier, full)
+				continue
+			}
+		}
+
+		if fi.IsDir() {
+			real, err := filepath.EvalSymlinks(full)
+			if err != nil || l.visited[real] {
+				// Loop of symbolic links
+				continue
+			}
+			l.visited[real] = true
+			if err := l.walk(full, path.Join(rel, e.Name()), identifier, stop); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !fi.Mode().IsRegular() {
+			continue
+		}
+
+		l.scan.ScannerAddFile(filedata{
+			path:    path.Join("/", rel, e.Name()),
+			size:    fi.Size(),
+			modTime: fi.ModTime().UTC(),
+		})
+	}
+	return nil
+}
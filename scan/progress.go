@@ -0,0 +1,74 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package scan
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/etix/mirrorbits/core"
+)
+
+// Phases of a scan
+const (
+	PhaseEnumerating = "enumerating"
+	PhaseIndexing    = "indexing"
+)
+
+// Progress reports the state of a scan running in this process
+type Progress struct {
+	Protocol string
+	Phase    string
+	Started  time.Time
+	Files    int64
+}
+
+var running = struct {
+	sync.Mutex
+	scans map[int]*scan
+}{scans: make(map[int]*scan)}
+
+// ScanProgress returns the progress of the scan of the given mirror, ok is
+// false if the mirror is not being scanned by this process
+func ScanProgress(id int) (p Progress, ok bool) {
+	running.Lock()
+	s, ok := running.scans[id]
+	running.Unlock()
+	if !ok {
+		return p, false
+	}
+	s.progressLock.Lock()
+	p = s.progress
+	s.progressLock.Unlock()
+	p.Files = atomic.LoadInt64(&s.count)
+	return p, true
+}
+
+// track registers the scan so its progress can be queried, the returned
+// function must be called once the scan is done
+func (s *scan) track(typ core.ScannerType) func() {
+	s.progress = Progress{
+		Protocol: scannerName(typ),
+		Phase:    PhaseEnumerating,
+		Started:  time.Now(),
+	}
+	running.Lock()
+	running.scans[s.mirrorid] = s
+	running.Unlock()
+	return func() {
+		running.Lock()
+		if running.scans[s.mirrorid] == s {
+			delete(running.scans, s.mirrorid)
+		}
+		running.Unlock()
+	}
+}
+
+// setPhase updates the phase reported by the progress of the scan
+func (s *scan) setPhase(phase string) {
+	s.progressLock.Lock()
+	s.progress.Phase = phase
+	s.progressLock.Unlock()
+}
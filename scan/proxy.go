@@ -0,0 +1,108 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package scan
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// dialFunc opens a connection to the given address
+type dialFunc func(network, address string) (net.Conn, error)
+
+// proxyDialFunc returns the function opening the connections through the
+// given proxy, or directly if proxyURL is nil. The http and https proxies
+// are used as tunnels (CONNECT method). Each step times out after timeout.
+func proxyDialFunc(proxyURL *url.URL, timeout time.Duration) (dialFunc, error) {
+	direct := &net.Dialer{Timeout: timeout}
+	if proxyURL == nil {
+		return direct.Dial, nil
+	}
+
+	switch proxyURL.Scheme {
+	case "socks5":
+		dialer, err := proxy.FromURL(proxyURL, direct)
+		if err != nil {
+			return nil, err
+		}
+		return dialer.Dial, nil
+	case "http", "https":
+		return func(network, address string) (net.Conn, error) {
+			return dialConnect(proxyURL, direct, network, address, timeout)
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported proxy scheme %s", proxyURL.Scheme)
+}
+
+// dialConnect opens a tunnel to the address through the http(s) proxy
+func dialConnect(proxyURL *url.URL, direct *net.Dialer, network, address string, timeout time.Duration) (net.Conn, error) {
+	proxyAddr := proxyURL.Host
+	if proxyURL.Port() == "" {
+		port := "80"
+		if proxyURL.Scheme == "https" {
+			port = "443"
+		}
+		proxyAddr = net.JoinHostPort(proxyURL.Hostname(), port)
+	}
+
+	c, err := direct.Dial(network, proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL.Scheme == "https" {
+		c = tls.Client(c, &tls.Config{ServerName: proxyURL.Hostname()})
+	}
+	c.SetDeadline(time.Now().Add(timeout))
+
+	req := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: http.Header{},
+	}
+	if u := proxyURL.User; u != nil {
+		password, _ := u.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(u.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err = req.Write(c); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	// The server may talk first (e.g. the FTP greeting), the bytes read
+	// past the response of the proxy must be kept
+	br := bufio.NewReader(c)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		c.Close()
+		return nil, fmt.Errorf("proxy %s refused the connection to %s: %s", proxyURL.Host, address, resp.Status)
+	}
+
+	c.SetDeadline(time.Time{})
+	return &bufferedConn{Conn: c, r: br}, nil
+}
+
+// bufferedConn is a net.Conn whose first reads are served from a buffer
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (b *bufferedConn) Read(buf []byte) (int, error) {
+	return b.r.Read(buf)
+}
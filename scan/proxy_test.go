@@ -0,0 +1,108 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package scan
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// connectProxy accepts a single CONNECT request, hands it to check and
+// writes the given reply in one go
+func connectProxy(t *testing.T, reply string, check func(*http.Request)) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		req, err := http.ReadRequest(bufio.NewReader(c))
+		if err != nil {
+			t.Errorf("Invalid request: %s", err)
+			return
+		}
+		check(req)
+		io.WriteString(c, reply)
+		// Leave the client time to read before closing
+		c.SetReadDeadline(time.Now().Add(time.Second))
+		c.Read(make([]byte, 1))
+	}()
+	return l.Addr().String()
+}
+
+func TestDialConnect(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	addr := connectProxy(t, "HTTP/1.1 200 Connection established\r\n\r\n220 FTP server ready\r\n", func(r *http.Request) {
+		requests <- r
+	})
+
+	proxyURL, _ := url.Parse("http://user:p%40ss:word@" + addr)
+	dial, err := proxyDialFunc(proxyURL, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := dial("tcp", "ftp.example.org:21")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer c.Close()
+
+	// The greeting sent along with the reply of the proxy isn't lost
+	greeting, err := bufio.NewReader(c).ReadString('\n')
+	if err != nil || greeting != "220 FTP server ready\r\n" {
+		t.Fatalf("Expected the FTP greeting, got %q (%v)", greeting, err)
+	}
+
+	got := <-requests
+	if got.Method != "CONNECT" || got.Host != "ftp.example.org:21" || got.RequestURI != "ftp.example.org:21" {
+		t.Fatalf("Unexpected request %s %s (Host %s)", got.Method, got.RequestURI, got.Host)
+	}
+	credentials := base64.StdEncoding.EncodeToString([]byte("user:p@ss:word"))
+	if auth := got.Header.Get("Proxy-Authorization"); auth != "Basic "+credentials {
+		t.Fatalf("Unexpected Proxy-Authorization %q", auth)
+	}
+}
+
+func TestDialConnectRefused(t *testing.T) {
+	addr := connectProxy(t, "HTTP/1.1 407 Proxy Authentication Required\r\nContent-Length: 0\r\n\r\n", func(r *http.Request) {
+		if auth := r.Header.Get("Proxy-Authorization"); auth != "" {
+			t.Errorf("Unexpected Proxy-Authorization %q", auth)
+		}
+	})
+
+	proxyURL, _ := url.Parse("http://" + addr)
+	dial, err := proxyDialFunc(proxyURL, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := dial("tcp", "ftp.example.org:21")
+	if err == nil {
+		c.Close()
+		t.Fatalf("Expected an error")
+	}
+	if !strings.Contains(err.Error(), "407") {
+		t.Fatalf("Expected the status of the proxy in the error, got %s", err)
+	}
+}
+
+func TestProxyDialFuncUnsupported(t *testing.T) {
+	proxyURL, _ := url.Parse("ftp://proxy.example")
+	if _, err := proxyDialFunc(proxyURL, time.Second); err == nil {
+		t.Fatalf("Expected an error for an unsupported scheme")
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package scan
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/etix/mirrorbits/core"
+	"github.com/etix/mirrorbits/utils"
+)
+
+// ErrTransient is returned (wrapped) by the scanners when the enumeration
+// failed on an error that may not happen again, such as a network error
+var ErrTransient = errors.New("transient error")
+
+// enumerate lists the files of the mirror with the given scanner. An
+// enumeration failing on a transient error is started over up to ScanRetries
+// times, waiting ScanRetryBackoff seconds doubled after each attempt. The
+// waits are interrupted as soon as stop is closed, as the scan is then
+// aborted or timed out.
+func (s *scan) enumerate(scanner Scanner, url, name string, stop <-chan struct{}) (core.Precision, error) {
+	retries := GetConfig().ScanRetries
+	backoff := time.Duration(GetConfig().ScanRetryBackoff) * time.Second
+
+	for attempt := 0; ; attempt++ {
+		if !s.dryRun {
+			s.conn.Send("MULTI")
+			// Remove any left over
+			s.conn.Send("DEL", s.filesTmpKey)
+		}
+
+		scanStop, release := s.limitFiles(stop)
+		precision, err := scanner.Scan(url, name, s.conn, scanStop)
+		release()
+		err = s.checkFileCap(name, err, stop)
+		if err == nil {
+			if attempt > 0 {
+				log.Noticef("[%s] Enumeration succeeded after %d retries", name, attempt)
+			}
+			return precision, nil
+		}
+
+		if !s.dryRun {
+			// Discard MULTI
+			s.ScannerDiscard()
+		}
+
+		if !isTransient(err) || utils.IsStopped(stop) {
+			return 0, err
+		}
+		if attempt >= retries {
+			if retries > 0 {
+				log.Errorf("[%s] Enumeration failed after %d retries", name, retries)
+			}
+			return 0, err
+		}
+
+		// Start over from scratch
+		atomic.StoreInt64(&s.count, 0)
+		s.found = nil
+
+		wait := backoff << attempt
+		log.Warningf("[%s] Transient error, retrying in %s (%d/%d): %s", name, wait, attempt+1, retries, err)
+		select {
+		case <-time.After(wait):
+		case <-stop:
+			return 0, ErrScanAborted
+		}
+	}
+}
+
+// isTransient returns true if the error may not happen on a new attempt
+func isTransient(err error) bool {
+	if errors.Is(err, ErrTransient) || errors.Is(err, ErrScanTimeout) {
+		return true
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ETIMEDOUT) ||
+		errors.Is(err, syscall.EHOSTUNREACH) ||
+		errors.Is(err, syscall.ENETUNREACH) {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package scan
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+
+	. "github.com/etix/mirrorbits/config"
+	"github.com/etix/mirrorbits/core"
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := map[string]struct {
+		err      error
+		expected bool
+	}{
+		"transient":          {fmt.Errorf("%w: rsync: Error in socket I/O", ErrTransient), true},
+		"timeout":            {fmt.Errorf("%w: rsync: Timeout in data send/receive", ErrScanTimeout), true},
+		"unexpected eof":     {io.ErrUnexpectedEOF, true},
+		"connection refused": {&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, true},
+		"connection reset":   {&net.OpError{Op: "read", Err: syscall.ECONNRESET}, true},
+		"dns timeout":        {&net.DNSError{Err: "timeout", IsTimeout: true}, true},
+		"unknown host":       {&net.DNSError{Err: "no such host", IsNotFound: true}, false},
+		"protocol error":     {errors.New("rsync: Error starting client-server protocol"), false},
+		"aborted":            {ErrScanAborted, false},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if transient := isTransient(test.err); transient != test.expected {
+				t.Fatalf("Expected %t, got %t", test.expected, transient)
+			}
+		})
+	}
+}
+
+// failingScanner fails with the given errors, one per attempt, before
+// succeeding
+type failingScanner struct {
+	errors   []error
+	attempts int
+}
+
+func (f *failingScanner) Scan(url, identifier string, conn redis.Conn, stop <-chan struct{}) (core.Precision, error) {
+	f.attempts++
+	if len(f.errors) == 0 {
+		return core.Precision(0), nil
+	}
+	err := f.errors[0]
+	f.errors = f.errors[1:]
+	return 0, err
+}
+
+func TestEnumerate(t *testing.T) {
+	transient := fmt.Errorf("%w: rsync: Error in socket I/O", ErrTransient)
+	permanent := errors.New("rsync: Error starting client-server protocol")
+
+	tests := map[string]struct {
+		errors   []error
+		retries  int
+		attempts int
+		err      error
+	}{
+		"success":           {nil, 2, 1, nil},
+		"retried":           {[]error{transient, transient}, 2, 3, nil},
+		"retries exhausted": {[]error{transient, transient, transient}, 2, 3, transient},
+		"no retries":        {[]error{transient}, 0, 1, transient},
+		"permanent":         {[]error{permanent}, 2, 1, permanent},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer SetConfiguration(GetConfig())
+			SetConfiguration(&Configuration{
+				ScanRetries:      test.retries,
+				ScanRetryBackoff: 0,
+			})
+
+			s := &scan{dryRun: true}
+			scanner := &failingScanner{errors: test.errors}
+			_, err := s.enumerate(scanner, "rsync://mirror.example/", "m1", make(chan struct{}))
+			if err != test.err {
+				t.Fatalf("Expected %v, got %v", test.err, err)
+			}
+			if scanner.attempts != test.attempts {
+				t.Fatalf("Expected %d attempts, got %d", test.attempts, scanner.attempts)
+			}
+		})
+	}
+}
+
+func TestEnumerateStopped(t *testing.T) {
+	defer SetConfiguration(GetConfig())
+	SetConfiguration(&Configuration{
+		ScanRetries:      2,
+		ScanRetryBackoff: 3600,
+	})
+
+	stop := make(chan struct{})
+	close(stop)
+
+	s := &scan{dryRun: true}
+	transient := fmt.Errorf("%w: timeout", ErrTransient)
+	scanner := &failingScanner{errors: []error{transient}}
+	if _, err := s.enumerate(scanner, "rsync://mirror.example/", "m1", stop); err != transient {
+		t.Fatalf("Expected %v, got %v", transient, err)
+	}
+	if scanner.attempts != 1 {
+		t.Fatalf("Expected no retry once stopped, got %d attempts", scanner.attempts)
+	}
+}
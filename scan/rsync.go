@@ -13,8 +13,10 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	. "github.com/etix/mirrorbits/config"
 	"github.com/etix/mirrorbits/core"
 	"github.com/etix/mirrorbits/utils"
 	"github.com/gomodule/redigo/redis"
@@ -64,16 +66,22 @@ func (r *RsyncScanner) Scan(rsyncURL, identifier string, conn redis.Conn, stop <
 		u.User = nil
 	}
 
+	// Only list the requested subtree
+	if r.scan.remoteOnly != "" {
+		u.Path = strings.TrimRight(u.Path, "/") + r.scan.remoteOnly + "/"
+	}
+
 	// Don't use the local timezone, use UTC
 	env = append(env, "TZ=UTC")
 
 	args := []string{"-r", "--no-motd", "--exclude=.~tmp~/"}
+	connTimeout, readTimeout := rsyncTimeouts(cmdName)
 	// rsync-ssl does not support --contimeout, but from what I see --timeout covers both and
 	// triggers if openssl takes too long to connect. So use a longer combined timeout there.
 	if cmdName == "rsync-ssl" {
-		args = append(args, "--timeout=60")
+		args = append(args, fmt.Sprintf("--timeout=%d", connTimeout+readTimeout))
 	} else {
-		args = append(args, "--timeout=30", "--contimeout=30")
+		args = append(args, fmt.Sprintf("--timeout=%d", readTimeout), fmt.Sprintf("--contimeout=%d", connTimeout))
 	}
 	args = append(args, u.String())
 
@@ -107,20 +115,44 @@ func (r *RsyncScanner) Scan(rsyncURL, identifier string, conn redis.Conn, stop <
 
 	log.Infof("[%s] Requesting file list via %s...", identifier, cmdName)
 
+	// Kill rsync if it stops sending data for longer than its own
+	// timeouts, in case it hangs without noticing
+	idleTimeout := 2 * time.Duration(connTimeout+readTimeout) * time.Second
+	watchdog := time.NewTimer(idleTimeout)
+	defer watchdog.Stop()
+	activity := make(chan struct{}, 1)
+	var timedOut int32
+
 	scanfinished := make(chan bool)
 	go func() {
-		select {
-		case <-stop:
-			cmd.Process.Kill()
-			return
-		case <-scanfinished:
-			return
+		for {
+			select {
+			case <-stop:
+				cmd.Process.Kill()
+				return
+			case <-activity:
+				if !watchdog.Stop() {
+					<-watchdog.C
+				}
+				watchdog.Reset(idleTimeout)
+			case <-watchdog.C:
+				atomic.StoreInt32(&timedOut, 1)
+				cmd.Process.Kill()
+				return
+			case <-scanfinished:
+				return
+			}
 		}
 	}()
 	defer close(scanfinished)
 
 	line, err := readln(reader)
 	for err == nil {
+		select {
+		case activity <- struct{}{}:
+		default:
+		}
+
 		var size int64
 		var f filedata
 		var modTime time.Time
@@ -162,7 +194,7 @@ func (r *RsyncScanner) Scan(rsyncURL, identifier string, conn redis.Conn, stop <
 		// Fill the struct
 		f.size = size
 		f.modTime = modTime
-		f.path = ret[4]
+		f.path = r.scan.remoteOnly + ret[4]
 
 		r.scan.ScannerAddFile(f)
 
@@ -178,13 +210,19 @@ func (r *RsyncScanner) Scan(rsyncURL, identifier string, conn redis.Conn, stop <
 	}
 
 	if err1 := cmd.Wait(); err1 != nil {
+		if atomic.LoadInt32(&timedOut) == 1 {
+			return 0, fmt.Errorf("%w: rsync: no data received for %s", ErrScanTimeout, idleTimeout)
+		}
 		switch err1.Error() {
 		case "exit status 5":
+			// Also returned for an unknown module or a denied access
 			err1 = errors.New("rsync: Error starting client-server protocol")
 		case "exit status 10":
-			err1 = errors.New("rsync: Error in socket I/O")
+			err1 = fmt.Errorf("%w: rsync: Error in socket I/O", ErrTransient)
 		case "exit status 11":
 			err1 = errors.New("rsync: Error in file I/O")
+		case "exit status 12":
+			err1 = fmt.Errorf("%w: rsync: Error in rsync protocol data stream", ErrTransient)
 		case "exit status 23":
 			for _, line := range rsyncErrors {
 				log.Warningf("[%s] %s", identifier, line)
@@ -192,9 +230,9 @@ func (r *RsyncScanner) Scan(rsyncURL, identifier string, conn redis.Conn, stop <
 			log.Warningf("[%s] rsync: Partial transfer due to error", identifier)
 			err1 = nil
 		case "exit status 30":
-			err1 = errors.New("rsync: Timeout in data send/receive")
+			err1 = fmt.Errorf("%w: rsync: Timeout in data send/receive", ErrScanTimeout)
 		case "exit status 35":
-			err1 = errors.New("Timeout waiting for daemon connection")
+			err1 = fmt.Errorf("%w: rsync: Timeout waiting for daemon connection", ErrScanTimeout)
 		default:
 			if utils.IsStopped(stop) {
 				err1 = ErrScanAborted
@@ -212,6 +250,22 @@ func (r *RsyncScanner) Scan(rsyncURL, identifier string, conn redis.Conn, stop <
 	return core.Precision(time.Second), nil
 }
 
+// rsyncTimeouts returns the connection and I/O timeouts in seconds
+func rsyncTimeouts(cmdName string) (connTimeout, readTimeout int) {
+	connTimeout, readTimeout = 30, 30
+	if cmdName == "rsync-ssl" {
+		// Keep the historical combined timeout of 60 seconds
+		connTimeout, readTimeout = 0, 60
+	}
+	if t := GetConfig().RsyncConnectTimeout; t > 0 {
+		connTimeout = t
+	}
+	if t := GetConfig().RsyncReadTimeout; t > 0 {
+		readTimeout = t
+	}
+	return
+}
+
 func readln(r *bufio.Reader) (string, error) {
 	var (
 		isPrefix = true
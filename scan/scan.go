@@ -4,17 +4,24 @@
 package scan
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	. "github.com/etix/mirrorbits/config"
 	"github.com/etix/mirrorbits/core"
 	"github.com/etix/mirrorbits/database"
 	"github.com/etix/mirrorbits/filesystem"
+	"github.com/etix/mirrorbits/metrics"
 	"github.com/etix/mirrorbits/mirrors"
 	"github.com/etix/mirrorbits/network"
 	"github.com/etix/mirrorbits/utils"
@@ -29,6 +36,13 @@ var (
 	ErrScanInProgress = errors.New("scan already in progress")
 	// ErrNoSyncMethod is returned when no sync protocol is available
 	ErrNoSyncMethod = errors.New("no suitable URL for the scan")
+	// ErrScanTimeout is returned (wrapped) when a scan is aborted after a timeout
+	ErrScanTimeout = errors.New("scan timeout")
+	// ErrInvalidScanPath is returned when the subtree to scan is not a valid relative path
+	ErrInvalidScanPath = errors.New("invalid scan path")
+	// ErrOutsidePrefix is returned when the subtree to scan is outside of
+	// the directory of the virtual host of the mirror
+	ErrOutsidePrefix = errors.New("scan path outside of the virtual host of the mirror")
 
 	log = logging.MustGetLogger("main")
 )
@@ -42,9 +56,21 @@ type filedata struct {
 	path    string
 	sha1    string
 	sha256  string
+	sha512  string
 	md5     string
 	size    int64
 	modTime time.Time
+	// pieces are the torrent piece hashes of the file (local repository
+	// only), left untouched in the database when keepPieces is set
+	pieces      []byte
+	pieceLength int
+	keepPieces  bool
+	// zsync are the block checksums of the zsync control file of the
+	// file (local repository only), left untouched when keepZsync is set
+	zsync          []byte
+	zsyncSha1      string
+	zsyncBlockSize int
+	keepZsync      bool
 }
 
 type scan struct {
@@ -55,6 +81,32 @@ type scan struct {
 	mirrorid    int
 	filesTmpKey string
 	count       int64
+	// only is the subtree being scanned (e.g. "/some/dir"), empty
+	// when the whole mirror is scanned
+	only string
+	// prefix is the directory of the repository the root of the mirror
+	// maps to when it serves a virtual host, remoteOnly being the scanned
+	// subtree relative to the root of the mirror
+	prefix     string
+	remoteOnly string
+	// localRoot is the directory of the mirror when it is scanned from
+	// the local filesystem, its files being hashed from the disk
+	localRoot string
+	// dryRun collects the files in found instead of indexing them
+	dryRun bool
+	found  []filedata
+	// cap is the maximum number of files of the scan
+	cap fileCap
+	// mismatched are the files of the mirror not matching the reference
+	// hashes, kept out of the index when CrossCheckExclude is set
+	mismatched map[string]struct{}
+	// forceRehash makes the cross-check hash all the files again
+	forceRehash bool
+	// precision is the precision of the modification times of the scan
+	precision core.Precision
+
+	progressLock sync.Mutex
+	progress     Progress
 }
 
 type ScanResult struct {
@@ -63,7 +115,13 @@ type ScanResult struct {
 	FilesIndexed int64
 	KnownIndexed int64
 	Removed      int64
+	Suspect      int64
 	TZOffsetMs   int64
+	Mismatches   int64
+	// MaxFiles is the cap on the number of files of the scan, 0 if none
+	MaxFiles int64
+	// Truncated is set when the files beyond MaxFiles were left out
+	Truncated bool
 }
 
 // IsScanning returns true is a scan is already in progress for the given mirror
@@ -73,36 +131,43 @@ func IsScanning(conn redis.Conn, id int) (bool, error) {
 
 // Scan starts a scan of the given mirror
 func Scan(typ core.ScannerType, r *database.Redis, c *mirrors.Cache, url string, id int, stop <-chan struct{}) (*ScanResult, error) {
+	return ScanPath(typ, r, c, url, id, "", false, stop)
+}
+
+// ScanPath starts a scan of the given subtree of the mirror. Only the files
+// found under this path are updated in the index of the mirror, the others
+// are left untouched. An empty path scans the whole mirror. When forceRehash
+// is set the cross-check hashes all the files again, regardless of their
+// modification time and of CrossCheckMaxFiles.
+func ScanPath(typ core.ScannerType, r *database.Redis, c *mirrors.Cache, url string, id int, only string, forceRehash bool, stop <-chan struct{}) (*ScanResult, error) {
+	only, err := CleanScanPath(only)
+	if err != nil {
+		return nil, err
+	}
+
 	// Connect to the database
 	conn := r.Get()
 	defer conn.Close()
 
 	s := &scan{
-		redis:    r,
-		mirrorid: id,
-		conn:     conn,
-		cache:    c,
+		redis:       r,
+		mirrorid:    id,
+		conn:        conn,
+		cache:       c,
+		only:        only,
+		forceRehash: forceRehash,
 	}
 
-	var scanner Scanner
-	switch typ {
-	case core.RSYNC:
-		scanner = &RsyncScanner{
-			scan: s,
-		}
-	case core.FTP:
-		scanner = &FTPScanner{
-			scan: s,
-		}
-	default:
-		panic(fmt.Sprintf("Unknown scanner"))
-	}
+	scanner := newScanner(typ, s)
 
 	// Get the mirror name
 	name, err := redis.String(conn.Do("HGET", "MIRRORS", id))
 	if err != nil {
 		return nil, err
 	}
+	if err = s.setPrefix(name); err != nil {
+		return nil, err
+	}
 
 	// Try to acquire a lock so we don't have a scanning race
 	// from different nodes.
@@ -118,8 +183,22 @@ func Scan(typ core.ScannerType, r *database.Redis, c *mirrors.Cache, url string,
 	}
 
 	defer lock.Release()
+	defer s.track(typ)()
 
-	s.setLastSync(conn, id, typ, 0, false)
+	// A partial scan says nothing about the synchronization
+	// state of the whole mirror
+	if only == "" {
+		s.setLastSync(conn, id, typ, 0, false)
+	}
+
+	start := time.Now()
+	defer func(err *error) {
+		result := "success"
+		if err != nil && *err != nil {
+			result = "failure"
+		}
+		metrics.ScanDuration.Observe(time.Since(start).Seconds(), name, scannerName(typ), result)
+	}(&err)
 
 	mirrors.PushLog(r, mirrors.NewLogScanStarted(id, typ))
 	defer func(err *error) {
@@ -128,28 +207,33 @@ func Scan(typ core.ScannerType, r *database.Redis, c *mirrors.Cache, url string,
 		}
 	}(&err)
 
-	conn.Send("MULTI")
+	if GetConfig().CrossCheckHashes {
+		s.mismatched, err = s.loadMismatches()
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	filesKey := fmt.Sprintf("MIRRORFILES_%d", id)
 	s.filesTmpKey = fmt.Sprintf("MIRRORFILESTMP_%d", id)
 
-	// Remove any left over
-	conn.Send("DEL", s.filesTmpKey)
-
 	var precision core.Precision
-	precision, err = scanner.Scan(url, name, conn, stop)
+	precision, err = s.enumerate(scanner, url, name, stop)
+	s.precision = precision
 	if err != nil {
-		// Discard MULTI
-		s.ScannerDiscard()
-
 		// Remove the temporary key
 		conn.Do("DEL", s.filesTmpKey)
 
-		log.Errorf("[%s] %s", name, err.Error())
+		if errors.Is(err, ErrScanTimeout) {
+			log.Errorf("[%s] Scan timed out: %s", name, err.Error())
+		} else {
+			log.Errorf("[%s] %s", name, err.Error())
+		}
 		return nil, err
 	}
 
 	log.Infof("[%s] Indexing the files...", name)
+	s.setPhase(PhaseIndexing)
 
 	// Exec multi
 	s.ScannerCommit()
@@ -161,11 +245,26 @@ func Scan(typ core.ScannerType, r *database.Redis, c *mirrors.Cache, url string,
 		return nil, err
 	}
 
+	if only != "" {
+		// Only consider the files of the scanned subtree
+		toremove = filterPrefix(toremove, only+"/")
+	}
+
+	// Keep the files missing for less than StaleFileGracePeriod
+	var suspect int64
+	toremove, suspect, err = s.holdSuspects(conn, name, toremove)
+	if err != nil {
+		return nil, err
+	}
+
 	// Remove this mirror from the given file SET
 	if len(toremove) > 0 {
 		conn.Send("MULTI")
 		for _, e := range toremove {
 			log.Debugf("[%s] Removing %s from mirror", name, e)
+			if only != "" {
+				conn.Send("SREM", filesKey, e)
+			}
 			conn.Send("SREM", fmt.Sprintf("FILEMIRRORS_%s", e), id)
 			conn.Send("DEL", fmt.Sprintf("FILEINFO_%d_%s", id, e))
 			// Publish update
@@ -180,7 +279,16 @@ func Scan(typ core.ScannerType, r *database.Redis, c *mirrors.Cache, url string,
 
 	// Finally rename the temporary sets containing the list
 	// of files for this mirror to the production key
-	if s.count > 0 {
+	if only != "" {
+		// Merge the files of the subtree with the rest of the index
+		conn.Send("MULTI")
+		conn.Send("SUNIONSTORE", filesKey, filesKey, s.filesTmpKey)
+		conn.Send("DEL", s.filesTmpKey)
+		_, err = conn.Do("EXEC")
+		if err != nil {
+			return nil, err
+		}
+	} else if s.count > 0 {
 		_, err = conn.Do("RENAME", s.filesTmpKey, filesKey)
 		if err != nil {
 			return nil, err
@@ -196,22 +304,42 @@ func Scan(typ core.ScannerType, r *database.Redis, c *mirrors.Cache, url string,
 		return nil, err
 	}
 
-	s.setLastSync(conn, id, typ, precision, true)
+	var mismatches int64
+	if GetConfig().CrossCheckHashes {
+		mismatches, err = s.crossCheckHashes(name, stop)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	var tzoffset int64
-	tzoffset, err = s.adjustTZOffset(name, precision)
-	if err != nil {
-		log.Warningf("Unable to check timezone shifts: %s", err)
+	if only == "" {
+		s.setLastSync(conn, id, typ, precision, true)
+
+		tzoffset, err = s.adjustTZOffset(name, precision)
+		if err != nil {
+			log.Warningf("Unable to check timezone shifts: %s", err)
+		}
+
+		if err := s.detectIPVersions(name); err != nil {
+			log.Warningf("[%s] Unable to detect the IP versions: %s", name, err)
+		}
+		log.Infof("[%s] Indexed %d files%s (%d known), %d removed, %d suspect", name, s.count, s.cap.summary(), common, len(toremove), suspect)
+	} else {
+		log.Infof("[%s] Indexed %d files%s under %s (%d known in total), %d removed, %d suspect", name, s.count, s.cap.summary(), only, common, len(toremove), suspect)
 	}
 
-	log.Infof("[%s] Indexed %d files (%d known), %d removed", name, s.count, common, len(toremove))
 	res := &ScanResult{
 		MirrorID:     id,
 		MirrorName:   name,
 		FilesIndexed: s.count,
 		KnownIndexed: common,
 		Removed:      int64(len(toremove)),
+		Suspect:      suspect,
 		TZOffsetMs:   tzoffset,
+		Mismatches:   mismatches,
+		MaxFiles:     s.cap.max,
+		Truncated:    s.cap.exceeded,
 	}
 
 	mirrors.PushLog(r, mirrors.NewLogScanCompleted(
@@ -219,20 +347,169 @@ func Scan(typ core.ScannerType, r *database.Redis, c *mirrors.Cache, url string,
 		res.FilesIndexed,
 		res.KnownIndexed,
 		res.Removed,
-		res.TZOffsetMs))
+		res.Suspect,
+		res.TZOffsetMs,
+		res.MaxFiles,
+		res.Truncated))
 
 	return res, nil
 }
 
+// holdSuspects returns the files missing from the mirror that must be removed
+// from its index. When StaleFileGracePeriod is set, the files are first kept
+// as suspect and only removed once they have been missing for that long
+// across the scans. The suspects found again are cleared.
+func (s *scan) holdSuspects(conn redis.Conn, name string, missing []any) (toremove []any, suspect int64, err error) {
+	suspectKey := fmt.Sprintf("MIRRORSUSPECT_%d", s.mirrorid)
+
+	grace := time.Duration(GetConfig().StaleFileGracePeriod) * time.Minute
+	if grace <= 0 {
+		if s.only == "" {
+			conn.Do("DEL", suspectKey)
+		}
+		return missing, 0, nil
+	}
+
+	since, err := redis.Int64Map(conn.Do("HGETALL", suspectKey))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	now := time.Now()
+	seen := make(map[string]bool, len(missing))
+
+	conn.Send("MULTI")
+	for _, e := range missing {
+		f, _ := redis.String(e, nil)
+		seen[f] = true
+		ts, ok := since[f]
+		if !ok {
+			conn.Send("HSET", suspectKey, f, now.Unix())
+		} else if now.Sub(time.Unix(ts, 0)) >= grace {
+			conn.Send("HDEL", suspectKey, f)
+			toremove = append(toremove, e)
+			continue
+		}
+		// Keep the file in the index of the mirror
+		log.Debugf("[%s] %s is missing, kept as suspect", name, f)
+		conn.Send("SADD", s.filesTmpKey, f)
+		suspect++
+	}
+	for f := range since {
+		if !seen[f] && (s.only == "" || strings.HasPrefix(f, s.only+"/")) {
+			// Back on the mirror
+			conn.Send("HDEL", suspectKey, f)
+		}
+	}
+	_, err = conn.Do("EXEC")
+	if err != nil {
+		return nil, 0, err
+	}
+	return toremove, suspect, nil
+}
+
+// CleanScanPath returns the canonical form (e.g. "/some/dir") of the subtree
+// to scan, or an empty string if the whole mirror must be scanned
+func CleanScanPath(only string) (string, error) {
+	if only == "" {
+		return "", nil
+	}
+	if strings.Contains("/"+filepath.ToSlash(only)+"/", "/../") {
+		return "", fmt.Errorf("%w: %s", ErrInvalidScanPath, only)
+	}
+	only = path.Clean("/" + only)
+	if only == "/" {
+		return "", nil
+	}
+	return only, nil
+}
+
+// filterPrefix returns the files of the list starting with the given prefix
+func filterPrefix(files []any, prefix string) []any {
+	filtered := files[:0]
+	for _, e := range files {
+		f, _ := redis.String(e, nil)
+		if strings.HasPrefix(f, prefix) {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+// setPrefix sets the directory of the repository the root of the mirror
+// maps to, if it serves a virtual host, the subtree to scan being relative
+// to the repository
+func (s *scan) setPrefix(name string) error {
+	s.prefix = GetConfig().MirrorPrefix(name)
+	s.remoteOnly = s.only
+	if s.prefix == "" || s.only == "" {
+		return nil
+	}
+	if s.only != s.prefix && !strings.HasPrefix(s.only, s.prefix+"/") {
+		return ErrOutsidePrefix
+	}
+	s.remoteOnly = strings.TrimPrefix(s.only, s.prefix)
+	return nil
+}
+
+// mirrorPath returns the path of the given file of the repository relative
+// to the root of the mirror
+func (s *scan) mirrorPath(path string) string {
+	return strings.TrimPrefix(path, s.prefix)
+}
+
+func newScanner(typ core.ScannerType, s *scan) Scanner {
+	switch typ {
+	case core.RSYNC:
+		return &RsyncScanner{
+			scan: s,
+		}
+	case core.FTP:
+		return &FTPScanner{
+			scan: s,
+		}
+	case core.LOCAL:
+		return &LocalScanner{
+			scan: s,
+		}
+	}
+	panic(fmt.Sprintf("Unknown scanner"))
+}
+
+func scannerName(typ core.ScannerType) string {
+	switch typ {
+	case core.RSYNC:
+		return "rsync"
+	case core.FTP:
+		return "ftp"
+	case core.LOCAL:
+		return "local"
+	}
+	return "unknown"
+}
+
 func (s *scan) ScannerAddFile(f filedata) {
-	s.count++
+	if s.overCap(atomic.LoadInt64(&s.count) + 1) {
+		return
+	}
+	atomic.AddInt64(&s.count, 1)
+
+	// Index the files by their path in the repository
+	f.path = s.prefix + f.path
+
+	if s.dryRun {
+		s.found = append(s.found, f)
+		return
+	}
 
 	// Add all the files to a temporary key
 	s.conn.Send("SADD", s.filesTmpKey, f.path)
 
 	// Mark the file as being supported by this mirror
-	rk := fmt.Sprintf("FILEMIRRORS_%s", f.path)
-	s.conn.Send("SADD", rk, s.mirrorid)
+	if _, ok := s.mismatched[f.path]; !ok || !GetConfig().CrossCheckExclude {
+		rk := fmt.Sprintf("FILEMIRRORS_%s", f.path)
+		s.conn.Send("SADD", rk, s.mirrorid)
+	}
 
 	// Save the size of the current file found on this mirror
 	ik := fmt.Sprintf("FILEINFO_%d_%s", s.mirrorid, f.path)
@@ -279,6 +556,46 @@ func (s *scan) setLastSync(conn redis.Conn, id int, protocol core.ScannerType, p
 	return err
 }
 
+// detectIPVersions resolves the host of the HTTP URL of the mirror to know
+// whether it is reachable over IPv4 and IPv6, unless the versions are set
+// manually. The previous values are kept if the host can't be resolved.
+func (s *scan) detectIPVersions(name string) error {
+	key := fmt.Sprintf("MIRROR_%d", s.mirrorid)
+	values, err := redis.Values(s.conn.Do("HMGET", key, "http", "manualIPFamilies"))
+	if err != nil {
+		return err
+	}
+	var httpURL string
+	var manual bool
+	if _, err = redis.Scan(values, &httpURL, &manual); err != nil {
+		return err
+	}
+	if manual {
+		return nil
+	}
+
+	if !utils.HasAnyPrefix(httpURL, "http://", "https://") {
+		httpURL = "http://" + httpURL
+	}
+	u, err := url.Parse(httpURL)
+	if err != nil {
+		return err
+	}
+	hasIPv4, hasIPv6, err := network.LookupIPVersions(u.Hostname())
+	if err != nil {
+		return err
+	}
+
+	_, err = s.conn.Do("HSET", key, "hasIPv4", hasIPv4, "hasIPv6", hasIPv6)
+	if err != nil {
+		return err
+	}
+
+	// Publish update
+	database.Publish(s.conn, database.MIRROR_UPDATE, strconv.Itoa(s.mirrorid))
+	return nil
+}
+
 func (s *scan) adjustTZOffset(name string, precision core.Precision) (ms int64, err error) {
 	type pair struct {
 		local  filesystem.FileInfo
@@ -313,7 +630,7 @@ func (s *scan) adjustTZOffset(name string, precision core.Precision) (ms int64,
 	for _, path := range filepaths {
 		p := pair{}
 
-		p.local, err = s.cache.GetFileInfo(path)
+		p.local, err = s.cache.GetFileInfo(context.Background(), path)
 		if err != nil {
 			return
 		}
@@ -402,12 +719,12 @@ func (s *sourcescanner) walkSource(conn redis.Conn, path string, f os.FileInfo,
 	d.modTime = f.ModTime()
 
 	// Get the previous file properties
-	properties, err := redis.Strings(conn.Do("HMGET", fmt.Sprintf("FILE_%s", d.path), "size", "modTime", "sha1", "sha256", "md5"))
+	properties, err := redis.Strings(conn.Do("HMGET", fmt.Sprintf("FILE_%s", d.path), "size", "modTime", "sha1", "sha256", "md5", "sha512", "pieceLength", "zsyncBlockSize"))
 	if err != nil && err != redis.ErrNil {
 		return nil, err
-	} else if len(properties) < 5 {
+	} else if len(properties) < 8 {
 		// This will force a rehash
-		properties = make([]string, 5)
+		properties = make([]string, 8)
 	}
 
 	size, _ := strconv.ParseInt(properties[0], 10, 64)
@@ -415,10 +732,12 @@ func (s *sourcescanner) walkSource(conn redis.Conn, path string, f os.FileInfo,
 	sha1 := properties[2]
 	sha256 := properties[3]
 	md5 := properties[4]
+	sha512 := properties[5]
 
 	rehash = rehash ||
 		(GetConfig().Hashes.SHA1 && len(sha1) == 0) ||
 		(GetConfig().Hashes.SHA256 && len(sha256) == 0) ||
+		(GetConfig().Hashes.SHA512 && len(sha512) == 0) ||
 		(GetConfig().Hashes.MD5 && len(md5) == 0)
 
 	if rehash || size != d.size || !modTime.Equal(d.modTime) {
@@ -428,6 +747,7 @@ func (s *sourcescanner) walkSource(conn redis.Conn, path string, f os.FileInfo,
 		} else {
 			d.sha1 = h.Sha1
 			d.sha256 = h.Sha256
+			d.sha512 = h.Sha512
 			d.md5 = h.Md5
 			if len(d.sha1) > 0 {
 				log.Infof("%s: SHA1 %s", d.path, d.sha1)
@@ -435,6 +755,9 @@ func (s *sourcescanner) walkSource(conn redis.Conn, path string, f os.FileInfo,
 			if len(d.sha256) > 0 {
 				log.Infof("%s: SHA256 %s", d.path, d.sha256)
 			}
+			if len(d.sha512) > 0 {
+				log.Infof("%s: SHA512 %s", d.path, d.sha512)
+			}
 			if len(d.md5) > 0 {
 				log.Infof("%s: MD5 %s", d.path, d.md5)
 			}
@@ -442,9 +765,45 @@ func (s *sourcescanner) walkSource(conn redis.Conn, path string, f os.FileInfo,
 	} else {
 		d.sha1 = sha1
 		d.sha256 = sha256
+		d.sha512 = sha512
 		d.md5 = md5
 	}
 
+	// Compute the torrent pieces of the large files
+	if min := GetConfig().TorrentMinSize; min > 0 && d.size >= min {
+		pieceLength, _ := strconv.Atoi(properties[6])
+		if rehash || size != d.size || !modTime.Equal(d.modTime) || pieceLength != GetConfig().TorrentPieceLength {
+			pieces, err := filesystem.PieceHashes(GetConfig().Repository+d.path, int64(GetConfig().TorrentPieceLength))
+			if err != nil {
+				log.Warningf("%s: computing the torrent pieces failed: %s", d.path, err.Error())
+			} else {
+				d.pieces = pieces
+				d.pieceLength = GetConfig().TorrentPieceLength
+				log.Infof("%s: %d torrent pieces", d.path, len(pieces)/20)
+			}
+		} else {
+			d.keepPieces = true
+		}
+	}
+
+	// Compute the zsync block checksums of the large files
+	if min := GetConfig().ZsyncMinSize; min > 0 && d.size >= min {
+		blockSize, _ := strconv.Atoi(properties[7])
+		if rehash || size != d.size || !modTime.Equal(d.modTime) || blockSize != GetConfig().ZsyncBlockSize {
+			sums, sha1sum, err := filesystem.ZsyncBlockSums(GetConfig().Repository+d.path, GetConfig().ZsyncBlockSize, d.size)
+			if err != nil {
+				log.Warningf("%s: computing the zsync checksums failed: %s", d.path, err.Error())
+			} else {
+				d.zsync = sums
+				d.zsyncSha1 = sha1sum
+				d.zsyncBlockSize = GetConfig().ZsyncBlockSize
+				log.Infof("%s: %d zsync blocks", d.path, (d.size+int64(d.zsyncBlockSize)-1)/int64(d.zsyncBlockSize))
+			}
+		} else {
+			d.keepZsync = true
+		}
+	}
+
 	return d, nil
 }
 
@@ -532,12 +891,21 @@ func ScanSource(r *database.Redis, forceRehash bool, stop <-chan struct{}) (err
 	// Create/Update the files' hash keys with the fresh infos
 	conn.Send("MULTI")
 	for _, e := range sourceFiles {
-		conn.Send("HSET", fmt.Sprintf("FILE_%s", e.path),
+		args := []any{fmt.Sprintf("FILE_%s", e.path),
 			"size", e.size,
 			"modTime", e.modTime,
 			"sha1", e.sha1,
 			"sha256", e.sha256,
-			"md5", e.md5)
+			"sha512", e.sha512,
+			"md5", e.md5}
+		if !e.keepPieces {
+			// Also clears the pieces of the files no longer eligible
+			args = append(args, "pieceLength", e.pieceLength, "pieces", e.pieces)
+		}
+		if !e.keepZsync {
+			args = append(args, "zsyncBlockSize", e.zsyncBlockSize, "zsyncSha1", e.zsyncSha1, "zsync", e.zsync)
+		}
+		conn.Send("HSET", args...)
 
 		// Publish update
 		database.SendPublish(conn, database.FILE_UPDATE, e.path)
@@ -88,9 +88,32 @@ func (t *Trace) GetLastUpdate(mirror mirrors.Mirror) error {
 		mirrorURL = "http://" + mirror.HttpURL
 	}
 
+	// Mirrors requiring a client certificate or scanned through a proxy get
+	// their own transport
+	client := &t.httpClient
+	tlsConfig, err := mirror.TLSConfig()
+	if err != nil {
+		return err
+	}
+	proxyURL, err := mirrors.ParseScanProxy(mirror.ScanProxy)
+	if err != nil {
+		return err
+	}
+	if tlsConfig != nil || proxyURL != nil {
+		transport := t.transport.Clone()
+		transport.TLSClientConfig = tlsConfig
+		if proxyURL != nil {
+			transport.Proxy = http.ProxyURL(proxyURL)
+		}
+		client = &http.Client{
+			Transport: transport,
+		}
+	}
+
 	// Prepare the HTTP request
 	req, err := http.NewRequest("GET", utils.ConcatURL(mirrorURL, traceFile), nil)
 	req.Header.Set("User-Agent", userAgent)
+	mirror.HTTPHeaders.Apply(req)
 	req.Close = true
 
 	// Prepare contexts
@@ -107,7 +130,7 @@ func (t *Trace) GetLastUpdate(mirror mirrors.Mirror) error {
 		}
 	}()
 
-	resp, err := t.httpClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
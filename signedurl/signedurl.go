@@ -0,0 +1,77 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+// Package signedurl signs the URLs of the redirects so that they expire,
+// and verifies them on the mirrors. It has no dependency on the rest of
+// mirrorbits and can be embedded in a verification service.
+//
+// A signed URL carries two query parameters:
+//
+//	expires  the expiration time, as a Unix timestamp in seconds
+//	sig      the lowercase hexadecimal HMAC-SHA256, keyed with the shared
+//	         secret, of the expiration time and the escaped path of the URL
+//	         separated by a colon, i.e. "1700000000:/pub/file.iso"
+//
+// The URL is valid until the expiration time if the signature matches. The
+// other query parameters and the host aren't part of the signature, a
+// mirror can be reached under several names.
+package signedurl
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrUnsigned is returned when the URL isn't signed
+	ErrUnsigned = errors.New("signedurl: missing signature")
+	// ErrExpired is returned when the URL is expired
+	ErrExpired = errors.New("signedurl: expired url")
+	// ErrInvalidSignature is returned when the signature doesn't match
+	ErrInvalidSignature = errors.New("signedurl: invalid signature")
+)
+
+// Signature returns the signature of the given path for the expiration time
+func Signature(secret, path string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(expires, 10) + ":" + path))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Sign returns the URL signed with the secret, valid until the given time
+func Sign(rawurl, secret string, expires time.Time) (string, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+	q.Set("sig", Signature(secret, u.EscapedPath(), expires.Unix()))
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// Verify checks that the URL was signed with the secret and isn't expired
+func Verify(u *url.URL, secret string, now time.Time) error {
+	q := u.Query()
+	sig := q.Get("sig")
+	if sig == "" || q.Get("expires") == "" {
+		return ErrUnsigned
+	}
+	expires, err := strconv.ParseInt(q.Get("expires"), 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(sig), []byte(Signature(secret, u.EscapedPath(), expires))) {
+		return ErrInvalidSignature
+	}
+	if now.Unix() > expires {
+		return ErrExpired
+	}
+	return nil
+}
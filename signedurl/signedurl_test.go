@@ -0,0 +1,66 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+package signedurl
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	const secret = "s3cr3t"
+	now := time.Unix(1700000000, 0)
+
+	signed, err := Sign("http://mirror.example.org/pub/my file.iso?foo=bar", secret, now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	tamper := func(f func(u *url.URL)) *url.URL {
+		u, err := url.Parse(signed)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		f(u)
+		return u
+	}
+
+	tests := []struct {
+		name   string
+		u      *url.URL
+		secret string
+		now    time.Time
+		err    error
+	}{
+		{"valid", tamper(func(u *url.URL) {}), secret, now, nil},
+		{"other host", tamper(func(u *url.URL) { u.Host = "mirror2.example.org" }), secret, now, nil},
+		{"expired", tamper(func(u *url.URL) {}), secret, now.Add(2 * time.Hour), ErrExpired},
+		{"wrong secret", tamper(func(u *url.URL) {}), "other", now, ErrInvalidSignature},
+		{"path", tamper(func(u *url.URL) { u.Path = "/pub/other.iso" }), secret, now, ErrInvalidSignature},
+		{"expires", tamper(func(u *url.URL) {
+			q := u.Query()
+			q.Set("expires", "1800000000")
+			u.RawQuery = q.Encode()
+		}), secret, now, ErrInvalidSignature},
+		{"signature", tamper(func(u *url.URL) {
+			q := u.Query()
+			q.Set("sig", strings.Repeat("0", 64))
+			u.RawQuery = q.Encode()
+		}), secret, now, ErrInvalidSignature},
+		{"unsigned", tamper(func(u *url.URL) { u.RawQuery = "foo=bar" }), secret, now, ErrUnsigned},
+	}
+
+	for _, test := range tests {
+		if err := Verify(test.u, test.secret, test.now); err != test.err {
+			t.Fatalf("%s: expected %v, got %v", test.name, test.err, err)
+		}
+	}
+
+	u := tamper(func(u *url.URL) {})
+	if u.Query().Get("foo") != "bar" {
+		t.Fatalf("Expected the query to be preserved, got %s", u.RawQuery)
+	}
+}
@@ -0,0 +1,14 @@
+// Copyright (c) 2014-2019 Ludovic Fauvet
+// Licensed under the MIT license
+
+// Package templates embeds the default templates of the web pages and their
+// translations. The files found in the TemplatesPath take precedence.
+package templates
+
+import "embed"
+
+// FS holds the default templates (i.e. base.html) and the message files of
+// the locales (i.e. locales/fr.yaml)
+//
+//go:embed *.html locales/*.yaml
+var FS embed.FS
@@ -7,7 +7,10 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"path"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/etix/mirrorbits/core"
@@ -49,6 +52,46 @@ func GetDistanceKm(lat1, lon1, lat2, lon2 float32) float32 {
 	return R * float32(c)
 }
 
+// RoundDistance rounds a distance in km to the nearest multiple of precision,
+// a precision <= 0 leaves the distance untouched
+func RoundDistance(km, precision float32) float32 {
+	if precision <= 0 {
+		return km
+	}
+	return float32(math.Round(float64(km/precision))) * precision
+}
+
+// pathRegexps caches the compiled regular expressions of MatchPathPattern
+var pathRegexps sync.Map
+
+// MatchPathPattern reports whether the given path matches the pattern. A
+// pattern prefixed with "regexp:" is a regular expression, anything else is
+// a glob that matches the path itself or any of its parent directories.
+func MatchPathPattern(pattern, p string) (bool, error) {
+	if strings.HasPrefix(pattern, "regexp:") {
+		re, ok := pathRegexps.Load(pattern)
+		if !ok {
+			compiled, err := regexp.Compile(strings.TrimPrefix(pattern, "regexp:"))
+			if err != nil {
+				return false, err
+			}
+			re, _ = pathRegexps.LoadOrStore(pattern, compiled)
+		}
+		return re.(*regexp.Regexp).MatchString(p), nil
+	}
+	for {
+		matched, err := path.Match(pattern, p)
+		if err != nil || matched {
+			return matched, err
+		}
+		parent := path.Dir(p)
+		if parent == p {
+			return false, nil
+		}
+		p = parent
+	}
+}
+
 // Min returns the smallest of the two values
 func Min(v1, v2 int) int {
 	if v1 < v2 {
@@ -199,6 +242,58 @@ func TimeKeyCoverage(start, end time.Time) (dates []string) {
 	return
 }
 
+// StatsPeriod is the part [Start, End) of a date range falling in one day,
+// month or year, Label being the name of this day, month or year
+type StatsPeriod struct {
+	Label string
+	Start time.Time
+	End   time.Time
+}
+
+// SplitStatsPeriods splits the date range into the days, months or years
+// it overlaps (period being daily, monthly or yearly), the first and the
+// last ones being truncated to the range. Each part is meant to be given
+// to TimeKeyCoverage, a range starting and ending the same day being that
+// day, so the parts add up to the stats of the whole range.
+func SplitStatsPeriods(start, end time.Time, period string) ([]StatsPeriod, error) {
+	var layout string
+	var first time.Time
+	var next func(time.Time) time.Time
+	switch period {
+	case "daily":
+		layout = "2006-01-02"
+		first = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+		next = func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }
+	case "monthly":
+		layout = "2006-01"
+		first = time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, start.Location())
+		next = func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }
+	case "yearly":
+		layout = "2006"
+		first = time.Date(start.Year(), 1, 1, 0, 0, 0, 0, start.Location())
+		next = func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }
+	default:
+		return nil, fmt.Errorf("period must be daily, monthly or yearly")
+	}
+
+	if start.Year() == end.Year() && start.YearDay() == end.YearDay() {
+		return []StatsPeriod{{Label: first.Format(layout), Start: start, End: end}}, nil
+	}
+
+	var periods []StatsPeriod
+	for p := first; p.Before(end); p = next(p) {
+		s, e := p, next(p)
+		if s.Before(start) {
+			s = start
+		}
+		if e.After(end) {
+			e = end
+		}
+		periods = append(periods, StatsPeriod{Label: p.Format(layout), Start: s, End: e})
+	}
+	return periods, nil
+}
+
 // FuzzyTimeStr returns the duration as fuzzy time
 func FuzzyTimeStr(duration time.Duration) string {
 	hours := duration.Hours()
@@ -4,6 +4,7 @@
 package utils
 
 import (
+	"reflect"
 	"testing"
 	"time"
 
@@ -54,6 +55,58 @@ func TestGetDistanceKm(t *testing.T) {
 	}
 }
 
+func TestRoundDistance(t *testing.T) {
+	tests := []struct {
+		km        float32
+		precision float32
+		expected  float32
+	}{
+		{876.4, 10, 880},
+		{334.9, 10, 330},
+		{4.9, 10, 0},
+		{5.1, 10, 10},
+		{876.4, 0, 876.4},
+	}
+	for _, test := range tests {
+		if r := RoundDistance(test.km, test.precision); r != test.expected {
+			t.Fatalf("Expected %f for %f/%f, got %f", test.expected, test.km, test.precision, r)
+		}
+	}
+}
+
+func TestMatchPathPattern(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		path     string
+		expected bool
+	}{
+		{"/staging", "/staging", true},
+		{"/staging", "/staging/foo/bar.iso", true},
+		{"/staging", "/stagingfoo", false},
+		{"/stag*", "/staging/foo/bar.iso", true},
+		{"/*/*.iso", "/release/bar.iso", true},
+		{"/*.iso", "/release/bar.iso", false},
+		{"regexp:\\.iso$", "/release/bar.iso", true},
+		{"regexp:^/internal/", "/release/internal/bar.iso", false},
+	}
+	for _, test := range tests {
+		matched, err := MatchPathPattern(test.pattern, test.path)
+		if err != nil {
+			t.Fatalf("Unexpected error for %s: %s", test.pattern, err)
+		}
+		if matched != test.expected {
+			t.Fatalf("Expected %t for %s on %s, got %t", test.expected, test.pattern, test.path, matched)
+		}
+	}
+
+	if _, err := MatchPathPattern("/[", "/foo"); err == nil {
+		t.Fatalf("Expected an error for an invalid glob")
+	}
+	if _, err := MatchPathPattern("regexp:(", "/foo"); err == nil {
+		t.Fatalf("Expected an error for an invalid regular expression")
+	}
+}
+
 func TestMin(t *testing.T) {
 	if r := Min(-10, 5); r != -10 {
 		t.Fatalf("Expected -10, got %d", r)
@@ -255,3 +308,54 @@ func TestTimeKeyCoverage(t *testing.T) {
 		}
 	}
 }
+
+func TestSplitStatsPeriods(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := map[string]struct {
+		start, end time.Time
+		period     string
+		labels     []string
+		keys       []string
+	}{
+		"monthly": {day(2015, 10, 30), day(2016, 1, 3), "monthly",
+			[]string{"2015-10", "2015-11", "2015-12", "2016-01"},
+			[]string{"2015_10_30", "2015_10_31", "2015_11", "2015_12", "2016_01_01", "2016_01_02"}},
+		"daily": {day(2015, 12, 30), day(2016, 1, 2), "daily",
+			[]string{"2015-12-30", "2015-12-31", "2016-01-01"},
+			[]string{"2015_12_30", "2015_12_31", "2016_01_01"}},
+		"yearly": {day(2015, 12, 1), day(2017, 1, 1), "yearly",
+			[]string{"2015", "2016"},
+			[]string{"2015_12", "2016"}},
+		"same day": {day(2015, 12, 2), day(2015, 12, 2), "monthly",
+			[]string{"2015-12"},
+			[]string{"2015_12_02"}},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			periods, err := SplitStatsPeriods(tt.start, tt.end, tt.period)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var labels, keys []string
+			for _, p := range periods {
+				labels = append(labels, p.Label)
+				keys = append(keys, TimeKeyCoverage(p.Start, p.End)...)
+			}
+			if !reflect.DeepEqual(labels, tt.labels) {
+				t.Fatalf("Expected %v, got %v", tt.labels, labels)
+			}
+			// The parts cover the same days as the whole range
+			if !reflect.DeepEqual(keys, tt.keys) {
+				t.Fatalf("Expected %v, got %v", tt.keys, keys)
+			}
+		})
+	}
+
+	if _, err := SplitStatsPeriods(day(2015, 1, 1), day(2015, 2, 1), "all"); err == nil {
+		t.Fatalf("Expected an error")
+	}
+}